@@ -2,32 +2,62 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"stockmarket/internal/ai"
 	"stockmarket/internal/api"
 	"stockmarket/internal/config"
 	"stockmarket/internal/db"
+	"stockmarket/internal/httpclient"
+	"stockmarket/internal/market"
+	"stockmarket/internal/notify"
+	"stockmarket/internal/scheduler"
 	"stockmarket/internal/web"
 )
 
 func main() {
+	migrateTo := flag.Int("migrate-to", -1, "roll the database schema back to this version and exit")
+	flag.Parse()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Initialize database
-	database, err := db.New(cfg.DatabasePath)
+	// Build the shared HTTP clients before any provider or notifier is created
+	market.SetHTTPClient(httpclient.New(cfg.MarketHTTP))
+	market.SetQuoteCacheTTL(cfg.QuoteCacheTTL)
+	ai.SetHTTPClient(httpclient.New(cfg.AIHTTP))
+	notify.SetHTTPClient(httpclient.New(cfg.NotifyHTTP))
+
+	// Initialize database, SQLite unless a PostgreSQL URL is set
+	var database *db.DB
+	if cfg.DatabaseURL != "" {
+		database, err = db.NewPostgres(cfg.DatabaseURL)
+	} else {
+		database, err = db.New(cfg.DatabasePath)
+	}
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.Close()
 
+	if *migrateTo >= 0 {
+		if err := database.MigrateTo(*migrateTo); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		log.Printf("Database schema is at version %d", *migrateTo)
+		return
+	}
+
+	market.SetCandleStore(database)
+
 	// Create templ handlers (new type-safe components)
 	templHandlers := web.NewTemplHandlers(database)
 
@@ -38,6 +68,19 @@ func main() {
 	pollingCtx, pollingCancel := context.WithCancel(context.Background())
 	apiServer.StartPollingService(pollingCtx)
 
+	// Start scheduled watchlist analyses (cadence set in Settings)
+	apiServer.StartAnalysisSchedule(pollingCtx)
+
+	// Retry failed notification deliveries with backoff
+	apiServer.StartNotificationRetries(pollingCtx)
+
+	// Start daily job scheduler (catches up on runs missed during downtime)
+	jobScheduler := scheduler.New(database)
+	jobScheduler.Register(apiServer.PruneJob())
+	jobScheduler.Register(apiServer.CrossoverJob())
+	jobScheduler.Register(apiServer.DigestJob())
+	jobScheduler.Start(pollingCtx)
+
 	// Setup routes
 	mux := http.NewServeMux()
 
@@ -64,6 +107,7 @@ func main() {
 	mux.HandleFunc("/partials/analysis-detail/", templHandlers.PartialAnalysisDetail)
 	mux.HandleFunc("/partials/alerts-list", templHandlers.PartialAlertsList)
 	mux.HandleFunc("/partials/quick-analyze", templHandlers.PartialQuickAnalyze)
+	mux.HandleFunc("/partials/analysis-presets", templHandlers.PartialAnalysisPresets)
 	mux.HandleFunc("/partials/watchlist-alert-buttons", templHandlers.PartialWatchlistAlertButtons)
 
 	// Add CORS middleware
@@ -5,10 +5,16 @@ go 1.25.0
 require (
 	github.com/a-h/templ v0.3.977
 	github.com/gorilla/websocket v1.5.1
+	github.com/jackc/pgx/v5 v5.11.0
 	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/scmhub/calendar v0.0.0-20250305134741-bdfe49f3f914
+	golang.org/x/sync v0.17.0
 )
 
 require (
-	github.com/scmhub/calendar v0.0.0-20250305134741-bdfe49f3f914 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
 )
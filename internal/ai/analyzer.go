@@ -4,26 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
+	"math"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
+	"stockmarket/internal/httpclient"
+	"stockmarket/internal/indicators"
 	"stockmarket/internal/models"
 )
 
-// Shared HTTP client with optimized transport for all AI providers
-var sharedHTTPClient = &http.Client{
-	Timeout: 60 * time.Second,
-	Transport: &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   10 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		MaxIdleConns:        50,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-		TLSHandshakeTimeout: 10 * time.Second,
-	},
+// Shared HTTP client for all AI providers, replaced at startup by SetHTTPClient
+var sharedHTTPClient = httpclient.New(httpclient.AIDefaults)
+
+// SetHTTPClient replaces the shared client; call it before creating any AI providers
+func SetHTTPClient(client *http.Client) {
+	sharedHTTPClient = client
 }
 
 // Analyzer defines the interface for AI analysis providers
@@ -35,11 +31,48 @@ type Analyzer interface {
 // ErrNoAPIKey is returned when no API key is configured
 var ErrNoAPIKey = errors.New("no API key configured")
 
+// ErrNoEndpoint is returned when a provider needing an endpoint and deployment,
+// such as Azure OpenAI, has none configured
+var ErrNoEndpoint = errors.New("no endpoint or deployment configured")
+
 // ErrAnalysisFailed is returned when analysis fails
 var ErrAnalysisFailed = errors.New("analysis failed")
 
-// NewAnalyzer creates an AI analyzer based on the provider name
-func NewAnalyzer(provider string, apiKey string, model string) (Analyzer, error) {
+// ErrRateLimited is returned when the provider rejects the request due to rate limits
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// ErrInvalidAPIKey is returned when the provider rejects the API key
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// ErrProviderUnavailable is returned when the provider cannot be reached or has an outage
+var ErrProviderUnavailable = errors.New("provider unavailable")
+
+// statusError classifies a non-200 provider response
+func statusError(status int, message string) error {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %w: %s", ErrAnalysisFailed, ErrRateLimited, message)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("%w: %w: %s", ErrAnalysisFailed, ErrInvalidAPIKey, message)
+	case status >= 500:
+		return fmt.Errorf("%w: %w: %s", ErrAnalysisFailed, ErrProviderUnavailable, message)
+	default:
+		return fmt.Errorf("%w: %s", ErrAnalysisFailed, message)
+	}
+}
+
+// IsRetryable reports whether a failed analysis may succeed with another provider
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) ||
+		errors.Is(err, ErrInvalidAPIKey) ||
+		errors.Is(err, ErrNoAPIKey) ||
+		errors.Is(err, ErrNoEndpoint) ||
+		errors.Is(err, ErrProviderUnavailable)
+}
+
+// NewAnalyzer creates an AI analyzer based on the provider name; endpoint is
+// only used by Azure OpenAI, whose model is the deployment name
+func NewAnalyzer(provider string, apiKey string, model string, endpoint string) (Analyzer, error) {
 	switch provider {
 	case "openai":
 		return NewOpenAI(apiKey, model), nil
@@ -47,6 +80,14 @@ func NewAnalyzer(provider string, apiKey string, model string) (Analyzer, error)
 		return NewClaude(apiKey, model), nil
 	case "gemini":
 		return NewGemini(apiKey, model), nil
+	case "groq":
+		return NewGroq(apiKey, model), nil
+	case "mistral":
+		return NewMistral(apiKey, model), nil
+	case "deepseek":
+		return NewDeepSeek(apiKey, model), nil
+	case "azure-openai":
+		return NewAzureOpenAI(endpoint, apiKey, model), nil
 	default:
 		return nil, errors.New("unknown AI provider: " + provider)
 	}
@@ -60,8 +101,8 @@ func BuildPrompt(req models.AnalysisRequest) string {
 	prompt := `You are an expert stock market analyst. Analyze the following stock data and provide a trading recommendation.
 
 Stock: ` + req.Symbol + `
-Current Price: $` + formatFloat(req.CurrentPrice) + `
-
+Current Price: ` + formatPrice(req.CurrentPrice, req.Currency) + `
+` + FormatCurrency(req.Currency) + FormatProfile(req.Profile) + `
 Risk Profile: ` + riskProfile.Name + `
 ` + riskProfile.PromptModifier + `
 
@@ -74,19 +115,34 @@ Historical Data (most recent ` + formatInt(len(req.HistoricalData)) + ` periods)
 
 	// Add historical data summary
 	if len(req.HistoricalData) > 0 {
-		prompt += formatHistoricalSummary(req.HistoricalData)
+		prompt += FormatHistoricalSummary(req.HistoricalData)
 	}
 
+	prompt += FormatIndicators(req.Indicators)
+	prompt += FormatBenchmark(req.Symbol, req.Benchmark)
+	prompt += FormatRelativeStrength(req.Symbol, req.RelativeStrength)
+	prompt += FormatTimeframes(req.Timeframes)
+	prompt += FormatNews(req.News)
+	prompt += FormatPosition(req.Position, req.Currency)
+
+	if req.Thesis != "" {
+		prompt += "\nInvestment Thesis (the investor's standing constraints; keep every recommendation consistent with them): " + req.Thesis + "\n"
+	}
 	if req.UserContext != "" {
 		prompt += "\nUser Notes: " + req.UserContext + "\n"
 	}
 
+	actions := `"BUY" | "SELL" | "HOLD" | "WATCH"`
+	if req.Position != nil {
+		actions = `"ADD" | "TRIM" | "HOLD" | "SELL"`
+	}
 	prompt += `
 Provide your analysis in the following JSON format:
 {
-  "action": "BUY" | "SELL" | "HOLD" | "WATCH",
+  "action": ` + actions + `,
   "confidence": 0.0-1.0,
   "reasoning": "detailed explanation",
+  "highlights": ["2-3 short bullet points with the key reasons"],
   "price_targets": {
     "entry": price,
     "target": price,
@@ -97,10 +153,33 @@ Provide your analysis in the following JSON format:
 }
 
 Respond ONLY with valid JSON, no additional text.`
+	prompt += FormatRepair(req.PreviousReply, req.ReplyError)
 
 	return prompt
 }
 
+// formatPrice formats a price with $ for US dollars and the ISO code
+// otherwise, keeping four decimals below 10 for exchange rates
+func formatPrice(price float64, currency string) string {
+	amount := formatFloat(price)
+	if price < 10 {
+		amount = fmt.Sprintf("%.4f", price)
+	}
+	if currency == "" || currency == "USD" {
+		return "$" + amount
+	}
+	return amount + " " + currency
+}
+
+// FormatCurrency tells the model which currency the prices are in; it returns
+// "" for US dollars
+func FormatCurrency(currency string) string {
+	if currency == "" || currency == "USD" {
+		return ""
+	}
+	return "Currency: all prices are in " + currency + "; give price targets in " + currency + " too\n"
+}
+
 func formatFloat(f float64) string {
 	return fmt.Sprintf("%.2f", f)
 }
@@ -109,7 +188,172 @@ func formatInt(i int) string {
 	return fmt.Sprintf("%d", i)
 }
 
-func formatHistoricalSummary(candles []models.Candle) string {
+// FormatProfile describes the company and its valuation for the prompt
+func FormatProfile(p *models.CompanyProfile) string {
+	if p == nil || p.Name == "" {
+		return ""
+	}
+
+	summary := "Company: " + p.Name
+	var details []string
+	for _, d := range []string{p.Sector, p.Industry, p.Exchange} {
+		if d != "" {
+			details = append(details, d)
+		}
+	}
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, ", ") + ")"
+	}
+	summary += "\n"
+	if p.MarketCap > 0 {
+		summary += "Market Cap: " + formatMarketCap(p.MarketCap) + "\n"
+	}
+	if p.PERatio > 0 {
+		summary += "P/E Ratio: " + formatFloat(p.PERatio) + "\n"
+	}
+	return summary
+}
+
+// formatMarketCap abbreviates a market capitalization (e.g. $2.95T)
+func formatMarketCap(v float64) string {
+	switch {
+	case v >= 1e12:
+		return fmt.Sprintf("$%.2fT", v/1e12)
+	case v >= 1e9:
+		return fmt.Sprintf("$%.2fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("$%.2fM", v/1e6)
+	}
+	return "$" + formatFloat(v)
+}
+
+// FormatIndicators lists the available technical indicators for the prompt
+func FormatIndicators(ind models.Indicators) string {
+	lines := ""
+	if ind.RSI14 != nil {
+		lines += fmt.Sprintf("RSI (14): %.1f\n", *ind.RSI14)
+	}
+	if ind.SMA20 != nil {
+		lines += fmt.Sprintf("SMA (20): $%.2f\n", *ind.SMA20)
+	}
+	if ind.ATR14 != nil {
+		lines += fmt.Sprintf("ATR (14): $%.2f\n", *ind.ATR14)
+	}
+	if lines == "" {
+		return ""
+	}
+	return "\nTechnical Indicators:\n" + lines
+}
+
+// FormatBenchmark compares the symbol's return over the history window with
+// the benchmark's; it returns "" when no benchmark is available
+func FormatBenchmark(symbol string, b *models.Benchmark) string {
+	if b == nil {
+		return ""
+	}
+	relative := b.SymbolReturn - b.Return
+	verb := "outperforming"
+	if relative < 0 {
+		verb = "underperforming"
+	}
+	return fmt.Sprintf("\nBenchmark (%s): %+.2f%% over the same period vs %+.2f%% for %s, %s it by %.2f percentage points\n",
+		b.Symbol, b.Return, b.SymbolReturn, symbol, verb, math.Abs(relative))
+}
+
+// FormatRelativeStrength lists the returns of the symbol's sector peers and
+// the benchmark; it returns "" when the analysis is not relative
+func FormatRelativeStrength(symbol string, rs *models.RelativeStrength) string {
+	if rs == nil {
+		return ""
+	}
+	summary := fmt.Sprintf("\nSector Peers (%s returns):\n- %s: %+.2f%%\n", rs.Period, symbol, rs.SymbolReturn)
+	for _, p := range rs.Peers {
+		summary += fmt.Sprintf("- %s: %+.2f%% (%+.2f%% latest session)\n", p.Symbol, p.Return, p.ChangePercent)
+	}
+	verb := "leading"
+	if rs.Spread < 0 {
+		verb = "lagging"
+	}
+	summary += fmt.Sprintf("- Peer average: %+.2f%%, %s %s by %.2f percentage points\n",
+		rs.PeerAverage, symbol, verb, math.Abs(rs.Spread))
+	if rs.Benchmark != "" {
+		summary += fmt.Sprintf("- Benchmark %s: %+.2f%%\n", rs.Benchmark, rs.BenchmarkReturn)
+	}
+	summary += "Judge whether the move is specific to " + symbol + " or shared by its sector, and weigh its relative strength in your recommendation.\n"
+	return summary
+}
+
+// FormatTimeframes summarizes each multi-timeframe window and asks the model
+// to reconcile them; it returns "" when no timeframes were requested
+func FormatTimeframes(frames []models.Timeframe) string {
+	if len(frames) == 0 {
+		return ""
+	}
+
+	summary := "\nMulti-Timeframe Summary:\n"
+	for _, f := range frames {
+		if len(f.Candles) == 0 {
+			summary += fmt.Sprintf("- %s: no data available\n", f.Label)
+			continue
+		}
+		first, last := f.Candles[0], f.Candles[len(f.Candles)-1]
+		high, low := first.High, first.Low
+		for _, c := range f.Candles {
+			high = max(high, c.High)
+			low = min(low, c.Low)
+		}
+		change := 0.0
+		if first.Open > 0 {
+			change = (last.Close - first.Open) / first.Open * 100
+		}
+		summary += fmt.Sprintf("- %s (%d bars): change %+.2f%%, range $%.2f-$%.2f, last close $%.2f",
+			f.Label, len(f.Candles), change, low, high, last.Close)
+		if sma, ok := indicators.SMA(f.Candles, indicators.DefaultSMAPeriod); ok {
+			trend := "above"
+			if last.Close < sma {
+				trend = "below"
+			}
+			summary += fmt.Sprintf(", %s its %d-bar average ($%.2f)", trend, indicators.DefaultSMAPeriod, sma)
+		}
+		summary += "\n"
+	}
+	summary += "Reconcile these timeframes: state whether their trends agree, and when they conflict (e.g. bullish daily but weekly downtrend) explain which one drives your recommendation.\n"
+	return summary
+}
+
+// FormatNews lists recent headlines so the model can weigh catalysts such as
+// earnings reports; it returns "" when there are none
+func FormatNews(items []models.NewsItem) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+	summary := "\nRecent News:\n"
+	for _, n := range items {
+		summary += "- " + n.PublishedAt.Format("2006-01-02")
+		if n.Source != "" {
+			summary += " (" + n.Source + ")"
+		}
+		summary += ": " + n.Headline + "\n"
+	}
+	summary += "Consider whether these headlines are catalysts that the price data does not reflect yet.\n"
+	return summary
+}
+
+// FormatPosition describes the user's holding and asks for a recommendation
+// relative to it; it returns "" when the analysis is not portfolio-aware
+func FormatPosition(p *models.PositionContext, currency string) string {
+	if p == nil {
+		return ""
+	}
+	return fmt.Sprintf("\nCurrent Position:\n- Shares held: %s at an average cost of %s\n- Market value: %s, unrealized P/L %+.2f%%\n- Portfolio weight: %.1f%% of the portfolio at cost\n",
+		strconv.FormatFloat(p.Shares, 'f', -1, 64), formatPrice(p.CostBasis, currency), formatPrice(p.MarketValue, currency),
+		p.UnrealizedPLPercent, p.PortfolioWeight) +
+		"Recommend relative to this position: ADD to buy more, TRIM to sell part of it, HOLD to keep it, or SELL to close it. Weigh the unrealized gain or loss and the position's weight; the entry price target is where to add.\n"
+}
+
+// FormatHistoricalSummary summarizes candles as included in the analysis prompt
+func FormatHistoricalSummary(candles []models.Candle) string {
 	if len(candles) == 0 {
 		return "No historical data available\n"
 	}
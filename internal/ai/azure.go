@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// azureAPIVersion is the Azure OpenAI REST API version requests are made against
+const azureAPIVersion = "2024-10-21"
+
+// AzureOpenAI implements the Analyzer interface for an Azure OpenAI resource,
+// where a deployment rather than a model is addressed
+type AzureOpenAI struct {
+	endpoint   string // resource URL, e.g. https://my-resource.openai.azure.com
+	apiKey     string
+	deployment string
+	client     *http.Client
+}
+
+// NewAzureOpenAI creates a new Azure OpenAI analyzer for a deployment of the
+// resource at endpoint
+func NewAzureOpenAI(endpoint, apiKey, deployment string) *AzureOpenAI {
+	return &AzureOpenAI{
+		endpoint:   strings.TrimRight(strings.TrimSpace(endpoint), "/"),
+		apiKey:     apiKey,
+		deployment: strings.TrimSpace(deployment),
+		client:     sharedHTTPClient,
+	}
+}
+
+// Name returns the provider name
+func (a *AzureOpenAI) Name() string {
+	return "azure-openai"
+}
+
+// Analyze performs stock analysis using the Azure OpenAI deployment
+func (a *AzureOpenAI) Analyze(ctx context.Context, req models.AnalysisRequest) (*models.AnalysisResponse, error) {
+	if a.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	if a.endpoint == "" || a.deployment == "" {
+		return nil, ErrNoEndpoint
+	}
+
+	content, usage, err := chatCompletion(ctx, a.client, a.chat(req))
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, a.Name(), a.deployment, usage)
+}
+
+// AnalyzeStream performs stock analysis using the Azure OpenAI deployment,
+// passing the reply to onText as it is generated
+func (a *AzureOpenAI) AnalyzeStream(ctx context.Context, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+	if a.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	if a.endpoint == "" || a.deployment == "" {
+		return nil, ErrNoEndpoint
+	}
+
+	content, usage, err := chatCompletionStream(ctx, a.client, a.chat(req), onText)
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, a.Name(), a.deployment, usage)
+}
+
+// chat builds the chat completions call of the deployment for req
+func (a *AzureOpenAI) chat(req models.AnalysisRequest) chatRequest {
+	return chatRequest{
+		endpoint: a.endpoint + "/openai/deployments/" + url.PathEscape(a.deployment) +
+			"/chat/completions?api-version=" + azureAPIVersion,
+		headers:     map[string]string{"api-key": a.apiKey},
+		model:       a.deployment,
+		prompt:      BuildPrompt(req),
+		generation:  generation(req),
+		reasoning:   true,
+		jsonSchema:  true,
+		streamUsage: true,
+	}
+}
@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"stockmarket/internal/models"
 )
 
 const claudeBaseURL = "https://api.anthropic.com/v1/messages"
 
+// recordAnalysisTool is the tool Claude is made to call with its analysis, so
+// the reply always follows analysisSchema
+const recordAnalysisTool = "record_analysis"
+
 // Claude implements the Analyzer interface for Anthropic Claude API
 type Claude struct {
 	apiKey string
@@ -42,16 +48,241 @@ func (c *Claude) Analyze(ctx context.Context, req models.AnalysisRequest) (*mode
 		return nil, ErrNoAPIKey
 	}
 
-	prompt := BuildPrompt(req)
+	result, err := c.message(ctx, c.request(req, false))
+	if err != nil {
+		return nil, err
+	}
+	content := result.reply()
+	if content == "" {
+		return nil, ErrAnalysisFailed
+	}
+
+	return finish(req.Symbol, content, c.Name(), c.model, result.Usage.tokens())
+}
+
+// AnalyzeWithTools performs stock analysis using Claude, letting the model
+// call tools for more market data
+func (c *Claude) AnalyzeWithTools(ctx context.Context, req models.AnalysisRequest, tools Tools, onText func(string)) (*models.AnalysisResponse, error) {
+	if c.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	// The model must call a tool: a data tool, or the record tool to answer,
+	// which the last round forces
+	body := c.request(req, false)
+	specs := body["tools"].([]interface{})
+	for _, spec := range toolSpecs {
+		specs = append(specs, map[string]interface{}{
+			"name":         spec.name,
+			"description":  spec.description,
+			"input_schema": spec.parameters,
+		})
+	}
+	body["tools"] = specs
+	body["tool_choice"] = map[string]string{"type": "any"}
+
+	var used tokens
+	for round := 1; round <= maxToolRounds; round++ {
+		if round == maxToolRounds {
+			body["tool_choice"] = recordAnalysisChoice
+		}
+		result, err := c.message(ctx, body)
+		if err != nil {
+			return nil, err
+		}
+		used.prompt += result.Usage.InputTokens
+		used.completion += result.Usage.OutputTokens
+
+		var toolResults []interface{}
+		for _, block := range result.Content {
+			if block.Type == "tool_use" && block.Name != recordAnalysisTool {
+				toolResults = append(toolResults, map[string]interface{}{
+					"type":        "tool_result",
+					"tool_use_id": block.ID,
+					"content":     callTool(ctx, tools, block.Name, block.Input),
+				})
+			}
+		}
+		if answer, ok := result.recorded(); ok || len(toolResults) == 0 {
+			if !ok {
+				answer = result.reply()
+			}
+			if onText != nil {
+				onText(answer)
+			}
+			return finish(req.Symbol, answer, c.Name(), c.model, used)
+		}
+		body["messages"] = append(body["messages"].([]interface{}),
+			map[string]interface{}{"role": "assistant", "content": result.Content},
+			map[string]interface{}{"role": "user", "content": toolResults},
+		)
+	}
+	return nil, ErrAnalysisFailed
+}
+
+// AnalyzeStream performs stock analysis using Claude, passing the reply to
+// onText as it is generated
+func (c *Claude) AnalyzeStream(ctx context.Context, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+	if c.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	resp, err := c.post(ctx, c.request(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var used tokens
+	err = readEvents(resp.Body, func(data []byte) error {
+		var event struct {
+			Type    string `json:"type"`
+			Message struct {
+				Usage claudeUsage `json:"usage"`
+			} `json:"message"`
+			Delta struct {
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+			} `json:"delta"`
+			Usage claudeUsage `json:"usage"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil
+		}
+		switch event.Type {
+		case "message_start":
+			used.prompt = event.Message.Usage.InputTokens
+		case "message_delta":
+			used.completion = event.Usage.OutputTokens
+		case "content_block_delta":
+			// The analysis arrives as the input of the record tool
+			if text := event.Delta.Text + event.Delta.PartialJSON; text != "" {
+				content.WriteString(text)
+				onText(text)
+			}
+		case "error":
+			// Errors after the stream started, e.g. overloaded_error
+			return fmt.Errorf("%w: %w: %s", ErrAnalysisFailed, ErrProviderUnavailable, event.Error.Message)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrAnalysisFailed) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
+	}
+	if content.Len() == 0 {
+		return nil, ErrAnalysisFailed
+	}
+	return finish(req.Symbol, content.String(), c.Name(), c.model, used)
+}
+
+// claudeUsage is the token usage reported by the messages API
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// tokens returns the token counts of the usage
+func (u claudeUsage) tokens() tokens {
+	return tokens{u.InputTokens, u.OutputTokens}
+}
+
+// claudeBlock is a content block of a message: text, or a tool call whose
+// input is kept as sent so the block can be returned in the conversation
+type claudeBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// claudeMessage is a reply of the messages API
+type claudeMessage struct {
+	Content []claudeBlock `json:"content"`
+	Usage   claudeUsage   `json:"usage"`
+}
+
+// recorded returns the analysis the model passed to the record tool
+func (m *claudeMessage) recorded() (string, bool) {
+	for _, block := range m.Content {
+		if block.Type == "tool_use" && block.Name == recordAnalysisTool {
+			return string(block.Input), true
+		}
+	}
+	return "", false
+}
+
+// reply returns the recorded analysis, or the text of the message when the
+// model did not call the record tool
+func (m *claudeMessage) reply() string {
+	if answer, ok := m.recorded(); ok {
+		return answer
+	}
+	var text strings.Builder
+	for _, block := range m.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String()
+}
+
+// recordAnalysisChoice forces the model to answer with the record tool
+var recordAnalysisChoice = map[string]string{"type": "tool", "name": recordAnalysisTool}
 
+// request builds a messages API request body for req, which has the model
+// answer through the record tool. Extended thinking cannot be combined with
+// a forced tool, so the reasoning effort is not applied.
+func (c *Claude) request(req models.AnalysisRequest, stream bool) map[string]interface{} {
+	gen := generation(req)
 	requestBody := map[string]interface{}{
-		"model":      c.model,
-		"max_tokens": 1000,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
+		"model":       c.model,
+		"max_tokens":  gen.MaxTokens,
+		"temperature": min(gen.Temperature, 1), // the messages API caps it at 1
+		"messages": []interface{}{
+			map[string]string{"role": "user", "content": BuildPrompt(req)},
+		},
+		"tools": []interface{}{
+			map[string]interface{}{
+				"name":         recordAnalysisTool,
+				"description":  "Record the stock analysis.",
+				"input_schema": analysisSchema(true),
+			},
 		},
+		"tool_choice": recordAnalysisChoice,
+	}
+	if stream {
+		requestBody["stream"] = true
+	}
+	return requestBody
+}
+
+// message sends a request to the messages API and decodes the reply
+func (c *Claude) message(ctx context.Context, requestBody map[string]interface{}) (*claudeMessage, error) {
+	resp, err := c.post(ctx, requestBody)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
+	var result claudeMessage
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// post sends a request to the messages API and returns the response when its
+// status is 200; the caller closes the body
+func (c *Claude) post(ctx context.Context, requestBody map[string]interface{}) (*http.Response, error) {
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, err
@@ -68,34 +299,18 @@ func (c *Claude) Analyze(ctx context.Context, req models.AnalysisRequest) (*mode
 
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
 		var errResp struct {
 			Error struct {
 				Message string `json:"message"`
 			} `json:"error"`
 		}
 		json.NewDecoder(resp.Body).Decode(&errResp)
-		return nil, fmt.Errorf("%w: %s", ErrAnalysisFailed, errResp.Error.Message)
-	}
-
-	var result struct {
-		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"content"`
+		return nil, statusError(resp.StatusCode, errResp.Error.Message)
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	if len(result.Content) == 0 {
-		return nil, ErrAnalysisFailed
-	}
-
-	return parseAnalysisResponse(req.Symbol, result.Content[0].Text)
+	return resp, nil
 }
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+
+	"stockmarket/internal/models"
+)
+
+// deepSeekBaseURL is DeepSeek's OpenAI-compatible chat completions endpoint
+const deepSeekBaseURL = "https://api.deepseek.com/chat/completions"
+
+// DeepSeek implements the Analyzer interface for the DeepSeek API
+type DeepSeek struct {
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewDeepSeek creates a new DeepSeek analyzer
+func NewDeepSeek(apiKey string, model string) *DeepSeek {
+	if model == "" {
+		model = "deepseek-chat"
+	}
+	return &DeepSeek{
+		apiKey: apiKey,
+		model:  model,
+		client: sharedHTTPClient,
+	}
+}
+
+// Name returns the provider name
+func (d *DeepSeek) Name() string {
+	return "deepseek"
+}
+
+// Analyze performs stock analysis using DeepSeek, asking for JSON output
+func (d *DeepSeek) Analyze(ctx context.Context, req models.AnalysisRequest) (*models.AnalysisResponse, error) {
+	if d.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	content, usage, err := chatCompletion(ctx, d.client, d.chat(req))
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, d.Name(), d.model, usage)
+}
+
+// AnalyzeStream performs stock analysis using DeepSeek, passing the reply to
+// onText as it is generated
+func (d *DeepSeek) AnalyzeStream(ctx context.Context, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+	if d.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	content, usage, err := chatCompletionStream(ctx, d.client, d.chat(req), onText)
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, d.Name(), d.model, usage)
+}
+
+// chat builds the chat completions call for req
+func (d *DeepSeek) chat(req models.AnalysisRequest) chatRequest {
+	return chatRequest{
+		endpoint:    deepSeekBaseURL,
+		headers:     bearer(d.apiKey),
+		model:       d.model,
+		prompt:      BuildPrompt(req),
+		generation:  generation(req),
+		jsonMode:    true,
+		streamUsage: true,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"stockmarket/internal/models"
 )
@@ -36,29 +37,121 @@ func (g *Gemini) Name() string {
 	return "gemini"
 }
 
+// geminiResponse is a generateContent response, or one chunk of a streamed one
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// tokens returns the token counts of the response; streamed chunks report
+// running totals, so the last chunk holds those of the whole call
+func (r geminiResponse) tokens() tokens {
+	return tokens{r.UsageMetadata.PromptTokenCount, r.UsageMetadata.CandidatesTokenCount}
+}
+
 // Analyze performs stock analysis using Gemini
 func (g *Gemini) Analyze(ctx context.Context, req models.AnalysisRequest) (*models.AnalysisResponse, error) {
 	if g.apiKey == "" {
 		return nil, ErrNoAPIKey
 	}
 
-	prompt := BuildPrompt(req)
+	resp, err := g.post(ctx, req, "generateContent")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
+		return nil, ErrAnalysisFailed
+	}
+
+	return finish(req.Symbol, result.Candidates[0].Content.Parts[0].Text, g.Name(), g.model, result.tokens())
+}
+
+// AnalyzeStream performs stock analysis using Gemini, passing the reply to
+// onText as it is generated
+func (g *Gemini) AnalyzeStream(ctx context.Context, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+	if g.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	resp, err := g.post(ctx, req, "streamGenerateContent?alt=sse")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var used tokens
+	err = readEvents(resp.Body, func(data []byte) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil
+		}
+		if t := chunk.tokens(); t.prompt > 0 || t.completion > 0 {
+			used = t
+		}
+		for _, candidate := range chunk.Candidates {
+			for _, part := range candidate.Content.Parts {
+				if part.Text != "" {
+					content.WriteString(part.Text)
+					onText(part.Text)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
+	}
+	if content.Len() == 0 {
+		return nil, ErrAnalysisFailed
+	}
+	return finish(req.Symbol, content.String(), g.Name(), g.model, used)
+}
 
+// post sends the prompt of req to the given method of the model and returns
+// the response when its status is 200; the caller closes the body
+func (g *Gemini) post(ctx context.Context, req models.AnalysisRequest, method string) (*http.Response, error) {
 	// Use header-based auth instead of URL param to prevent key from being logged
-	url := fmt.Sprintf("%s/%s:generateContent", geminiBaseURL, g.model)
+	url := fmt.Sprintf("%s/%s:%s", geminiBaseURL, g.model, method)
+
+	gen := generation(req)
+	generationConfig := map[string]interface{}{
+		"temperature":      gen.Temperature,
+		"maxOutputTokens":  gen.MaxTokens,
+		"responseMimeType": "application/json",
+		"responseSchema":   analysisSchema(false),
+	}
+	// Thinking tokens count towards maxOutputTokens
+	if budget, ok := reasoningBudgets[gen.ReasoningEffort]; ok {
+		generationConfig["maxOutputTokens"] = gen.MaxTokens + budget
+		generationConfig["thinkingConfig"] = map[string]interface{}{"thinkingBudget": budget}
+	}
 
 	requestBody := map[string]interface{}{
 		"contents": []map[string]interface{}{
 			{
 				"parts": []map[string]string{
-					{"text": prompt},
+					{"text": BuildPrompt(req)},
 				},
 			},
 		},
-		"generationConfig": map[string]interface{}{
-			"temperature":     0.3,
-			"maxOutputTokens": 1000,
-		},
+		"generationConfig": generationConfig,
 	}
 
 	jsonBody, err := json.Marshal(requestBody)
@@ -76,37 +169,18 @@ func (g *Gemini) Analyze(ctx context.Context, req models.AnalysisRequest) (*mode
 
 	resp, err := g.client.Do(httpReq)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
 		var errResp struct {
 			Error struct {
 				Message string `json:"message"`
 			} `json:"error"`
 		}
 		json.NewDecoder(resp.Body).Decode(&errResp)
-		return nil, fmt.Errorf("%w: %s", ErrAnalysisFailed, errResp.Error.Message)
-	}
-
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
+		return nil, statusError(resp.StatusCode, errResp.Error.Message)
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return nil, ErrAnalysisFailed
-	}
-
-	return parseAnalysisResponse(req.Symbol, result.Candidates[0].Content.Parts[0].Text)
+	return resp, nil
 }
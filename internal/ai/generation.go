@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"slices"
+
+	"stockmarket/internal/models"
+)
+
+// Default generation settings, used for requests that set none
+const (
+	DefaultTemperature = 0.3
+	DefaultMaxTokens   = 2048
+)
+
+// Bounds of the configurable generation settings
+const (
+	MaxTemperature = 2.0
+	MinMaxTokens   = 256
+	MaxMaxTokens   = 32768
+)
+
+// ReasoningEfforts are the reasoning efforts a user can choose; "" leaves the
+// model's default
+var ReasoningEfforts = []string{"low", "medium", "high"}
+
+// reasoningBudgets are the tokens reserved for reasoning at each effort, on
+// top of the reply's max tokens, for providers that count them together
+var reasoningBudgets = map[string]int{"low": 1024, "medium": 4096, "high": 16384}
+
+// ValidReasoningEffort reports whether effort is "" or one of ReasoningEfforts
+func ValidReasoningEffort(effort string) bool {
+	return effort == "" || slices.Contains(ReasoningEfforts, effort)
+}
+
+// generation returns the generation settings of req, or the defaults when
+// it sets none
+func generation(req models.AnalysisRequest) models.GenerationSettings {
+	g := req.Generation
+	if g.MaxTokens == 0 {
+		g.Temperature = DefaultTemperature
+		g.MaxTokens = DefaultMaxTokens
+	}
+	return g
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+
+	"stockmarket/internal/models"
+)
+
+// groqBaseURL is Groq's OpenAI-compatible chat completions endpoint
+const groqBaseURL = "https://api.groq.com/openai/v1/chat/completions"
+
+// Groq implements the Analyzer interface for the Groq API
+type Groq struct {
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewGroq creates a new Groq analyzer
+func NewGroq(apiKey string, model string) *Groq {
+	if model == "" {
+		model = "llama-3.3-70b-versatile"
+	}
+	return &Groq{
+		apiKey: apiKey,
+		model:  model,
+		client: sharedHTTPClient,
+	}
+}
+
+// Name returns the provider name
+func (g *Groq) Name() string {
+	return "groq"
+}
+
+// Analyze performs stock analysis using Groq
+func (g *Groq) Analyze(ctx context.Context, req models.AnalysisRequest) (*models.AnalysisResponse, error) {
+	if g.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	content, usage, err := chatCompletion(ctx, g.client, g.chat(req))
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, g.Name(), g.model, usage)
+}
+
+// AnalyzeStream performs stock analysis using Groq, passing the reply to
+// onText as it is generated
+func (g *Groq) AnalyzeStream(ctx context.Context, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+	if g.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	content, usage, err := chatCompletionStream(ctx, g.client, g.chat(req), onText)
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, g.Name(), g.model, usage)
+}
+
+// chat builds the chat completions call for req
+func (g *Groq) chat(req models.AnalysisRequest) chatRequest {
+	return chatRequest{
+		endpoint:   groqBaseURL,
+		headers:    bearer(g.apiKey),
+		model:      g.model,
+		prompt:     BuildPrompt(req),
+		generation: generation(req),
+	}
+}
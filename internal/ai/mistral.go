@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+
+	"stockmarket/internal/models"
+)
+
+// mistralBaseURL is Mistral's chat completions endpoint
+const mistralBaseURL = "https://api.mistral.ai/v1/chat/completions"
+
+// Mistral implements the Analyzer interface for the Mistral AI API
+type Mistral struct {
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewMistral creates a new Mistral analyzer
+func NewMistral(apiKey string, model string) *Mistral {
+	if model == "" {
+		model = "mistral-large-latest"
+	}
+	return &Mistral{
+		apiKey: apiKey,
+		model:  model,
+		client: sharedHTTPClient,
+	}
+}
+
+// Name returns the provider name
+func (m *Mistral) Name() string {
+	return "mistral"
+}
+
+// Analyze performs stock analysis using Mistral, asking for JSON output so the
+// reply parses without stripping prose around it
+func (m *Mistral) Analyze(ctx context.Context, req models.AnalysisRequest) (*models.AnalysisResponse, error) {
+	if m.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	content, usage, err := chatCompletion(ctx, m.client, m.chat(req))
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, m.Name(), m.model, usage)
+}
+
+// AnalyzeStream performs stock analysis using Mistral, passing the reply to
+// onText as it is generated
+func (m *Mistral) AnalyzeStream(ctx context.Context, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+	if m.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	content, usage, err := chatCompletionStream(ctx, m.client, m.chat(req), onText)
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, m.Name(), m.model, usage)
+}
+
+// chat builds the chat completions call for req
+func (m *Mistral) chat(req models.AnalysisRequest) chatRequest {
+	return chatRequest{
+		endpoint:   mistralBaseURL,
+		headers:    bearer(m.apiKey),
+		model:      m.model,
+		prompt:     BuildPrompt(req),
+		generation: generation(req),
+		jsonMode:   true,
+	}
+}
@@ -44,15 +44,279 @@ func (o *OpenAI) Analyze(ctx context.Context, req models.AnalysisRequest) (*mode
 		return nil, ErrNoAPIKey
 	}
 
-	prompt := BuildPrompt(req)
+	content, usage, err := chatCompletion(ctx, o.client, o.chat(req))
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, o.Name(), o.model, usage)
+}
+
+// AnalyzeStream performs stock analysis using OpenAI, passing the reply to
+// onText as it is generated
+func (o *OpenAI) AnalyzeStream(ctx context.Context, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+	if o.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	content, usage, err := chatCompletionStream(ctx, o.client, o.chat(req), onText)
+	if err != nil {
+		return nil, err
+	}
+	return finish(req.Symbol, content, o.Name(), o.model, usage)
+}
+
+// AnalyzeWithTools performs stock analysis using OpenAI, letting the model
+// call tools for more market data
+func (o *OpenAI) AnalyzeWithTools(ctx context.Context, req models.AnalysisRequest, tools Tools, onText func(string)) (*models.AnalysisResponse, error) {
+	if o.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	content, usage, err := chatCompletionTools(ctx, o.client, o.chat(req), tools)
+	if err != nil {
+		return nil, err
+	}
+	if onText != nil {
+		onText(content)
+	}
+	return finish(req.Symbol, content, o.Name(), o.model, usage)
+}
+
+// chat builds the chat completions call for req
+func (o *OpenAI) chat(req models.AnalysisRequest) chatRequest {
+	return chatRequest{
+		endpoint:    openAIBaseURL,
+		headers:     bearer(o.apiKey),
+		model:       o.model,
+		prompt:      BuildPrompt(req),
+		generation:  generation(req),
+		reasoning:   true,
+		jsonSchema:  true,
+		streamUsage: true,
+	}
+}
+
+// chatRequest is a call to an OpenAI-compatible chat completions endpoint
+type chatRequest struct {
+	endpoint    string
+	headers     map[string]string // authentication
+	model       string
+	prompt      string
+	generation  models.GenerationSettings
+	reasoning   bool // the endpoint accepts reasoning_effort
+	jsonMode    bool // ask for the reply as a JSON object
+	jsonSchema  bool // ask for a reply matching analysisSchema, overriding jsonMode
+	streamUsage bool // ask for token counts at the end of a stream via stream_options
+
+	// Set by chatCompletionTools: the conversation so far, replacing prompt,
+	// and the tools offered to the model
+	messages   []interface{}
+	tools      []interface{}
+	toolChoice string
+}
 
+// chatMessage is the message of a chat completions choice
+type chatMessage struct {
+	Content   string         `json:"content"`
+	ToolCalls []chatToolCall `json:"tool_calls"`
+}
+
+// chatToolCall is a tool call requested by a chat completions model
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// chatUsage is the token usage reported by chat completions endpoints
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// chatCompletion sends a chat completions request and returns the content of
+// the first choice and the tokens used
+func chatCompletion(ctx context.Context, client *http.Client, req chatRequest) (string, tokens, error) {
+	message, used, err := chatCompletionMessage(ctx, client, req)
+	if err != nil {
+		return "", tokens{}, err
+	}
+	return message.Content, used, nil
+}
+
+// chatCompletionMessage sends a chat completions request and returns the
+// message of the first choice and the tokens used
+func chatCompletionMessage(ctx context.Context, client *http.Client, req chatRequest) (*chatMessage, tokens, error) {
+	resp, err := postChat(ctx, client, req, false)
+	if err != nil {
+		return nil, tokens{}, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+		Usage chatUsage `json:"usage"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, tokens{}, err
+	}
+
+	if len(result.Choices) == 0 {
+		return nil, tokens{}, ErrAnalysisFailed
+	}
+
+	return &result.Choices[0].Message, tokens{result.Usage.PromptTokens, result.Usage.CompletionTokens}, nil
+}
+
+// chatCompletionTools runs a chat completion in which the model may call
+// tools, sending back their results until it answers, and returns the answer
+// and the tokens used over all rounds
+func chatCompletionTools(ctx context.Context, client *http.Client, req chatRequest, tools Tools) (string, tokens, error) {
+	req.messages = []interface{}{
+		map[string]string{"role": "user", "content": req.prompt},
+	}
+	for _, spec := range toolSpecs {
+		req.tools = append(req.tools, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        spec.name,
+				"description": spec.description,
+				"parameters":  spec.parameters,
+			},
+		})
+	}
+
+	var used tokens
+	for round := 1; round <= maxToolRounds; round++ {
+		if round == maxToolRounds {
+			req.toolChoice = "none"
+		}
+		message, usage, err := chatCompletionMessage(ctx, client, req)
+		if err != nil {
+			return "", tokens{}, err
+		}
+		used.prompt += usage.prompt
+		used.completion += usage.completion
+
+		if len(message.ToolCalls) == 0 {
+			return message.Content, used, nil
+		}
+		req.messages = append(req.messages, map[string]interface{}{
+			"role":       "assistant",
+			"content":    message.Content,
+			"tool_calls": message.ToolCalls,
+		})
+		for _, call := range message.ToolCalls {
+			req.messages = append(req.messages, map[string]string{
+				"role":         "tool",
+				"tool_call_id": call.ID,
+				"content":      callTool(ctx, tools, call.Function.Name, []byte(call.Function.Arguments)),
+			})
+		}
+	}
+	return "", tokens{}, ErrAnalysisFailed
+}
+
+// chatCompletionStream is chatCompletion with the reply streamed, passing
+// each piece of content to onText as it arrives. Token counts are read from
+// the chunk carrying them, under x_groq for Groq.
+func chatCompletionStream(ctx context.Context, client *http.Client, req chatRequest, onText func(string)) (string, tokens, error) {
+	resp, err := postChat(ctx, client, req, true)
+	if err != nil {
+		return "", tokens{}, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var used tokens
+	err = readEvents(resp.Body, func(data []byte) error {
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *chatUsage `json:"usage"`
+			XGroq struct {
+				Usage *chatUsage `json:"usage"`
+			} `json:"x_groq"`
+		}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil
+		}
+		for _, choice := range chunk.Choices {
+			if text := choice.Delta.Content; text != "" {
+				content.WriteString(text)
+				onText(text)
+			}
+		}
+		for _, usage := range []*chatUsage{chunk.Usage, chunk.XGroq.Usage} {
+			if usage != nil {
+				used = tokens{usage.PromptTokens, usage.CompletionTokens}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", tokens{}, fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
+	}
+	if content.Len() == 0 {
+		return "", tokens{}, ErrAnalysisFailed
+	}
+	return content.String(), used, nil
+}
+
+// postChat sends a chat completions request and returns the response when
+// its status is 200; the caller closes the body
+func postChat(ctx context.Context, client *http.Client, req chatRequest, stream bool) (*http.Response, error) {
 	requestBody := map[string]interface{}{
-		"model": o.model,
+		"model": req.model,
 		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
+			{"role": "user", "content": req.prompt},
 		},
-		"temperature": 0.3,
-		"max_tokens":  1000,
+		"temperature": req.generation.Temperature,
+		"max_tokens":  req.generation.MaxTokens,
+	}
+	// Reasoning models reject temperature and count their reasoning in
+	// max_completion_tokens
+	if effort := req.generation.ReasoningEffort; effort != "" && req.reasoning {
+		delete(requestBody, "temperature")
+		delete(requestBody, "max_tokens")
+		requestBody["reasoning_effort"] = effort
+		requestBody["max_completion_tokens"] = req.generation.MaxTokens + reasoningBudgets[effort]
+	}
+	if req.messages != nil {
+		requestBody["messages"] = req.messages
+	}
+	if len(req.tools) > 0 {
+		requestBody["tools"] = req.tools
+		if req.toolChoice != "" {
+			requestBody["tool_choice"] = req.toolChoice
+		}
+	}
+	if req.jsonSchema {
+		requestBody["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "stock_analysis",
+				"strict": true,
+				"schema": analysisSchema(true),
+			},
+		}
+	} else if req.jsonMode {
+		requestBody["response_format"] = map[string]string{"type": "json_object"}
+	}
+	if stream {
+		requestBody["stream"] = true
+		if req.streamUsage {
+			requestBody["stream_options"] = map[string]bool{"include_usage": true}
+		}
 	}
 
 	jsonBody, err := json.Marshal(requestBody)
@@ -60,47 +324,37 @@ func (o *OpenAI) Analyze(ctx context.Context, req models.AnalysisRequest) (*mode
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openAIBaseURL, bytes.NewBuffer(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", req.endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	for k, v := range req.headers {
+		httpReq.Header.Set(k, v)
+	}
 
-	resp, err := o.client.Do(httpReq)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
 		var errResp struct {
 			Error struct {
 				Message string `json:"message"`
 			} `json:"error"`
 		}
 		json.NewDecoder(resp.Body).Decode(&errResp)
-		return nil, fmt.Errorf("%w: %s", ErrAnalysisFailed, errResp.Error.Message)
-	}
-
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	if len(result.Choices) == 0 {
-		return nil, ErrAnalysisFailed
+		return nil, statusError(resp.StatusCode, errResp.Error.Message)
 	}
+	return resp, nil
+}
 
-	return parseAnalysisResponse(req.Symbol, result.Choices[0].Message.Content)
+// bearer returns the Authorization header of APIs taking a bearer token
+func bearer(apiKey string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + apiKey}
 }
 
 // parseAnalysisResponse parses the AI response into an AnalysisResponse
@@ -119,6 +373,14 @@ func parseAnalysisResponse(symbol string, content string) (*models.AnalysisRespo
 		content = strings.TrimSpace(content)
 	}
 
+	// Answers wrapped in prose keep the outermost JSON object
+	if !strings.HasPrefix(content, "{") {
+		start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+		if start >= 0 && end > start {
+			content = content[start : end+1]
+		}
+	}
+
 	var response struct {
 		Action       string              `json:"action"`
 		Confidence   float64             `json:"confidence"`
@@ -126,20 +388,34 @@ func parseAnalysisResponse(symbol string, content string) (*models.AnalysisRespo
 		PriceTargets models.PriceTargets `json:"price_targets"`
 		Risks        []string            `json:"risks"`
 		Timeframe    string              `json:"timeframe"`
+		Highlights   []string            `json:"highlights"`
 	}
 
 	if err := json.Unmarshal([]byte(content), &response); err != nil {
 		return nil, fmt.Errorf("%w: failed to parse response: %v", ErrAnalysisFailed, err)
 	}
 
-	return &models.AnalysisResponse{
+	// Keep at most three non-empty highlights
+	highlights := []string{}
+	for _, h := range response.Highlights {
+		if h = strings.TrimSpace(h); h != "" && len(highlights) < 3 {
+			highlights = append(highlights, h)
+		}
+	}
+
+	analysis := &models.AnalysisResponse{
 		Symbol:       symbol,
-		Action:       response.Action,
+		Action:       strings.ToUpper(strings.TrimSpace(response.Action)),
 		Confidence:   response.Confidence,
 		Reasoning:    response.Reasoning,
+		Highlights:   highlights,
 		PriceTargets: response.PriceTargets,
 		Risks:        response.Risks,
 		Timeframe:    response.Timeframe,
 		GeneratedAt:  time.Now(),
-	}, nil
+	}
+	if err := validateAnalysis(analysis); err != nil {
+		return nil, err
+	}
+	return analysis, nil
 }
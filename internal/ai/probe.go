@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ProbeResult is what a successful provider probe learned
+type ProbeResult struct {
+	RateLimitRemaining *int // requests left in the current window, nil when not reported
+}
+
+// Prober is implemented by analyzers that can check their API key and model
+// without running an analysis
+type Prober interface {
+	Probe(ctx context.Context) (*ProbeResult, error)
+}
+
+// Probe checks that analyzer's API key is accepted and its model exists.
+// Analyzers without a probe are assumed to be reachable.
+func Probe(ctx context.Context, analyzer Analyzer) (*ProbeResult, error) {
+	prober, ok := analyzer.(Prober)
+	if !ok {
+		return &ProbeResult{}, nil
+	}
+	return prober.Probe(ctx)
+}
+
+// Probe fetches the configured model, which needs a valid key but no tokens
+func (o *OpenAI) Probe(ctx context.Context) (*ProbeResult, error) {
+	if o.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return probeModel(ctx, o.client, "https://api.openai.com/v1/models/"+url.PathEscape(o.model), o.model,
+		bearer(o.apiKey), "x-ratelimit-remaining-requests")
+}
+
+// Probe fetches the configured model, which needs a valid key but no tokens
+func (c *Claude) Probe(ctx context.Context) (*ProbeResult, error) {
+	if c.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return probeModel(ctx, c.client, "https://api.anthropic.com/v1/models/"+url.PathEscape(c.model), c.model,
+		map[string]string{"x-api-key": c.apiKey, "anthropic-version": "2023-06-01"}, "anthropic-ratelimit-requests-remaining")
+}
+
+// Probe fetches the configured model, which needs a valid key but no tokens
+func (g *Gemini) Probe(ctx context.Context) (*ProbeResult, error) {
+	if g.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return probeModel(ctx, g.client, geminiBaseURL+"/"+url.PathEscape(g.model), g.model,
+		map[string]string{"x-goog-api-key": g.apiKey}, "")
+}
+
+// Probe fetches the configured model, which needs a valid key but no tokens
+func (g *Groq) Probe(ctx context.Context) (*ProbeResult, error) {
+	if g.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return probeModel(ctx, g.client, "https://api.groq.com/openai/v1/models/"+url.PathEscape(g.model), g.model,
+		bearer(g.apiKey), "x-ratelimit-remaining-requests")
+}
+
+// Probe fetches the configured model, which needs a valid key but no tokens
+func (m *Mistral) Probe(ctx context.Context) (*ProbeResult, error) {
+	if m.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return probeModel(ctx, m.client, "https://api.mistral.ai/v1/models/"+url.PathEscape(m.model), m.model,
+		bearer(m.apiKey), "")
+}
+
+// Probe lists the available models, which checks the key; DeepSeek has no
+// endpoint describing a single model, so the model itself is not checked
+func (d *DeepSeek) Probe(ctx context.Context) (*ProbeResult, error) {
+	if d.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	return probeModel(ctx, d.client, "https://api.deepseek.com/models", d.model, bearer(d.apiKey), "")
+}
+
+// probeModel requests a model description and classifies the response; the
+// remaining request count is read from remainingHeader when it is set
+func probeModel(ctx context.Context, client *http.Client, endpoint, model string, headers map[string]string, remainingHeader string) (*ProbeResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, ErrRateLimited
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, ErrInvalidAPIKey
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("unknown model: %s", model)
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: status %d", ErrProviderUnavailable, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	result := &ProbeResult{}
+	if remainingHeader != "" {
+		if n, err := strconv.Atoi(resp.Header.Get(remainingHeader)); err == nil {
+			result.RateLimitRemaining = &n
+		}
+	}
+	return result, nil
+}
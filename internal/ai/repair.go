@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"stockmarket/internal/models"
+)
+
+// maxRepairAttempts caps how often a model is asked to fix a reply that
+// could not be parsed
+const maxRepairAttempts = 2
+
+// maxRepairReplyLength caps how much of the malformed reply is quoted back
+const maxRepairReplyLength = 2000
+
+// malformedReply is returned when a reply cannot be parsed into a valid
+// analysis; it keeps the reply and the tokens spent on it for a repair
+type malformedReply struct {
+	reply    string
+	provider string
+	model    string
+	used     tokens
+	err      error
+}
+
+func (e *malformedReply) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap lets errors.Is(err, ErrAnalysisFailed) match
+func (e *malformedReply) Unwrap() error {
+	return e.err
+}
+
+// analyzeWithRepair runs analyze and, while the reply is malformed, runs it
+// again with the parse error and the reply added to the prompt. Repair
+// attempts do not stream. The tokens of every attempt are counted in the
+// usage of the result.
+func analyzeWithRepair(ctx context.Context, req models.AnalysisRequest, onText func(string),
+	analyze func(req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error)) (*models.AnalysisResponse, error) {
+	var spent tokens
+	for attempt := 0; ; attempt++ {
+		result, err := analyze(req, onText)
+		var malformed *malformedReply
+		if !errors.As(err, &malformed) {
+			if err == nil && attempt > 0 && result.Usage != nil {
+				used := spent
+				used.prompt += result.Usage.PromptTokens
+				used.completion += result.Usage.CompletionTokens
+				result.Usage = NewTokenUsage(result.Usage.Provider, result.Usage.Model, used.prompt, used.completion)
+			}
+			return result, err
+		}
+		spent.prompt += malformed.used.prompt
+		spent.completion += malformed.used.completion
+		if attempt == maxRepairAttempts {
+			return nil, fmt.Errorf("%w (after %d repair attempts)", err, attempt)
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		log.Printf("[AI] Malformed reply from %s for %s (%v), asking for a repair", malformed.provider, req.Symbol, malformed.err)
+		req.PreviousReply = malformed.reply
+		if len(req.PreviousReply) > maxRepairReplyLength {
+			req.PreviousReply = req.PreviousReply[:maxRepairReplyLength]
+		}
+		req.ReplyError = malformed.err.Error()
+		onText = nil
+	}
+}
+
+// FormatRepair asks the model to fix its previous reply; it returns "" when
+// the request is not a repair
+func FormatRepair(previousReply, replyError string) string {
+	if replyError == "" {
+		return ""
+	}
+	schema, _ := json.Marshal(analysisSchema(true))
+	return fmt.Sprintf("\n\nYour previous reply could not be used (%s):\n%s\n\nReturn only valid JSON matching this schema, with no other text:\n%s",
+		replyError, previousReply, schema)
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+
+	"stockmarket/internal/models"
+)
+
+// analysisActions are the recommendations an analysis can make; ADD and TRIM
+// are relative to a position
+var analysisActions = []string{"BUY", "SELL", "HOLD", "WATCH", "ADD", "TRIM"}
+
+// analysisSchema returns the JSON schema of the reply BuildPrompt asks for.
+// closed forbids properties the schema does not list, which OpenAI's strict
+// mode requires and Gemini does not accept.
+func analysisSchema(closed bool) map[string]interface{} {
+	number := map[string]interface{}{"type": "number"}
+	list := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	priceTargets := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"entry":     number,
+			"target":    number,
+			"stop_loss": number,
+		},
+		"required": []string{"entry", "target", "stop_loss"},
+	}
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action":        map[string]interface{}{"type": "string", "enum": analysisActions},
+			"confidence":    map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+			"reasoning":     map[string]interface{}{"type": "string"},
+			"highlights":    list,
+			"price_targets": priceTargets,
+			"risks":         list,
+			"timeframe":     map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"action", "confidence", "reasoning", "highlights", "price_targets", "risks", "timeframe"},
+	}
+	if closed {
+		priceTargets["additionalProperties"] = false
+		schema["additionalProperties"] = false
+	}
+	return schema
+}
+
+// validateAnalysis checks a parsed reply against the constraints of the
+// schema, which providers without structured output do not enforce
+func validateAnalysis(a *models.AnalysisResponse) error {
+	if !slices.Contains(analysisActions, a.Action) {
+		return fmt.Errorf("%w: invalid action %q", ErrAnalysisFailed, a.Action)
+	}
+	if a.Confidence < 0 || a.Confidence > 1 {
+		return fmt.Errorf("%w: confidence %v is outside 0-1", ErrAnalysisFailed, a.Confidence)
+	}
+	t := a.PriceTargets
+	if t.Entry < 0 || t.Target < 0 || t.StopLoss < 0 {
+		return fmt.Errorf("%w: negative price target", ErrAnalysisFailed)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// StreamAnalyzer is implemented by analyzers that can pass on the model's
+// reply as it is generated
+type StreamAnalyzer interface {
+	AnalyzeStream(ctx context.Context, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error)
+}
+
+// AnalyzeStream runs an analysis, passing the reply to onText as it is
+// generated. Analyzers without streaming run to completion without calling
+// onText.
+func AnalyzeStream(ctx context.Context, analyzer Analyzer, req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+	if streamer, ok := analyzer.(StreamAnalyzer); ok && onText != nil {
+		return streamer.AnalyzeStream(ctx, req, onText)
+	}
+	return analyzer.Analyze(ctx, req)
+}
+
+// readEvents calls fn with the data of each server-sent event read from body,
+// skipping the [DONE] marker some providers end the stream with
+func readEvents(body io.Reader, fn func(data []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		if err := fn([]byte(data)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
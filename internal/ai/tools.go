@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// maxToolRounds caps the tool-call round trips of an analysis; the last
+// round forbids tool calls so the model has to answer
+const maxToolRounds = 4
+
+// Tools serves the market data a model may request during an analysis
+type Tools interface {
+	GetQuote(ctx context.Context, symbol string) (*models.Quote, error)
+	GetHistorical(ctx context.Context, symbol, period string) ([]models.Candle, error)
+	GetIndicators(ctx context.Context, symbol string) (models.Indicators, error)
+}
+
+// ToolAnalyzer is implemented by analyzers whose model can call tools; the
+// final reply is passed to onText, when set, once the model has answered
+type ToolAnalyzer interface {
+	AnalyzeWithTools(ctx context.Context, req models.AnalysisRequest, tools Tools, onText func(string)) (*models.AnalysisResponse, error)
+}
+
+// AnalyzeWithTools runs an analysis in which the model may call tools, for
+// analyzers that support them. Tool-calling runs do not stream: onText gets
+// the whole reply once the model has answered. Other analyzers and a nil
+// tools run as AnalyzeStream. Malformed replies are sent back to the model
+// for repair, up to maxRepairAttempts times.
+func AnalyzeWithTools(ctx context.Context, analyzer Analyzer, req models.AnalysisRequest, tools Tools, onText func(string)) (*models.AnalysisResponse, error) {
+	return analyzeWithRepair(ctx, req, onText, func(req models.AnalysisRequest, onText func(string)) (*models.AnalysisResponse, error) {
+		if caller, ok := analyzer.(ToolAnalyzer); ok && tools != nil {
+			return caller.AnalyzeWithTools(ctx, req, tools, onText)
+		}
+		return AnalyzeStream(ctx, analyzer, req, onText)
+	})
+}
+
+// toolSpec describes a tool to the model; parameters is a JSON schema
+type toolSpec struct {
+	name        string
+	description string
+	parameters  map[string]interface{}
+}
+
+// symbolParam is the schema of the symbol argument every tool takes
+var symbolParam = map[string]interface{}{
+	"type":        "string",
+	"description": "Ticker symbol, e.g. AAPL or ^GSPC",
+}
+
+// toolSpecs are the tools offered to the model
+var toolSpecs = []toolSpec{
+	{
+		name:        "get_quote",
+		description: "Get the latest quote of a symbol.",
+		parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"symbol": symbolParam},
+			"required":   []string{"symbol"},
+		},
+	},
+	{
+		name:        "get_historical",
+		description: "Get a summary of the price history of a symbol over a period, e.g. a 1-year view when the default window is ambiguous.",
+		parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"symbol": symbolParam,
+				"period": map[string]interface{}{
+					"type": "string",
+					"enum": []string{"5d", "1m", "3m", "1y", "5y", "ytd"},
+				},
+			},
+			"required": []string{"symbol", "period"},
+		},
+	},
+	{
+		name:        "get_indicators",
+		description: "Get the RSI, SMA and ATR of a symbol computed on daily candles.",
+		parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"symbol": symbolParam},
+			"required":   []string{"symbol"},
+		},
+	},
+}
+
+// callTool runs a tool call and returns the text given back to the model;
+// failures are reported to the model rather than ending the analysis
+func callTool(ctx context.Context, tools Tools, name string, arguments []byte) string {
+	var args struct {
+		Symbol string `json:"symbol"`
+		Period string `json:"period"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil || strings.TrimSpace(args.Symbol) == "" {
+		return "Error: a symbol is required"
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(args.Symbol))
+
+	switch name {
+	case "get_quote":
+		q, err := tools.GetQuote(ctx, symbol)
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+		return fmt.Sprintf("%s: %s (%+.2f%%), open %s, high %s, low %s, previous close %s, volume %d, as of %s",
+			q.Symbol, formatPrice(q.Price, q.Currency), q.ChangePercent, formatPrice(q.Open, q.Currency),
+			formatPrice(q.High, q.Currency), formatPrice(q.Low, q.Currency),
+			formatPrice(q.PreviousClose, q.Currency), q.Volume, q.Timestamp.Format("2006-01-02 15:04 MST"))
+	case "get_historical":
+		candles, err := tools.GetHistorical(ctx, symbol, args.Period)
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+		return fmt.Sprintf("%s over %s (%d periods):\n", symbol, args.Period, len(candles)) + FormatHistoricalSummary(candles)
+	case "get_indicators":
+		ind, err := tools.GetIndicators(ctx, symbol)
+		if err != nil {
+			return "Error: " + err.Error()
+		}
+		if summary := FormatIndicators(ind); summary != "" {
+			return symbol + " " + strings.TrimPrefix(summary, "\n")
+		}
+		return "Not enough history to compute indicators for " + symbol
+	default:
+		return "Error: unknown tool " + name
+	}
+}
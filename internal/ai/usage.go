@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// modelPrice is the list price of a model in US dollars per million tokens
+type modelPrice struct {
+	input, output float64
+}
+
+// modelPrices holds list prices by model name prefix, the longest matching
+// prefix winning; estimates only, update them when providers change prices
+var modelPrices = map[string]modelPrice{
+	"gpt-4o":                  {2.50, 10.00},
+	"gpt-4o-mini":             {0.15, 0.60},
+	"gpt-4.1":                 {2.00, 8.00},
+	"gpt-4.1-mini":            {0.40, 1.60},
+	"gpt-4.1-nano":            {0.10, 0.40},
+	"gpt-4-turbo":             {10.00, 30.00},
+	"o3-mini":                 {1.10, 4.40},
+	"o4-mini":                 {1.10, 4.40},
+	"claude-opus-4":           {15.00, 75.00},
+	"claude-sonnet-4":         {3.00, 15.00},
+	"claude-3-opus":           {15.00, 75.00},
+	"claude-3-5-sonnet":       {3.00, 15.00},
+	"claude-3-7-sonnet":       {3.00, 15.00},
+	"claude-3-5-haiku":        {0.80, 4.00},
+	"claude-3-haiku":          {0.25, 1.25},
+	"gemini-pro":              {0.50, 1.50},
+	"gemini-1.5-pro":          {1.25, 5.00},
+	"gemini-1.5-flash":        {0.075, 0.30},
+	"gemini-2.0-flash":        {0.10, 0.40},
+	"gemini-2.5-pro":          {1.25, 10.00},
+	"gemini-2.5-flash":        {0.30, 2.50},
+	"llama-3.3-70b-versatile": {0.59, 0.79},
+	"llama-3.1-8b-instant":    {0.05, 0.08},
+	"mistral-large":           {2.00, 6.00},
+	"mistral-small":           {0.20, 0.60},
+	"deepseek-chat":           {0.27, 1.10},
+	"deepseek-reasoner":       {0.55, 2.19},
+}
+
+// tokens are the token counts a provider reported for one call
+type tokens struct {
+	prompt, completion int
+}
+
+// NewTokenUsage records the tokens of one call with its cost estimated from
+// list prices; the cost is nil for models without a known price
+func NewTokenUsage(provider, model string, promptTokens, completionTokens int) *models.TokenUsage {
+	usage := &models.TokenUsage{
+		Provider:         provider,
+		Model:            model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}
+	if price, ok := priceOf(model); ok {
+		cost := (float64(promptTokens)*price.input + float64(completionTokens)*price.output) / 1e6
+		usage.CostUSD = &cost
+	}
+	return usage
+}
+
+// priceOf looks up the list price of model by its longest known prefix
+func priceOf(model string) (modelPrice, bool) {
+	model = strings.ToLower(model)
+	best, found := "", false
+	for prefix := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, found = prefix, true
+		}
+	}
+	return modelPrices[best], found
+}
+
+// finish parses a model's reply into an analysis carrying the token usage
+// of the call
+func finish(symbol, content, provider, model string, t tokens) (*models.AnalysisResponse, error) {
+	result, err := parseAnalysisResponse(symbol, content)
+	if err != nil {
+		return nil, &malformedReply{reply: content, provider: provider, model: model, used: t, err: err}
+	}
+	result.Usage = NewTokenUsage(provider, model, t.prompt, t.completion)
+	return result, nil
+}
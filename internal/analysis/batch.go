@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"context"
+	"sync"
+
+	"stockmarket/internal/models"
+)
+
+// batchWorkers bounds how many analyses of a batch run at once, keeping
+// within the rate limits of the market and AI providers
+const batchWorkers = 3
+
+// BatchResult is the outcome of analyzing one symbol of a batch
+type BatchResult struct {
+	Symbol   string
+	Analysis *models.AnalysisResponse
+	Quote    *models.Quote
+	Err      error
+}
+
+// RunBatch analyzes symbols, batchWorkers at a time, and returns the results
+// in the order of symbols. onResult, when set, is called with each result as
+// it completes, one call at a time. Batch runs do not stream.
+func (s *Service) RunBatch(ctx context.Context, symbols []string, opts Options, onResult func(BatchResult)) []BatchResult {
+	opts.OnText = nil
+	results := make([]BatchResult, len(symbols))
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range min(batchWorkers, len(symbols)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := BatchResult{Symbol: symbols[i]}
+				result.Analysis, result.Quote, result.Err = s.RunWithOptions(ctx, symbols[i], "", opts)
+				results[i] = result
+				if onResult != nil {
+					mu.Lock()
+					onResult(result)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < len(symbols); next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Symbols never started fail with the context's error
+	for i := next; i < len(symbols); i++ {
+		results[i] = BatchResult{Symbol: symbols[i], Err: ctx.Err()}
+	}
+	return results
+}
@@ -0,0 +1,47 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// errShortHistory is returned when a window has too few candles for a return
+var errShortHistory = errors.New("not enough history for a return")
+
+// compareBenchmark measures the return of the analyzed symbol over its
+// history window against the benchmark's return over the same period
+func compareBenchmark(ctx context.Context, provider market.Provider, benchmark, period string, historical []models.Candle) (*models.Benchmark, error) {
+	symbolReturn, ok := periodReturn(historical)
+	if !ok {
+		return nil, errShortHistory
+	}
+	candles, _, err := history(ctx, provider, benchmark, period)
+	if err != nil {
+		return nil, err
+	}
+	benchmarkReturn, ok := periodReturn(candles)
+	if !ok {
+		return nil, errShortHistory
+	}
+	return &models.Benchmark{
+		Symbol:       benchmark,
+		Return:       benchmarkReturn,
+		SymbolReturn: symbolReturn,
+	}, nil
+}
+
+// periodReturn is the percent change from the first close of candles to the last
+func periodReturn(candles []models.Candle) (float64, bool) {
+	if len(candles) < 2 {
+		return 0, false
+	}
+	candles = sortedCandles(candles)
+	first, last := candles[0].Close, candles[len(candles)-1].Close
+	if first <= 0 {
+		return 0, false
+	}
+	return (last - first) / first * 100, true
+}
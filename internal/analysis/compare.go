@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// CompareAnalyses compares two analyses of the same symbol, ordering them by
+// when they were generated. Risks are matched ignoring case and spacing.
+func CompareAnalyses(a, b models.AnalysisResponse) models.AnalysisComparison {
+	if b.GeneratedAt.Before(a.GeneratedAt) {
+		a, b = b, a
+	}
+	return models.AnalysisComparison{
+		Symbol:           a.Symbol,
+		Before:           a,
+		After:            b,
+		ActionChanged:    a.Action != b.Action,
+		ConfidenceChange: b.Confidence - a.Confidence,
+		TargetChanges: models.PriceTargets{
+			Entry:    b.PriceTargets.Entry - a.PriceTargets.Entry,
+			Target:   b.PriceTargets.Target - a.PriceTargets.Target,
+			StopLoss: b.PriceTargets.StopLoss - a.PriceTargets.StopLoss,
+		},
+		RisksAdded:   missingRisks(b.Risks, a.Risks),
+		RisksRemoved: missingRisks(a.Risks, b.Risks),
+	}
+}
+
+// missingRisks returns the risks of risks that others does not list
+func missingRisks(risks, others []string) []string {
+	known := make(map[string]bool, len(others))
+	for _, r := range others {
+		known[riskKey(r)] = true
+	}
+	missing := []string{}
+	for _, r := range risks {
+		if !known[riskKey(r)] {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// riskKey normalizes a risk for matching
+func riskKey(risk string) string {
+	return strings.ToLower(strings.Join(strings.Fields(risk), " "))
+}
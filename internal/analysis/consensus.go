@@ -0,0 +1,211 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"stockmarket/internal/ai"
+	"stockmarket/internal/models"
+)
+
+// consensusMaxModels caps how many analyzers a consensus run asks
+const consensusMaxModels = 3
+
+// ErrConsensusUnavailable is returned when fewer than two distinct AI
+// providers are configured for a consensus run
+var ErrConsensusUnavailable = errors.New("Consensus needs at least two configured AI providers")
+
+// panelist is one analyzer asked in a consensus run
+type panelist struct {
+	provider     string
+	model        string
+	encryptedKey string
+}
+
+// consensusPanel picks the analyzers of a consensus run: the primary and
+// fallback providers, then the AI overrides of the analysis presets, skipping
+// duplicates, up to consensusMaxModels
+func (s *Service) consensusPanel(cfg *models.UserConfig, params Params) ([]panelist, error) {
+	candidates := []panelist{
+		{params.AIProvider, params.AIModel, params.AIAPIKey},
+	}
+	if params.FallbackAIProvider != "" {
+		candidates = append(candidates, panelist{params.FallbackAIProvider, params.FallbackAIModel, params.FallbackAIAPIKey})
+	}
+	presets, err := s.store.GetAnalysisPresets(cfg.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range presets {
+		if p.AIProvider == "" {
+			continue
+		}
+		key := p.AIProviderAPIKey
+		if key == "" {
+			key = cfg.AIProviderAPIKey
+		}
+		candidates = append(candidates, panelist{p.AIProvider, p.AIModel, key})
+	}
+
+	var panel []panelist
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		id := c.provider + "/" + c.model
+		if c.provider == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		panel = append(panel, c)
+		if len(panel) == consensusMaxModels {
+			break
+		}
+	}
+	if len(panel) < 2 {
+		return nil, ErrConsensusUnavailable
+	}
+	return panel, nil
+}
+
+// consensus sends req to every analyzer of the panel concurrently and merges
+// the answers; it fails only when no analyzer answered
+func (s *Service) consensus(ctx context.Context, params Params, panel []panelist, req models.AnalysisRequest, tools ai.Tools) (*models.AnalysisResponse, error) {
+	results := make([]*models.AnalysisResponse, len(panel))
+	errs := make([]error, len(panel))
+	var wg sync.WaitGroup
+	for i, p := range panel {
+		wg.Add(1)
+		go func(i int, p panelist) {
+			defer wg.Done()
+			results[i], errs[i] = s.analyze(ctx, p.provider, p.encryptedKey, p.model, params.AzureEndpoint, tools, nil, req)
+		}(i, p)
+	}
+	wg.Wait()
+
+	votes := make([]models.ConsensusVote, len(panel))
+	var answers []*models.AnalysisResponse
+	for i, p := range panel {
+		votes[i] = models.ConsensusVote{Provider: p.provider, Model: p.model}
+		if errs[i] != nil {
+			votes[i].Error = errs[i].Error()
+			continue
+		}
+		votes[i].Action = strings.ToUpper(strings.TrimSpace(results[i].Action))
+		votes[i].Confidence = results[i].Confidence
+		answers = append(answers, results[i])
+	}
+	if len(answers) == 0 {
+		return nil, fmt.Errorf("no model answered: %w", errors.Join(errs...))
+	}
+
+	result := mergeConsensus(req.Symbol, answers)
+	result.Consensus = votes
+	result.Usage = sumUsage(answers)
+	return result, nil
+}
+
+// sumUsage adds up the token usage of the answers of a consensus run; the
+// cost is nil when the price of any model is unknown
+func sumUsage(answers []*models.AnalysisResponse) *models.TokenUsage {
+	total := &models.TokenUsage{Provider: "consensus"}
+	var cost float64
+	priced := true
+	for _, a := range answers {
+		if a.Usage == nil {
+			priced = false
+			continue
+		}
+		total.PromptTokens += a.Usage.PromptTokens
+		total.CompletionTokens += a.Usage.CompletionTokens
+		if a.Usage.CostUSD == nil {
+			priced = false
+		} else {
+			cost += *a.Usage.CostUSD
+		}
+	}
+	if priced {
+		total.CostUSD = &cost
+	}
+	return total
+}
+
+// mergeConsensus combines the answers of several analyzers: the majority
+// action wins (ties go to the higher total confidence), confidence is averaged
+// over all answers with dissenting ones counting as zero, price targets are
+// averaged over the majority, and risks are combined. Reasoning, highlights
+// and timeframe come from the most confident answer in the majority.
+func mergeConsensus(symbol string, answers []*models.AnalysisResponse) *models.AnalysisResponse {
+	votes := map[string]int{}
+	weight := map[string]float64{}
+	var order []string
+	for _, a := range answers {
+		action := strings.ToUpper(strings.TrimSpace(a.Action))
+		if votes[action] == 0 {
+			order = append(order, action)
+		}
+		votes[action]++
+		weight[action] += a.Confidence
+	}
+	winner := order[0]
+	for _, action := range order[1:] {
+		if votes[action] > votes[winner] || (votes[action] == votes[winner] && weight[action] > weight[winner]) {
+			winner = action
+		}
+	}
+
+	var lead *models.AnalysisResponse
+	var targets models.PriceTargets
+	var entries, targetCount, stops int
+	var risks []string
+	seenRisk := map[string]bool{}
+	for _, a := range answers {
+		for _, r := range a.Risks {
+			if k := strings.ToLower(strings.TrimSpace(r)); k != "" && !seenRisk[k] {
+				seenRisk[k] = true
+				risks = append(risks, r)
+			}
+		}
+		if strings.ToUpper(strings.TrimSpace(a.Action)) != winner {
+			continue
+		}
+		if lead == nil || a.Confidence > lead.Confidence {
+			lead = a
+		}
+		if a.PriceTargets.Entry > 0 {
+			targets.Entry += a.PriceTargets.Entry
+			entries++
+		}
+		if a.PriceTargets.Target > 0 {
+			targets.Target += a.PriceTargets.Target
+			targetCount++
+		}
+		if a.PriceTargets.StopLoss > 0 {
+			targets.StopLoss += a.PriceTargets.StopLoss
+			stops++
+		}
+	}
+	if entries > 0 {
+		targets.Entry /= float64(entries)
+	}
+	if targetCount > 0 {
+		targets.Target /= float64(targetCount)
+	}
+	if stops > 0 {
+		targets.StopLoss /= float64(stops)
+	}
+
+	return &models.AnalysisResponse{
+		Symbol:       symbol,
+		Action:       winner,
+		Confidence:   weight[winner] / float64(len(answers)),
+		Reasoning:    strings.TrimSpace(fmt.Sprintf("%d of %d models recommend %s. %s", votes[winner], len(answers), winner, lead.Reasoning)),
+		Highlights:   lead.Highlights,
+		PriceTargets: targets,
+		Risks:        risks,
+		Timeframe:    lead.Timeframe,
+		GeneratedAt:  time.Now(),
+	}
+}
@@ -0,0 +1,194 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// outcomeWindow is how long after an analysis its target or stop may still
+// be reached; outcomePeriod is the history period that covers it
+const (
+	outcomeWindow = 365 * 24 * time.Hour
+	outcomePeriod = "1y"
+)
+
+// ResolveOutcomes checks the pending directional analyses against the daily
+// candles since they were generated and records those whose target or stop
+// loss has been reached. It returns how many remain pending.
+func (s *Service) ResolveOutcomes(ctx context.Context) (int, error) {
+	pending, err := s.store.GetPendingOutcomes(time.Now().Add(-outcomeWindow))
+	if err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	cfg, err := s.store.GetOrCreateConfig()
+	if err != nil {
+		return 0, err
+	}
+	provider, err := s.newProvider(cfg.MarketDataProvider, s.decrypt(cfg.MarketDataAPIKey))
+	if err != nil {
+		return 0, fmt.Errorf("Market provider error: %w", err)
+	}
+
+	bySymbol := map[string][]models.PendingOutcome{}
+	var symbols []string
+	for _, p := range pending {
+		if _, ok := bySymbol[p.Symbol]; !ok {
+			symbols = append(symbols, p.Symbol)
+		}
+		bySymbol[p.Symbol] = append(bySymbol[p.Symbol], p)
+	}
+
+	remaining := 0
+	for _, symbol := range symbols {
+		// Targets were set on quoted prices, so outcomes use unadjusted candles
+		candles, err := provider.GetHistoricalData(ctx, symbol, outcomePeriod)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			log.Printf("[OUTCOMES] No history for %s: %v", symbol, err)
+			remaining += len(bySymbol[symbol])
+			continue
+		}
+		candles = sortedCandles(candles)
+		for _, p := range bySymbol[symbol] {
+			outcome, at, ok := resolveOutcome(p, candles)
+			if !ok {
+				remaining++
+				continue
+			}
+			if err := s.store.SetAnalysisOutcome(p.ID, outcome, at); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return remaining, nil
+}
+
+// resolveOutcome walks the oldest-first candles after the day an analysis
+// was generated and reports whether its target or its stop loss was reached
+// first; ok is false while neither was. A candle that spans both counts as
+// the stop. Analyses whose targets contradict their action never resolve.
+func resolveOutcome(p models.PendingOutcome, candles []models.Candle) (string, time.Time, bool) {
+	target, stop := p.PriceTargets.Target, p.PriceTargets.StopLoss
+	long := p.Action == "BUY" || p.Action == "ADD"
+	if (long && target <= stop) || (!long && target >= stop) {
+		return "", time.Time{}, false
+	}
+
+	day := p.GeneratedAt.UTC().Format("2006-01-02")
+	for _, c := range candles {
+		if c.Timestamp.UTC().Format("2006-01-02") <= day {
+			continue
+		}
+		if long {
+			if c.Low <= stop {
+				return models.OutcomeMiss, c.Timestamp, true
+			}
+			if c.High >= target {
+				return models.OutcomeHit, c.Timestamp, true
+			}
+			continue
+		}
+		if c.High >= stop {
+			return models.OutcomeMiss, c.Timestamp, true
+		}
+		if c.Low <= target {
+			return models.OutcomeHit, c.Timestamp, true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+// Calibration resolves pending outcomes and reports the realized hit rate of
+// past analyses per confidence bucket, overall and per AI provider
+func (s *Service) Calibration(ctx context.Context) (*models.CalibrationReport, error) {
+	pending, err := s.ResolveOutcomes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	outcomes, err := s.store.GetResolvedOutcomes()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.CalibrationReport{
+		Resolved:  len(outcomes),
+		Pending:   pending,
+		Buckets:   calibrationBuckets(outcomes),
+		Providers: []models.ProviderCalibration{},
+	}
+
+	byProvider := map[string][]models.ResolvedOutcome{}
+	var providers []string
+	for _, o := range outcomes {
+		if _, ok := byProvider[o.Provider]; !ok {
+			providers = append(providers, o.Provider)
+		}
+		byProvider[o.Provider] = append(byProvider[o.Provider], o)
+	}
+	for _, provider := range providers {
+		pc := models.ProviderCalibration{
+			Provider: provider,
+			Resolved: len(byProvider[provider]),
+			Buckets:  calibrationBuckets(byProvider[provider]),
+		}
+		for _, b := range pc.Buckets {
+			pc.Hits += b.Hits
+		}
+		pc.HitRate = ratio(pc.Hits, pc.Resolved)
+		report.Providers = append(report.Providers, pc)
+	}
+	return report, nil
+}
+
+// calibrationBuckets groups outcomes into a bucket below 0.5 and one per
+// tenth of confidence from 0.5 to 1.0
+func calibrationBuckets(outcomes []models.ResolvedOutcome) []models.CalibrationBucket {
+	buckets := make([]models.CalibrationBucket, 6)
+	buckets[0] = models.CalibrationBucket{Range: "<0.5", MinConfidence: 0, MaxConfidence: 0.5}
+	for i := 1; i < len(buckets); i++ {
+		low, high := float64(i+4)/10, float64(i+5)/10
+		buckets[i] = models.CalibrationBucket{
+			Range:         fmt.Sprintf("%.1f-%.1f", low, high),
+			MinConfidence: low,
+			MaxConfidence: high,
+		}
+	}
+
+	sums := make([]float64, len(buckets))
+	for _, o := range outcomes {
+		i := min(max(int(math.Floor(o.Confidence*10+1e-9))-4, 0), len(buckets)-1)
+		buckets[i].Resolved++
+		if o.Hit {
+			buckets[i].Hits++
+		}
+		sums[i] += o.Confidence
+	}
+	for i := range buckets {
+		buckets[i].HitRate = ratio(buckets[i].Hits, buckets[i].Resolved)
+		if buckets[i].Resolved > 0 {
+			mean := sums[i] / float64(buckets[i].Resolved)
+			buckets[i].MeanConfidence = &mean
+		}
+	}
+	return buckets
+}
+
+// ratio returns n / total, or nil when total is zero
+func ratio(n, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	r := float64(n) / float64(total)
+	return &r
+}
@@ -0,0 +1,95 @@
+package analysis
+
+import (
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// DefaultHistoryPeriod is the historical window used when no preset overrides it
+const DefaultHistoryPeriod = "1m"
+
+// Params holds the explicit inputs for a single analysis run, so callers
+// can override the global configuration (e.g. from a preset)
+type Params struct {
+	AIProvider         string
+	AIModel            string
+	AIAPIKey           string // encrypted at rest
+	RiskTolerance      string
+	FallbackAIProvider string // "" disables fallback
+	FallbackAIModel    string
+	FallbackAIAPIKey   string // encrypted at rest
+	AzureEndpoint      string // Azure OpenAI resource URL
+	TradeFrequency     string
+	HistoryPeriod      string
+	UserContext        string
+	Thesis             string // the user's standing investment thesis
+	Preset             string
+	MultiFrame         bool // also summarize a short- and long-term window
+	Generation         models.GenerationSettings
+}
+
+// NewParams builds analysis parameters from the user config, applying
+// the overrides of preset when one is given
+func NewParams(cfg *models.UserConfig, preset *models.AnalysisPreset, userContext string) Params {
+	params := Params{
+		AIProvider:         cfg.AIProvider,
+		AIModel:            cfg.AIModel,
+		AIAPIKey:           cfg.AIProviderAPIKey,
+		FallbackAIProvider: cfg.FallbackAIProvider,
+		FallbackAIModel:    cfg.FallbackAIModel,
+		FallbackAIAPIKey:   cfg.FallbackAIAPIKey,
+		AzureEndpoint:      cfg.AzureOpenAIEndpoint,
+		RiskTolerance:      cfg.RiskTolerance,
+		TradeFrequency:     cfg.TradeFrequency,
+		HistoryPeriod:      DefaultHistoryPeriod,
+		UserContext:        userContext,
+		Thesis:             cfg.InvestmentThesis,
+		Generation: models.GenerationSettings{
+			Temperature:     cfg.AITemperature,
+			MaxTokens:       cfg.AIMaxTokens,
+			ReasoningEffort: cfg.AIReasoningEffort,
+		},
+	}
+	if preset == nil {
+		return params
+	}
+
+	params.Preset = preset.Name
+	if preset.AIProvider != "" && preset.AIProvider != cfg.AIProvider {
+		// Configured model belongs to another provider, fall back to the provider default
+		params.AIProvider = preset.AIProvider
+		params.AIModel = ""
+	}
+	if preset.AIModel != "" {
+		params.AIModel = preset.AIModel
+	}
+	if preset.AIProviderAPIKey != "" {
+		params.AIAPIKey = preset.AIProviderAPIKey
+	}
+	if preset.TradeFrequency != "" {
+		params.TradeFrequency = preset.TradeFrequency
+	}
+	if preset.HistoryPeriod != "" {
+		params.HistoryPeriod = preset.HistoryPeriod
+	}
+	if preset.UserContext != "" {
+		params.UserContext = strings.TrimSpace(preset.UserContext + "\n" + userContext)
+	}
+	return params
+}
+
+// Request builds the AI analysis request for a symbol
+func (p Params) Request(symbol string, quote *models.Quote, historical []models.Candle) models.AnalysisRequest {
+	return models.AnalysisRequest{
+		Symbol:         symbol,
+		CurrentPrice:   quote.Price,
+		Currency:       quote.Currency,
+		HistoricalData: historical,
+		RiskProfile:    p.RiskTolerance,
+		TradeFrequency: p.TradeFrequency,
+		UserContext:    p.UserContext,
+		Thesis:         p.Thesis,
+		Generation:     p.Generation,
+	}
+}
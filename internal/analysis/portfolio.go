@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// positionContext values the position in symbol at price, or returns nil when
+// there is none. The portfolio weight is measured at cost, so the other
+// positions need no quotes.
+func positionContext(positions []models.Position, symbol string, price float64) *models.PositionContext {
+	var held *models.Position
+	totalCost := 0.0
+	for i, p := range positions {
+		totalCost += p.Shares * p.CostBasis
+		if strings.EqualFold(p.Symbol, symbol) {
+			held = &positions[i]
+		}
+	}
+	if held == nil {
+		return nil
+	}
+
+	cost := held.Shares * held.CostBasis
+	pos := &models.PositionContext{
+		Shares:       held.Shares,
+		CostBasis:    held.CostBasis,
+		MarketValue:  held.Shares * price,
+		UnrealizedPL: held.Shares*price - cost,
+	}
+	if cost > 0 {
+		pos.UnrealizedPLPercent = pos.UnrealizedPL / cost * 100
+	}
+	if totalCost > 0 {
+		pos.PortfolioWeight = cost / totalCost * 100
+	}
+	return pos
+}
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// defaultRelativeBenchmark is compared against when no benchmark is configured
+const defaultRelativeBenchmark = "SPY"
+
+// errNoPeerReturns is returned when no peer had enough history for a return
+var errNoPeerReturns = errors.New("no peer returns")
+
+// compareRelative measures the return of the analyzed symbol over its history
+// window against those of its sector peers and the benchmark. Peers whose
+// quote or history cannot be fetched are left out.
+func compareRelative(ctx context.Context, provider market.Provider, symbol, benchmark, period string, historical []models.Candle) (*models.RelativeStrength, error) {
+	symbolReturn, ok := periodReturn(historical)
+	if !ok {
+		return nil, errShortHistory
+	}
+	peers, err := market.GetPeers(ctx, provider, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	returns := make([]*models.PeerReturn, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			quote, err := provider.GetQuote(ctx, peer)
+			if err != nil {
+				return
+			}
+			candles, _, err := history(ctx, provider, peer, period)
+			if err != nil {
+				return
+			}
+			if r, ok := periodReturn(candles); ok {
+				returns[i] = &models.PeerReturn{Symbol: peer, Return: r, ChangePercent: quote.ChangePercent}
+			}
+		}()
+	}
+	wg.Wait()
+
+	rs := &models.RelativeStrength{Period: period, SymbolReturn: symbolReturn}
+	total := 0.0
+	for _, r := range returns {
+		if r != nil {
+			rs.Peers = append(rs.Peers, *r)
+			total += r.Return
+		}
+	}
+	if len(rs.Peers) == 0 {
+		return nil, errNoPeerReturns
+	}
+	rs.PeerAverage = total / float64(len(rs.Peers))
+	rs.Spread = symbolReturn - rs.PeerAverage
+
+	if benchmark == "" {
+		benchmark = defaultRelativeBenchmark
+	}
+	if candles, _, err := history(ctx, provider, benchmark, period); err == nil {
+		if r, ok := periodReturn(candles); ok {
+			rs.Benchmark = benchmark
+			rs.BenchmarkReturn = r
+		}
+	}
+	return rs, nil
+}
@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"fmt"
+	"time"
+
+	"stockmarket/internal/market"
+	"stockmarket/internal/scheduler"
+)
+
+// Cadences of the scheduled watchlist analysis
+const (
+	ScheduleOff    = "off"
+	ScheduleDaily  = "daily"  // every NYSE trading day
+	ScheduleWeekly = "weekly" // every Monday
+)
+
+// ScheduleJobName is the scheduler state key of the scheduled watchlist analysis
+const ScheduleJobName = "scheduled-analysis"
+
+// DefaultScheduleTime is the default time of the scheduled analysis, in New
+// York time: an hour before the opening bell
+const DefaultScheduleTime = "08:30"
+
+// ValidSchedule reports whether cadence is a known schedule cadence
+func ValidSchedule(cadence string) bool {
+	return cadence == ScheduleOff || cadence == ScheduleDaily || cadence == ScheduleWeekly
+}
+
+// ParseScheduleTime parses an HH:MM time of day
+func ParseScheduleTime(at string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid schedule time %q", at)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// ScheduleJob returns the scheduler job of a cadence running at an HH:MM
+// time in New York; ok is false when the cadence is off or invalid
+func ScheduleJob(cadence, at string) (job scheduler.Job, ok bool) {
+	hour, minute, err := ParseScheduleTime(at)
+	if err != nil {
+		return scheduler.Job{}, false
+	}
+	job = scheduler.Job{Name: ScheduleJobName, Hour: hour, Minute: minute, Location: market.ExchangeLocation()}
+	switch cadence {
+	case ScheduleDaily:
+		job.Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	case ScheduleWeekly:
+		job.Weekdays = []time.Weekday{time.Monday}
+	default:
+		return scheduler.Job{}, false
+	}
+	return job, true
+}
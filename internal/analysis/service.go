@@ -0,0 +1,363 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"stockmarket/internal/ai"
+	"stockmarket/internal/config"
+	"stockmarket/internal/events"
+	"stockmarket/internal/indicators"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// analysisTimeout bounds data fetching and the AI call for a single run
+const analysisTimeout = 60 * time.Second
+
+// ProviderError is returned when a call to the market data or AI provider
+// fails during an analysis
+type ProviderError struct {
+	Kind     string // "market" or "ai"
+	Provider string
+	Err      error
+}
+
+func (e *ProviderError) Error() string { return e.Err.Error() }
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// ConfigError is returned when the configured market data or AI provider
+// cannot be used, e.g. an unknown provider or a missing API key
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string { return e.Err.Error() }
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// Store is the persistence needed by the analysis service
+type Store interface {
+	GetOrCreateConfig() (*models.UserConfig, error)
+	GetAnalysisPreset(configID, id int64) (*models.AnalysisPreset, error)
+	GetAnalysisPresets(configID int64) ([]models.AnalysisPreset, error)
+	SaveAnalysis(analysis *models.AnalysisResponse) error
+	RecordAIUsage(symbol string, usage *models.TokenUsage, t time.Time) error
+	GetPositions() ([]models.Position, error)
+	GetPendingOutcomes(since time.Time) ([]models.PendingOutcome, error)
+	SetAnalysisOutcome(id int64, outcome string, at time.Time) error
+	GetResolvedOutcomes() ([]models.ResolvedOutcome, error)
+}
+
+// Service runs stock analyses: it resolves configuration, fetches market data,
+// calls the AI provider, saves the result and publishes analysis.completed
+type Service struct {
+	store         Store
+	encryptionKey []byte
+	bus           *events.Bus
+	indicators    *indicators.Cache
+
+	// Factories, replaceable for testing
+	newProvider func(name, apiKey string) (market.Provider, error)
+	newAnalyzer func(provider, apiKey, model, endpoint string) (ai.Analyzer, error)
+}
+
+// NewService creates a new analysis service
+func NewService(store Store, encryptionKey []byte, bus *events.Bus, cache *indicators.Cache) *Service {
+	return &Service{
+		store:         store,
+		encryptionKey: encryptionKey,
+		bus:           bus,
+		indicators:    cache,
+		newProvider:   market.NewProvider,
+		newAnalyzer:   ai.NewAnalyzer,
+	}
+}
+
+// Options selects optional behavior for an analysis run
+type Options struct {
+	PresetID   int64 // zero uses the global configuration
+	MultiFrame bool  // summarize a short- and long-term window in the prompt
+	Consensus  bool  // ask several AI providers and merge their answers
+	Portfolio  bool  // recommend relative to the user's position in the symbol
+	Relative   bool  // compare the symbol with its sector peers and the benchmark
+
+	// OnText receives the model's reply as it is generated, for providers
+	// that stream; consensus runs do not stream
+	OnText func(string)
+}
+
+// Run analyzes symbol using the global configuration
+func (s *Service) Run(ctx context.Context, symbol, userContext string) (*models.AnalysisResponse, *models.Quote, error) {
+	return s.RunWithOptions(ctx, symbol, userContext, Options{})
+}
+
+// RunWithPreset analyzes symbol applying the overrides of a preset; a zero
+// presetID uses the global configuration
+func (s *Service) RunWithPreset(ctx context.Context, symbol, userContext string, presetID int64) (*models.AnalysisResponse, *models.Quote, error) {
+	return s.RunWithOptions(ctx, symbol, userContext, Options{PresetID: presetID})
+}
+
+// RunWithOptions analyzes symbol with the given options
+func (s *Service) RunWithOptions(ctx context.Context, symbol, userContext string, opts Options) (*models.AnalysisResponse, *models.Quote, error) {
+	cfg, err := s.store.GetOrCreateConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, analysisTimeout)
+	defer cancel()
+
+	prepared, err := s.prepare(ctx, cfg, symbol, userContext, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	params, quote, req, tools := prepared.Params, prepared.Quote, prepared.Request, prepared.Tools
+
+	aiProvider := params.AIProvider
+	var result *models.AnalysisResponse
+	if opts.Consensus {
+		panel, perr := s.consensusPanel(cfg, params)
+		if perr != nil {
+			return nil, nil, perr
+		}
+		aiProvider = "consensus"
+		result, err = s.consensus(ctx, params, panel, req, tools)
+	} else {
+		result, err = s.analyze(ctx, aiProvider, params.AIAPIKey, params.AIModel, params.AzureEndpoint, tools, opts.OnText, req)
+	}
+
+	// Retry once with the fallback provider when the primary one is rate limited,
+	// unreachable or rejects its key
+	if err != nil && !opts.Consensus && params.FallbackAIProvider != "" && ai.IsRetryable(err) && ctx.Err() == nil {
+		log.Printf("[ANALYSIS] %s failed for %s (%v), falling back to %s",
+			params.AIProvider, symbol, err, params.FallbackAIProvider)
+		primaryErr := err
+		aiProvider = params.FallbackAIProvider
+		result, err = s.analyze(ctx, aiProvider, params.FallbackAIAPIKey, params.FallbackAIModel, params.AzureEndpoint, tools, opts.OnText, req)
+		if err != nil {
+			err = fmt.Errorf("%w (primary %s: %v)", err, params.AIProvider, primaryErr)
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get analyze: %w", err)
+	}
+	result.Preset = params.Preset
+	result.AIProvider = aiProvider
+	result.RelativeStrength = req.RelativeStrength
+	exchange := market.ResolveExchange(cfg.SymbolExchanges, symbol)
+	applyFreshness(result, quote, exchange, time.Duration(cfg.StaleQuoteMinutes)*time.Minute, time.Now())
+
+	// Low-confidence analyses are returned to the caller but kept out of
+	// history, and only saved analyses are published
+	saved := false
+	if result.Confidence >= cfg.MinStoreConfidence {
+		if err := s.store.SaveAnalysis(result); err != nil {
+			log.Printf("Failed to save analysis: %v", err)
+		} else {
+			saved = true
+		}
+	}
+
+	if s.bus != nil && saved {
+		s.bus.Publish(events.AnalysisCompleted, events.AnalysisCompletedPayload{
+			Analysis: result,
+			Config:   cfg,
+		})
+	}
+
+	return result, quote, nil
+}
+
+// Prepared holds everything resolved for an analysis run before the AI call
+type Prepared struct {
+	Params  Params
+	Quote   *models.Quote
+	Request models.AnalysisRequest
+	Tools   ai.Tools // serves the market data the model asks for
+}
+
+// Prepare resolves configuration and fetches market data for an analysis of
+// symbol without calling the AI provider
+func (s *Service) Prepare(ctx context.Context, symbol, userContext string, opts Options) (*Prepared, error) {
+	cfg, err := s.store.GetOrCreateConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, analysisTimeout)
+	defer cancel()
+
+	return s.prepare(ctx, cfg, symbol, userContext, opts)
+}
+
+// prepare applies the preset and fetches the quote and historical window(s)
+func (s *Service) prepare(ctx context.Context, cfg *models.UserConfig, symbol, userContext string, opts Options) (*Prepared, error) {
+	preset, err := s.loadPreset(cfg, opts.PresetID)
+	if err != nil {
+		return nil, err
+	}
+	params := NewParams(cfg, preset, userContext)
+	params.MultiFrame = opts.MultiFrame
+
+	provider, err := s.newProvider(cfg.MarketDataProvider, s.decrypt(cfg.MarketDataAPIKey))
+	if err != nil {
+		return nil, &ConfigError{Err: fmt.Errorf("Market provider error: %w", err)}
+	}
+
+	quote, err := provider.GetQuote(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get quote: %w", s.providerFailed("market", cfg.MarketDataProvider, err))
+	}
+
+	historical, adjusted, err := history(ctx, provider, symbol, params.HistoryPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get historical data: %w", s.providerFailed("market", cfg.MarketDataProvider, err))
+	}
+
+	req := params.Request(symbol, quote, historical)
+	// The profile only adds context, so the analysis goes ahead without it
+	if profile, err := market.GetCompanyProfile(ctx, provider, symbol); err == nil {
+		req.Profile = profile
+	} else {
+		log.Printf("[ANALYSIS] No company profile for %s: %v", symbol, err)
+	}
+	// So is the comparison with the benchmark
+	if benchmark := cfg.BenchmarkSymbol; benchmark != "" && !strings.EqualFold(benchmark, symbol) {
+		if cmp, err := compareBenchmark(ctx, provider, benchmark, params.HistoryPeriod, historical); err == nil {
+			req.Benchmark = cmp
+		} else {
+			log.Printf("[ANALYSIS] No %s benchmark for %s: %v", benchmark, symbol, err)
+		}
+	}
+	// So are the headlines
+	if cfg.IncludeNews {
+		if news, err := market.GetCompanyNews(ctx, provider, symbol); err == nil {
+			req.News = news
+		} else {
+			log.Printf("[ANALYSIS] No news for %s: %v", symbol, err)
+		}
+	}
+	if opts.Portfolio {
+		positions, err := s.store.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		req.Position = positionContext(positions, symbol, quote.Price)
+	}
+	// The peer comparison is best effort too
+	if opts.Relative {
+		if rs, err := compareRelative(ctx, provider, symbol, cfg.BenchmarkSymbol, params.HistoryPeriod, historical); err == nil {
+			req.RelativeStrength = rs
+		} else {
+			log.Printf("[ANALYSIS] No peer comparison for %s: %v", symbol, err)
+		}
+	}
+	if s.indicators != nil {
+		interval := params.HistoryPeriod
+		if adjusted {
+			interval += "@adjusted"
+		}
+		req.Indicators = s.indicators.Snapshot(symbol, interval, historical)
+	}
+	if params.MultiFrame {
+		req.Timeframes, err = fetchTimeframes(ctx, provider, symbol, params.TradeFrequency)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get historical data: %w", s.providerFailed("market", cfg.MarketDataProvider, err))
+		}
+	}
+
+	return &Prepared{
+		Params:  params,
+		Quote:   quote,
+		Request: req,
+		Tools:   marketTools{provider: provider, indicators: s.indicators},
+	}, nil
+}
+
+// history fetches candles adjusted for splits and dividends, so the price
+// changes in the prompt are not distorted by a split, falling back to
+// unadjusted candles when none are available
+func history(ctx context.Context, provider market.Provider, symbol, period string) ([]models.Candle, bool, error) {
+	candles, err := market.GetAdjustedHistoricalData(ctx, provider, symbol, period)
+	if err == nil && len(candles) > 0 {
+		return candles, true, nil
+	}
+	if ctx.Err() != nil {
+		return nil, false, ctx.Err()
+	}
+	log.Printf("[ANALYSIS] No adjusted history for %s: %v", symbol, err)
+	candles, err = provider.GetHistoricalData(ctx, symbol, period)
+	return candles, false, err
+}
+
+// applyFreshness records the quote time and the state of the symbol's
+// exchange an analysis was built on, flagging quotes older than staleAfter
+func applyFreshness(result *models.AnalysisResponse, quote *models.Quote, exchange string, staleAfter time.Duration, now time.Time) {
+	result.MarketState = market.ExchangeState(exchange, now)
+	result.AfterHours = market.IsOffHours(result.MarketState)
+	if quote.Timestamp.IsZero() {
+		return
+	}
+	quoteTime := quote.Timestamp
+	result.QuoteTime = &quoteTime
+	result.StaleData = staleAfter > 0 && now.Sub(quoteTime) > staleAfter
+}
+
+// analyze runs the request against a single AI provider, letting the model
+// call tools when it supports them and streaming the reply to onText when it
+// is set
+func (s *Service) analyze(ctx context.Context, provider, encryptedKey, model, endpoint string, tools ai.Tools, onText func(string), req models.AnalysisRequest) (*models.AnalysisResponse, error) {
+	analyzer, err := s.newAnalyzer(provider, s.decrypt(encryptedKey), model, endpoint)
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+	result, err := ai.AnalyzeWithTools(ctx, analyzer, req, tools, onText)
+	if err != nil {
+		return nil, s.providerFailed("ai", provider, err)
+	}
+	if result.Usage != nil {
+		if err := s.store.RecordAIUsage(req.Symbol, result.Usage, time.Now()); err != nil {
+			log.Printf("Failed to record AI usage: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// providerFailed publishes provider.failed for a failed provider call and
+// returns err as a ProviderError; unknown symbols are the caller's mistake and
+// requests turned away by the local rate limit or an open circuit never
+// reached the provider, so neither is reported
+func (s *Service) providerFailed(kind, provider string, err error) error {
+	if s.bus != nil && !errors.Is(err, market.ErrInvalidSymbol) && !market.Rejected(err) {
+		s.bus.Publish(events.ProviderFailed, events.ProviderFailedPayload{
+			Kind:     kind,
+			Provider: provider,
+			Err:      err,
+		})
+	}
+	return &ProviderError{Kind: kind, Provider: provider, Err: err}
+}
+
+// loadPreset loads an analysis preset by ID, returning nil when id is zero;
+// it returns sql.ErrNoRows when there is no such preset
+func (s *Service) loadPreset(cfg *models.UserConfig, id int64) (*models.AnalysisPreset, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	return s.store.GetAnalysisPreset(cfg.ID, id)
+}
+
+// decrypt decrypts an API key stored at rest, returning "" when unset
+func (s *Service) decrypt(encrypted string) string {
+	if encrypted == "" {
+		return ""
+	}
+	key, _ := config.Decrypt(encrypted, s.encryptionKey)
+	return key
+}
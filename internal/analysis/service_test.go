@@ -0,0 +1,220 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stockmarket/internal/ai"
+	"stockmarket/internal/events"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// fakeStore keeps the configuration and saved analyses in memory
+type fakeStore struct {
+	Store
+	cfg   models.UserConfig
+	saved []*models.AnalysisResponse
+}
+
+func (s *fakeStore) GetOrCreateConfig() (*models.UserConfig, error) {
+	cfg := s.cfg
+	return &cfg, nil
+}
+
+func (s *fakeStore) GetAnalysisPreset(configID, id int64) (*models.AnalysisPreset, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (s *fakeStore) SaveAnalysis(analysis *models.AnalysisResponse) error {
+	analysis.ID = int64(len(s.saved) + 1)
+	s.saved = append(s.saved, analysis)
+	return nil
+}
+
+func (s *fakeStore) RecordAIUsage(symbol string, usage *models.TokenUsage, t time.Time) error {
+	return nil
+}
+
+// fakeProvider serves a fixed quote and history; it implements the optional
+// profile and adjusted history interfaces so nothing falls back to Yahoo
+type fakeProvider struct {
+	name     string
+	quoteErr error
+}
+
+// fakeProviders numbers the fakes, whose names key the market package caches
+var fakeProviders atomic.Int32
+
+func newFakeProvider() *fakeProvider {
+	return &fakeProvider{name: fmt.Sprintf("fake-%d", fakeProviders.Add(1))}
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	if p.quoteErr != nil {
+		return nil, p.quoteErr
+	}
+	return &models.Quote{Symbol: symbol, Price: 100}, nil
+}
+
+func (p *fakeProvider) GetHistoricalData(ctx context.Context, symbol, period string) ([]models.Candle, error) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, 30)
+	for i := range candles {
+		price := 90 + float64(i)/3
+		candles[i] = models.Candle{Timestamp: start.AddDate(0, 0, i), Open: price, High: price + 1, Low: price - 1, Close: price, Volume: 1000}
+	}
+	return candles, nil
+}
+
+func (p *fakeProvider) GetAdjustedHistoricalData(ctx context.Context, symbol, period string) ([]models.Candle, error) {
+	return p.GetHistoricalData(ctx, symbol, period)
+}
+
+func (p *fakeProvider) GetCompanyProfile(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	return nil, errors.New("no profile")
+}
+
+func (p *fakeProvider) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return nil
+}
+
+// fakeAnalyzer returns a fixed recommendation or error
+type fakeAnalyzer struct {
+	result *models.AnalysisResponse
+	err    error
+}
+
+func (a *fakeAnalyzer) Name() string { return "fake" }
+
+func (a *fakeAnalyzer) Analyze(ctx context.Context, req models.AnalysisRequest) (*models.AnalysisResponse, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	result := *a.result
+	result.Symbol = req.Symbol
+	return &result, nil
+}
+
+// newTestService builds a service on the fakes
+func newTestService(store *fakeStore, provider market.Provider, analyzer ai.Analyzer) *Service {
+	s := NewService(store, nil, events.NewBus(), nil)
+	s.newProvider = func(name, apiKey string) (market.Provider, error) { return provider, nil }
+	s.newAnalyzer = func(provider, apiKey, model, endpoint string) (ai.Analyzer, error) { return analyzer, nil }
+	return s
+}
+
+func TestRunPresetNotFound(t *testing.T) {
+	s := newTestService(&fakeStore{}, newFakeProvider(), &fakeAnalyzer{err: errors.New("not called")})
+
+	_, _, err := s.RunWithPreset(context.Background(), "AAPL", "", 42)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestRunProviderError(t *testing.T) {
+	analyzer := &fakeAnalyzer{err: errors.New("not called")}
+
+	s := newTestService(&fakeStore{}, nil, analyzer)
+	s.newProvider = func(name, apiKey string) (market.Provider, error) { return nil, errors.New("unknown provider") }
+	var configErr *ConfigError
+	if _, _, err := s.Run(context.Background(), "AAPL", ""); !errors.As(err, &configErr) {
+		t.Fatalf("err = %v, want a ConfigError when the provider cannot be created", err)
+	}
+
+	provider := newFakeProvider()
+	provider.quoteErr = errors.New("upstream down")
+	store := &fakeStore{}
+	s = newTestService(store, provider, analyzer)
+	_, _, err := s.Run(context.Background(), "AAPL", "")
+	var providerErr *ProviderError
+	if !errors.Is(err, provider.quoteErr) || !errors.As(err, &providerErr) || providerErr.Kind != "market" {
+		t.Fatalf("err = %v, want the quote error as a market ProviderError", err)
+	}
+	if len(store.saved) != 0 {
+		t.Fatalf("saved %d analyses after a failed quote", len(store.saved))
+	}
+}
+
+func TestRunAnalyzerError(t *testing.T) {
+	analyzerErr := errors.New("model unavailable")
+	store := &fakeStore{cfg: models.UserConfig{AIProvider: "openai"}}
+	s := newTestService(store, newFakeProvider(), &fakeAnalyzer{err: analyzerErr})
+
+	_, _, err := s.Run(context.Background(), "AAPL", "")
+	var providerErr *ProviderError
+	if !errors.Is(err, analyzerErr) || !errors.As(err, &providerErr) || providerErr.Kind != "ai" {
+		t.Fatalf("err = %v, want the analyzer error as an ai ProviderError", err)
+	}
+	if len(store.saved) != 0 {
+		t.Fatalf("saved %d analyses after a failed analysis", len(store.saved))
+	}
+}
+
+func TestRunSavesAndPublishes(t *testing.T) {
+	store := &fakeStore{cfg: models.UserConfig{AIProvider: "openai"}}
+	analyzer := &fakeAnalyzer{result: &models.AnalysisResponse{Action: "BUY", Confidence: 0.8, Reasoning: "trend"}}
+	s := newTestService(store, newFakeProvider(), analyzer)
+
+	published := make(chan *models.AnalysisResponse, 1)
+	s.bus.Subscribe(events.AnalysisCompleted, func(e events.Event) {
+		published <- e.Payload.(events.AnalysisCompletedPayload).Analysis
+	})
+
+	result, quote, err := s.Run(context.Background(), "AAPL", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Symbol != "AAPL" || result.Action != "BUY" || result.AIProvider != "openai" {
+		t.Fatalf("result = %+v", result)
+	}
+	if quote == nil || quote.Price != 100 {
+		t.Fatalf("quote = %+v", quote)
+	}
+	if len(store.saved) != 1 || store.saved[0] != result {
+		t.Fatalf("saved %d analyses, want the result", len(store.saved))
+	}
+	select {
+	case analysis := <-published:
+		if analysis != result {
+			t.Fatalf("published %+v, want the result", analysis)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("analysis.completed was not published")
+	}
+}
+
+func TestRunUnsavedNotPublished(t *testing.T) {
+	store := &fakeStore{cfg: models.UserConfig{AIProvider: "openai", MinStoreConfidence: 0.9}}
+	analyzer := &fakeAnalyzer{result: &models.AnalysisResponse{Action: "BUY", Confidence: 0.8}}
+	s := newTestService(store, newFakeProvider(), analyzer)
+
+	published := make(chan *models.AnalysisResponse, 1)
+	s.bus.Subscribe(events.AnalysisCompleted, func(e events.Event) {
+		published <- e.Payload.(events.AnalysisCompletedPayload).Analysis
+	})
+
+	result, _, err := s.Run(context.Background(), "AAPL", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Action != "BUY" || result.ID != 0 {
+		t.Fatalf("result = %+v, want the unsaved analysis", result)
+	}
+	if len(store.saved) != 0 {
+		t.Fatalf("saved %d analyses below the minimum confidence", len(store.saved))
+	}
+	select {
+	case analysis := <-published:
+		t.Fatalf("published unsaved analysis %+v", analysis)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
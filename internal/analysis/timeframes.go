@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"stockmarket/internal/market"
+	"stockmarket/internal/market/resample"
+	"stockmarket/internal/models"
+)
+
+// maxTimeframeCandles caps the candles kept per timeframe so multi-timeframe
+// prompts stay within a predictable token budget
+const maxTimeframeCandles = 65
+
+// timeframeSpec describes one window fetched for multi-timeframe analysis
+type timeframeSpec struct {
+	label    string
+	period   string
+	interval string // resample to this bar size so every provider looks alike
+}
+
+// timeframesFor returns the short- and long-term windows for a trade frequency
+func timeframesFor(tradeFrequency string) []timeframeSpec {
+	if tradeFrequency == "daily" {
+		return []timeframeSpec{
+			{label: "Hourly (5 days)", period: "5d", interval: resample.Interval1h},
+			{label: "Daily (3 months)", period: "3m", interval: resample.Interval1d},
+		}
+	}
+	return []timeframeSpec{
+		{label: "Daily (3 months)", period: "3m", interval: resample.Interval1d},
+		{label: "Weekly (1 year)", period: "1y", interval: resample.Interval1w},
+	}
+}
+
+// fetchTimeframes fetches and down-samples the windows for a trade frequency
+func fetchTimeframes(ctx context.Context, provider market.Provider, symbol, tradeFrequency string) ([]models.Timeframe, error) {
+	var frames []models.Timeframe
+	for _, spec := range timeframesFor(tradeFrequency) {
+		candles, err := provider.GetHistoricalData(ctx, symbol, spec.period)
+		if err != nil {
+			return nil, err
+		}
+		candles = sortedCandles(candles)
+		// Providers with coarser history (e.g. daily-only) keep their own bars
+		resampled, err := resample.Resample(candles, spec.interval, market.ExchangeLocation())
+		if err == nil {
+			candles = resampled
+		} else if !errors.Is(err, resample.ErrFinerThanSource) {
+			return nil, err
+		}
+		frames = append(frames, models.Timeframe{
+			Label:   spec.label,
+			Period:  spec.period,
+			Candles: downsample(candles, maxTimeframeCandles),
+		})
+	}
+	return frames, nil
+}
+
+// sortedCandles returns a copy of candles ordered oldest first
+func sortedCandles(candles []models.Candle) []models.Candle {
+	out := append([]models.Candle{}, candles...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Timestamp.Before(out[j].Timestamp)
+	})
+	return out
+}
+
+// downsample merges consecutive oldest-first candles so at most limit remain
+func downsample(candles []models.Candle, limit int) []models.Candle {
+	if limit <= 0 || len(candles) <= limit {
+		return candles
+	}
+	size := (len(candles) + limit - 1) / limit
+	out := make([]models.Candle, 0, limit)
+	for i := 0; i < len(candles); i += size {
+		end := min(i+size, len(candles))
+		out = append(out, resample.Merge(candles[i:end]))
+	}
+	return out
+}
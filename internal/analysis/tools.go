@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+
+	"stockmarket/internal/indicators"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// toolIndicatorPeriod is the history the indicators requested by the model are
+// computed on
+const toolIndicatorPeriod = "3m"
+
+// errNoIndicators is returned to the model when indicators cannot be computed
+var errNoIndicators = errors.New("indicators not available")
+
+// marketTools serves the market data the AI model requests during an analysis
+// from the configured market provider
+type marketTools struct {
+	provider   market.Provider
+	indicators *indicators.Cache
+}
+
+// GetQuote fetches the latest quote of symbol
+func (t marketTools) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	return t.provider.GetQuote(ctx, market.NormalizeSymbol(symbol))
+}
+
+// GetHistorical fetches the candles of symbol over period, adjusted when
+// available
+func (t marketTools) GetHistorical(ctx context.Context, symbol, period string) ([]models.Candle, error) {
+	if _, err := market.ParsePeriod(period); err != nil {
+		return nil, err
+	}
+	candles, _, err := history(ctx, t.provider, market.NormalizeSymbol(symbol), period)
+	return candles, err
+}
+
+// GetIndicators computes the default indicators of symbol over
+// toolIndicatorPeriod
+func (t marketTools) GetIndicators(ctx context.Context, symbol string) (models.Indicators, error) {
+	symbol = market.NormalizeSymbol(symbol)
+	candles, adjusted, err := history(ctx, t.provider, symbol, toolIndicatorPeriod)
+	if err != nil {
+		return models.Indicators{}, err
+	}
+	if len(candles) == 0 || t.indicators == nil {
+		return models.Indicators{}, errNoIndicators
+	}
+	interval := toolIndicatorPeriod
+	if adjusted {
+		interval += "@adjusted"
+	}
+	return t.indicators.Snapshot(symbol, interval, candles), nil
+}
@@ -1,19 +1,158 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"stockmarket/internal/indicators"
+	"stockmarket/internal/market"
 	"stockmarket/internal/models"
+	"stockmarket/internal/rules"
 	"stockmarket/internal/web/pages"
 )
 
+// defaultAlertCooldown and maxAlertCooldown are the default and longest
+// cooldown of recurring alerts, in minutes
+const (
+	defaultAlertCooldown = 60
+	maxAlertCooldown     = 7 * 24 * 60
+)
+
+// maxHysteresisPercent bounds the hysteresis band of recurring price alerts
+const maxHysteresisPercent = 10
+
+// alertChannelTypes are the notification channel types an alert can be
+// limited to
+var alertChannelTypes = []string{"email", "discord", "sms", "pushover", "webhook", "matrix", "webpush", "fcm", "smtp"}
+
+// alertHistoryLimit bounds the triggers listed by /api/alerts/{id}/history
+const alertHistoryLimit = 100
+
+// maxAlertLabel and maxAlertNote cap the length of an alert's label and
+// note, in characters
+const (
+	maxAlertLabel = 32
+	maxAlertNote  = 500
+)
+
+// maxAlertExpiryDays bounds how far ahead the alerts form sets expiry
+const maxAlertExpiryDays = 365
+
+// validateAlert checks the condition of an alert with its price, trailing
+// distance, moving average periods or rule, the cooldown and hysteresis band of a
+// recurring alert, its expiry, notification channels, label and note; it
+// returns an error message if the alert is invalid
+func validateAlert(alert *models.PriceAlert) string {
+	alert.Label = strings.Join(strings.Fields(alert.Label), " ")
+	if utf8.RuneCountInString(alert.Label) > maxAlertLabel {
+		return INVALID_ALERT_LABEL
+	}
+	alert.Note = strings.TrimSpace(alert.Note)
+	if utf8.RuneCountInString(alert.Note) > maxAlertNote {
+		return INVALID_ALERT_NOTE
+	}
+	if alert.ExpiresAt != nil && !alert.ExpiresAt.After(time.Now()) {
+		return INVALID_ALERT_EXPIRY
+	}
+	var channels []string
+	for _, ch := range alert.Channels {
+		ch = strings.ToLower(strings.TrimSpace(ch))
+		if !slices.Contains(alertChannelTypes, ch) {
+			return INVALID_ALERT_CHANNELS
+		}
+		if !slices.Contains(channels, ch) {
+			channels = append(channels, ch)
+		}
+	}
+	alert.Channels = channels
+	if isGap(alert.Condition) {
+		// Gap alerts fire once every trading day already
+		alert.Recurring = false
+	}
+	if !alert.Recurring {
+		alert.CooldownMinutes = 0
+	} else if alert.CooldownMinutes == 0 {
+		alert.CooldownMinutes = defaultAlertCooldown
+	} else if alert.CooldownMinutes < 1 || alert.CooldownMinutes > maxAlertCooldown {
+		return INVALID_ALERT_COOLDOWN
+	}
+	if !alert.Recurring || (alert.Condition != "above" && alert.Condition != "below") {
+		alert.HysteresisPercent = 0
+	} else if alert.HysteresisPercent < 0 || alert.HysteresisPercent > maxHysteresisPercent {
+		return INVALID_HYSTERESIS
+	}
+	alert.Rearming = false
+
+	if alert.Condition == "composite" {
+		if alert.Rule == nil {
+			return INVALID_ALERT_RULE
+		}
+		if err := rules.Validate(alert.Rule); err != nil {
+			return INVALID_ALERT_RULE + ": " + err.Error()
+		}
+		alert.Price, alert.FastPeriod, alert.SlowPeriod, alert.Period = 0, 0, 0, 0
+		return ""
+	}
+	alert.Rule = nil
+
+	if isCrossover(alert.Condition) {
+		if alert.FastPeriod < 1 || alert.FastPeriod >= alert.SlowPeriod || alert.SlowPeriod > maxSMAPeriod {
+			return INVALID_SMA_PERIODS
+		}
+		alert.Price = 0
+		return ""
+	}
+	alert.FastPeriod, alert.SlowPeriod = 0, 0
+
+	if isRSI(alert.Condition) {
+		if alert.Period == 0 {
+			alert.Period = indicators.DefaultRSIPeriod
+		}
+		if alert.Price <= 0 || alert.Price >= 100 || alert.Period < 2 || alert.Period > maxRSIPeriod {
+			return INVALID_RSI_ALERT
+		}
+		return ""
+	}
+	alert.Period = 0
+
+	if isExtreme(alert.Condition) {
+		alert.Price = 0
+		return ""
+	}
+
+	if alert.Price <= 0 {
+		return INVALID_PRICE
+	}
+	switch alert.Condition {
+	case "above", "below", "gap", "gap_up", "gap_down", "trail_amount":
+	case "trail_percent":
+		if alert.Price >= 100 {
+			return INVALID_TRAIL_PERCENT
+		}
+	default:
+		return INVALID_ALERT_CONDITION
+	}
+	return ""
+}
+
+// handleAlerts lists the active alerts, or the triggered ones with
+// ?state=triggered, and creates alerts from JSON
 func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		alerts, err := s.db.GetActiveAlerts()
+		alerts, err := s.alertsInState(r.URL.Query().Get("state"))
+		if errors.Is(err, errInvalidAlertState) {
+			respondError(w, http.StatusBadRequest, INVALID_ALERT_STATE)
+			return
+		}
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -21,19 +160,19 @@ func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
 		respondJSON(w, http.StatusOK, alerts)
 
 	case http.MethodPost:
-		var alert models.PriceAlert
+		alert := models.PriceAlert{Enabled: true}
 		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
 			respondError(w, http.StatusBadRequest, INVALID_JSON)
 			return
 		}
 
-		alert.Symbol = strings.ToUpper(strings.TrimSpace(alert.Symbol))
-		if alert.Symbol == "" || alert.Price <= 0 {
+		alert.Symbol = market.NormalizeSymbol(alert.Symbol)
+		if alert.Symbol == "" {
 			respondError(w, http.StatusBadRequest, "Symbol and price required")
 			return
 		}
-		if alert.Condition != "above" && alert.Condition != "below" {
-			respondError(w, http.StatusBadRequest, "Condition must be 'above' or 'below'")
+		if msg := validateAlert(&alert); msg != "" {
+			respondError(w, http.StatusBadRequest, msg)
 			return
 		}
 
@@ -71,8 +210,28 @@ func (s *Server) handleAlertDelete(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
-// handleNotificationChannels handles notification channel CRUD
+// errInvalidAlertState is returned by alertsInState for unknown states
+var errInvalidAlertState = errors.New("invalid alert state")
+
+// alertsInState gets the active alerts, or the triggered ones for state
+// "triggered"
+func (s *Server) alertsInState(state string) ([]models.PriceAlert, error) {
+	switch state {
+	case "", "active":
+		return s.db.GetActiveAlerts()
+	case "triggered":
+		return s.db.GetTriggeredAlerts()
+	}
+	return nil, errInvalidAlertState
+}
+
+// handleAlertsHTMX creates an alert from the alerts form and returns the
+// updated list; listing alerts and JSON requests go to handleAlerts
 func (s *Server) handleAlertsHTMX(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet || strings.HasPrefix(r.Header.Get(HEADER_CONTENT_TYPE), CONTENT_TYPE_JSON) {
+		s.handleAlerts(w, r)
+		return
+	}
 	if r.Method != http.MethodPost {
 		http.Error(w, METHOD_NOT_ALLOWED, http.StatusMethodNotAllowed)
 		return
@@ -83,25 +242,59 @@ func (s *Server) handleAlertsHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	symbol := strings.ToUpper(strings.TrimSpace(r.FormValue("symbol")))
+	symbol := market.NormalizeSymbol(r.FormValue("symbol"))
 	condition := r.FormValue("condition")
 	priceStr := r.FormValue("target_price")
 
-	if symbol == "" || condition == "" || priceStr == "" {
+	if symbol == "" || condition == "" || (priceStr == "" && !isCrossover(condition) && !isExtreme(condition) && condition != "composite") {
 		htmxError(w, ALL_FIELDS_REQUIRED)
 		return
 	}
 
-	price, err := strconv.ParseFloat(priceStr, 64)
-	if err != nil {
-		htmxError(w, INVALID_PRICE)
-		return
-	}
-
 	alert := &models.PriceAlert{
 		Symbol:    symbol,
 		Condition: condition,
-		Price:     price,
+		Recurring: r.FormValue("recurring") == "on",
+		Enabled:   true,
+		Channels:  r.Form["channels"],
+		Label:     r.FormValue("label"),
+		Note:      r.FormValue("note"),
+	}
+	if alert.Recurring {
+		alert.CooldownMinutes, _ = strconv.Atoi(r.FormValue("cooldown_minutes"))
+		alert.HysteresisPercent, _ = strconv.ParseFloat(r.FormValue("hysteresis_percent"), 64)
+	}
+	if days := r.FormValue("expires_in"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 || n > maxAlertExpiryDays {
+			htmxError(w, INVALID_ALERT_EXPIRY)
+			return
+		}
+		expiresAt := time.Now().UTC().AddDate(0, 0, n)
+		alert.ExpiresAt = &expiresAt
+	}
+	if condition == "composite" {
+		rule, err := rules.Parse(r.FormValue("rule"))
+		if err != nil {
+			htmxError(w, INVALID_ALERT_RULE+": "+err.Error())
+			return
+		}
+		alert.Rule = &rule
+	} else if isCrossover(condition) {
+		alert.FastPeriod, _ = strconv.Atoi(r.FormValue("fast_period"))
+		alert.SlowPeriod, _ = strconv.Atoi(r.FormValue("slow_period"))
+	} else if !isExtreme(condition) {
+		alert.Period, _ = strconv.Atoi(r.FormValue("period"))
+		price, err := strconv.ParseFloat(priceStr, 64)
+		if err != nil {
+			htmxError(w, INVALID_PRICE)
+			return
+		}
+		alert.Price = price
+	}
+	if msg := validateAlert(alert); msg != "" {
+		htmxError(w, msg)
+		return
 	}
 
 	if err := s.db.SavePriceAlert(alert); err != nil {
@@ -115,6 +308,28 @@ func (s *Server) handleAlertsHTMX(w http.ResponseWriter, r *http.Request) {
 
 // handleAlertDeleteHTMX handles deleting alerts and returns updated list
 func (s *Server) handleAlertDeleteHTMX(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/alerts/")
+	if idStr, ok := strings.CutSuffix(path, "/pause"); ok {
+		s.handleAlertEnabled(w, r, idStr, false)
+		return
+	}
+	if idStr, ok := strings.CutSuffix(path, "/resume"); ok {
+		s.handleAlertEnabled(w, r, idStr, true)
+		return
+	}
+	if idStr, ok := strings.CutSuffix(path, "/history"); ok {
+		s.handleAlertHistory(w, r, idStr)
+		return
+	}
+	if idStr, ok := strings.CutSuffix(path, "/test"); ok {
+		s.handleAlertTest(w, r, idStr)
+		return
+	}
+	if idStr, ok := strings.CutSuffix(path, "/rearm"); ok {
+		s.handleAlertRearm(w, r, idStr)
+		return
+	}
+
 	if r.Method != http.MethodDelete {
 		http.Error(w, METHOD_NOT_ALLOWED, http.StatusMethodNotAllowed)
 		return
@@ -135,8 +350,198 @@ func (s *Server) handleAlertDeleteHTMX(w http.ResponseWriter, r *http.Request) {
 	s.renderAlertsList(w, r)
 }
 
+// handleAlertEnabled pauses or resumes an alert, returning the updated list
+// to HTMX and the status otherwise
+func (s *Server) handleAlertEnabled(w http.ResponseWriter, r *http.Request, idStr string, enabled bool) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	htmx := r.Header.Get("HX-Request") == "true"
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		if htmx {
+			htmxError(w, "Invalid alert ID")
+			return
+		}
+		respondError(w, http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+
+	if err := s.db.SetAlertEnabled(id, enabled); err != nil {
+		status, msg := http.StatusInternalServerError, err.Error()
+		if errors.Is(err, sql.ErrNoRows) {
+			status, msg = http.StatusNotFound, ALERT_NOT_FOUND
+		}
+		if htmx {
+			htmxError(w, msg)
+			return
+		}
+		respondError(w, status, msg)
+		return
+	}
+
+	if htmx {
+		s.renderAlertsList(w, r)
+		return
+	}
+	status := "paused"
+	if enabled {
+		status = "resumed"
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+// handleAlertRearm reactivates a triggered alert, returning the updated
+// list of triggered alerts to HTMX and the status otherwise
+func (s *Server) handleAlertRearm(w http.ResponseWriter, r *http.Request, idStr string) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	htmx := r.Header.Get("HX-Request") == "true"
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		if htmx {
+			htmxError(w, "Invalid alert ID")
+			return
+		}
+		respondError(w, http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+
+	if err := s.db.ReactivateAlert(id); err != nil {
+		status, msg := http.StatusInternalServerError, err.Error()
+		if errors.Is(err, sql.ErrNoRows) {
+			status, msg = http.StatusNotFound, ALERT_NOT_FOUND
+		}
+		if htmx {
+			htmxError(w, msg)
+			return
+		}
+		respondError(w, status, msg)
+		return
+	}
+
+	if htmx {
+		s.renderAlertsList(w, r)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"status": "rearmed"})
+}
+
+// handleAlertHistory lists the times an alert fired, newest first, with
+// the price and the notification channels reached
+func (s *Server) handleAlertHistory(w http.ResponseWriter, r *http.Request, idStr string) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+
+	triggers, err := s.db.GetAlertTriggers(id, alertHistoryLimit)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, ALERT_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, triggers)
+}
+
+// handleAlertTest sends a test notification for an alert through its
+// channels and broadcasts it to WebSocket clients, without firing the alert
+// or recording it in the history, so notification channels can be checked
+// end to end. HTMX gets a toast, other clients the channels reached and the
+// delivery errors.
+func (s *Server) handleAlertTest(w http.ResponseWriter, r *http.Request, idStr string) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	htmx := r.Header.Get("HX-Request") == "true"
+	fail := func(status int, msg string) {
+		if htmx {
+			htmxError(w, msg)
+			return
+		}
+		respondError(w, status, msg)
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		fail(http.StatusBadRequest, "Invalid alert ID")
+		return
+	}
+	alert, err := s.db.GetPriceAlert(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		fail(http.StatusNotFound, ALERT_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	message := withAlertNote(*alert, fmt.Sprintf(TEST_ALERT, alert.Symbol, alert.Condition))
+	s.BroadcastAlert(alert.Symbol, message)
+	sent, errs := s.notifyService.Attempt(models.Notification{
+		Type:         "price_alert",
+		Title:        fmt.Sprintf(PRICE_ALERT, alert.Symbol),
+		Message:      message,
+		Symbol:       alert.Symbol,
+		Price:        alert.Price,
+		ChannelTypes: alert.Channels,
+	}, cfg.NotificationChannels)
+
+	errMsgs := make([]string, len(errs))
+	for i, err := range errs {
+		errMsgs[i] = err.Error()
+	}
+	if sent == nil {
+		sent = []string{}
+	}
+
+	if htmx {
+		switch {
+		case len(errs) > 0:
+			htmxError(w, "Test alert failed: "+strings.Join(errMsgs, "; "))
+		case len(sent) == 0:
+			htmxError(w, NO_ALERT_CHANNELS)
+		default:
+			htmxSuccess(w, "Test alert sent to "+strings.Join(sent, ", "))
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  message,
+		"channels": sent,
+		"errors":   errMsgs,
+	})
+}
+
+// renderAlertsList renders the alerts in the state of the request's
+// ?state=, active by default
 func (s *Server) renderAlertsList(w http.ResponseWriter, r *http.Request) {
-	alertsRaw, _ := s.db.GetActiveAlerts()
+	state := r.URL.Query().Get("state")
+	alertsRaw, err := s.alertsInState(state)
+	if errors.Is(err, errInvalidAlertState) {
+		state = "active"
+		alertsRaw, _ = s.db.GetActiveAlerts()
+	}
 
 	// Convert to pages.Alert
 	alerts := make([]pages.Alert, len(alertsRaw))
@@ -146,12 +551,29 @@ func (s *Server) renderAlertsList(w http.ResponseWriter, r *http.Request) {
 			Symbol:      a.Symbol,
 			Condition:   a.Condition,
 			TargetPrice: a.Price,
+			PeakPrice:   a.PeakPrice,
+			FastPeriod:  a.FastPeriod,
+			SlowPeriod:  a.SlowPeriod,
+			Period:      a.Period,
+			Recurring:   a.Recurring,
+			Cooldown:    a.CooldownMinutes,
+			Hysteresis:  a.HysteresisPercent,
+			Rearming:    a.Rearming,
+			Enabled:     a.Enabled,
+			Channels:    a.Channels,
+			Label:       a.Label,
+			Note:        a.Note,
+			LastFired:   a.TriggeredAt,
+			ExpiresAt:   a.ExpiresAt,
 			Triggered:   a.Triggered,
 		}
+		if a.Rule != nil {
+			alerts[i].Rule = rules.Describe(*a.Rule)
+		}
 	}
 
 	w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
-	pages.AlertsListPartial(alerts).Render(r.Context(), w)
+	pages.AlertsListPartial(alerts, state).Render(r.Context(), w)
 }
 
 // HTMX response helpers
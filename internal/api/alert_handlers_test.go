@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// postAlertForm posts the alerts form as HTMX does
+func postAlertForm(s *Server, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/alerts", strings.NewReader(form.Encode()))
+	req.Header.Set(HEADER_CONTENT_TYPE, "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	s.handleAlertsHTMX(rec, req)
+	return rec
+}
+
+// postAlertJSON posts an alert as JSON
+func postAlertJSON(s *Server, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/alerts", strings.NewReader(body))
+	req.Header.Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_JSON)
+	rec := httptest.NewRecorder()
+	s.handleAlertsHTMX(rec, req)
+	return rec
+}
+
+func TestCreateAlertValidatesBothPaths(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name      string
+		condition string
+		price     string
+		message   string
+	}{
+		{"unknown condition", "sideways", "5", INVALID_ALERT_CONDITION},
+		{"zero gap threshold", "gap", "0", INVALID_PRICE},
+		{"negative gap threshold", "gap_down", "-2", INVALID_PRICE},
+		{"trailing percent over 100", "trail_percent", "150", INVALID_TRAIL_PERCENT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"symbol": {"AAPL"}, "condition": {tt.condition}, "target_price": {tt.price}}
+			rec := postAlertForm(s, form)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Header().Get("HX-Trigger"), tt.message) {
+				t.Fatalf("form: status = %d, HX-Trigger = %q, want %q", rec.Code, rec.Header().Get("HX-Trigger"), tt.message)
+			}
+
+			rec = postAlertJSON(s, `{"symbol": "AAPL", "condition": "`+tt.condition+`", "price": `+tt.price+`}`)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.message) {
+				t.Fatalf("json: status = %d, body = %s, want %q", rec.Code, rec.Body, tt.message)
+			}
+		})
+	}
+
+	alerts, err := s.db.GetActiveAlerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 0 {
+		t.Fatalf("saved %d invalid alerts", len(alerts))
+	}
+
+	if rec := postAlertForm(s, url.Values{"symbol": {"AAPL"}, "condition": {"gap_up"}, "target_price": {"2"}}); rec.Code != http.StatusOK {
+		t.Fatalf("valid gap alert: status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -1,17 +1,18 @@
 package api
 
 import (
-	"context"
+	"bytes"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"stockmarket/internal/ai"
-	"stockmarket/internal/config"
+	"stockmarket/internal/analysis"
 	"stockmarket/internal/market"
 	"stockmarket/internal/models"
 	c "stockmarket/internal/web/components"
@@ -19,101 +20,177 @@ import (
 )
 
 func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/analyze/")
+	if symbol, ok := strings.CutSuffix(path, "/stream"); ok {
+		s.handleAnalyzeStream(w, r, symbol)
+		return
+	}
+
 	if r.Method != http.MethodPost {
 		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
 		return
 	}
 
-	symbol := strings.TrimPrefix(r.URL.Path, "/api/analyze/")
-	if symbol == "" {
+	symbol, isPrompt := strings.CutSuffix(path, "/prompt")
+	if symbol == "" || strings.Contains(symbol, "/") {
 		respondError(w, http.StatusBadRequest, SYMBOL_REQUIRED)
 		return
 	}
-	symbol = strings.ToUpper(symbol)
+	symbol = market.NormalizeSymbol(symbol)
 
 	var input struct {
 		UserContext string `json:"user_context"`
+		PresetID    int64  `json:"preset_id"`
 	}
 	json.NewDecoder(r.Body).Decode(&input)
 
-	cfg, err := s.db.GetOrCreateConfig()
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+	opts := analysis.Options{PresetID: input.PresetID}
+	switch r.URL.Query().Get("multiframe") {
+	case "1", "true":
+		opts.MultiFrame = true
+	}
+	switch r.URL.Query().Get("portfolio") {
+	case "1", "true":
+		opts.Portfolio = true
+	}
+	switch r.URL.Query().Get("mode") {
+	case "", "single":
+	case "consensus":
+		opts.Consensus = true
+	case "relative":
+		opts.Relative = true
+	default:
+		respondError(w, http.StatusBadRequest, INVALID_ANALYSIS_MODE)
 		return
 	}
 
-	// Get market data
-	marketAPIKey := ""
-	if cfg.MarketDataAPIKey != "" {
-		marketAPIKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
+	if isPrompt {
+		s.handleAnalyzePrompt(w, r, symbol, input.UserContext, opts)
+		return
 	}
 
-	provider, err := market.NewProvider(cfg.MarketDataProvider, marketAPIKey)
+	result, _, err := s.analysisService.RunWithOptions(r.Context(), symbol, input.UserContext, opts)
 	if err != nil {
-		respondError(w, http.StatusBadRequest, "Market provider error: "+err.Error())
+		respondAnalysisError(w, err)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
-	defer cancel()
+	respondJSON(w, http.StatusOK, result)
+}
 
-	quote, err := provider.GetQuote(ctx, symbol)
-	if err != nil {
-		respondError(w, http.StatusBadRequest, FAILED_TO_GET_QUOTE+": "+err.Error())
-		return
+// respondAnalysisError sends the error of an analysis: 404 for an unknown
+// preset, 400 for an invalid request or provider configuration, 429 or 503
+// when the market data provider turned the request away, 502 when a provider
+// call failed and 500 otherwise
+func respondAnalysisError(w http.ResponseWriter, err error) {
+	var limited *market.RateLimitError
+	var open *market.CircuitOpenError
+	var configErr *analysis.ConfigError
+	var providerErr *analysis.ProviderError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		respondError(w, http.StatusNotFound, PRESET_NOT_FOUND)
+	case errors.Is(err, analysis.ErrConsensusUnavailable):
+		respondError(w, http.StatusBadRequest, CONSENSUS_UNAVAILABLE)
+	case errors.As(err, &limited), errors.As(err, &open):
+		respondProviderError(w, err)
+	case errors.As(err, &configErr), errors.Is(err, market.ErrInvalidSymbol), errors.Is(err, market.ErrInvalidPeriod),
+		errors.Is(err, ai.ErrNoAPIKey), errors.Is(err, ai.ErrNoEndpoint):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &providerErr):
+		respondError(w, http.StatusBadGateway, err.Error())
+	default:
+		respondError(w, http.StatusInternalServerError, err.Error())
 	}
+}
 
-	historical, err := provider.GetHistoricalData(ctx, symbol, "1m")
-	if err != nil {
-		respondError(w, http.StatusBadRequest, FAILED_TO_GET_HISTORICAL_DATA+": "+err.Error())
+// handleAnalyzeStream runs an analysis and streams it as server-sent events:
+// "token" events carry the model's reply as it is generated, then a "result"
+// event carries the analysis and a "card" event its rendered result card, or
+// an "error" event carries the failure. Options are taken from the query
+// (context, preset_id, multiframe and portfolio) so EventSource can be used.
+func (s *Server) handleAnalyzeStream(w http.ResponseWriter, r *http.Request, symbol string) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
 		return
 	}
-
-	// Get AI analyzer
-	aiAPIKey := ""
-	if cfg.AIProviderAPIKey != "" {
-		aiAPIKey, _ = config.Decrypt(cfg.AIProviderAPIKey, s.config.EncryptionKey)
+	if symbol == "" || strings.Contains(symbol, "/") {
+		respondError(w, http.StatusBadRequest, SYMBOL_REQUIRED)
+		return
 	}
+	symbol = market.NormalizeSymbol(symbol)
 
-	analyzer, err := ai.NewAnalyzer(cfg.AIProvider, aiAPIKey, cfg.AIModel)
-	if err != nil {
-		respondError(w, http.StatusBadRequest, FAILED_TO_GET_ANALYZE+": "+err.Error())
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondError(w, http.StatusInternalServerError, STREAMING_UNSUPPORTED)
 		return
 	}
 
-	// Perform analysis
-	analysisReq := models.AnalysisRequest{
-		Symbol:         symbol,
-		CurrentPrice:   quote.Price,
-		HistoricalData: historical,
-		RiskProfile:    cfg.RiskTolerance,
-		TradeFrequency: cfg.TradeFrequency,
-		UserContext:    input.UserContext,
+	query := r.URL.Query()
+	opts := analysis.Options{}
+	opts.PresetID, _ = strconv.ParseInt(query.Get("preset_id"), 10, 64)
+	switch query.Get("multiframe") {
+	case "1", "true":
+		opts.MultiFrame = true
+	}
+	switch query.Get("portfolio") {
+	case "1", "true":
+		opts.Portfolio = true
+	}
+
+	w.Header().Set(HEADER_CONTENT_TYPE, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data interface{}) {
+		payload, _ := json.Marshal(data)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		flusher.Flush()
+	}
+	opts.OnText = func(text string) {
+		send("token", text)
 	}
 
-	analysis, err := analyzer.Analyze(ctx, analysisReq)
+	result, quote, err := s.analysisService.RunWithOptions(r.Context(), symbol, query.Get("context"), opts)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = errors.New(PRESET_NOT_FOUND)
+	}
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, FAILED_TO_GET_ANALYZE+": "+err.Error())
+		send("error", map[string]string{"error": err.Error()})
 		return
 	}
 
-	// Save analysis
-	if err := s.db.SaveAnalysis(analysis); err != nil {
-		log.Printf("Failed to save analysis: %v", err)
-	}
+	send("result", result)
+	var card bytes.Buffer
+	pages.AnalysisResultCard(analysisResultCard(result, quote)).Render(r.Context(), &card)
+	send("card", card.String())
+}
 
-	// Send notifications if action is BUY or SELL with high confidence
-	if (analysis.Action == "BUY" || analysis.Action == "SELL") && analysis.Confidence >= 0.7 {
-		notification := models.Notification{
-			Type:    strings.ToLower(analysis.Action) + "_signal",
-			Title:   fmt.Sprintf("%s Signal: %s", analysis.Action, symbol),
-			Message: analysis.Reasoning,
-			Symbol:  symbol,
-		}
-		go s.notifyService.SendToChannels(notification, cfg.NotificationChannels)
+// handleAnalyzePrompt returns the prompt that would be sent to the AI provider
+// for symbol, without calling the model
+func (s *Server) handleAnalyzePrompt(w http.ResponseWriter, r *http.Request, symbol, userContext string, opts analysis.Options) {
+	prepared, err := s.analysisService.Prepare(r.Context(), symbol, userContext, opts)
+	if err != nil {
+		respondAnalysisError(w, err)
+		return
 	}
 
-	respondJSON(w, http.StatusOK, analysis)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol":             symbol,
+		"prompt":             ai.BuildPrompt(prepared.Request),
+		"historical_summary": ai.FormatHistoricalSummary(prepared.Request.HistoricalData),
+		"history_period":     prepared.Params.HistoryPeriod,
+		"candles":            len(prepared.Request.HistoricalData),
+		"ai_provider":        prepared.Params.AIProvider,
+		"ai_model":           prepared.Params.AIModel,
+		"preset":             prepared.Params.Preset,
+		"multiframe":         prepared.Params.MultiFrame,
+		"timeframes":         ai.FormatTimeframes(prepared.Request.Timeframes),
+		"benchmark":          prepared.Request.Benchmark,
+		"position":           prepared.Request.Position,
+		"relative_strength":  prepared.Request.RelativeStrength,
+	})
 }
 
 // handleAnalyses returns recent analysis results
@@ -131,7 +208,13 @@ func (s *Server) handleAnalyses(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	analyses, err := s.db.GetRecentAnalyses(limit)
+	var analyses []models.AnalysisResponse
+	var err error
+	if preset := r.URL.Query().Get("preset"); preset != "" {
+		analyses, err = s.db.GetAnalysesForPreset(preset, limit)
+	} else {
+		analyses, err = s.db.GetRecentAnalyses(limit)
+	}
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -142,17 +225,26 @@ func (s *Server) handleAnalyses(w http.ResponseWriter, r *http.Request) {
 
 // handleAnalysesForSymbol returns analyses for a specific symbol
 func (s *Server) handleAnalysesForSymbol(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.TrimPrefix(r.URL.Path, "/api/analyses/")
+	if idStr, ok := strings.CutSuffix(symbol, "/feedback"); ok {
+		s.handleAnalysisFeedback(w, r, idStr)
+		return
+	}
+	if idStr, tagPath, ok := strings.Cut(symbol, "/tags"); ok && (tagPath == "" || tagPath[0] == '/') {
+		s.handleAnalysisTags(w, r, idStr, strings.TrimPrefix(tagPath, "/"))
+		return
+	}
+
 	if r.Method != http.MethodGet {
 		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
 		return
 	}
 
-	symbol := strings.TrimPrefix(r.URL.Path, "/api/analyses/")
 	if symbol == "" {
 		respondError(w, http.StatusBadRequest, SYMBOL_REQUIRED)
 		return
 	}
-	symbol = strings.ToUpper(symbol)
+	symbol = market.NormalizeSymbol(symbol)
 
 	limitStr := r.URL.Query().Get("limit")
 	limit := 20
@@ -171,6 +263,119 @@ func (s *Server) handleAnalysesForSymbol(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusOK, analyses)
 }
 
+// maxSearchResults bounds the analyses returned by a search
+const maxSearchResults = 100
+
+// handleSearchAnalyses returns the analyses whose reasoning or risks match
+// the words and "quoted phrases" of q, newest first
+func (s *Server) handleSearchAnalyses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondError(w, http.StatusBadRequest, SEARCH_QUERY_REQUIRED)
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, maxSearchResults)
+	}
+
+	analyses, err := s.db.SearchAnalyses(query, limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, analyses)
+}
+
+// handleCompareAnalyses compares two analyses of the same symbol given as
+// ids=12,57. HTMX requests get the comparison rendered side by side.
+func (s *Server) handleCompareAnalyses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	htmx := r.Header.Get("HX-Request") == "true"
+
+	fail := func(status int, message string) {
+		if htmx {
+			htmxError(w, message)
+			return
+		}
+		respondError(w, status, message)
+	}
+
+	parts := strings.Split(r.URL.Query().Get("ids"), ",")
+	if len(parts) != 2 {
+		fail(http.StatusBadRequest, INVALID_COMPARE_IDS)
+		return
+	}
+	var analyses [2]*models.AnalysisResponse
+	for i, part := range parts {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil || id <= 0 {
+			fail(http.StatusBadRequest, INVALID_COMPARE_IDS)
+			return
+		}
+		analyses[i], err = s.db.GetAnalysisResult(id)
+		if err == sql.ErrNoRows {
+			fail(http.StatusNotFound, ANALYSIS_NOT_FOUND)
+			return
+		}
+		if err != nil {
+			fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if analyses[0].ID == analyses[1].ID {
+		fail(http.StatusBadRequest, INVALID_COMPARE_IDS)
+		return
+	}
+	if analyses[0].Symbol != analyses[1].Symbol {
+		fail(http.StatusBadRequest, COMPARE_SYMBOL_MISMATCH)
+		return
+	}
+
+	cmp := analysis.CompareAnalyses(*analyses[0], *analyses[1])
+	if htmx {
+		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+		pages.AnalysisComparePartial(pages.AnalysisComparison{
+			Symbol:       cmp.Symbol,
+			Before:       comparedAnalysis(cmp.Before),
+			After:        comparedAnalysis(cmp.After),
+			RisksAdded:   cmp.RisksAdded,
+			RisksRemoved: cmp.RisksRemoved,
+		}).Render(r.Context(), w)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, cmp)
+}
+
+// comparedAnalysis converts an analysis for the comparison partial
+func comparedAnalysis(a models.AnalysisResponse) pages.ComparedAnalysis {
+	provider := a.AIProvider
+	if provider == "" {
+		provider = "AI"
+	}
+	return pages.ComparedAnalysis{
+		ID:         a.ID,
+		Action:     a.Action,
+		Confidence: a.Confidence,
+		Entry:      a.PriceTargets.Entry,
+		Target:     a.PriceTargets.Target,
+		StopLoss:   a.PriceTargets.StopLoss,
+		AIProvider: provider,
+		CreatedAt:  a.GeneratedAt,
+	}
+}
+
 // handleAnalyzeHTMX handles HTMX form submissions for stock analysis
 func (s *Server) handleAnalyzeHTMX(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -186,7 +391,7 @@ func (s *Server) handleAnalyzeHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	symbol := strings.ToUpper(strings.TrimSpace(r.FormValue("symbol")))
+	symbol := market.NormalizeSymbol(r.FormValue("symbol"))
 	userContext := r.FormValue("context")
 
 	if symbol == "" {
@@ -195,82 +400,139 @@ func (s *Server) handleAnalyzeHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get config
-	cfg, err := s.db.GetOrCreateConfig()
-	if err != nil {
-		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
-		c.ErrorMessage(FAILED_TO_GET_CONFIG).Render(ctx, w)
-		return
+	// Resolve preset overrides (submitted by the preset buttons)
+	var presetID int64
+	if presetStr := r.FormValue("preset_id"); presetStr != "" {
+		presetID, _ = strconv.ParseInt(presetStr, 10, 64)
 	}
 
-	// Get market data
-	marketAPIKey := ""
-	if cfg.MarketDataAPIKey != "" {
-		marketAPIKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
-	}
-	provider, err := market.NewProvider(cfg.MarketDataProvider, marketAPIKey)
+	result, quote, err := s.analysisService.RunWithPreset(ctx, symbol, userContext, presetID)
 	if err != nil {
 		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
-		c.ErrorMessage("Market provider error: "+err.Error()).Render(ctx, w)
+		c.ErrorMessage(err.Error()).Render(ctx, w)
 		return
 	}
 
-	quote, err := provider.GetQuote(ctx, symbol)
-	if err != nil {
-		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
-		c.ErrorMessage(FAILED_TO_GET_QUOTE+": "+err.Error()).Render(ctx, w)
-		return
-	}
+	w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+	pages.AnalysisResultCard(analysisResultCard(result, quote)).Render(ctx, w)
+}
+
+// batchAnalysisResult is the outcome of one symbol of a watchlist analysis
+type batchAnalysisResult struct {
+	Symbol   string                   `json:"symbol"`
+	Analysis *models.AnalysisResponse `json:"analysis,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+}
 
-	historical, _ := provider.GetHistoricalData(ctx, symbol, "1d")
+func newBatchAnalysisResult(r analysis.BatchResult) batchAnalysisResult {
+	result := batchAnalysisResult{Symbol: r.Symbol, Analysis: r.Analysis}
+	if r.Err != nil {
+		result.Error = r.Err.Error()
+	}
+	return result
+}
 
-	// Get AI analyzer
-	aiAPIKey := cfg.AIProviderAPIKey
-	if aiAPIKey != "" {
-		aiAPIKey, _ = config.Decrypt(aiAPIKey, s.config.EncryptionKey)
+// handleAnalyzeWatchlist analyzes every tracked symbol, a few at a time. It
+// responds with all results once done, as JSON or, for HTMX, as a results
+// table; with ?stream=1 each result is sent as a "result" server-sent event
+// as it completes, followed by a "done" event with the totals.
+func (s *Server) handleAnalyzeWatchlist(w http.ResponseWriter, r *http.Request) {
+	isHTMX := r.Header.Get("HX-Request") == "true"
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
 	}
 
-	analyzer, err := ai.NewAnalyzer(cfg.AIProvider, aiAPIKey, cfg.AIModel)
+	cfg, err := s.db.GetOrCreateConfig()
 	if err != nil {
-		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
-		c.ErrorMessage(FAILED_TO_GET_ANALYZE+": "+err.Error()).Render(ctx, w)
+		respondError(w, http.StatusInternalServerError, FAILED_TO_GET_CONFIG)
+		return
+	}
+	if len(cfg.TrackedSymbols) == 0 {
+		if isHTMX {
+			w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+			c.ErrorMessage(WATCHLIST_EMPTY).Render(r.Context(), w)
+			return
+		}
+		respondError(w, http.StatusBadRequest, WATCHLIST_EMPTY)
 		return
 	}
 
-	// Run analysis
-	analysisReq := models.AnalysisRequest{
-		Symbol:         symbol,
-		CurrentPrice:   quote.Price,
-		HistoricalData: historical,
-		RiskProfile:    cfg.RiskTolerance,
-		TradeFrequency: cfg.TradeFrequency,
-		UserContext:    userContext,
+	var send func(event string, data interface{})
+	switch r.URL.Query().Get("stream") {
+	case "1", "true":
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondError(w, http.StatusInternalServerError, STREAMING_UNSUPPORTED)
+			return
+		}
+		w.Header().Set(HEADER_CONTENT_TYPE, "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		send = func(event string, data interface{}) {
+			payload, _ := json.Marshal(data)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+			flusher.Flush()
+		}
 	}
 
-	analysisCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
+	var onResult func(analysis.BatchResult)
+	if send != nil {
+		onResult = func(result analysis.BatchResult) {
+			send("result", newBatchAnalysisResult(result))
+		}
+	}
+	results := s.analysisService.RunBatch(r.Context(), cfg.TrackedSymbols, analysis.Options{}, onResult)
+
+	response := struct {
+		Results   []batchAnalysisResult `json:"results,omitempty"`
+		Succeeded int                   `json:"succeeded"`
+		Failed    int                   `json:"failed"`
+	}{}
+	rows := make([]pages.BatchAnalysisRow, len(results))
+	for i, result := range results {
+		item := newBatchAnalysisResult(result)
+		response.Results = append(response.Results, item)
+		rows[i] = pages.BatchAnalysisRow{Symbol: result.Symbol, Error: item.Error}
+		if result.Err != nil {
+			response.Failed++
+			continue
+		}
+		response.Succeeded++
+		rows[i].Action = result.Analysis.Action
+		rows[i].Confidence = result.Analysis.Confidence
+	}
 
-	result, err := analyzer.Analyze(analysisCtx, analysisReq)
-	if err != nil {
+	switch {
+	case send != nil:
+		response.Results = nil
+		send("done", response)
+	case isHTMX:
 		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
-		c.ErrorMessage(FAILED_TO_GET_ANALYZE+": "+err.Error()).Render(ctx, w)
-		return
+		pages.BatchAnalysisPartial(rows).Render(r.Context(), w)
+	default:
+		respondJSON(w, http.StatusOK, response)
 	}
+}
 
-	// Save to database
-	s.db.SaveAnalysis(result)
-
-	// Convert to pages.AnalysisResult and render
-	analysisResult := pages.AnalysisResult{
+// analysisResultCard converts a fresh analysis and its quote for the result card
+func analysisResultCard(result *models.AnalysisResponse, quote *models.Quote) pages.AnalysisResult {
+	return pages.AnalysisResult{
+		ID:         result.ID,
 		Symbol:     result.Symbol,
 		CreatedAt:  time.Now(),
-		AIProvider: cfg.AIProvider,
+		AIProvider: result.AIProvider,
+		Feedback:   pages.AnalysisFeedback{AnalysisID: result.ID},
+		Tags:       pages.AnalysisTags{AnalysisID: result.ID},
 		Recommendation: pages.AnalysisRecommendation{
 			Action:      result.Action,
 			Confidence:  result.Confidence,
 			TargetPrice: result.PriceTargets.Target,
 			StopLoss:    result.PriceTargets.StopLoss,
 			Reasoning:   result.Reasoning,
+			StaleData:   result.StaleData,
+			AfterHours:  result.AfterHours,
 		},
 		MarketData: &pages.MarketData{
 			Price:         quote.Price,
@@ -279,9 +541,6 @@ func (s *Server) handleAnalyzeHTMX(w http.ResponseWriter, r *http.Request) {
 			MarketCap:     "-",
 		},
 	}
-
-	w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
-	pages.AnalysisResultCard(analysisResult).Render(ctx, w)
 }
 
 // formatVolume formats a volume number for display
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"stockmarket/internal/ai"
+	"stockmarket/internal/analysis"
+	"stockmarket/internal/market"
+)
+
+func TestRespondAnalysisError(t *testing.T) {
+	upstream := errors.New("upstream down")
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"unknown preset", sql.ErrNoRows, http.StatusNotFound},
+		{"consensus unavailable", analysis.ErrConsensusUnavailable, http.StatusBadRequest},
+		{"unknown provider", &analysis.ConfigError{Err: errors.New("unknown provider: nope")}, http.StatusBadRequest},
+		{"unknown symbol", fmt.Errorf("Failed to get quote: %w", &analysis.ProviderError{Kind: "market", Err: market.ErrInvalidSymbol}), http.StatusBadRequest},
+		{"missing AI key", &analysis.ProviderError{Kind: "ai", Err: ai.ErrNoAPIKey}, http.StatusBadRequest},
+		{"rate limited", fmt.Errorf("Failed to get quote: %w", &market.RateLimitError{RetryAfter: time.Second}), http.StatusTooManyRequests},
+		{"market failure", fmt.Errorf("Failed to get quote: %w", &analysis.ProviderError{Kind: "market", Err: upstream}), http.StatusBadGateway},
+		{"AI failure", fmt.Errorf("Failed to get analyze: %w", &analysis.ProviderError{Kind: "ai", Err: upstream}), http.StatusBadGateway},
+		{"database failure", errors.New("disk I/O error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			respondAnalysisError(rec, tt.err)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// maxArchiveBytes bounds the size of an archive posted to /api/import
+const maxArchiveBytes = 64 << 20
+
+// handleExport downloads the configuration, watchlist, notification
+// channels, presets, alerts, positions and analyses as a JSON archive. API
+// keys are decrypted, so the archive can be imported on a server with
+// another encryption key; it must be kept private.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	archive, err := s.db.ExportArchive(cfg.ID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for _, key := range archiveKeys(archive) {
+		if *key == "" {
+			continue
+		}
+		plain, err := config.Decrypt(*key, s.config.EncryptionKey)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, FAILED_TO_DECRYPT_API_KEY)
+			return
+		}
+		*key = plain
+	}
+
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="stockmarket-%s.json"`, archive.ExportedAt.Format("2006-01-02")))
+	respondJSON(w, http.StatusOK, archive)
+}
+
+// handleImport restores an archive written by /api/export, replacing the
+// configuration, notification channels, presets, alerts, positions,
+// analyses and tags. API keys are encrypted with this server's key.
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	var archive models.Archive
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxArchiveBytes)).Decode(&archive); err != nil {
+		respondError(w, http.StatusBadRequest, INVALID_JSON)
+		return
+	}
+	if msg := validateArchive(&archive); msg != "" {
+		respondError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	for _, key := range archiveKeys(&archive) {
+		if *key == "" {
+			continue
+		}
+		encrypted, err := config.Encrypt(*key, s.config.EncryptionKey)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, FAILED_TO_ENCRYPT_API_KEY)
+			return
+		}
+		*key = encrypted
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	counts, err := s.db.ImportArchive(cfg.ID, &archive)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"imported": counts,
+	})
+}
+
+// archiveKeys points at the API keys of an archive
+func archiveKeys(archive *models.Archive) []*string {
+	keys := []*string{
+		&archive.Config.MarketDataAPIKey,
+		&archive.Config.AIProviderAPIKey,
+		&archive.Config.FallbackAIAPIKey,
+	}
+	for i := range archive.Presets {
+		keys = append(keys, &archive.Presets[i].AIProviderAPIKey)
+	}
+	return keys
+}
+
+// validateArchive checks the version of an archive and normalizes its
+// symbols and tags; it returns an error message if the archive is invalid
+func validateArchive(archive *models.Archive) string {
+	if archive.Version < 1 || archive.Version > models.ArchiveVersion {
+		return fmt.Sprintf("Unsupported archive version %d, expected 1-%d", archive.Version, models.ArchiveVersion)
+	}
+
+	tracked := []string{}
+	for _, symbol := range archive.Config.TrackedSymbols {
+		if symbol = market.NormalizeSymbol(symbol); symbol != "" {
+			tracked = append(tracked, symbol)
+		}
+	}
+	archive.Config.TrackedSymbols = tracked
+
+	if archive.Version < 2 {
+		for i := range archive.Alerts {
+			archive.Alerts[i].Enabled = true
+		}
+	}
+
+	for i := range archive.Analyses {
+		for j, tag := range archive.Analyses[i].Tags {
+			name, ok := normalizeTagName(tag)
+			if !ok {
+				return INVALID_TAG_NAME
+			}
+			archive.Analyses[i].Tags[j] = name
+		}
+	}
+	return ""
+}
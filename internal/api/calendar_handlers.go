@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"stockmarket/internal/market"
+)
+
+// Bounds of the days parameter of the market calendar
+const (
+	defaultCalendarDays = 90
+	maxCalendarDays     = 365
+)
+
+// handleMarketCalendar reports the session, the next open and close and the
+// holidays and early closes of the next ?days= days for the NYSE, another
+// ?exchange= or the exchange of a ?symbol= such as SAP.DE
+func (s *Server) handleMarketCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	days := defaultCalendarDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxCalendarDays {
+			respondError(w, http.StatusBadRequest, INVALID_CALENDAR_DAYS)
+			return
+		}
+		days = n
+	}
+
+	exchange := market.ExchangeNYSE
+	if v := r.URL.Query().Get("exchange"); v != "" {
+		exchange = strings.ToUpper(v)
+		if !market.IsSupportedExchange(exchange) {
+			respondError(w, http.StatusBadRequest, INVALID_EXCHANGE)
+			return
+		}
+	} else if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+		cfg, err := s.db.GetOrCreateConfig()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		exchange = market.ResolveExchange(cfg.SymbolExchanges, symbol)
+	}
+
+	respondJSON(w, http.StatusOK, market.GetExchangeCalendar(exchange, time.Now(), days))
+}
@@ -1,17 +1,30 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
+	"stockmarket/internal/ai"
+	"stockmarket/internal/analysis"
 	"stockmarket/internal/config"
+	"stockmarket/internal/market"
 	"stockmarket/internal/models"
+	"stockmarket/internal/notify"
 	"stockmarket/internal/web/pages"
 )
 
+// maxInvestmentThesis caps the length of the investment thesis, in characters
+const maxInvestmentThesis = 2000
+
 // handleConfigMarket handles market data provider configuration updates
 func (s *Server) handleConfigMarket(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -37,6 +50,11 @@ func (s *Server) handleConfigMarket(w http.ResponseWriter, r *http.Request) {
 
 	// Only update API key if a new one is provided
 	if apiKey != "" {
+		if err := checkMarketKey(r.Context(), provider, apiKey); err != nil {
+			log.Printf("[CONFIG] %s rejected the new API key: %v", provider, err)
+			htmxError(w, INVALID_MARKET_API_KEY)
+			return
+		}
 		encrypted, err := config.Encrypt(apiKey, s.config.EncryptionKey)
 		if err != nil {
 			http.Error(w, FAILED_TO_ENCRYPT_API_KEY, http.StatusInternalServerError)
@@ -68,6 +86,34 @@ func (s *Server) handleConfigAI(w http.ResponseWriter, r *http.Request) {
 	provider := r.FormValue("ai_provider")
 	model := r.FormValue("ai_model")
 	apiKey := r.FormValue("ai_provider_api_key")
+	fallbackProvider := r.FormValue("fallback_ai_provider")
+	fallbackModel := r.FormValue("fallback_ai_model")
+	fallbackAPIKey := r.FormValue("fallback_ai_api_key")
+	azureEndpoint := strings.TrimSpace(r.FormValue("azure_openai_endpoint"))
+
+	if azureEndpoint != "" && !validAzureEndpoint(azureEndpoint) {
+		http.Error(w, INVALID_AZURE_ENDPOINT, http.StatusBadRequest)
+		return
+	}
+	if fallbackProvider != "" && !validAIProviders[fallbackProvider] {
+		http.Error(w, "Unknown AI provider: "+fallbackProvider, http.StatusBadRequest)
+		return
+	}
+	temperature, err := strconv.ParseFloat(r.FormValue("ai_temperature"), 64)
+	if err != nil || temperature < 0 || temperature > ai.MaxTemperature {
+		http.Error(w, INVALID_AI_TEMPERATURE, http.StatusBadRequest)
+		return
+	}
+	maxTokens, err := strconv.Atoi(r.FormValue("ai_max_tokens"))
+	if err != nil || maxTokens < ai.MinMaxTokens || maxTokens > ai.MaxMaxTokens {
+		http.Error(w, INVALID_AI_MAX_TOKENS, http.StatusBadRequest)
+		return
+	}
+	reasoningEffort := r.FormValue("ai_reasoning_effort")
+	if !ai.ValidReasoningEffort(reasoningEffort) {
+		http.Error(w, INVALID_REASONING_EFFORT, http.StatusBadRequest)
+		return
+	}
 
 	cfg, err := s.db.GetOrCreateConfig()
 	if err != nil {
@@ -77,9 +123,18 @@ func (s *Server) handleConfigAI(w http.ResponseWriter, r *http.Request) {
 
 	cfg.AIProvider = provider
 	cfg.AIModel = model
+	cfg.AzureOpenAIEndpoint = azureEndpoint
+	cfg.AITemperature = temperature
+	cfg.AIMaxTokens = maxTokens
+	cfg.AIReasoningEffort = reasoningEffort
 
 	// Only update API key if a new one is provided
 	if apiKey != "" {
+		if err := checkAIKey(r.Context(), provider, apiKey, model, azureEndpoint); err != nil {
+			log.Printf("[CONFIG] %s rejected the new API key: %v", provider, err)
+			htmxError(w, aiKeyMessage(err))
+			return
+		}
 		encrypted, err := config.Encrypt(apiKey, s.config.EncryptionKey)
 		if err != nil {
 			http.Error(w, FAILED_TO_ENCRYPT_API_KEY, http.StatusInternalServerError)
@@ -88,6 +143,25 @@ func (s *Server) handleConfigAI(w http.ResponseWriter, r *http.Request) {
 		cfg.AIProviderAPIKey = encrypted
 	}
 
+	// Fallback provider is opt-in; an empty provider disables it
+	cfg.FallbackAIProvider = fallbackProvider
+	cfg.FallbackAIModel = fallbackModel
+	if fallbackAPIKey != "" && fallbackProvider != "" {
+		if err := checkAIKey(r.Context(), fallbackProvider, fallbackAPIKey, fallbackModel, azureEndpoint); err != nil {
+			log.Printf("[CONFIG] %s rejected the new fallback API key: %v", fallbackProvider, err)
+			htmxError(w, "Fallback "+aiKeyMessage(err))
+			return
+		}
+	}
+	if fallbackAPIKey != "" {
+		encrypted, err := config.Encrypt(fallbackAPIKey, s.config.EncryptionKey)
+		if err != nil {
+			http.Error(w, FAILED_TO_ENCRYPT_API_KEY, http.StatusInternalServerError)
+			return
+		}
+		cfg.FallbackAIAPIKey = encrypted
+	}
+
 	if err := s.db.UpdateConfig(cfg); err != nil {
 		http.Error(w, FAILED_TO_UPDATE_CONFIG, http.StatusInternalServerError)
 		return
@@ -96,6 +170,56 @@ func (s *Server) handleConfigAI(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// validAzureEndpoint reports whether endpoint is an https URL with a host
+func validAzureEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// checkMarketKey quotes a symbol with a new market data API key. A provider
+// that is unreachable or rate limited cannot tell whether the key works, so
+// only other failures are returned.
+func checkMarketKey(ctx context.Context, name, apiKey string) error {
+	provider, err := market.NewProvider(name, apiKey)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, providerProbeTimeout)
+	defer cancel()
+
+	_, err = market.Probe(ctx, provider)
+	var netErr net.Error
+	if err == nil || market.Rejected(err) || errors.Is(err, market.ErrRateLimited) || errors.As(err, &netErr) {
+		return nil
+	}
+	return err
+}
+
+// checkAIKey checks a new AI API key and model with the provider's probe.
+// As for market keys, outages and rate limits are not reported.
+func checkAIKey(ctx context.Context, provider, apiKey, model, endpoint string) error {
+	analyzer, err := ai.NewAnalyzer(provider, apiKey, model, endpoint)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, providerProbeTimeout)
+	defer cancel()
+
+	_, err = ai.Probe(ctx, analyzer)
+	if err == nil || errors.Is(err, ai.ErrRateLimited) || errors.Is(err, ai.ErrProviderUnavailable) {
+		return nil
+	}
+	return err
+}
+
+// aiKeyMessage describes a failed AI key check to the user
+func aiKeyMessage(err error) string {
+	if errors.Is(err, ai.ErrInvalidAPIKey) {
+		return INVALID_AI_API_KEY
+	}
+	return AI_KEY_CHECK_FAILED
+}
+
 // handleConfigStrategy handles trading strategy configuration updates
 func (s *Server) handleConfigStrategy(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -120,6 +244,59 @@ func (s *Server) handleConfigStrategy(w http.ResponseWriter, r *http.Request) {
 	cfg.RiskTolerance = riskTolerance
 	cfg.TradeFrequency = tradeFrequency
 
+	// The thesis can be cleared, so it is updated whenever the form has it
+	if _, ok := r.Form["investment_thesis"]; ok {
+		thesis := strings.TrimSpace(r.FormValue("investment_thesis"))
+		if utf8.RuneCountInString(thesis) > maxInvestmentThesis {
+			http.Error(w, INVALID_INVESTMENT_THESIS, http.StatusBadRequest)
+			return
+		}
+		cfg.InvestmentThesis = thesis
+	}
+
+	if minConfidence := r.FormValue("min_store_confidence"); minConfidence != "" {
+		value, err := strconv.ParseFloat(minConfidence, 64)
+		if err != nil || value < 0 || value > 1 {
+			http.Error(w, INVALID_MIN_STORE_CONFIDENCE, http.StatusBadRequest)
+			return
+		}
+		cfg.MinStoreConfidence = value
+	}
+
+	if staleMinutes := r.FormValue("stale_quote_minutes"); staleMinutes != "" {
+		value, err := strconv.Atoi(staleMinutes)
+		if err != nil || value < 1 || value > 1440 {
+			http.Error(w, INVALID_STALE_QUOTE_MINUTES, http.StatusBadRequest)
+			return
+		}
+		cfg.StaleQuoteMinutes = value
+	}
+
+	if extendedHours := r.FormValue("extended_hours_alerts"); extendedHours != "" {
+		cfg.ExtendedHoursAlerts = extendedHours == "true"
+	}
+
+	if includeNews := r.FormValue("include_news"); includeNews != "" {
+		cfg.IncludeNews = includeNews == "true"
+	}
+
+	if currency := r.FormValue("display_currency"); currency != "" {
+		if !market.IsSupportedCurrency(currency) {
+			http.Error(w, INVALID_CURRENCY, http.StatusBadRequest)
+			return
+		}
+		cfg.DisplayCurrency = currency
+	}
+
+	if benchmark := r.FormValue("benchmark_symbol"); benchmark != "" {
+		benchmark = market.NormalizeSymbol(benchmark)
+		if !symbolPattern.MatchString(benchmark) {
+			http.Error(w, INVALID_BENCHMARK, http.StatusBadRequest)
+			return
+		}
+		cfg.BenchmarkSymbol = benchmark
+	}
+
 	if err := s.db.UpdateConfig(cfg); err != nil {
 		http.Error(w, FAILED_TO_UPDATE_CONFIG, http.StatusInternalServerError)
 		return
@@ -128,8 +305,12 @@ func (s *Server) handleConfigStrategy(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// handleConfigWatchlist handles watchlist updates (adding symbols)
+// handleConfigWatchlist handles watchlist updates (adding symbols, or replacing the list via PUT)
 func (s *Server) handleConfigWatchlist(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		s.handleReplaceWatchlist(w, r)
+		return
+	}
 	if r.Method != http.MethodPost {
 		http.Error(w, METHOD_NOT_ALLOWED, http.StatusMethodNotAllowed)
 		return
@@ -140,7 +321,7 @@ func (s *Server) handleConfigWatchlist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	symbol := strings.ToUpper(strings.TrimSpace(r.FormValue("symbol")))
+	symbol := market.NormalizeSymbol(r.FormValue("symbol"))
 
 	if symbol == "" {
 		http.Error(w, "Symbol is required", http.StatusBadRequest)
@@ -157,7 +338,7 @@ func (s *Server) handleConfigWatchlist(w http.ResponseWriter, r *http.Request) {
 	for _, existing := range cfg.TrackedSymbols {
 		if existing == symbol {
 			// Already exists, just return the list
-			s.renderWatchlistSettings(w, r, cfg.TrackedSymbols)
+			s.renderWatchlistSettings(w, r, cfg)
 			return
 		}
 	}
@@ -169,19 +350,20 @@ func (s *Server) handleConfigWatchlist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.renderWatchlistSettings(w, r, cfg.TrackedSymbols)
+	s.renderWatchlistSettings(w, r, cfg)
 }
 
-// handleConfigWatchlistSymbol handles individual symbol deletion
+// handleConfigWatchlistSymbol removes a symbol (DELETE) or sets the exchange
+// whose hours apply to it (PUT with an exchange form value)
 func (s *Server) handleConfigWatchlistSymbol(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
+	if r.Method != http.MethodDelete && r.Method != http.MethodPut {
 		http.Error(w, METHOD_NOT_ALLOWED, http.StatusMethodNotAllowed)
 		return
 	}
 
 	// Extract symbol from URL path
 	path := strings.TrimPrefix(r.URL.Path, "/api/config/watchlist/")
-	symbol := strings.ToUpper(strings.TrimSpace(path))
+	symbol := market.NormalizeSymbol(path)
 
 	if symbol == "" {
 		http.Error(w, SYMBOL_REQUIRED, http.StatusBadRequest)
@@ -194,6 +376,11 @@ func (s *Server) handleConfigWatchlistSymbol(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if r.Method == http.MethodPut {
+		s.updateSymbolExchange(w, r, cfg, symbol)
+		return
+	}
+
 	// Remove symbol from tracked list
 	newSymbols := []string{}
 	for _, s := range cfg.TrackedSymbols {
@@ -203,19 +390,54 @@ func (s *Server) handleConfigWatchlistSymbol(w http.ResponseWriter, r *http.Requ
 	}
 
 	cfg.TrackedSymbols = newSymbols
+	delete(cfg.SymbolExchanges, symbol)
+
+	if err := s.db.UpdateConfig(cfg); err != nil {
+		http.Error(w, FAILED_TO_UPDATE_CONFIG, http.StatusInternalServerError)
+		return
+	}
+
+	s.renderWatchlistSettings(w, r, cfg)
+}
+
+// updateSymbolExchange stores the exchange of a tracked symbol; choosing the
+// exchange inferred from the symbol drops the override
+func (s *Server) updateSymbolExchange(w http.ResponseWriter, r *http.Request, cfg *models.UserConfig, symbol string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, INVALID_FORM_DATA, http.StatusBadRequest)
+		return
+	}
+	exchange := strings.ToUpper(strings.TrimSpace(r.FormValue("exchange")))
+	if !market.IsSupportedExchange(exchange) {
+		http.Error(w, INVALID_EXCHANGE, http.StatusBadRequest)
+		return
+	}
+
+	if cfg.SymbolExchanges == nil {
+		cfg.SymbolExchanges = map[string]string{}
+	}
+	if exchange == market.SymbolExchange(symbol) {
+		delete(cfg.SymbolExchanges, symbol)
+	} else {
+		cfg.SymbolExchanges[symbol] = exchange
+	}
 
 	if err := s.db.UpdateConfig(cfg); err != nil {
 		http.Error(w, FAILED_TO_UPDATE_CONFIG, http.StatusInternalServerError)
 		return
 	}
 
-	s.renderWatchlistSettings(w, r, cfg.TrackedSymbols)
+	s.renderWatchlistSettings(w, r, cfg)
 }
 
 // renderWatchlistSettings renders the watchlist items using templ
-func (s *Server) renderWatchlistSettings(w http.ResponseWriter, r *http.Request, symbols []string) {
+func (s *Server) renderWatchlistSettings(w http.ResponseWriter, r *http.Request, cfg *models.UserConfig) {
+	entries := make([]pages.WatchlistEntry, len(cfg.TrackedSymbols))
+	for i, symbol := range cfg.TrackedSymbols {
+		entries[i] = pages.WatchlistEntry{Symbol: symbol, Exchange: market.ResolveExchange(cfg.SymbolExchanges, symbol)}
+	}
 	w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
-	pages.WatchlistSettingsItemsPartial(symbols).Render(r.Context(), w)
+	pages.WatchlistSettingsItemsPartial(entries, market.Exchanges).Render(r.Context(), w)
 }
 
 // handleConfigPolling handles polling interval configuration
@@ -253,6 +475,84 @@ func (s *Server) handleConfigPolling(w http.ResponseWriter, r *http.Request) {
 	htmxSuccess(w, "Polling interval updated successfully")
 }
 
+// handleConfigSchedule handles scheduled analysis settings updates
+func (s *Server) handleConfigSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, METHOD_NOT_ALLOWED, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, INVALID_FORM_DATA, http.StatusBadRequest)
+		return
+	}
+
+	schedule := r.FormValue("analysis_schedule")
+	if !analysis.ValidSchedule(schedule) {
+		http.Error(w, INVALID_ANALYSIS_SCHEDULE, http.StatusBadRequest)
+		return
+	}
+	scheduleTime := r.FormValue("analysis_schedule_time")
+	if _, _, err := analysis.ParseScheduleTime(scheduleTime); err != nil {
+		http.Error(w, INVALID_ANALYSIS_SCHEDULE_TIME, http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		http.Error(w, FAILED_TO_GET_CONFIG, http.StatusInternalServerError)
+		return
+	}
+
+	cfg.AnalysisSchedule = schedule
+	cfg.AnalysisScheduleTime = scheduleTime
+
+	if err := s.db.UpdateConfig(cfg); err != nil {
+		htmxError(w, FAILED_TO_UPDATE_CONFIG)
+		return
+	}
+
+	htmxSuccess(w, "Analysis schedule updated successfully")
+}
+
+// handleConfigRetention handles data retention settings updates
+func (s *Server) handleConfigRetention(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, METHOD_NOT_ALLOWED, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, INVALID_FORM_DATA, http.StatusBadRequest)
+		return
+	}
+
+	var days [3]int
+	for i, name := range []string{"analysis_retention_days", "alert_retention_days", "notification_retention_days"} {
+		value, err := strconv.Atoi(r.FormValue(name))
+		if err != nil || !validRetentionDays(value) {
+			http.Error(w, INVALID_RETENTION_DAYS, http.StatusBadRequest)
+			return
+		}
+		days[i] = value
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		http.Error(w, FAILED_TO_GET_CONFIG, http.StatusInternalServerError)
+		return
+	}
+
+	cfg.Retention = models.RetentionPolicy{AnalysisDays: days[0], AlertDays: days[1], NotificationDays: days[2]}
+
+	if err := s.db.UpdateConfig(cfg); err != nil {
+		htmxError(w, FAILED_TO_UPDATE_CONFIG)
+		return
+	}
+
+	htmxSuccess(w, "Data retention updated successfully")
+}
+
 // handleConfigNotifications handles notification settings updates
 func (s *Server) handleConfigNotifications(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -271,22 +571,43 @@ func (s *Server) handleConfigNotifications(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Message templates, template_<channel type>
+	for key, values := range r.Form {
+		channelType, ok := strings.CutPrefix(key, "template_")
+		if !ok || strings.TrimSpace(values[0]) == "" {
+			continue
+		}
+		if err := notify.CheckTemplate(values[0]); err != nil {
+			htmxError(w, fmt.Sprintf("%s for %s: %v", INVALID_NOTIFICATION_TEMPLATE, channelType, err))
+			return
+		}
+	}
+
 	var updateErrors []string
 
 	// Handle email
 	emailAddr := r.FormValue("email_address")
 	emailEnabled := r.FormValue("email_enabled") == "on"
 	if emailAddr != "" || emailEnabled {
-		if err := s.updateNotificationChannel(cfg.ID, "email", emailAddr, emailEnabled); err != nil {
+		if err := s.updateNotificationChannel(cfg, r.Form, "email", emailAddr, emailEnabled); err != nil {
 			updateErrors = append(updateErrors, "email")
 		}
 	}
 
+	// Handle SMTP email
+	smtpAddr := strings.TrimSpace(r.FormValue("smtp_address"))
+	smtpEnabled := r.FormValue("smtp_enabled") == "on"
+	if smtpAddr != "" || smtpEnabled {
+		if err := s.updateNotificationChannel(cfg, r.Form, "smtp", smtpAddr, smtpEnabled); err != nil {
+			updateErrors = append(updateErrors, "smtp")
+		}
+	}
+
 	// Handle discord
 	discordWebhook := r.FormValue("discord_webhook")
 	discordEnabled := r.FormValue("discord_enabled") == "on"
 	if discordWebhook != "" || discordEnabled {
-		if err := s.updateNotificationChannel(cfg.ID, "discord", discordWebhook, discordEnabled); err != nil {
+		if err := s.updateNotificationChannel(cfg, r.Form, "discord", discordWebhook, discordEnabled); err != nil {
 			updateErrors = append(updateErrors, "discord")
 		}
 	}
@@ -295,11 +616,61 @@ func (s *Server) handleConfigNotifications(w http.ResponseWriter, r *http.Reques
 	smsPhone := r.FormValue("sms_phone")
 	smsEnabled := r.FormValue("sms_enabled") == "on"
 	if smsPhone != "" || smsEnabled {
-		if err := s.updateNotificationChannel(cfg.ID, "sms", smsPhone, smsEnabled); err != nil {
+		if err := s.updateNotificationChannel(cfg, r.Form, "sms", smsPhone, smsEnabled); err != nil {
 			updateErrors = append(updateErrors, "sms")
 		}
 	}
 
+	// Handle Pushover
+	pushoverUserKey := strings.TrimSpace(r.FormValue("pushover_user_key"))
+	pushoverEnabled := r.FormValue("pushover_enabled") == "on"
+	if pushoverUserKey != "" || pushoverEnabled {
+		if err := s.updateNotificationChannel(cfg, r.Form, "pushover", pushoverUserKey, pushoverEnabled); err != nil {
+			updateErrors = append(updateErrors, "pushover")
+		}
+	}
+
+	// Handle webhook
+	webhookURL := strings.TrimSpace(r.FormValue("webhook_url"))
+	webhookEnabled := r.FormValue("webhook_enabled") == "on"
+	if webhookURL != "" {
+		if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			htmxError(w, INVALID_WEBHOOK_URL)
+			return
+		}
+	}
+	if webhookURL != "" || webhookEnabled {
+		if err := s.updateNotificationChannel(cfg, r.Form, "webhook", webhookURL, webhookEnabled); err != nil {
+			updateErrors = append(updateErrors, "webhook")
+		}
+	}
+
+	// Handle Matrix
+	matrixRoomID := strings.TrimSpace(r.FormValue("matrix_room_id"))
+	matrixEnabled := r.FormValue("matrix_enabled") == "on"
+	if matrixRoomID != "" || matrixEnabled {
+		if err := s.updateNotificationChannel(cfg, r.Form, "matrix", matrixRoomID, matrixEnabled); err != nil {
+			updateErrors = append(updateErrors, "matrix")
+		}
+	}
+
+	// Handle browser push; the subscribed browsers are the targets
+	webPushEnabled := r.FormValue("webpush_enabled") == "on"
+	if webPushEnabled || hasNotificationChannel(cfg, "webpush") {
+		if err := s.updateNotificationChannel(cfg, r.Form, "webpush", "", webPushEnabled); err != nil {
+			updateErrors = append(updateErrors, "webpush")
+		}
+	}
+
+	// Handle FCM; without a device token every registered device is notified
+	fcmDeviceToken := strings.TrimSpace(r.FormValue("fcm_device_token"))
+	fcmEnabled := r.FormValue("fcm_enabled") == "on"
+	if fcmDeviceToken != "" || fcmEnabled || hasNotificationChannel(cfg, "fcm") {
+		if err := s.updateNotificationChannel(cfg, r.Form, "fcm", fcmDeviceToken, fcmEnabled); err != nil {
+			updateErrors = append(updateErrors, "fcm")
+		}
+	}
+
 	if len(updateErrors) > 0 {
 		htmxError(w, fmt.Sprintf("Failed to update: %s", strings.Join(updateErrors, ", ")))
 		return
@@ -308,17 +679,44 @@ func (s *Server) handleConfigNotifications(w http.ResponseWriter, r *http.Reques
 	htmxSuccess(w, "Notification settings saved")
 }
 
-// updateNotificationChannel is a helper for updating individual notification channels
-func (s *Server) updateNotificationChannel(configID int64, channelType, target string, enabled bool) error {
+// defaultChannelEvents are the events a channel set up in Settings notifies
+var defaultChannelEvents = []string{"buy_signal", "sell_signal", "price_alert"}
+
+// updateNotificationChannel is a helper for updating individual notification
+// channels; the config's channel of the type is updated, keeping its events.
+// Its digest mode and message template are read from the settings form.
+func (s *Server) updateNotificationChannel(cfg *models.UserConfig, form url.Values, channelType, target string, enabled bool) error {
 	ch := &models.NotificationConfig{
-		Type:    channelType,
-		Target:  target,
-		Enabled: enabled,
+		Type:     channelType,
+		Target:   target,
+		Enabled:  enabled,
+		Events:   defaultChannelEvents,
+		Digest:   slices.Contains(form["digest_channels"], channelType),
+		Template: strings.TrimSpace(form.Get("template_" + channelType)),
+	}
+	for _, existing := range cfg.NotificationChannels {
+		if existing.Type == channelType {
+			ch.ID = existing.ID
+			if existing.Events != nil {
+				ch.Events = existing.Events
+			}
+			break
+		}
 	}
 
-	if err := s.db.SaveNotificationChannel(configID, ch); err != nil {
+	if err := s.db.SaveNotificationChannel(cfg.ID, ch); err != nil {
 		log.Printf("Failed to update notification channel %s: %v", channelType, err)
 		return err
 	}
 	return nil
 }
+
+// hasNotificationChannel reports whether the config has a channel of a type
+func hasNotificationChannel(cfg *models.UserConfig, channelType string) bool {
+	for _, ch := range cfg.NotificationChannels {
+		if ch.Type == channelType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"stockmarket/internal/db"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// demoSymbols is the watchlist used for demo data
+var demoSymbols = []string{"AAPL", "MSFT", "GOOGL", "AMZN", "NVDA", "META", "TSLA", "JPM", "V", "KO"}
+
+// handleSeedDemo populates the database with a deterministic demo dataset
+func (s *Server) handleSeedDemo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	if s.config.Environment != "development" {
+		respondError(w, http.StatusForbidden, DEMO_DATA_DEVELOPMENT_ONLY)
+		return
+	}
+
+	input := struct {
+		Seed int64 `json:"seed"`
+	}{Seed: market.DefaultDemoSeed}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	data, err := generateDemoData(r.Context(), input.Seed, time.Now())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	counts, err := s.db.SeedDemoData(cfg.ID, data)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"seed":    input.Seed,
+		"created": counts,
+	})
+}
+
+// handleClearDemo removes all demo data
+func (s *Server) handleClearDemo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	if s.config.Environment != "development" {
+		respondError(w, http.StatusForbidden, DEMO_DATA_DEVELOPMENT_ONLY)
+		return
+	}
+
+	counts, err := s.db.ClearDemoData()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"removed": counts,
+	})
+}
+
+// generateDemoData builds a reproducible demo dataset from seed, with dates relative to now
+func generateDemoData(ctx context.Context, seed int64, now time.Time) (*db.DemoDataset, error) {
+	provider := market.NewDemo(seed)
+	r := rand.New(rand.NewSource(seed))
+	data := &db.DemoDataset{Symbols: demoSymbols}
+
+	history := make(map[string][]models.Candle, len(demoSymbols))
+	for _, symbol := range demoSymbols {
+		candles, err := provider.GetHistoricalData(ctx, symbol, "3m")
+		if err != nil {
+			return nil, err
+		}
+		// Keep the last 60 trading days
+		history[symbol] = candles[len(candles)-60:]
+	}
+
+	actions := []string{"BUY", "SELL", "HOLD", "WATCH"}
+	timeframes := []string{"1-2 weeks", "2-4 weeks", "1-3 months"}
+	reasons := map[string]string{
+		"BUY":   "%s is trending above its 20-day average on rising volume, with momentum supporting a move toward resistance.",
+		"SELL":  "%s has lost support after a failed breakout; momentum is fading and risk/reward favors reducing exposure.",
+		"HOLD":  "%s is consolidating in a tight range. No clear edge until price breaks out of the current channel.",
+		"WATCH": "%s is approaching a key level. Wait for confirmation before opening a position.",
+	}
+	highlights := map[string][]string{
+		"BUY":   {"Trading above the 20-day average", "Volume rising on up days"},
+		"SELL":  {"Failed breakout lost support", "Momentum fading"},
+		"HOLD":  {"Consolidating in a tight range", "No clear edge yet"},
+		"WATCH": {"Approaching a key level", "Wait for confirmation"},
+	}
+
+	for i := 0; i < 25; i++ {
+		symbol := demoSymbols[r.Intn(len(demoSymbols))]
+		candles := history[symbol]
+		day := r.Intn(len(candles))
+		price := candles[day].Close
+		action := actions[r.Intn(len(actions))]
+		confidence := math.Round((0.45+r.Float64()*0.5)*100) / 100
+
+		targets := models.PriceTargets{Entry: price, Target: roundCents(price * 1.08), StopLoss: roundCents(price * 0.95)}
+		if action == "SELL" {
+			targets = models.PriceTargets{Entry: price, Target: roundCents(price * 0.92), StopLoss: roundCents(price * 1.05)}
+		}
+
+		data.Analyses = append(data.Analyses, models.AnalysisResponse{
+			Symbol:       symbol,
+			Action:       action,
+			Confidence:   confidence,
+			Reasoning:    fmt.Sprintf(reasons[action], symbol),
+			Highlights:   highlights[action],
+			PriceTargets: targets,
+			Risks:        []string{"Broader market volatility", "Upcoming earnings report"},
+			Timeframe:    timeframes[r.Intn(len(timeframes))],
+			GeneratedAt:  candles[day].Timestamp.Add(-time.Duration(r.Intn(6)) * time.Hour),
+		})
+
+		if (action == "BUY" || action == "SELL") && confidence >= 0.7 {
+			data.Notifications = append(data.Notifications, models.Notification{
+				Type:     strings.ToLower(action) + "_signal",
+				Title:    fmt.Sprintf("%s Signal: %s", action, symbol),
+				Message:  fmt.Sprintf(reasons[action], symbol),
+				Symbol:   symbol,
+				Channels: []string{"email"},
+				SentAt:   candles[day].Timestamp,
+			})
+		}
+	}
+
+	for i, symbol := range demoSymbols[:6] {
+		candles := history[symbol]
+		price := candles[len(candles)-1].Close
+		alert := models.PriceAlert{
+			Symbol:    symbol,
+			Condition: "above",
+			Price:     roundCents(price * 1.05),
+			CreatedAt: now.AddDate(0, 0, -r.Intn(30)),
+		}
+		if i%2 == 1 {
+			alert.Condition = "below"
+			alert.Price = roundCents(price * 0.95)
+		}
+		// The last two alerts have already fired
+		if i >= 4 {
+			alert.Triggered = true
+			alert.Price = candles[len(candles)-10].Close
+			data.Notifications = append(data.Notifications, models.Notification{
+				Type:     "price_alert",
+				Title:    fmt.Sprintf(PRICE_ALERT, symbol),
+				Message:  fmt.Sprintf("%s is now $%.2f (%s $%.2f)", symbol, candles[len(candles)-5].Close, alert.Condition, alert.Price),
+				Symbol:   symbol,
+				Channels: []string{"discord"},
+				SentAt:   candles[len(candles)-5].Timestamp,
+			})
+		}
+		data.Alerts = append(data.Alerts, alert)
+	}
+
+	return data, nil
+}
+
+// roundCents rounds a price to two decimal places
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
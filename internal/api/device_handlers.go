@@ -0,0 +1,83 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"stockmarket/internal/models"
+)
+
+// devicePlatforms are the platforms a device can register from
+var devicePlatforms = []string{"android", "ios", "web"}
+
+// Device token and name limits, in characters
+const (
+	maxDeviceToken = 4096
+	maxDeviceName  = 64
+)
+
+// handleDevices lists (GET) or registers (POST) the devices fcm
+// notifications are sent to
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		devices, err := s.db.GetDevices()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, devices)
+
+	case http.MethodPost:
+		var device models.Device
+		if err := json.NewDecoder(r.Body).Decode(&device); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+		device.Token = strings.TrimSpace(device.Token)
+		device.Platform = strings.ToLower(strings.TrimSpace(device.Platform))
+		device.Name = strings.TrimSpace(device.Name)
+		if device.Token == "" || len(device.Token) > maxDeviceToken ||
+			!slices.Contains(devicePlatforms, device.Platform) || utf8.RuneCountInString(device.Name) > maxDeviceName {
+			respondError(w, http.StatusBadRequest, INVALID_DEVICE)
+			return
+		}
+
+		if err := s.db.SaveDevice(&device); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusCreated, device)
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+	}
+}
+
+// handleDevice unregisters (DELETE) a device
+func (s *Server) handleDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/devices/"), 10, 64)
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, INVALID_DEVICE_ID)
+		return
+	}
+
+	if err := s.db.DeleteDeviceByID(id); err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, DEVICE_NOT_FOUND)
+		return
+	} else if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+	"stockmarket/internal/notify"
+	"stockmarket/internal/scheduler"
+)
+
+// digestWindow is the stretch of time the daily digest covers, up to when it
+// is sent
+const digestWindow = 24 * time.Hour
+
+// digestMaxItems bounds the signals and alerts listed in the digest, the
+// latest kept; digestMovers is how many watchlist symbols it lists, biggest
+// moves first
+const (
+	digestMaxItems = 20
+	digestMovers   = 5
+)
+
+// DigestJob sends the daily digest to the digest channels after each trading
+// day's close. A missed digest is not caught up: it would repeat most of the
+// next one.
+func (s *Server) DigestJob() scheduler.Job {
+	return scheduler.Job{
+		Name:     "daily_digest",
+		Hour:     17,
+		Minute:   0,
+		Location: market.ExchangeLocation(),
+		Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Run: func(ctx context.Context) error {
+			return s.sendDigest(ctx, time.Now())
+		},
+	}
+}
+
+// sendDigest composes the digest of the day up to now and sends it to the
+// enabled digest channels, if there are any
+func (s *Server) sendDigest(ctx context.Context, now time.Time) error {
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(cfg.NotificationChannels, func(ch models.NotificationConfig) bool {
+		return ch.Enabled && ch.Digest
+	}) {
+		return nil
+	}
+
+	digest, err := s.buildDigest(ctx, cfg, now)
+	if err != nil {
+		return err
+	}
+	sent, errs := s.notifyService.Deliver(notify.ComposeDigest(*digest), cfg.NotificationChannels)
+	log.Printf("[DIGEST] Sent %d signals, %d alerts and %d movers to %v, %d failed",
+		len(digest.Signals), len(digest.Alerts), len(digest.Movers), sent, len(errs))
+	return nil
+}
+
+// buildDigest gathers the high-confidence signals and triggered alerts of the
+// digest window and the biggest watchlist moves of the day
+func (s *Server) buildDigest(ctx context.Context, cfg *models.UserConfig, now time.Time) (*models.Digest, error) {
+	loc := market.ExchangeLocation()
+	since := now.Add(-digestWindow)
+	digest := &models.Digest{Date: now.In(loc)}
+
+	analyses, err := s.db.GetSignalsSince(since, signalConfidence)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range analyses {
+		summary := ""
+		if highlights := models.HighlightsOrTruncated(a.Highlights, a.Reasoning, notificationSummaryLength); len(highlights) > 0 {
+			summary = highlights[0]
+		}
+		digest.Signals = append(digest.Signals, models.DigestSignal{
+			Symbol:     a.Symbol,
+			Action:     a.Action,
+			Confidence: a.Confidence,
+			Summary:    summary,
+			At:         a.GeneratedAt.In(loc),
+		})
+	}
+	digest.Signals = lastN(digest.Signals, digestMaxItems)
+
+	alerts, err := s.db.GetAlertTriggersSince(since)
+	if err != nil {
+		return nil, err
+	}
+	for i := range alerts {
+		alerts[i].At = alerts[i].At.In(loc)
+	}
+	digest.Alerts = lastN(alerts, digestMaxItems)
+
+	digest.Movers = s.watchlistMovers(ctx, cfg)
+	return digest, nil
+}
+
+// watchlistMovers quotes the tracked symbols and returns those that moved
+// most, by absolute change; symbols that cannot be quoted are left out
+func (s *Server) watchlistMovers(ctx context.Context, cfg *models.UserConfig) []models.DigestMover {
+	if len(cfg.TrackedSymbols) == 0 {
+		return nil
+	}
+	apiKey := ""
+	if cfg.MarketDataAPIKey != "" {
+		apiKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
+	}
+	provider, err := market.NewProvider(cfg.MarketDataProvider, apiKey)
+	if err != nil {
+		log.Printf("[DIGEST] No market data for watchlist movers: %v", err)
+		return nil
+	}
+
+	var movers []models.DigestMover
+	for _, symbol := range cfg.TrackedSymbols {
+		quote, err := provider.GetQuote(ctx, symbol)
+		if err != nil || quote == nil || quote.Price <= 0 {
+			log.Printf("[DIGEST] Failed to quote %s: %v", symbol, err)
+			continue
+		}
+		movers = append(movers, models.DigestMover{
+			Symbol:        symbol,
+			Price:         quote.Price,
+			ChangePercent: quote.ChangePercent,
+		})
+	}
+	slices.SortFunc(movers, func(a, b models.DigestMover) int {
+		return cmp.Compare(math.Abs(b.ChangePercent), math.Abs(a.ChangePercent))
+	})
+	return movers[:min(len(movers), digestMovers)]
+}
+
+// lastN returns the last n items of s
+func lastN[T any](s []T, n int) []T {
+	return s[max(0, len(s)-n):]
+}
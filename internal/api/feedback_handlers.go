@@ -0,0 +1,180 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stockmarket/internal/models"
+	c "stockmarket/internal/web/components"
+	"stockmarket/internal/web/pages"
+)
+
+// maxFeedbackNoteLength bounds the free-text note stored with a rating
+const maxFeedbackNoteLength = 500
+
+// handleAnalysisFeedback records a thumbs up/down rating with an optional note.
+// HTMX requests post form values and get the updated buttons back; other
+// clients post JSON.
+func (s *Server) handleAnalysisFeedback(w http.ResponseWriter, r *http.Request, idStr string) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	htmx := r.Header.Get("HX-Request") == "true"
+
+	fail := func(status int, message string) {
+		if htmx {
+			htmxError(w, message)
+			return
+		}
+		respondError(w, status, message)
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		fail(http.StatusBadRequest, INVALID_ANALYSIS_ID)
+		return
+	}
+
+	var input struct {
+		Rating *int   `json:"rating"`
+		Note   string `json:"note"`
+	}
+	withNote := false
+	if htmx {
+		if err := r.ParseForm(); err != nil {
+			fail(http.StatusBadRequest, INVALID_FORM_DATA)
+			return
+		}
+		if rating, err := strconv.Atoi(r.FormValue("rating")); err == nil {
+			input.Rating = &rating
+		}
+		input.Note = r.FormValue("note")
+		_, withNote = r.Form["note"]
+	} else if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		fail(http.StatusBadRequest, INVALID_JSON)
+		return
+	}
+
+	if input.Rating == nil || *input.Rating < -1 || *input.Rating > 1 {
+		fail(http.StatusBadRequest, INVALID_RATING)
+		return
+	}
+	note := strings.TrimSpace(input.Note)
+	if len(note) > maxFeedbackNoteLength {
+		fail(http.StatusBadRequest, "Note must be at most 500 characters")
+		return
+	}
+
+	feedback, err := s.db.SetAnalysisFeedback(id, *input.Rating, note)
+	if err == sql.ErrNoRows {
+		fail(http.StatusNotFound, ANALYSIS_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if htmx {
+		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+		pages.FeedbackButtons(pages.AnalysisFeedback{
+			AnalysisID: id,
+			Rating:     feedback.Rating,
+			Note:       feedback.Note,
+		}, withNote).Render(r.Context(), w)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"id":       id,
+		"feedback": feedback,
+	})
+}
+
+// handlePerformance reports per-provider feedback statistics
+func (s *Server) handlePerformance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	providers, err := s.db.GetProviderPerformance()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"providers": providers,
+	})
+}
+
+// handleCalibration resolves the outcomes of past analyses and reports the
+// realized hit rate per confidence bucket and per AI provider. HTMX requests
+// get the recommendations page table.
+func (s *Server) handleCalibration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	htmx := r.Header.Get("HX-Request") == "true"
+
+	report, err := s.analysisService.Calibration(r.Context())
+	if err != nil {
+		if htmx {
+			w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+			c.ErrorMessage(err.Error()).Render(r.Context(), w)
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if htmx {
+		cal := pages.Calibration{
+			Resolved: report.Resolved,
+			Pending:  report.Pending,
+			Buckets:  calibrationBuckets(report.Buckets),
+		}
+		for _, p := range report.Providers {
+			cal.Providers = append(cal.Providers, pages.ProviderCalibration{
+				Provider: p.Provider,
+				Resolved: p.Resolved,
+				HitRate:  deref(p.HitRate),
+				Buckets:  calibrationBuckets(p.Buckets),
+			})
+		}
+		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+		pages.CalibrationPartial(cal).Render(r.Context(), w)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// calibrationBuckets converts calibration buckets for the partial
+func calibrationBuckets(buckets []models.CalibrationBucket) []pages.CalibrationBucket {
+	out := make([]pages.CalibrationBucket, len(buckets))
+	for i, b := range buckets {
+		out[i] = pages.CalibrationBucket{
+			Range:          b.Range,
+			Resolved:       b.Resolved,
+			Hits:           b.Hits,
+			HitRate:        deref(b.HitRate),
+			MeanConfidence: deref(b.MeanConfidence),
+		}
+	}
+	return out
+}
+
+// deref returns *f, or 0 when f is nil
+func deref(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/db"
+	"stockmarket/internal/models"
+)
+
+// newTestServer returns a server on a fresh database
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	d, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return NewServer(d, &config.Config{})
+}
+
+// postFeedback posts body to the feedback endpoint of analysis id
+func postFeedback(s *Server, id, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/analyses/"+id+"/feedback", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if contentType == "application/x-www-form-urlencoded" {
+		req.Header.Set("HX-Request", "true")
+	}
+	rec := httptest.NewRecorder()
+	s.handleAnalysesForSymbol(rec, req)
+	return rec
+}
+
+func TestAnalysisFeedback(t *testing.T) {
+	s := newTestServer(t)
+	analysis := &models.AnalysisResponse{Symbol: "AAPL", Action: "BUY", Confidence: 0.8, AIProvider: "openai"}
+	if err := s.db.SaveAnalysis(analysis); err != nil {
+		t.Fatal(err)
+	}
+	id := analysis.ID
+	idStr := strconv.FormatInt(id, 10)
+
+	rec := postFeedback(s, idStr, "application/json", `{"rating": 1, "note": "  spot on  "}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		ID       int64           `json:"id"`
+		Feedback models.Feedback `json:"feedback"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != id || body.Feedback.Rating != 1 || body.Feedback.Note != "spot on" || body.Feedback.RatedAt == nil {
+		t.Fatalf("response = %+v", body)
+	}
+	stats, err := s.db.GetProviderPerformance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Up != 1 || stats[0].Rated != 1 {
+		t.Fatalf("stats = %+v, want one thumbs up", stats)
+	}
+
+	form := url.Values{"rating": {"-1"}, "note": {"aged badly"}}.Encode()
+	rec = postFeedback(s, idStr, "application/x-www-form-urlencoded", form)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("htmx status = %d: %s", rec.Code, rec.Body)
+	}
+	if html := rec.Body.String(); !strings.Contains(html, "feedback-controls") || !strings.Contains(html, `value="aged badly"`) {
+		t.Fatalf("htmx response = %s", html)
+	}
+	stats, err = s.db.GetProviderPerformance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[0].Up != 0 || stats[0].Down != 1 {
+		t.Fatalf("stats = %+v, want the rating replaced by a thumbs down", stats)
+	}
+}
+
+func TestAnalysisFeedbackErrors(t *testing.T) {
+	s := newTestServer(t)
+	analysis := &models.AnalysisResponse{Symbol: "AAPL", Action: "BUY", Confidence: 0.8}
+	if err := s.db.SaveAnalysis(analysis); err != nil {
+		t.Fatal(err)
+	}
+	idStr := strconv.FormatInt(analysis.ID, 10)
+
+	tests := []struct {
+		name    string
+		id      string
+		body    string
+		status  int
+		message string
+	}{
+		{"bad id", "abc", `{"rating": 1}`, http.StatusBadRequest, INVALID_ANALYSIS_ID},
+		{"unknown analysis", "999", `{"rating": 1}`, http.StatusNotFound, ANALYSIS_NOT_FOUND},
+		{"rating out of range", idStr, `{"rating": 2}`, http.StatusBadRequest, INVALID_RATING},
+		{"rating missing", idStr, `{"note": "x"}`, http.StatusBadRequest, INVALID_RATING},
+		{"invalid json", idStr, `{`, http.StatusBadRequest, INVALID_JSON},
+		{"note too long", idStr, `{"rating": 1, "note": "` + strings.Repeat("x", maxFeedbackNoteLength+1) + `"}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postFeedback(s, tt.id, "application/json", tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.message != "" && !strings.Contains(rec.Body.String(), tt.message) {
+				t.Fatalf("body = %s, want %q", rec.Body, tt.message)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/analyses/"+idStr+"/feedback", nil)
+	rec := httptest.NewRecorder()
+	s.handleAnalysesForSymbol(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET status = %d, want 405", rec.Code)
+	}
+}
@@ -5,18 +5,50 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"stockmarket/internal/ai"
+	"stockmarket/internal/analysis"
 	"stockmarket/internal/config"
+	"stockmarket/internal/market"
 	"stockmarket/internal/models"
 )
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	// A provider whose circuit is not closed is failing or being probed
+	status := "healthy"
+	breakers := market.GetCircuitBreakerStats()
+	for _, b := range breakers {
+		if b.State != market.BreakerClosed {
+			status = "degraded"
+		}
+	}
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"status": "healthy",
-		"time":   time.Now().Format(time.RFC3339),
+		"status":           status,
+		"time":             time.Now().Format(time.RFC3339),
+		"indicators":       s.indicators.Stats(),
+		"quote_cache":      market.GetQuoteCacheStats(),
+		"circuit_breakers": breakers,
 	})
 }
 
+// handleDBStats reports table row counts, database and WAL sizes, cache use
+// and connection pool stats, to tell whether the database slows the app down
+func (s *Server) handleDBStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	stats, err := s.db.Stats()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, stats)
+}
+
 // handleConfig handles configuration CRUD
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -28,31 +60,44 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Decrypt API keys for response (masked)
-		if cfg.MarketDataAPIKey != "" {
-			key, _ := config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
-			if len(key) > 4 {
-				cfg.MarketDataAPIKey = key[:4] + "****" + key[len(key)-4:]
-			}
-		}
-		if cfg.AIProviderAPIKey != "" {
-			key, _ := config.Decrypt(cfg.AIProviderAPIKey, s.config.EncryptionKey)
-			if len(key) > 4 {
-				cfg.AIProviderAPIKey = key[:4] + "****" + key[len(key)-4:]
-			}
-		}
+		cfg.MarketDataAPIKey = s.maskAPIKey(cfg.MarketDataAPIKey)
+		cfg.AIProviderAPIKey = s.maskAPIKey(cfg.AIProviderAPIKey)
+		cfg.FallbackAIAPIKey = s.maskAPIKey(cfg.FallbackAIAPIKey)
 
 		respondJSON(w, http.StatusOK, cfg)
 
 	case http.MethodPut:
 		var input struct {
-			MarketDataProvider string   `json:"market_data_provider"`
-			MarketDataAPIKey   string   `json:"market_data_api_key"`
-			AIProvider         string   `json:"ai_provider"`
-			AIProviderAPIKey   string   `json:"ai_provider_api_key"`
-			AIModel            string   `json:"ai_model"`
-			RiskTolerance      string   `json:"risk_tolerance"`
-			TradeFrequency     string   `json:"trade_frequency"`
-			TrackedSymbols     []string `json:"tracked_symbols"`
+			MarketDataProvider   string            `json:"market_data_provider"`
+			MarketDataAPIKey     string            `json:"market_data_api_key"`
+			AIProvider           string            `json:"ai_provider"`
+			AIProviderAPIKey     string            `json:"ai_provider_api_key"`
+			AIModel              string            `json:"ai_model"`
+			FallbackAIProvider   *string           `json:"fallback_ai_provider"`
+			FallbackAIModel      *string           `json:"fallback_ai_model"`
+			FallbackAIAPIKey     string            `json:"fallback_ai_api_key"`
+			AzureOpenAIEndpoint  *string           `json:"azure_openai_endpoint"`
+			RiskTolerance        string            `json:"risk_tolerance"`
+			TradeFrequency       string            `json:"trade_frequency"`
+			TrackedSymbols       []string          `json:"tracked_symbols"`
+			MinStoreConfidence   *float64          `json:"min_store_confidence"`
+			StaleQuoteMinutes    *int              `json:"stale_quote_minutes"`
+			ExtendedHoursAlerts  *bool             `json:"extended_hours_alerts"`
+			DisplayCurrency      string            `json:"display_currency"`
+			SymbolExchanges      map[string]string `json:"symbol_exchanges"`
+			BenchmarkSymbol      string            `json:"benchmark_symbol"`
+			IncludeNews          *bool             `json:"include_news"`
+			AnalysisSchedule     string            `json:"analysis_schedule"`
+			AnalysisScheduleTime string            `json:"analysis_schedule_time"`
+			AITemperature        *float64          `json:"ai_temperature"`
+			AIMaxTokens          *int              `json:"ai_max_tokens"`
+			AIReasoningEffort    *string           `json:"ai_reasoning_effort"`
+			InvestmentThesis     *string           `json:"investment_thesis"`
+			Retention            *struct {
+				AnalysisDays     *int `json:"analysis_days"`
+				AlertDays        *int `json:"alert_days"`
+				NotificationDays *int `json:"notification_days"`
+			} `json:"retention"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -84,16 +129,148 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		if input.AIModel != "" {
 			cfg.AIModel = input.AIModel
 		}
+		if input.FallbackAIProvider != nil {
+			if *input.FallbackAIProvider != "" && !validAIProviders[*input.FallbackAIProvider] {
+				respondError(w, http.StatusBadRequest, "Unknown AI provider: "+*input.FallbackAIProvider)
+				return
+			}
+			cfg.FallbackAIProvider = *input.FallbackAIProvider
+		}
+		if input.FallbackAIModel != nil {
+			cfg.FallbackAIModel = *input.FallbackAIModel
+		}
+		if input.FallbackAIAPIKey != "" && !strings.Contains(input.FallbackAIAPIKey, "****") {
+			encrypted, _ := config.Encrypt(input.FallbackAIAPIKey, s.config.EncryptionKey)
+			cfg.FallbackAIAPIKey = encrypted
+		}
+		if input.AzureOpenAIEndpoint != nil {
+			endpoint := strings.TrimSpace(*input.AzureOpenAIEndpoint)
+			if endpoint != "" && !validAzureEndpoint(endpoint) {
+				respondError(w, http.StatusBadRequest, INVALID_AZURE_ENDPOINT)
+				return
+			}
+			cfg.AzureOpenAIEndpoint = endpoint
+		}
+		if input.AITemperature != nil {
+			if *input.AITemperature < 0 || *input.AITemperature > ai.MaxTemperature {
+				respondError(w, http.StatusBadRequest, INVALID_AI_TEMPERATURE)
+				return
+			}
+			cfg.AITemperature = *input.AITemperature
+		}
+		if input.AIMaxTokens != nil {
+			if *input.AIMaxTokens < ai.MinMaxTokens || *input.AIMaxTokens > ai.MaxMaxTokens {
+				respondError(w, http.StatusBadRequest, INVALID_AI_MAX_TOKENS)
+				return
+			}
+			cfg.AIMaxTokens = *input.AIMaxTokens
+		}
+		if input.AIReasoningEffort != nil {
+			if !ai.ValidReasoningEffort(*input.AIReasoningEffort) {
+				respondError(w, http.StatusBadRequest, INVALID_REASONING_EFFORT)
+				return
+			}
+			cfg.AIReasoningEffort = *input.AIReasoningEffort
+		}
 		if input.RiskTolerance != "" {
 			cfg.RiskTolerance = input.RiskTolerance
 		}
 		if input.TradeFrequency != "" {
 			cfg.TradeFrequency = input.TradeFrequency
 		}
+		if input.InvestmentThesis != nil {
+			thesis := strings.TrimSpace(*input.InvestmentThesis)
+			if utf8.RuneCountInString(thesis) > maxInvestmentThesis {
+				respondError(w, http.StatusBadRequest, INVALID_INVESTMENT_THESIS)
+				return
+			}
+			cfg.InvestmentThesis = thesis
+		}
+		if input.Retention != nil {
+			for _, days := range []struct {
+				input *int
+				field *int
+			}{
+				{input.Retention.AnalysisDays, &cfg.Retention.AnalysisDays},
+				{input.Retention.AlertDays, &cfg.Retention.AlertDays},
+				{input.Retention.NotificationDays, &cfg.Retention.NotificationDays},
+			} {
+				if days.input == nil {
+					continue
+				}
+				if !validRetentionDays(*days.input) {
+					respondError(w, http.StatusBadRequest, INVALID_RETENTION_DAYS)
+					return
+				}
+				*days.field = *days.input
+			}
+		}
+		if input.MinStoreConfidence != nil {
+			if *input.MinStoreConfidence < 0 || *input.MinStoreConfidence > 1 {
+				respondError(w, http.StatusBadRequest, INVALID_MIN_STORE_CONFIDENCE)
+				return
+			}
+			cfg.MinStoreConfidence = *input.MinStoreConfidence
+		}
+		if input.StaleQuoteMinutes != nil {
+			if *input.StaleQuoteMinutes < 1 || *input.StaleQuoteMinutes > 1440 {
+				respondError(w, http.StatusBadRequest, INVALID_STALE_QUOTE_MINUTES)
+				return
+			}
+			cfg.StaleQuoteMinutes = *input.StaleQuoteMinutes
+		}
+		if input.ExtendedHoursAlerts != nil {
+			cfg.ExtendedHoursAlerts = *input.ExtendedHoursAlerts
+		}
+		if input.IncludeNews != nil {
+			cfg.IncludeNews = *input.IncludeNews
+		}
+		if input.AnalysisSchedule != "" {
+			if !analysis.ValidSchedule(input.AnalysisSchedule) {
+				respondError(w, http.StatusBadRequest, INVALID_ANALYSIS_SCHEDULE)
+				return
+			}
+			cfg.AnalysisSchedule = input.AnalysisSchedule
+		}
+		if input.AnalysisScheduleTime != "" {
+			if _, _, err := analysis.ParseScheduleTime(input.AnalysisScheduleTime); err != nil {
+				respondError(w, http.StatusBadRequest, INVALID_ANALYSIS_SCHEDULE_TIME)
+				return
+			}
+			cfg.AnalysisScheduleTime = input.AnalysisScheduleTime
+		}
+		if input.DisplayCurrency != "" {
+			currency := strings.ToUpper(input.DisplayCurrency)
+			if !market.IsSupportedCurrency(currency) {
+				respondError(w, http.StatusBadRequest, INVALID_CURRENCY)
+				return
+			}
+			cfg.DisplayCurrency = currency
+		}
+		if input.SymbolExchanges != nil {
+			exchanges := make(map[string]string, len(input.SymbolExchanges))
+			for symbol, exchange := range input.SymbolExchanges {
+				exchange = strings.ToUpper(exchange)
+				if !market.IsSupportedExchange(exchange) {
+					respondError(w, http.StatusBadRequest, INVALID_EXCHANGE)
+					return
+				}
+				exchanges[market.NormalizeSymbol(symbol)] = exchange
+			}
+			cfg.SymbolExchanges = exchanges
+		}
+		if input.BenchmarkSymbol != "" {
+			benchmark := market.NormalizeSymbol(input.BenchmarkSymbol)
+			if !symbolPattern.MatchString(benchmark) {
+				respondError(w, http.StatusBadRequest, INVALID_BENCHMARK)
+				return
+			}
+			cfg.BenchmarkSymbol = benchmark
+		}
 		if input.TrackedSymbols != nil {
 			// Normalize symbols to uppercase
 			for i := range input.TrackedSymbols {
-				input.TrackedSymbols[i] = strings.ToUpper(strings.TrimSpace(input.TrackedSymbols[i]))
+				input.TrackedSymbols[i] = market.NormalizeSymbol(input.TrackedSymbols[i])
 			}
 			cfg.TrackedSymbols = input.TrackedSymbols
 		}
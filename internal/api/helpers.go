@@ -2,8 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/market"
 )
 
 // respondJSON sends a JSON response
@@ -18,14 +24,59 @@ func respondError(w http.ResponseWriter, status int, message string) {
 	respondJSON(w, status, map[string]string{"error": message})
 }
 
+// respondProviderError sends a market data error: requests turned away by a
+// provider's rate limit queue get 429 with Retry-After, anything else 400
+func respondProviderError(w http.ResponseWriter, err error) {
+	var limited *market.RateLimitError
+	if errors.As(err, &limited) {
+		seconds := int(math.Ceil(limited.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		respondJSON(w, http.StatusTooManyRequests, map[string]interface{}{
+			"error":       err.Error(),
+			"retry_after": seconds,
+		})
+		return
+	}
+	var open *market.CircuitOpenError
+	if errors.As(err, &open) {
+		seconds := int(math.Ceil(open.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		respondJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error":       err.Error(),
+			"retry_after": seconds,
+		})
+		return
+	}
+	respondError(w, http.StatusBadRequest, err.Error())
+}
+
 // htmxSuccess sends a success notification via HTMX
 func htmxSuccess(w http.ResponseWriter, message string) {
-	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showToast": {"message": "%s", "type": "success"}}`, message))
+	htmxToast(w, message, "success")
 	w.WriteHeader(http.StatusOK)
 }
 
 // htmxError sends an error notification via HTMX
 func htmxError(w http.ResponseWriter, message string) {
-	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showToast": {"message": "%s", "type": "error"}}`, message))
+	htmxToast(w, message, "error")
 	w.WriteHeader(http.StatusBadRequest)
 }
+
+// htmxToast sets the HX-Trigger header showing a toast, quoting message so
+// that quotes in error messages keep the header valid JSON
+func htmxToast(w http.ResponseWriter, message, kind string) {
+	quoted, _ := json.Marshal(message)
+	w.Header().Set("HX-Trigger", fmt.Sprintf(`{"showToast": {"message": %s, "type": "%s"}}`, quoted, kind))
+}
+
+// maskAPIKey decrypts an API key and masks all but its first and last four characters
+func (s *Server) maskAPIKey(encrypted string) string {
+	if encrypted == "" {
+		return ""
+	}
+	key, _ := config.Decrypt(encrypted, s.config.EncryptionKey)
+	if len(key) > 4 {
+		return key[:4] + "****" + key[len(key)-4:]
+	}
+	return encrypted
+}
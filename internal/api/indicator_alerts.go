@@ -0,0 +1,347 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/events"
+	"stockmarket/internal/indicators"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+	"stockmarket/internal/scheduler"
+)
+
+// maxSMAPeriod bounds the moving average periods of crossover alerts
+const maxSMAPeriod = 250
+
+// crossoverLookback is how far back crossover alerts look for a crossover
+// they have not fired for yet, e.g. after a long downtime
+const crossoverLookback = 365 * 24 * time.Hour
+
+// maxRSIPeriod bounds the period of RSI alerts
+const maxRSIPeriod = 50
+
+// rsiHistoryPeriod is the daily history RSI alerts are computed over, long
+// enough for Wilder's smoothing to settle
+const rsiHistoryPeriod = "1y"
+
+// extremeHistoryPeriod is the daily history 52-week high and low alerts
+// compare the price with
+const extremeHistoryPeriod = "1y"
+
+// isRSI reports whether an alert condition is an RSI threshold
+func isRSI(condition string) bool {
+	return condition == "rsi_above" || condition == "rsi_below"
+}
+
+// isExtreme reports whether an alert condition is a new 52-week high or low
+func isExtreme(condition string) bool {
+	return condition == "high_52w" || condition == "low_52w"
+}
+
+// isCrossover reports whether an alert condition is a moving-average crossover
+func isCrossover(condition string) bool {
+	return condition == "sma_cross_above" || condition == "sma_cross_below"
+}
+
+// CrossoverJob evaluates the moving-average crossover alerts on the daily
+// closes after the US market closes on weekdays, catching up once after
+// downtime
+func (s *Server) CrossoverJob() scheduler.Job {
+	return scheduler.Job{
+		Name:     "sma_crossovers",
+		Hour:     16,
+		Minute:   30,
+		Location: market.ExchangeLocation(),
+		Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		CatchUp:  true,
+		Run: func(ctx context.Context) error {
+			return s.checkCrossoverAlerts(ctx, time.Now())
+		},
+	}
+}
+
+// checkCrossoverAlerts fires the crossover alerts whose fast moving average
+// crossed the slow one in the wanted direction on a daily close since the
+// alert was created
+func (s *Server) checkCrossoverAlerts(ctx context.Context, now time.Time) error {
+	alerts, err := s.db.GetActiveAlerts()
+	if err != nil {
+		return err
+	}
+	bySymbol := map[string][]models.PriceAlert{}
+	for _, alert := range alerts {
+		if isCrossover(alert.Condition) && alert.Enabled && !coolingDown(alert, now) {
+			bySymbol[alert.Symbol] = append(bySymbol[alert.Symbol], alert)
+		}
+	}
+	if len(bySymbol) == 0 {
+		return nil
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		return err
+	}
+	apiKey := ""
+	if cfg.MarketDataAPIKey != "" {
+		apiKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
+	}
+	provider, err := market.NewProvider(cfg.MarketDataProvider, apiKey)
+	if err != nil {
+		return err
+	}
+
+	for symbol, symbolAlerts := range bySymbol {
+		// Enough daily candles before the oldest alert to average over the
+		// slowest period, counting weekends and holidays
+		since, slowest := now, 0
+		for _, alert := range symbolAlerts {
+			if t := crossoverSince(alert, now); t.Before(since) {
+				since = t
+			}
+			slowest = max(slowest, alert.SlowPeriod)
+		}
+		from := since.AddDate(0, 0, -(slowest*3/2 + 14))
+		period := from.Format("2006-01-02") + ":" + now.Format("2006-01-02")
+
+		candles, err := provider.GetHistoricalData(ctx, symbol, period)
+		if err != nil {
+			log.Printf("[ALERTS] Failed to load history of %s for crossover alerts: %v", symbol, err)
+			continue
+		}
+
+		for _, alert := range symbolAlerts {
+			i, fast, slow, ok := findCrossover(alert, candles, crossoverSince(alert, now))
+			if !ok {
+				continue
+			}
+			if fired, err := s.fireAlert(alert); err != nil || !fired {
+				if err != nil {
+					log.Printf("[ALERTS] Failed to trigger crossover alert %d: %v", alert.ID, err)
+				}
+				continue
+			}
+
+			direction := "above"
+			if alert.Condition == "sma_cross_below" {
+				direction = "below"
+			}
+			message := fmt.Sprintf("%s: %d-day SMA ($%.2f) crossed %s the %d-day SMA ($%.2f) on %s, closing at $%.2f",
+				symbol, alert.FastPeriod, fast, direction, alert.SlowPeriod, slow,
+				candles[i].Timestamp.UTC().Format("2006-01-02"), candles[i].Close)
+			s.announceAlert(alert, candles[i].Close, message, cfg)
+		}
+	}
+	return nil
+}
+
+// crossoverSince is the earliest time a crossover fires an alert: when it
+// was created, or the day after a recurring alert last fired, but no earlier
+// than crossoverLookback
+func crossoverSince(alert models.PriceAlert, now time.Time) time.Time {
+	since := alert.CreatedAt
+	if alert.TriggeredAt != nil && alert.TriggeredAt.Add(24*time.Hour).After(since) {
+		since = alert.TriggeredAt.Add(24 * time.Hour)
+	}
+	if earliest := now.Add(-crossoverLookback); since.Before(earliest) {
+		return earliest
+	}
+	return since
+}
+
+// findCrossover finds the first daily close from the day of since on where
+// the fast moving average of an alert crossed the slow one in its direction,
+// returning the candle index and both averages there. Candles must be
+// ordered oldest first.
+func findCrossover(alert models.PriceAlert, candles []models.Candle, since time.Time) (i int, fast, slow float64, ok bool) {
+	day := since.UTC().Format("2006-01-02")
+	for i = alert.SlowPeriod; i < len(candles); i++ {
+		if candles[i].Timestamp.UTC().Format("2006-01-02") < day {
+			continue
+		}
+		prevFast, _ := indicators.SMA(candles[:i], alert.FastPeriod)
+		prevSlow, _ := indicators.SMA(candles[:i], alert.SlowPeriod)
+		fast, _ = indicators.SMA(candles[:i+1], alert.FastPeriod)
+		slow, _ = indicators.SMA(candles[:i+1], alert.SlowPeriod)
+
+		if alert.Condition == "sma_cross_above" && prevFast <= prevSlow && fast > slow {
+			return i, fast, slow, true
+		}
+		if alert.Condition == "sma_cross_below" && prevFast >= prevSlow && fast < slow {
+			return i, fast, slow, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// checkRSIAlerts fires the RSI alerts of the quoted symbol whose RSI is past
+// their level. RSI is computed from the stored daily closes, with the quoted
+// price as the close of its day.
+func (s *Server) checkRSIAlerts(ctx context.Context, provider market.Provider, quote *models.Quote, alerts []models.PriceAlert, cfg *models.UserConfig) {
+	var rsiAlerts []models.PriceAlert
+	for _, alert := range alerts {
+		if alert.Symbol == quote.Symbol && isRSI(alert.Condition) && alert.Enabled && !coolingDown(alert, time.Now()) {
+			rsiAlerts = append(rsiAlerts, alert)
+		}
+	}
+	if len(rsiAlerts) == 0 {
+		return
+	}
+
+	candles, err := provider.GetHistoricalData(ctx, quote.Symbol, rsiHistoryPeriod)
+	if err != nil || len(candles) == 0 {
+		log.Printf("[ALERTS] Failed to load history of %s for RSI alerts: %v", quote.Symbol, err)
+		return
+	}
+	candles = withLatestClose(candles, quote)
+
+	for _, alert := range rsiAlerts {
+		rsi, ok := indicators.RSI(candles, alert.Period)
+		if !ok {
+			continue
+		}
+		direction := "above"
+		if alert.Condition == "rsi_below" {
+			direction = "below"
+		}
+		if (direction == "above" && rsi <= alert.Price) || (direction == "below" && rsi >= alert.Price) {
+			continue
+		}
+
+		if fired, err := s.fireAlert(alert); err != nil || !fired {
+			if err != nil {
+				log.Printf("[ALERTS] Failed to trigger RSI alert %d: %v", alert.ID, err)
+			}
+			continue
+		}
+		message := fmt.Sprintf("%s RSI(%d) is %.1f, %s %g (price $%.2f)",
+			alert.Symbol, alert.Period, rsi, direction, alert.Price, quote.Price)
+		s.announceAlert(alert, quote.Price, message, cfg)
+	}
+}
+
+// checkExtremeAlerts fires the 52-week high and low alerts of the quoted
+// symbol whose price is past the highest high or lowest low of the daily
+// candles in the year before the quote's day
+func (s *Server) checkExtremeAlerts(ctx context.Context, provider market.Provider, quote *models.Quote, alerts []models.PriceAlert, cfg *models.UserConfig) {
+	var extremeAlerts []models.PriceAlert
+	for _, alert := range alerts {
+		if alert.Symbol == quote.Symbol && isExtreme(alert.Condition) && alert.Enabled && !coolingDown(alert, time.Now()) {
+			extremeAlerts = append(extremeAlerts, alert)
+		}
+	}
+	if len(extremeAlerts) == 0 {
+		return
+	}
+
+	candles, err := provider.GetHistoricalData(ctx, quote.Symbol, extremeHistoryPeriod)
+	if err != nil || len(candles) == 0 {
+		log.Printf("[ALERTS] Failed to load history of %s for 52-week alerts: %v", quote.Symbol, err)
+		return
+	}
+	at := quote.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	high, low, ok := yearExtremes(candles, at)
+	if !ok {
+		return
+	}
+
+	for _, alert := range extremeAlerts {
+		var message string
+		if alert.Condition == "high_52w" {
+			if quote.Price <= high.High {
+				continue
+			}
+			message = fmt.Sprintf("%s made a new 52-week high at $%.2f, above the previous high of $%.2f set on %s",
+				alert.Symbol, quote.Price, high.High, high.Timestamp.UTC().Format("2006-01-02"))
+		} else {
+			if quote.Price >= low.Low {
+				continue
+			}
+			message = fmt.Sprintf("%s made a new 52-week low at $%.2f, below the previous low of $%.2f set on %s",
+				alert.Symbol, quote.Price, low.Low, low.Timestamp.UTC().Format("2006-01-02"))
+		}
+
+		if fired, err := s.fireAlert(alert); err != nil || !fired {
+			if err != nil {
+				log.Printf("[ALERTS] Failed to trigger 52-week alert %d: %v", alert.ID, err)
+			}
+			continue
+		}
+		s.announceAlert(alert, quote.Price, message, cfg)
+	}
+}
+
+// yearExtremes finds the daily candles with the highest high and the lowest
+// low in the year before the day of at, leaving out that day's candle. ok is
+// false when there are no such candles.
+func yearExtremes(candles []models.Candle, at time.Time) (high, low models.Candle, ok bool) {
+	day := at.UTC().Format("2006-01-02")
+	from := at.UTC().AddDate(-1, 0, 0).Format("2006-01-02")
+	for _, c := range candles {
+		d := c.Timestamp.UTC().Format("2006-01-02")
+		if d < from || d >= day || c.Low <= 0 {
+			continue
+		}
+		if !ok || c.High > high.High {
+			high = c
+		}
+		if !ok || c.Low < low.Low {
+			low = c
+		}
+		ok = true
+	}
+	return high, low, ok
+}
+
+// withLatestClose returns a copy of daily candles with the quoted price as
+// the close of the quote's day, replacing that day's candle or adding one
+func withLatestClose(candles []models.Candle, quote *models.Quote) []models.Candle {
+	at := quote.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	out := append([]models.Candle{}, candles...)
+	last := &out[len(out)-1]
+	if last.Timestamp.UTC().Format("2006-01-02") == at.UTC().Format("2006-01-02") {
+		last.Close = quote.Price
+		last.High = max(last.High, quote.Price)
+		last.Low = min(last.Low, quote.Price)
+		return out
+	}
+	return append(out, models.Candle{Timestamp: at, Open: quote.Price, High: quote.Price, Low: quote.Price, Close: quote.Price})
+}
+
+// announceAlert publishes a fired alert, broadcasts it to WebSocket clients,
+// sends it to the notification channels and records it in the alert's
+// history with the channels reached. The alert's label and note are added to
+// the message.
+func (s *Server) announceAlert(alert models.PriceAlert, price float64, message string, cfg *models.UserConfig) {
+	message = withAlertNote(alert, message)
+	s.bus.Publish(events.AlertTriggered, events.AlertTriggeredPayload{Alert: alert, Price: price})
+	s.BroadcastAlert(alert.Symbol, message)
+
+	notification := models.Notification{
+		Type:         "price_alert",
+		Title:        fmt.Sprintf(PRICE_ALERT, alert.Symbol),
+		Message:      message,
+		Symbol:       alert.Symbol,
+		Price:        price,
+		ChannelTypes: alert.Channels,
+	}
+	trigger := models.AlertTrigger{AlertID: alert.ID, Price: price, Message: message, TriggeredAt: time.Now().UTC()}
+	go func() {
+		trigger.Channels, _ = s.notifyService.Deliver(notification, cfg.NotificationChannels)
+		if err := s.db.SaveAlertTrigger(&trigger); err != nil {
+			log.Printf("[ALERTS] Failed to record trigger of alert %d: %v", alert.ID, err)
+		}
+	}()
+
+	log.Printf("Alert triggered: %s", message)
+}
@@ -2,12 +2,17 @@ package api
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"stockmarket/internal/config"
 	"stockmarket/internal/market"
+	"stockmarket/internal/market/resample"
+	"stockmarket/internal/models"
+	"stockmarket/internal/web/pages"
 )
 
 // handleQuote fetches a quote for a symbol
@@ -22,7 +27,7 @@ func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, SYMBOL_REQUIRED)
 		return
 	}
-	symbol = strings.ToUpper(symbol)
+	symbol = market.NormalizeSymbol(symbol)
 
 	cfg, err := s.db.GetOrCreateConfig()
 	if err != nil {
@@ -47,7 +52,7 @@ func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
 
 	quote, err := provider.GetQuote(ctx, symbol)
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondProviderError(w, err)
 		return
 	}
 
@@ -66,11 +71,29 @@ func (s *Server) handleHistorical(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, SYMBOL_REQUIRED)
 		return
 	}
-	symbol = strings.ToUpper(symbol)
+	symbol = market.NormalizeSymbol(symbol)
+
+	// A custom from/to date range takes precedence over a named period
+	query := r.URL.Query()
+	var p market.Period
+	var err error
+	if from, to := query.Get("from"), query.Get("to"); from != "" || to != "" {
+		p, err = market.CustomPeriod(from, to, time.Now())
+	} else {
+		p, err = market.ParsePeriod(query.Get("period")) // Defaults to 1 month
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	period := p.String()
 
-	period := r.URL.Query().Get("period")
-	if period == "" {
-		period = "1m" // Default to 1 month
+	interval := query.Get("interval")
+	if interval != "" {
+		if interval, err = market.ParseInterval(interval); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_INTERVAL)
+			return
+		}
 	}
 
 	cfg, err := s.db.GetOrCreateConfig()
@@ -93,11 +116,162 @@ func (s *Server) handleHistorical(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	candles, err := provider.GetHistoricalData(ctx, symbol, period)
+	// Adjusted candles come at the provider's default size and are merged
+	// into the requested interval
+	adjusted := query.Get("adjusted") == "true"
+	var candles []models.Candle
+	switch {
+	case adjusted:
+		candles, err = market.GetAdjustedHistoricalData(ctx, provider, symbol, period)
+		if err == nil && interval != "" {
+			candles, err = resample.Resample(candles, interval, market.ExchangeLocation())
+		}
+	case interval != "":
+		candles, err = market.GetHistoricalDataInterval(ctx, provider, symbol, period, interval)
+	default:
+		candles, err = provider.GetHistoricalData(ctx, symbol, period)
+	}
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondProviderError(w, err)
+		return
+	}
+
+	// Candles of a requested interval or adjusted ones are cached under their
+	// own indicator key
+	if interval != "" {
+		period += "@" + interval
+	}
+	if adjusted {
+		period += "@adjusted"
+	}
+
+	// Charts can request indicator overlays alongside the candles
+	if r.URL.Query().Get("indicators") == "true" {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"candles":    candles,
+			"indicators": s.indicators.Snapshot(symbol, period, candles),
+		})
 		return
 	}
 
 	respondJSON(w, http.StatusOK, candles)
 }
+
+// handleSymbols lists the symbols traded on ?exchange= for providers with
+// exchange symbol lists
+func (s *Server) handleSymbols(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	exchange := r.URL.Query().Get("exchange")
+	if exchange == "" {
+		respondError(w, http.StatusBadRequest, EXCHANGE_REQUIRED)
+		return
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	apiKey := ""
+	if cfg.MarketDataAPIKey != "" {
+		apiKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
+	}
+
+	provider, err := market.NewProvider(cfg.MarketDataProvider, apiKey)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	symbols, err := market.ListSymbols(ctx, provider, exchange)
+	if errors.Is(err, market.ErrSymbolListUnsupported) {
+		respondError(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		respondProviderError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, symbols)
+}
+
+// handleSymbolSearch finds symbols matching ?q= by ticker or company name
+func (s *Server) handleSymbolSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondError(w, http.StatusBadRequest, SEARCH_QUERY_REQUIRED)
+		return
+	}
+
+	matches, err := s.searchSymbols(r.Context(), query)
+	if err != nil {
+		respondProviderError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, matches)
+}
+
+// handleSymbolSuggestionsHTMX renders datalist options for the watchlist
+// symbol input, searching by its ?symbol= value
+func (s *Server) handleSymbolSuggestionsHTMX(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	var suggestions []pages.SymbolSuggestion
+	if query := strings.TrimSpace(r.URL.Query().Get("symbol")); query != "" {
+		matches, err := s.searchSymbols(r.Context(), query)
+		if err != nil {
+			log.Printf("Symbol search for %q failed: %v", query, err)
+		}
+		for _, m := range matches {
+			suggestions = append(suggestions, pages.SymbolSuggestion{
+				Symbol:   m.Symbol,
+				Name:     m.Name,
+				Exchange: m.Exchange,
+			})
+		}
+	}
+
+	w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+	pages.SymbolSuggestionsPartial(suggestions).Render(r.Context(), w)
+}
+
+// searchSymbols searches symbols with the configured market data provider
+func (s *Server) searchSymbols(ctx context.Context, query string) ([]models.SymbolMatch, error) {
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := ""
+	if cfg.MarketDataAPIKey != "" {
+		apiKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
+	}
+
+	provider, err := market.NewProvider(cfg.MarketDataProvider, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return market.SearchSymbols(ctx, provider, query)
+}
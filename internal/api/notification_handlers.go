@@ -1,14 +1,71 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"stockmarket/internal/models"
+	"stockmarket/internal/notify"
 )
 
+// deliveryRetryTick is how often failed notification deliveries due for a
+// retry are resent
+const deliveryRetryTick = 30 * time.Second
+
+// deliveriesLimit bounds the deliveries listed by /api/notifications/deliveries
+const deliveriesLimit = 100
+
+// StartNotificationRetries resends failed notification deliveries in the
+// background as their retries come due
+func (s *Server) StartNotificationRetries(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(deliveryRetryTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.notifyService.RetryDue(time.Now()); err != nil {
+					log.Printf("[NOTIFY] Failed to load due deliveries: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// handleNotificationDeliveries lists the latest notification deliveries,
+// newest first, optionally only those in a ?status=
+func (s *Server) handleNotificationDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySent, models.DeliveryFailed:
+	default:
+		respondError(w, http.StatusBadRequest, INVALID_DELIVERY_STATUS)
+		return
+	}
+
+	deliveries, err := s.db.GetDeliveries(status, deliveriesLimit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, deliveries)
+}
+
 func (s *Server) handleNotificationChannels(w http.ResponseWriter, r *http.Request) {
 	cfg, err := s.db.GetOrCreateConfig()
 	if err != nil {
@@ -31,6 +88,10 @@ func (s *Server) handleNotificationChannels(w http.ResponseWriter, r *http.Reque
 			respondError(w, http.StatusBadRequest, "Type and target required")
 			return
 		}
+		if err := checkChannelTemplate(channel); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		if err := s.db.SaveNotificationChannel(cfg.ID, &channel); err != nil {
 			respondError(w, http.StatusInternalServerError, err.Error())
@@ -50,6 +111,10 @@ func (s *Server) handleNotificationChannels(w http.ResponseWriter, r *http.Reque
 			respondError(w, http.StatusBadRequest, "Channel ID required")
 			return
 		}
+		if err := checkChannelTemplate(channel); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		if err := s.db.SaveNotificationChannel(cfg.ID, &channel); err != nil {
 			respondError(w, http.StatusInternalServerError, err.Error())
@@ -63,14 +128,36 @@ func (s *Server) handleNotificationChannels(w http.ResponseWriter, r *http.Reque
 	}
 }
 
-// handleNotificationChannelDelete deletes a notification channel
-func (s *Server) handleNotificationChannelDelete(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+// checkChannelTemplate validates a channel's message template, if it has one
+func checkChannelTemplate(channel models.NotificationConfig) error {
+	if channel.Template == "" {
+		return nil
+	}
+	if err := notify.CheckTemplate(channel.Template); err != nil {
+		return fmt.Errorf("%s: %v", INVALID_NOTIFICATION_TEMPLATE, err)
+	}
+	return nil
+}
+
+// handleNotificationChannel routes requests for one notification channel:
+// DELETE removes it and POST to /test sends it a test notification
+func (s *Server) handleNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/notification-channels/")
+	if idStr, ok := strings.CutSuffix(path, "/test"); ok {
+		s.handleNotificationChannelTest(w, r, idStr)
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/notification-channels/")
+	switch r.Method {
+	case http.MethodDelete:
+		s.handleNotificationChannelDelete(w, path)
+	default:
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+	}
+}
+
+// handleNotificationChannelDelete deletes a notification channel
+func (s *Server) handleNotificationChannelDelete(w http.ResponseWriter, idStr string) {
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid channel ID")
@@ -85,4 +172,70 @@ func (s *Server) handleNotificationChannelDelete(w http.ResponseWriter, r *http.
 	respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// handleNotificationChannelTest sends a sample notification through one
+// channel only, even a disabled or digest one, and reports whether the
+// provider accepted it or its error
+func (s *Server) handleNotificationChannelTest(w http.ResponseWriter, r *http.Request, idStr string) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	htmx := r.Header.Get("HX-Request") == "true"
+	fail := func(status int, msg string) {
+		if htmx {
+			htmxError(w, msg)
+			return
+		}
+		respondError(w, status, msg)
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		fail(http.StatusBadRequest, "Invalid channel ID")
+		return
+	}
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+	i := slices.IndexFunc(cfg.NotificationChannels, func(ch models.NotificationConfig) bool { return ch.ID == id })
+	if i < 0 {
+		fail(http.StatusNotFound, CHANNEL_NOT_FOUND)
+		return
+	}
+
+	notification := models.Notification{
+		Type:       "buy_signal",
+		Title:      "BUY Signal: AAPL",
+		Message:    TEST_NOTIFICATION,
+		Symbol:     "AAPL",
+		Action:     "BUY",
+		Price:      190.5,
+		Confidence: 0.8,
+	}
+	ch := cfg.NotificationChannels[i]
+	ch.Enabled, ch.Digest = true, false
+	ch.Events = []string{notification.Type}
+	_, errs := s.notifyService.Attempt(notification, []models.NotificationConfig{ch})
+
+	errMsg := ""
+	if len(errs) > 0 {
+		errMsg = errs[0].Error()
+	}
+	if htmx {
+		if errMsg != "" {
+			htmxError(w, "Test notification failed: "+errMsg)
+		} else {
+			htmxSuccess(w, "Test notification sent to "+ch.Type)
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"channel": ch.Type,
+		"sent":    errMsg == "",
+		"error":   errMsg,
+	})
+}
+
 // handleProfiles returns available risk and frequency profiles
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"stockmarket/internal/models"
+)
+
+func TestNotificationChannelRoutes(t *testing.T) {
+	s := newTestServer(t)
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := &models.NotificationConfig{Type: "webhook", Target: "http://127.0.0.1:1/hook", Enabled: true}
+	if err := s.db.SaveNotificationChannel(cfg.ID, channel); err != nil {
+		t.Fatal(err)
+	}
+	idStr := strconv.FormatInt(channel.ID, 10)
+
+	call := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleNotificationChannel(rec, httptest.NewRequest(method, "/api/notification-channels/"+path, nil))
+		return rec
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, idStr, http.StatusMethodNotAllowed},
+		{http.MethodDelete, "abc", http.StatusBadRequest},
+		{http.MethodGet, idStr + "/test", http.StatusMethodNotAllowed},
+		{http.MethodPost, "999/test", http.StatusNotFound},
+		{http.MethodDelete, idStr, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := call(tt.method, tt.path); rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	channels, err := s.db.GetNotificationChannels(cfg.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 0 {
+		t.Fatalf("%d channels left after DELETE", len(channels))
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// handlePositions lists the positions used by portfolio-aware analyses
+func (s *Server) handlePositions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	positions, err := s.db.GetPositions()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, positions)
+}
+
+// handlePosition sets (PUT) or removes (DELETE) the position in a symbol
+func (s *Server) handlePosition(w http.ResponseWriter, r *http.Request) {
+	symbol := market.NormalizeSymbol(strings.TrimPrefix(r.URL.Path, "/api/positions/"))
+	if !symbolPattern.MatchString(symbol) {
+		respondError(w, http.StatusBadRequest, SYMBOL_REQUIRED)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var position models.Position
+		if err := json.NewDecoder(r.Body).Decode(&position); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+		if position.Shares <= 0 || position.CostBasis < 0 {
+			respondError(w, http.StatusBadRequest, INVALID_POSITION)
+			return
+		}
+
+		position.Symbol = symbol
+		position.UpdatedAt = time.Now()
+		if err := s.db.SavePosition(&position); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, position)
+
+	case http.MethodDelete:
+		if err := s.db.DeletePosition(symbol); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+	}
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// validAIProviders lists the AI providers a preset or fallback may use
+var validAIProviders = map[string]bool{
+	"openai":       true,
+	"claude":       true,
+	"gemini":       true,
+	"groq":         true,
+	"mistral":      true,
+	"azure-openai": true,
+	"deepseek":     true,
+}
+
+// validHistoryPeriods lists the history periods understood by market providers
+var validHistoryPeriods = map[string]bool{
+	"1d": true,
+	"5d": true,
+	"1m": true,
+	"3m": true,
+	"1y": true,
+	"5y": true,
+
+	market.PeriodYTD: true,
+	market.PeriodMax: true,
+}
+
+// handlePresets lists and creates analysis presets
+func (s *Server) handlePresets(w http.ResponseWriter, r *http.Request) {
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		presets, err := s.db.GetAnalysisPresets(cfg.ID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if presets == nil {
+			presets = []models.AnalysisPreset{}
+		}
+		for i := range presets {
+			presets[i].AIProviderAPIKey = s.maskAPIKey(presets[i].AIProviderAPIKey)
+		}
+		respondJSON(w, http.StatusOK, presets)
+
+	case http.MethodPost:
+		var preset models.AnalysisPreset
+		if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+
+		preset.ID = 0
+		if msg := validatePreset(&preset); msg != "" {
+			respondError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		if preset.AIProviderAPIKey != "" {
+			encrypted, err := config.Encrypt(preset.AIProviderAPIKey, s.config.EncryptionKey)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, FAILED_TO_ENCRYPT_API_KEY)
+				return
+			}
+			preset.AIProviderAPIKey = encrypted
+		}
+
+		if err := s.db.SaveAnalysisPreset(cfg.ID, &preset); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		preset.AIProviderAPIKey = s.maskAPIKey(preset.AIProviderAPIKey)
+		respondJSON(w, http.StatusCreated, preset)
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+	}
+}
+
+// handlePreset gets, updates or deletes a single analysis preset
+func (s *Server) handlePreset(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/presets/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, INVALID_PRESET_ID)
+		return
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	existing, err := s.db.GetAnalysisPreset(cfg.ID, id)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, PRESET_NOT_FOUND)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		existing.AIProviderAPIKey = s.maskAPIKey(existing.AIProviderAPIKey)
+		respondJSON(w, http.StatusOK, existing)
+
+	case http.MethodPut:
+		var preset models.AnalysisPreset
+		if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+
+		preset.ID = existing.ID
+		if msg := validatePreset(&preset); msg != "" {
+			respondError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		// Keep the stored key unless a new (unmasked) one is provided
+		if preset.AIProviderAPIKey == "" || strings.Contains(preset.AIProviderAPIKey, "****") {
+			preset.AIProviderAPIKey = existing.AIProviderAPIKey
+		} else {
+			encrypted, err := config.Encrypt(preset.AIProviderAPIKey, s.config.EncryptionKey)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, FAILED_TO_ENCRYPT_API_KEY)
+				return
+			}
+			preset.AIProviderAPIKey = encrypted
+		}
+
+		if err := s.db.SaveAnalysisPreset(cfg.ID, &preset); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		preset.CreatedAt = existing.CreatedAt
+		preset.AIProviderAPIKey = s.maskAPIKey(preset.AIProviderAPIKey)
+		respondJSON(w, http.StatusOK, preset)
+
+	case http.MethodDelete:
+		if err := s.db.DeleteAnalysisPreset(cfg.ID, id); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+	}
+}
+
+// validatePreset normalizes a preset and returns an error message if it is invalid
+func validatePreset(p *models.AnalysisPreset) string {
+	p.Name = strings.TrimSpace(p.Name)
+	p.AIProvider = strings.ToLower(strings.TrimSpace(p.AIProvider))
+	p.AIModel = strings.TrimSpace(p.AIModel)
+	p.TradeFrequency = strings.ToLower(strings.TrimSpace(p.TradeFrequency))
+	p.HistoryPeriod = strings.TrimSpace(p.HistoryPeriod)
+
+	if p.Name == "" {
+		return "Preset name is required"
+	}
+	if p.AIProvider != "" && !validAIProviders[p.AIProvider] {
+		return "Unknown AI provider: " + p.AIProvider
+	}
+	if _, ok := models.TradeFrequencyProfiles[p.TradeFrequency]; p.TradeFrequency != "" && !ok {
+		return "Unknown trade frequency: " + p.TradeFrequency
+	}
+	if p.HistoryPeriod != "" && !validHistoryPeriods[p.HistoryPeriod] {
+		return "Unknown history period: " + p.HistoryPeriod
+	}
+	return ""
+}
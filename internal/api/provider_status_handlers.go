@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"stockmarket/internal/ai"
+	"stockmarket/internal/config"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+	"stockmarket/internal/web/pages"
+)
+
+// providerProbeTimeout bounds each provider probe
+const providerProbeTimeout = 10 * time.Second
+
+// ProviderStatus is the outcome of probing one configured provider
+type ProviderStatus struct {
+	Kind               string                  `json:"kind"` // "market", "ai" or "fallback_ai"
+	Provider           string                  `json:"provider"`
+	OK                 bool                    `json:"ok"`
+	LatencyMS          int64                   `json:"latency_ms"`
+	Error              string                  `json:"error,omitempty"`
+	Note               string                  `json:"note,omitempty"`
+	RateLimit          *market.RateLimitStatus `json:"rate_limit,omitempty"`
+	RateLimitRemaining *int                    `json:"rate_limit_remaining,omitempty"` // as reported by the AI provider
+}
+
+// handleProviderStatus probes the configured market, AI and fallback AI
+// providers concurrently. HTMX requests get the settings page indicators.
+func (s *Server) handleProviderStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	probes := []func(context.Context) ProviderStatus{
+		func(ctx context.Context) ProviderStatus { return s.probeMarket(ctx, cfg) },
+		func(ctx context.Context) ProviderStatus {
+			return s.probeAI(ctx, "ai", cfg.AIProvider, cfg.AIProviderAPIKey, cfg.AIModel, cfg.AzureOpenAIEndpoint)
+		},
+	}
+	if cfg.FallbackAIProvider != "" {
+		probes = append(probes, func(ctx context.Context) ProviderStatus {
+			return s.probeAI(ctx, "fallback_ai", cfg.FallbackAIProvider, cfg.FallbackAIAPIKey, cfg.FallbackAIModel, cfg.AzureOpenAIEndpoint)
+		})
+	}
+
+	statuses := make([]ProviderStatus, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe func(context.Context) ProviderStatus) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), providerProbeTimeout)
+			defer cancel()
+			statuses[i] = probe(ctx)
+		}(i, probe)
+	}
+	wg.Wait()
+
+	if r.Header.Get("HX-Request") == "true" {
+		indicators := make([]pages.ProviderIndicator, len(statuses))
+		for i, st := range statuses {
+			indicators[i] = pages.ProviderIndicator{
+				Label:     providerKindLabels[st.Kind],
+				Provider:  st.Provider,
+				OK:        st.OK,
+				LatencyMS: st.LatencyMS,
+				Detail:    st.Error,
+			}
+			if st.Error == "" {
+				indicators[i].Detail = st.Note
+			}
+		}
+		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+		pages.ProviderStatusPartial(indicators).Render(r.Context(), w)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"providers":  statuses,
+		"checked_at": time.Now().Format(time.RFC3339),
+	})
+}
+
+// providerKindLabels names each probed provider on the settings page
+var providerKindLabels = map[string]string{
+	"market":      "Market Data",
+	"ai":          "AI",
+	"fallback_ai": "Fallback AI",
+}
+
+// probeMarket quotes a well-known symbol with the configured market provider
+func (s *Server) probeMarket(ctx context.Context, cfg *models.UserConfig) ProviderStatus {
+	status := ProviderStatus{Kind: "market", Provider: cfg.MarketDataProvider}
+
+	apiKey := ""
+	if cfg.MarketDataAPIKey != "" {
+		apiKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
+	}
+	provider, err := market.NewProvider(cfg.MarketDataProvider, apiKey)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	// Providers that need a key fall back to Stooq until one is set
+	if provider.Name() != cfg.MarketDataProvider {
+		status.Provider = provider.Name()
+		status.Note = fmt.Sprintf("No API key for %s, using %s", cfg.MarketDataProvider, provider.Name())
+	}
+
+	latency, err := market.Probe(ctx, provider)
+	status.LatencyMS = latency.Milliseconds()
+	status.RateLimit = market.GetRateLimitStatus(provider.Name())
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.OK = true
+	return status
+}
+
+// probeAI checks the API key and model of an AI provider
+func (s *Server) probeAI(ctx context.Context, kind, provider, encryptedKey, model, endpoint string) ProviderStatus {
+	status := ProviderStatus{Kind: kind, Provider: provider}
+
+	apiKey := ""
+	if encryptedKey != "" {
+		apiKey, _ = config.Decrypt(encryptedKey, s.config.EncryptionKey)
+	}
+	analyzer, err := ai.NewAnalyzer(provider, apiKey, model, endpoint)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	start := time.Now()
+	result, err := ai.Probe(ctx, analyzer)
+	status.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.OK = true
+	status.RateLimitRemaining = result.RateLimitRemaining
+	return status
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/db"
+	"stockmarket/internal/models"
+	"stockmarket/internal/notify"
+)
+
+// maxUserAgent is the longest user agent kept with a push subscription
+const maxUserAgent = 255
+
+// loadVAPIDKeys reads the VAPID keys, generating and saving them on first use
+func loadVAPIDKeys(database *db.DB, encryptionKey []byte) (*notify.VAPIDKeys, error) {
+	_, encrypted, err := database.GetVAPIDKeys()
+	if err == nil {
+		private, err := config.Decrypt(encrypted, encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		return notify.ParseVAPIDKeys(private)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	keys, err := notify.GenerateVAPIDKeys()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err = config.Encrypt(keys.PrivateKey(), encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.SaveVAPIDKeys(keys.PublicKey(), encrypted); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// handlePushPublicKey returns the VAPID public key browsers subscribe with
+func (s *Server) handlePushPublicKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	if s.vapidKeys == nil {
+		respondError(w, http.StatusServiceUnavailable, PUSH_UNAVAILABLE)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"public_key": s.vapidKeys.PublicKey()})
+}
+
+// handlePushSubscriptions lists (GET), registers (POST) or removes (DELETE,
+// by endpoint) the browsers webpush notifications are sent to
+func (s *Server) handlePushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		subs, err := s.db.GetPushSubscriptions()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, subs)
+
+	case http.MethodPost:
+		var sub models.PushSubscription
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+		if err := notify.CheckPushSubscription(sub); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_PUSH_SUBSCRIPTION+": "+err.Error())
+			return
+		}
+
+		sub.UserAgent = r.UserAgent()
+		if len(sub.UserAgent) > maxUserAgent {
+			sub.UserAgent = sub.UserAgent[:maxUserAgent]
+		}
+		if err := s.db.SavePushSubscription(&sub); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusCreated, sub)
+
+	case http.MethodDelete:
+		var sub models.PushSubscription
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+		if sub.Endpoint == "" {
+			respondError(w, http.StatusBadRequest, INVALID_PUSH_SUBSCRIPTION+": endpoint is required")
+			return
+		}
+
+		if err := s.db.DeletePushSubscription(sub.Endpoint); err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"stockmarket/internal/models"
+	"stockmarket/internal/scheduler"
+)
+
+// maxRetentionDays bounds the configurable retention periods
+const maxRetentionDays = 3650
+
+// validRetentionDays reports whether days is a valid retention, 0 keeping
+// records forever
+func validRetentionDays(days int) bool {
+	return days >= 0 && days <= maxRetentionDays
+}
+
+// PruneJob deletes the records older than the configured retention every
+// night, catching up once after downtime
+func (s *Server) PruneJob() scheduler.Job {
+	return scheduler.Job{
+		Name:    "prune",
+		Hour:    3,
+		Minute:  30,
+		CatchUp: true,
+		Run: func(ctx context.Context) error {
+			_, err := s.prune()
+			return err
+		},
+	}
+}
+
+// prune applies the configured retention
+func (s *Server) prune() (*models.PruneResult, error) {
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.db.Prune(cfg.Retention, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if result.Analyses+result.Alerts+result.Notifications+result.ExpiredAlerts > 0 {
+		log.Printf("[RETENTION] Pruned %d analyses, %d triggered alerts, %d expired alerts and %d notifications",
+			result.Analyses, result.Alerts, result.ExpiredAlerts, result.Notifications)
+	}
+	return result, nil
+}
+
+// handlePrune applies the configured retention now
+func (s *Server) handlePrune(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+	htmx := r.Header.Get("HX-Request") == "true"
+
+	result, err := s.prune()
+	if err != nil {
+		if htmx {
+			htmxError(w, err.Error())
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if htmx {
+		htmxSuccess(w, fmt.Sprintf("Pruned %d analyses, %d alerts and %d notifications",
+			result.Analyses, result.Alerts+result.ExpiredAlerts, result.Notifications))
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"removed": result,
+	})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"log"
+
+	"stockmarket/internal/indicators"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+	"stockmarket/internal/rules"
+)
+
+// volumeAveragePeriod is the number of daily volumes the volume ratio of
+// composite alerts is measured against
+const volumeAveragePeriod = 20
+
+// quoteMetrics supplies the metrics of composite alert rules for a quote,
+// loading the daily history the first time a rule needs it
+type quoteMetrics struct {
+	ctx      context.Context
+	provider market.Provider
+	quote    *models.Quote
+	price    float64
+
+	loaded  bool
+	candles []models.Candle // daily candles with the quote as today's
+}
+
+// newQuoteMetrics creates the metrics of a quote, evaluated at price
+func newQuoteMetrics(ctx context.Context, provider market.Provider, quote *models.Quote, price float64) *quoteMetrics {
+	return &quoteMetrics{ctx: ctx, provider: provider, quote: quote, price: price}
+}
+
+// Value returns a metric of the quote
+func (m *quoteMetrics) Value(metric string, period int) (float64, bool) {
+	switch metric {
+	case rules.MetricPrice:
+		return m.price, m.price > 0
+	case rules.MetricChangePercent:
+		if m.quote.PreviousClose <= 0 {
+			return 0, false
+		}
+		return (m.price - m.quote.PreviousClose) / m.quote.PreviousClose * 100, true
+	case rules.MetricVolumeRatio:
+		candles := m.history()
+		if len(candles) < volumeAveragePeriod+1 || m.quote.Volume <= 0 {
+			return 0, false
+		}
+		var sum int64
+		for _, c := range candles[len(candles)-volumeAveragePeriod-1 : len(candles)-1] {
+			sum += c.Volume
+		}
+		if sum <= 0 {
+			return 0, false
+		}
+		return float64(m.quote.Volume) / (float64(sum) / volumeAveragePeriod), true
+	case rules.MetricRSI:
+		return indicators.RSI(m.history(), period)
+	}
+	return 0, false
+}
+
+// history loads the daily candles of the quoted symbol once, with the quote
+// as the close of its day
+func (m *quoteMetrics) history() []models.Candle {
+	if m.loaded {
+		return m.candles
+	}
+	m.loaded = true
+	candles, err := m.provider.GetHistoricalData(m.ctx, m.quote.Symbol, rsiHistoryPeriod)
+	if err != nil || len(candles) == 0 {
+		log.Printf("[ALERTS] Failed to load history of %s for composite alerts: %v", m.quote.Symbol, err)
+		return nil
+	}
+	m.candles = withLatestClose(candles, m.quote)
+	return m.candles
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"stockmarket/internal/analysis"
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+	"stockmarket/internal/scheduler"
+)
+
+const (
+	// analysisScheduleTick is how often the analysis schedule is checked
+	analysisScheduleTick = time.Minute
+	// analysisScheduleGrace is how late a scheduled analysis may still start,
+	// e.g. after a restart; runs missed for longer are skipped
+	analysisScheduleGrace = 2 * time.Hour
+	// defaultScheduledRuns and maxScheduledRuns bound the run history listed
+	defaultScheduledRuns = 20
+	maxScheduledRuns     = 100
+)
+
+// StartAnalysisSchedule analyzes the watchlist on the cadence set in the
+// configuration until ctx is done. Results are saved like manual analyses,
+// so high-confidence BUY and SELL signals are notified.
+func (s *Server) StartAnalysisSchedule(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(analysisScheduleTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.checkAnalysisSchedule(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+// checkAnalysisSchedule runs the scheduled analysis when a scheduled time
+// has passed since the last run. The run is recorded before it starts, so a
+// slow batch or a restart never repeats it.
+func (s *Server) checkAnalysisSchedule(ctx context.Context, now time.Time) {
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil || len(cfg.TrackedSymbols) == 0 {
+		return
+	}
+	job, ok := analysis.ScheduleJob(cfg.AnalysisSchedule, cfg.AnalysisScheduleTime)
+	if !ok {
+		return
+	}
+
+	due := scheduler.PreviousRun(job, now)
+	lastRun, err := s.db.GetJobLastRun(job.Name)
+	if err != nil {
+		log.Printf("[SCHEDULE] Failed to load state: %v", err)
+		return
+	}
+	if !due.After(lastRun) {
+		return
+	}
+	if err := s.db.SetJobLastRun(job.Name, due); err != nil {
+		log.Printf("[SCHEDULE] Failed to save state: %v", err)
+		return
+	}
+
+	// A schedule that was just enabled waits for its next time
+	if lastRun.IsZero() {
+		return
+	}
+	if now.Sub(due) > analysisScheduleGrace {
+		log.Printf("[SCHEDULE] Skipping the analysis scheduled for %s, missed by %s",
+			due.Format(time.RFC3339), now.Sub(due).Round(time.Minute))
+		return
+	}
+	if cfg.AnalysisSchedule == analysis.ScheduleDaily && !market.IsTradingDay(due) {
+		log.Printf("[SCHEDULE] Skipping the analysis scheduled for %s, the market is closed", due.Format(time.RFC3339))
+		return
+	}
+
+	s.runScheduledAnalysis(ctx, cfg.TrackedSymbols, due)
+}
+
+// runScheduledAnalysis analyzes symbols and records the run in the history
+func (s *Server) runScheduledAnalysis(ctx context.Context, symbols []string, due time.Time) {
+	run := &models.ScheduledRun{ScheduledFor: due, StartedAt: time.Now(), Symbols: len(symbols)}
+	if err := s.db.CreateScheduledRun(run); err != nil {
+		log.Printf("[SCHEDULE] Failed to record run: %v", err)
+	}
+	log.Printf("[SCHEDULE] Analyzing %d symbols", len(symbols))
+
+	var failures []string
+	for _, result := range s.analysisService.RunBatch(ctx, symbols, analysis.Options{}, nil) {
+		if result.Err != nil {
+			run.Failed++
+			failures = append(failures, result.Symbol+": "+result.Err.Error())
+			continue
+		}
+		run.Succeeded++
+		if isSignal(result.Analysis) {
+			run.Signals++
+		}
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Errors = strings.Join(failures, "; ")
+
+	if run.ID != 0 {
+		if err := s.db.FinishScheduledRun(run); err != nil {
+			log.Printf("[SCHEDULE] Failed to record run: %v", err)
+		}
+	}
+	log.Printf("[SCHEDULE] Finished in %s: %d succeeded, %d failed, %d signals",
+		finishedAt.Sub(run.StartedAt).Round(time.Second), run.Succeeded, run.Failed, run.Signals)
+}
+
+// handleScheduledRuns lists the most recent scheduled analyses, newest first
+func (s *Server) handleScheduledRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	limit := defaultScheduledRuns
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxScheduledRuns {
+			respondError(w, http.StatusBadRequest, INVALID_SCHEDULED_RUNS_LIMIT)
+			return
+		}
+		limit = n
+	}
+
+	runs, err := s.db.GetScheduledRuns(limit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, runs)
+}
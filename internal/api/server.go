@@ -1,13 +1,18 @@
 package api
 
 import (
+	"log"
 	"net/http"
 	"sync"
 
 	"github.com/gorilla/websocket"
 
+	"stockmarket/internal/analysis"
 	"stockmarket/internal/config"
 	"stockmarket/internal/db"
+	"stockmarket/internal/events"
+	"stockmarket/internal/indicators"
+	"stockmarket/internal/market"
 	"stockmarket/internal/notify"
 )
 
@@ -27,28 +32,103 @@ const (
 	INVALID_FORM_DATA = "Invalid form data"
 
 	// Errors
-	ALL_FIELDS_REQUIRED           = "All fields are required"
-	FAILED_TO_DECRYPT_API_KEY     = "Failed to decrypt API key"
-	FAILED_TO_ENCRYPT_API_KEY     = "Failed to encrypt API key"
-	FAILED_TO_GET_ANALYZE         = "Failed to get analyze"
-	FAILED_TO_GET_CONFIG          = "Failed to get config"
-	FAILED_TO_GET_HISTORICAL_DATA = "Failed to get historical data"
-	FAILED_TO_GET_QUOTE           = "Failed to get quote"
-	FAILED_TO_UPDATE_CONFIG       = "Failed to update config"
-	INVALID_ALERT_ID              = "Invalid alert ID"
-	INVALID_POLLING_INTERVAL      = "Invalid polling interval"
-	INVALID_PRICE                 = "Invalid price"
-	SYMBOL_REQUIRED               = "Symbol is required"
+	ALL_FIELDS_REQUIRED            = "All fields are required"
+	ANALYSIS_NOT_FOUND             = "Analysis not found"
+	DEMO_DATA_DEVELOPMENT_ONLY     = "Demo data is only available in development"
+	EXCHANGE_REQUIRED              = "Exchange is required"
+	SEARCH_QUERY_REQUIRED          = "Search query is required"
+	INVALID_INTERVAL               = "Interval must be one of: 1m, 5m, 15m, 30m, 1h, 1d, 1wk"
+	FAILED_TO_DECRYPT_API_KEY      = "Failed to decrypt API key"
+	FAILED_TO_ENCRYPT_API_KEY      = "Failed to encrypt API key"
+	FAILED_TO_GET_ANALYZE          = "Failed to get analyze"
+	FAILED_TO_GET_CONFIG           = "Failed to get config"
+	FAILED_TO_GET_HISTORICAL_DATA  = "Failed to get historical data"
+	FAILED_TO_GET_QUOTE            = "Failed to get quote"
+	FAILED_TO_UPDATE_CONFIG        = "Failed to update config"
+	AI_KEY_CHECK_FAILED            = "AI API key check failed: check the key and model"
+	INVALID_AI_API_KEY             = "AI API key was rejected by the provider"
+	INVALID_ALERT_ID               = "Invalid alert ID"
+	INVALID_ALERT_CONDITION        = "Condition must be above, below, gap, gap_up, gap_down, trail_percent, trail_amount, sma_cross_above, sma_cross_below, rsi_above, rsi_below, high_52w, low_52w or composite"
+	INVALID_TRAIL_PERCENT          = "Trailing percent must be below 100"
+	INVALID_SMA_PERIODS            = "Moving average periods must be 1-250 days, the fast one shorter than the slow one"
+	INVALID_RSI_ALERT              = "RSI level must be between 0 and 100 and the period 2-50 days"
+	INVALID_ALERT_COOLDOWN         = "Cooldown of a recurring alert must be 1-10080 minutes"
+	INVALID_HYSTERESIS             = "Hysteresis band must be 0-10%"
+	INVALID_ALERT_CHANNELS         = "Alert channels must be email, discord, sms, pushover, webhook, matrix, webpush, fcm or smtp"
+	INVALID_WEBHOOK_URL            = "Webhook URL must be an http or https URL"
+	INVALID_ALERT_RULE             = "Invalid alert rule"
+	INVALID_ALERT_EXPIRY           = "Alert expiry must be in the future, up to a year ahead"
+	INVALID_ANALYSIS_SCHEDULE      = "Schedule must be one of: off, daily, weekly"
+	INVALID_ANALYSIS_SCHEDULE_TIME = "Schedule time must be HH:MM, e.g. 08:30"
+	INVALID_ANALYSIS_ID            = "Invalid analysis ID"
+	INVALID_COMPARE_IDS            = "ids must be two different analysis IDs, e.g. ids=12,57"
+	COMPARE_SYMBOL_MISMATCH        = "Only analyses of the same symbol can be compared"
+	INVALID_AZURE_ENDPOINT         = "Azure endpoint must be an https URL, e.g. https://my-resource.openai.azure.com"
+	INVALID_AI_TEMPERATURE         = "Temperature must be between 0 and 2"
+	INVALID_AI_MAX_TOKENS          = "Max tokens must be between 256 and 32768"
+	INVALID_REASONING_EFFORT       = "Reasoning effort must be low, medium, high or empty"
+	INVALID_INVESTMENT_THESIS      = "Investment thesis must be at most 2000 characters"
+	INVALID_RETENTION_DAYS         = "Retention must be between 0 (keep forever) and 3650 days"
+	INVALID_BENCHMARK              = "Benchmark must be a ticker or index symbol, e.g. SPY or ^GSPC"
+	INVALID_CALENDAR_DAYS          = "Days must be between 1 and 365"
+	INVALID_CURRENCY               = "Currency must be one of: USD, EUR, GBP, JPY, CAD, AUD, CHF, HKD"
+	INVALID_EXCHANGE               = "Exchange must be one of: NYSE, NASDAQ, LSE, XETRA, EURONEXT, BME, SIX, TSE, HKEX, TSX, ASX, CRYPTO"
+	INVALID_MARKET_API_KEY         = "Market data API key was rejected by the provider"
+	INVALID_MIN_STORE_CONFIDENCE   = "Minimum confidence must be between 0 and 1"
+	INVALID_POLLING_INTERVAL       = "Invalid polling interval"
+	INVALID_POSITION               = "Shares must be positive and cost basis cannot be negative"
+	INVALID_PRESET_ID              = "Invalid preset ID"
+	INVALID_PRICE                  = "Invalid price"
+	INVALID_RATING                 = "Rating must be -1, 0 or 1"
+	INVALID_SCHEDULED_RUNS_LIMIT   = "Limit must be between 1 and 100"
+	INVALID_STALE_QUOTE_MINUTES    = "Stale quote threshold must be between 1 and 1440 minutes"
+	INVALID_USAGE_DAYS             = "Days must be between 1 and 365"
+	CONSENSUS_UNAVAILABLE          = "Consensus needs at least two configured AI providers: set a fallback provider or a preset with another provider"
+	INVALID_ANALYSIS_MODE          = "Mode must be single, consensus or relative"
+	PRESET_NOT_FOUND               = "Preset not found"
+	INVALID_TAG_ID                 = "Invalid tag ID"
+	INVALID_TAG_NAME               = "Tag names are 1-32 letters, digits, dashes or underscores"
+	TAG_NOT_FOUND                  = "Tag not found"
+	ALERT_NOT_FOUND                = "Alert not found"
+	INVALID_ALERT_STATE            = "State must be active or triggered"
+	INVALID_ALERT_LABEL            = "Alert labels are at most 32 characters"
+	INVALID_ALERT_NOTE             = "Alert notes are at most 500 characters"
+	NO_ALERT_CHANNELS              = "No enabled notification channel receives this alert: check its channels and the price alert events in Settings"
+	INVALID_PUSH_SUBSCRIPTION      = "Invalid push subscription"
+	PUSH_UNAVAILABLE               = "Browser push is unavailable: the VAPID keys could not be loaded, see the server log"
+	INVALID_DEVICE                 = "Devices need a token and a platform of android, ios or web, and names are at most 64 characters"
+	INVALID_DEVICE_ID              = "Invalid device ID"
+	INVALID_DELIVERY_STATUS        = "Status must be pending, sent or failed"
+	INVALID_NOTIFICATION_TEMPLATE  = "Invalid message template"
+	DEVICE_NOT_FOUND               = "Device not found"
+	CHANNEL_NOT_FOUND              = "Notification channel not found"
+	TEST_NOTIFICATION              = "Test notification from StockAI: this channel is set up correctly"
+	TAG_EXISTS                     = "A tag with this name already exists"
+	STREAMING_UNSUPPORTED          = "Streaming not supported"
+	SYMBOL_REQUIRED                = "Symbol is required"
+	WATCHLIST_EMPTY                = "Watchlist is empty: add symbols in Settings"
 )
 
 // Server holds the API server dependencies
 type Server struct {
-	db            *db.DB
-	config        *config.Config
-	notifyService *notify.Service
-	clients       map[*websocket.Conn]bool
-	clientsMu     sync.RWMutex
-	upgrader      websocket.Upgrader
+	db              *db.DB
+	config          *config.Config
+	notifyService   *notify.Service
+	bus             *events.Bus
+	analysisService *analysis.Service
+	indicators      *indicators.Cache
+	vapidKeys       *notify.VAPIDKeys
+	clients         map[*websocket.Conn]bool
+	clientsMu       sync.RWMutex
+	upgrader        websocket.Upgrader
+
+	// Trading day each gap alert was last evaluated, by alert ID
+	gapChecked   map[int64]string
+	gapCheckedMu sync.Mutex
+
+	// Market provider factory for watchlist validation and backfill,
+	// replaceable for testing
+	newMarketProvider func(name, apiKey string) (market.Provider, error)
 }
 
 // NewServer creates a new API server
@@ -56,26 +136,52 @@ func NewServer(database *db.DB, cfg *config.Config) *Server {
 	// Initialize notification service with notifiers
 	notifyService := notify.NewService()
 	notifyService.RegisterNotifier(notify.NewEmailNotifier(map[string]string{}))
+	notifyService.RegisterNotifier(notify.NewSMTPNotifier(map[string]string{}))
 	notifyService.RegisterNotifier(notify.NewDiscordNotifier())
 	notifyService.RegisterNotifier(notify.NewSMSNotifier(map[string]string{}))
+	notifyService.RegisterNotifier(notify.NewPushoverNotifier(map[string]string{}))
+	notifyService.RegisterNotifier(notify.NewWebhookNotifier(map[string]string{}))
+	notifyService.RegisterNotifier(notify.NewMatrixNotifier(map[string]string{}))
+
+	vapidKeys, err := loadVAPIDKeys(database, cfg.EncryptionKey)
+	if err != nil {
+		log.Printf("Web Push disabled: could not load VAPID keys: %v", err)
+	}
+	notifyService.RegisterNotifier(notify.NewWebPushNotifier(vapidKeys, database))
+	notifyService.RegisterNotifier(notify.NewFCMNotifier(map[string]string{}, database))
+	notifyService.SetQueue(database)
+	notifyService.SetLimits(cfg.NotifyDedupWindow, cfg.NotifyRateLimit)
 
-	return &Server{
-		db:            database,
-		config:        cfg,
-		notifyService: notifyService,
-		clients:       make(map[*websocket.Conn]bool),
+	bus := events.NewBus()
+	indicatorCache := indicators.NewCache(indicators.DefaultCacheSize)
+
+	s := &Server{
+		db:                database,
+		config:            cfg,
+		notifyService:     notifyService,
+		bus:               bus,
+		analysisService:   analysis.NewService(database, cfg.EncryptionKey, bus, indicatorCache),
+		indicators:        indicatorCache,
+		vapidKeys:         vapidKeys,
+		clients:           make(map[*websocket.Conn]bool),
+		gapChecked:        make(map[int64]string),
+		newMarketProvider: market.NewProvider,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Allow all origins in development
 			},
 		},
 	}
+	s.registerSubscribers()
+	return s
 }
 
 // SetupRoutes sets up all API routes
 func (s *Server) SetupRoutes(mux *http.ServeMux) {
 	// Health check
 	mux.HandleFunc("/api/health", s.handleHealth)
+	mux.HandleFunc("/api/market/calendar", s.handleMarketCalendar)
+	mux.HandleFunc("/api/providers/status", s.handleProviderStatus)
 
 	// Configuration (JSON API)
 	mux.HandleFunc("/api/config", s.handleConfig)
@@ -87,19 +193,44 @@ func (s *Server) SetupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/config/watchlist", s.handleConfigWatchlist)
 	mux.HandleFunc("/api/config/watchlist/", s.handleConfigWatchlistSymbol)
 	mux.HandleFunc("/api/config/polling", s.handleConfigPolling)
+	mux.HandleFunc("/api/config/schedule", s.handleConfigSchedule)
+	mux.HandleFunc("/api/config/retention", s.handleConfigRetention)
 	mux.HandleFunc("/api/config/notifications", s.handleConfigNotifications)
 
 	// Market data
 	mux.HandleFunc("/api/quote/", s.handleQuote)
 	mux.HandleFunc("/api/historical/", s.handleHistorical)
+	mux.HandleFunc("/api/symbols", s.handleSymbols)
+	mux.HandleFunc("/api/symbols/search", s.handleSymbolSearch)
+	mux.HandleFunc("/api/symbols/suggestions", s.handleSymbolSuggestionsHTMX)
 
 	// Analysis (JSON API)
 	mux.HandleFunc("/api/analyze/", s.handleAnalyze)
 	mux.HandleFunc("/api/analyses", s.handleAnalyses)
 	mux.HandleFunc("/api/analyses/", s.handleAnalysesForSymbol)
+	mux.HandleFunc("/api/analyses/compare", s.handleCompareAnalyses)
+	mux.HandleFunc("/api/analyses/search", s.handleSearchAnalyses)
+	mux.HandleFunc("/api/performance", s.handlePerformance)
+	mux.HandleFunc("/api/performance/calibration", s.handleCalibration)
+	mux.HandleFunc("/api/usage", s.handleAIUsage)
+	mux.HandleFunc("/api/usage/summary", s.handleUsageSummary)
+	mux.HandleFunc("/api/schedule/runs", s.handleScheduledRuns)
 
 	// Analysis (HTMX)
 	mux.HandleFunc("/api/analyze", s.handleAnalyzeHTMX)
+	mux.HandleFunc("/api/analyze-watchlist", s.handleAnalyzeWatchlist)
+
+	// Positions for portfolio-aware analyses
+	mux.HandleFunc("/api/positions", s.handlePositions)
+	mux.HandleFunc("/api/positions/", s.handlePosition)
+
+	// Analysis presets
+	mux.HandleFunc("/api/presets", s.handlePresets)
+	mux.HandleFunc("/api/presets/", s.handlePreset)
+
+	// Tags for organizing analyses
+	mux.HandleFunc("/api/tags", s.handleTags)
+	mux.HandleFunc("/api/tags/", s.handleTag)
 
 	// Alerts (JSON API)
 	mux.HandleFunc("/api/alerts", s.handleAlertsHTMX)       // Changed to HTMX handler
@@ -107,11 +238,34 @@ func (s *Server) SetupRoutes(mux *http.ServeMux) {
 
 	// Notification channels
 	mux.HandleFunc("/api/notification-channels", s.handleNotificationChannels)
-	mux.HandleFunc("/api/notification-channels/", s.handleNotificationChannelDelete)
+	mux.HandleFunc("/api/notification-channels/", s.handleNotificationChannel)
+	mux.HandleFunc("/api/notifications/deliveries", s.handleNotificationDeliveries)
+
+	// Browser push subscriptions
+	mux.HandleFunc("/api/push/vapid-public-key", s.handlePushPublicKey)
+	mux.HandleFunc("/api/push/subscriptions", s.handlePushSubscriptions)
+
+	// Devices registered for FCM push
+	mux.HandleFunc("/api/devices", s.handleDevices)
+	mux.HandleFunc("/api/devices/", s.handleDevice)
 
 	// WebSocket for real-time updates
 	mux.HandleFunc("/api/ws", s.handleWebSocket)
 
+	// Demo data (development only)
+	mux.HandleFunc("/api/admin/seed-demo", s.handleSeedDemo)
+	mux.HandleFunc("/api/admin/clear-demo", s.handleClearDemo)
+
+	// Data retention
+	mux.HandleFunc("/api/admin/prune", s.handlePrune)
+
+	// Database diagnostics
+	mux.HandleFunc("/api/admin/db-stats", s.handleDBStats)
+
+	// Export and import of all user data
+	mux.HandleFunc("/api/export", s.handleExport)
+	mux.HandleFunc("/api/import", s.handleImport)
+
 	// Risk and frequency profiles
 	mux.HandleFunc("/api/profiles", s.handleProfiles)
 }
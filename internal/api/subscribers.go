@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/events"
+	"stockmarket/internal/models"
+)
+
+// notificationSummaryLength bounds the reasoning used when an analysis has no highlights
+const notificationSummaryLength = 280
+
+// signalConfidence is the confidence from which BUY and SELL analyses are notified
+const signalConfidence = 0.7
+
+// backfillPeriod is the daily history fetched, and kept in the candle store,
+// for symbols added to the watchlist
+const backfillPeriod = "1y"
+
+// backfillTimeout bounds the history fetches for one watchlist change
+const backfillTimeout = 2 * time.Minute
+
+// registerSubscribers wires the server's internal event subscribers
+func (s *Server) registerSubscribers() {
+	s.bus.Subscribe(events.AnalysisCompleted, s.notifyAnalysisSignal)
+	s.bus.Subscribe(events.WatchlistChanged, s.backfillWatchlist)
+	if s.config.UsageStats {
+		s.bus.Subscribe(events.AnalysisCompleted, s.recordUsage)
+		s.bus.Subscribe(events.AlertTriggered, s.recordUsage)
+		s.bus.Subscribe(events.ProviderFailed, s.recordUsage)
+	}
+}
+
+// notifyAnalysisSignal sends notifications for trading signals with high confidence
+func (s *Server) notifyAnalysisSignal(e events.Event) {
+	payload, ok := e.Payload.(events.AnalysisCompletedPayload)
+	if !ok {
+		return
+	}
+	analysis := payload.Analysis
+	if !isSignal(analysis) {
+		return
+	}
+
+	// Keep messages short: highlights as bullets, or the start of the reasoning
+	highlights := models.HighlightsOrTruncated(analysis.Highlights, analysis.Reasoning, notificationSummaryLength)
+	notification := models.Notification{
+		Type:       strings.ToLower(analysis.Action) + "_signal",
+		Title:      fmt.Sprintf("%s Signal: %s", analysis.Action, analysis.Symbol),
+		Message:    "• " + strings.Join(highlights, "\n• "),
+		Symbol:     analysis.Symbol,
+		Action:     analysis.Action,
+		Price:      analysis.PriceTargets.Entry,
+		Confidence: analysis.Confidence,
+	}
+	s.notifyService.SendToChannels(notification, payload.Config.NotificationChannels)
+}
+
+// backfillWatchlist fetches the history of symbols added to the watchlist so
+// their charts and first analysis are served from the candle store
+func (s *Server) backfillWatchlist(e events.Event) {
+	payload, ok := e.Payload.(events.WatchlistChangedPayload)
+	if !ok || len(payload.Added) == 0 {
+		return
+	}
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		log.Printf("[WATCHLIST] Failed to get config for backfill: %v", err)
+		return
+	}
+	apiKey := ""
+	if cfg.MarketDataAPIKey != "" {
+		apiKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
+	}
+	provider, err := s.newMarketProvider(cfg.MarketDataProvider, apiKey)
+	if err != nil {
+		log.Printf("[WATCHLIST] No market data for backfill: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backfillTimeout)
+	defer cancel()
+	for _, symbol := range payload.Added {
+		if _, err := provider.GetHistoricalData(ctx, symbol, backfillPeriod); err != nil {
+			log.Printf("[WATCHLIST] Failed to backfill %s: %v", symbol, err)
+		}
+	}
+}
+
+// isSignal reports whether an analysis recommends trading, BUY or SELL or
+// ADD or TRIM for a position, with high confidence
+func isSignal(analysis *models.AnalysisResponse) bool {
+	switch analysis.Action {
+	case "BUY", "SELL", "ADD", "TRIM":
+		return analysis.Confidence >= signalConfidence
+	}
+	return false
+}
+
+// recordUsage updates the local usage rollup for analyses, triggered alerts
+// and provider errors
+func (s *Server) recordUsage(e events.Event) {
+	var metric, key string
+	switch payload := e.Payload.(type) {
+	case events.AnalysisCompletedPayload:
+		metric, key = models.UsageAnalysis, payload.Analysis.Symbol
+	case events.AlertTriggeredPayload:
+		metric, key = models.UsageAlertTriggered, payload.Alert.Symbol
+	case events.ProviderFailedPayload:
+		metric, key = models.UsageProviderError, payload.Kind+":"+payload.Provider
+	default:
+		return
+	}
+	if err := s.db.RecordUsage(metric, key, time.Now()); err != nil {
+		log.Printf("Failed to record usage: %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stockmarket/internal/web/pages"
+)
+
+// maxTagNameLength bounds the length of a tag name
+const maxTagNameLength = 32
+
+// normalizeTagName lowercases a tag name and joins its words with dashes,
+// e.g. "Earnings Play" becomes "earnings-play". ok is false unless the
+// result is 1-32 letters, digits, dashes or underscores.
+func normalizeTagName(name string) (string, bool) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), "-"))
+	if name == "" || len(name) > maxTagNameLength {
+		return "", false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// handleTags lists the tags with their analysis counts and creates tags
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tags, err := s.db.GetTags()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, tags)
+
+	case http.MethodPost:
+		var input struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+		name, ok := normalizeTagName(input.Name)
+		if !ok {
+			respondError(w, http.StatusBadRequest, INVALID_TAG_NAME)
+			return
+		}
+
+		tag, created, err := s.db.CreateTag(name)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !created {
+			respondError(w, http.StatusConflict, TAG_EXISTS)
+			return
+		}
+		respondJSON(w, http.StatusCreated, tag)
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+	}
+}
+
+// handleTag gets, renames or deletes a single tag; deleting a tag removes it
+// from every analysis
+func (s *Server) handleTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/tags/"), 10, 64)
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, INVALID_TAG_ID)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tag, err := s.db.GetTag(id)
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, TAG_NOT_FOUND)
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, tag)
+
+	case http.MethodPut:
+		var input struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			respondError(w, http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+		name, ok := normalizeTagName(input.Name)
+		if !ok {
+			respondError(w, http.StatusBadRequest, INVALID_TAG_NAME)
+			return
+		}
+
+		renamed, err := s.db.RenameTag(id, name)
+		if err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, TAG_NOT_FOUND)
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !renamed {
+			respondError(w, http.StatusConflict, TAG_EXISTS)
+			return
+		}
+		tag, err := s.db.GetTag(id)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, tag)
+
+	case http.MethodDelete:
+		if err := s.db.DeleteTag(id); err == sql.ErrNoRows {
+			respondError(w, http.StatusNotFound, TAG_NOT_FOUND)
+			return
+		} else if err != nil {
+			respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+	}
+}
+
+// handleAnalysisTags lists, adds and removes the tags of an analysis: GET and
+// POST on /api/analyses/:id/tags, DELETE on /api/analyses/:id/tags/:tagID.
+// Adding a tag that does not exist yet creates it. HTMX requests post form
+// values and get the updated tag editor back.
+func (s *Server) handleAnalysisTags(w http.ResponseWriter, r *http.Request, idStr, tagIDStr string) {
+	htmx := r.Header.Get("HX-Request") == "true"
+
+	fail := func(status int, message string) {
+		if htmx {
+			htmxError(w, message)
+			return
+		}
+		respondError(w, status, message)
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		fail(http.StatusBadRequest, INVALID_ANALYSIS_ID)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && tagIDStr == "":
+		// The tags are listed below
+
+	case r.Method == http.MethodPost && tagIDStr == "":
+		var input struct {
+			Tag string `json:"tag"`
+		}
+		if htmx {
+			if err := r.ParseForm(); err != nil {
+				fail(http.StatusBadRequest, INVALID_FORM_DATA)
+				return
+			}
+			input.Tag = r.FormValue("tag")
+		} else if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			fail(http.StatusBadRequest, INVALID_JSON)
+			return
+		}
+		name, ok := normalizeTagName(input.Tag)
+		if !ok {
+			fail(http.StatusBadRequest, INVALID_TAG_NAME)
+			return
+		}
+
+		if err := s.db.TagAnalysis(id, name); err == sql.ErrNoRows {
+			fail(http.StatusNotFound, ANALYSIS_NOT_FOUND)
+			return
+		} else if err != nil {
+			fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+
+	case r.Method == http.MethodDelete && tagIDStr != "":
+		tagID, err := strconv.ParseInt(tagIDStr, 10, 64)
+		if err != nil || tagID <= 0 {
+			fail(http.StatusBadRequest, INVALID_TAG_ID)
+			return
+		}
+		if err := s.db.UntagAnalysis(id, tagID); err != nil {
+			fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+
+	default:
+		fail(http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	tags, err := s.db.GetAnalysisTags(id)
+	if err != nil {
+		fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if htmx {
+		editor := pages.AnalysisTags{AnalysisID: id}
+		for _, tag := range tags {
+			editor.Tags = append(editor.Tags, pages.AnalysisTag{ID: tag.ID, Name: tag.Name})
+		}
+		if all, err := s.db.GetTags(); err == nil {
+			for _, tag := range all {
+				editor.Suggestions = append(editor.Suggestions, tag.Name)
+			}
+		}
+		w.Header().Set(HEADER_CONTENT_TYPE, CONTENT_TYPE_HTML)
+		pages.AnalysisTagsEditor(editor).Render(r.Context(), w)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, tags)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Bounds of the days parameter of the usage summary
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 365
+)
+
+// handleUsageSummary reports local usage trends: analyses per day, the most
+// analyzed symbols, alert triggers and provider errors. Usage is only
+// recorded when USAGE_STATS is enabled and never leaves this server.
+func (s *Server) handleUsageSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	days := defaultUsageDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUsageDays {
+			respondError(w, http.StatusBadRequest, INVALID_USAGE_DAYS)
+			return
+		}
+		days = n
+	}
+
+	summary, err := s.db.GetUsageSummary(days, time.Now())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	summary.Enabled = s.config.UsageStats
+
+	respondJSON(w, http.StatusOK, summary)
+}
+
+// handleAIUsage reports the tokens and estimated cost of AI calls by day,
+// provider and model. Costs are estimated from list prices; calls to models
+// without a known price are counted as unpriced.
+func (s *Server) handleAIUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, METHOD_NOT_ALLOWED)
+		return
+	}
+
+	days := defaultUsageDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUsageDays {
+			respondError(w, http.StatusBadRequest, INVALID_USAGE_DAYS)
+			return
+		}
+		days = n
+	}
+
+	summary, err := s.db.GetAIUsageSummary(days, time.Now())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"stockmarket/internal/config"
+	"stockmarket/internal/db"
+	"stockmarket/internal/events"
+	"stockmarket/internal/market"
+)
+
+// symbolPattern matches well-formed ticker symbols (e.g. AAPL, BRK.B, ^GSPC, 7203.T)
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9.\-^=]{1,12}$`)
+
+// symbolValidationTimeout bounds the quote lookup used to validate a new symbol
+const symbolValidationTimeout = 10 * time.Second
+
+// symbolQuoteTimeout bounds each symbol's quote while validating several, so
+// one slow symbol can't use up the whole validation budget
+const symbolQuoteTimeout = 3 * time.Second
+
+// WatchlistDiff describes the changes a watchlist replacement makes
+type WatchlistDiff struct {
+	Added     []string             `json:"added"`
+	Removed   []string             `json:"removed"`
+	Unchanged []string             `json:"unchanged"`
+	Symbols   []string             `json:"symbols"`
+	DryRun    bool                 `json:"dry_run"`
+	Cleanup   string               `json:"cleanup"`
+	Deleted   *db.WatchlistCleanup `json:"deleted,omitempty"`
+}
+
+// handleReplaceWatchlist replaces the full watchlist with the desired symbol
+// list, validating additions and returning the applied (or previewed) diff
+func (s *Server) handleReplaceWatchlist(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Symbols []string `json:"symbols"`
+		Cleanup string   `json:"cleanup"`
+		DryRun  bool     `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, INVALID_JSON)
+		return
+	}
+	if input.Symbols == nil {
+		respondError(w, http.StatusBadRequest, "Symbols list is required")
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		input.DryRun = true
+	}
+
+	switch input.Cleanup {
+	case "":
+		input.Cleanup = db.WatchlistCleanupKeep
+	case db.WatchlistCleanupKeep, db.WatchlistCleanupAlerts, db.WatchlistCleanupAll:
+	default:
+		respondError(w, http.StatusBadRequest, "Cleanup must be one of: keep, alerts, all")
+		return
+	}
+
+	// Normalize and dedupe, preserving the requested order
+	desired := []string{}
+	malformed := map[string]string{}
+	for _, symbol := range input.Symbols {
+		symbol = market.NormalizeSymbol(symbol)
+		if symbol == "" || slices.Contains(desired, symbol) {
+			continue
+		}
+		if !symbolPattern.MatchString(symbol) {
+			malformed[symbol] = "malformed symbol"
+			continue
+		}
+		desired = append(desired, symbol)
+	}
+	if len(malformed) > 0 {
+		respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Invalid symbols",
+			"invalid": malformed,
+		})
+		return
+	}
+
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	diff := WatchlistDiff{
+		Added:     []string{},
+		Removed:   []string{},
+		Unchanged: []string{},
+		Symbols:   desired,
+		DryRun:    input.DryRun,
+		Cleanup:   input.Cleanup,
+	}
+	for _, symbol := range desired {
+		if slices.Contains(cfg.TrackedSymbols, symbol) {
+			diff.Unchanged = append(diff.Unchanged, symbol)
+		} else {
+			diff.Added = append(diff.Added, symbol)
+		}
+	}
+	for _, symbol := range cfg.TrackedSymbols {
+		if !slices.Contains(desired, symbol) {
+			diff.Removed = append(diff.Removed, symbol)
+		}
+	}
+
+	// Validate all additions before applying anything
+	if len(diff.Added) > 0 {
+		apiKey := ""
+		if cfg.MarketDataAPIKey != "" {
+			apiKey, _ = config.Decrypt(cfg.MarketDataAPIKey, s.config.EncryptionKey)
+		}
+		provider, err := s.newMarketProvider(cfg.MarketDataProvider, apiKey)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Market provider error: "+err.Error())
+			return
+		}
+
+		if invalid := validateSymbols(r.Context(), provider, diff.Added); len(invalid) > 0 {
+			respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"error":   "Invalid symbols",
+				"invalid": invalid,
+				"diff":    diff,
+			})
+			return
+		}
+	}
+
+	if input.DryRun {
+		respondJSON(w, http.StatusOK, diff)
+		return
+	}
+
+	diff.Deleted, err = s.db.ReplaceWatchlist(cfg.ID, desired, diff.Removed, input.Cleanup)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, FAILED_TO_UPDATE_CONFIG)
+		return
+	}
+
+	if len(diff.Added) > 0 || len(diff.Removed) > 0 {
+		s.bus.Publish(events.WatchlistChanged, events.WatchlistChangedPayload{
+			Added:   diff.Added,
+			Removed: diff.Removed,
+		})
+	}
+
+	respondJSON(w, http.StatusOK, diff)
+}
+
+// validateSymbols looks up each symbol concurrently and returns a map of the
+// ones that could not be quoted to the reason
+func validateSymbols(ctx context.Context, provider market.Provider, symbols []string) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, symbolValidationTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	invalid := map[string]string{}
+	for _, symbol := range symbols {
+		wg.Add(1)
+		go func(symbol string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, symbolQuoteTimeout)
+			defer cancel()
+			quote, err := provider.GetQuote(ctx, symbol)
+			if err == nil && quote != nil && quote.Price > 0 {
+				return
+			}
+			reason := "no price data"
+			if errors.Is(err, market.ErrInvalidSymbol) {
+				reason = "symbol not found"
+			} else if err != nil {
+				reason = err.Error()
+			}
+			mu.Lock()
+			invalid[symbol] = reason
+			mu.Unlock()
+		}(symbol)
+	}
+	wg.Wait()
+
+	if len(invalid) == 0 {
+		return nil
+	}
+	return invalid
+}
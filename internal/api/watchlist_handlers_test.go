@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// watchlistProvider quotes every symbol but BAD and reports the history it
+// is asked for
+type watchlistProvider struct {
+	market.Provider
+	history chan string
+}
+
+func (p *watchlistProvider) Name() string { return "test" }
+
+func (p *watchlistProvider) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	if symbol == "BAD" {
+		return nil, market.ErrInvalidSymbol
+	}
+	return &models.Quote{Symbol: symbol, Price: 100}, nil
+}
+
+func (p *watchlistProvider) GetHistoricalData(ctx context.Context, symbol, period string) ([]models.Candle, error) {
+	p.history <- symbol
+	return nil, nil
+}
+
+// newWatchlistServer returns a server tracking AAPL and MSFT
+func newWatchlistServer(t *testing.T) (*Server, *watchlistProvider) {
+	t.Helper()
+	s := newTestServer(t)
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TrackedSymbols = []string{"AAPL", "MSFT"}
+	if err := s.db.UpdateConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &watchlistProvider{history: make(chan string, 10)}
+	s.newMarketProvider = func(name, apiKey string) (market.Provider, error) { return provider, nil }
+	return s, provider
+}
+
+// replaceWatchlist calls PUT /api/config/watchlist with body
+func replaceWatchlist(s *Server, query, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/api/config/watchlist"+query, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.handleReplaceWatchlist(rec, req)
+	return rec
+}
+
+// trackedSymbols returns the stored watchlist
+func trackedSymbols(t *testing.T, s *Server) []string {
+	t.Helper()
+	cfg, err := s.db.GetOrCreateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.TrackedSymbols
+}
+
+func TestReplaceWatchlistDryRun(t *testing.T) {
+	s, _ := newWatchlistServer(t)
+
+	rec := replaceWatchlist(s, "?dry_run=true", `{"symbols": ["aapl", "NVDA"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var diff WatchlistDiff
+	if err := json.NewDecoder(rec.Body).Decode(&diff); err != nil {
+		t.Fatal(err)
+	}
+	if !diff.DryRun || !slices.Equal(diff.Added, []string{"NVDA"}) || !slices.Equal(diff.Removed, []string{"MSFT"}) ||
+		!slices.Equal(diff.Unchanged, []string{"AAPL"}) || diff.Deleted != nil {
+		t.Fatalf("diff = %+v", diff)
+	}
+	if got := trackedSymbols(t, s); !slices.Equal(got, []string{"AAPL", "MSFT"}) {
+		t.Fatalf("dry run changed the watchlist to %v", got)
+	}
+}
+
+func TestReplaceWatchlistPartialValidationFailure(t *testing.T) {
+	s, _ := newWatchlistServer(t)
+
+	rec := replaceWatchlist(s, "", `{"symbols": ["AAPL", "NVDA", "BAD"]}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Invalid map[string]string `json:"invalid"`
+		Diff    WatchlistDiff     `json:"diff"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Invalid) != 1 || body.Invalid["BAD"] != "symbol not found" {
+		t.Fatalf("invalid = %v, want only BAD", body.Invalid)
+	}
+	if !slices.Equal(body.Diff.Added, []string{"NVDA", "BAD"}) {
+		t.Fatalf("diff = %+v", body.Diff)
+	}
+	// Nothing is applied when any addition is invalid
+	if got := trackedSymbols(t, s); !slices.Equal(got, []string{"AAPL", "MSFT"}) {
+		t.Fatalf("failed replace changed the watchlist to %v", got)
+	}
+}
+
+func TestReplaceWatchlistBackfillsAdditions(t *testing.T) {
+	s, provider := newWatchlistServer(t)
+
+	rec := replaceWatchlist(s, "", `{"symbols": ["AAPL", "NVDA"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if got := trackedSymbols(t, s); !slices.Equal(got, []string{"AAPL", "NVDA"}) {
+		t.Fatalf("watchlist = %v", got)
+	}
+	select {
+	case symbol := <-provider.history:
+		if symbol != "NVDA" {
+			t.Fatalf("backfilled %s, want NVDA", symbol)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the added symbol was not backfilled")
+	}
+}
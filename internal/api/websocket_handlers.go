@@ -2,21 +2,26 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"sync"
 	"time"
 
 	"stockmarket/internal/config"
+	"stockmarket/internal/events"
 	"stockmarket/internal/market"
 	"stockmarket/internal/models"
+	"stockmarket/internal/rules"
 
 	"github.com/gorilla/websocket"
 )
 
 const (
 	PRICE_ALERT = "Price Alert: %s"
+	TEST_ALERT  = "Test of your %s %s alert: its notifications will arrive like this when it fires"
 )
 
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -42,7 +47,7 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Get user config for tracked symbols
 	cfg, err := s.db.GetOrCreateConfig()
 	if err != nil {
-		log.Printf(FAILED_TO_GET_CONFIG, err)
+		log.Printf("WebSocket config error: %v", err)
 		conn.WriteJSON(map[string]string{"type": "error", "message": FAILED_TO_GET_CONFIG})
 		return
 	}
@@ -122,64 +127,176 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Check alerts for this quote
-			s.checkAndTriggerAlerts(quote, cfg, conn, &writeMu)
+			s.checkAndTriggerAlerts(ctx, provider, quote, cfg, conn, &writeMu)
 		}
 	}
 }
 
 // checkAndTriggerAlerts checks if any price alerts should be triggered for a quote
-func (s *Server) checkAndTriggerAlerts(quote models.Quote, cfg *models.UserConfig, conn *websocket.Conn, writeMu *sync.Mutex) {
+func (s *Server) checkAndTriggerAlerts(ctx context.Context, provider market.Provider, quote models.Quote, cfg *models.UserConfig, conn *websocket.Conn, writeMu *sync.Mutex) {
 	alerts, err := s.db.GetActiveAlerts()
 	if err != nil {
 		return
 	}
+	price, session := alertPrice(&quote, cfg)
+	metrics := newQuoteMetrics(ctx, provider, &quote, price)
 
 	for _, alert := range alerts {
-		if alert.Symbol != quote.Symbol {
+		if alert.Symbol != quote.Symbol || !alert.Enabled || coolingDown(alert, time.Now()) {
 			continue
 		}
 
-		var triggered bool
-		switch alert.Condition {
-		case "above":
-			triggered = quote.Price >= alert.Price
-		case "below":
-			triggered = quote.Price <= alert.Price
-		}
-
-		if triggered {
-			// Mark alert as triggered in database
-			s.db.TriggerAlert(alert.ID)
-
+		if s.priceTriggered(&alert, price, metrics) {
+			// Mark alert as triggered, or re-arm it after its cooldown. Other
+			// clients and the poller see the same quote; only one fires it.
+			if fired, err := s.fireAlert(alert); err != nil || !fired {
+				if err != nil {
+					log.Printf("Failed to trigger alert %d: %v", alert.ID, err)
+				}
+				continue
+			}
 			// Create alert message
-			message := fmt.Sprintf("%s is now $%.2f (%s $%.2f)", alert.Symbol, quote.Price, alert.Condition, alert.Price)
+			message := alertMessage(alert, price, session)
 
 			// Send alert to this WebSocket client
 			writeMu.Lock()
 			conn.WriteJSON(map[string]interface{}{
 				"type":    "alert",
 				"title":   fmt.Sprintf(PRICE_ALERT, alert.Symbol),
-				"message": message,
+				"message": withAlertNote(alert, message),
 				"symbol":  alert.Symbol,
-				"price":   quote.Price,
+				"price":   price,
 			})
 			writeMu.Unlock()
 
-			// Also broadcast to all other clients
-			s.BroadcastAlert(alert.Symbol, message)
+			// Also broadcast to all other clients and notify
+			s.announceAlert(alert, price, message, cfg)
+		}
+	}
+}
+
+// alertPrice returns the price that price alerts are evaluated against: the
+// extended-hours trade during pre-market and after-hours when enabled in the
+// config, otherwise the regular-session price. The session suffix labels
+// extended-hours prices in alert messages.
+func alertPrice(quote *models.Quote, cfg *models.UserConfig) (price float64, session string) {
+	if !cfg.ExtendedHoursAlerts || quote.ExtendedPrice <= 0 {
+		return quote.Price, ""
+	}
+	switch quote.Session {
+	case market.MarketStatePreMarket:
+		return quote.ExtendedPrice, " pre-market"
+	case market.MarketStateAfterHours:
+		return quote.ExtendedPrice, " after hours"
+	}
+	return quote.Price, ""
+}
 
-			// Send external notifications
-			notification := models.Notification{
-				Type:    "price_alert",
-				Title:   fmt.Sprintf(PRICE_ALERT, alert.Symbol),
-				Message: message,
-				Symbol:  alert.Symbol,
+// priceTriggered reports whether a price, trailing or composite alert fires
+// at price, composite rules being evaluated on metrics. A recurring alert
+// that fired is re-armed instead, once price leaves its hysteresis band.
+func (s *Server) priceTriggered(alert *models.PriceAlert, price float64, metrics rules.Source) bool {
+	if alert.Rearming {
+		if outsideHysteresis(*alert, price) {
+			if err := s.db.RearmAlert(alert.ID); err != nil {
+				log.Printf("Failed to re-arm alert %d: %v", alert.ID, err)
 			}
-			go s.notifyService.SendToChannels(notification, cfg.NotificationChannels)
+		}
+		return false
+	}
+	switch alert.Condition {
+	case "above":
+		return price >= alert.Price
+	case "below":
+		return price <= alert.Price
+	case "trail_percent", "trail_amount":
+		return s.trailingTriggered(alert, price)
+	case "composite":
+		return alert.Rule != nil && rules.Evaluate(*alert.Rule, metrics)
+	}
+	return false
+}
+
+// outsideHysteresis reports whether price is back below the hysteresis band
+// under the level of an "above" alert, or above the band over the level of
+// a "below" alert
+func outsideHysteresis(alert models.PriceAlert, price float64) bool {
+	band := alert.Price * alert.HysteresisPercent / 100
+	if alert.Condition == "below" {
+		return price > alert.Price+band
+	}
+	return price < alert.Price-band
+}
+
+// trailingTriggered raises the high-water mark of a trailing alert to price
+// and reports whether price has fallen to the trailing stop below it. The
+// first price seen sets the mark.
+func (s *Server) trailingTriggered(alert *models.PriceAlert, price float64) bool {
+	if price > alert.PeakPrice {
+		if err := s.db.RaiseAlertPeak(alert.ID, price); err != nil {
+			log.Printf("Failed to record peak of trailing alert %d: %v", alert.ID, err)
+		}
+		alert.PeakPrice = price
+		return false
+	}
+	return price <= trailingStop(*alert)
+}
+
+// trailingStop is the price at which a trailing alert fires: its trailing
+// distance, in percent or dollars, below the high-water mark
+func trailingStop(alert models.PriceAlert) float64 {
+	if alert.Condition == "trail_percent" {
+		return alert.PeakPrice * (1 - alert.Price/100)
+	}
+	return alert.PeakPrice - alert.Price
+}
+
+// coolingDown reports whether a recurring alert fired less than its cooldown
+// before now
+func coolingDown(alert models.PriceAlert, now time.Time) bool {
+	if !alert.Recurring || alert.TriggeredAt == nil {
+		return false
+	}
+	return now.Sub(*alert.TriggeredAt) < time.Duration(alert.CooldownMinutes)*time.Minute
+}
+
+// fireAlert marks an alert as triggered, or records when a recurring alert
+// fired so that it re-arms after its cooldown and hysteresis band. fired is
+// false when another evaluation fired the alert first.
+func (s *Server) fireAlert(alert models.PriceAlert) (fired bool, err error) {
+	if alert.Recurring {
+		cooledAt := time.Now().Add(-time.Duration(alert.CooldownMinutes) * time.Minute)
+		return s.db.MarkRecurringAlertFired(alert.ID, cooledAt, alert.HysteresisPercent > 0)
+	}
+	return s.db.TriggerAlert(alert.ID)
+}
 
-			log.Printf("Alert triggered: %s", message)
+// alertMessage describes a triggered price or trailing alert
+func alertMessage(alert models.PriceAlert, price float64, session string) string {
+	switch alert.Condition {
+	case "trail_percent":
+		return fmt.Sprintf("%s is now $%.2f%s, %.2f%% below its $%.2f peak (trailing stop %.2f%%)",
+			alert.Symbol, price, session, (alert.PeakPrice-price)/alert.PeakPrice*100, alert.PeakPrice, alert.Price)
+	case "trail_amount":
+		return fmt.Sprintf("%s is now $%.2f%s, $%.2f below its $%.2f peak (trailing stop $%.2f)",
+			alert.Symbol, price, session, alert.PeakPrice-price, alert.PeakPrice, alert.Price)
+	case "composite":
+		if alert.Rule != nil {
+			return fmt.Sprintf("%s is now $%.2f%s: %s", alert.Symbol, price, session, rules.Describe(*alert.Rule))
 		}
 	}
+	return fmt.Sprintf("%s is now $%.2f%s (%s $%.2f)", alert.Symbol, price, session, alert.Condition, alert.Price)
+}
+
+// withAlertNote adds the label and note of an alert to a message about it
+func withAlertNote(alert models.PriceAlert, message string) string {
+	if alert.Label != "" {
+		message = "[" + alert.Label + "] " + message
+	}
+	if alert.Note != "" {
+		message += "\nNote: " + alert.Note
+	}
+	return message
 }
 
 // BroadcastAlert sends an alert message to all connected WebSocket clients
@@ -259,7 +376,18 @@ func (s *Server) pollAndCheckAlerts(ctx context.Context) {
 	// Get quotes for all tracked symbols
 	for _, symbol := range cfg.TrackedSymbols {
 		quote, err := provider.GetQuote(ctx, symbol)
+		if errors.Is(err, market.ErrCircuitOpen) {
+			// The provider is down; the rest of the watchlist would fail too
+			return
+		}
 		if err != nil {
+			if !errors.Is(err, market.ErrInvalidSymbol) && !market.Rejected(err) {
+				s.bus.Publish(events.ProviderFailed, events.ProviderFailedPayload{
+					Kind:     "market",
+					Provider: cfg.MarketDataProvider,
+					Err:      err,
+				})
+			}
 			continue
 		}
 
@@ -274,38 +402,80 @@ func (s *Server) pollAndCheckAlerts(ctx context.Context) {
 		if err != nil {
 			continue
 		}
+		price, session := alertPrice(quote, cfg)
+		metrics := newQuoteMetrics(ctx, provider, quote, price)
 
 		for _, alert := range alerts {
-			if alert.Symbol != quote.Symbol {
+			if alert.Symbol != quote.Symbol || !alert.Enabled || coolingDown(alert, time.Now()) {
 				continue
 			}
 
-			var triggered bool
-			switch alert.Condition {
-			case "above":
-				triggered = quote.Price >= alert.Price
-			case "below":
-				triggered = quote.Price <= alert.Price
+			if s.priceTriggered(&alert, price, metrics) {
+				if fired, err := s.fireAlert(alert); err != nil || !fired {
+					if err != nil {
+						log.Printf("Failed to trigger alert %d: %v", alert.ID, err)
+					}
+					continue
+				}
+				s.announceAlert(alert, price, alertMessage(alert, price, session), cfg)
 			}
+		}
 
-			if triggered {
-				s.db.TriggerAlert(alert.ID)
-				message := fmt.Sprintf("%s is now $%.2f (%s $%.2f)", alert.Symbol, quote.Price, alert.Condition, alert.Price)
+		s.checkGapAlerts(quote, alerts, cfg, time.Now())
+		s.checkRSIAlerts(ctx, provider, quote, alerts, cfg)
+		s.checkExtremeAlerts(ctx, provider, quote, alerts, cfg)
+	}
+}
 
-				// Broadcast alert to all clients
-				s.BroadcastAlert(alert.Symbol, message)
+// isGap reports whether an alert condition is an opening gap: "gap" in
+// either direction, "gap_up" or "gap_down"
+func isGap(condition string) bool {
+	return condition == "gap" || condition == "gap_up" || condition == "gap_down"
+}
 
-				// Send external notifications
-				notification := models.Notification{
-					Type:    "price_alert",
-					Title:   fmt.Sprintf(PRICE_ALERT, alert.Symbol),
-					Message: message,
-					Symbol:  alert.Symbol,
-				}
-				go s.notifyService.SendToChannels(notification, cfg.NotificationChannels)
+// checkGapAlerts evaluates each opening gap alert for a symbol once per
+// trading day of its exchange, on the first quote seen after the exchange
+// opens or after the alert was created
+func (s *Server) checkGapAlerts(quote *models.Quote, alerts []models.PriceAlert, cfg *models.UserConfig, now time.Time) {
+	exchange := market.ResolveExchange(cfg.SymbolExchanges, quote.Symbol)
+	if !market.IsExchangeOpen(exchange, now) || quote.Open <= 0 || quote.PreviousClose <= 0 {
+		return
+	}
+	day := market.ExchangeDay(exchange, now)
+	gap := (quote.Open - quote.PreviousClose) / quote.PreviousClose * 100
 
-				log.Printf("Alert triggered (polling): %s", message)
-			}
+	for _, alert := range alerts {
+		if alert.Symbol != quote.Symbol || !isGap(alert.Condition) || !alert.Enabled {
+			continue
+		}
+		// Each alert is evaluated once a day, so one created after the open is
+		// still checked against that day's gap
+		s.gapCheckedMu.Lock()
+		checked := s.gapChecked[alert.ID] == day
+		s.gapChecked[alert.ID] = day
+		s.gapCheckedMu.Unlock()
+		if checked {
+			continue
+		}
+		// Already fired today (e.g. before a restart)
+		if alert.LastFiredDate == day || math.Abs(gap) < alert.Price {
+			continue
 		}
+		if (alert.Condition == "gap_up" && gap < 0) || (alert.Condition == "gap_down" && gap > 0) {
+			continue
+		}
+
+		if err := s.db.MarkAlertFired(alert.ID, day); err != nil {
+			log.Printf("Failed to record gap alert %d: %v", alert.ID, err)
+			continue
+		}
+		direction := "up"
+		if gap < 0 {
+			direction = "down"
+		}
+		message := fmt.Sprintf("%s gapped %s %.2f%% at the open ($%.2f vs previous close $%.2f)",
+			alert.Symbol, direction, math.Abs(gap), quote.Open, quote.PreviousClose)
+
+		s.announceAlert(alert, quote.Open, message, cfg)
 	}
 }
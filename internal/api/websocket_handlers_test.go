@@ -0,0 +1,76 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"stockmarket/internal/events"
+	"stockmarket/internal/models"
+)
+
+func TestGapAlertsCreatedAfterTheOpen(t *testing.T) {
+	s := newTestServer(t)
+	triggered := make(chan int64, 10)
+	s.bus.Subscribe(events.AlertTriggered, func(e events.Event) {
+		triggered <- e.Payload.(events.AlertTriggeredPayload).Alert.ID
+	})
+	// expect checks which alerts fired, in any order
+	expect := func(ids ...int64) {
+		t.Helper()
+		want := map[int64]bool{}
+		for _, id := range ids {
+			want[id] = true
+		}
+		for range ids {
+			select {
+			case id := <-triggered:
+				if !want[id] {
+					t.Fatalf("alert %d fired, want %v", id, ids)
+				}
+				delete(want, id)
+			case <-time.After(time.Second):
+				t.Fatalf("alerts %v did not fire", want)
+			}
+		}
+		select {
+		case id := <-triggered:
+			t.Fatalf("alert %d fired again", id)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	activeAlerts := func() []models.PriceAlert {
+		t.Helper()
+		alerts, err := s.db.GetActiveAlerts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return alerts
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := time.Date(2026, 3, 4, 9, 35, 0, 0, ny) // a Wednesday
+	quote := &models.Quote{Symbol: "AAPL", Price: 103, Open: 103, PreviousClose: 100}
+	cfg := &models.UserConfig{}
+
+	first := &models.PriceAlert{Symbol: "AAPL", Condition: "gap_up", Price: 2, Enabled: true}
+	if err := s.db.SavePriceAlert(first); err != nil {
+		t.Fatal(err)
+	}
+	s.checkGapAlerts(quote, activeAlerts(), cfg, open)
+	expect(first.ID)
+
+	// An alert created later in the day is still checked against the gap
+	second := &models.PriceAlert{Symbol: "AAPL", Condition: "gap", Price: 1, Enabled: true}
+	if err := s.db.SavePriceAlert(second); err != nil {
+		t.Fatal(err)
+	}
+	s.checkGapAlerts(quote, activeAlerts(), cfg, open.Add(2*time.Hour))
+	expect(second.ID)
+
+	// And every alert only once that day
+	s.checkGapAlerts(quote, activeAlerts(), cfg, open.Add(3*time.Hour))
+	expect()
+}
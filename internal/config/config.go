@@ -8,14 +8,39 @@ import (
 	"errors"
 	"io"
 	"os"
+	"strconv"
+	"time"
+
+	"stockmarket/internal/httpclient"
+	"stockmarket/internal/market"
+	"stockmarket/internal/notify"
 )
 
 // Config holds application configuration
 type Config struct {
 	Port          string
 	DatabasePath  string
+	DatabaseURL   string // PostgreSQL URL; when set, DatabasePath is unused
 	EncryptionKey []byte // 32 bytes for AES-256
 	Environment   string
+
+	// Shared HTTP client settings, tunable via MARKET_HTTP_*, AI_HTTP_* and NOTIFY_HTTP_*
+	MarketHTTP httpclient.Settings
+	AIHTTP     httpclient.Settings
+	NotifyHTTP httpclient.Settings
+
+	// QuoteCacheTTL is how long fetched quotes are shared (QUOTE_CACHE_TTL, 0 disables)
+	QuoteCacheTTL time.Duration
+
+	// NotifyDedupWindow suppresses a notification identical to one a channel
+	// was sent within it (NOTIFY_DEDUP_WINDOW, 0 disables)
+	NotifyDedupWindow time.Duration
+	// NotifyRateLimit caps the notifications a channel is sent an hour
+	// (NOTIFY_RATE_LIMIT, 0 disables)
+	NotifyRateLimit int
+
+	// UsageStats enables the local-only usage rollup (USAGE_STATS=true)
+	UsageStats bool
 }
 
 // Load loads configuration from environment variables
@@ -29,6 +54,7 @@ func Load() (*Config, error) {
 	if dbPath == "" {
 		dbPath = "./stockmarket.db"
 	}
+	dbURL := os.Getenv("DATABASE_URL")
 
 	env := os.Getenv("ENVIRONMENT")
 	if env == "" {
@@ -52,11 +78,64 @@ func Load() (*Config, error) {
 		}
 	}
 
+	marketHTTP, err := httpclient.FromEnv("MARKET_HTTP", httpclient.MarketDefaults)
+	if err != nil {
+		return nil, err
+	}
+	aiHTTP, err := httpclient.FromEnv("AI_HTTP", httpclient.AIDefaults)
+	if err != nil {
+		return nil, err
+	}
+	notifyHTTP, err := httpclient.FromEnv("NOTIFY_HTTP", httpclient.NotifyDefaults)
+	if err != nil {
+		return nil, err
+	}
+
+	quoteCacheTTL := market.DefaultQuoteCacheTTL
+	if v := os.Getenv("QUOTE_CACHE_TTL"); v != "" {
+		quoteCacheTTL, err = time.ParseDuration(v)
+		if err != nil || quoteCacheTTL < 0 {
+			return nil, errors.New("QUOTE_CACHE_TTL must be a duration such as 30s, or 0 to disable")
+		}
+	}
+
+	notifyDedupWindow := notify.DefaultDedupWindow
+	if v := os.Getenv("NOTIFY_DEDUP_WINDOW"); v != "" {
+		notifyDedupWindow, err = time.ParseDuration(v)
+		if err != nil || notifyDedupWindow < 0 {
+			return nil, errors.New("NOTIFY_DEDUP_WINDOW must be a duration such as 15m, or 0 to disable")
+		}
+	}
+
+	notifyRateLimit := notify.DefaultHourlyLimit
+	if v := os.Getenv("NOTIFY_RATE_LIMIT"); v != "" {
+		notifyRateLimit, err = strconv.Atoi(v)
+		if err != nil || notifyRateLimit < 0 {
+			return nil, errors.New("NOTIFY_RATE_LIMIT must be a number of notifications per hour, or 0 to disable")
+		}
+	}
+
+	usageStats := false
+	if v := os.Getenv("USAGE_STATS"); v != "" {
+		usageStats, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("USAGE_STATS must be true or false")
+		}
+	}
+
 	return &Config{
-		Port:          port,
-		DatabasePath:  dbPath,
-		EncryptionKey: encKey,
-		Environment:   env,
+		Port:              port,
+		DatabasePath:      dbPath,
+		DatabaseURL:       dbURL,
+		EncryptionKey:     encKey,
+		Environment:       env,
+		MarketHTTP:        marketHTTP,
+		AIHTTP:            aiHTTP,
+		NotifyHTTP:        notifyHTTP,
+		QuoteCacheTTL:     quoteCacheTTL,
+		NotifyDedupWindow: notifyDedupWindow,
+		NotifyRateLimit:   notifyRateLimit,
+		UsageStats:        usageStats,
 	}, nil
 }
 
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// RecordAIUsage records the tokens and estimated cost of an AI call made for
+// symbol at t
+func (db *DB) RecordAIUsage(symbol string, usage *models.TokenUsage, t time.Time) error {
+	var cost sql.NullFloat64
+	if usage.CostUSD != nil {
+		cost = sql.NullFloat64{Float64: *usage.CostUSD, Valid: true}
+	}
+	_, err := db.conn.Exec(`
+		INSERT INTO ai_usage (day, provider, model, symbol, prompt_tokens, completion_tokens, cost_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, t.Format(usageDayFormat), usage.Provider, usage.Model, symbol,
+		usage.PromptTokens, usage.CompletionTokens, cost)
+	return err
+}
+
+// GetAIUsageSummary sums the AI calls of the last days days, including today,
+// by day, provider and model
+func (db *DB) GetAIUsageSummary(days int, now time.Time) (*models.AIUsageSummary, error) {
+	start := now.AddDate(0, 0, -(days - 1))
+	summary := &models.AIUsageSummary{
+		Days:  days,
+		Since: start.Format(usageDayFormat),
+		Daily: make([]models.AIUsageTotals, 0, days),
+	}
+
+	byDay := map[string]*models.AIUsageTotals{}
+	for i := 0; i < days; i++ {
+		date := start.AddDate(0, 0, i).Format(usageDayFormat)
+		summary.Daily = append(summary.Daily, models.AIUsageTotals{Date: date})
+		byDay[date] = &summary.Daily[i]
+	}
+
+	daily, err := db.aiUsageTotals("day, '', ''", "day", summary.Since)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range daily {
+		if day, ok := byDay[t.Date]; ok {
+			*day = t
+		}
+		summary.Totals.Calls += t.Calls
+		summary.Totals.PromptTokens += t.PromptTokens
+		summary.Totals.CompletionTokens += t.CompletionTokens
+		summary.Totals.CostUSD += t.CostUSD
+		summary.Totals.UnpricedCalls += t.UnpricedCalls
+	}
+
+	if summary.ByProvider, err = db.aiUsageTotals("'', provider, ''", "provider", summary.Since); err != nil {
+		return nil, err
+	}
+	if summary.ByModel, err = db.aiUsageTotals("'', provider, model", "provider, model", summary.Since); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// aiUsageTotals sums the AI calls since the given day grouped by groupBy;
+// keys selects the day, provider and model columns of each group
+func (db *DB) aiUsageTotals(keys, groupBy, since string) ([]models.AIUsageTotals, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+keys+`, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens),
+		       COALESCE(SUM(cost_usd), 0), SUM(CASE WHEN cost_usd IS NULL THEN 1 ELSE 0 END)
+		FROM ai_usage WHERE day >= ?
+		GROUP BY `+groupBy+` ORDER BY `+groupBy, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []models.AIUsageTotals{}
+	for rows.Next() {
+		var t models.AIUsageTotals
+		if err := rows.Scan(&t.Date, &t.Provider, &t.Model, &t.Calls, &t.PromptTokens,
+			&t.CompletionTokens, &t.CostUSD, &t.UnpricedCalls); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// SaveAlertTrigger records an alert firing
+func (db *DB) SaveAlertTrigger(t *models.AlertTrigger) error {
+	channelsJSON, _ := json.Marshal(t.Channels)
+	id, err := db.conn.Insert(`
+		INSERT INTO alert_triggers (alert_id, price, message, channels, triggered_at) VALUES (?, ?, ?, ?, ?)
+	`, t.AlertID, t.Price, t.Message, string(channelsJSON), t.TriggeredAt)
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	return nil
+}
+
+// GetAlertTriggers lists the latest times an alert fired, newest first; it
+// returns sql.ErrNoRows when there is no such alert
+func (db *DB) GetAlertTriggers(alertID int64, limit int) ([]models.AlertTrigger, error) {
+	var n int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM price_alerts WHERE id = ?`, alertID).Scan(&n); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT id, alert_id, price, message, channels, triggered_at
+		FROM alert_triggers WHERE alert_id = ? ORDER BY triggered_at DESC, id DESC LIMIT ?
+	`, alertID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	triggers := []models.AlertTrigger{}
+	for rows.Next() {
+		var t models.AlertTrigger
+		var channelsJSON string
+		if err := rows.Scan(&t.ID, &t.AlertID, &t.Price, &t.Message, &channelsJSON, &t.TriggeredAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(channelsJSON), &t.Channels)
+		if t.Channels == nil {
+			t.Channels = []string{}
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, rows.Err()
+}
+
+// GetAlertTriggersSince lists the alerts that fired since a time with their
+// symbols, oldest first
+func (db *DB) GetAlertTriggersSince(since time.Time) ([]models.DigestAlert, error) {
+	rows, err := db.conn.Query(`
+		SELECT a.symbol, t.message, t.price, t.triggered_at
+		FROM alert_triggers t JOIN price_alerts a ON a.id = t.alert_id
+		WHERE t.triggered_at >= ? ORDER BY t.triggered_at, t.id
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.DigestAlert{}
+	for rows.Next() {
+		var a models.DigestAlert
+		if err := rows.Scan(&a.Symbol, &a.Message, &a.Price, &a.At); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
@@ -0,0 +1,252 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// ExportArchive reads the configuration, presets, alerts, positions and
+// analyses of a config into an archive, leaving out demo data. API keys are
+// as stored, encrypted.
+func (db *DB) ExportArchive(configID int64) (*models.Archive, error) {
+	config, err := db.fetchConfigFromDB()
+	if err != nil {
+		return nil, err
+	}
+	archive := &models.Archive{
+		Version:    models.ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Config:     *config,
+	}
+	if archive.Config.NotificationChannels == nil {
+		archive.Config.NotificationChannels = []models.NotificationConfig{}
+	}
+
+	if archive.Presets, err = db.GetAnalysisPresets(configID); err != nil {
+		return nil, err
+	}
+	if archive.Presets == nil {
+		archive.Presets = []models.AnalysisPreset{}
+	}
+	if archive.Positions, err = db.GetPositions(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT id, symbol, condition, price, COALESCE(peak_price, 0), COALESCE(fast_period, 0), COALESCE(slow_period, 0),
+			COALESCE(period, 0), COALESCE(recurring, 0), COALESCE(cooldown_minutes, 0), expires_at,
+			COALESCE(hysteresis_percent, 0), COALESCE(enabled, 1), COALESCE(channels, ''), COALESCE(rule, ''),
+			COALESCE(label, ''), COALESCE(note, ''), triggered, COALESCE(last_fired_date, ''), created_at
+		FROM price_alerts WHERE COALESCE(demo, 0) = 0 ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	archive.Alerts = []models.PriceAlert{}
+	for rows.Next() {
+		var a models.PriceAlert
+		var expiresAt sql.NullTime
+		var channelsJSON, ruleJSON string
+		if err := rows.Scan(&a.ID, &a.Symbol, &a.Condition, &a.Price, &a.PeakPrice, &a.FastPeriod, &a.SlowPeriod,
+			&a.Period, &a.Recurring, &a.CooldownMinutes, &expiresAt,
+			&a.HysteresisPercent, &a.Enabled, &channelsJSON, &ruleJSON, &a.Label, &a.Note, &a.Triggered, &a.LastFiredDate, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			a.ExpiresAt = &expiresAt.Time
+		}
+		if channelsJSON != "" {
+			json.Unmarshal([]byte(channelsJSON), &a.Channels)
+		}
+		if ruleJSON != "" {
+			a.Rule = &models.AlertRule{}
+			json.Unmarshal([]byte(ruleJSON), a.Rule)
+		}
+		archive.Alerts = append(archive.Alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	analysisRows, err := db.conn.Query(`
+		SELECT ` + analysisColumns + `
+		FROM analysis_results WHERE COALESCE(demo, 0) = 0 ORDER BY generated_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	if archive.Analyses, err = scanAnalyses(analysisRows); err != nil {
+		return nil, err
+	}
+	if archive.Analyses == nil {
+		archive.Analyses = []models.AnalysisResponse{}
+	}
+
+	tagRows, err := db.conn.Query(`
+		SELECT tagged.analysis_id, t.name
+		FROM analysis_tags tagged JOIN tags t ON t.id = tagged.tag_id ORDER BY t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	tags := map[int64][]string{}
+	for tagRows.Next() {
+		var id int64
+		var name string
+		if err := tagRows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], name)
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range archive.Analyses {
+		archive.Analyses[i].Tags = tags[archive.Analyses[i].ID]
+	}
+
+	return archive, nil
+}
+
+// ImportArchive replaces the configuration, notification channels, presets,
+// alerts, positions, analyses and tags of a config with those of an archive,
+// in a single transaction. API keys must already be encrypted for this
+// database. Notifications sent and the scheduling history are kept.
+func (db *DB) ImportArchive(configID int64, archive *models.Archive) (*models.ArchiveCounts, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	config := archive.Config
+	config.ID = configID
+	if err := updateConfig(tx, &config); err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM notification_channels WHERE config_id = ?`,
+		`DELETE FROM analysis_presets WHERE config_id = ?`,
+		`DELETE FROM demo_state WHERE config_id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, configID); err != nil {
+			return nil, err
+		}
+	}
+	for _, table := range []string{"price_alerts", "positions", "analysis_results", "tags"} {
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ch := range config.NotificationChannels {
+		eventsJSON, _ := json.Marshal(ch.Events)
+		if _, err := tx.Exec(`
+			INSERT INTO notification_channels (config_id, type, target, enabled, events, digest, template)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, configID, ch.Type, ch.Target, ch.Enabled, string(eventsJSON), ch.Digest, ch.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range archive.Presets {
+		if _, err := tx.Exec(`
+			INSERT INTO analysis_presets (config_id, name, ai_provider, ai_model, ai_provider_api_key,
+				trade_frequency, user_context, history_period, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, configID, p.Name, p.AIProvider, p.AIModel, p.AIProviderAPIKey,
+			p.TradeFrequency, p.UserContext, p.HistoryPeriod, p.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range archive.Alerts {
+		if _, err := tx.Exec(`
+			INSERT INTO price_alerts (symbol, condition, price, peak_price, fast_period, slow_period, period,
+				recurring, cooldown_minutes, expires_at, hysteresis_percent, enabled, channels, rule,
+				label, note, triggered, last_fired_date, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, a.Symbol, a.Condition, a.Price, a.PeakPrice, a.FastPeriod, a.SlowPeriod, a.Period,
+			a.Recurring, a.CooldownMinutes, a.ExpiresAt, a.HysteresisPercent, a.Enabled, alertChannelsJSON(a.Channels),
+			alertRuleJSON(a.Rule), a.Label, a.Note, a.Triggered, a.LastFiredDate, a.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range archive.Positions {
+		if _, err := tx.Exec(`
+			INSERT INTO positions (symbol, shares, cost_basis, updated_at) VALUES (?, ?, ?, ?)
+		`, p.Symbol, p.Shares, p.CostBasis, p.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	tagIDs := map[string]int64{}
+	for _, a := range archive.Analyses {
+		priceTargetsJSON, _ := json.Marshal(a.PriceTargets)
+		risksJSON, _ := json.Marshal(a.Risks)
+		highlightsJSON, _ := json.Marshal(a.Highlights)
+		consensusJSON, relativeJSON := "", ""
+		if len(a.Consensus) > 0 {
+			b, _ := json.Marshal(a.Consensus)
+			consensusJSON = string(b)
+		}
+		if a.RelativeStrength != nil {
+			b, _ := json.Marshal(a.RelativeStrength)
+			relativeJSON = string(b)
+		}
+		var rating int
+		var note string
+		var ratedAt *time.Time
+		if a.Feedback != nil {
+			rating, note, ratedAt = a.Feedback.Rating, a.Feedback.Note, a.Feedback.RatedAt
+		}
+
+		id, err := tx.Insert(`
+			INSERT INTO analysis_results (symbol, action, confidence, reasoning, price_targets, risks, timeframe,
+				preset, ai_provider, quote_time, market_state, stale_data, highlights, consensus, relative_strength,
+				feedback_rating, feedback_note, feedback_rated_at, generated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, a.Symbol, a.Action, a.Confidence, a.Reasoning, string(priceTargetsJSON), string(risksJSON), a.Timeframe,
+			a.Preset, a.AIProvider, a.QuoteTime, a.MarketState, a.StaleData, string(highlightsJSON), consensusJSON, relativeJSON,
+			rating, note, ratedAt, a.GeneratedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range a.Tags {
+			tagID, ok := tagIDs[name]
+			if !ok {
+				if tagID, err = tx.Insert(`INSERT INTO tags (name) VALUES (?)`, name); err != nil {
+					return nil, err
+				}
+				tagIDs[name] = tagID
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO analysis_tags (analysis_id, tag_id) VALUES (?, ?)
+				ON CONFLICT(analysis_id, tag_id) DO NOTHING
+			`, id, tagID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.InvalidateConfigCache()
+
+	return &models.ArchiveCounts{
+		NotificationChannels: len(config.NotificationChannels),
+		Presets:              len(archive.Presets),
+		Alerts:               len(archive.Alerts),
+		Positions:            len(archive.Positions),
+		Analyses:             len(archive.Analyses),
+	}, nil
+}
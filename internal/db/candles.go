@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// SaveCandles stores daily candles fetched from provider, replacing stored
+// candles with the same timestamp, and records the sync. The covered range
+// only ever grows.
+func (db *DB) SaveCandles(provider, symbol string, candles []models.Candle, sync models.CandleSync) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO candles (provider, symbol, timestamp, open, high, low, close, volume)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(provider, symbol, timestamp) DO UPDATE SET
+			open = excluded.open, high = excluded.high, low = excluded.low,
+			close = excluded.close, volume = excluded.volume
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range candles {
+		if _, err := stmt.Exec(provider, symbol, c.Timestamp.Unix(), c.Open, c.High, c.Low, c.Close, c.Volume); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO candle_sync (provider, symbol, covered_from, synced_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(provider, symbol) DO UPDATE SET
+			covered_from = CASE WHEN excluded.covered_from < candle_sync.covered_from
+				THEN excluded.covered_from ELSE candle_sync.covered_from END, synced_at = excluded.synced_at
+	`, provider, symbol, sync.CoveredFrom.Unix(), sync.SyncedAt.Unix()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetCandles returns the stored daily candles of symbol within [from, to),
+// newest first
+func (db *DB) GetCandles(provider, symbol string, from, to time.Time) ([]models.Candle, error) {
+	rows, err := db.conn.Query(`
+		SELECT timestamp, open, high, low, close, volume FROM candles
+		WHERE provider = ? AND symbol = ? AND timestamp >= ? AND timestamp < ?
+		ORDER BY timestamp DESC
+	`, provider, symbol, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candles := []models.Candle{}
+	for rows.Next() {
+		var c models.Candle
+		var ts int64
+		if err := rows.Scan(&ts, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume); err != nil {
+			return nil, err
+		}
+		c.Timestamp = time.Unix(ts, 0)
+		candles = append(candles, c)
+	}
+	return candles, rows.Err()
+}
+
+// GetCandleSync returns the stored history range of symbol, nil when none
+// has been stored
+func (db *DB) GetCandleSync(provider, symbol string) (*models.CandleSync, error) {
+	var coveredFrom, syncedAt int64
+	err := db.conn.QueryRow(`
+		SELECT covered_from, synced_at FROM candle_sync WHERE provider = ? AND symbol = ?
+	`, provider, symbol).Scan(&coveredFrom, &syncedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.CandleSync{
+		CoveredFrom: time.Unix(coveredFrom, 0),
+		SyncedAt:    time.Unix(syncedAt, 0),
+	}, nil
+}
@@ -3,50 +3,77 @@ package db
 import (
 	"database/sql"
 	"encoding/json"
+	"maps"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"stockmarket/internal/market"
 	"stockmarket/internal/models"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// DB wraps the database connection
+// DB wraps the database connection, SQLite by default or PostgreSQL
 type DB struct {
-	conn *sql.DB
+	conn *conn
+
+	// path is the SQLite database file, empty on PostgreSQL
+	path string
+
+	// search is set when SQLite has FTS5 and the analysis search index is kept
+	search bool
 
 	// Config cache with TTL
 	configCache     *models.UserConfig
 	configCacheTime time.Time
 	configCacheMu   sync.RWMutex
+
+	// configHits and configMisses count config reads for diagnostics
+	configHits   atomic.Int64
+	configMisses atomic.Int64
 }
 
 // configCacheTTL is how long to cache config before refreshing
 const configCacheTTL = 5 * time.Second
 
-// New creates a new database connection and initializes schema
+// New creates a new SQLite database connection and initializes schema
 func New(path string) (*DB, error) {
-	conn, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_foreign_keys=on&_busy_timeout=5000")
+	sqlDB, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_foreign_keys=on&_busy_timeout=5000")
 	if err != nil {
 		return nil, err
 	}
 
 	// Connection pool settings for SQLite
 	// SQLite doesn't benefit from many connections, but these prevent resource exhaustion
-	conn.SetMaxOpenConns(25)
-	conn.SetMaxIdleConns(5)
-	conn.SetConnMaxLifetime(5 * time.Minute)
-	conn.SetConnMaxIdleTime(1 * time.Minute)
+	sqlDB.SetMaxOpenConns(25)
+	sqlDB.SetMaxIdleConns(5)
+	sqlDB.SetConnMaxLifetime(5 * time.Minute)
+	sqlDB.SetConnMaxIdleTime(1 * time.Minute)
+
+	db, err := open(&conn{DB: sqlDB})
+	if err != nil {
+		return nil, err
+	}
+	db.path = path
+	return db, nil
+}
 
+// open verifies the connection and migrates the schema
+func open(c *conn) (*DB, error) {
 	// Verify connection is working
-	if err := conn.Ping(); err != nil {
-		conn.Close()
+	if err := c.Ping(); err != nil {
+		c.Close()
 		return nil, err
 	}
 
-	db := &DB{conn: conn}
+	db := &DB{conn: c}
 	if err := db.migrate(); err != nil {
-		conn.Close()
+		c.Close()
+		return nil, err
+	}
+	if err := db.ensureSearchIndex(); err != nil {
+		c.Close()
 		return nil, err
 	}
 
@@ -58,81 +85,6 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
-// migrate runs database migrations
-func (db *DB) migrate() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS user_config (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		market_data_provider TEXT DEFAULT 'alphavantage',
-		market_data_api_key TEXT DEFAULT '',
-		ai_provider TEXT DEFAULT 'openai',
-		ai_provider_api_key TEXT DEFAULT '',
-		ai_model TEXT DEFAULT 'gpt-4o',
-		risk_tolerance TEXT DEFAULT 'moderate',
-		trade_frequency TEXT DEFAULT 'weekly',
-		tracked_symbols TEXT DEFAULT '[]',
-		polling_interval INTEGER DEFAULT 30,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS notification_channels (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		config_id INTEGER NOT NULL,
-		type TEXT NOT NULL,
-		target TEXT NOT NULL,
-		enabled INTEGER DEFAULT 1,
-		events TEXT DEFAULT '[]',
-		FOREIGN KEY (config_id) REFERENCES user_config(id) ON DELETE CASCADE
-	);
-
-	CREATE TABLE IF NOT EXISTS analysis_results (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		symbol TEXT NOT NULL,
-		action TEXT NOT NULL,
-		confidence REAL NOT NULL,
-		reasoning TEXT NOT NULL,
-		price_targets TEXT NOT NULL,
-		risks TEXT NOT NULL,
-		timeframe TEXT NOT NULL,
-		generated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS price_alerts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		symbol TEXT NOT NULL,
-		condition TEXT NOT NULL,
-		price REAL NOT NULL,
-		triggered INTEGER DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS notifications (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		type TEXT NOT NULL,
-		title TEXT NOT NULL,
-		message TEXT NOT NULL,
-		symbol TEXT NOT NULL,
-		channels TEXT NOT NULL,
-		sent_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_analysis_symbol ON analysis_results(symbol);
-	CREATE INDEX IF NOT EXISTS idx_analysis_generated ON analysis_results(generated_at);
-	CREATE INDEX IF NOT EXISTS idx_alerts_symbol ON price_alerts(symbol);
-	`
-
-	_, err := db.conn.Exec(schema)
-	if err != nil {
-		return err
-	}
-
-	// Run column migrations (ignore errors for existing columns)
-	db.conn.Exec(`ALTER TABLE user_config ADD COLUMN polling_interval INTEGER DEFAULT 30`)
-
-	return nil
-}
-
 // GetOrCreateConfig gets the user config or creates a default one (with caching)
 func (db *DB) GetOrCreateConfig() (*models.UserConfig, error) {
 	// Check cache first
@@ -141,13 +93,16 @@ func (db *DB) GetOrCreateConfig() (*models.UserConfig, error) {
 		// Return a copy to prevent mutation
 		cached := *db.configCache
 		cached.TrackedSymbols = append([]string{}, db.configCache.TrackedSymbols...)
+		cached.SymbolExchanges = maps.Clone(db.configCache.SymbolExchanges)
 		cached.NotificationChannels = append([]models.NotificationConfig{}, db.configCache.NotificationChannels...)
 		db.configCacheMu.RUnlock()
+		db.configHits.Add(1)
 		return &cached, nil
 	}
 	db.configCacheMu.RUnlock()
 
 	// Cache miss - fetch from DB
+	db.configMisses.Add(1)
 	config, err := db.fetchConfigFromDB()
 	if err != nil {
 		return nil, err
@@ -162,6 +117,7 @@ func (db *DB) GetOrCreateConfig() (*models.UserConfig, error) {
 	// Return a copy
 	result := *config
 	result.TrackedSymbols = append([]string{}, config.TrackedSymbols...)
+	result.SymbolExchanges = maps.Clone(config.SymbolExchanges)
 	result.NotificationChannels = append([]models.NotificationConfig{}, config.NotificationChannels...)
 	return &result, nil
 }
@@ -169,29 +125,46 @@ func (db *DB) GetOrCreateConfig() (*models.UserConfig, error) {
 // fetchConfigFromDB retrieves config directly from database
 func (db *DB) fetchConfigFromDB() (*models.UserConfig, error) {
 	var config models.UserConfig
-	var trackedSymbolsJSON string
+	var trackedSymbolsJSON, symbolExchangesJSON string
 
 	err := db.conn.QueryRow(`
 		SELECT id, market_data_provider, market_data_api_key, ai_provider,
-		       ai_provider_api_key, ai_model, risk_tolerance, trade_frequency,
-		       tracked_symbols, COALESCE(polling_interval, 30), created_at, updated_at
+		       ai_provider_api_key, ai_model, COALESCE(fallback_ai_provider, ''),
+		       COALESCE(fallback_ai_model, ''), COALESCE(fallback_ai_api_key, ''),
+		       COALESCE(azure_openai_endpoint, ''), risk_tolerance, trade_frequency,
+		       tracked_symbols, COALESCE(polling_interval, 30), COALESCE(min_store_confidence, 0),
+		       COALESCE(stale_quote_minutes, 15), COALESCE(extended_hours_alerts, 0),
+		       COALESCE(display_currency, 'USD'), COALESCE(symbol_exchanges, '{}'),
+		       COALESCE(benchmark_symbol, 'SPY'), COALESCE(include_news, 1),
+		       COALESCE(analysis_schedule, 'off'), COALESCE(analysis_schedule_time, '08:30'),
+		       COALESCE(ai_temperature, 0.3), COALESCE(ai_max_tokens, 2048), COALESCE(ai_reasoning_effort, ''),
+		       COALESCE(investment_thesis, ''), COALESCE(analysis_retention_days, 0),
+		       COALESCE(alert_retention_days, 0), COALESCE(notification_retention_days, 0),
+		       created_at, updated_at
 		FROM user_config LIMIT 1
 	`).Scan(
 		&config.ID, &config.MarketDataProvider, &config.MarketDataAPIKey,
 		&config.AIProvider, &config.AIProviderAPIKey, &config.AIModel,
-		&config.RiskTolerance, &config.TradeFrequency, &trackedSymbolsJSON,
-		&config.PollingInterval, &config.CreatedAt, &config.UpdatedAt,
+		&config.FallbackAIProvider, &config.FallbackAIModel, &config.FallbackAIAPIKey,
+		&config.AzureOpenAIEndpoint, &config.RiskTolerance, &config.TradeFrequency, &trackedSymbolsJSON,
+		&config.PollingInterval, &config.MinStoreConfidence, &config.StaleQuoteMinutes,
+		&config.ExtendedHoursAlerts, &config.DisplayCurrency, &symbolExchangesJSON,
+		&config.BenchmarkSymbol, &config.IncludeNews,
+		&config.AnalysisSchedule, &config.AnalysisScheduleTime,
+		&config.AITemperature, &config.AIMaxTokens, &config.AIReasoningEffort,
+		&config.InvestmentThesis, &config.Retention.AnalysisDays,
+		&config.Retention.AlertDays, &config.Retention.NotificationDays,
+		&config.CreatedAt, &config.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
 		// Create default config
-		result, err := db.conn.Exec(`
+		id, err := db.conn.Insert(`
 			INSERT INTO user_config (tracked_symbols, polling_interval) VALUES ('[]', 30)
 		`)
 		if err != nil {
 			return nil, err
 		}
-		id, _ := result.LastInsertId()
 		config.ID = id
 		config.MarketDataProvider = "alphavantage"
 		config.AIProvider = "openai"
@@ -200,6 +173,15 @@ func (db *DB) fetchConfigFromDB() (*models.UserConfig, error) {
 		config.TradeFrequency = "weekly"
 		config.TrackedSymbols = []string{}
 		config.PollingInterval = 30
+		config.StaleQuoteMinutes = 15
+		config.DisplayCurrency = "USD"
+		config.SymbolExchanges = map[string]string{}
+		config.BenchmarkSymbol = "SPY"
+		config.IncludeNews = true
+		config.AnalysisSchedule = "off"
+		config.AnalysisScheduleTime = "08:30"
+		config.AITemperature = 0.3
+		config.AIMaxTokens = 2048
 		config.CreatedAt = time.Now()
 		config.UpdatedAt = time.Now()
 		return &config, nil
@@ -210,11 +192,18 @@ func (db *DB) fetchConfigFromDB() (*models.UserConfig, error) {
 
 	// Parse tracked symbols
 	json.Unmarshal([]byte(trackedSymbolsJSON), &config.TrackedSymbols)
+	json.Unmarshal([]byte(symbolExchangesJSON), &config.SymbolExchanges)
+	if config.SymbolExchanges == nil {
+		config.SymbolExchanges = map[string]string{}
+	}
 
 	// Default polling interval if not set
 	if config.PollingInterval == 0 {
 		config.PollingInterval = 30
 	}
+	if config.StaleQuoteMinutes == 0 {
+		config.StaleQuoteMinutes = 15
+	}
 
 	// Load notification channels
 	channels, err := db.GetNotificationChannels(config.ID)
@@ -228,33 +217,71 @@ func (db *DB) fetchConfigFromDB() (*models.UserConfig, error) {
 
 // UpdateConfig updates the user configuration
 func (db *DB) UpdateConfig(config *models.UserConfig) error {
+	err := updateConfig(db.conn, config)
+
+	// Invalidate cache on update
+	if err == nil {
+		db.InvalidateConfigCache()
+	}
+
+	return err
+}
+
+// updateConfig writes the user configuration, but not its notification
+// channels, with e
+func updateConfig(e execer, config *models.UserConfig) error {
 	trackedSymbolsJSON, _ := json.Marshal(config.TrackedSymbols)
+	symbolExchangesJSON, _ := json.Marshal(config.SymbolExchanges)
+	if config.SymbolExchanges == nil {
+		symbolExchangesJSON = []byte("{}")
+	}
 
-	_, err := db.conn.Exec(`
+	_, err := e.Exec(`
 		UPDATE user_config SET
 			market_data_provider = ?,
 			market_data_api_key = ?,
 			ai_provider = ?,
 			ai_provider_api_key = ?,
 			ai_model = ?,
+			fallback_ai_provider = ?,
+			fallback_ai_model = ?,
+			fallback_ai_api_key = ?,
+			azure_openai_endpoint = ?,
 			risk_tolerance = ?,
 			trade_frequency = ?,
 			tracked_symbols = ?,
 			polling_interval = ?,
+			min_store_confidence = ?,
+			stale_quote_minutes = ?,
+			extended_hours_alerts = ?,
+			display_currency = ?,
+			symbol_exchanges = ?,
+			benchmark_symbol = ?,
+			include_news = ?,
+			analysis_schedule = ?,
+			analysis_schedule_time = ?,
+			ai_temperature = ?,
+			ai_max_tokens = ?,
+			ai_reasoning_effort = ?,
+			investment_thesis = ?,
+			analysis_retention_days = ?,
+			alert_retention_days = ?,
+			notification_retention_days = ?,
 			updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`,
 		config.MarketDataProvider, config.MarketDataAPIKey,
 		config.AIProvider, config.AIProviderAPIKey, config.AIModel,
-		config.RiskTolerance, config.TradeFrequency, string(trackedSymbolsJSON),
-		config.PollingInterval, config.ID,
+		config.FallbackAIProvider, config.FallbackAIModel, config.FallbackAIAPIKey,
+		config.AzureOpenAIEndpoint, config.RiskTolerance, config.TradeFrequency, string(trackedSymbolsJSON),
+		config.PollingInterval, config.MinStoreConfidence, config.StaleQuoteMinutes,
+		config.ExtendedHoursAlerts, config.DisplayCurrency, string(symbolExchangesJSON),
+		config.BenchmarkSymbol, config.IncludeNews,
+		config.AnalysisSchedule, config.AnalysisScheduleTime,
+		config.AITemperature, config.AIMaxTokens, config.AIReasoningEffort,
+		config.InvestmentThesis, config.Retention.AnalysisDays,
+		config.Retention.AlertDays, config.Retention.NotificationDays, config.ID,
 	)
-
-	// Invalidate cache on update
-	if err == nil {
-		db.InvalidateConfigCache()
-	}
-
 	return err
 }
 
@@ -268,7 +295,8 @@ func (db *DB) InvalidateConfigCache() {
 // GetNotificationChannels gets all notification channels for a config
 func (db *DB) GetNotificationChannels(configID int64) ([]models.NotificationConfig, error) {
 	rows, err := db.conn.Query(`
-		SELECT id, type, target, enabled, events FROM notification_channels WHERE config_id = ?
+		SELECT id, type, target, enabled, events, COALESCE(digest, 0), COALESCE(template, '')
+		FROM notification_channels WHERE config_id = ?
 	`, configID)
 	if err != nil {
 		return nil, err
@@ -278,12 +306,13 @@ func (db *DB) GetNotificationChannels(configID int64) ([]models.NotificationConf
 	var channels []models.NotificationConfig
 	for rows.Next() {
 		var ch models.NotificationConfig
-		var enabled int
+		var enabled, digest int
 		var eventsJSON string
-		if err := rows.Scan(&ch.ID, &ch.Type, &ch.Target, &enabled, &eventsJSON); err != nil {
+		if err := rows.Scan(&ch.ID, &ch.Type, &ch.Target, &enabled, &eventsJSON, &digest, &ch.Template); err != nil {
 			return nil, err
 		}
 		ch.Enabled = enabled == 1
+		ch.Digest = digest == 1
 		json.Unmarshal([]byte(eventsJSON), &ch.Events)
 		channels = append(channels, ch)
 	}
@@ -293,27 +322,28 @@ func (db *DB) GetNotificationChannels(configID int64) ([]models.NotificationConf
 // SaveNotificationChannel saves a notification channel
 func (db *DB) SaveNotificationChannel(configID int64, ch *models.NotificationConfig) error {
 	eventsJSON, _ := json.Marshal(ch.Events)
-	enabled := 0
+	enabled, digest := 0, 0
 	if ch.Enabled {
 		enabled = 1
 	}
+	if ch.Digest {
+		digest = 1
+	}
 
 	var err error
 	if ch.ID == 0 {
-		var result sql.Result
-		result, err = db.conn.Exec(`
-			INSERT INTO notification_channels (config_id, type, target, enabled, events)
-			VALUES (?, ?, ?, ?, ?)
-		`, configID, ch.Type, ch.Target, enabled, string(eventsJSON))
+		ch.ID, err = db.conn.Insert(`
+			INSERT INTO notification_channels (config_id, type, target, enabled, events, digest, template)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, configID, ch.Type, ch.Target, enabled, string(eventsJSON), digest, ch.Template)
 		if err != nil {
 			return err
 		}
-		ch.ID, _ = result.LastInsertId()
 	} else {
 		_, err = db.conn.Exec(`
-			UPDATE notification_channels SET type = ?, target = ?, enabled = ?, events = ?
+			UPDATE notification_channels SET type = ?, target = ?, enabled = ?, events = ?, digest = ?, template = ?
 			WHERE id = ?
-		`, ch.Type, ch.Target, enabled, string(eventsJSON), ch.ID)
+		`, ch.Type, ch.Target, enabled, string(eventsJSON), digest, ch.Template, ch.ID)
 	}
 
 	// Invalidate config cache since notification channels are part of config
@@ -334,66 +364,119 @@ func (db *DB) DeleteNotificationChannel(id int64) error {
 func (db *DB) SaveAnalysis(analysis *models.AnalysisResponse) error {
 	priceTargetsJSON, _ := json.Marshal(analysis.PriceTargets)
 	risksJSON, _ := json.Marshal(analysis.Risks)
-
-	result, err := db.conn.Exec(`
-		INSERT INTO analysis_results (symbol, action, confidence, reasoning, price_targets, risks, timeframe)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+	highlightsJSON, _ := json.Marshal(analysis.Highlights)
+	consensusJSON := ""
+	if len(analysis.Consensus) > 0 {
+		b, _ := json.Marshal(analysis.Consensus)
+		consensusJSON = string(b)
+	}
+	relativeJSON := ""
+	if analysis.RelativeStrength != nil {
+		b, _ := json.Marshal(analysis.RelativeStrength)
+		relativeJSON = string(b)
+	}
+
+	id, err := db.conn.Insert(`
+		INSERT INTO analysis_results (symbol, action, confidence, reasoning, price_targets, risks, timeframe, preset, ai_provider,
+			quote_time, market_state, stale_data, highlights, consensus, relative_strength)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, analysis.Symbol, analysis.Action, analysis.Confidence, analysis.Reasoning,
-		string(priceTargetsJSON), string(risksJSON), analysis.Timeframe)
+		string(priceTargetsJSON), string(risksJSON), analysis.Timeframe, analysis.Preset, analysis.AIProvider,
+		analysis.QuoteTime, analysis.MarketState, analysis.StaleData, string(highlightsJSON), consensusJSON, relativeJSON)
 	if err != nil {
 		return err
 	}
-	analysis.ID, _ = result.LastInsertId()
+	analysis.ID = id
 	return nil
 }
 
 // GetRecentAnalyses gets recent analysis results
 func (db *DB) GetRecentAnalyses(limit int) ([]models.AnalysisResponse, error) {
 	rows, err := db.conn.Query(`
-		SELECT id, symbol, action, confidence, reasoning, price_targets, risks, timeframe, generated_at
+		SELECT `+analysisColumns+`
 		FROM analysis_results ORDER BY generated_at DESC LIMIT ?
 	`, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var results []models.AnalysisResponse
-	for rows.Next() {
-		var r models.AnalysisResponse
-		var priceTargetsJSON, risksJSON string
-		if err := rows.Scan(&r.ID, &r.Symbol, &r.Action, &r.Confidence, &r.Reasoning,
-			&priceTargetsJSON, &risksJSON, &r.Timeframe, &r.GeneratedAt); err != nil {
-			return nil, err
-		}
-		json.Unmarshal([]byte(priceTargetsJSON), &r.PriceTargets)
-		json.Unmarshal([]byte(risksJSON), &r.Risks)
-		results = append(results, r)
-	}
-	return results, nil
+	return scanAnalyses(rows)
 }
 
 // GetAnalysesForSymbol gets analysis results for a specific symbol
 func (db *DB) GetAnalysesForSymbol(symbol string, limit int) ([]models.AnalysisResponse, error) {
 	rows, err := db.conn.Query(`
-		SELECT id, symbol, action, confidence, reasoning, price_targets, risks, timeframe, generated_at
+		SELECT `+analysisColumns+`
 		FROM analysis_results WHERE symbol = ? ORDER BY generated_at DESC LIMIT ?
 	`, symbol, limit)
 	if err != nil {
 		return nil, err
 	}
+	return scanAnalyses(rows)
+}
+
+// GetAnalysesForPreset gets analysis results produced with a given preset
+func (db *DB) GetAnalysesForPreset(preset string, limit int) ([]models.AnalysisResponse, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+analysisColumns+`
+		FROM analysis_results WHERE preset = ? ORDER BY generated_at DESC LIMIT ?
+	`, preset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAnalyses(rows)
+}
+
+// GetSignalsSince gets the BUY, SELL, ADD and TRIM recommendations made
+// since a time with at least a confidence, oldest first
+func (db *DB) GetSignalsSince(since time.Time, minConfidence float64) ([]models.AnalysisResponse, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+analysisColumns+`
+		FROM analysis_results
+		WHERE generated_at >= ? AND confidence >= ? AND action IN ('BUY', 'SELL', 'ADD', 'TRIM')
+		ORDER BY generated_at
+	`, since, minConfidence)
+	if err != nil {
+		return nil, err
+	}
+	return scanAnalyses(rows)
+}
+
+// analysisColumns are the analysis_results columns read by scanAnalyses
+const analysisColumns = `id, symbol, action, confidence, reasoning, price_targets, risks, timeframe,
+		       COALESCE(preset, ''), COALESCE(ai_provider, ''), feedback_rating, COALESCE(feedback_note, ''),
+		       feedback_rated_at, quote_time, COALESCE(market_state, ''), COALESCE(stale_data, 0),
+		       COALESCE(highlights, '[]'), COALESCE(consensus, ''),
+		       COALESCE(relative_strength, ''), generated_at`
+
+// scanAnalyses reads and closes rows selecting analysisColumns
+func scanAnalyses(rows *sql.Rows) ([]models.AnalysisResponse, error) {
 	defer rows.Close()
 
 	var results []models.AnalysisResponse
 	for rows.Next() {
 		var r models.AnalysisResponse
-		var priceTargetsJSON, risksJSON string
+		var priceTargetsJSON, risksJSON, highlightsJSON, consensusJSON, relativeJSON, note string
+		var rating int
+		var ratedAt, quoteTime sql.NullTime
 		if err := rows.Scan(&r.ID, &r.Symbol, &r.Action, &r.Confidence, &r.Reasoning,
-			&priceTargetsJSON, &risksJSON, &r.Timeframe, &r.GeneratedAt); err != nil {
+			&priceTargetsJSON, &risksJSON, &r.Timeframe, &r.Preset, &r.AIProvider,
+			&rating, &note, &ratedAt, &quoteTime, &r.MarketState, &r.StaleData, &highlightsJSON, &consensusJSON, &relativeJSON, &r.GeneratedAt); err != nil {
 			return nil, err
 		}
+		json.Unmarshal([]byte(highlightsJSON), &r.Highlights)
+		if consensusJSON != "" {
+			json.Unmarshal([]byte(consensusJSON), &r.Consensus)
+		}
+		if relativeJSON != "" {
+			json.Unmarshal([]byte(relativeJSON), &r.RelativeStrength)
+		}
+		if quoteTime.Valid {
+			r.QuoteTime = &quoteTime.Time
+		}
+		r.AfterHours = market.IsOffHours(r.MarketState)
 		json.Unmarshal([]byte(priceTargetsJSON), &r.PriceTargets)
 		json.Unmarshal([]byte(risksJSON), &r.Risks)
+		r.Feedback = feedbackFrom(rating, note, ratedAt)
 		results = append(results, r)
 	}
 	return results, nil
@@ -401,43 +484,202 @@ func (db *DB) GetAnalysesForSymbol(symbol string, limit int) ([]models.AnalysisR
 
 // SavePriceAlert saves a price alert
 func (db *DB) SavePriceAlert(alert *models.PriceAlert) error {
-	result, err := db.conn.Exec(`
-		INSERT INTO price_alerts (symbol, condition, price) VALUES (?, ?, ?)
-	`, alert.Symbol, alert.Condition, alert.Price)
+	id, err := db.conn.Insert(`
+		INSERT INTO price_alerts (symbol, condition, price, fast_period, slow_period, period, recurring, cooldown_minutes,
+			expires_at, hysteresis_percent, enabled, channels, rule, label, note)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, alert.Symbol, alert.Condition, alert.Price, alert.FastPeriod, alert.SlowPeriod, alert.Period,
+		alert.Recurring, alert.CooldownMinutes, alert.ExpiresAt, alert.HysteresisPercent, alert.Enabled,
+		alertChannelsJSON(alert.Channels), alertRuleJSON(alert.Rule), alert.Label, alert.Note)
 	if err != nil {
 		return err
 	}
-	alert.ID, _ = result.LastInsertId()
+	alert.ID = id
 	return nil
 }
 
-// GetActiveAlerts gets all untriggered price alerts
+// GetActiveAlerts gets all untriggered price alerts that have not expired,
+// paused ones included
 func (db *DB) GetActiveAlerts() ([]models.PriceAlert, error) {
 	rows, err := db.conn.Query(`
-		SELECT id, symbol, condition, price, triggered, created_at
-		FROM price_alerts WHERE triggered = 0
+		SELECT `+alertColumns+`
+		FROM price_alerts WHERE triggered = 0 AND (expires_at IS NULL OR expires_at > ?)
+	`, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanAlerts(rows)
+}
+
+// GetTriggeredAlerts gets the alerts that fired and stay triggered, most
+// recently triggered first
+func (db *DB) GetTriggeredAlerts() ([]models.PriceAlert, error) {
+	rows, err := db.conn.Query(`
+		SELECT ` + alertColumns + `
+		FROM price_alerts WHERE triggered = 1 ORDER BY triggered_at DESC, id DESC
 	`)
 	if err != nil {
 		return nil, err
 	}
+	return scanAlerts(rows)
+}
+
+// GetPriceAlert gets a price alert by ID, triggered or not; it returns
+// sql.ErrNoRows when there is no such alert
+func (db *DB) GetPriceAlert(id int64) (*models.PriceAlert, error) {
+	rows, err := db.conn.Query(`SELECT `+alertColumns+` FROM price_alerts WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := scanAlerts(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(alerts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &alerts[0], nil
+}
+
+// alertColumns are the price_alerts columns read by scanAlerts
+const alertColumns = `id, symbol, condition, price, COALESCE(peak_price, 0), COALESCE(fast_period, 0), COALESCE(slow_period, 0),
+		       COALESCE(period, 0), COALESCE(recurring, 0), COALESCE(cooldown_minutes, 0), triggered_at, expires_at,
+		       COALESCE(hysteresis_percent, 0), COALESCE(rearming, 0), COALESCE(enabled, 1), COALESCE(channels, ''),
+		       COALESCE(rule, ''), COALESCE(label, ''), COALESCE(note, ''), triggered, COALESCE(last_fired_date, ''), created_at`
+
+// scanAlerts reads and closes rows selecting alertColumns
+func scanAlerts(rows *sql.Rows) ([]models.PriceAlert, error) {
 	defer rows.Close()
 
 	var alerts []models.PriceAlert
 	for rows.Next() {
 		var a models.PriceAlert
 		var triggered int
-		if err := rows.Scan(&a.ID, &a.Symbol, &a.Condition, &a.Price, &triggered, &a.CreatedAt); err != nil {
+		var triggeredAt, expiresAt sql.NullTime
+		var channelsJSON, ruleJSON string
+		if err := rows.Scan(&a.ID, &a.Symbol, &a.Condition, &a.Price, &a.PeakPrice, &a.FastPeriod, &a.SlowPeriod,
+			&a.Period, &a.Recurring, &a.CooldownMinutes, &triggeredAt, &expiresAt,
+			&a.HysteresisPercent, &a.Rearming, &a.Enabled, &channelsJSON, &ruleJSON, &a.Label, &a.Note, &triggered, &a.LastFiredDate, &a.CreatedAt); err != nil {
 			return nil, err
 		}
 		a.Triggered = triggered == 1
+		if triggeredAt.Valid {
+			a.TriggeredAt = &triggeredAt.Time
+		}
+		if expiresAt.Valid {
+			a.ExpiresAt = &expiresAt.Time
+		}
+		if channelsJSON != "" {
+			json.Unmarshal([]byte(channelsJSON), &a.Channels)
+		}
+		if ruleJSON != "" {
+			a.Rule = &models.AlertRule{}
+			json.Unmarshal([]byte(ruleJSON), a.Rule)
+		}
 		alerts = append(alerts, a)
 	}
-	return alerts, nil
+	return alerts, rows.Err()
+}
+
+// alertChannelsJSON encodes the notification channel types of an alert,
+// empty when it notifies every channel
+func alertChannelsJSON(channels []string) string {
+	if len(channels) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(channels)
+	return string(b)
+}
+
+// alertRuleJSON encodes the rule of a composite alert, empty for other alerts
+func alertRuleJSON(rule *models.AlertRule) string {
+	if rule == nil {
+		return ""
+	}
+	b, _ := json.Marshal(rule)
+	return string(b)
+}
+
+// TriggerAlert marks an alert as triggered; fired is false when it already
+// was, e.g. by another WebSocket client evaluating the same quote
+func (db *DB) TriggerAlert(id int64) (fired bool, err error) {
+	result, err := db.conn.Exec(`
+		UPDATE price_alerts SET triggered = 1, triggered_at = CURRENT_TIMESTAMP WHERE id = ? AND triggered = 0
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// MarkRecurringAlertFired records when a recurring alert fired, leaving it
+// active, restarts the high-water mark of a trailing alert and, with
+// rearming, waits for the price to leave the hysteresis band. fired is false
+// when the alert last fired after cooledAt or is still re-arming.
+func (db *DB) MarkRecurringAlertFired(id int64, cooledAt time.Time, rearming bool) (fired bool, err error) {
+	result, err := db.conn.Exec(`
+		UPDATE price_alerts SET triggered_at = CURRENT_TIMESTAMP, peak_price = 0, rearming = ?
+		WHERE id = ? AND triggered = 0 AND COALESCE(rearming, 0) = 0 AND (triggered_at IS NULL OR triggered_at <= ?)
+	`, rearming, id, cooledAt.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// SetAlertEnabled pauses or resumes an alert; it returns sql.ErrNoRows when
+// there is no such alert
+func (db *DB) SetAlertEnabled(id int64, enabled bool) error {
+	result, err := db.conn.Exec(`UPDATE price_alerts SET enabled = ? WHERE id = ?`, enabled, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
-// TriggerAlert marks an alert as triggered
-func (db *DB) TriggerAlert(id int64) error {
-	_, err := db.conn.Exec(`UPDATE price_alerts SET triggered = 1 WHERE id = ?`, id)
+// ReactivateAlert resets a triggered alert so it is evaluated again,
+// restarting the high-water mark of a trailing alert and dropping an expiry
+// that has passed; it returns sql.ErrNoRows when there is no such alert
+func (db *DB) ReactivateAlert(id int64) error {
+	now := time.Now().UTC()
+	result, err := db.conn.Exec(`
+		UPDATE price_alerts SET triggered = 0, peak_price = 0, rearming = 0,
+			expires_at = CASE WHEN expires_at <= ? THEN NULL ELSE expires_at END
+		WHERE id = ?
+	`, now, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RearmAlert re-arms a recurring alert once its price has left the
+// hysteresis band
+func (db *DB) RearmAlert(id int64) error {
+	_, err := db.conn.Exec(`UPDATE price_alerts SET rearming = 0 WHERE id = ?`, id)
+	return err
+}
+
+// MarkAlertFired records the trading day a gap alert last fired
+func (db *DB) MarkAlertFired(id int64, day string) error {
+	_, err := db.conn.Exec(`
+		UPDATE price_alerts SET last_fired_date = ?, triggered_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, day, id)
+	return err
+}
+
+// RaiseAlertPeak records a new high-water mark for a trailing alert; a lower
+// price than the stored peak is ignored
+func (db *DB) RaiseAlertPeak(id int64, price float64) error {
+	_, err := db.conn.Exec(`UPDATE price_alerts SET peak_price = ? WHERE id = ? AND COALESCE(peak_price, 0) < ?`, price, id, price)
 	return err
 }
 
@@ -450,13 +692,13 @@ func (db *DB) DeletePriceAlert(id int64) error {
 // SaveNotification saves a notification record
 func (db *DB) SaveNotification(n *models.Notification) error {
 	channelsJSON, _ := json.Marshal(n.Channels)
-	result, err := db.conn.Exec(`
+	id, err := db.conn.Insert(`
 		INSERT INTO notifications (type, title, message, symbol, channels) VALUES (?, ?, ?, ?, ?)
 	`, n.Type, n.Title, n.Message, n.Symbol, string(channelsJSON))
 	if err != nil {
 		return err
 	}
-	n.ID, _ = result.LastInsertId()
+	n.ID = id
 	return nil
 }
 
@@ -464,7 +706,9 @@ func (db *DB) SaveNotification(n *models.Notification) error {
 func (db *DB) GetRecommendationsToday() ([]models.Recommendation, error) {
 	today := time.Now().Truncate(24 * time.Hour)
 	rows, err := db.conn.Query(`
-		SELECT id, symbol, action, confidence, reasoning, '', 0, '', generated_at, 'unknown'
+		SELECT id, symbol, action, confidence, reasoning, '', 0, '', generated_at,
+		       COALESCE(NULLIF(ai_provider, ''), 'unknown'), feedback_rating, COALESCE(stale_data, 0), COALESCE(market_state, ''),
+		       COALESCE(highlights, '[]')
 		FROM analysis_results WHERE generated_at >= ?
 	`, today)
 	if err != nil {
@@ -475,11 +719,14 @@ func (db *DB) GetRecommendationsToday() ([]models.Recommendation, error) {
 	var recs []models.Recommendation
 	for rows.Next() {
 		var r models.Recommendation
-		var reasoning string
+		var reasoning, marketState, highlightsJSON string
 		if err := rows.Scan(&r.ID, &r.Symbol, &r.Action, &r.Confidence, &reasoning,
-			&r.Timeframe, &r.TargetPrice, &r.Reasoning, &r.CreatedAt, &r.AIProvider); err != nil {
+			&r.Timeframe, &r.TargetPrice, &r.Reasoning, &r.CreatedAt, &r.AIProvider, &r.Feedback,
+			&r.StaleData, &marketState, &highlightsJSON); err != nil {
 			return nil, err
 		}
+		json.Unmarshal([]byte(highlightsJSON), &r.Highlights)
+		r.AfterHours = market.IsOffHours(marketState)
 		if r.Reasoning == "" {
 			r.Reasoning = reasoning
 		}
@@ -491,7 +738,9 @@ func (db *DB) GetRecommendationsToday() ([]models.Recommendation, error) {
 // GetRecentRecommendations gets recent recommendations
 func (db *DB) GetRecentRecommendations(limit int) ([]models.Recommendation, error) {
 	rows, err := db.conn.Query(`
-		SELECT id, symbol, action, confidence, reasoning, '', 0, '', generated_at, 'unknown'
+		SELECT id, symbol, action, confidence, reasoning, '', 0, '', generated_at,
+		       COALESCE(NULLIF(ai_provider, ''), 'unknown'), feedback_rating, COALESCE(stale_data, 0), COALESCE(market_state, ''),
+		       COALESCE(highlights, '[]')
 		FROM analysis_results ORDER BY generated_at DESC LIMIT ?
 	`, limit)
 	if err != nil {
@@ -502,11 +751,14 @@ func (db *DB) GetRecentRecommendations(limit int) ([]models.Recommendation, erro
 	var recs []models.Recommendation
 	for rows.Next() {
 		var r models.Recommendation
-		var reasoning string
+		var reasoning, marketState, highlightsJSON string
 		if err := rows.Scan(&r.ID, &r.Symbol, &r.Action, &r.Confidence, &reasoning,
-			&r.Timeframe, &r.TargetPrice, &r.Reasoning, &r.CreatedAt, &r.AIProvider); err != nil {
+			&r.Timeframe, &r.TargetPrice, &r.Reasoning, &r.CreatedAt, &r.AIProvider, &r.Feedback,
+			&r.StaleData, &marketState, &highlightsJSON); err != nil {
 			return nil, err
 		}
+		json.Unmarshal([]byte(highlightsJSON), &r.Highlights)
+		r.AfterHours = market.IsOffHours(marketState)
 		if r.Reasoning == "" {
 			r.Reasoning = reasoning
 		}
@@ -515,9 +767,13 @@ func (db *DB) GetRecentRecommendations(limit int) ([]models.Recommendation, erro
 	return recs, nil
 }
 
-// GetFilteredRecommendations gets recommendations with filters
-func (db *DB) GetFilteredRecommendations(action string, minConfidence float64, symbol string) ([]models.Recommendation, error) {
-	query := `SELECT id, symbol, action, confidence, reasoning, '', 0, '', generated_at, 'unknown'
+// GetFilteredRecommendations gets recommendations with filters, with their
+// tags. feedback is one of "up", "down", "rated" or "unrated"; empty matches
+// everything.
+func (db *DB) GetFilteredRecommendations(action string, minConfidence float64, symbol, preset, feedback, tag string) ([]models.Recommendation, error) {
+	query := `SELECT id, symbol, action, confidence, reasoning, '', 0, '', generated_at,
+		       COALESCE(NULLIF(ai_provider, ''), 'unknown'), feedback_rating, COALESCE(stale_data, 0), COALESCE(market_state, ''),
+		       COALESCE(highlights, '[]')
 		FROM analysis_results WHERE 1=1`
 	args := []interface{}{}
 
@@ -533,6 +789,24 @@ func (db *DB) GetFilteredRecommendations(action string, minConfidence float64, s
 		query += " AND symbol = ?"
 		args = append(args, symbol)
 	}
+	if preset != "" {
+		query += " AND preset = ?"
+		args = append(args, preset)
+	}
+	switch feedback {
+	case "up":
+		query += " AND feedback_rating = 1"
+	case "down":
+		query += " AND feedback_rating = -1"
+	case "rated":
+		query += " AND feedback_rated_at IS NOT NULL"
+	case "unrated":
+		query += " AND feedback_rated_at IS NULL"
+	}
+	if tag != "" {
+		query += " AND id IN (SELECT analysis_id FROM analysis_tags JOIN tags ON tags.id = tag_id WHERE tags.name = ?)"
+		args = append(args, tag)
+	}
 	query += " ORDER BY generated_at DESC LIMIT 100"
 
 	rows, err := db.conn.Query(query, args...)
@@ -544,33 +818,96 @@ func (db *DB) GetFilteredRecommendations(action string, minConfidence float64, s
 	var recs []models.Recommendation
 	for rows.Next() {
 		var r models.Recommendation
-		var reasoning string
+		var reasoning, marketState, highlightsJSON string
 		if err := rows.Scan(&r.ID, &r.Symbol, &r.Action, &r.Confidence, &reasoning,
-			&r.Timeframe, &r.TargetPrice, &r.Reasoning, &r.CreatedAt, &r.AIProvider); err != nil {
+			&r.Timeframe, &r.TargetPrice, &r.Reasoning, &r.CreatedAt, &r.AIProvider, &r.Feedback,
+			&r.StaleData, &marketState, &highlightsJSON); err != nil {
 			return nil, err
 		}
+		json.Unmarshal([]byte(highlightsJSON), &r.Highlights)
+		r.AfterHours = market.IsOffHours(marketState)
 		if r.Reasoning == "" {
 			r.Reasoning = reasoning
 		}
 		recs = append(recs, r)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(recs))
+	for i, r := range recs {
+		ids[i] = r.ID
+	}
+	tags, err := db.getTagNames(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range recs {
+		recs[i].Tags = tags[recs[i].ID]
+	}
 	return recs, nil
 }
 
+// GetAnalysisResult gets a single analysis result by ID; it returns
+// sql.ErrNoRows when there is none
+func (db *DB) GetAnalysisResult(id int64) (*models.AnalysisResponse, error) {
+	var r models.AnalysisResponse
+	var priceTargetsJSON, risksJSON, highlightsJSON, consensusJSON, relativeJSON, note string
+	var rating int
+	var ratedAt, quoteTime sql.NullTime
+	err := db.conn.QueryRow(`
+		SELECT id, symbol, action, confidence, reasoning, price_targets, risks, timeframe,
+		       COALESCE(preset, ''), COALESCE(ai_provider, ''), feedback_rating, COALESCE(feedback_note, ''),
+		       feedback_rated_at, quote_time, COALESCE(market_state, ''), COALESCE(stale_data, 0),
+		       COALESCE(highlights, '[]'), COALESCE(consensus, ''),
+		       COALESCE(relative_strength, ''), generated_at
+		FROM analysis_results WHERE id = ?
+	`, id).Scan(&r.ID, &r.Symbol, &r.Action, &r.Confidence, &r.Reasoning,
+		&priceTargetsJSON, &risksJSON, &r.Timeframe, &r.Preset, &r.AIProvider,
+		&rating, &note, &ratedAt, &quoteTime, &r.MarketState, &r.StaleData, &highlightsJSON, &consensusJSON, &relativeJSON, &r.GeneratedAt)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(highlightsJSON), &r.Highlights)
+	if consensusJSON != "" {
+		json.Unmarshal([]byte(consensusJSON), &r.Consensus)
+	}
+	if relativeJSON != "" {
+		json.Unmarshal([]byte(relativeJSON), &r.RelativeStrength)
+	}
+	if quoteTime.Valid {
+		r.QuoteTime = &quoteTime.Time
+	}
+	r.AfterHours = market.IsOffHours(r.MarketState)
+	json.Unmarshal([]byte(priceTargetsJSON), &r.PriceTargets)
+	json.Unmarshal([]byte(risksJSON), &r.Risks)
+	r.Feedback = feedbackFrom(rating, note, ratedAt)
+	return &r, nil
+}
+
 // GetAnalysis gets a single analysis by ID
 func (db *DB) GetAnalysis(id int64) (*models.Analysis, error) {
 	var a models.Analysis
-	var priceTargetsJSON, risksJSON string
+	var priceTargetsJSON, risksJSON, note string
+	var rating int
+	var ratedAt sql.NullTime
+	var marketState, highlightsJSON string
 	err := db.conn.QueryRow(`
-		SELECT id, symbol, action, confidence, reasoning, price_targets, risks, timeframe, generated_at
+		SELECT id, symbol, action, confidence, reasoning, price_targets, risks, timeframe, generated_at,
+		       COALESCE(NULLIF(ai_provider, ''), 'unknown'), feedback_rating, COALESCE(feedback_note, ''), feedback_rated_at,
+		       COALESCE(stale_data, 0), COALESCE(market_state, ''), COALESCE(highlights, '[]')
 		FROM analysis_results WHERE id = ?
 	`, id).Scan(&a.ID, &a.Symbol, &a.Recommendation.Action, &a.Recommendation.Confidence,
-		&a.Recommendation.Reasoning, &priceTargetsJSON, &risksJSON, &a.Recommendation.Timeframe, &a.CreatedAt)
+		&a.Recommendation.Reasoning, &priceTargetsJSON, &risksJSON, &a.Recommendation.Timeframe, &a.CreatedAt,
+		&a.AIProvider, &rating, &note, &ratedAt, &a.Recommendation.StaleData, &marketState, &highlightsJSON)
 	if err != nil {
 		return nil, err
 	}
+	json.Unmarshal([]byte(highlightsJSON), &a.Recommendation.Highlights)
+	a.Recommendation.AfterHours = market.IsOffHours(marketState)
 
-	a.AIProvider = "unknown"
+	a.Feedback = feedbackFrom(rating, note, ratedAt)
 	return &a, nil
 }
 
@@ -582,30 +919,76 @@ func (db *DB) GetConfig() (*models.AppConfig, error) {
 	}
 
 	config := &models.AppConfig{
-		MarketDataProvider: uc.MarketDataProvider,
-		HasMarketAPIKey:    uc.MarketDataAPIKey != "",
-		AIProvider:         uc.AIProvider,
-		HasAIAPIKey:        uc.AIProviderAPIKey != "",
-		AIModel:            uc.AIModel,
-		RiskTolerance:      uc.RiskTolerance,
-		TradeFrequency:     uc.TradeFrequency,
-		TrackedSymbols:     uc.TrackedSymbols,
-		PollingInterval:    uc.PollingInterval,
+		MarketDataProvider:   uc.MarketDataProvider,
+		HasMarketAPIKey:      uc.MarketDataAPIKey != "",
+		AIProvider:           uc.AIProvider,
+		HasAIAPIKey:          uc.AIProviderAPIKey != "",
+		AIModel:              uc.AIModel,
+		FallbackAIProvider:   uc.FallbackAIProvider,
+		FallbackAIModel:      uc.FallbackAIModel,
+		HasFallbackAIKey:     uc.FallbackAIAPIKey != "",
+		AzureOpenAIEndpoint:  uc.AzureOpenAIEndpoint,
+		RiskTolerance:        uc.RiskTolerance,
+		TradeFrequency:       uc.TradeFrequency,
+		TrackedSymbols:       uc.TrackedSymbols,
+		PollingInterval:      uc.PollingInterval,
+		MinStoreConfidence:   uc.MinStoreConfidence,
+		StaleQuoteMinutes:    uc.StaleQuoteMinutes,
+		ExtendedHoursAlerts:  uc.ExtendedHoursAlerts,
+		DisplayCurrency:      uc.DisplayCurrency,
+		SymbolExchanges:      uc.SymbolExchanges,
+		BenchmarkSymbol:      uc.BenchmarkSymbol,
+		IncludeNews:          uc.IncludeNews,
+		AnalysisSchedule:     uc.AnalysisSchedule,
+		AnalysisScheduleTime: uc.AnalysisScheduleTime,
+		AITemperature:        uc.AITemperature,
+		AIMaxTokens:          uc.AIMaxTokens,
+		AIReasoningEffort:    uc.AIReasoningEffort,
+		InvestmentThesis:     uc.InvestmentThesis,
+		Retention:            uc.Retention,
 	}
 
 	// Get notification channels
 	channels, _ := db.GetNotificationChannels(uc.ID)
+	config.ChannelIDs = make(map[string]int64, len(channels))
 	for _, ch := range channels {
+		config.ChannelIDs[ch.Type] = ch.ID
 		switch ch.Type {
 		case "email":
 			config.EmailAddress = ch.Target
 			config.EmailEnabled = ch.Enabled
+		case "smtp":
+			config.SMTPAddress = ch.Target
+			config.SMTPEnabled = ch.Enabled
 		case "discord":
 			config.DiscordWebhook = ch.Target
 			config.DiscordEnabled = ch.Enabled
 		case "sms":
 			config.SMSPhone = ch.Target
 			config.SMSEnabled = ch.Enabled
+		case "pushover":
+			config.PushoverUserKey = ch.Target
+			config.PushoverEnabled = ch.Enabled
+		case "webhook":
+			config.WebhookURL = ch.Target
+			config.WebhookEnabled = ch.Enabled
+		case "matrix":
+			config.MatrixRoomID = ch.Target
+			config.MatrixEnabled = ch.Enabled
+		case "webpush":
+			config.WebPushEnabled = ch.Enabled
+		case "fcm":
+			config.FCMDeviceToken = ch.Target
+			config.FCMEnabled = ch.Enabled
+		}
+		if ch.Digest {
+			config.DigestChannels = append(config.DigestChannels, ch.Type)
+		}
+		if ch.Template != "" {
+			if config.ChannelTemplates == nil {
+				config.ChannelTemplates = map[string]string{}
+			}
+			config.ChannelTemplates[ch.Type] = ch.Template
 		}
 	}
 
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// deliveryColumns are the notification_deliveries columns scanDeliveries reads
+const deliveryColumns = `id, channel_type, target, notification, status, attempts, last_error,
+	next_attempt_at, created_at, updated_at`
+
+// EnqueueDelivery records a delivery about to be attempted
+func (db *DB) EnqueueDelivery(d *models.NotificationDelivery) error {
+	notificationJSON, _ := json.Marshal(d.Notification)
+	id, err := db.conn.Insert(`
+		INSERT INTO notification_deliveries (channel_type, target, notification, status, attempts, last_error, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, d.ChannelType, d.Target, string(notificationJSON), d.Status, d.Attempts, d.LastError, d.NextAttemptAt.UTC())
+	if err != nil {
+		return err
+	}
+	d.ID = id
+	return nil
+}
+
+// UpdateDelivery saves the status, attempts, error and next attempt of a
+// delivery
+func (db *DB) UpdateDelivery(d *models.NotificationDelivery) error {
+	_, err := db.conn.Exec(`
+		UPDATE notification_deliveries SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, d.Status, d.Attempts, d.LastError, d.NextAttemptAt.UTC(), d.ID)
+	return err
+}
+
+// DueDeliveries returns up to limit pending deliveries whose next attempt is
+// due, oldest first
+func (db *DB) DueDeliveries(now time.Time, limit int) ([]models.NotificationDelivery, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+deliveryColumns+`
+		FROM notification_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?
+	`, models.DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// GetDeliveries lists the latest deliveries, newest first, in a status or
+// in any when status is empty
+func (db *DB) GetDeliveries(status string, limit int) ([]models.NotificationDelivery, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+deliveryColumns+`
+		FROM notification_deliveries WHERE ? = '' OR status = ? ORDER BY id DESC LIMIT ?
+	`, status, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// scanDeliveries reads and closes rows selecting deliveryColumns
+func scanDeliveries(rows *sql.Rows) ([]models.NotificationDelivery, error) {
+	defer rows.Close()
+
+	deliveries := []models.NotificationDelivery{}
+	for rows.Next() {
+		var d models.NotificationDelivery
+		var notificationJSON string
+		if err := rows.Scan(&d.ID, &d.ChannelType, &d.Target, &notificationJSON, &d.Status, &d.Attempts, &d.LastError,
+			&d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(notificationJSON), &d.Notification)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
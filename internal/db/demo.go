@@ -0,0 +1,222 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"slices"
+
+	"stockmarket/internal/models"
+)
+
+// DemoDataset holds the generated records inserted by SeedDemoData
+type DemoDataset struct {
+	Symbols       []string
+	Analyses      []models.AnalysisResponse
+	Alerts        []models.PriceAlert
+	Notifications []models.Notification
+}
+
+// DemoCounts reports how many demo records were written or removed
+type DemoCounts struct {
+	Symbols       int `json:"symbols"`
+	Analyses      int `json:"analyses"`
+	Alerts        int `json:"alerts"`
+	Notifications int `json:"notifications"`
+}
+
+// SeedDemoData replaces any existing demo data with the given dataset in a single
+// transaction. Rows are tagged demo=1, and the watchlist additions and previous
+// market provider are recorded so ClearDemoData can undo them.
+func (db *DB) SeedDemoData(configID int64, data *DemoDataset) (*DemoCounts, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := clearDemoData(tx); err != nil {
+		return nil, err
+	}
+
+	var provider, trackedJSON string
+	if err := tx.QueryRow(`SELECT market_data_provider, tracked_symbols FROM user_config WHERE id = ?`,
+		configID).Scan(&provider, &trackedJSON); err != nil {
+		return nil, err
+	}
+	var tracked []string
+	json.Unmarshal([]byte(trackedJSON), &tracked)
+
+	added := []string{}
+	for _, symbol := range data.Symbols {
+		if !slices.Contains(tracked, symbol) {
+			tracked = append(tracked, symbol)
+			added = append(added, symbol)
+		}
+	}
+	trackedOut, _ := json.Marshal(tracked)
+	addedOut, _ := json.Marshal(added)
+
+	if _, err := tx.Exec(`
+		UPDATE user_config SET tracked_symbols = ?, market_data_provider = 'demo', updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, string(trackedOut), configID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO demo_state (config_id, added_symbols, previous_provider) VALUES (?, ?, ?)
+	`, configID, string(addedOut), provider); err != nil {
+		return nil, err
+	}
+
+	for i := range data.Analyses {
+		a := &data.Analyses[i]
+		priceTargetsJSON, _ := json.Marshal(a.PriceTargets)
+		risksJSON, _ := json.Marshal(a.Risks)
+		highlightsJSON, _ := json.Marshal(a.Highlights)
+		a.ID, err = tx.Insert(`
+			INSERT INTO analysis_results (symbol, action, confidence, reasoning, price_targets, risks, timeframe, highlights, generated_at, demo)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+		`, a.Symbol, a.Action, a.Confidence, a.Reasoning, string(priceTargetsJSON), string(risksJSON),
+			a.Timeframe, string(highlightsJSON), a.GeneratedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range data.Alerts {
+		a := &data.Alerts[i]
+		a.ID, err = tx.Insert(`
+			INSERT INTO price_alerts (symbol, condition, price, triggered, created_at, demo) VALUES (?, ?, ?, ?, ?, 1)
+		`, a.Symbol, a.Condition, a.Price, a.Triggered, a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range data.Notifications {
+		n := &data.Notifications[i]
+		channelsJSON, _ := json.Marshal(n.Channels)
+		n.ID, err = tx.Insert(`
+			INSERT INTO notifications (type, title, message, symbol, channels, sent_at, demo) VALUES (?, ?, ?, ?, ?, ?, 1)
+		`, n.Type, n.Title, n.Message, n.Symbol, string(channelsJSON), n.SentAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.InvalidateConfigCache()
+
+	return &DemoCounts{
+		Symbols:       len(added),
+		Analyses:      len(data.Analyses),
+		Alerts:        len(data.Alerts),
+		Notifications: len(data.Notifications),
+	}, nil
+}
+
+// ClearDemoData removes every record tagged as demo data and restores the
+// watchlist and market provider changed by SeedDemoData
+func (db *DB) ClearDemoData() (*DemoCounts, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	counts, err := clearDemoData(tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.InvalidateConfigCache()
+	return counts, nil
+}
+
+// clearDemoData deletes demo rows and reverts recorded config changes within tx
+func clearDemoData(tx *tx) (*DemoCounts, error) {
+	counts := &DemoCounts{}
+
+	deletes := []struct {
+		table string
+		count *int
+	}{
+		{"analysis_results", &counts.Analyses},
+		{"price_alerts", &counts.Alerts},
+		{"notifications", &counts.Notifications},
+	}
+	for _, d := range deletes {
+		result, err := tx.Exec(`DELETE FROM ` + d.table + ` WHERE demo = 1`)
+		if err != nil {
+			return nil, err
+		}
+		n, _ := result.RowsAffected()
+		*d.count = int(n)
+	}
+
+	rows, err := tx.Query(`SELECT config_id, added_symbols, previous_provider FROM demo_state`)
+	if err != nil {
+		return nil, err
+	}
+	type demoState struct {
+		configID int64
+		added    []string
+		provider string
+	}
+	var states []demoState
+	for rows.Next() {
+		var st demoState
+		var addedJSON string
+		if err := rows.Scan(&st.configID, &addedJSON, &st.provider); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		json.Unmarshal([]byte(addedJSON), &st.added)
+		states = append(states, st)
+	}
+	rows.Close()
+
+	for _, st := range states {
+		var provider, trackedJSON string
+		err := tx.QueryRow(`SELECT market_data_provider, tracked_symbols FROM user_config WHERE id = ?`,
+			st.configID).Scan(&provider, &trackedJSON)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var tracked []string
+		json.Unmarshal([]byte(trackedJSON), &tracked)
+		kept := []string{}
+		for _, symbol := range tracked {
+			if slices.Contains(st.added, symbol) {
+				counts.Symbols++
+				continue
+			}
+			kept = append(kept, symbol)
+		}
+		// Keep a provider the user chose after seeding
+		if provider == "demo" && st.provider != "" {
+			provider = st.provider
+		}
+		keptJSON, _ := json.Marshal(kept)
+
+		if _, err := tx.Exec(`
+			UPDATE user_config SET tracked_symbols = ?, market_data_provider = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, string(keptJSON), provider, st.configID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM demo_state`); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
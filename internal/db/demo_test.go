@@ -0,0 +1,114 @@
+package db
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// newTestDB opens a fresh database in a temporary directory
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	d, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// countRows counts a table's rows, demo ones or the user's
+func countRows(t *testing.T, d *DB, table string, demo bool) int {
+	t.Helper()
+	flag := 0
+	if demo {
+		flag = 1
+	}
+	var n int
+	if err := d.conn.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE COALESCE(demo, 0) = ?`, flag).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestClearDemoDataKeepsUserRows(t *testing.T) {
+	d := newTestDB(t)
+	cfg, err := d.GetOrCreateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.conn.Exec(`UPDATE user_config SET tracked_symbols = ?, market_data_provider = 'yahoo' WHERE id = ?`,
+		`["AAPL"]`, cfg.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user's own rows
+	if err := d.SaveAnalysis(&models.AnalysisResponse{Symbol: "AAPL", Action: "BUY", Confidence: 0.8}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SavePriceAlert(&models.PriceAlert{Symbol: "AAPL", Condition: "above", Price: 200, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveNotification(&models.Notification{Type: "price_alert", Title: "t", Message: "m", Symbol: "AAPL"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	_, err = d.SeedDemoData(cfg.ID, &DemoDataset{
+		Symbols: []string{"AAPL", "DEMO"},
+		Analyses: []models.AnalysisResponse{
+			{Symbol: "DEMO", Action: "BUY", Confidence: 0.9, GeneratedAt: now},
+			{Symbol: "DEMO", Action: "SELL", Confidence: 0.7, GeneratedAt: now},
+		},
+		Alerts: []models.PriceAlert{
+			{Symbol: "DEMO", Condition: "above", Price: 10, CreatedAt: now},
+			{Symbol: "DEMO", Condition: "below", Price: 5, CreatedAt: now},
+		},
+		Notifications: []models.Notification{
+			{Type: "buy_signal", Title: "t", Message: "m", Symbol: "DEMO", SentAt: now},
+			{Type: "price_alert", Title: "t", Message: "m", Symbol: "DEMO", SentAt: now},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []string{"analysis_results", "price_alerts", "notifications"}
+	for _, table := range tables {
+		if n := countRows(t, d, table, true); n != 2 {
+			t.Fatalf("%s: %d demo rows after seeding, want 2", table, n)
+		}
+	}
+
+	counts, err := d.ClearDemoData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DemoCounts{Symbols: 1, Analyses: 2, Alerts: 2, Notifications: 2}
+	if *counts != want {
+		t.Fatalf("cleared %+v, want %+v", *counts, want)
+	}
+
+	for _, table := range tables {
+		if n := countRows(t, d, table, true); n != 0 {
+			t.Errorf("%s: %d demo rows left", table, n)
+		}
+		if n := countRows(t, d, table, false); n != 1 {
+			t.Errorf("%s: %d user rows, want 1", table, n)
+		}
+	}
+
+	cfg, err = d.GetOrCreateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.TrackedSymbols, []string{"AAPL"}) {
+		t.Errorf("watchlist = %v, want [AAPL]", cfg.TrackedSymbols)
+	}
+	if cfg.MarketDataProvider != "yahoo" {
+		t.Errorf("market provider = %q, want yahoo", cfg.MarketDataProvider)
+	}
+}
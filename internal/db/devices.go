@@ -0,0 +1,58 @@
+package db
+
+import (
+	"database/sql"
+
+	"stockmarket/internal/models"
+)
+
+// GetDevices returns the devices registered for FCM push, oldest first
+func (db *DB) GetDevices() ([]models.Device, error) {
+	rows, err := db.conn.Query(`SELECT id, token, platform, name, created_at FROM devices ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.Device{}
+	for rows.Next() {
+		var d models.Device
+		if err := rows.Scan(&d.ID, &d.Token, &d.Platform, &d.Name, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// SaveDevice registers a device, updating the platform and name of one with
+// the same token
+func (db *DB) SaveDevice(d *models.Device) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO devices (token, platform, name) VALUES (?, ?, ?)
+		ON CONFLICT(token) DO UPDATE SET platform = excluded.platform, name = excluded.name
+	`, d.Token, d.Platform, d.Name)
+	if err != nil {
+		return err
+	}
+	return db.conn.QueryRow(`SELECT id, created_at FROM devices WHERE token = ?`, d.Token).Scan(&d.ID, &d.CreatedAt)
+}
+
+// DeleteDevice removes the device with a token
+func (db *DB) DeleteDevice(token string) error {
+	_, err := db.conn.Exec(`DELETE FROM devices WHERE token = ?`, token)
+	return err
+}
+
+// DeleteDeviceByID removes a device; it returns sql.ErrNoRows when there is
+// no such device
+func (db *DB) DeleteDeviceByID(id int64) error {
+	res, err := db.conn.Exec(`DELETE FROM devices WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
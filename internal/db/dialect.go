@@ -0,0 +1,208 @@
+package db
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// conn runs the queries of this package, which are written for SQLite,
+// against SQLite or PostgreSQL. For PostgreSQL it numbers the ? placeholders
+// and passes booleans as the integers the schema stores.
+type conn struct {
+	*sql.DB
+	postgres bool
+}
+
+// tx is a transaction on a conn
+type tx struct {
+	*sql.Tx
+	postgres bool
+}
+
+// execer runs statements on a conn or within a tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func (c *conn) Exec(query string, args ...any) (sql.Result, error) {
+	return c.DB.Exec(rebind(c.postgres, query), bindArgs(c.postgres, args)...)
+}
+
+func (c *conn) Query(query string, args ...any) (*sql.Rows, error) {
+	return c.DB.Query(rebind(c.postgres, query), bindArgs(c.postgres, args)...)
+}
+
+func (c *conn) QueryRow(query string, args ...any) *sql.Row {
+	return c.DB.QueryRow(rebind(c.postgres, query), bindArgs(c.postgres, args)...)
+}
+
+func (c *conn) Begin() (*tx, error) {
+	t, err := c.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, postgres: c.postgres}, nil
+}
+
+// Insert runs an INSERT into a table with an id column and returns the id of
+// the new row; PostgreSQL has no LastInsertId, so it asks for it instead
+func (c *conn) Insert(query string, args ...any) (int64, error) {
+	if c.postgres {
+		var id int64
+		err := c.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := c.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ExecDDL runs schema statements, separated by semicolons, translating the
+// SQLite column types for PostgreSQL
+func (c *conn) ExecDDL(schema string) (sql.Result, error) {
+	return execDDL(c.DB.Exec, c.postgres, schema)
+}
+
+// tableExists reports whether the database has a table
+func (c *conn) tableExists(table string) (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if c.postgres {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+	var n int
+	err := c.QueryRow(query, table).Scan(&n)
+	return n > 0, err
+}
+
+// columnExists reports whether a table has a column
+func (c *conn) columnExists(table, column string) (bool, error) {
+	query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+	if c.postgres {
+		query = `SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`
+	}
+	var n int
+	err := c.QueryRow(query, table, column).Scan(&n)
+	return n > 0, err
+}
+
+func (t *tx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(rebind(t.postgres, query), bindArgs(t.postgres, args)...)
+}
+
+func (t *tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.Query(rebind(t.postgres, query), bindArgs(t.postgres, args)...)
+}
+
+func (t *tx) QueryRow(query string, args ...any) *sql.Row {
+	return t.Tx.QueryRow(rebind(t.postgres, query), bindArgs(t.postgres, args)...)
+}
+
+// Prepare prepares a statement; its arguments must not be booleans
+func (t *tx) Prepare(query string) (*sql.Stmt, error) {
+	return t.Tx.Prepare(rebind(t.postgres, query))
+}
+
+// ExecDDL is conn.ExecDDL within the transaction
+func (t *tx) ExecDDL(schema string) (sql.Result, error) {
+	return execDDL(t.Tx.Exec, t.postgres, schema)
+}
+
+// Insert is conn.Insert within the transaction
+func (t *tx) Insert(query string, args ...any) (int64, error) {
+	if t.postgres {
+		var id int64
+		err := t.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := t.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// execDDL runs schema statements with exec; PostgreSQL gets them one at a
+// time, translated
+func execDDL(exec func(string, ...any) (sql.Result, error), postgres bool, schema string) (sql.Result, error) {
+	if !postgres {
+		return exec(schema)
+	}
+	var result sql.Result
+	for _, stmt := range strings.Split(schema, ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		var err error
+		if result, err = exec(postgresDDL(stmt)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// rebind replaces the ? placeholders of a query with $1, $2... for
+// PostgreSQL, leaving quoted text alone
+func rebind(postgres bool, query string) string {
+	if !postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// bindArgs converts booleans to 0 or 1 for PostgreSQL, where the columns
+// holding them are integers as in SQLite
+func bindArgs(postgres bool, args []any) []any {
+	if !postgres {
+		return args
+	}
+	out := make([]any, len(args))
+	for i, arg := range args {
+		if b, ok := arg.(bool); ok {
+			arg = 0
+			if b {
+				arg = 1
+			}
+		}
+		out[i] = arg
+	}
+	return out
+}
+
+// postgresTypes maps the SQLite column types of the schema to PostgreSQL;
+// integers widen to BIGINT since SQLite integers are 64-bit
+var postgresTypes = []struct {
+	pattern *regexp.Regexp
+	with    string
+}{
+	{regexp.MustCompile(`\bINTEGER PRIMARY KEY AUTOINCREMENT\b`), "BIGSERIAL PRIMARY KEY"},
+	{regexp.MustCompile(`\bINTEGER\b`), "BIGINT"},
+	{regexp.MustCompile(`\bREAL\b`), "DOUBLE PRECISION"},
+	{regexp.MustCompile(`\bDATETIME\b`), "TIMESTAMPTZ"},
+}
+
+// postgresDDL translates a schema statement for PostgreSQL
+func postgresDDL(stmt string) string {
+	for _, t := range postgresTypes {
+		stmt = t.pattern.ReplaceAllString(stmt, t.with)
+	}
+	return stmt
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name     string
+		postgres bool
+		query    string
+		want     string
+	}{
+		{"sqlite unchanged", false, `SELECT * FROM t WHERE a = ? AND b = ?`, `SELECT * FROM t WHERE a = ? AND b = ?`},
+		{"numbered", true, `SELECT * FROM t WHERE a = ? AND b = ?`, `SELECT * FROM t WHERE a = $1 AND b = $2`},
+		{"no placeholders", true, `SELECT 1`, `SELECT 1`},
+		{"quoted text kept", true, `UPDATE t SET note = 'why?' WHERE id = ?`, `UPDATE t SET note = 'why?' WHERE id = $1`},
+		{"escaped quote", true, `SELECT 'it''s ?' FROM t WHERE a = ? OR b IN (?, ?)`, `SELECT 'it''s ?' FROM t WHERE a = $1 OR b IN ($2, $3)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rebind(tt.postgres, tt.query); got != tt.want {
+				t.Errorf("rebind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindArgs(t *testing.T) {
+	args := []any{true, false, 1, "x", nil}
+	if got := bindArgs(false, args); !slices.Equal(got, args) {
+		t.Errorf("sqlite args = %v, want them unchanged", got)
+	}
+	if got, want := bindArgs(true, args), []any{1, 0, 1, "x", nil}; !slices.Equal(got, want) {
+		t.Errorf("postgres args = %v, want %v", got, want)
+	}
+	if args[0] != true {
+		t.Error("bindArgs changed its input")
+	}
+}
+
+func TestPostgresDDL(t *testing.T) {
+	stmt := `CREATE TABLE IF NOT EXISTS t (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		config_id INTEGER NOT NULL,
+		price REAL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		note TEXT,
+		intervals TEXT -- INTEGERS in a name are kept
+	)`
+	want := `CREATE TABLE IF NOT EXISTS t (
+		id BIGSERIAL PRIMARY KEY,
+		config_id BIGINT NOT NULL,
+		price DOUBLE PRECISION,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		note TEXT,
+		intervals TEXT -- INTEGERS in a name are kept
+	)`
+	if got := postgresDDL(stmt); got != want {
+		t.Errorf("postgresDDL() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExecDDLSplitsForPostgres(t *testing.T) {
+	var ran []string
+	exec := func(query string, args ...any) (sql.Result, error) {
+		ran = append(ran, query)
+		return nil, nil
+	}
+	schema := `CREATE TABLE a (id INTEGER); ; CREATE INDEX a_id ON a(id);`
+
+	if _, err := execDDL(exec, false, schema); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ran, []string{schema}) {
+		t.Fatalf("sqlite ran %q, want the schema in one call", ran)
+	}
+
+	ran = nil
+	if _, err := execDDL(exec, true, schema); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`CREATE TABLE a (id BIGINT)`, ` CREATE INDEX a_id ON a(id)`}; !slices.Equal(ran, want) {
+		t.Fatalf("postgres ran %q, want %q", ran, want)
+	}
+
+	failing := func(query string, args ...any) (sql.Result, error) { return nil, errors.New("syntax error") }
+	if _, err := execDDL(failing, true, schema); err == nil {
+		t.Fatal("expected the statement's error")
+	}
+}
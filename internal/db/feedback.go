@@ -0,0 +1,75 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// SetAnalysisFeedback records the user's rating of an analysis. A neutral
+// rating without a note clears the feedback. Returns sql.ErrNoRows when the
+// analysis does not exist.
+func (db *DB) SetAnalysisFeedback(id int64, rating int, note string) (*models.Feedback, error) {
+	var ratedAt *time.Time
+	if rating != 0 || note != "" {
+		now := time.Now()
+		ratedAt = &now
+	}
+
+	result, err := db.conn.Exec(`
+		UPDATE analysis_results SET feedback_rating = ?, feedback_note = ?, feedback_rated_at = ? WHERE id = ?
+	`, rating, note, ratedAt, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &models.Feedback{Rating: rating, Note: note, RatedAt: ratedAt}, nil
+}
+
+// GetProviderPerformance aggregates feedback per AI provider
+func (db *DB) GetProviderPerformance() ([]models.ProviderPerformance, error) {
+	rows, err := db.conn.Query(`
+		SELECT COALESCE(NULLIF(ai_provider, ''), 'unknown') AS provider,
+		       COUNT(*),
+		       SUM(CASE WHEN feedback_rated_at IS NOT NULL THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN feedback_rating = 1 THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN feedback_rating = -1 THEN 1 ELSE 0 END)
+		FROM analysis_results GROUP BY provider ORDER BY provider
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.ProviderPerformance{}
+	for rows.Next() {
+		var p models.ProviderPerformance
+		if err := rows.Scan(&p.Provider, &p.Analyses, &p.Rated, &p.Up, &p.Down); err != nil {
+			return nil, err
+		}
+		p.AgreementRate = agreementRate(p.Up, p.Down)
+		stats = append(stats, p)
+	}
+	return stats, rows.Err()
+}
+
+// agreementRate returns the share of thumbs-up votes, or nil without votes
+func agreementRate(up, down int) *float64 {
+	if up+down == 0 {
+		return nil
+	}
+	rate := float64(up) / float64(up+down)
+	return &rate
+}
+
+// feedbackFrom builds the feedback of a scanned analysis row, nil when unrated
+func feedbackFrom(rating int, note string, ratedAt sql.NullTime) *models.Feedback {
+	if !ratedAt.Valid {
+		return nil
+	}
+	t := ratedAt.Time
+	return &models.Feedback{Rating: rating, Note: note, RatedAt: &t}
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"testing"
+
+	"stockmarket/internal/models"
+)
+
+func TestGetProviderPerformance(t *testing.T) {
+	d := newTestDB(t)
+
+	// openai: two up, one down, one neutral note, one unrated; claude: unrated;
+	// no provider: one down
+	ratings := []struct {
+		provider string
+		rating   int
+		note     string
+		rated    bool
+	}{
+		{"openai", 1, "", true},
+		{"openai", 1, "spot on", true},
+		{"openai", -1, "", true},
+		{"openai", 0, "not sure", true},
+		{"openai", 0, "", false},
+		{"claude", 0, "", false},
+		{"", -1, "", true},
+	}
+	for _, r := range ratings {
+		analysis := &models.AnalysisResponse{Symbol: "AAPL", Action: "BUY", Confidence: 0.8, AIProvider: r.provider}
+		if err := d.SaveAnalysis(analysis); err != nil {
+			t.Fatal(err)
+		}
+		if r.rated {
+			if _, err := d.SetAnalysisFeedback(analysis.ID, r.rating, r.note); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	stats, err := d.GetProviderPerformance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		provider                  string
+		analyses, rated, up, down int
+		rate                      float64 // -1 for no rate
+	}{
+		{"claude", 1, 0, 0, 0, -1},
+		{"openai", 5, 4, 2, 1, 2.0 / 3},
+		{"unknown", 1, 1, 0, 1, 0},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("got %d providers, want %d: %+v", len(stats), len(want), stats)
+	}
+	for i, w := range want {
+		p := stats[i]
+		if p.Provider != w.provider || p.Analyses != w.analyses || p.Rated != w.rated || p.Up != w.up || p.Down != w.down {
+			t.Errorf("provider %d = %+v, want %+v", i, p, w)
+		}
+		switch {
+		case w.rate < 0 && p.AgreementRate != nil:
+			t.Errorf("%s agreement rate = %v, want none", p.Provider, *p.AgreementRate)
+		case w.rate >= 0 && (p.AgreementRate == nil || *p.AgreementRate != w.rate):
+			t.Errorf("%s agreement rate = %v, want %v", p.Provider, p.AgreementRate, w.rate)
+		}
+	}
+}
+
+func TestSetAnalysisFeedbackClears(t *testing.T) {
+	d := newTestDB(t)
+	analysis := &models.AnalysisResponse{Symbol: "AAPL", Action: "BUY", Confidence: 0.8, AIProvider: "openai"}
+	if err := d.SaveAnalysis(analysis); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.SetAnalysisFeedback(analysis.ID, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	feedback, err := d.SetAnalysisFeedback(analysis.ID, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feedback.RatedAt != nil {
+		t.Fatal("a neutral rating without a note should clear the feedback")
+	}
+	stats, err := d.GetProviderPerformance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Rated != 0 || stats[0].AgreementRate != nil {
+		t.Fatalf("stats = %+v, want one unrated analysis", stats)
+	}
+}
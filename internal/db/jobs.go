@@ -0,0 +1,25 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetJobLastRun returns the last scheduled run of a job, or the zero time if it never ran
+func (db *DB) GetJobLastRun(name string) (time.Time, error) {
+	var lastRun time.Time
+	err := db.conn.QueryRow(`SELECT last_run_at FROM jobs_state WHERE name = ?`, name).Scan(&lastRun)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return lastRun, err
+}
+
+// SetJobLastRun records the last scheduled run of a job
+func (db *DB) SetJobLastRun(name string, t time.Time) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO jobs_state (name, last_run_at, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(name) DO UPDATE SET last_run_at = excluded.last_run_at, updated_at = CURRENT_TIMESTAMP
+	`, name, t.UTC())
+	return err
+}
@@ -0,0 +1,504 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migration is a numbered schema change; up and down hold statements
+// separated by semicolons. Migrations are applied in order and never edited
+// once released: a change to the schema is a new migration at the end.
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+
+	// table and column are set on migrations that add a column, which
+	// databases created before migrations were tracked may already have
+	table  string
+	column string
+}
+
+// addColumn builds a migration adding a column to a table
+func addColumn(version int, table, column, definition string) migration {
+	return migration{
+		version: version,
+		name:    "add " + table + "." + column,
+		up:      "ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition,
+		down:    "ALTER TABLE " + table + " DROP COLUMN " + column,
+		table:   table,
+		column:  column,
+	}
+}
+
+// migrations is the schema history, oldest first
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial schema",
+		up: `
+			CREATE TABLE IF NOT EXISTS user_config (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				market_data_provider TEXT DEFAULT 'alphavantage',
+				market_data_api_key TEXT DEFAULT '',
+				ai_provider TEXT DEFAULT 'openai',
+				ai_provider_api_key TEXT DEFAULT '',
+				ai_model TEXT DEFAULT 'gpt-4o',
+				risk_tolerance TEXT DEFAULT 'moderate',
+				trade_frequency TEXT DEFAULT 'weekly',
+				tracked_symbols TEXT DEFAULT '[]',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS notification_channels (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				config_id INTEGER NOT NULL,
+				type TEXT NOT NULL,
+				target TEXT NOT NULL,
+				enabled INTEGER DEFAULT 1,
+				events TEXT DEFAULT '[]',
+				FOREIGN KEY (config_id) REFERENCES user_config(id) ON DELETE CASCADE
+			);
+
+			CREATE TABLE IF NOT EXISTS analysis_results (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				symbol TEXT NOT NULL,
+				action TEXT NOT NULL,
+				confidence REAL NOT NULL,
+				reasoning TEXT NOT NULL,
+				price_targets TEXT NOT NULL,
+				risks TEXT NOT NULL,
+				timeframe TEXT NOT NULL,
+				generated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS analysis_presets (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				config_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				ai_provider TEXT DEFAULT '',
+				ai_model TEXT DEFAULT '',
+				ai_provider_api_key TEXT DEFAULT '',
+				trade_frequency TEXT DEFAULT '',
+				user_context TEXT DEFAULT '',
+				history_period TEXT DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (config_id, name),
+				FOREIGN KEY (config_id) REFERENCES user_config(id) ON DELETE CASCADE
+			);
+
+			CREATE TABLE IF NOT EXISTS price_alerts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				symbol TEXT NOT NULL,
+				condition TEXT NOT NULL,
+				price REAL NOT NULL,
+				triggered INTEGER DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS notifications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				type TEXT NOT NULL,
+				title TEXT NOT NULL,
+				message TEXT NOT NULL,
+				symbol TEXT NOT NULL,
+				channels TEXT NOT NULL,
+				sent_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS demo_state (
+				config_id INTEGER PRIMARY KEY,
+				added_symbols TEXT NOT NULL DEFAULT '[]',
+				previous_provider TEXT NOT NULL DEFAULT '',
+				seeded_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (config_id) REFERENCES user_config(id) ON DELETE CASCADE
+			);
+
+			CREATE TABLE IF NOT EXISTS jobs_state (
+				name TEXT PRIMARY KEY,
+				last_run_at DATETIME NOT NULL,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS positions (
+				symbol TEXT PRIMARY KEY,
+				shares REAL NOT NULL,
+				cost_basis REAL NOT NULL DEFAULT 0,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS scheduled_runs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				scheduled_for DATETIME NOT NULL,
+				started_at DATETIME NOT NULL,
+				finished_at DATETIME,
+				symbols INTEGER NOT NULL DEFAULT 0,
+				succeeded INTEGER NOT NULL DEFAULT 0,
+				failed INTEGER NOT NULL DEFAULT 0,
+				signals INTEGER NOT NULL DEFAULT 0,
+				errors TEXT NOT NULL DEFAULT ''
+			);
+
+			CREATE TABLE IF NOT EXISTS usage_stats (
+				day TEXT NOT NULL,
+				metric TEXT NOT NULL,
+				key TEXT NOT NULL DEFAULT '',
+				count INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (day, metric, key)
+			);
+
+			CREATE TABLE IF NOT EXISTS ai_usage (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				day TEXT NOT NULL,
+				provider TEXT NOT NULL,
+				model TEXT NOT NULL DEFAULT '',
+				symbol TEXT NOT NULL DEFAULT '',
+				prompt_tokens INTEGER NOT NULL DEFAULT 0,
+				completion_tokens INTEGER NOT NULL DEFAULT 0,
+				cost_usd REAL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_ai_usage_day ON ai_usage(day);
+
+			CREATE TABLE IF NOT EXISTS candles (
+				provider TEXT NOT NULL,
+				symbol TEXT NOT NULL,
+				timestamp INTEGER NOT NULL,
+				open REAL NOT NULL,
+				high REAL NOT NULL,
+				low REAL NOT NULL,
+				close REAL NOT NULL,
+				volume INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (provider, symbol, timestamp)
+			);
+
+			CREATE TABLE IF NOT EXISTS candle_sync (
+				provider TEXT NOT NULL,
+				symbol TEXT NOT NULL,
+				covered_from INTEGER NOT NULL,
+				synced_at INTEGER NOT NULL,
+				PRIMARY KEY (provider, symbol)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analysis_symbol ON analysis_results(symbol);
+			CREATE INDEX IF NOT EXISTS idx_analysis_generated ON analysis_results(generated_at);
+			CREATE INDEX IF NOT EXISTS idx_alerts_symbol ON price_alerts(symbol);
+		`,
+		down: `
+			DROP TABLE IF EXISTS candle_sync;
+			DROP TABLE IF EXISTS candles;
+			DROP TABLE IF EXISTS ai_usage;
+			DROP TABLE IF EXISTS usage_stats;
+			DROP TABLE IF EXISTS scheduled_runs;
+			DROP TABLE IF EXISTS positions;
+			DROP TABLE IF EXISTS jobs_state;
+			DROP TABLE IF EXISTS demo_state;
+			DROP TABLE IF EXISTS notifications;
+			DROP TABLE IF EXISTS price_alerts;
+			DROP TABLE IF EXISTS analysis_presets;
+			DROP TABLE IF EXISTS analysis_results;
+			DROP TABLE IF EXISTS notification_channels;
+			DROP TABLE IF EXISTS user_config;
+		`,
+	},
+	addColumn(2, "user_config", "polling_interval", "INTEGER DEFAULT 30"),
+	addColumn(3, "user_config", "min_store_confidence", "REAL DEFAULT 0"),
+	addColumn(4, "user_config", "stale_quote_minutes", "INTEGER DEFAULT 15"),
+	addColumn(5, "user_config", "extended_hours_alerts", "INTEGER DEFAULT 0"),
+	addColumn(6, "user_config", "display_currency", "TEXT DEFAULT 'USD'"),
+	addColumn(7, "user_config", "symbol_exchanges", "TEXT DEFAULT '{}'"),
+	addColumn(8, "user_config", "benchmark_symbol", "TEXT DEFAULT 'SPY'"),
+	addColumn(9, "user_config", "fallback_ai_provider", "TEXT DEFAULT ''"),
+	addColumn(10, "user_config", "fallback_ai_model", "TEXT DEFAULT ''"),
+	addColumn(11, "user_config", "fallback_ai_api_key", "TEXT DEFAULT ''"),
+	addColumn(12, "user_config", "azure_openai_endpoint", "TEXT DEFAULT ''"),
+	addColumn(13, "user_config", "include_news", "INTEGER DEFAULT 1"),
+	addColumn(14, "user_config", "analysis_schedule", "TEXT DEFAULT 'off'"),
+	addColumn(15, "user_config", "analysis_schedule_time", "TEXT DEFAULT '08:30'"),
+	addColumn(16, "user_config", "ai_temperature", "REAL DEFAULT 0.3"),
+	addColumn(17, "user_config", "ai_max_tokens", "INTEGER DEFAULT 2048"),
+	addColumn(18, "user_config", "ai_reasoning_effort", "TEXT DEFAULT ''"),
+	addColumn(19, "user_config", "investment_thesis", "TEXT DEFAULT ''"),
+	addColumn(20, "price_alerts", "last_fired_date", "TEXT DEFAULT ''"),
+	addColumn(21, "analysis_results", "preset", "TEXT DEFAULT ''"),
+	addColumn(22, "analysis_results", "demo", "INTEGER DEFAULT 0"),
+	addColumn(23, "analysis_results", "ai_provider", "TEXT DEFAULT ''"),
+	addColumn(24, "analysis_results", "feedback_rating", "INTEGER DEFAULT 0"),
+	addColumn(25, "analysis_results", "feedback_note", "TEXT DEFAULT ''"),
+	addColumn(26, "analysis_results", "feedback_rated_at", "DATETIME"),
+	addColumn(27, "analysis_results", "quote_time", "DATETIME"),
+	addColumn(28, "analysis_results", "market_state", "TEXT DEFAULT ''"),
+	addColumn(29, "analysis_results", "stale_data", "INTEGER DEFAULT 0"),
+	addColumn(30, "analysis_results", "highlights", "TEXT DEFAULT '[]'"),
+	addColumn(31, "analysis_results", "consensus", "TEXT DEFAULT ''"),
+	addColumn(32, "analysis_results", "relative_strength", "TEXT DEFAULT ''"),
+	addColumn(33, "analysis_results", "outcome", "TEXT DEFAULT ''"),
+	addColumn(34, "analysis_results", "outcome_at", "DATETIME"),
+	addColumn(35, "price_alerts", "demo", "INTEGER DEFAULT 0"),
+	addColumn(36, "notifications", "demo", "INTEGER DEFAULT 0"),
+	addColumn(37, "user_config", "analysis_retention_days", "INTEGER DEFAULT 0"),
+	addColumn(38, "user_config", "alert_retention_days", "INTEGER DEFAULT 0"),
+	addColumn(39, "user_config", "notification_retention_days", "INTEGER DEFAULT 0"),
+	addColumn(40, "price_alerts", "triggered_at", "DATETIME"),
+	{
+		version: 41,
+		name:    "analysis tags",
+		up: `
+			CREATE TABLE IF NOT EXISTS tags (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS analysis_tags (
+				analysis_id INTEGER NOT NULL,
+				tag_id INTEGER NOT NULL,
+				PRIMARY KEY (analysis_id, tag_id),
+				FOREIGN KEY (analysis_id) REFERENCES analysis_results(id) ON DELETE CASCADE,
+				FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analysis_tags_tag ON analysis_tags(tag_id);
+		`,
+		down: `
+			DROP TABLE IF EXISTS analysis_tags;
+			DROP TABLE IF EXISTS tags;
+		`,
+	},
+	{
+		version: 42,
+		name:    "symbol metadata",
+		up: `
+			CREATE TABLE IF NOT EXISTS symbols (
+				symbol TEXT PRIMARY KEY,
+				name TEXT NOT NULL DEFAULT '',
+				exchange TEXT NOT NULL DEFAULT '',
+				sector TEXT NOT NULL DEFAULT '',
+				asset_type TEXT NOT NULL DEFAULT '',
+				currency TEXT NOT NULL DEFAULT '',
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)
+		`,
+		down: "DROP TABLE IF EXISTS symbols",
+	},
+	addColumn(43, "price_alerts", "peak_price", "REAL DEFAULT 0"),
+	addColumn(44, "price_alerts", "fast_period", "INTEGER DEFAULT 0"),
+	addColumn(45, "price_alerts", "slow_period", "INTEGER DEFAULT 0"),
+	addColumn(46, "price_alerts", "period", "INTEGER DEFAULT 0"),
+	addColumn(47, "price_alerts", "recurring", "INTEGER DEFAULT 0"),
+	addColumn(48, "price_alerts", "cooldown_minutes", "INTEGER DEFAULT 0"),
+	addColumn(49, "price_alerts", "expires_at", "DATETIME"),
+	addColumn(50, "price_alerts", "hysteresis_percent", "REAL DEFAULT 0"),
+	addColumn(51, "price_alerts", "rearming", "INTEGER DEFAULT 0"),
+	addColumn(52, "price_alerts", "enabled", "INTEGER DEFAULT 1"),
+	addColumn(53, "price_alerts", "channels", "TEXT DEFAULT ''"),
+	{
+		version: 54,
+		name:    "alert trigger history",
+		up: `
+			CREATE TABLE IF NOT EXISTS alert_triggers (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				alert_id INTEGER NOT NULL,
+				price REAL NOT NULL,
+				message TEXT NOT NULL DEFAULT '',
+				channels TEXT NOT NULL DEFAULT '',
+				triggered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (alert_id) REFERENCES price_alerts(id) ON DELETE CASCADE
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_alert_triggers_alert ON alert_triggers(alert_id, triggered_at);
+		`,
+		down: "DROP TABLE IF EXISTS alert_triggers",
+	},
+	addColumn(55, "price_alerts", "rule", "TEXT DEFAULT ''"),
+	addColumn(56, "price_alerts", "label", "TEXT DEFAULT ''"),
+	addColumn(57, "price_alerts", "note", "TEXT DEFAULT ''"),
+	{
+		version: 58,
+		name:    "browser push subscriptions",
+		up: `
+			CREATE TABLE IF NOT EXISTS push_subscriptions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				endpoint TEXT NOT NULL UNIQUE,
+				p256dh TEXT NOT NULL,
+				auth TEXT NOT NULL,
+				user_agent TEXT NOT NULL DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS vapid_keys (
+				id INTEGER PRIMARY KEY,
+				public_key TEXT NOT NULL,
+				private_key TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+		down: `
+			DROP TABLE IF EXISTS vapid_keys;
+			DROP TABLE IF EXISTS push_subscriptions;
+		`,
+	},
+	{
+		version: 59,
+		name:    "fcm devices",
+		up: `
+			CREATE TABLE IF NOT EXISTS devices (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				token TEXT NOT NULL UNIQUE,
+				platform TEXT NOT NULL,
+				name TEXT NOT NULL DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+		down: "DROP TABLE IF EXISTS devices",
+	},
+	{
+		version: 60,
+		name:    "notification delivery queue",
+		up: `
+			CREATE TABLE IF NOT EXISTS notification_deliveries (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				channel_type TEXT NOT NULL,
+				target TEXT NOT NULL DEFAULT '',
+				notification TEXT NOT NULL,
+				status TEXT NOT NULL DEFAULT 'pending',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT NOT NULL DEFAULT '',
+				next_attempt_at DATETIME NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_notification_deliveries_due ON notification_deliveries(status, next_attempt_at);
+		`,
+		down: "DROP TABLE IF EXISTS notification_deliveries",
+	},
+	addColumn(61, "notification_channels", "digest", "INTEGER DEFAULT 0"),
+	addColumn(62, "notification_channels", "template", "TEXT DEFAULT ''"),
+}
+
+// migrate creates the schema_migrations table and applies the migrations
+// the database has not had yet
+func (db *DB) migrate() error {
+	if _, err := db.conn.ExecDDL(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return err
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this release supports (%d); "+
+			"roll it back with -migrate-to %d using the newer release", current, latest, latest)
+	}
+
+	// Databases created before migrations were tracked have the tables and
+	// some of the columns already
+	adopting := false
+	if current == 0 {
+		if adopting, err = db.conn.tableExists("user_config"); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		skip := false
+		if adopting && m.column != "" {
+			if skip, err = db.conn.columnExists(m.table, m.column); err != nil {
+				return err
+			}
+		}
+		if err := db.apply(m, true, skip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateTo rolls the schema back to version, undoing later migrations
+// newest first; it is meant to run before starting an older release
+func (db *DB) MigrateTo(version int) error {
+	if version < 0 {
+		return fmt.Errorf("invalid schema version %d", version)
+	}
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version <= version || m.version > current {
+			continue
+		}
+		if err := db.apply(m, false, false); err != nil {
+			return err
+		}
+	}
+	db.InvalidateConfigCache()
+	return nil
+}
+
+// SchemaVersion returns the version of the newest applied migration, 0 for
+// an empty database
+func (db *DB) SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	err := db.conn.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	return int(version.Int64), err
+}
+
+// apply runs a migration up or down in a transaction together with its
+// schema_migrations row; skip only records an up migration already in place
+func (db *DB) apply(m migration, up, skip bool) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := m.down
+	if up {
+		stmts = m.up
+	}
+	if !skip {
+		if _, err := tx.ExecDDL(stmts); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name)
+	} else if m.version > 1 {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.version)
+	} else {
+		// The initial schema's down drops everything but the history
+		_, err = tx.Exec(`DELETE FROM schema_migrations`)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	direction := "Applied"
+	if !up {
+		direction = "Reverted"
+	} else if skip {
+		direction = "Recorded"
+	}
+	log.Printf("[DB] %s migration %d: %s", direction, m.version, m.name)
+	return nil
+}
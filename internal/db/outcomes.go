@@ -0,0 +1,70 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// GetPendingOutcomes lists the BUY, ADD, SELL and TRIM analyses generated
+// since a time whose outcome is not known yet and that set both a target
+// and a stop loss, oldest first
+func (db *DB) GetPendingOutcomes(since time.Time) ([]models.PendingOutcome, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, symbol, action, price_targets, generated_at
+		FROM analysis_results
+		WHERE action IN ('BUY', 'ADD', 'SELL', 'TRIM') AND COALESCE(outcome, '') = '' AND generated_at >= ?
+		ORDER BY generated_at
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := []models.PendingOutcome{}
+	for rows.Next() {
+		var p models.PendingOutcome
+		var priceTargetsJSON string
+		if err := rows.Scan(&p.ID, &p.Symbol, &p.Action, &priceTargetsJSON, &p.GeneratedAt); err != nil {
+			return nil, err
+		}
+		if json.Unmarshal([]byte(priceTargetsJSON), &p.PriceTargets) != nil ||
+			p.PriceTargets.Target <= 0 || p.PriceTargets.StopLoss <= 0 {
+			continue
+		}
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// SetAnalysisOutcome records whether an analysis hit its target or its stop
+func (db *DB) SetAnalysisOutcome(id int64, outcome string, at time.Time) error {
+	_, err := db.conn.Exec(`UPDATE analysis_results SET outcome = ?, outcome_at = ? WHERE id = ?`, outcome, at, id)
+	return err
+}
+
+// GetResolvedOutcomes lists the provider, confidence and outcome of every
+// analysis whose outcome is known
+func (db *DB) GetResolvedOutcomes() ([]models.ResolvedOutcome, error) {
+	rows, err := db.conn.Query(`
+		SELECT COALESCE(NULLIF(ai_provider, ''), 'unknown'), confidence, outcome
+		FROM analysis_results WHERE outcome IN (?, ?) ORDER BY generated_at
+	`, models.OutcomeHit, models.OutcomeMiss)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outcomes := []models.ResolvedOutcome{}
+	for rows.Next() {
+		var o models.ResolvedOutcome
+		var outcome string
+		if err := rows.Scan(&o.Provider, &o.Confidence, &outcome); err != nil {
+			return nil, err
+		}
+		o.Hit = outcome == models.OutcomeHit
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"stockmarket/internal/models"
+)
+
+// GetPositions returns all positions ordered by symbol
+func (db *DB) GetPositions() ([]models.Position, error) {
+	rows, err := db.conn.Query(`SELECT symbol, shares, cost_basis, updated_at FROM positions ORDER BY symbol`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []models.Position{}
+	for rows.Next() {
+		var p models.Position
+		if err := rows.Scan(&p.Symbol, &p.Shares, &p.CostBasis, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	return positions, rows.Err()
+}
+
+// SavePosition creates or replaces the position in a symbol
+func (db *DB) SavePosition(p *models.Position) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO positions (symbol, shares, cost_basis, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(symbol) DO UPDATE SET shares = excluded.shares, cost_basis = excluded.cost_basis,
+			updated_at = CURRENT_TIMESTAMP
+	`, p.Symbol, p.Shares, p.CostBasis)
+	return err
+}
+
+// DeletePosition removes the position in a symbol
+func (db *DB) DeletePosition(symbol string) error {
+	_, err := db.conn.Exec(`DELETE FROM positions WHERE symbol = ?`, symbol)
+	return err
+}
@@ -0,0 +1,6 @@
+//go:build postgres
+
+package db
+
+// Registers the "pgx" driver used by NewPostgres
+import _ "github.com/jackc/pgx/v5/stdlib"
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// GetAnalysisPresets gets all analysis presets for a config
+func (db *DB) GetAnalysisPresets(configID int64) ([]models.AnalysisPreset, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, name, ai_provider, ai_model, ai_provider_api_key, trade_frequency,
+		       user_context, history_period, created_at
+		FROM analysis_presets WHERE config_id = ? ORDER BY name
+	`, configID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []models.AnalysisPreset
+	for rows.Next() {
+		var p models.AnalysisPreset
+		if err := rows.Scan(&p.ID, &p.Name, &p.AIProvider, &p.AIModel, &p.AIProviderAPIKey,
+			&p.TradeFrequency, &p.UserContext, &p.HistoryPeriod, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		presets = append(presets, p)
+	}
+	return presets, nil
+}
+
+// GetAnalysisPreset gets a single analysis preset by ID
+func (db *DB) GetAnalysisPreset(configID, id int64) (*models.AnalysisPreset, error) {
+	var p models.AnalysisPreset
+	err := db.conn.QueryRow(`
+		SELECT id, name, ai_provider, ai_model, ai_provider_api_key, trade_frequency,
+		       user_context, history_period, created_at
+		FROM analysis_presets WHERE config_id = ? AND id = ?
+	`, configID, id).Scan(&p.ID, &p.Name, &p.AIProvider, &p.AIModel, &p.AIProviderAPIKey,
+		&p.TradeFrequency, &p.UserContext, &p.HistoryPeriod, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SaveAnalysisPreset creates or updates an analysis preset
+func (db *DB) SaveAnalysisPreset(configID int64, p *models.AnalysisPreset) error {
+	if p.ID == 0 {
+		id, err := db.conn.Insert(`
+			INSERT INTO analysis_presets (config_id, name, ai_provider, ai_model, ai_provider_api_key,
+				trade_frequency, user_context, history_period)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, configID, p.Name, p.AIProvider, p.AIModel, p.AIProviderAPIKey,
+			p.TradeFrequency, p.UserContext, p.HistoryPeriod)
+		if err != nil {
+			return err
+		}
+		p.ID = id
+		p.CreatedAt = time.Now()
+		return nil
+	}
+
+	_, err := db.conn.Exec(`
+		UPDATE analysis_presets SET name = ?, ai_provider = ?, ai_model = ?, ai_provider_api_key = ?,
+			trade_frequency = ?, user_context = ?, history_period = ?
+		WHERE id = ? AND config_id = ?
+	`, p.Name, p.AIProvider, p.AIModel, p.AIProviderAPIKey,
+		p.TradeFrequency, p.UserContext, p.HistoryPeriod, p.ID, configID)
+	return err
+}
+
+// DeleteAnalysisPreset deletes an analysis preset
+func (db *DB) DeleteAnalysisPreset(configID, id int64) error {
+	_, err := db.conn.Exec(`DELETE FROM analysis_presets WHERE id = ? AND config_id = ?`, id, configID)
+	return err
+}
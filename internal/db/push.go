@@ -0,0 +1,64 @@
+package db
+
+import (
+	"stockmarket/internal/models"
+)
+
+// GetPushSubscriptions returns all browser push subscriptions, oldest first
+func (db *DB) GetPushSubscriptions() ([]models.PushSubscription, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, endpoint, p256dh, auth, user_agent, created_at FROM push_subscriptions ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.PushSubscription{}
+	for rows.Next() {
+		var sub models.PushSubscription
+		if err := rows.Scan(&sub.ID, &sub.Endpoint, &sub.Keys.P256dh, &sub.Keys.Auth, &sub.UserAgent, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// SavePushSubscription adds a browser push subscription, replacing the keys
+// of one with the same endpoint
+func (db *DB) SavePushSubscription(sub *models.PushSubscription) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO push_subscriptions (endpoint, p256dh, auth, user_agent) VALUES (?, ?, ?, ?)
+		ON CONFLICT(endpoint) DO UPDATE SET p256dh = excluded.p256dh, auth = excluded.auth,
+			user_agent = excluded.user_agent
+	`, sub.Endpoint, sub.Keys.P256dh, sub.Keys.Auth, sub.UserAgent)
+	if err != nil {
+		return err
+	}
+	return db.conn.QueryRow(`
+		SELECT id, created_at FROM push_subscriptions WHERE endpoint = ?
+	`, sub.Endpoint).Scan(&sub.ID, &sub.CreatedAt)
+}
+
+// DeletePushSubscription removes the browser push subscription with an endpoint
+func (db *DB) DeletePushSubscription(endpoint string) error {
+	_, err := db.conn.Exec(`DELETE FROM push_subscriptions WHERE endpoint = ?`, endpoint)
+	return err
+}
+
+// GetVAPIDKeys returns the saved VAPID public key and encrypted private key;
+// it returns sql.ErrNoRows when none have been generated
+func (db *DB) GetVAPIDKeys() (publicKey, privateKey string, err error) {
+	err = db.conn.QueryRow(`SELECT public_key, private_key FROM vapid_keys WHERE id = 1`).Scan(&publicKey, &privateKey)
+	return publicKey, privateKey, err
+}
+
+// SaveVAPIDKeys saves the VAPID key pair, the private key encrypted
+func (db *DB) SaveVAPIDKeys(publicKey, privateKey string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO vapid_keys (id, public_key, private_key) VALUES (1, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET public_key = excluded.public_key, private_key = excluded.private_key
+	`, publicKey, privateKey)
+	return err
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// Prune deletes the analyses, triggered alerts, alert history, notifications
+// and finished notification deliveries older than the retention of policy,
+// and the alerts that have expired. Alerts triggered before trigger times
+// were recorded age from their creation.
+func (db *DB) Prune(policy models.RetentionPolicy, now time.Time) (*models.PruneResult, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &models.PruneResult{}
+	for _, p := range []struct {
+		days    int
+		query   string
+		removed *int64
+	}{
+		{policy.AnalysisDays, `DELETE FROM analysis_results WHERE generated_at < ?`, &result.Analyses},
+		{policy.AlertDays, `DELETE FROM price_alerts WHERE triggered = 1 AND COALESCE(triggered_at, created_at) < ?`, &result.Alerts},
+		{policy.AlertDays, `DELETE FROM alert_triggers WHERE triggered_at < ?`, &result.AlertTriggers},
+		{policy.NotificationDays, `DELETE FROM notifications WHERE sent_at < ?`, &result.Notifications},
+		{policy.NotificationDays, `DELETE FROM notification_deliveries WHERE status <> 'pending' AND created_at < ?`, &result.Deliveries},
+	} {
+		if p.days <= 0 {
+			continue
+		}
+		res, err := tx.Exec(p.query, now.AddDate(0, 0, -p.days).UTC())
+		if err != nil {
+			return nil, err
+		}
+		*p.removed, _ = res.RowsAffected()
+	}
+
+	res, err := tx.Exec(`DELETE FROM price_alerts WHERE expires_at IS NOT NULL AND expires_at <= ?`, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	result.ExpiredAlerts, _ = res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"database/sql"
+
+	"stockmarket/internal/models"
+)
+
+// CreateScheduledRun records the start of a scheduled analysis and sets its ID
+func (db *DB) CreateScheduledRun(run *models.ScheduledRun) error {
+	var err error
+	run.ID, err = db.conn.Insert(`
+		INSERT INTO scheduled_runs (scheduled_for, started_at, symbols) VALUES (?, ?, ?)
+	`, run.ScheduledFor.UTC(), run.StartedAt.UTC(), run.Symbols)
+	return err
+}
+
+// FinishScheduledRun records the outcome of a scheduled analysis
+func (db *DB) FinishScheduledRun(run *models.ScheduledRun) error {
+	_, err := db.conn.Exec(`
+		UPDATE scheduled_runs SET finished_at = ?, succeeded = ?, failed = ?, signals = ?, errors = ?
+		WHERE id = ?
+	`, run.FinishedAt.UTC(), run.Succeeded, run.Failed, run.Signals, run.Errors, run.ID)
+	return err
+}
+
+// GetScheduledRuns returns the most recent scheduled analyses, newest first
+func (db *DB) GetScheduledRuns(limit int) ([]models.ScheduledRun, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, scheduled_for, started_at, finished_at, symbols, succeeded, failed, signals, errors
+		FROM scheduled_runs ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []models.ScheduledRun{}
+	for rows.Next() {
+		var run models.ScheduledRun
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&run.ID, &run.ScheduledFor, &run.StartedAt, &finishedAt,
+			&run.Symbols, &run.Succeeded, &run.Failed, &run.Signals, &run.Errors); err != nil {
+			return nil, err
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
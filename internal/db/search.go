@@ -0,0 +1,133 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+
+	"stockmarket/internal/models"
+)
+
+// searchIndexSchema creates the FTS5 index over the reasoning and risks of
+// analyses and the triggers keeping it in step with analysis_results
+const searchIndexSchema = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS analysis_search USING fts5(
+		reasoning, risks, content='analysis_results', content_rowid='id'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS analysis_search_insert AFTER INSERT ON analysis_results BEGIN
+		INSERT INTO analysis_search(rowid, reasoning, risks) VALUES (new.id, new.reasoning, new.risks);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS analysis_search_delete AFTER DELETE ON analysis_results BEGIN
+		INSERT INTO analysis_search(analysis_search, rowid, reasoning, risks) VALUES ('delete', old.id, old.reasoning, old.risks);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS analysis_search_update AFTER UPDATE OF reasoning, risks ON analysis_results BEGIN
+		INSERT INTO analysis_search(analysis_search, rowid, reasoning, risks) VALUES ('delete', old.id, old.reasoning, old.risks);
+		INSERT INTO analysis_search(rowid, reasoning, risks) VALUES (new.id, new.reasoning, new.risks);
+	END;
+
+	INSERT INTO analysis_search(analysis_search) VALUES ('rebuild');
+`
+
+// searchIndexTriggers are the triggers of searchIndexSchema
+var searchIndexTriggers = []string{"analysis_search_insert", "analysis_search_delete", "analysis_search_update"}
+
+// ensureSearchIndex keeps the full-text index of analyses when SQLite was
+// built with FTS5 (go build -tags sqlite_fts5). Without it the triggers are
+// dropped, since they would fail every write, and the index is rebuilt the
+// next time FTS5 is available. Searches then fall back to LIKE.
+func (db *DB) ensureSearchIndex() error {
+	if db.conn.postgres {
+		return nil
+	}
+
+	var fts5 bool
+	if err := db.conn.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return err
+	}
+	if !fts5 {
+		for _, trigger := range searchIndexTriggers {
+			if _, err := db.conn.Exec(`DROP TRIGGER IF EXISTS ` + trigger); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var n int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?`,
+		searchIndexTriggers[0]).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := db.conn.Exec(searchIndexSchema); err != nil {
+			return err
+		}
+	}
+	db.search = true
+	return nil
+}
+
+// SearchAnalyses returns the analyses, newest first, whose reasoning or
+// risks contain every word and "quoted phrase" of query
+func (db *DB) SearchAnalyses(query string, limit int) ([]models.AnalysisResponse, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []models.AnalysisResponse{}, nil
+	}
+
+	var where string
+	var args []any
+	if db.search {
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		}
+		where = `id IN (SELECT rowid FROM analysis_search WHERE analysis_search MATCH ?)`
+		args = append(args, strings.Join(quoted, " "))
+	} else {
+		conditions := make([]string, len(terms))
+		for i, term := range terms {
+			conditions[i] = `(LOWER(reasoning) LIKE ? ESCAPE '\' OR LOWER(risks) LIKE ? ESCAPE '\')`
+			pattern := "%" + likeEscaper.Replace(strings.ToLower(term)) + "%"
+			args = append(args, pattern, pattern)
+		}
+		where = strings.Join(conditions, " AND ")
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT `+analysisColumns+`
+		FROM analysis_results WHERE `+where+` ORDER BY generated_at DESC LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	results, err := scanAnalyses(rows)
+	if results == nil {
+		results = []models.AnalysisResponse{}
+	}
+	return results, err
+}
+
+// likeEscaper escapes the LIKE wildcards of a search term
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchTerms splits a search query into its "quoted phrases" and words,
+// dropping those without a letter or digit
+func searchTerms(query string) []string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		candidates := strings.Fields(part)
+		if i%2 == 1 {
+			candidates = []string{strings.Join(candidates, " ")}
+		}
+		for _, term := range candidates {
+			if strings.IndexFunc(term, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"os"
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// Stats reports the row count of every table, the size of the database and
+// its write-ahead log, cache use and the connection pool, for diagnosing
+// slow requests
+func (db *DB) Stats() (*models.DBStats, error) {
+	stats := &models.DBStats{Driver: "sqlite", Tables: []models.TableStats{}}
+	if db.conn.postgres {
+		stats.Driver = "postgres"
+	}
+
+	var err error
+	if stats.SchemaVersion, err = db.SchemaVersion(); err != nil {
+		return nil, err
+	}
+
+	tables, err := db.tableNames()
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		t := models.TableStats{Name: table}
+		quoted := `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+		if err := db.conn.QueryRow(`SELECT COUNT(*) FROM ` + quoted).Scan(&t.Rows); err != nil {
+			return nil, err
+		}
+		stats.Tables = append(stats.Tables, t)
+	}
+
+	stats.Cache.ConfigHits = db.configHits.Load()
+	stats.Cache.ConfigMisses = db.configMisses.Load()
+
+	if db.conn.postgres {
+		err := db.conn.QueryRow(`SELECT pg_database_size(current_database())`).Scan(&stats.SizeBytes)
+		if err != nil {
+			return nil, err
+		}
+		err = db.conn.QueryRow(`
+			SELECT blks_hit, blks_read FROM pg_stat_database WHERE datname = current_database()
+		`).Scan(&stats.Cache.BlocksHit, &stats.Cache.BlocksRead)
+		if err != nil {
+			return nil, err
+		}
+		if total := stats.Cache.BlocksHit + stats.Cache.BlocksRead; total > 0 {
+			ratio := float64(stats.Cache.BlocksHit) / float64(total)
+			stats.Cache.HitRatio = &ratio
+		}
+	} else {
+		if info, err := os.Stat(db.path); err == nil {
+			stats.SizeBytes = info.Size()
+		}
+		if info, err := os.Stat(db.path + "-wal"); err == nil {
+			stats.WALBytes = info.Size()
+		}
+
+		// A negative cache_size is in KiB, a positive one in pages
+		var cacheSize, pageSize int64
+		if err := db.conn.QueryRow(`PRAGMA cache_size`).Scan(&cacheSize); err != nil {
+			return nil, err
+		}
+		if err := db.conn.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+			return nil, err
+		}
+		if cacheSize < 0 {
+			stats.Cache.PageCacheKiB = -cacheSize
+		} else {
+			stats.Cache.PageCacheKiB = cacheSize * pageSize / 1024
+		}
+	}
+
+	pool := db.conn.DB.Stats()
+	stats.Pool = models.PoolStats{
+		MaxOpen:           pool.MaxOpenConnections,
+		Open:              pool.OpenConnections,
+		InUse:             pool.InUse,
+		Idle:              pool.Idle,
+		WaitCount:         pool.WaitCount,
+		WaitMillis:        pool.WaitDuration.Milliseconds(),
+		MaxIdleClosed:     pool.MaxIdleClosed,
+		MaxIdleTimeClosed: pool.MaxIdleTimeClosed,
+		MaxLifetimeClosed: pool.MaxLifetimeClosed,
+	}
+
+	return stats, nil
+}
+
+// tableNames lists the tables of the database by name, leaving out SQLite's
+// internal tables and the virtual search index with its shadow tables
+func (db *DB) tableNames() ([]string, error) {
+	query := `
+		SELECT name FROM pragma_table_list
+		WHERE schema = 'main' AND type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY name
+	`
+	if db.conn.postgres {
+		query = `
+			SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+			ORDER BY table_name
+		`
+	}
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// GetSymbols returns the stored metadata of symbols, keyed by symbol;
+// symbols without metadata are left out
+func (db *DB) GetSymbols(symbols []string) (map[string]models.Symbol, error) {
+	found := map[string]models.Symbol{}
+	if len(symbols) == 0 {
+		return found, nil
+	}
+
+	args := make([]any, len(symbols))
+	for i, symbol := range symbols {
+		args[i] = symbol
+	}
+	rows, err := db.conn.Query(`
+		SELECT symbol, name, exchange, sector, asset_type, currency, updated_at
+		FROM symbols WHERE symbol IN (`+strings.Repeat("?, ", len(args)-1)+`?)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Symbol
+		if err := rows.Scan(&s.Symbol, &s.Name, &s.Exchange, &s.Sector, &s.AssetType, &s.Currency, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		found[s.Symbol] = s
+	}
+	return found, rows.Err()
+}
+
+// SaveSymbol creates or replaces the metadata of a symbol
+func (db *DB) SaveSymbol(s *models.Symbol) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO symbols (symbol, name, exchange, sector, asset_type, currency, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(symbol) DO UPDATE SET name = excluded.name, exchange = excluded.exchange,
+			sector = excluded.sector, asset_type = excluded.asset_type, currency = excluded.currency,
+			updated_at = CURRENT_TIMESTAMP
+	`, s.Symbol, s.Name, s.Exchange, s.Sector, s.AssetType, s.Currency)
+	return err
+}
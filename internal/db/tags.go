@@ -0,0 +1,183 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// GetTags lists the tags with the number of analyses carrying each, by name
+func (db *DB) GetTags() ([]models.Tag, error) {
+	rows, err := db.conn.Query(`
+		SELECT t.id, t.name, COUNT(tagged.analysis_id), t.created_at
+		FROM tags t LEFT JOIN analysis_tags tagged ON tagged.tag_id = t.id
+		GROUP BY t.id, t.name, t.created_at ORDER BY t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Analyses, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// GetTag gets a tag by ID; it returns sql.ErrNoRows when there is none
+func (db *DB) GetTag(id int64) (*models.Tag, error) {
+	var t models.Tag
+	err := db.conn.QueryRow(`
+		SELECT t.id, t.name, COUNT(tagged.analysis_id), t.created_at
+		FROM tags t LEFT JOIN analysis_tags tagged ON tagged.tag_id = t.id
+		WHERE t.id = ? GROUP BY t.id, t.name, t.created_at
+	`, id).Scan(&t.ID, &t.Name, &t.Analyses, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// tagIDByName returns the ID of the tag with a name, or 0 if there is none
+func (db *DB) tagIDByName(name string) (int64, error) {
+	var id int64
+	err := db.conn.QueryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// CreateTag creates a tag unless one has the name already; created reports
+// which happened
+func (db *DB) CreateTag(name string) (tag *models.Tag, created bool, err error) {
+	id, err := db.tagIDByName(name)
+	if err != nil {
+		return nil, false, err
+	}
+	if id == 0 {
+		if id, err = db.conn.Insert(`INSERT INTO tags (name) VALUES (?)`, name); err != nil {
+			return nil, false, err
+		}
+		created = true
+	}
+	tag, err = db.GetTag(id)
+	return tag, created, err
+}
+
+// RenameTag renames a tag; ok is false when another tag has the name
+func (db *DB) RenameTag(id int64, name string) (ok bool, err error) {
+	existing, err := db.tagIDByName(name)
+	if err != nil || (existing != 0 && existing != id) {
+		return false, err
+	}
+	result, err := db.conn.Exec(`UPDATE tags SET name = ? WHERE id = ?`, name, id)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, sql.ErrNoRows
+	}
+	return true, nil
+}
+
+// DeleteTag deletes a tag and removes it from every analysis
+func (db *DB) DeleteTag(id int64) error {
+	result, err := db.conn.Exec(`DELETE FROM tags WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TagAnalysis adds the tag with a name to an analysis, creating the tag if
+// needed; it returns sql.ErrNoRows when there is no such analysis
+func (db *DB) TagAnalysis(analysisID int64, name string) error {
+	var n int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM analysis_results WHERE id = ?`, analysisID).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	tag, _, err := db.CreateTag(name)
+	if err != nil {
+		return err
+	}
+	_, err = db.conn.Exec(`
+		INSERT INTO analysis_tags (analysis_id, tag_id) VALUES (?, ?)
+		ON CONFLICT(analysis_id, tag_id) DO NOTHING
+	`, analysisID, tag.ID)
+	return err
+}
+
+// UntagAnalysis removes a tag from an analysis
+func (db *DB) UntagAnalysis(analysisID, tagID int64) error {
+	_, err := db.conn.Exec(`DELETE FROM analysis_tags WHERE analysis_id = ? AND tag_id = ?`, analysisID, tagID)
+	return err
+}
+
+// GetAnalysisTags lists the tags of an analysis by name
+func (db *DB) GetAnalysisTags(analysisID int64) ([]models.Tag, error) {
+	rows, err := db.conn.Query(`
+		SELECT t.id, t.name, (SELECT COUNT(*) FROM analysis_tags counted WHERE counted.tag_id = t.id), t.created_at
+		FROM tags t JOIN analysis_tags tagged ON tagged.tag_id = t.id
+		WHERE tagged.analysis_id = ? ORDER BY t.name
+	`, analysisID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Analyses, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// getTagNames maps analysis IDs to the names of their tags
+func (db *DB) getTagNames(analysisIDs []int64) (map[int64][]string, error) {
+	names := map[int64][]string{}
+	if len(analysisIDs) == 0 {
+		return names, nil
+	}
+
+	args := make([]any, len(analysisIDs))
+	for i, id := range analysisIDs {
+		args[i] = id
+	}
+	rows, err := db.conn.Query(`
+		SELECT tagged.analysis_id, t.name
+		FROM analysis_tags tagged JOIN tags t ON t.id = tagged.tag_id
+		WHERE tagged.analysis_id IN (`+strings.Repeat("?, ", len(args)-1)+`?) ORDER BY t.name
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = append(names[id], name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// usageDayFormat is the local calendar day usage counters are rolled up by
+const usageDayFormat = "2006-01-02"
+
+// usageTopLimit caps the symbols and providers listed in a usage summary
+const usageTopLimit = 10
+
+// RecordUsage increments the counter of metric and key for the day of t
+func (db *DB) RecordUsage(metric, key string, t time.Time) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO usage_stats (day, metric, key, count) VALUES (?, ?, ?, 1)
+		ON CONFLICT(day, metric, key) DO UPDATE SET count = usage_stats.count + 1
+	`, t.Format(usageDayFormat), metric, key)
+	return err
+}
+
+// GetUsageSummary rolls up the usage counters of the last days days, including today
+func (db *DB) GetUsageSummary(days int, now time.Time) (*models.UsageSummary, error) {
+	start := now.AddDate(0, 0, -(days - 1))
+	summary := &models.UsageSummary{
+		Days:           days,
+		Since:          start.Format(usageDayFormat),
+		Daily:          make([]models.UsageDay, 0, days),
+		TopSymbols:     []models.UsageCount{},
+		AlertSymbols:   []models.UsageCount{},
+		ProviderErrors: []models.UsageCount{},
+	}
+
+	byDay := map[string]*models.UsageDay{}
+	for i := 0; i < days; i++ {
+		date := start.AddDate(0, 0, i).Format(usageDayFormat)
+		summary.Daily = append(summary.Daily, models.UsageDay{Date: date})
+		byDay[date] = &summary.Daily[i]
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT day, metric, SUM(count) FROM usage_stats WHERE day >= ? GROUP BY day, metric
+	`, summary.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date, metric string
+		var count int
+		if err := rows.Scan(&date, &metric, &count); err != nil {
+			return nil, err
+		}
+		day, ok := byDay[date]
+		if !ok {
+			continue
+		}
+		switch metric {
+		case models.UsageAnalysis:
+			day.Analyses += count
+			summary.Totals.Analyses += count
+		case models.UsageAlertTriggered:
+			day.AlertsTriggered += count
+			summary.Totals.AlertsTriggered += count
+		case models.UsageProviderError:
+			day.ProviderErrors += count
+			summary.Totals.ProviderErrors += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tops := []struct {
+		metric string
+		out    *[]models.UsageCount
+	}{
+		{models.UsageAnalysis, &summary.TopSymbols},
+		{models.UsageAlertTriggered, &summary.AlertSymbols},
+		{models.UsageProviderError, &summary.ProviderErrors},
+	}
+	for _, top := range tops {
+		counts, err := db.topUsage(top.metric, summary.Since)
+		if err != nil {
+			return nil, err
+		}
+		*top.out = counts
+	}
+	return summary, nil
+}
+
+// topUsage returns the most frequent keys of metric since the given day
+func (db *DB) topUsage(metric, since string) ([]models.UsageCount, error) {
+	rows, err := db.conn.Query(`
+		SELECT key, SUM(count) AS total FROM usage_stats
+		WHERE metric = ? AND day >= ?
+		GROUP BY key ORDER BY total DESC, key LIMIT ?
+	`, metric, since, usageTopLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.UsageCount{}
+	for rows.Next() {
+		var c models.UsageCount
+		if err := rows.Scan(&c.Key, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"encoding/json"
+)
+
+// Watchlist cleanup policies applied to symbols removed by ReplaceWatchlist
+const (
+	WatchlistCleanupKeep   = "keep"   // leave alerts and analyses untouched
+	WatchlistCleanupAlerts = "alerts" // delete active alerts for removed symbols
+	WatchlistCleanupAll    = "all"    // delete alerts and analysis history for removed symbols
+)
+
+// WatchlistCleanup reports the records deleted for removed symbols
+type WatchlistCleanup struct {
+	AlertsDeleted   int64 `json:"alerts_deleted"`
+	AnalysesDeleted int64 `json:"analyses_deleted"`
+}
+
+// ReplaceWatchlist sets the tracked symbols and applies the cleanup policy to
+// removed symbols in a single transaction
+func (db *DB) ReplaceWatchlist(configID int64, symbols, removed []string, policy string) (*WatchlistCleanup, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	symbolsJSON, _ := json.Marshal(symbols)
+	if _, err := tx.Exec(`
+		UPDATE user_config SET tracked_symbols = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, string(symbolsJSON), configID); err != nil {
+		return nil, err
+	}
+
+	cleanup := &WatchlistCleanup{}
+	for _, symbol := range removed {
+		if policy == WatchlistCleanupAlerts || policy == WatchlistCleanupAll {
+			result, err := tx.Exec(`DELETE FROM price_alerts WHERE symbol = ? AND triggered = 0`, symbol)
+			if err != nil {
+				return nil, err
+			}
+			n, _ := result.RowsAffected()
+			cleanup.AlertsDeleted += n
+		}
+		if policy == WatchlistCleanupAll {
+			result, err := tx.Exec(`DELETE FROM analysis_results WHERE symbol = ?`, symbol)
+			if err != nil {
+				return nil, err
+			}
+			n, _ := result.RowsAffected()
+			cleanup.AnalysesDeleted += n
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	db.InvalidateConfigCache()
+	return cleanup, nil
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"log"
+	"sync"
+
+	"stockmarket/internal/models"
+)
+
+// Event names
+const (
+	AnalysisCompleted = "analysis.completed"
+	WatchlistChanged  = "watchlist.changed"
+	AlertTriggered    = "alert.triggered"
+	ProviderFailed    = "provider.failed"
+)
+
+// Event is a named message published on the bus
+type Event struct {
+	Name    string
+	Payload any
+}
+
+// AnalysisCompletedPayload is published after an analysis has been run and saved
+type AnalysisCompletedPayload struct {
+	Analysis *models.AnalysisResponse
+	Config   *models.UserConfig
+}
+
+// WatchlistChangedPayload is published after symbols are added to or removed from the watchlist
+type WatchlistChangedPayload struct {
+	Added   []string
+	Removed []string
+}
+
+// AlertTriggeredPayload is published when a price, gap or trailing alert fires
+type AlertTriggeredPayload struct {
+	Alert models.PriceAlert
+	Price float64
+}
+
+// ProviderFailedPayload is published when a market data or AI provider call fails
+type ProviderFailedPayload struct {
+	Kind     string // "market" or "ai"
+	Provider string
+	Err      error
+}
+
+// Handler processes a published event
+type Handler func(Event)
+
+// Bus is a minimal in-process publish/subscribe event bus
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates a new event bus
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers a handler for the named event
+func (b *Bus) Subscribe(name string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], h)
+}
+
+// Publish delivers an event to every subscriber asynchronously, so slow
+// subscribers never block the publisher
+func (b *Bus) Publish(name string, payload any) {
+	b.mu.RLock()
+	handlers := b.handlers[name]
+	b.mu.RUnlock()
+
+	event := Event{Name: name, Payload: payload}
+	for _, h := range handlers {
+		go func(h Handler) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("[EVENTS] Subscriber for %s panicked: %v", name, r)
+				}
+			}()
+			h(event)
+		}(h)
+	}
+}
@@ -0,0 +1,120 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Settings tunes an HTTP client and its connection pool
+type Settings struct {
+	Timeout             time.Duration // whole request, including reading the body
+	DialTimeout         time.Duration
+	KeepAlive           time.Duration
+	TLSHandshakeTimeout time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+}
+
+// Defaults for the shared client of each package
+var (
+	MarketDefaults = Settings{
+		Timeout:             30 * time.Second,
+		DialTimeout:         10 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+	}
+	AIDefaults = Settings{
+		Timeout:             60 * time.Second,
+		DialTimeout:         10 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        50,
+		MaxIdleConnsPerHost: 10,
+	}
+	NotifyDefaults = Settings{
+		Timeout:             10 * time.Second,
+		DialTimeout:         5 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        50,
+		MaxIdleConnsPerHost: 10,
+	}
+)
+
+// New builds an HTTP client with its own transport from settings
+func New(s Settings) *http.Client {
+	return &http.Client{
+		Timeout: s.Timeout,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   s.DialTimeout,
+				KeepAlive: s.KeepAlive,
+			}).DialContext,
+			MaxIdleConns:        s.MaxIdleConns,
+			MaxIdleConnsPerHost: s.MaxIdleConnsPerHost,
+			IdleConnTimeout:     s.IdleConnTimeout,
+			TLSHandshakeTimeout: s.TLSHandshakeTimeout,
+		},
+	}
+}
+
+// FromEnv overrides defaults with <prefix>_TIMEOUT, <prefix>_DIAL_TIMEOUT,
+// <prefix>_KEEP_ALIVE, <prefix>_TLS_HANDSHAKE_TIMEOUT, <prefix>_IDLE_CONN_TIMEOUT
+// (Go durations such as "30s") and <prefix>_MAX_IDLE_CONNS,
+// <prefix>_MAX_IDLE_CONNS_PER_HOST (integers)
+func FromEnv(prefix string, defaults Settings) (Settings, error) {
+	s := defaults
+	durations := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"TIMEOUT", &s.Timeout},
+		{"DIAL_TIMEOUT", &s.DialTimeout},
+		{"KEEP_ALIVE", &s.KeepAlive},
+		{"TLS_HANDSHAKE_TIMEOUT", &s.TLSHandshakeTimeout},
+		{"IDLE_CONN_TIMEOUT", &s.IdleConnTimeout},
+	}
+	for _, d := range durations {
+		key := prefix + "_" + d.name
+		raw := os.Getenv(key)
+		if raw == "" {
+			continue
+		}
+		value, err := time.ParseDuration(raw)
+		if err != nil || value <= 0 {
+			return s, fmt.Errorf("%s must be a positive duration such as 30s", key)
+		}
+		*d.value = value
+	}
+
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{"MAX_IDLE_CONNS", &s.MaxIdleConns},
+		{"MAX_IDLE_CONNS_PER_HOST", &s.MaxIdleConnsPerHost},
+	}
+	for _, n := range ints {
+		key := prefix + "_" + n.name
+		raw := os.Getenv(key)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return s, fmt.Errorf("%s must be a non-negative integer", key)
+		}
+		*n.value = value
+	}
+	return s, nil
+}
@@ -0,0 +1,225 @@
+package indicators
+
+import (
+	"container/list"
+	"sync"
+
+	"stockmarket/internal/models"
+)
+
+// DefaultCacheSize bounds the number of indicator values kept in memory
+const DefaultCacheSize = 2048
+
+// Key identifies a computed indicator value
+type Key struct {
+	Symbol    string
+	Interval  string
+	Indicator string
+	Period    int
+}
+
+// Stats reports cache effectiveness
+type Stats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Size          int    `json:"size"`
+	Capacity      int    `json:"capacity"`
+}
+
+// series identifies the candle series a key was computed from
+type series struct {
+	symbol   string
+	interval string
+}
+
+// fingerprint identifies the state of a candle series by its length and its
+// latest candle, which changes while the latest bar is still forming
+type fingerprint struct {
+	count     int
+	timestamp int64 // UnixNano, so equal instants in other locations match
+	close     float64
+	high      float64
+	low       float64
+	volume    int64
+}
+
+// fingerprintOf returns the fingerprint of a non-empty candle series
+func fingerprintOf(candles []models.Candle) fingerprint {
+	last := candles[len(candles)-1]
+	return fingerprint{
+		count:     len(candles),
+		timestamp: last.Timestamp.UnixNano(),
+		close:     last.Close,
+		high:      last.High,
+		low:       last.Low,
+		volume:    last.Volume,
+	}
+}
+
+type entry struct {
+	key   Key
+	value float64
+	ok    bool
+}
+
+// Cache is a bounded, concurrency-safe LRU of indicator values. Values for a
+// symbol/interval are dropped as soon as newer candles are observed for it.
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	items    map[Key]*list.Element
+
+	// Fingerprint of the candles last seen and invalidation generation per series
+	latest     map[series]fingerprint
+	generation map[series]uint64
+
+	hits, misses, evictions, invalidations uint64
+}
+
+// NewCache creates a cache holding at most capacity values
+func NewCache(capacity int) *Cache {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+	return &Cache{
+		capacity:   capacity,
+		order:      list.New(),
+		items:      make(map[Key]*list.Element),
+		latest:     make(map[series]fingerprint),
+		generation: make(map[series]uint64),
+	}
+}
+
+// GetOrCompute returns the cached value for key, calling compute on a miss.
+// A value computed while its series was invalidated is returned but not stored.
+func (c *Cache) GetOrCompute(key Key, compute func() (float64, bool)) (float64, bool) {
+	c.mu.Lock()
+	gen := c.generation[series{key.Symbol, key.Interval}]
+	c.mu.Unlock()
+	return c.getOrCompute(key, gen, compute)
+}
+
+// getOrCompute is GetOrCompute for a caller whose candles were observed at
+// generation gen; once the series has moved on, their values are computed
+// but neither served from nor stored in the cache
+func (c *Cache) getOrCompute(key Key, gen uint64, compute func() (float64, bool)) (float64, bool) {
+	s := series{key.Symbol, key.Interval}
+
+	c.mu.Lock()
+	if c.generation[s] != gen {
+		c.misses++
+		c.mu.Unlock()
+		return compute()
+	}
+	if el, found := c.items[key]; found {
+		c.order.MoveToFront(el)
+		c.hits++
+		e := el.Value.(*entry)
+		c.mu.Unlock()
+		return e.value, e.ok
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	value, ok := compute()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation[s] != gen {
+		return value, ok
+	}
+	if el, found := c.items[key]; found {
+		// Another reader stored it first
+		c.order.MoveToFront(el)
+		return value, ok
+	}
+	c.items[key] = c.order.PushFront(&entry{key: key, value: value, ok: ok})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+		c.evictions++
+	}
+	return value, ok
+}
+
+// Observe records the candles fetched for a symbol/interval and invalidates its
+// cached values when the series differs from the one last seen: a new candle,
+// a longer history, or an update of the latest candle
+func (c *Cache) Observe(symbol, interval string, candles []models.Candle) {
+	c.observe(symbol, interval, candles)
+}
+
+// observe is Observe returning the series' generation after it
+func (c *Cache) observe(symbol, interval string, candles []models.Candle) uint64 {
+	s := series{symbol, interval}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(candles) == 0 {
+		return c.generation[s]
+	}
+	latest := fingerprintOf(candles)
+	if seen, found := c.latest[s]; !found || seen != latest {
+		c.latest[s] = latest
+		c.invalidate(s)
+	}
+	return c.generation[s]
+}
+
+// Invalidate drops every cached value for a symbol/interval
+func (c *Cache) Invalidate(symbol, interval string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(series{symbol, interval})
+}
+
+// invalidate drops the series' values; c.mu must be held
+func (c *Cache) invalidate(s series) {
+	c.generation[s]++
+	for key, el := range c.items {
+		if key.Symbol == s.symbol && key.Interval == s.interval {
+			c.order.Remove(el)
+			delete(c.items, key)
+		}
+	}
+	c.invalidations++
+}
+
+// Stats returns a snapshot of the cache counters
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Evictions:     c.evictions,
+		Invalidations: c.invalidations,
+		Size:          c.order.Len(),
+		Capacity:      c.capacity,
+	}
+}
+
+// Snapshot observes candles for a symbol/interval and returns the default
+// indicator set, computing only values missing from the cache
+func (c *Cache) Snapshot(symbol, interval string, candles []models.Candle) models.Indicators {
+	gen := c.observe(symbol, interval, candles)
+
+	get := func(name string, period int, fn func([]models.Candle, int) (float64, bool)) *float64 {
+		key := Key{Symbol: symbol, Interval: interval, Indicator: name, Period: period}
+		value, ok := c.getOrCompute(key, gen, func() (float64, bool) { return fn(candles, period) })
+		if !ok {
+			return nil
+		}
+		return &value
+	}
+
+	return models.Indicators{
+		RSI14: get("rsi", DefaultRSIPeriod, RSI),
+		SMA20: get("sma", DefaultSMAPeriod, SMA),
+		ATR14: get("atr", DefaultATRPeriod, ATR),
+	}
+}
@@ -0,0 +1,99 @@
+package indicators
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// testCandles returns n daily candles rising by one each day
+func testCandles(n int) []models.Candle {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, n)
+	for i := range candles {
+		price := 100 + float64(i)
+		candles[i] = models.Candle{Timestamp: start.AddDate(0, 0, i), Open: price, High: price + 1, Low: price - 1, Close: price, Volume: 1000}
+	}
+	return candles
+}
+
+func TestObserveInvalidatesOnSeriesChange(t *testing.T) {
+	candles := testCandles(30)
+	tests := []struct {
+		name       string
+		update     func([]models.Candle) []models.Candle
+		invalidate bool
+	}{
+		{"same series", func(c []models.Candle) []models.Candle { return c }, false},
+		{"same instant in another location", func(c []models.Candle) []models.Candle {
+			c[len(c)-1].Timestamp = c[len(c)-1].Timestamp.In(time.FixedZone("EST", -5*3600))
+			return c
+		}, false},
+		{"new candle", func(c []models.Candle) []models.Candle { return testCandles(31) }, true},
+		{"longer history", func(c []models.Candle) []models.Candle {
+			return append([]models.Candle{{Timestamp: c[0].Timestamp.AddDate(0, 0, -1), Close: 99}}, c...)
+		}, true},
+		{"latest close updated", func(c []models.Candle) []models.Candle { c[len(c)-1].Close++; return c }, true},
+		{"latest high updated", func(c []models.Candle) []models.Candle { c[len(c)-1].High++; return c }, true},
+		{"latest low updated", func(c []models.Candle) []models.Candle { c[len(c)-1].Low--; return c }, true},
+		{"latest volume updated", func(c []models.Candle) []models.Candle { c[len(c)-1].Volume += 10; return c }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache(0)
+			c.Snapshot("AAPL", "1m", candles)
+			before := c.Stats().Invalidations
+
+			updated := tt.update(append([]models.Candle(nil), candles...))
+			snapshot := c.Snapshot("AAPL", "1m", updated)
+			invalidated := c.Stats().Invalidations > before
+			if invalidated != tt.invalidate {
+				t.Fatalf("invalidated = %v, want %v", invalidated, tt.invalidate)
+			}
+			if want, _ := SMA(updated, DefaultSMAPeriod); *snapshot.SMA20 != want {
+				t.Fatalf("SMA20 = %v, want %v", *snapshot.SMA20, want)
+			}
+		})
+	}
+}
+
+func TestCacheParallelReadersDuringInvalidation(t *testing.T) {
+	c := NewCache(16)
+	series := [][]models.Candle{testCandles(30), testCandles(31), testCandles(32)}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 200 {
+				candles := series[(i+j)%len(series)]
+				snapshot := c.Snapshot("AAPL", "1m", candles)
+				if snapshot.SMA20 == nil || snapshot.RSI14 == nil || snapshot.ATR14 == nil {
+					t.Error("missing indicator")
+					return
+				}
+				if j%10 == 0 {
+					c.Invalidate("AAPL", "1m")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Whatever the interleaving, the cache must serve the values of the series
+	// observed last
+	last := series[0]
+	snapshot := c.Snapshot("AAPL", "1m", last)
+	if want, _ := SMA(last, DefaultSMAPeriod); *snapshot.SMA20 != want {
+		t.Fatalf("SMA20 = %v, want %v", *snapshot.SMA20, want)
+	}
+	if want, _ := RSI(last, DefaultRSIPeriod); *snapshot.RSI14 != want {
+		t.Fatalf("RSI14 = %v, want %v", *snapshot.RSI14, want)
+	}
+	if stats := c.Stats(); stats.Size > stats.Capacity {
+		t.Fatalf("size %d over capacity %d", stats.Size, stats.Capacity)
+	}
+}
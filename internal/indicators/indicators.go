@@ -0,0 +1,86 @@
+package indicators
+
+import (
+	"math"
+
+	"stockmarket/internal/models"
+)
+
+// Default periods used for the indicators included in prompts and charts
+const (
+	DefaultRSIPeriod = 14
+	DefaultSMAPeriod = 20
+	DefaultATRPeriod = 14
+)
+
+// SMA returns the simple moving average of the last period closes. Candles
+// must be ordered oldest first; ok is false when there are too few of them.
+func SMA(candles []models.Candle, period int) (value float64, ok bool) {
+	if period <= 0 || len(candles) < period {
+		return 0, false
+	}
+	var sum float64
+	for _, c := range candles[len(candles)-period:] {
+		sum += c.Close
+	}
+	return sum / float64(period), true
+}
+
+// RSI returns Wilder's relative strength index over period, evaluated at the
+// latest candle
+func RSI(candles []models.Candle, period int) (value float64, ok bool) {
+	if period <= 0 || len(candles) <= period {
+		return 0, false
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		change := candles[i].Close - candles[i-1].Close
+		if change > 0 {
+			avgGain += change
+		} else {
+			avgLoss -= change
+		}
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+
+	// Wilder smoothing over the remaining candles
+	for i := period + 1; i < len(candles); i++ {
+		change := candles[i].Close - candles[i-1].Close
+		gain, loss := math.Max(change, 0), math.Max(-change, 0)
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+	}
+
+	if avgLoss == 0 {
+		return 100, true
+	}
+	rs := avgGain / avgLoss
+	return 100 - 100/(1+rs), true
+}
+
+// ATR returns Wilder's average true range over period, evaluated at the
+// latest candle
+func ATR(candles []models.Candle, period int) (value float64, ok bool) {
+	if period <= 0 || len(candles) <= period {
+		return 0, false
+	}
+
+	trueRange := func(i int) float64 {
+		prevClose := candles[i-1].Close
+		return math.Max(candles[i].High-candles[i].Low,
+			math.Max(math.Abs(candles[i].High-prevClose), math.Abs(candles[i].Low-prevClose)))
+	}
+
+	var atr float64
+	for i := 1; i <= period; i++ {
+		atr += trueRange(i)
+	}
+	atr /= float64(period)
+
+	for i := period + 1; i < len(candles); i++ {
+		atr = (atr*float64(period-1) + trueRange(i)) / float64(period)
+	}
+	return atr, true
+}
@@ -0,0 +1,42 @@
+package market
+
+import (
+	"context"
+
+	"stockmarket/internal/models"
+)
+
+// AdjustedHistoryProvider is implemented by providers that can adjust
+// historical prices for splits and dividends
+type AdjustedHistoryProvider interface {
+	GetAdjustedHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error)
+}
+
+// GetAdjustedHistoricalData returns candles for period with prices adjusted
+// for splits and dividends, so changes across a split are not mistaken for
+// price moves. Providers without adjusted data use Yahoo Finance.
+func GetAdjustedHistoricalData(ctx context.Context, p Provider, symbol string, period string) ([]models.Candle, error) {
+	p = forSymbol(p, symbol)
+	// Only take the provider's rate limit when it is the one called
+	if _, ok := inner(p).(AdjustedHistoryProvider); !ok {
+		return NewYahooFinance().GetAdjustedHistoricalData(ctx, symbol, period)
+	}
+	p, err := unwrap(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return p.(AdjustedHistoryProvider).GetAdjustedHistoricalData(ctx, symbol, period)
+}
+
+// adjustCandle scales a candle's prices to its adjusted close; volume is
+// left as reported
+func adjustCandle(c *models.Candle, adjClose float64) {
+	if adjClose <= 0 || c.Close <= 0 {
+		return
+	}
+	factor := adjClose / c.Close
+	c.Open *= factor
+	c.High *= factor
+	c.Low *= factor
+	c.Close = adjClose
+}
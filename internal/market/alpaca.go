@@ -0,0 +1,289 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"stockmarket/internal/models"
+)
+
+// Alpaca Data API v2 endpoints. The free plan only includes the IEX feed.
+const (
+	alpacaBaseURL   = "https://data.alpaca.markets/v2"
+	alpacaStreamURL = "wss://stream.data.alpaca.markets/v2/iex"
+	alpacaFeed      = "iex"
+)
+
+// alpacaMaxBars is the largest page size the bars endpoint accepts
+const alpacaMaxBars = 10000
+
+// Alpaca implements the Provider interface for the Alpaca Data API, with
+// trades streamed over its WebSocket. The API key is stored as
+// "KEY_ID:SECRET_KEY", the same pair used for trading.
+type Alpaca struct {
+	keyID     string
+	secretKey string
+	client    *http.Client
+}
+
+// NewAlpaca creates a new Alpaca provider from a "KEY_ID:SECRET_KEY" pair
+func NewAlpaca(apiKey string) *Alpaca {
+	keyID, secretKey, _ := strings.Cut(apiKey, ":")
+	return &Alpaca{
+		keyID:     strings.TrimSpace(keyID),
+		secretKey: strings.TrimSpace(secretKey),
+		client:    sharedHTTPClient,
+	}
+}
+
+// Name returns the provider name
+func (a *Alpaca) Name() string {
+	return "alpaca"
+}
+
+// alpacaBar is a bar as returned by the bars and snapshot endpoints
+type alpacaBar struct {
+	T time.Time `json:"t"`
+	O float64   `json:"o"`
+	H float64   `json:"h"`
+	L float64   `json:"l"`
+	C float64   `json:"c"`
+	V int64     `json:"v"`
+}
+
+// GetQuote builds a quote from the symbol's snapshot: latest trade, today's
+// bar and the previous daily bar
+func (a *Alpaca) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	var result struct {
+		LatestTrade *struct {
+			T time.Time `json:"t"`
+			P float64   `json:"p"`
+		} `json:"latestTrade"`
+		DailyBar     *alpacaBar `json:"dailyBar"`
+		PrevDailyBar *alpacaBar `json:"prevDailyBar"`
+	}
+	params := url.Values{"feed": {alpacaFeed}}
+	if err := a.get(ctx, "/stocks/"+url.PathEscape(symbol)+"/snapshot", params, &result); err != nil {
+		return nil, err
+	}
+	if result.LatestTrade == nil || result.LatestTrade.P == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	quote := &models.Quote{
+		Symbol:    symbol,
+		Price:     result.LatestTrade.P,
+		Timestamp: result.LatestTrade.T,
+	}
+	if bar := result.DailyBar; bar != nil {
+		quote.Open, quote.High, quote.Low, quote.Volume = bar.O, bar.H, bar.L, bar.V
+	}
+	// Outside the regular session the latest trade is an extended-hours
+	// trade; the price stays at the regular close
+	if IsExtendedSession(MarketState(result.LatestTrade.T)) && result.DailyBar != nil {
+		quote.ExtendedPrice = result.LatestTrade.P
+		quote.Price = result.DailyBar.C
+	}
+	if prev := result.PrevDailyBar; prev != nil && prev.C > 0 {
+		quote.PreviousClose = prev.C
+		quote.Change = quote.Price - prev.C
+		quote.ChangePercent = quote.Change / prev.C * 100
+	}
+	return quote, nil
+}
+
+// GetHistoricalData fetches split-adjusted bars, newest first
+func (a *Alpaca) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from, to := p.Bounds(now)
+
+	// Map period to Alpaca bar timeframes
+	timeframe := "1Day"
+	switch p.Name {
+	case "1d":
+		timeframe = "5Min"
+	case "5d":
+		timeframe = "15Min"
+	case "5y":
+		timeframe = "1Week"
+	case PeriodMax:
+		timeframe = "1Month"
+	case "":
+		switch p.Granularity(now) {
+		case GranularityIntraday:
+			timeframe = "15Min"
+		case GranularityWeekly:
+			timeframe = "1Week"
+		}
+	}
+
+	params := url.Values{
+		"timeframe":  {timeframe},
+		"start":      {from.UTC().Format(time.RFC3339)},
+		"end":        {to.UTC().Format(time.RFC3339)},
+		"adjustment": {"all"},
+		"feed":       {alpacaFeed},
+		"limit":      {fmt.Sprint(alpacaMaxBars)},
+	}
+
+	var candles []models.Candle
+	for {
+		var page struct {
+			Bars          []alpacaBar `json:"bars"`
+			NextPageToken *string     `json:"next_page_token"`
+		}
+		if err := a.get(ctx, "/stocks/"+url.PathEscape(symbol)+"/bars", params, &page); err != nil {
+			return nil, err
+		}
+		for _, b := range page.Bars {
+			candles = append(candles, models.Candle{
+				Timestamp: b.T,
+				Open:      b.O,
+				High:      b.H,
+				Low:       b.L,
+				Close:     b.C,
+				Volume:    b.V,
+			})
+		}
+		if page.NextPageToken == nil || *page.NextPageToken == "" {
+			break
+		}
+		params.Set("page_token", *page.NextPageToken)
+	}
+
+	if len(candles) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Timestamp.After(candles[j].Timestamp)
+	})
+
+	return candles, nil
+}
+
+// get performs an authenticated GET request and decodes the JSON response
+func (a *Alpaca) get(ctx context.Context, path string, params url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", alpacaBaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("APCA-API-KEY-ID", a.keyID)
+	req.Header.Set("APCA-API-SECRET-KEY", a.secretKey)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusBadRequest:
+		// Unknown or malformed symbols
+		return ErrInvalidSymbol
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return fmt.Errorf("%w: Alpaca returned status %d", ErrAPIError, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StreamQuotes streams trades over the Alpaca WebSocket. Each symbol is
+// seeded with a snapshot quote, and streamed trades update its price, change
+// and day range. Dropped connections are retried until ctx is done.
+func (a *Alpaca) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	quotes, err := seedQuotes(ctx, a, symbols, ch)
+	if err != nil {
+		return err
+	}
+	return streamWithReconnect(ctx, "ALPACA", func(ctx context.Context) error {
+		return a.stream(ctx, symbols, quotes, ch)
+	})
+}
+
+// alpacaMessage is a control or trade message from the stream; messages
+// arrive in JSON arrays
+type alpacaMessage struct {
+	Type   string    `json:"T"`
+	Msg    string    `json:"msg"`
+	Code   int       `json:"code"`
+	Symbol string    `json:"S"`
+	Price  float64   `json:"p"`
+	Time   time.Time `json:"t"`
+}
+
+// stream runs a single authenticated WebSocket session until it fails or ctx is done
+func (a *Alpaca) stream(ctx context.Context, symbols []string, quotes map[string]*models.Quote, ch chan<- models.Quote) error {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	conn, _, err := dialer.DialContext(ctx, alpacaStreamURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Close the connection when ctx is done to unblock ReadJSON
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := conn.WriteJSON(map[string]string{"action": "auth", "key": a.keyID, "secret": a.secretKey}); err != nil {
+		return err
+	}
+	if err := conn.WriteJSON(map[string]any{"action": "subscribe", "trades": symbols}); err != nil {
+		return err
+	}
+
+	for {
+		var messages []alpacaMessage
+		if err := conn.ReadJSON(&messages); err != nil {
+			return err
+		}
+
+		for _, m := range messages {
+			switch m.Type {
+			case "t":
+			case "error":
+				return errors.New("alpaca stream: " + m.Msg)
+			default:
+				// Connection, auth and subscription confirmations
+				continue
+			}
+
+			quote, ok := quotes[m.Symbol]
+			if !ok || m.Price <= 0 {
+				continue
+			}
+			applyTrade(quote, m.Price, m.Time)
+
+			select {
+			case ch <- *quote:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
@@ -34,10 +35,42 @@ func (av *AlphaVantage) Name() string {
 	return "alphavantage"
 }
 
+// alphaVantageSuffixes maps the Yahoo suffixes of the international listings
+// Alpha Vantage has data for to its own
+var alphaVantageSuffixes = map[string]string{
+	"L":  "LON",
+	"DE": "DEX",
+	"TO": "TRT",
+	"V":  "TRV",
+}
+
+// covers reports whether Alpha Vantage has data for symbol: US listings and
+// the markets in alphaVantageSuffixes, but no indices
+func (av *AlphaVantage) covers(symbol string) bool {
+	if IsIndexSymbol(symbol) {
+		return false
+	}
+	suffix := ListingSuffix(symbol)
+	return suffix == "" || alphaVantageSuffixes[suffix] != ""
+}
+
+// alphaVantageSymbol maps international listings to Alpha Vantage's suffixes,
+// e.g. SAP.DE to SAP.DEX
+func alphaVantageSymbol(symbol string) string {
+	if ticker, suffix := splitListing(symbol); alphaVantageSuffixes[suffix] != "" {
+		return ticker + "." + alphaVantageSuffixes[suffix]
+	}
+	return symbol
+}
+
 // GetQuote fetches the current quote for a symbol
 func (av *AlphaVantage) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	if base, quote, ok := ParseCurrencyPair(symbol); ok {
+		return av.getExchangeRate(ctx, symbol, base, quote)
+	}
+
 	url := fmt.Sprintf("%s?function=GLOBAL_QUOTE&symbol=%s&apikey=%s",
-		alphaVantageBaseURL, symbol, av.apiKey)
+		alphaVantageBaseURL, alphaVantageSymbol(symbol), av.apiKey)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -102,28 +135,111 @@ func (av *AlphaVantage) GetQuote(ctx context.Context, symbol string) (*models.Qu
 	}, nil
 }
 
+// getExchangeRate quotes a currency pair via CURRENCY_EXCHANGE_RATE, which
+// reports only the latest rate
+func (av *AlphaVantage) getExchangeRate(ctx context.Context, symbol, base, quote string) (*models.Quote, error) {
+	url := fmt.Sprintf("%s?function=CURRENCY_EXCHANGE_RATE&from_currency=%s&to_currency=%s&apikey=%s",
+		alphaVantageBaseURL, base, quote, av.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := av.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Rate struct {
+			ExchangeRate  string `json:"5. Exchange Rate"`
+			LastRefreshed string `json:"6. Last Refreshed"`
+		} `json:"Realtime Currency Exchange Rate"`
+		Note string `json:"Note"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if result.Note != "" && strings.Contains(result.Note, "API call frequency") {
+		return nil, ErrRateLimited
+	}
+
+	rate, _ := strconv.ParseFloat(result.Rate.ExchangeRate, 64)
+	if rate == 0 {
+		return nil, ErrInvalidSymbol
+	}
+	timestamp, err := time.Parse("2006-01-02 15:04:05", result.Rate.LastRefreshed)
+	if err != nil {
+		timestamp = time.Now()
+	}
+
+	return &models.Quote{
+		Symbol:    symbol,
+		Price:     rate,
+		Timestamp: timestamp,
+		Currency:  quote,
+	}, nil
+}
+
 // GetHistoricalData fetches historical OHLCV data
 func (av *AlphaVantage) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	return av.history(ctx, symbol, period, false)
+}
+
+// GetAdjustedHistoricalData fetches daily data from TIME_SERIES_DAILY_ADJUSTED,
+// with prices adjusted for splits and dividends; intraday data is unadjusted
+func (av *AlphaVantage) GetAdjustedHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	return av.history(ctx, symbol, period, true)
+}
+
+// history fetches historical OHLCV data, adjusted for splits and dividends
+// when requested
+func (av *AlphaVantage) history(ctx context.Context, symbol string, period string, adjusted bool) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
 	// Map period to Alpha Vantage function
 	function := "TIME_SERIES_DAILY"
 	outputSize := "compact" // 100 data points
 
-	switch period {
+	switch p.Name {
 	case "1d", "5d":
 		function = "TIME_SERIES_INTRADAY"
 	case "1m", "3m":
 		outputSize = "compact"
-	case "1y", "5y":
+	case "1y", "5y", PeriodYTD, PeriodMax:
+		outputSize = "full"
+	default:
+		// Custom ranges are cut from the full daily series (or the recent
+		// intraday one) below
 		outputSize = "full"
+		if p.Granularity(time.Now()) == GranularityIntraday {
+			function = "TIME_SERIES_INTRADAY"
+		}
+	}
+
+	symbolParams := "symbol=" + alphaVantageSymbol(symbol)
+	if base, quote, ok := ParseCurrencyPair(symbol); ok {
+		// Currency pairs use the FX series, which report no volume
+		function = strings.Replace(function, "TIME_SERIES", "FX", 1)
+		symbolParams = "from_symbol=" + base + "&to_symbol=" + quote
+	} else if adjusted && function == "TIME_SERIES_DAILY" {
+		function = "TIME_SERIES_DAILY_ADJUSTED"
 	}
 
 	var url string
-	if function == "TIME_SERIES_INTRADAY" {
-		url = fmt.Sprintf("%s?function=%s&symbol=%s&interval=5min&outputsize=%s&apikey=%s",
-			alphaVantageBaseURL, function, symbol, outputSize, av.apiKey)
+	if function == "TIME_SERIES_INTRADAY" || function == "FX_INTRADAY" {
+		url = fmt.Sprintf("%s?function=%s&%s&interval=5min&outputsize=%s&apikey=%s",
+			alphaVantageBaseURL, function, symbolParams, outputSize, av.apiKey)
 	} else {
-		url = fmt.Sprintf("%s?function=%s&symbol=%s&outputsize=%s&apikey=%s",
-			alphaVantageBaseURL, function, symbol, outputSize, av.apiKey)
+		url = fmt.Sprintf("%s?function=%s&%s&outputsize=%s&apikey=%s",
+			alphaVantageBaseURL, function, symbolParams, outputSize, av.apiKey)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -184,16 +300,26 @@ func (av *AlphaVantage) GetHistoricalData(ctx context.Context, symbol string, pe
 		high, _ := strconv.ParseFloat(dataMap["2. high"].(string), 64)
 		low, _ := strconv.ParseFloat(dataMap["3. low"].(string), 64)
 		close, _ := strconv.ParseFloat(dataMap["4. close"].(string), 64)
-		volume, _ := strconv.ParseInt(dataMap["5. volume"].(string), 10, 64)
+		volumeStr, _ := dataMap["5. volume"].(string)
+
+		// The adjusted series moves volume to "6. volume"
+		var adjClose float64
+		if adjStr, ok := dataMap["5. adjusted close"].(string); ok {
+			adjClose, _ = strconv.ParseFloat(adjStr, 64)
+			volumeStr, _ = dataMap["6. volume"].(string)
+		}
+		volume, _ := strconv.ParseInt(volumeStr, 10, 64)
 
-		candles = append(candles, models.Candle{
+		candle := models.Candle{
 			Timestamp: timestamp,
 			Open:      open,
 			High:      high,
 			Low:       low,
 			Close:     close,
 			Volume:    volume,
-		})
+		}
+		adjustCandle(&candle, adjClose)
+		candles = append(candles, candle)
 	}
 
 	// Sort by timestamp (newest first) - O(n log n)
@@ -201,31 +327,139 @@ func (av *AlphaVantage) GetHistoricalData(ctx context.Context, symbol string, pe
 		return candles[i].Timestamp.After(candles[j].Timestamp)
 	})
 
+	if p.IsCustom() || p.Name == PeriodYTD {
+		from, to := p.Bounds(time.Now())
+		candles = filterCandles(candles, from, to)
+	}
+
 	return candles, nil
 }
 
+// GetCompanyProfile fetches the company overview
+func (av *AlphaVantage) GetCompanyProfile(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	url := fmt.Sprintf("%s?function=OVERVIEW&symbol=%s&apikey=%s",
+		alphaVantageBaseURL, alphaVantageSymbol(symbol), av.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := av.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Symbol               string `json:"Symbol"`
+		Name                 string `json:"Name"`
+		AssetType            string `json:"AssetType"`
+		Exchange             string `json:"Exchange"`
+		Currency             string `json:"Currency"`
+		Sector               string `json:"Sector"`
+		Industry             string `json:"Industry"`
+		MarketCapitalization string `json:"MarketCapitalization"`
+		PERatio              string `json:"PERatio"`
+		Note                 string `json:"Note"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	// Check for rate limit
+	if result.Note != "" && strings.Contains(result.Note, "API call frequency") {
+		return nil, ErrRateLimited
+	}
+
+	if result.Symbol == "" {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Missing values are reported as "None" and parse to zero
+	marketCap, _ := strconv.ParseFloat(result.MarketCapitalization, 64)
+	peRatio, _ := strconv.ParseFloat(result.PERatio, 64)
+
+	// Asset types are named like Yahoo's, e.g. "Common Stock" is EQUITY
+	assetType := strings.ToUpper(result.AssetType)
+	if assetType == "COMMON STOCK" {
+		assetType = "EQUITY"
+	}
+
+	return &models.CompanyProfile{
+		Symbol:    symbol,
+		Name:      result.Name,
+		Exchange:  result.Exchange,
+		Currency:  result.Currency,
+		Sector:    titleCase(result.Sector),
+		Industry:  titleCase(result.Industry),
+		AssetType: assetType,
+		MarketCap: marketCap,
+		PERatio:   peRatio,
+	}, nil
+}
+
+// titleCase converts the upper-case sector and industry names of the
+// overview (e.g. "CONSUMER ELECTRONICS") to title case
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// SearchSymbols finds symbols via the SYMBOL_SEARCH endpoint
+func (av *AlphaVantage) SearchSymbols(ctx context.Context, query string) ([]models.SymbolMatch, error) {
+	url := fmt.Sprintf("%s?function=SYMBOL_SEARCH&keywords=%s&apikey=%s",
+		alphaVantageBaseURL, url.QueryEscape(query), av.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := av.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		BestMatches []struct {
+			Symbol string `json:"1. symbol"`
+			Name   string `json:"2. name"`
+			Type   string `json:"3. type"`
+			Region string `json:"4. region"`
+		} `json:"bestMatches"`
+		Note string `json:"Note"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	// Check for rate limit
+	if result.Note != "" && strings.Contains(result.Note, "API call frequency") {
+		return nil, ErrRateLimited
+	}
+
+	// Alpha Vantage reports the market region rather than the exchange
+	matches := make([]models.SymbolMatch, 0, len(result.BestMatches))
+	for _, m := range result.BestMatches {
+		matches = append(matches, models.SymbolMatch{
+			Symbol:   m.Symbol,
+			Name:     m.Name,
+			Exchange: m.Region,
+			Type:     m.Type,
+		})
+	}
+	return matches, nil
+}
+
 // StreamQuotes streams real-time quotes (Alpha Vantage doesn't support real-time streaming in free tier)
 func (av *AlphaVantage) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
 	// Alpha Vantage doesn't support WebSocket streaming, so we poll
-	ticker := time.NewTicker(15 * time.Second) // Rate limit friendly
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			for _, symbol := range symbols {
-				quote, err := av.GetQuote(ctx, symbol)
-				if err != nil {
-					continue // Skip on error
-				}
-				select {
-				case ch <- *quote:
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
-		}
-	}
+	return pollQuotes(ctx, av, symbols, ch, 15*time.Second) // Rate limit friendly
 }
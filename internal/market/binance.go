@@ -0,0 +1,321 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"stockmarket/internal/market/resample"
+	"stockmarket/internal/models"
+)
+
+const (
+	binanceBaseURL   = "https://api.binance.com/api/v3"
+	binanceStreamURL = "wss://stream.binance.com:9443/stream"
+)
+
+// binanceMaxKlines is the largest page size of the klines endpoint
+const binanceMaxKlines = 1000
+
+// Binance implements the Provider interface for crypto pairs on Binance's
+// public market data API, which needs no API key. Pairs may be written as
+// BTCUSDT, BTC-USDT, BTC/USDT or btc_usdt; quotes keep the symbol as given.
+type Binance struct {
+	client *http.Client
+}
+
+// NewBinance creates a new Binance provider
+func NewBinance() *Binance {
+	return &Binance{client: sharedHTTPClient}
+}
+
+// Name returns the provider name
+func (b *Binance) Name() string {
+	return "binance"
+}
+
+// binancePair normalizes a crypto pair to Binance's form, e.g. "btc-usdt" to "BTCUSDT"
+func binancePair(symbol string) string {
+	return strings.NewReplacer("-", "", "/", "", "_", "").Replace(strings.ToUpper(strings.TrimSpace(symbol)))
+}
+
+// binanceTicker is a 24 hour rolling window ticker, as returned by the REST
+// API and (with short keys) the ticker stream
+type binanceTicker struct {
+	LastPrice          string `json:"lastPrice"`
+	OpenPrice          string `json:"openPrice"`
+	HighPrice          string `json:"highPrice"`
+	LowPrice           string `json:"lowPrice"`
+	Volume             string `json:"volume"`
+	PrevClosePrice     string `json:"prevClosePrice"`
+	PriceChange        string `json:"priceChange"`
+	PriceChangePercent string `json:"priceChangePercent"`
+	CloseTime          int64  `json:"closeTime"`
+}
+
+// quote converts a ticker into a quote for symbol
+func (t binanceTicker) quote(symbol string) *models.Quote {
+	volume, _ := strconv.ParseFloat(t.Volume, 64)
+	return &models.Quote{
+		Symbol:        symbol,
+		Price:         parseBinanceFloat(t.LastPrice),
+		Open:          parseBinanceFloat(t.OpenPrice),
+		High:          parseBinanceFloat(t.HighPrice),
+		Low:           parseBinanceFloat(t.LowPrice),
+		Volume:        int64(volume),
+		PreviousClose: parseBinanceFloat(t.PrevClosePrice),
+		Change:        parseBinanceFloat(t.PriceChange),
+		ChangePercent: parseBinanceFloat(t.PriceChangePercent),
+		Timestamp:     time.UnixMilli(t.CloseTime),
+		Session:       MarketStateOpen, // crypto trades around the clock
+	}
+}
+
+// GetQuote fetches the 24 hour ticker of a pair
+func (b *Binance) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	var ticker binanceTicker
+	if err := b.get(ctx, "/ticker/24hr", url.Values{"symbol": {binancePair(symbol)}}, &ticker); err != nil {
+		return nil, err
+	}
+	quote := ticker.quote(symbol)
+	if quote.Price == 0 {
+		return nil, ErrInvalidSymbol
+	}
+	return quote, nil
+}
+
+// GetHistoricalData fetches klines for a pair, newest first
+func (b *Binance) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from, to := p.Bounds(now)
+
+	// Map period to kline intervals
+	interval := "1d"
+	switch p.Name {
+	case "1d":
+		interval = "5m"
+	case "5d":
+		interval = "15m"
+	case "5y":
+		interval = "1w"
+	case PeriodMax:
+		interval = "1M"
+	case "":
+		switch p.Granularity(now) {
+		case GranularityIntraday:
+			interval = "15m"
+		case GranularityWeekly:
+			interval = "1w"
+		}
+	}
+
+	return b.klines(ctx, symbol, interval, from, to)
+}
+
+// binanceIntervals maps canonical intervals to kline intervals
+var binanceIntervals = map[string]string{
+	resample.Interval1m:  "1m",
+	resample.Interval5m:  "5m",
+	resample.Interval15m: "15m",
+	resample.Interval30m: "30m",
+	resample.Interval1h:  "1h",
+	resample.Interval1d:  "1d",
+	resample.Interval1w:  "1w",
+}
+
+// GetHistoricalDataInterval fetches candles of the given interval for period
+func (b *Binance) GetHistoricalDataInterval(ctx context.Context, symbol, period, interval string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	klineInterval, ok := binanceIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", resample.ErrInvalidInterval, interval)
+	}
+	from, to := p.Bounds(time.Now())
+	return b.klines(ctx, symbol, klineInterval, from, to)
+}
+
+// klines fetches candles between from and to, paging through the kline
+// limit, newest first
+func (b *Binance) klines(ctx context.Context, symbol, interval string, from, to time.Time) ([]models.Candle, error) {
+	params := url.Values{
+		"symbol":   {binancePair(symbol)},
+		"interval": {interval},
+		"endTime":  {fmt.Sprint(to.UnixMilli())},
+		"limit":    {fmt.Sprint(binanceMaxKlines)},
+	}
+
+	var candles []models.Candle
+	start := from.UnixMilli()
+	for {
+		params.Set("startTime", fmt.Sprint(start))
+
+		// Each kline is [openTime, open, high, low, close, volume, closeTime, ...]
+		var klines [][]any
+		if err := b.get(ctx, "/klines", params, &klines); err != nil {
+			return nil, err
+		}
+		for _, k := range klines {
+			if len(k) < 6 {
+				continue
+			}
+			openTime, _ := k[0].(float64)
+			volume, _ := strconv.ParseFloat(fmt.Sprint(k[5]), 64)
+			candles = append(candles, models.Candle{
+				Timestamp: time.UnixMilli(int64(openTime)),
+				Open:      parseBinanceFloat(fmt.Sprint(k[1])),
+				High:      parseBinanceFloat(fmt.Sprint(k[2])),
+				Low:       parseBinanceFloat(fmt.Sprint(k[3])),
+				Close:     parseBinanceFloat(fmt.Sprint(k[4])),
+				Volume:    int64(volume),
+			})
+		}
+		if len(klines) < binanceMaxKlines {
+			break
+		}
+		start = candles[len(candles)-1].Timestamp.UnixMilli() + 1
+	}
+
+	if len(candles) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Timestamp.After(candles[j].Timestamp)
+	})
+
+	return candles, nil
+}
+
+// get performs a GET request against the REST API and decodes the JSON response
+func (b *Binance) get(ctx context.Context, path string, params url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", binanceBaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest:
+		// Unknown pairs are reported as {"code":-1121,"msg":"Invalid symbol."}
+		return ErrInvalidSymbol
+	case http.StatusTooManyRequests, http.StatusTeapot:
+		// 418 means the IP was banned after ignoring 429s
+		return ErrRateLimited
+	default:
+		return fmt.Errorf("%w: Binance returned status %d", ErrAPIError, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StreamQuotes streams 24 hour tickers over the Binance WebSocket. Every
+// ticker event carries a full quote. Dropped connections are retried until
+// ctx is done.
+func (b *Binance) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	// Map stream names back to the symbols as tracked
+	streams := make([]string, 0, len(symbols))
+	bySymbol := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		pair := binancePair(symbol)
+		streams = append(streams, strings.ToLower(pair)+"@ticker")
+		bySymbol[pair] = symbol
+	}
+	streamURL := binanceStreamURL + "?streams=" + strings.Join(streams, "/")
+
+	return streamWithReconnect(ctx, "BINANCE", func(ctx context.Context) error {
+		return b.stream(ctx, streamURL, bySymbol, ch)
+	})
+}
+
+// stream runs a single WebSocket session until it fails or ctx is done
+func (b *Binance) stream(ctx context.Context, streamURL string, bySymbol map[string]string, ch chan<- models.Quote) error {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	conn, _, err := dialer.DialContext(ctx, streamURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Close the connection when ctx is done to unblock ReadJSON
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		var message struct {
+			Data struct {
+				Symbol      string `json:"s"`
+				Last        string `json:"c"`
+				Open        string `json:"o"`
+				High        string `json:"h"`
+				Low         string `json:"l"`
+				Volume      string `json:"v"`
+				PrevClose   string `json:"x"`
+				Change      string `json:"p"`
+				ChangePct   string `json:"P"`
+				EventTimeMs int64  `json:"E"`
+			} `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			return err
+		}
+
+		d := message.Data
+		symbol, ok := bySymbol[d.Symbol]
+		if !ok {
+			continue
+		}
+		ticker := binanceTicker{
+			LastPrice:          d.Last,
+			OpenPrice:          d.Open,
+			HighPrice:          d.High,
+			LowPrice:           d.Low,
+			Volume:             d.Volume,
+			PrevClosePrice:     d.PrevClose,
+			PriceChange:        d.Change,
+			PriceChangePercent: d.ChangePct,
+			CloseTime:          d.EventTimeMs,
+		}
+
+		select {
+		case ch <- *ticker.quote(symbol):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// parseBinanceFloat parses the string-encoded numbers Binance returns
+func parseBinanceFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -0,0 +1,128 @@
+package market
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// candleRefreshInterval is how long stored daily candles are served without
+// asking the provider for newer ones while the market is open
+const candleRefreshInterval = 15 * time.Minute
+
+// candleDeltaPeriods are the daily presets used to fetch the candles added
+// since the last sync, smallest first; longer gaps refetch the whole period
+var candleDeltaPeriods = []struct {
+	period string
+	span   time.Duration
+}{
+	{"1m", 25 * 24 * time.Hour},
+	{"3m", 85 * 24 * time.Hour},
+	{"1y", 360 * 24 * time.Hour},
+}
+
+// CandleStore persists daily candles per provider and symbol
+type CandleStore interface {
+	SaveCandles(provider, symbol string, candles []models.Candle, sync models.CandleSync) error
+	GetCandles(provider, symbol string, from, to time.Time) ([]models.Candle, error)
+	GetCandleSync(provider, symbol string) (*models.CandleSync, error)
+}
+
+// candleStore is used by every provider returned from NewProvider; nil
+// disables stored history
+var candleStore CandleStore
+
+// SetCandleStore sets where daily candles are stored; call it before creating
+// any market providers
+func SetCandleStore(store CandleStore) {
+	candleStore = store
+}
+
+// storedHistoryProvider wraps a provider so daily history is read from the
+// candle store and only candles added since the last sync are fetched
+type storedHistoryProvider struct {
+	Provider
+	store CandleStore
+}
+
+// withCandleStore wraps p with the shared candle store, if one is set. The
+// demo provider generates its history and is never stored.
+func withCandleStore(p Provider) Provider {
+	if candleStore == nil || p.Name() == "demo" {
+		return p
+	}
+	return storedHistoryProvider{Provider: p, store: candleStore}
+}
+
+// GetHistoricalData serves daily periods from the candle store, fetching the
+// whole period when it reaches back past the stored range and a delta when
+// the stored candles may be out of date. Stored candles are still served
+// when a delta fetch fails.
+func (p storedHistoryProvider) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	now := time.Now()
+	parsed, err := ParsePeriod(period)
+	if err != nil || parsed.Granularity(now) != GranularityDaily {
+		return p.Provider.GetHistoricalData(ctx, symbol, period)
+	}
+	from, to := parsed.Bounds(now)
+
+	sync, err := p.store.GetCandleSync(p.Name(), symbol)
+	if err != nil {
+		log.Printf("[CANDLES] Failed to load sync state for %s: %v", symbol, err)
+		return p.Provider.GetHistoricalData(ctx, symbol, period)
+	}
+
+	if sync == nil || from.Before(sync.CoveredFrom) {
+		candles, err := p.Provider.GetHistoricalData(ctx, symbol, period)
+		if err != nil {
+			return nil, err
+		}
+		p.save(symbol, candles, from, now)
+		return candles, nil
+	}
+
+	if candlesOutdated(SymbolExchange(symbol), sync.SyncedAt, now) {
+		fetch := period
+		for _, d := range candleDeltaPeriods {
+			if now.Sub(sync.SyncedAt) <= d.span {
+				fetch = d.period
+				break
+			}
+		}
+		candles, err := p.Provider.GetHistoricalData(ctx, symbol, fetch)
+		if err != nil {
+			log.Printf("[CANDLES] Serving stored %s history, refresh failed: %v", symbol, err)
+		} else {
+			p.save(symbol, candles, sync.CoveredFrom, now)
+		}
+	}
+
+	candles, err := p.store.GetCandles(p.Name(), symbol, from, to)
+	if err != nil {
+		log.Printf("[CANDLES] Failed to load stored %s history: %v", symbol, err)
+		return p.Provider.GetHistoricalData(ctx, symbol, period)
+	}
+	return candles, nil
+}
+
+// save stores fetched candles, logging failures since the candles are still
+// returned to the caller
+func (p storedHistoryProvider) save(symbol string, candles []models.Candle, coveredFrom, now time.Time) {
+	sync := models.CandleSync{CoveredFrom: coveredFrom, SyncedAt: now}
+	if err := p.store.SaveCandles(p.Name(), symbol, candles, sync); err != nil {
+		log.Printf("[CANDLES] Failed to store %s history: %v", symbol, err)
+	}
+}
+
+// candlesOutdated reports whether candles synced at syncedAt may be missing
+// newer prices: during a session of exchange after candleRefreshInterval,
+// and otherwise once after the session closes and once per day
+func candlesOutdated(exchange string, syncedAt, now time.Time) bool {
+	if now.Sub(syncedAt) < candleRefreshInterval {
+		return false
+	}
+	return IsExchangeOpen(exchange, now) || IsExchangeOpen(exchange, syncedAt) ||
+		ExchangeDay(exchange, syncedAt) != ExchangeDay(exchange, now)
+}
@@ -0,0 +1,237 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// breakerFailureThreshold is the number of consecutive upstream failures that
+// opens a provider's circuit
+const breakerFailureThreshold = 5
+
+// breakerCooldown is how long an open circuit rejects calls before a single
+// probe is let through
+const breakerCooldown = 30 * time.Second
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen is matched by CircuitOpenError with errors.Is
+var ErrCircuitOpen = errors.New("provider circuit open")
+
+// CircuitOpenError is returned without calling the provider while its
+// circuit is open
+type CircuitOpenError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s is failing, retry after %s", e.Provider, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap lets errors.Is(err, ErrCircuitOpen) match
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// Rejected reports whether err was returned locally, by the rate limiter or
+// an open circuit, without the provider being called
+func Rejected(err error) bool {
+	var limited *RateLimitError
+	var open *CircuitOpenError
+	return errors.As(err, &limited) || errors.As(err, &open)
+}
+
+// CircuitBreakerStats reports the state of a provider's circuit breaker
+type CircuitBreakerStats struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Trips               uint64     `json:"trips"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// circuitBreaker opens after breakerFailureThreshold consecutive failures,
+// rejects calls for breakerCooldown, then lets one probe through: success
+// closes the circuit and failure opens it again
+type circuitBreaker struct {
+	mu        sync.Mutex
+	name      string
+	state     string
+	failures  int
+	trips     uint64
+	openedAt  time.Time
+	probing   bool
+	lastError string
+}
+
+// allow reports whether a call may go ahead, moving an open circuit whose
+// cooldown has passed to half-open for a single probe. probe is true for the
+// call let through as the probe and is passed back to record.
+func (b *circuitBreaker) allow(now time.Time) (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if wait := b.openedAt.Add(breakerCooldown).Sub(now); wait > 0 {
+			return false, &CircuitOpenError{Provider: b.name, RetryAfter: wait}
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true, nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, &CircuitOpenError{Provider: b.name, RetryAfter: time.Second}
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// check rejects calls while the circuit is open without changing its state;
+// it is used for calls whose outcome is not recorded
+func (b *circuitBreaker) check(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if wait := b.openedAt.Add(breakerCooldown).Sub(now); wait > 0 {
+			return &CircuitOpenError{Provider: b.name, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// record updates the circuit with the outcome of an allowed call. Only the
+// probe frees the half-open circuit for the next probe; late outcomes of calls
+// allowed while it was closed must not let a second probe through.
+func (b *circuitBreaker) record(ctx context.Context, probe bool, err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	switch {
+	case err == nil || errors.Is(err, ErrInvalidSymbol):
+		// The upstream answered
+		b.state = BreakerClosed
+		b.failures = 0
+	case ctx.Err() != nil || Rejected(err):
+		// Calls cut short by their caller or turned away locally prove
+		// nothing either way
+	default:
+		b.failures++
+		b.lastError = err.Error()
+		if b.state == BreakerHalfOpen || b.failures >= breakerFailureThreshold {
+			if b.state == BreakerClosed {
+				b.trips++
+			}
+			b.state = BreakerOpen
+			b.openedAt = now
+		}
+	}
+}
+
+func (b *circuitBreaker) stats() CircuitBreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := CircuitBreakerStats{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Trips:               b.trips,
+		LastError:           b.lastError,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
+
+// breakers holds one circuit breaker per provider, shared by all its instances
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*circuitBreaker{}
+)
+
+// breakerFor returns the shared circuit breaker of a provider
+func breakerFor(name string) *circuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	if b, ok := breakers[name]; ok {
+		return b
+	}
+	b := &circuitBreaker{name: name, state: BreakerClosed}
+	breakers[name] = b
+	return b
+}
+
+// GetCircuitBreakerStats returns the circuit breaker state of every provider
+// used since startup
+func GetCircuitBreakerStats() map[string]CircuitBreakerStats {
+	breakersMu.Lock()
+	list := make([]*circuitBreaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	breakersMu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	stats := make(map[string]CircuitBreakerStats, len(list))
+	for _, b := range list {
+		stats[b.name] = b.stats()
+	}
+	return stats
+}
+
+// breakerProvider wraps a provider so calls fail fast while its upstream is
+// down instead of every caller waiting on it
+type breakerProvider struct {
+	Provider
+	breaker *circuitBreaker
+}
+
+// WithCircuitBreaker wraps p with its provider's shared circuit breaker
+func WithCircuitBreaker(p Provider) Provider {
+	if _, ok := p.(breakerProvider); ok {
+		return p
+	}
+	return breakerProvider{Provider: p, breaker: breakerFor(p.Name())}
+}
+
+// GetQuote fetches a quote unless the circuit is open
+func (p breakerProvider) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	probe, err := p.breaker.allow(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	quote, err := p.Provider.GetQuote(ctx, symbol)
+	p.breaker.record(ctx, probe, err, time.Now())
+	return quote, err
+}
+
+// GetHistoricalData fetches candles unless the circuit is open
+func (p breakerProvider) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	probe, err := p.breaker.allow(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	candles, err := p.Provider.GetHistoricalData(ctx, symbol, period)
+	p.breaker.record(ctx, probe, err, time.Now())
+	return candles, err
+}
@@ -0,0 +1,116 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// tripBreaker fails breakerFailureThreshold calls, opening the circuit at now
+func tripBreaker(t *testing.T, b *circuitBreaker, now time.Time) {
+	t.Helper()
+	for range breakerFailureThreshold {
+		probe, err := b.allow(now)
+		if err != nil || probe {
+			t.Fatalf("closed circuit: probe %v, err %v", probe, err)
+		}
+		b.record(context.Background(), probe, errors.New("upstream down"), now)
+	}
+	if state := b.stats().State; state != BreakerOpen {
+		t.Fatalf("state after %d failures = %s, want open", breakerFailureThreshold, state)
+	}
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	ctx := context.Background()
+	b := &circuitBreaker{name: "breaker-test", state: BreakerClosed}
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	// Failures below the threshold keep the circuit closed
+	b.record(ctx, false, errors.New("upstream down"), now)
+	b.record(ctx, false, nil, now)
+	if stats := b.stats(); stats.State != BreakerClosed || stats.ConsecutiveFailures != 0 {
+		t.Fatalf("after a success: %+v", stats)
+	}
+
+	// A call allowed while the circuit is still closed
+	lateProbe, err := b.allow(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tripBreaker(t, b, now)
+	if trips := b.stats().Trips; trips != 1 {
+		t.Fatalf("trips = %d, want 1", trips)
+	}
+
+	// Open: calls are rejected until the cooldown has passed
+	if _, err := b.allow(now.Add(breakerCooldown / 2)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open circuit allowed a call: %v", err)
+	}
+
+	// Half-open: a single probe goes through
+	later := now.Add(breakerCooldown)
+	probe, err := b.allow(later)
+	if err != nil || !probe {
+		t.Fatalf("after the cooldown: probe %v, err %v, want the probe", probe, err)
+	}
+	if state := b.stats().State; state != BreakerHalfOpen {
+		t.Fatalf("state = %s, want half_open", state)
+	}
+	if _, err := b.allow(later); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second probe allowed: %v", err)
+	}
+
+	// The late outcome of the call allowed while closed, cut short by its
+	// caller, does not free the probe slot
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	b.record(cancelled, lateProbe, context.Canceled, later)
+	if _, err := b.allow(later); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second probe allowed after a late outcome: %v", err)
+	}
+
+	// A failed probe opens the circuit again
+	b.record(ctx, probe, errors.New("still down"), later)
+	if stats := b.stats(); stats.State != BreakerOpen || stats.Trips != 1 {
+		t.Fatalf("after a failed probe: %+v", stats)
+	}
+
+	// A successful probe closes it
+	again := later.Add(breakerCooldown)
+	probe, err = b.allow(again)
+	if err != nil || !probe {
+		t.Fatalf("after the second cooldown: probe %v, err %v", probe, err)
+	}
+	b.record(ctx, probe, nil, again)
+	if stats := b.stats(); stats.State != BreakerClosed || stats.ConsecutiveFailures != 0 {
+		t.Fatalf("after a successful probe: %+v", stats)
+	}
+	if probe, err := b.allow(again); err != nil || probe {
+		t.Fatalf("closed circuit: probe %v, err %v", probe, err)
+	}
+}
+
+func TestCircuitBreakerProbeCutShort(t *testing.T) {
+	b := &circuitBreaker{name: "breaker-cut-short", state: BreakerClosed}
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	tripBreaker(t, b, now)
+
+	later := now.Add(breakerCooldown)
+	probe, err := b.allow(later)
+	if err != nil || !probe {
+		t.Fatalf("probe %v, err %v, want the probe", probe, err)
+	}
+
+	// A probe cancelled by its caller proves nothing and frees the slot
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.record(ctx, probe, context.Canceled, later)
+	if state := b.stats().State; state != BreakerHalfOpen {
+		t.Fatalf("state = %s, want half_open", state)
+	}
+	if probe, err := b.allow(later); err != nil || !probe {
+		t.Fatalf("next probe %v, err %v, want the probe", probe, err)
+	}
+}
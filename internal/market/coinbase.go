@@ -0,0 +1,224 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+const coinbaseBaseURL = "https://api.exchange.coinbase.com"
+
+// coinbaseMaxCandles is the most candles the candles endpoint returns per request
+const coinbaseMaxCandles = 300
+
+// coinbaseQuoteCurrencies are the quote currencies recognized in pairs written
+// without a separator, e.g. "BTCUSD"
+var coinbaseQuoteCurrencies = []string{"USDC", "USDT", "USD"}
+
+// Coinbase implements the Provider interface for crypto products on the
+// Coinbase Exchange public API, which needs no API key. Products may be
+// written as BTC-USD, BTC/USD or BTCUSD; quotes keep the symbol as given.
+type Coinbase struct {
+	client *http.Client
+}
+
+// NewCoinbase creates a new Coinbase provider
+func NewCoinbase() *Coinbase {
+	return &Coinbase{client: sharedHTTPClient}
+}
+
+// Name returns the provider name
+func (cb *Coinbase) Name() string {
+	return "coinbase"
+}
+
+// coinbaseProduct normalizes a pair to a Coinbase product ID, e.g. "btcusd" to "BTC-USD"
+func coinbaseProduct(symbol string) string {
+	product := strings.NewReplacer("/", "-", "_", "-").Replace(strings.ToUpper(strings.TrimSpace(symbol)))
+	if strings.Contains(product, "-") {
+		return product
+	}
+	for _, quote := range coinbaseQuoteCurrencies {
+		if base, ok := strings.CutSuffix(product, quote); ok && base != "" {
+			return base + "-" + quote
+		}
+	}
+	return product
+}
+
+// GetQuote fetches the latest trade and 24 hour stats of a product
+func (cb *Coinbase) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	product := url.PathEscape(coinbaseProduct(symbol))
+
+	var ticker struct {
+		Price string    `json:"price"`
+		Time  time.Time `json:"time"`
+	}
+	if err := cb.get(ctx, "/products/"+product+"/ticker", nil, &ticker); err != nil {
+		return nil, err
+	}
+	var stats struct {
+		Open   string `json:"open"`
+		High   string `json:"high"`
+		Low    string `json:"low"`
+		Volume string `json:"volume"`
+	}
+	if err := cb.get(ctx, "/products/"+product+"/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+
+	price := parseCoinbaseFloat(ticker.Price)
+	if price == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Crypto trades around the clock: the 24 hour open stands in for the previous close
+	quote := &models.Quote{
+		Symbol:        symbol,
+		Price:         price,
+		Open:          parseCoinbaseFloat(stats.Open),
+		High:          parseCoinbaseFloat(stats.High),
+		Low:           parseCoinbaseFloat(stats.Low),
+		Volume:        int64(parseCoinbaseFloat(stats.Volume)),
+		PreviousClose: parseCoinbaseFloat(stats.Open),
+		Timestamp:     ticker.Time,
+		Session:       MarketStateOpen, // crypto trades around the clock
+	}
+	if quote.PreviousClose > 0 {
+		quote.Change = price - quote.PreviousClose
+		quote.ChangePercent = quote.Change / quote.PreviousClose * 100
+	}
+	return quote, nil
+}
+
+// GetHistoricalData fetches candles for a product, newest first. Coinbase only
+// offers fixed granularities up to one day, so long periods are paged in
+// windows of coinbaseMaxCandles daily candles.
+func (cb *Coinbase) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from, to := p.Bounds(now)
+
+	// Map period to granularities in seconds (60, 300, 900, 3600, 21600, 86400)
+	granularity := 86400
+	switch p.Name {
+	case "1d":
+		granularity = 300
+	case "5d":
+		granularity = 900
+	case "":
+		if p.Granularity(now) == GranularityIntraday {
+			granularity = 900
+		}
+	}
+	step := time.Duration(granularity) * time.Second
+
+	var candles []models.Candle
+	for end := to; end.After(from); end = end.Add(-coinbaseMaxCandles * step) {
+		start := end.Add(-coinbaseMaxCandles * step)
+		if start.Before(from) {
+			start = from
+		}
+		params := url.Values{
+			"granularity": {strconv.Itoa(granularity)},
+			"start":       {start.UTC().Format(time.RFC3339)},
+			"end":         {end.UTC().Format(time.RFC3339)},
+		}
+
+		// Each candle is [time, low, high, open, close, volume], newest first
+		var page [][]float64
+		if err := cb.get(ctx, "/products/"+url.PathEscape(coinbaseProduct(symbol))+"/candles", params, &page); err != nil {
+			return nil, err
+		}
+		// Pages before the product was listed are empty
+		if len(page) == 0 {
+			break
+		}
+		for _, c := range page {
+			if len(c) < 6 {
+				continue
+			}
+			candles = append(candles, models.Candle{
+				Timestamp: time.Unix(int64(c[0]), 0),
+				Low:       c[1],
+				High:      c[2],
+				Open:      c[3],
+				Close:     c[4],
+				Volume:    int64(c[5]),
+			})
+		}
+	}
+
+	if len(candles) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Sort by timestamp (newest first); page boundaries may repeat a candle
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Timestamp.After(candles[j].Timestamp)
+	})
+	deduped := candles[:1]
+	for _, c := range candles[1:] {
+		if !c.Timestamp.Equal(deduped[len(deduped)-1].Timestamp) {
+			deduped = append(deduped, c)
+		}
+	}
+
+	return deduped, nil
+}
+
+// get performs a GET request against the public API and decodes the JSON response
+func (cb *Coinbase) get(ctx context.Context, path string, params url.Values, out any) error {
+	u := coinbaseBaseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	// Coinbase rejects requests without a User-Agent
+	req.Header.Set("User-Agent", "stockmarket")
+
+	resp, err := cb.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusBadRequest:
+		return ErrInvalidSymbol
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return fmt.Errorf("%w: Coinbase returned status %d", ErrAPIError, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StreamQuotes streams quotes by polling the ticker endpoint
+func (cb *Coinbase) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return pollQuotes(ctx, cb, symbols, ch, 10*time.Second)
+}
+
+// parseCoinbaseFloat parses the string-encoded numbers Coinbase returns
+func parseCoinbaseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
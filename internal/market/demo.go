@@ -0,0 +1,392 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"stockmarket/internal/market/resample"
+	"stockmarket/internal/models"
+)
+
+// DefaultDemoSeed is the seed used when the demo provider is selected in settings
+const DefaultDemoSeed = 42
+
+// demoHistoryDays is the number of daily candles generated per symbol
+const demoHistoryDays = 5 * 252
+
+// Demo implements the Provider interface with deterministic synthetic data,
+// so the same seed always produces the same prices for a given day
+type Demo struct {
+	seed int64
+}
+
+// NewDemo creates a new demo provider
+func NewDemo(seed int64) *Demo {
+	return &Demo{seed: seed}
+}
+
+// Name returns the provider name
+func (d *Demo) Name() string {
+	return "demo"
+}
+
+// covers reports that the demo dataset has every symbol
+func (d *Demo) covers(symbol string) bool {
+	return true
+}
+
+// GetQuote returns the synthetic quote for the current trading day
+func (d *Demo) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	now := time.Now()
+	daily := d.dailyCandles(symbol, now)
+	last := daily[len(daily)-1]
+	prev := daily[len(daily)-2]
+
+	quote := &models.Quote{
+		Symbol:        symbol,
+		Price:         last.Close,
+		Open:          last.Open,
+		High:          last.High,
+		Low:           last.Low,
+		Volume:        last.Volume,
+		PreviousClose: prev.Close,
+		Change:        last.Close - prev.Close,
+		ChangePercent: (last.Close - prev.Close) / prev.Close * 100,
+		Timestamp:     now,
+	}
+	// Extended-hours trades drift from the close, changing every quarter hour
+	if IsExtendedSession(MarketState(now)) && demoPairRate(symbol) == 0 {
+		r := d.rng(symbol + now.Truncate(15*time.Minute).Format(time.RFC3339))
+		quote.ExtendedPrice = round2(last.Close * (1 + r.NormFloat64()*0.005))
+	}
+	return quote, nil
+}
+
+// GetHistoricalData returns synthetic candles for the given period
+func (d *Demo) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	daily := d.dailyCandles(symbol, now)
+
+	switch p.Name {
+	case "1d":
+		return d.intradayCandles(symbol, daily[len(daily)-1], 78, 5*time.Minute), nil
+	case "5d":
+		var candles []models.Candle
+		for _, day := range daily[len(daily)-5:] {
+			candles = append(candles, d.intradayCandles(symbol, day, 26, 15*time.Minute)...)
+		}
+		return candles, nil
+	case "3m":
+		return daily[len(daily)-63:], nil
+	case "1y":
+		return daily[len(daily)-252:], nil
+	case "5y", PeriodMax:
+		return daily, nil
+	case "1m":
+		return daily[len(daily)-21:], nil
+	default:
+		// Year to date and custom ranges; today's candle is stamped at the
+		// close, so keep it when the range runs up to now
+		from, to := p.Bounds(now)
+		if !to.Before(now) {
+			to = daily[len(daily)-1].Timestamp.Add(time.Second)
+		}
+		return filterCandles(daily, from, to), nil
+	}
+}
+
+// GetAdjustedHistoricalData returns the synthetic candles, which have no
+// splits or dividends to adjust for
+func (d *Demo) GetAdjustedHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	return d.GetHistoricalData(ctx, symbol, period)
+}
+
+// demoSessionLength is the length of the regular session split into
+// intraday candles
+const demoSessionLength = 390 * time.Minute
+
+// GetHistoricalDataInterval returns synthetic candles of the given interval
+// for period; intraday candles are kept for intradayHistoryLimit
+func (d *Demo) GetHistoricalDataInterval(ctx context.Context, symbol, period, interval string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	size := resample.Size(interval)
+	if size == 0 {
+		return nil, fmt.Errorf("%w: %q", resample.ErrInvalidInterval, interval)
+	}
+
+	now := time.Now()
+	daily := d.dailyCandles(symbol, now)
+	from, to := p.Bounds(now)
+	if !to.Before(now) {
+		to = daily[len(daily)-1].Timestamp.Add(time.Second)
+	}
+	days := filterCandles(daily, from, to)
+	if p.Name == "1d" || len(days) == 0 {
+		// One day, weekends and holidays show the last session
+		days = daily[len(daily)-1:]
+	}
+
+	switch interval {
+	case resample.Interval1d:
+		return days, nil
+	case resample.Interval1w:
+		return resample.Resample(days, interval, easternTime)
+	}
+	if now.Sub(from) > intradayHistoryLimit {
+		return nil, fmt.Errorf("%w: %s candles only go back %d days", ErrIntervalUnavailable, interval, int(intradayHistoryLimit.Hours()/24))
+	}
+	n := int(math.Ceil(float64(demoSessionLength) / float64(size)))
+	var candles []models.Candle
+	for _, day := range days {
+		candles = append(candles, d.intradayCandles(symbol, day, n, size)...)
+	}
+	return candles, nil
+}
+
+// demoUSDRates is the approximate US dollar value of one unit of each currency
+var demoUSDRates = map[string]float64{
+	"USD": 1,
+	"EUR": 1.08,
+	"GBP": 1.27,
+	"JPY": 0.0067,
+	"CAD": 0.73,
+	"AUD": 0.66,
+	"CHF": 1.12,
+	"HKD": 0.128,
+}
+
+// demoPairRate returns the reference rate of a currency pair symbol, or zero
+// when symbol is not a pair of known currencies
+func demoPairRate(symbol string) float64 {
+	base, quote, ok := ParseCurrencyPair(symbol)
+	if !ok || demoUSDRates[base] == 0 || demoUSDRates[quote] == 0 {
+		return 0
+	}
+	return demoUSDRates[base] / demoUSDRates[quote]
+}
+
+// demoIndexLevels is the approximate level of the indices in the demo dataset
+var demoIndexLevels = map[string]float64{
+	"^GSPC": 5800,
+	"^DJI":  42000,
+	"^IXIC": 18500,
+	"^NDX":  20500,
+	"^RUT":  2200,
+	"^VIX":  16,
+	"^FTSE": 8300,
+}
+
+// demoCompanies names the symbols used by the demo dataset
+var demoCompanies = map[string][2]string{
+	"AAPL":  {"Apple Inc.", "Technology"},
+	"MSFT":  {"Microsoft Corporation", "Technology"},
+	"GOOGL": {"Alphabet Inc.", "Communication Services"},
+	"AMZN":  {"Amazon.com, Inc.", "Consumer Cyclical"},
+	"NVDA":  {"NVIDIA Corporation", "Technology"},
+	"META":  {"Meta Platforms, Inc.", "Communication Services"},
+	"TSLA":  {"Tesla, Inc.", "Consumer Cyclical"},
+	"JPM":   {"JPMorgan Chase & Co.", "Financial Services"},
+	"V":     {"Visa Inc.", "Financial Services"},
+	"KO":    {"The Coca-Cola Company", "Consumer Defensive"},
+}
+
+// GetCompanyProfile returns a synthetic profile whose valuation follows the
+// generated price
+func (d *Demo) GetCompanyProfile(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	if name, ok := IndexNames[symbol]; ok {
+		// Indices have no valuation
+		return &models.CompanyProfile{Symbol: symbol, Name: name, Currency: SymbolCurrency(symbol), AssetType: "INDEX"}, nil
+	}
+	company, ok := demoCompanies[symbol]
+	if !ok {
+		company = [2]string{symbol + " Demo Corp.", "Technology"}
+	}
+
+	r := d.rng(symbol + ":profile")
+	daily := d.dailyCandles(symbol, time.Now())
+	shares := float64(500_000_000 + r.Int63n(15_000_000_000))
+
+	return &models.CompanyProfile{
+		Symbol:    symbol,
+		Name:      company[0],
+		Exchange:  "NASDAQ",
+		Currency:  SymbolCurrency(symbol),
+		Sector:    company[1],
+		AssetType: "EQUITY",
+		MarketCap: math.Round(daily[len(daily)-1].Close * shares),
+		PERatio:   round2(8 + r.Float64()*40),
+	}, nil
+}
+
+// GetPeers returns the other demo companies in the sector of symbol
+func (d *Demo) GetPeers(ctx context.Context, symbol string) ([]string, error) {
+	company, ok := demoCompanies[symbol]
+	if !ok {
+		company = [2]string{"", "Technology"}
+	}
+	var peers []string
+	for peer, other := range demoCompanies {
+		if other[1] == company[1] && peer != symbol {
+			peers = append(peers, peer)
+		}
+	}
+	sort.Strings(peers)
+	return peers, nil
+}
+
+// demoHeadlines are the templates of the synthetic news, %s being the company
+var demoHeadlines = []string{
+	"%s beats quarterly earnings estimates",
+	"Analysts raise price target on %s",
+	"%s announces share buyback program",
+	"%s shares slip as sector rotates",
+	"%s faces regulatory scrutiny over new product",
+	"%s to present at investor conference next week",
+}
+
+// GetCompanyNews returns a few synthetic headlines spread over the window
+func (d *Demo) GetCompanyNews(ctx context.Context, symbol string, from, to time.Time) ([]models.NewsItem, error) {
+	if _, ok := IndexNames[symbol]; ok {
+		return nil, nil
+	}
+	name := symbol
+	if company, ok := demoCompanies[symbol]; ok {
+		name = company[0]
+	}
+
+	// Seed on the day so the headlines stay put between analyses
+	r := d.rng(symbol + ":news:" + to.Format("2006-01-02"))
+	count := 2 + r.Intn(3)
+	span := to.Sub(from)
+	items := make([]models.NewsItem, 0, count)
+	for _, i := range r.Perm(len(demoHeadlines))[:count] {
+		items = append(items, models.NewsItem{
+			Headline:    fmt.Sprintf(demoHeadlines[i], name),
+			Source:      "Demo Wire",
+			PublishedAt: to.Add(-time.Duration(r.Int63n(int64(span)))),
+		})
+	}
+	return items, nil
+}
+
+// StreamQuotes emits synthetic quotes for the symbols periodically
+func (d *Demo) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return pollQuotes(ctx, d, symbols, ch, 10*time.Second)
+}
+
+// rng returns a random source seeded from the provider seed and the given key
+func (d *Demo) rng(key string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return rand.New(rand.NewSource(d.seed ^ int64(h.Sum64())))
+}
+
+// dailyCandles generates a random walk of weekday candles ending on the day of now
+func (d *Demo) dailyCandles(symbol string, now time.Time) []models.Candle {
+	end := time.Date(now.Year(), now.Month(), now.Day(), 16, 0, 0, 0, easternTime)
+	for end.Weekday() == time.Saturday || end.Weekday() == time.Sunday {
+		end = end.AddDate(0, 0, -1)
+	}
+
+	// The walk starts at a fixed date so every day extends the same series
+	r := d.rng(symbol)
+	price := 20 + r.Float64()*480
+	drift := (r.Float64() - 0.4) * 0.002
+	volatility := 0.01 + r.Float64()*0.02
+	baseVolume := 1_000_000 + r.Int63n(50_000_000)
+
+	day := time.Date(2000, 1, 3, 16, 0, 0, 0, easternTime)
+	var candles []models.Candle
+	for !day.After(end) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			open := price * (1 + r.NormFloat64()*volatility/4)
+			price = math.Max(1, price*(1+drift+r.NormFloat64()*volatility))
+			high := math.Max(open, price) * (1 + r.Float64()*volatility/2)
+			low := math.Min(open, price) * (1 - r.Float64()*volatility/2)
+			candles = append(candles, models.Candle{
+				Timestamp: day,
+				Open:      round2(open),
+				High:      round2(high),
+				Low:       round2(low),
+				Close:     round2(price),
+				Volume:    baseVolume/2 + r.Int63n(baseVolume),
+			})
+			if len(candles) > demoHistoryDays {
+				candles = candles[1:]
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	// Currency pairs follow the same walk, scaled to end at a realistic rate
+	if rate := demoPairRate(symbol); rate > 0 {
+		scale := rate / candles[len(candles)-1].Close
+		for i := range candles {
+			c := &candles[i]
+			c.Open, c.High, c.Low, c.Close = round4(c.Open*scale), round4(c.High*scale), round4(c.Low*scale), round4(c.Close*scale)
+		}
+	}
+	// Indices likewise end near their usual level
+	if level, ok := demoIndexLevels[symbol]; ok {
+		scale := level / candles[len(candles)-1].Close
+		for i := range candles {
+			c := &candles[i]
+			c.Open, c.High, c.Low, c.Close = round2(c.Open*scale), round2(c.High*scale), round2(c.Low*scale), round2(c.Close*scale)
+		}
+	}
+	return candles
+}
+
+// intradayCandles splits a daily candle into n bars that move from its open to its close
+func (d *Demo) intradayCandles(symbol string, day models.Candle, n int, interval time.Duration) []models.Candle {
+	r := d.rng(symbol + day.Timestamp.Format("2006-01-02"))
+	start := time.Date(day.Timestamp.Year(), day.Timestamp.Month(), day.Timestamp.Day(), 9, 30, 0, 0, easternTime)
+	spread := day.High - day.Low
+	round := round2
+	if demoPairRate(symbol) > 0 {
+		round = round4
+	}
+
+	candles := make([]models.Candle, n)
+	prev := day.Open
+	for i := range candles {
+		target := day.Open + (day.Close-day.Open)*float64(i+1)/float64(n)
+		close := target
+		if i < n-1 {
+			close = math.Min(day.High, math.Max(day.Low, target+r.NormFloat64()*spread/6))
+		}
+		candles[i] = models.Candle{
+			Timestamp: start.Add(time.Duration(i) * interval),
+			Open:      round(prev),
+			High:      round(math.Max(prev, close)),
+			Low:       round(math.Min(prev, close)),
+			Close:     round(close),
+			Volume:    day.Volume / int64(n),
+		}
+		prev = close
+	}
+	return candles
+}
+
+// round2 rounds a price to cents
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// round4 rounds an exchange rate to four decimal places
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
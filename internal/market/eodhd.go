@@ -0,0 +1,290 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+const eodhdBaseURL = "https://eodhd.com/api"
+
+// EODHD implements the Provider and SymbolLister interfaces for EOD
+// Historical Data, which covers many non-US exchanges. Symbols carry an
+// exchange suffix such as "VOD.LSE" or "SAP.XETRA"; bare tickers are US listings.
+type EODHD struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewEODHD creates a new EODHD provider
+func NewEODHD(apiKey string) *EODHD {
+	return &EODHD{
+		apiKey: apiKey,
+		client: sharedHTTPClient,
+	}
+}
+
+// Name returns the provider name
+func (e *EODHD) Name() string {
+	return "eodhd"
+}
+
+// eodhdExchanges maps Yahoo listing suffixes to EODHD exchange codes
+var eodhdExchanges = map[string]string{
+	"L": "LSE", "DE": "XETRA", "F": "F",
+	"PA": "PA", "AS": "AS", "BR": "BR", "MI": "MI", "MC": "MC", "SW": "SW",
+	"T": "TSE", "HK": "HK", "TO": "TO", "V": "V", "AX": "AU",
+}
+
+// covers reports that EODHD has data for every symbol, indices and
+// international listings included
+func (e *EODHD) covers(symbol string) bool {
+	return true
+}
+
+// eodhdSymbol maps a ticker to EODHD's TICKER.EXCHANGE form. Yahoo listing
+// suffixes are translated ("SAP.DE" to "SAP.XETRA"), another one-letter
+// suffix is a share class ("BRK.B" to "BRK-B.US"), a longer one an exchange,
+// and indices are listed on INDX ("^GSPC" to "GSPC.INDX").
+func eodhdSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if IsIndexSymbol(symbol) {
+		return strings.TrimPrefix(symbol, "^") + ".INDX"
+	}
+	if base, quote, ok := ParseCurrencyPair(symbol); ok {
+		return base + quote + ".FOREX"
+	}
+	if ticker, suffix := splitListing(symbol); suffix != "" {
+		return ticker + "." + eodhdExchanges[suffix]
+	}
+	symbol = NormalizeSymbol(symbol)
+	if i := strings.LastIndex(symbol, "."); i >= 0 && len(symbol)-i-1 >= 2 {
+		return symbol
+	}
+	return strings.ReplaceAll(symbol, ".", "-") + ".US"
+}
+
+// eodhdNumber decodes numbers that EODHD reports as "NA" when unavailable
+type eodhdNumber float64
+
+// UnmarshalJSON accepts numbers, numeric strings and "NA"
+func (n *eodhdNumber) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		f = 0
+	}
+	*n = eodhdNumber(f)
+	return nil
+}
+
+// GetQuote fetches the delayed real-time quote for a symbol
+func (e *EODHD) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	var result struct {
+		Timestamp     eodhdNumber `json:"timestamp"`
+		Open          eodhdNumber `json:"open"`
+		High          eodhdNumber `json:"high"`
+		Low           eodhdNumber `json:"low"`
+		Close         eodhdNumber `json:"close"`
+		Volume        eodhdNumber `json:"volume"`
+		PreviousClose eodhdNumber `json:"previousClose"`
+		Change        eodhdNumber `json:"change"`
+		ChangeP       eodhdNumber `json:"change_p"`
+	}
+	if err := e.get(ctx, "/real-time/"+url.PathEscape(eodhdSymbol(symbol)), nil, &result); err != nil {
+		return nil, err
+	}
+	if result.Close == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	return &models.Quote{
+		Symbol:        symbol,
+		Price:         float64(result.Close),
+		Open:          float64(result.Open),
+		High:          float64(result.High),
+		Low:           float64(result.Low),
+		Volume:        int64(result.Volume),
+		PreviousClose: float64(result.PreviousClose),
+		Change:        float64(result.Change),
+		ChangePercent: float64(result.ChangeP),
+		Timestamp:     time.Unix(int64(result.Timestamp), 0),
+	}, nil
+}
+
+// GetHistoricalData fetches intraday or end-of-day candles, newest first
+func (e *EODHD) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from, to := p.Bounds(now)
+	code := url.PathEscape(eodhdSymbol(symbol))
+
+	// Intraday offers 1m, 5m and 1h bars; end of day offers d, w and m
+	intraday, eod := "", "d"
+	switch p.Name {
+	case "1d", "5d":
+		intraday = "5m"
+	case "5y":
+		eod = "w"
+	case PeriodMax:
+		eod = "m"
+	case "":
+		switch p.Granularity(now) {
+		case GranularityIntraday:
+			intraday = "5m"
+		case GranularityWeekly:
+			eod = "w"
+		}
+	}
+
+	var candles []models.Candle
+	if intraday != "" {
+		params := url.Values{
+			"interval": {intraday},
+			"from":     {strconv.FormatInt(from.Unix(), 10)},
+			"to":       {strconv.FormatInt(to.Unix(), 10)},
+		}
+		var result []struct {
+			Timestamp int64       `json:"timestamp"`
+			Open      eodhdNumber `json:"open"`
+			High      eodhdNumber `json:"high"`
+			Low       eodhdNumber `json:"low"`
+			Close     eodhdNumber `json:"close"`
+			Volume    eodhdNumber `json:"volume"`
+		}
+		if err := e.get(ctx, "/intraday/"+code, params, &result); err != nil {
+			return nil, err
+		}
+		for _, r := range result {
+			candles = append(candles, models.Candle{
+				Timestamp: time.Unix(r.Timestamp, 0),
+				Open:      float64(r.Open),
+				High:      float64(r.High),
+				Low:       float64(r.Low),
+				Close:     float64(r.Close),
+				Volume:    int64(r.Volume),
+			})
+		}
+	} else {
+		params := url.Values{
+			"period": {eod},
+			"from":   {from.Format("2006-01-02")},
+			"to":     {to.Format("2006-01-02")},
+		}
+		var result []struct {
+			Date   string      `json:"date"`
+			Open   eodhdNumber `json:"open"`
+			High   eodhdNumber `json:"high"`
+			Low    eodhdNumber `json:"low"`
+			Close  eodhdNumber `json:"close"`
+			Volume eodhdNumber `json:"volume"`
+		}
+		if err := e.get(ctx, "/eod/"+code, params, &result); err != nil {
+			return nil, err
+		}
+		for _, r := range result {
+			date, err := time.ParseInLocation("2006-01-02", r.Date, easternTime)
+			if err != nil {
+				continue
+			}
+			candles = append(candles, models.Candle{
+				Timestamp: date,
+				Open:      float64(r.Open),
+				High:      float64(r.High),
+				Low:       float64(r.Low),
+				Close:     float64(r.Close),
+				Volume:    int64(r.Volume),
+			})
+		}
+	}
+
+	if len(candles) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Timestamp.After(candles[j].Timestamp)
+	})
+
+	return candles, nil
+}
+
+// ListSymbols lists the tickers traded on an exchange, e.g. "LSE" or "XETRA"
+func (e *EODHD) ListSymbols(ctx context.Context, exchange string) ([]models.ListedSymbol, error) {
+	var result []struct {
+		Code     string `json:"Code"`
+		Name     string `json:"Name"`
+		Country  string `json:"Country"`
+		Exchange string `json:"Exchange"`
+		Currency string `json:"Currency"`
+		Type     string `json:"Type"`
+	}
+	exchange = strings.ToUpper(strings.TrimSpace(exchange))
+	if err := e.get(ctx, "/exchange-symbol-list/"+url.PathEscape(exchange), nil, &result); err != nil {
+		return nil, err
+	}
+
+	symbols := make([]models.ListedSymbol, 0, len(result))
+	for _, r := range result {
+		symbols = append(symbols, models.ListedSymbol{
+			Symbol:   NormalizeSymbol(r.Code + "." + exchange), // in Yahoo's form, e.g. VOD.L
+			Name:     r.Name,
+			Exchange: r.Exchange,
+			Country:  r.Country,
+			Currency: r.Currency,
+			Type:     r.Type,
+		})
+	}
+	return symbols, nil
+}
+
+// get performs a GET request and decodes the JSON response
+func (e *EODHD) get(ctx context.Context, path string, params url.Values, out any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("api_token", e.apiKey)
+	params.Set("fmt", "json")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", eodhdBaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrInvalidSymbol
+	case http.StatusTooManyRequests, http.StatusPaymentRequired:
+		// 402 means the daily API call allowance is used up
+		return ErrRateLimited
+	default:
+		return fmt.Errorf("%w: EODHD returned status %d", ErrAPIError, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StreamQuotes streams delayed quotes by polling
+func (e *EODHD) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return pollQuotes(ctx, e, symbols, ch, 15*time.Second)
+}
@@ -0,0 +1,184 @@
+package market
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/scmhub/calendar"
+)
+
+// Exchanges whose trading hours are known
+const (
+	ExchangeNYSE     = "NYSE"
+	ExchangeNASDAQ   = "NASDAQ"
+	ExchangeLSE      = "LSE"
+	ExchangeXETRA    = "XETRA"
+	ExchangeEuronext = "EURONEXT" // Paris, Amsterdam, Brussels and Milan
+	ExchangeBME      = "BME"      // Madrid
+	ExchangeSIX      = "SIX"      // Zurich
+	ExchangeTSE      = "TSE"      // Tokyo
+	ExchangeHKEX     = "HKEX"
+	ExchangeTSX      = "TSX" // Toronto
+	ExchangeASX      = "ASX"
+	ExchangeCrypto   = "CRYPTO" // trades around the clock
+)
+
+// Exchanges lists the exchanges a watchlist symbol can be assigned to
+var Exchanges = []string{
+	ExchangeNYSE, ExchangeNASDAQ, ExchangeLSE, ExchangeXETRA, ExchangeEuronext, ExchangeBME,
+	ExchangeSIX, ExchangeTSE, ExchangeHKEX, ExchangeTSX, ExchangeASX, ExchangeCrypto,
+}
+
+// exchangeCalendars holds the trading calendar of each exchange with fixed
+// hours (immutable, safe to share)
+var exchangeCalendars = map[string]*calendar.Calendar{
+	ExchangeNYSE:     nyseCalendar,
+	ExchangeNASDAQ:   calendar.XNAS(),
+	ExchangeLSE:      calendar.XLON(),
+	ExchangeXETRA:    calendar.XETR(),
+	ExchangeEuronext: calendar.XPAR(),
+	ExchangeBME:      calendar.XMAD(),
+	ExchangeSIX:      calendar.XSWX(),
+	ExchangeTSE:      calendar.XJPX(),
+	ExchangeHKEX:     calendar.XHKG(),
+	ExchangeTSX:      calendar.XTSE(),
+	ExchangeASX:      calendar.XASX(),
+}
+
+// listingExchanges maps the Yahoo Finance suffixes of international listings,
+// as in SAP.DE or 7203.T, to their exchange
+var listingExchanges = map[string]string{
+	"L":  ExchangeLSE,
+	"DE": ExchangeXETRA, "F": ExchangeXETRA,
+	"PA": ExchangeEuronext, "AS": ExchangeEuronext, "BR": ExchangeEuronext, "MI": ExchangeEuronext,
+	"MC": ExchangeBME,
+	"SW": ExchangeSIX,
+	"T":  ExchangeTSE,
+	"HK": ExchangeHKEX,
+	"TO": ExchangeTSX, "V": ExchangeTSX,
+	"AX": ExchangeASX,
+}
+
+// suffixAliases maps the exchange suffixes used by EODHD and Stooq to Yahoo
+// Finance's; US listings need no suffix
+var suffixAliases = map[string]string{
+	"LSE":   "L",
+	"UK":    "L",
+	"XETRA": "DE",
+	"TSE":   "T",
+	"JP":    "T",
+	"AU":    "AX",
+	"US":    "",
+}
+
+// NormalizeSymbol uppercases a symbol and rewrites EODHD and Stooq exchange
+// suffixes in Yahoo Finance's form ("vod.lse" to "VOD.L", "AAPL.US" to
+// "AAPL"), which every provider maps from
+func NormalizeSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if IsIndexSymbol(symbol) {
+		return symbol
+	}
+	if i := strings.LastIndex(symbol, "."); i > 0 {
+		if alias, ok := suffixAliases[symbol[i+1:]]; ok {
+			if alias == "" {
+				return symbol[:i]
+			}
+			return symbol[:i+1] + alias
+		}
+	}
+	return symbol
+}
+
+// ListingSuffix returns the Yahoo Finance exchange suffix of an international
+// listing such as SAP.DE, or "" for US listings, indices and pairs
+func ListingSuffix(symbol string) string {
+	_, suffix := splitListing(symbol)
+	return suffix
+}
+
+// splitListing splits an international listing into its ticker and Yahoo
+// Finance suffix; other symbols are returned normalized with no suffix
+func splitListing(symbol string) (ticker, suffix string) {
+	symbol = NormalizeSymbol(symbol)
+	if i := strings.LastIndex(symbol, "."); i > 0 {
+		if _, ok := listingExchanges[symbol[i+1:]]; ok {
+			return symbol[:i], symbol[i+1:]
+		}
+	}
+	return symbol, ""
+}
+
+// cryptoQuotes are the quote assets of crypto pairs such as BTCUSDT
+var cryptoQuotes = []string{"USDT", "USDC", "BUSD"}
+
+// IsSupportedExchange reports whether exchange is one of Exchanges
+func IsSupportedExchange(exchange string) bool {
+	return slices.Contains(Exchanges, exchange)
+}
+
+// SymbolExchange infers the exchange a symbol trades on: crypto pairs trade
+// around the clock, international listings and indices on the exchange of
+// their suffix and everything else on the NYSE
+func SymbolExchange(symbol string) string {
+	symbol = NormalizeSymbol(symbol)
+	if IsIndexSymbol(symbol) {
+		if index, ok := foreignIndices[symbol]; ok {
+			return index.exchange
+		}
+		return ExchangeNYSE
+	}
+	if suffix := ListingSuffix(symbol); suffix != "" {
+		return listingExchanges[suffix]
+	}
+	if i := strings.LastIndex(symbol, "-"); i > 0 && IsSupportedCurrency(symbol[i+1:]) {
+		return ExchangeCrypto // e.g. BTC-USD
+	}
+	for _, quote := range cryptoQuotes {
+		if len(symbol) > len(quote) && strings.HasSuffix(symbol, quote) {
+			return ExchangeCrypto
+		}
+	}
+	return ExchangeNYSE
+}
+
+// ResolveExchange returns the exchange configured for symbol in overrides,
+// or the one inferred from the symbol
+func ResolveExchange(overrides map[string]string, symbol string) string {
+	if exchange, ok := overrides[NormalizeSymbol(symbol)]; ok && IsSupportedExchange(exchange) {
+		return exchange
+	}
+	return SymbolExchange(symbol)
+}
+
+// ExchangeDay returns the date of t in the time zone of exchange as
+// YYYY-MM-DD; crypto markets use UTC and unknown exchanges New York time
+func ExchangeDay(exchange string, t time.Time) string {
+	loc := easternTime
+	if exchange == ExchangeCrypto {
+		loc = time.UTC
+	} else if cal, ok := exchangeCalendars[exchange]; ok {
+		loc = cal.Loc
+	}
+	return t.In(loc).Format("2006-01-02")
+}
+
+// IsExchangeOpen reports whether exchange is in its regular session at t;
+// unknown exchanges follow the NYSE
+func IsExchangeOpen(exchange string, t time.Time) bool {
+	return ExchangeState(exchange, t) == MarketStateOpen
+}
+
+// ExchangeState reports the session of exchange at t, as MarketState does
+// for the NYSE; crypto markets are always open
+func ExchangeState(exchange string, t time.Time) string {
+	if exchange == ExchangeCrypto {
+		return MarketStateOpen
+	}
+	cal, ok := exchangeCalendars[exchange]
+	if !ok {
+		cal = nyseCalendar
+	}
+	return sessionState(cal, t)
+}
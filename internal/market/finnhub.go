@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"stockmarket/internal/models"
@@ -31,6 +32,12 @@ func (f *Finnhub) Name() string {
 	return "finnhub"
 }
 
+// covers reports whether Finnhub has data for symbol: it takes international
+// listings with Yahoo's suffixes but has no index quotes
+func (f *Finnhub) covers(symbol string) bool {
+	return !IsIndexSymbol(symbol)
+}
+
 // GetQuote fetches the current quote for a symbol
 func (f *Finnhub) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
 	url := fmt.Sprintf("%s/quote?symbol=%s&token=%s", finnhubBaseURL, symbol, f.apiKey)
@@ -88,12 +95,17 @@ func (f *Finnhub) GetQuote(ctx context.Context, symbol string) (*models.Quote, e
 
 // GetHistoricalData fetches historical OHLCV data
 func (f *Finnhub) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
 	// Calculate time range based on period
 	resolution := "D"
 	var from, to time.Time
 	to = time.Now()
 
-	switch period {
+	switch p.Name {
 	case "1d":
 		resolution = "5"
 		from = to.AddDate(0, 0, -1)
@@ -112,9 +124,21 @@ func (f *Finnhub) GetHistoricalData(ctx context.Context, symbol string, period s
 	case "5y":
 		resolution = "W"
 		from = to.AddDate(-5, 0, 0)
-	default:
+	case PeriodYTD:
 		resolution = "D"
-		from = to.AddDate(0, -1, 0)
+		from, to = p.Bounds(to)
+	case PeriodMax:
+		resolution = "M"
+		from, to = p.Bounds(to)
+	default:
+		now := to
+		from, to = p.Bounds(now)
+		switch p.Granularity(now) {
+		case GranularityIntraday:
+			resolution = "15"
+		case GranularityWeekly:
+			resolution = "W"
+		}
 	}
 
 	url := fmt.Sprintf("%s/stock/candle?symbol=%s&resolution=%s&from=%d&to=%d&token=%s",
@@ -173,27 +197,154 @@ func (f *Finnhub) GetHistoricalData(ctx context.Context, symbol string, period s
 	return candles, nil
 }
 
-// StreamQuotes streams real-time quotes via polling
-func (f *Finnhub) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
-	ticker := time.NewTicker(5 * time.Second) // Finnhub has better rate limits
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			for _, symbol := range symbols {
-				quote, err := f.GetQuote(ctx, symbol)
-				if err != nil {
-					continue
-				}
-				select {
-				case ch <- *quote:
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
+// GetCompanyProfile combines the company profile with the trailing P/E from
+// the basic financials
+func (f *Finnhub) GetCompanyProfile(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	var profile struct {
+		Name                 string  `json:"name"`
+		Exchange             string  `json:"exchange"`
+		Currency             string  `json:"currency"`
+		Industry             string  `json:"finnhubIndustry"`
+		MarketCapitalization float64 `json:"marketCapitalization"` // in millions
+	}
+	if err := f.get(ctx, fmt.Sprintf("%s/stock/profile2?symbol=%s&token=%s", finnhubBaseURL, symbol, f.apiKey), &profile); err != nil {
+		return nil, err
+	}
+	// Unknown symbols return an empty object
+	if profile.Name == "" {
+		return nil, ErrInvalidSymbol
+	}
+
+	var financials struct {
+		Metric struct {
+			PE float64 `json:"peTTM"`
+		} `json:"metric"`
+	}
+	// Valuation is optional, so a failed lookup still returns the profile
+	f.get(ctx, fmt.Sprintf("%s/stock/metric?symbol=%s&metric=all&token=%s", finnhubBaseURL, symbol, f.apiKey), &financials)
+
+	return &models.CompanyProfile{
+		Symbol:    symbol,
+		Name:      profile.Name,
+		Exchange:  profile.Exchange,
+		Currency:  profile.Currency,
+		Industry:  profile.Industry,
+		MarketCap: profile.MarketCapitalization * 1e6,
+		PERatio:   financials.Metric.PE,
+	}, nil
+}
+
+// GetPeers lists the companies in the same industry via the /stock/peers endpoint
+func (f *Finnhub) GetPeers(ctx context.Context, symbol string) ([]string, error) {
+	var peers []string
+	if err := f.get(ctx, fmt.Sprintf("%s/stock/peers?symbol=%s&token=%s", finnhubBaseURL, symbol, f.apiKey), &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// GetCompanyNews lists the company news published between from and to via
+// the /company-news endpoint
+func (f *Finnhub) GetCompanyNews(ctx context.Context, symbol string, from, to time.Time) ([]models.NewsItem, error) {
+	var news []struct {
+		Headline string `json:"headline"`
+		Source   string `json:"source"`
+		Summary  string `json:"summary"`
+		URL      string `json:"url"`
+		Datetime int64  `json:"datetime"`
+	}
+	url := fmt.Sprintf("%s/company-news?symbol=%s&from=%s&to=%s&token=%s", finnhubBaseURL, symbol,
+		from.Format("2006-01-02"), to.Format("2006-01-02"), f.apiKey)
+	if err := f.get(ctx, url, &news); err != nil {
+		return nil, err
+	}
+
+	items := make([]models.NewsItem, 0, len(news))
+	for _, n := range news {
+		if n.Headline == "" {
+			continue
 		}
+		items = append(items, models.NewsItem{
+			Headline:    n.Headline,
+			Source:      n.Source,
+			Summary:     n.Summary,
+			URL:         n.URL,
+			PublishedAt: time.Unix(n.Datetime, 0),
+		})
+	}
+	return items, nil
+}
+
+// get performs a GET request and decodes the JSON response
+func (f *Finnhub) get(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return ErrRateLimited
+	}
+	if resp.StatusCode != 200 {
+		return ErrAPIError
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// SearchSymbols finds symbols via the /search endpoint
+func (f *Finnhub) SearchSymbols(ctx context.Context, query string) ([]models.SymbolMatch, error) {
+	url := fmt.Sprintf("%s/search?q=%s&token=%s", finnhubBaseURL, url.QueryEscape(query), f.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return nil, ErrRateLimited
+	}
+	if resp.StatusCode != 200 {
+		return nil, ErrAPIError
+	}
+
+	var result struct {
+		Result []struct {
+			Description   string `json:"description"`
+			DisplaySymbol string `json:"displaySymbol"`
+			Symbol        string `json:"symbol"`
+			Type          string `json:"type"`
+		} `json:"result"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	// Finnhub search results do not name the exchange
+	matches := make([]models.SymbolMatch, 0, len(result.Result))
+	for _, r := range result.Result {
+		matches = append(matches, models.SymbolMatch{
+			Symbol: r.Symbol,
+			Name:   r.Description,
+			Type:   r.Type,
+		})
+	}
+	return matches, nil
+}
+
+// StreamQuotes streams real-time quotes via polling
+func (f *Finnhub) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return pollQuotes(ctx, f, symbols, ch, 5*time.Second) // Finnhub has better rate limits
 }
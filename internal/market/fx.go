@@ -0,0 +1,164 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// DefaultCurrency is assumed for quotes whose provider reports no currency
+const DefaultCurrency = "USD"
+
+// Currencies lists the display currencies users can choose from
+var Currencies = []string{"USD", "EUR", "GBP", "JPY", "CAD", "AUD", "CHF", "HKD"}
+
+// currencyPairPattern matches currency pair symbols such as EURUSD=X
+var currencyPairPattern = regexp.MustCompile(`^([A-Z]{3})([A-Z]{3})=X$`)
+
+// ParseCurrencyPair splits a currency pair symbol such as EURUSD=X into its
+// base and quote currencies
+func ParseCurrencyPair(symbol string) (base, quote string, ok bool) {
+	m := currencyPairPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(symbol)))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// CurrencyPairSymbol returns the symbol quoting one unit of base in quote
+func CurrencyPairSymbol(base, quote string) string {
+	return strings.ToUpper(base) + strings.ToUpper(quote) + "=X"
+}
+
+// minorUnits maps currencies quoted in hundredths, such as London listings in
+// pence, to their major currency
+var minorUnits = map[string]string{
+	"GBp": "GBP",
+	"GBX": "GBP",
+	"ZAc": "ZAR",
+	"ILA": "ILS",
+}
+
+// exchangeCurrencies maps the exchange suffixes used by Yahoo Finance, EODHD
+// and Stooq to the currency their listings trade in
+var exchangeCurrencies = map[string]string{
+	"US": "USD",
+	"L":  "GBp", "LSE": "GBX", "UK": "GBp",
+	"DE": "EUR", "F": "EUR", "XETRA": "EUR", "PA": "EUR", "AS": "EUR", "MI": "EUR", "MC": "EUR", "BR": "EUR",
+	"TO": "CAD", "V": "CAD",
+	"T": "JPY", "JP": "JPY",
+	"HK": "HKD",
+	"AX": "AUD", "AU": "AUD",
+	"SW": "CHF",
+}
+
+// SymbolCurrency infers the currency a symbol is quoted in from its pair,
+// crypto quote currency, exchange suffix or index, defaulting to USD
+func SymbolCurrency(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if index, ok := foreignIndices[symbol]; ok {
+		return index.currency
+	}
+	if _, quote, ok := ParseCurrencyPair(symbol); ok {
+		return quote
+	}
+	if i := strings.LastIndex(symbol, "-"); i >= 0 && IsSupportedCurrency(symbol[i+1:]) {
+		return symbol[i+1:] // crypto pairs, e.g. BTC-EUR
+	}
+	if i := strings.LastIndex(symbol, "."); i >= 0 {
+		if currency, ok := exchangeCurrencies[symbol[i+1:]]; ok {
+			return currency
+		}
+	}
+	return DefaultCurrency
+}
+
+// IsSupportedCurrency reports whether code is one of the display currencies
+func IsSupportedCurrency(code string) bool {
+	return slices.Contains(Currencies, code)
+}
+
+// annotateCurrency fills in the quote currency when the provider did not
+// report one
+func annotateCurrency(quote *models.Quote) {
+	if quote.Currency == "" {
+		quote.Currency = SymbolCurrency(quote.Symbol)
+	}
+}
+
+// fxProviders lists the providers that quote currency pairs; rates for the
+// others come from Yahoo Finance
+var fxProviders = map[string]bool{
+	"yahoo":        true,
+	"alphavantage": true,
+	"twelvedata":   true,
+	"stooq":        true,
+	"eodhd":        true,
+	"demo":         true,
+}
+
+// ExchangeRate returns how many units of to one unit of from is worth, using
+// the currency pair quote of p or of Yahoo Finance when p has no FX data.
+// Minor units such as GBp are converted to their major currency.
+func ExchangeRate(ctx context.Context, p Provider, from, to string) (float64, error) {
+	scale := 1.0
+	if major, ok := minorUnits[from]; ok {
+		from, scale = major, scale/100
+	}
+	if major, ok := minorUnits[to]; ok {
+		to, scale = major, scale*100
+	}
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return scale, nil
+	}
+
+	if !fxProviders[p.Name()] {
+		p = wrap(NewYahooFinance())
+	}
+	quote, err := p.GetQuote(ctx, CurrencyPairSymbol(from, to))
+	if err != nil {
+		return 0, fmt.Errorf("%s/%s rate: %w", from, to, err)
+	}
+	if quote.Price <= 0 {
+		return 0, fmt.Errorf("%s/%s rate: %w", from, to, ErrInvalidSymbol)
+	}
+	return quote.Price * scale, nil
+}
+
+// Convert converts amount from one currency to another
+func Convert(ctx context.Context, p Provider, amount float64, from, to string) (float64, error) {
+	rate, err := ExchangeRate(ctx, p, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// ConvertQuote returns a copy of quote with its prices converted to currency;
+// percentages and volume are unchanged
+func ConvertQuote(ctx context.Context, p Provider, quote *models.Quote, currency string) (*models.Quote, error) {
+	from := quote.Currency
+	if from == "" {
+		from = SymbolCurrency(quote.Symbol)
+	}
+	rate, err := ExchangeRate(ctx, p, from, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := *quote
+	converted.Currency = currency
+	converted.Price *= rate
+	converted.Open *= rate
+	converted.High *= rate
+	converted.Low *= rate
+	converted.PreviousClose *= rate
+	converted.Change *= rate
+	converted.ExtendedPrice *= rate
+	return &converted, nil
+}
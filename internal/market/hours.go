@@ -0,0 +1,213 @@
+package market
+
+import (
+	"time"
+
+	"github.com/scmhub/calendar"
+
+	"stockmarket/internal/models"
+)
+
+// Package-level cached calendar (immutable, safe to share)
+var nyseCalendar = calendar.XNYS()
+
+// easternTime is the NYSE time zone, America/New_York, so sessions follow
+// daylight saving time
+var easternTime = nyseCalendar.Loc
+
+// calendarHorizon bounds how many days ahead GetMarketCalendar looks
+const calendarHorizon = 365
+
+// Market states reported by MarketState
+const (
+	MarketStateOpen       = "open"
+	MarketStatePreMarket  = "pre_market"
+	MarketStateAfterHours = "after_hours"
+	MarketStateClosed     = "closed"
+)
+
+// ExchangeLocation returns the time zone used for exchange-local dates
+func ExchangeLocation() *time.Location {
+	return easternTime
+}
+
+// IsMarketOpen reports whether the NYSE is open at the given time
+func IsMarketOpen(t time.Time) bool {
+	return IsExchangeOpen(ExchangeNYSE, t)
+}
+
+// MarketState reports the NYSE session at the given time: the regular session,
+// the pre-market or after-hours extended sessions, or closed
+func MarketState(t time.Time) string {
+	return ExchangeState(ExchangeNYSE, t)
+}
+
+// sessionState reports the session of cal at t; exchanges without extended
+// hours are either open or closed
+func sessionState(cal *calendar.Calendar, t time.Time) string {
+	local := t.In(cal.Loc)
+	if cal.IsOpen(local) {
+		return MarketStateOpen
+	}
+	if !cal.IsBusinessDay(local) {
+		return MarketStateClosed
+	}
+
+	session := cal.Session()
+	sinceMidnight := local.Sub(calendar.BOD(local))
+	switch {
+	case session.EarlyOpen > 0 && sinceMidnight >= session.EarlyOpen && sinceMidnight < session.Open:
+		return MarketStatePreMarket
+	case session.LateClose > 0 && sinceMidnight >= session.Open && sinceMidnight < session.LateClose:
+		return MarketStateAfterHours
+	default:
+		return MarketStateClosed
+	}
+}
+
+// IsOffHours reports whether a market state is outside the regular session
+func IsOffHours(state string) bool {
+	return state != "" && state != MarketStateOpen
+}
+
+// IsExtendedSession reports whether a market state is the pre-market or
+// after-hours session
+func IsExtendedSession(state string) bool {
+	return state == MarketStatePreMarket || state == MarketStateAfterHours
+}
+
+// annotateSession fills in the session of the quoted symbol's exchange at now
+// and the change of its extended-hours price against the regular price
+func annotateSession(quote *models.Quote, now time.Time) {
+	if quote.Session == "" {
+		quote.Session = ExchangeState(SymbolExchange(quote.Symbol), now)
+	}
+	if quote.ExtendedPrice > 0 && quote.Price > 0 && quote.ExtendedChangePercent == 0 {
+		quote.ExtendedChangePercent = (quote.ExtendedPrice - quote.Price) / quote.Price * 100
+	}
+}
+
+// IsTradingDay reports whether the NYSE has a regular session on the
+// exchange-local date of t
+func IsTradingDay(t time.Time) bool {
+	return nyseCalendar.IsBusinessDay(t.In(easternTime))
+}
+
+// TradingDay returns the exchange-local date for t as YYYY-MM-DD
+func TradingDay(t time.Time) string {
+	return t.In(easternTime).Format("2006-01-02")
+}
+
+// CalendarDay is an exchange holiday or early close
+type CalendarDay struct {
+	Date  string     `json:"date"` // YYYY-MM-DD
+	Name  string     `json:"name,omitempty"`
+	Close *time.Time `json:"close,omitempty"` // early closing time
+}
+
+// MarketCalendar describes the current session of an exchange and its
+// upcoming holidays and early closes; crypto markets have neither
+type MarketCalendar struct {
+	Exchange    string        `json:"exchange"`
+	TimeZone    string        `json:"time_zone"`
+	State       string        `json:"state"`
+	Open        bool          `json:"open"`
+	NextOpen    *time.Time    `json:"next_open,omitempty"`
+	NextClose   *time.Time    `json:"next_close,omitempty"`
+	Holidays    []CalendarDay `json:"holidays"`
+	EarlyCloses []CalendarDay `json:"early_closes"`
+}
+
+// NextOpen returns the start of the next regular NYSE session after t
+func NextOpen(t time.Time) time.Time {
+	return nextOpen(nyseCalendar, t)
+}
+
+// NextClose returns the end of the current regular NYSE session, or of the
+// next one when the market is closed
+func NextClose(t time.Time) time.Time {
+	return nextClose(nyseCalendar, t)
+}
+
+// nextOpen returns the start of the next regular session of cal after t
+func nextOpen(cal *calendar.Calendar, t time.Time) time.Time {
+	local := t.In(cal.Loc)
+	open := calendar.BOD(local).Add(cal.Session().Open)
+	if cal.IsBusinessDay(local) && local.Before(open) {
+		return open
+	}
+	return calendar.BOD(cal.NextBusinessDay(local)).Add(cal.Session().Open)
+}
+
+// nextClose returns the end of the current regular session of cal, or of
+// the next one when it is closed
+func nextClose(cal *calendar.Calendar, t time.Time) time.Time {
+	local := t.In(cal.Loc)
+	if close := cal.NextClose(local); close.After(local) {
+		return close
+	}
+	return cal.NextClose(cal.NextBusinessDay(local))
+}
+
+// GetMarketCalendar returns the NYSE session at now with the holidays and
+// early closes of the next days days (at most a year)
+func GetMarketCalendar(now time.Time, days int) MarketCalendar {
+	return GetExchangeCalendar(ExchangeNYSE, now, days)
+}
+
+// GetExchangeCalendar returns the session of exchange at now with the
+// holidays and early closes of the next days days (at most a year); dates
+// are in the exchange's time zone and unknown exchanges follow the NYSE
+func GetExchangeCalendar(exchange string, now time.Time, days int) MarketCalendar {
+	if exchange == ExchangeCrypto {
+		return MarketCalendar{
+			Exchange:    exchange,
+			TimeZone:    "UTC",
+			State:       MarketStateOpen,
+			Open:        true,
+			Holidays:    []CalendarDay{},
+			EarlyCloses: []CalendarDay{},
+		}
+	}
+	exch, ok := exchangeCalendars[exchange]
+	if !ok {
+		exchange, exch = ExchangeNYSE, nyseCalendar
+	}
+
+	days = min(max(days, 1), calendarHorizon)
+	local := now.In(exch.Loc)
+	end := calendar.BOD(local).AddDate(0, 0, days)
+	open, close := nextOpen(exch, now), nextClose(exch, now)
+
+	cal := MarketCalendar{
+		Exchange:    exchange,
+		TimeZone:    exch.Loc.String(),
+		State:       ExchangeState(exchange, now),
+		Open:        IsExchangeOpen(exchange, now),
+		NextOpen:    &open,
+		NextClose:   &close,
+		Holidays:    []CalendarDay{},
+		EarlyCloses: []CalendarDay{},
+	}
+
+	// Holidays falling on a weekend are observed on a weekday, which is
+	// listed separately
+	for t := calendar.BOD(local).Add(-time.Second); ; {
+		day, holiday := exch.NextHoliday(t)
+		if holiday == nil || !day.Before(end) {
+			break
+		}
+		if !calendar.IsWeekend(day) {
+			cal.Holidays = append(cal.Holidays, CalendarDay{Date: day.Format("2006-01-02"), Name: holiday.Name})
+		}
+		t = day
+	}
+
+	for day := calendar.BOD(local); day.Before(end); day = day.AddDate(0, 0, 1) {
+		if exch.IsBusinessDay(day) && exch.IsEarlyClose(day) {
+			close := day.Add(exch.Session().EarlyClose)
+			cal.EarlyCloses = append(cal.EarlyCloses, CalendarDay{Date: day.Format("2006-01-02"), Close: &close})
+		}
+	}
+	return cal
+}
@@ -0,0 +1,230 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// IEX Cloud-compatible endpoints. Sandbox tokens (prefixed "Tpk_" or
+// "Tsk_") are routed to the sandbox host, which serves scrambled test data.
+const (
+	iexBaseURL        = "https://cloud.iexapis.com/stable"
+	iexSandboxBaseURL = "https://sandbox.iexapis.com/stable"
+)
+
+// iexChartRanges are the chart ranges used to cover custom periods, shortest first
+var iexChartRanges = []struct {
+	name string
+	span time.Duration
+}{
+	{"1m", 31 * 24 * time.Hour},
+	{"3m", 92 * 24 * time.Hour},
+	{"6m", 183 * 24 * time.Hour},
+	{"1y", 366 * 24 * time.Hour},
+	{"2y", 2 * 366 * 24 * time.Hour},
+	{"5y", 5 * 366 * 24 * time.Hour},
+}
+
+// IEX implements the Provider interface for IEX Cloud-compatible APIs
+type IEX struct {
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+// NewIEX creates a new IEX provider; sandbox tokens select the sandbox host
+func NewIEX(token string) *IEX {
+	baseURL := iexBaseURL
+	if IsIEXSandboxToken(token) {
+		baseURL = iexSandboxBaseURL
+	}
+	return &IEX{
+		token:   token,
+		baseURL: baseURL,
+		client:  sharedHTTPClient,
+	}
+}
+
+// IsIEXSandboxToken reports whether token is an IEX sandbox (test) token
+func IsIEXSandboxToken(token string) bool {
+	return strings.HasPrefix(token, "Tpk_") || strings.HasPrefix(token, "Tsk_")
+}
+
+// Name returns the provider name
+func (x *IEX) Name() string {
+	return "iex"
+}
+
+// GetQuote fetches the current quote for a symbol
+func (x *IEX) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	var result struct {
+		LatestPrice   float64 `json:"latestPrice"`
+		Open          float64 `json:"open"`
+		High          float64 `json:"high"`
+		Low           float64 `json:"low"`
+		LatestVolume  int64   `json:"latestVolume"`
+		PreviousClose float64 `json:"previousClose"`
+		Change        float64 `json:"change"`
+		ChangePercent float64 `json:"changePercent"` // fraction, e.g. 0.0123
+		LatestUpdate  int64   `json:"latestUpdate"`  // milliseconds since epoch
+	}
+	if err := x.get(ctx, "/stock/"+url.PathEscape(symbol)+"/quote", nil, &result); err != nil {
+		return nil, err
+	}
+	if result.LatestPrice == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	return &models.Quote{
+		Symbol:        symbol,
+		Price:         result.LatestPrice,
+		Open:          result.Open,
+		High:          result.High,
+		Low:           result.Low,
+		Volume:        result.LatestVolume,
+		PreviousClose: result.PreviousClose,
+		Change:        result.Change,
+		ChangePercent: result.ChangePercent * 100,
+		Timestamp:     time.UnixMilli(result.LatestUpdate),
+	}, nil
+}
+
+// GetHistoricalData fetches historical OHLCV data from the chart endpoint, newest first
+func (x *IEX) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map period to IEX chart ranges; chartInterval keeps every nth point
+	now := time.Now()
+	chartRange, interval := "1m", 0
+	switch p.Name {
+	case "1d":
+		chartRange, interval = "1d", 5
+	case "5d":
+		chartRange, interval = "5dm", 15
+	case "1m", "3m", "1y", "5y", PeriodYTD, PeriodMax:
+		chartRange = p.Name
+		if p.Name == "5y" {
+			interval = 5
+		}
+	default:
+		// Custom ranges use the shortest chart range covering them
+		from, _ := p.Bounds(now)
+		chartRange = "max"
+		for _, r := range iexChartRanges {
+			if now.Sub(from) <= r.span {
+				chartRange = r.name
+				break
+			}
+		}
+		if p.Granularity(now) == GranularityWeekly {
+			interval = 5
+		}
+	}
+
+	params := url.Values{}
+	if interval > 0 {
+		params.Set("chartInterval", fmt.Sprint(interval))
+	}
+
+	var result []struct {
+		Date   string  `json:"date"`
+		Minute string  `json:"minute"` // set for intraday points
+		Open   float64 `json:"open"`
+		High   float64 `json:"high"`
+		Low    float64 `json:"low"`
+		Close  float64 `json:"close"`
+		Volume int64   `json:"volume"`
+	}
+	if err := x.get(ctx, "/stock/"+url.PathEscape(symbol)+"/chart/"+chartRange, params, &result); err != nil {
+		return nil, err
+	}
+
+	var candles []models.Candle
+	for _, r := range result {
+		// Intraday points without trades have no prices
+		if r.Close == 0 {
+			continue
+		}
+		layout, value := "2006-01-02", r.Date
+		if r.Minute != "" {
+			layout, value = "2006-01-02 15:04", r.Date+" "+r.Minute
+		}
+		timestamp, err := time.ParseInLocation(layout, value, easternTime)
+		if err != nil {
+			continue
+		}
+		candles = append(candles, models.Candle{
+			Timestamp: timestamp,
+			Open:      r.Open,
+			High:      r.High,
+			Low:       r.Low,
+			Close:     r.Close,
+			Volume:    r.Volume,
+		})
+	}
+
+	if p.IsCustom() {
+		from, to := p.Bounds(now)
+		candles = filterCandles(candles, from, to)
+	}
+	if len(candles) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Timestamp.After(candles[j].Timestamp)
+	})
+
+	return candles, nil
+}
+
+// get performs a token-authenticated GET request and decodes the JSON response
+func (x *IEX) get(ctx context.Context, path string, params url.Values, out any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("token", x.token)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", x.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := x.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrInvalidSymbol
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden:
+		// Invalid token, exhausted message quota or missing entitlement
+		return fmt.Errorf("%w: IEX rejected the token (status %d)", ErrAPIError, resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: IEX returned status %d", ErrAPIError, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StreamQuotes streams quotes by polling the quote endpoint
+func (x *IEX) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return pollQuotes(ctx, x, symbols, ch, 10*time.Second)
+}
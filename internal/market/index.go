@@ -0,0 +1,30 @@
+package market
+
+import "strings"
+
+// DefaultBenchmark is the symbol relative performance is measured against
+// until one is configured
+const DefaultBenchmark = "SPY"
+
+// IndexNames names the well-known indices, keyed by their Yahoo symbol
+var IndexNames = map[string]string{
+	"^GSPC": "S&P 500",
+	"^DJI":  "Dow Jones Industrial Average",
+	"^IXIC": "Nasdaq Composite",
+	"^NDX":  "Nasdaq 100",
+	"^RUT":  "Russell 2000",
+	"^VIX":  "CBOE Volatility Index",
+	"^FTSE": "FTSE 100",
+}
+
+// foreignIndices holds the exchange and currency of the non-US indices in
+// IndexNames; the others follow the NYSE and are in US dollars
+var foreignIndices = map[string]struct{ exchange, currency string }{
+	"^FTSE": {ExchangeLSE, "GBP"},
+}
+
+// IsIndexSymbol reports whether symbol is a market index in Yahoo's ^ form,
+// e.g. ^GSPC or ^VIX
+func IsIndexSymbol(symbol string) bool {
+	return strings.HasPrefix(strings.TrimSpace(symbol), "^")
+}
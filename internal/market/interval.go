@@ -0,0 +1,59 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"stockmarket/internal/market/resample"
+	"stockmarket/internal/models"
+)
+
+// ErrIntervalUnavailable is returned when a provider keeps no candles of the
+// requested interval that far back
+var ErrIntervalUnavailable = errors.New("interval not available for this period")
+
+// intervalAliases accepts the Yahoo Finance spelling of intervals
+var intervalAliases = map[string]string{
+	"60m": resample.Interval1h,
+	"1wk": resample.Interval1w,
+}
+
+// ParseInterval validates a candle interval such as 5m, 1h or 1wk and
+// returns its canonical form
+func ParseInterval(interval string) (string, error) {
+	if alias, ok := intervalAliases[interval]; ok {
+		interval = alias
+	}
+	if !resample.Valid(interval) {
+		return "", fmt.Errorf("%w: %q", resample.ErrInvalidInterval, interval)
+	}
+	return interval, nil
+}
+
+// IntervalProvider is implemented by providers that can fetch candles of a
+// requested interval rather than the one they pick for the period
+type IntervalProvider interface {
+	GetHistoricalDataInterval(ctx context.Context, symbol, period, interval string) ([]models.Candle, error)
+}
+
+// GetHistoricalDataInterval fetches candles of the given canonical interval
+// for period. Providers without interval support fetch their usual candles,
+// which are resampled, so intervals finer than those return
+// resample.ErrFinerThanSource.
+func GetHistoricalDataInterval(ctx context.Context, p Provider, symbol, period, interval string) ([]models.Candle, error) {
+	p = forSymbol(p, symbol)
+	if _, ok := inner(p).(IntervalProvider); ok {
+		raw, err := unwrap(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		return raw.(IntervalProvider).GetHistoricalDataInterval(ctx, symbol, period, interval)
+	}
+
+	candles, err := p.GetHistoricalData(ctx, symbol, period)
+	if err != nil {
+		return nil, err
+	}
+	return resample.Resample(candles, interval, ExchangeLocation())
+}
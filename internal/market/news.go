@@ -0,0 +1,77 @@
+package market
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+const (
+	// newsLookback is how far back headlines are fetched for an analysis
+	newsLookback = 7 * 24 * time.Hour
+	// maxNewsItems caps the headlines included in an analysis prompt
+	maxNewsItems = 8
+	// newsCacheTTL is how long fetched headlines are reused
+	newsCacheTTL = 15 * time.Minute
+)
+
+// NewsProvider is implemented by providers that can list recent headlines
+// about a symbol
+type NewsProvider interface {
+	GetCompanyNews(ctx context.Context, symbol string, from, to time.Time) ([]models.NewsItem, error)
+}
+
+type cachedNews struct {
+	items     []models.NewsItem
+	fetchedAt time.Time
+}
+
+// news caches headlines per provider and symbol
+var (
+	newsMu    sync.Mutex
+	newsCache = map[string]cachedNews{}
+)
+
+// GetCompanyNews returns the most recent headlines about symbol from the last
+// week, newest first. Providers without news use Yahoo Finance's search.
+func GetCompanyNews(ctx context.Context, p Provider, symbol string) ([]models.NewsItem, error) {
+	p = forSymbol(p, symbol)
+	key := p.Name() + ":" + strings.ToUpper(symbol)
+	now := time.Now()
+
+	newsMu.Lock()
+	entry, ok := newsCache[key]
+	newsMu.Unlock()
+	if ok && now.Sub(entry.fetchedAt) < newsCacheTTL {
+		return entry.items, nil
+	}
+
+	p, err := unwrap(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	reporter, ok := p.(NewsProvider)
+	if !ok {
+		reporter = NewYahooFinance()
+	}
+	items, err := reporter.GetCompanyNews(ctx, symbol, now.Add(-newsLookback), now)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].PublishedAt.After(items[j].PublishedAt)
+	})
+	if len(items) > maxNewsItems {
+		items = items[:maxNewsItems]
+	}
+
+	newsMu.Lock()
+	newsCache[key] = cachedNews{items: items, fetchedAt: now}
+	newsMu.Unlock()
+	return items, nil
+}
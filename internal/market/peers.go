@@ -0,0 +1,81 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxPeers caps the peers a symbol is compared with
+	maxPeers = 5
+	// peersCacheTTL is how long fetched peers are reused; they change rarely
+	peersCacheTTL = 24 * time.Hour
+)
+
+// ErrNoPeers is returned when no peers are known for a symbol
+var ErrNoPeers = errors.New("no peers found")
+
+// PeersProvider is implemented by providers that can list companies in the
+// same sector or industry as a symbol
+type PeersProvider interface {
+	GetPeers(ctx context.Context, symbol string) ([]string, error)
+}
+
+type cachedPeers struct {
+	symbols   []string
+	fetchedAt time.Time
+}
+
+// peers caches peers per provider and symbol
+var (
+	peersMu    sync.Mutex
+	peersCache = map[string]cachedPeers{}
+)
+
+// GetPeers returns up to maxPeers symbols in the same sector as symbol,
+// excluding symbol itself. Providers without peer data use Yahoo Finance's
+// similar symbols.
+func GetPeers(ctx context.Context, p Provider, symbol string) ([]string, error) {
+	p = forSymbol(p, symbol)
+	key := p.Name() + ":" + strings.ToUpper(symbol)
+	now := time.Now()
+
+	peersMu.Lock()
+	entry, ok := peersCache[key]
+	peersMu.Unlock()
+	if ok && now.Sub(entry.fetchedAt) < peersCacheTTL {
+		return entry.symbols, nil
+	}
+
+	p, err := unwrap(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := p.(PeersProvider)
+	if !ok {
+		lister = NewYahooFinance()
+	}
+	found, err := lister.GetPeers(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]string, 0, maxPeers)
+	for _, peer := range found {
+		peer = NormalizeSymbol(peer)
+		if peer != "" && !strings.EqualFold(peer, symbol) && len(symbols) < maxPeers {
+			symbols = append(symbols, peer)
+		}
+	}
+	if len(symbols) == 0 {
+		return nil, ErrNoPeers
+	}
+
+	peersMu.Lock()
+	peersCache[key] = cachedPeers{symbols: symbols, fetchedAt: now}
+	peersMu.Unlock()
+	return symbols, nil
+}
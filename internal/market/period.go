@@ -0,0 +1,159 @@
+package market
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// Extended history periods accepted alongside the fixed 1d..5y presets
+const (
+	PeriodYTD = "ytd"
+	PeriodMax = "max"
+)
+
+// PeriodDateLayout is the date format of custom range bounds
+const PeriodDateLayout = "2006-01-02"
+
+// maxCustomRange bounds the span of a custom date range
+const maxCustomRange = 30 * 365 * 24 * time.Hour
+
+// Candle granularities chosen for custom ranges
+const (
+	GranularityIntraday = "intraday"
+	GranularityDaily    = "daily"
+	GranularityWeekly   = "weekly"
+)
+
+// Custom ranges up to intradayRangeLimit use intraday candles while they are
+// within intradayHistoryLimit (how far back providers keep them); ranges
+// longer than weeklyRangeLimit use weekly candles
+const (
+	intradayRangeLimit   = 5 * 24 * time.Hour
+	intradayHistoryLimit = 60 * 24 * time.Hour
+	weeklyRangeLimit     = 5 * 365 * 24 * time.Hour
+)
+
+// DefaultPeriod is used for empty and unknown periods
+const DefaultPeriod = "1m"
+
+// ErrInvalidPeriod is returned for malformed date ranges
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// presetPeriods maps each fixed period to the span of history it covers
+var presetPeriods = map[string]func(now time.Time) time.Time{
+	"1d": func(now time.Time) time.Time { return now.AddDate(0, 0, -1) },
+	"5d": func(now time.Time) time.Time { return now.AddDate(0, 0, -5) },
+	"1m": func(now time.Time) time.Time { return now.AddDate(0, -1, 0) },
+	"3m": func(now time.Time) time.Time { return now.AddDate(0, -3, 0) },
+	"1y": func(now time.Time) time.Time { return now.AddDate(-1, 0, 0) },
+	"5y": func(now time.Time) time.Time { return now.AddDate(-5, 0, 0) },
+	PeriodYTD: func(now time.Time) time.Time {
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+	},
+	PeriodMax: func(now time.Time) time.Time { return time.Unix(0, 0) },
+}
+
+// Period is a parsed history period: a named preset ("1d".."5y", "ytd",
+// "max") or a custom date range written as "2024-01-02:2024-06-28"
+type Period struct {
+	Name string    // preset name, empty for custom ranges
+	From time.Time // custom range start (inclusive day)
+	To   time.Time // custom range end (inclusive day)
+}
+
+// ParsePeriod parses a period as passed to Provider.GetHistoricalData. An
+// empty period is treated as DefaultPeriod, as are unknown names, which
+// stored presets and older clients may still send; only malformed date
+// ranges are rejected
+func ParsePeriod(period string) (Period, error) {
+	if period == "" {
+		return Period{Name: DefaultPeriod}, nil
+	}
+	if _, ok := presetPeriods[period]; ok {
+		return Period{Name: period}, nil
+	}
+	fromStr, toStr, ok := strings.Cut(period, ":")
+	if !ok {
+		log.Printf("[MARKET] Unknown period %q, using %s", period, DefaultPeriod)
+		return Period{Name: DefaultPeriod}, nil
+	}
+	return CustomPeriod(fromStr, toStr, time.Now())
+}
+
+// CustomPeriod validates a custom date range given as YYYY-MM-DD bounds: from
+// must be before to, not in the future, and the range at most 30 years
+func CustomPeriod(from, to string, now time.Time) (Period, error) {
+	fromDate, err := time.ParseInLocation(PeriodDateLayout, from, easternTime)
+	if err != nil {
+		return Period{}, fmt.Errorf("%w: from must be a YYYY-MM-DD date", ErrInvalidPeriod)
+	}
+	toDate, err := time.ParseInLocation(PeriodDateLayout, to, easternTime)
+	if err != nil {
+		return Period{}, fmt.Errorf("%w: to must be a YYYY-MM-DD date", ErrInvalidPeriod)
+	}
+	switch {
+	case !fromDate.Before(toDate):
+		return Period{}, fmt.Errorf("%w: from must be before to", ErrInvalidPeriod)
+	case fromDate.After(now):
+		return Period{}, fmt.Errorf("%w: from must not be in the future", ErrInvalidPeriod)
+	case toDate.Sub(fromDate) > maxCustomRange:
+		return Period{}, fmt.Errorf("%w: date range must not exceed 30 years", ErrInvalidPeriod)
+	}
+	return Period{From: fromDate, To: toDate}, nil
+}
+
+// IsCustom reports whether the period is a custom date range
+func (p Period) IsCustom() bool {
+	return p.Name == ""
+}
+
+// String formats the period as accepted by ParsePeriod
+func (p Period) String() string {
+	if !p.IsCustom() {
+		return p.Name
+	}
+	return p.From.Format(PeriodDateLayout) + ":" + p.To.Format(PeriodDateLayout)
+}
+
+// Bounds returns the time range the period covers as of now
+func (p Period) Bounds(now time.Time) (from, to time.Time) {
+	if !p.IsCustom() {
+		return presetPeriods[p.Name](now), now
+	}
+	// Include the whole last day, but never ask for data from the future
+	to = p.To.AddDate(0, 0, 1)
+	if to.After(now) {
+		to = now
+	}
+	return p.From, to
+}
+
+// Granularity returns the candle size suited to a custom range: intraday for
+// short recent ranges, weekly for ranges over five years, daily otherwise
+func (p Period) Granularity(now time.Time) string {
+	from, to := p.Bounds(now)
+	switch span := to.Sub(from); {
+	case span <= intradayRangeLimit && now.Sub(from) <= intradayHistoryLimit:
+		return GranularityIntraday
+	case span > weeklyRangeLimit:
+		return GranularityWeekly
+	default:
+		return GranularityDaily
+	}
+}
+
+// filterCandles keeps the candles whose timestamp falls within [from, to)
+func filterCandles(candles []models.Candle, from, to time.Time) []models.Candle {
+	var out []models.Candle
+	for _, c := range candles {
+		if !c.Timestamp.Before(from) && c.Timestamp.Before(to) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
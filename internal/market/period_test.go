@@ -0,0 +1,34 @@
+package market
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		period string
+		want   string
+	}{
+		{"", DefaultPeriod},
+		{"5d", "5d"},
+		{"ytd", "ytd"},
+		{"6m", DefaultPeriod}, // unknown names fall back
+		{"2024-01-02:2024-06-28", "2024-01-02:2024-06-28"},
+	}
+	for _, tt := range tests {
+		p, err := ParsePeriod(tt.period)
+		if err != nil {
+			t.Fatalf("ParsePeriod(%q): %v", tt.period, err)
+		}
+		if got := p.String(); got != tt.want {
+			t.Errorf("ParsePeriod(%q) = %q, want %q", tt.period, got, tt.want)
+		}
+	}
+
+	for _, period := range []string{"2024-06-28:2024-01-02", "2024-01-02:", "jan:jun", "2090-01-01:2091-01-01"} {
+		if _, err := ParsePeriod(period); !errors.Is(err, ErrInvalidPeriod) {
+			t.Errorf("ParsePeriod(%q) err = %v, want ErrInvalidPeriod", period, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package market
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// defaultProbeSymbol is quoted to check that a provider is reachable and
+// accepts its API key
+const defaultProbeSymbol = "AAPL"
+
+// probeSymbols overrides the probe symbol for providers without US equities
+var probeSymbols = map[string]string{
+	"binance":  "BTCUSDT",
+	"coinbase": "BTC-USD",
+}
+
+// RateLimitStatus reports how much of a provider's local request budget is left
+type RateLimitStatus struct {
+	Remaining int    `json:"remaining"`
+	Limit     int    `json:"limit"`
+	Per       string `json:"per"`
+}
+
+// Probe fetches a fresh quote from p, bypassing the quote cache, and returns
+// how long the upstream took to answer. It fails fast while p's circuit is
+// open and waits its turn under p's rate limit.
+func Probe(ctx context.Context, p Provider) (time.Duration, error) {
+	symbol, ok := probeSymbols[p.Name()]
+	if !ok {
+		symbol = defaultProbeSymbol
+	}
+	p, err := unwrap(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	_, err = p.GetQuote(ctx, symbol)
+	return time.Since(start), err
+}
+
+// GetRateLimitStatus returns the local request budget left for a provider,
+// nil when the provider is not rate limited
+func GetRateLimitStatus(name string) *RateLimitStatus {
+	b := limiterFor(name)
+	if b == nil {
+		return nil
+	}
+	limit := providerRateLimits[name]
+
+	b.mu.Lock()
+	tokens := math.Min(b.burst, b.tokens+time.Since(b.lastFill).Seconds()*b.rate)
+	b.mu.Unlock()
+
+	return &RateLimitStatus{
+		Remaining: max(0, int(math.Floor(tokens))),
+		Limit:     limit.Requests,
+		Per:       limit.Per.String(),
+	}
+}
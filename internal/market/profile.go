@@ -0,0 +1,65 @@
+package market
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// profileCacheTTL is how long a fetched company profile is reused; profiles
+// change rarely and some providers allow only a few calls a minute
+const profileCacheTTL = 24 * time.Hour
+
+// ProfileProvider is implemented by providers that can describe the company
+// behind a symbol
+type ProfileProvider interface {
+	GetCompanyProfile(ctx context.Context, symbol string) (*models.CompanyProfile, error)
+}
+
+type cachedProfile struct {
+	profile   models.CompanyProfile
+	fetchedAt time.Time
+}
+
+// profiles caches company profiles per provider and symbol
+var (
+	profilesMu sync.Mutex
+	profiles   = map[string]cachedProfile{}
+)
+
+// GetCompanyProfile returns the company profile of symbol. Providers without
+// profile data use the name, exchange and currency reported by Yahoo Finance.
+func GetCompanyProfile(ctx context.Context, p Provider, symbol string) (*models.CompanyProfile, error) {
+	p = forSymbol(p, symbol)
+	key := p.Name() + ":" + strings.ToUpper(symbol)
+	now := time.Now()
+
+	profilesMu.Lock()
+	entry, ok := profiles[key]
+	profilesMu.Unlock()
+	if ok && now.Sub(entry.fetchedAt) < profileCacheTTL {
+		profile := entry.profile
+		return &profile, nil
+	}
+
+	p, err := unwrap(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	profiler, ok := p.(ProfileProvider)
+	if !ok {
+		profiler = NewYahooFinance()
+	}
+	profile, err := profiler.GetCompanyProfile(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	profilesMu.Lock()
+	profiles[key] = cachedProfile{profile: *profile, fetchedAt: now}
+	profilesMu.Unlock()
+	return profile, nil
+}
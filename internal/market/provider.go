@@ -3,26 +3,19 @@ package market
 import (
 	"context"
 	"errors"
-	"net"
 	"net/http"
 	"time"
 
+	"stockmarket/internal/httpclient"
 	"stockmarket/internal/models"
 )
 
-// Shared HTTP client with optimized transport for all market providers
-var sharedHTTPClient = &http.Client{
-	Timeout: 30 * time.Second,
-	Transport: &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   10 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-		TLSHandshakeTimeout: 10 * time.Second,
-	},
+// Shared HTTP client for all market providers, replaced at startup by SetHTTPClient
+var sharedHTTPClient = httpclient.New(httpclient.MarketDefaults)
+
+// SetHTTPClient replaces the shared client; call it before creating any market providers
+func SetHTTPClient(client *http.Client) {
+	sharedHTTPClient = client
 }
 
 // Provider defines the interface for market data providers
@@ -33,6 +26,102 @@ type Provider interface {
 	Name() string
 }
 
+// SymbolLister is implemented by providers that can list the symbols traded on an exchange
+type SymbolLister interface {
+	ListSymbols(ctx context.Context, exchange string) ([]models.ListedSymbol, error)
+}
+
+// SymbolSearcher is implemented by providers with a symbol search API
+type SymbolSearcher interface {
+	SearchSymbols(ctx context.Context, query string) ([]models.SymbolMatch, error)
+}
+
+// ErrSymbolListUnsupported is returned by ListSymbols for providers without symbol lists
+var ErrSymbolListUnsupported = errors.New("market provider does not list exchange symbols")
+
+// maxSymbolMatches caps the number of symbol search results
+const maxSymbolMatches = 10
+
+// ListSymbols lists the symbols traded on an exchange when p supports it
+func ListSymbols(ctx context.Context, p Provider, exchange string) ([]models.ListedSymbol, error) {
+	p, err := unwrap(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := p.(SymbolLister)
+	if !ok {
+		return nil, ErrSymbolListUnsupported
+	}
+	return lister.ListSymbols(ctx, exchange)
+}
+
+// SearchSymbols finds symbols matching a ticker or company name. Providers
+// without a search API use Yahoo Finance's keyless autocomplete.
+func SearchSymbols(ctx context.Context, p Provider, query string) ([]models.SymbolMatch, error) {
+	p, err := unwrap(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	searcher, ok := p.(SymbolSearcher)
+	if !ok {
+		searcher = NewYahooFinance()
+	}
+	matches, err := searcher.SearchSymbols(ctx, query)
+	if len(matches) > maxSymbolMatches {
+		matches = matches[:maxSymbolMatches]
+	}
+	return matches, err
+}
+
+// inner returns the provider behind the NewProvider wrappers without
+// touching its circuit breaker or rate limit, for capability checks
+func inner(p Provider) Provider {
+	if router, ok := p.(yahooRouter); ok {
+		p = router.Provider
+	}
+	if wrapped, ok := p.(singleflightProvider); ok {
+		p = wrapped.Provider
+	}
+	if stored, ok := p.(storedHistoryProvider); ok {
+		p = stored.Provider
+	}
+	if guarded, ok := p.(breakerProvider); ok {
+		p = guarded.Provider
+	}
+	if limited, ok := p.(rateLimitedProvider); ok {
+		p = limited.Provider
+	}
+	return p
+}
+
+// unwrap returns the provider behind the NewProvider wrappers, failing fast
+// while its circuit is open and waiting for its rate limit so the call made
+// on it is counted
+func unwrap(ctx context.Context, p Provider) (Provider, error) {
+	if router, ok := p.(yahooRouter); ok {
+		p = router.Provider
+	}
+	if wrapped, ok := p.(singleflightProvider); ok {
+		p = wrapped.Provider
+	}
+	if stored, ok := p.(storedHistoryProvider); ok {
+		p = stored.Provider
+	}
+	if guarded, ok := p.(breakerProvider); ok {
+		if err := guarded.breaker.check(time.Now()); err != nil {
+			return nil, err
+		}
+		p = guarded.Provider
+	}
+	if limited, ok := p.(rateLimitedProvider); ok {
+		if err := limited.wait(ctx); err != nil {
+			return nil, err
+		}
+		p = limited.Provider
+	}
+	return p, nil
+}
+
 // ErrRateLimited is returned when rate limit is exceeded
 var ErrRateLimited = errors.New("rate limit exceeded")
 
@@ -42,16 +131,62 @@ var ErrInvalidSymbol = errors.New("invalid symbol")
 // ErrAPIError is returned when the API returns an error
 var ErrAPIError = errors.New("API error")
 
-// NewProvider creates a market data provider based on the provider name
+// requiresAPIKey lists the providers that cannot work without an API key
+var requiresAPIKey = map[string]bool{
+	"alphavantage": true,
+	"finnhub":      true,
+	"tiingo":       true,
+	"twelvedata":   true,
+	"iex":          true,
+	"alpaca":       true,
+	"eodhd":        true,
+}
+
+// NewProvider creates a market data provider based on the provider name.
+// Providers that need an API key fall back to keyless Stooq data until one
+// is configured. Index symbols such as ^GSPC and international listings such
+// as SAP.DE are quoted by Yahoo Finance when the provider has no data for
+// them. Requests queue for the provider's rate limit, daily history
+// is kept in the candle store, recent quotes are cached, and concurrent quote
+// fetches for the same symbol share one upstream request.
 func NewProvider(name string, apiKey string) (Provider, error) {
+	if requiresAPIKey[name] && apiKey == "" {
+		name = "stooq"
+	}
+
 	switch name {
 	case "alphavantage":
-		return NewAlphaVantage(apiKey), nil
+		return wrap(NewAlphaVantage(apiKey)), nil
 	case "yahoo":
-		return NewYahooFinance(), nil
+		return wrap(NewYahooFinance()), nil
 	case "finnhub":
-		return NewFinnhub(apiKey), nil
+		return wrap(NewFinnhub(apiKey)), nil
+	case "tiingo":
+		return wrap(NewTiingo(apiKey)), nil
+	case "twelvedata":
+		return wrap(NewTwelveData(apiKey)), nil
+	case "iex":
+		return wrap(NewIEX(apiKey)), nil
+	case "alpaca":
+		return wrap(NewAlpaca(apiKey)), nil
+	case "binance":
+		return wrap(NewBinance()), nil
+	case "coinbase":
+		return wrap(NewCoinbase()), nil
+	case "stooq":
+		return wrap(NewStooq()), nil
+	case "eodhd":
+		return wrap(NewEODHD(apiKey)), nil
+	case "demo":
+		return wrap(NewDemo(DefaultDemoSeed)), nil
 	default:
 		return nil, errors.New("unknown provider: " + name)
 	}
 }
+
+// wrap applies the rate limit, circuit breaker, stored history, quote cache
+// and singleflight wrappers, and sends the index symbols and international
+// listings the provider has no data for to Yahoo Finance
+func wrap(p Provider) Provider {
+	return withYahooFallback(WithSingleflight(withCandleStore(WithCircuitBreaker(WithRateLimit(p)))))
+}
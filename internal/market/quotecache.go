@@ -0,0 +1,97 @@
+package market
+
+import (
+	"sync"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// DefaultQuoteCacheTTL is how long a fetched quote is reused by default
+const DefaultQuoteCacheTTL = 15 * time.Second
+
+// quoteCachePruneSize is the entry count above which expired quotes are pruned
+const quoteCachePruneSize = 1024
+
+// QuoteCacheStats reports quote cache activity
+type QuoteCacheStats struct {
+	TTL     string `json:"ttl"`
+	Size    int    `json:"size"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Enabled bool   `json:"enabled"`
+}
+
+// quoteCache keeps recently fetched quotes per provider and symbol so the
+// quote endpoint, watchlist, polling and analysis share upstream calls
+type quoteCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedQuote
+	hits    uint64
+	misses  uint64
+}
+
+type cachedQuote struct {
+	quote     models.Quote
+	fetchedAt time.Time
+}
+
+// quotes is the cache shared by every provider returned from NewProvider
+var quotes = &quoteCache{ttl: DefaultQuoteCacheTTL, entries: make(map[string]cachedQuote)}
+
+// SetQuoteCacheTTL sets how long fetched quotes are reused; zero disables
+// the cache. Call it before creating any market providers.
+func SetQuoteCacheTTL(ttl time.Duration) {
+	quotes.mu.Lock()
+	defer quotes.mu.Unlock()
+	quotes.ttl = ttl
+	quotes.entries = make(map[string]cachedQuote)
+}
+
+// GetQuoteCacheStats returns the shared quote cache statistics
+func GetQuoteCacheStats() QuoteCacheStats {
+	quotes.mu.Lock()
+	defer quotes.mu.Unlock()
+	return QuoteCacheStats{
+		TTL:     quotes.ttl.String(),
+		Size:    len(quotes.entries),
+		Hits:    quotes.hits,
+		Misses:  quotes.misses,
+		Enabled: quotes.ttl > 0,
+	}
+}
+
+// get returns a copy of the cached quote for key if it is still fresh
+func (c *quoteCache) get(key string, now time.Time) (*models.Quote, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	entry, ok := c.entries[key]
+	if !ok || now.Sub(entry.fetchedAt) >= c.ttl {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	quote := entry.quote
+	return &quote, true
+}
+
+// put stores a copy of quote under key
+func (c *quoteCache) put(key string, quote *models.Quote, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return
+	}
+	if len(c.entries) >= quoteCachePruneSize {
+		for k, entry := range c.entries {
+			if now.Sub(entry.fetchedAt) >= c.ttl {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = cachedQuote{quote: *quote, fetchedAt: now}
+}
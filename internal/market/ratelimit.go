@@ -0,0 +1,173 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// maxQueueWait is how long a request may wait in a provider's queue for a
+// token; requests that would wait longer fail with a RateLimitError
+const maxQueueWait = 15 * time.Second
+
+// RateLimit is a provider's request budget: Requests per Per, with bursts of
+// up to Burst requests
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// providerRateLimits are the free-tier request budgets of providers that
+// enforce one; providers not listed are not limited
+var providerRateLimits = map[string]RateLimit{
+	"alphavantage": {Requests: 5, Per: time.Minute, Burst: 5},
+	"finnhub":      {Requests: 60, Per: time.Minute, Burst: 30},
+	"twelvedata":   {Requests: 8, Per: time.Minute, Burst: 8},
+	"tiingo":       {Requests: 50, Per: time.Hour, Burst: 10},
+	"alpaca":       {Requests: 200, Per: time.Minute, Burst: 50},
+	"eodhd":        {Requests: 1000, Per: time.Minute, Burst: 50},
+}
+
+// RateLimitError is returned when a provider's queue is too long to wait
+// for; it matches ErrRateLimited with errors.Is
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s requests are queued, retry after %s", e.Provider, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap lets errors.Is(err, ErrRateLimited) match
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// tokenBucket is a token bucket whose balance may go negative: each queued
+// request reserves a token and waits until it has been refilled
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // tokens per second
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	return &tokenBucket{
+		rate:     float64(limit.Requests) / limit.Per.Seconds(),
+		burst:    float64(limit.Burst),
+		tokens:   float64(limit.Burst),
+		lastFill: time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long to wait before using it. When
+// the wait would exceed maxWait no token is taken and ok is false.
+func (b *tokenBucket) reserve(now time.Time, maxWait time.Duration) (wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.lastFill).Seconds()*b.rate)
+	b.lastFill = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait > maxWait {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// cancel returns a token reserved by a request that gave up waiting
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+	b.mu.Unlock()
+}
+
+// rateLimiters holds one bucket per provider, shared by all its instances
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = map[string]*tokenBucket{}
+)
+
+// limiterFor returns the shared bucket of a provider, nil when it is unlimited
+func limiterFor(name string) *tokenBucket {
+	limit, ok := providerRateLimits[name]
+	if !ok {
+		return nil
+	}
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	if b, ok := rateLimiters[name]; ok {
+		return b
+	}
+	b := newTokenBucket(limit)
+	rateLimiters[name] = b
+	return b
+}
+
+// rateLimitedProvider wraps a provider so upstream requests wait their turn
+// in the provider's queue
+type rateLimitedProvider struct {
+	Provider
+	bucket *tokenBucket
+}
+
+// WithRateLimit wraps p with its provider's shared rate limit, if it has one
+func WithRateLimit(p Provider) Provider {
+	bucket := limiterFor(p.Name())
+	if bucket == nil {
+		return p
+	}
+	return rateLimitedProvider{Provider: p, bucket: bucket}
+}
+
+// wait queues for a token, failing with a RateLimitError when the queue is
+// longer than maxQueueWait
+func (p rateLimitedProvider) wait(ctx context.Context) error {
+	wait, ok := p.bucket.reserve(time.Now(), maxQueueWait)
+	if !ok {
+		return &RateLimitError{Provider: p.Name(), RetryAfter: wait}
+	}
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		p.bucket.cancel()
+		return ctx.Err()
+	}
+}
+
+// GetQuote fetches a quote once the provider's queue allows
+func (p rateLimitedProvider) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.Provider.GetQuote(ctx, symbol)
+}
+
+// GetHistoricalData fetches candles once the provider's queue allows
+func (p rateLimitedProvider) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.Provider.GetHistoricalData(ctx, symbol, period)
+}
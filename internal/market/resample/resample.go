@@ -0,0 +1,134 @@
+// Package resample aggregates OHLCV candles into larger, consistent bars so
+// history looks the same whichever intervals a provider supports
+package resample
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// Bar intervals accepted by Resample
+const (
+	Interval1m  = "1m"
+	Interval5m  = "5m"
+	Interval15m = "15m"
+	Interval30m = "30m"
+	Interval1h  = "1h"
+	Interval1d  = "1d"
+	Interval1w  = "1w"
+)
+
+// intervals maps each interval to its bar length
+var intervals = map[string]time.Duration{
+	Interval1m:  time.Minute,
+	Interval5m:  5 * time.Minute,
+	Interval15m: 15 * time.Minute,
+	Interval30m: 30 * time.Minute,
+	Interval1h:  time.Hour,
+	Interval1d:  24 * time.Hour,
+	Interval1w:  7 * 24 * time.Hour,
+}
+
+// ErrInvalidInterval is returned for intervals Resample does not support
+var ErrInvalidInterval = errors.New("invalid interval")
+
+// ErrFinerThanSource is returned when the interval is shorter than the
+// spacing of the source candles, which cannot be split
+var ErrFinerThanSource = errors.New("interval is finer than the source candles")
+
+// Valid reports whether interval is supported by Resample
+func Valid(interval string) bool {
+	_, ok := intervals[interval]
+	return ok
+}
+
+// Size returns the bar length of interval, or zero when it is not supported
+func Size(interval string) time.Duration {
+	return intervals[interval]
+}
+
+// Resample merges candles into bars of the given interval. Intraday bars are
+// aligned to the start of the day in loc, daily bars to midnight and weekly
+// bars to Monday midnight; each bar is stamped with its start. Bars are
+// returned in the order of the input (oldest or newest first).
+func Resample(candles []models.Candle, interval string, loc *time.Location) ([]models.Candle, error) {
+	size, ok := intervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidInterval, interval)
+	}
+	if len(candles) < 2 {
+		return candles, nil
+	}
+
+	newestFirst := candles[0].Timestamp.After(candles[len(candles)-1].Timestamp)
+	sorted := append([]models.Candle{}, candles...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	// Allow an hour of slack for daily candles spanning a DST change
+	if spacing := minSpacing(sorted); spacing > size+time.Hour {
+		return nil, fmt.Errorf("%w: source candles are %s apart", ErrFinerThanSource, spacing)
+	}
+
+	var out []models.Candle
+	start := 0
+	for i := 1; i <= len(sorted); i++ {
+		if i < len(sorted) && bucket(sorted[i].Timestamp, interval, size, loc).Equal(bucket(sorted[start].Timestamp, interval, size, loc)) {
+			continue
+		}
+		bar := Merge(sorted[start:i])
+		bar.Timestamp = bucket(sorted[start].Timestamp, interval, size, loc)
+		out = append(out, bar)
+		start = i
+	}
+
+	if newestFirst {
+		slices.Reverse(out)
+	}
+	return out, nil
+}
+
+// Merge combines oldest-first candles into a single OHLCV bar stamped with
+// the first candle's time
+func Merge(group []models.Candle) models.Candle {
+	merged := group[0]
+	for _, c := range group[1:] {
+		merged.High = max(merged.High, c.High)
+		merged.Low = min(merged.Low, c.Low)
+		merged.Volume += c.Volume
+	}
+	merged.Close = group[len(group)-1].Close
+	return merged
+}
+
+// bucket returns the start of the bar containing t
+func bucket(t time.Time, interval string, size time.Duration, loc *time.Location) time.Time {
+	local := t.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	switch interval {
+	case Interval1d:
+		return midnight
+	case Interval1w:
+		// Weeks start on Monday
+		offset := (int(midnight.Weekday()) + 6) % 7
+		return midnight.AddDate(0, 0, -offset)
+	}
+	return midnight.Add(local.Sub(midnight).Truncate(size))
+}
+
+// minSpacing returns the smallest gap between consecutive oldest-first candles
+func minSpacing(sorted []models.Candle) time.Duration {
+	spacing := time.Duration(0)
+	for i := 1; i < len(sorted); i++ {
+		gap := sorted[i].Timestamp.Sub(sorted[i-1].Timestamp)
+		if gap > 0 && (spacing == 0 || gap < spacing) {
+			spacing = gap
+		}
+	}
+	return spacing
+}
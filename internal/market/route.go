@@ -0,0 +1,101 @@
+package market
+
+import (
+	"context"
+	"sync"
+
+	"stockmarket/internal/models"
+)
+
+// symbolCoverage is implemented by providers that quote indices or
+// international listings; the others only quote US listings
+type symbolCoverage interface {
+	covers(symbol string) bool
+}
+
+// covers reports whether p has data for symbol
+func covers(p Provider, symbol string) bool {
+	if c, ok := p.(symbolCoverage); ok {
+		return c.covers(symbol)
+	}
+	return !IsIndexSymbol(symbol) && ListingSuffix(symbol) == ""
+}
+
+// yahooRouter wraps a provider so the index symbols and international
+// listings it has no data for are fetched from Yahoo Finance
+type yahooRouter struct {
+	Provider
+	yahoo Provider
+}
+
+// withYahooFallback routes the symbols p does not cover to Yahoo Finance
+func withYahooFallback(p Provider) Provider {
+	if _, ok := inner(p).(*YahooFinance); ok {
+		return p
+	}
+	if _, ok := p.(yahooRouter); ok {
+		return p
+	}
+	return yahooRouter{Provider: p, yahoo: wrap(NewYahooFinance())}
+}
+
+// routes reports whether symbol is sent to Yahoo Finance
+func (p yahooRouter) routes(symbol string) bool {
+	return !covers(inner(p.Provider), symbol)
+}
+
+// forSymbol returns the provider that serves symbol
+func forSymbol(p Provider, symbol string) Provider {
+	if router, ok := p.(yahooRouter); ok && router.routes(symbol) {
+		return router.yahoo
+	}
+	return p
+}
+
+// GetQuote fetches a quote from the provider serving symbol
+func (p yahooRouter) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	if p.routes(symbol) {
+		return p.yahoo.GetQuote(ctx, symbol)
+	}
+	return p.Provider.GetQuote(ctx, symbol)
+}
+
+// GetHistoricalData fetches candles from the provider serving symbol
+func (p yahooRouter) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	if p.routes(symbol) {
+		return p.yahoo.GetHistoricalData(ctx, symbol, period)
+	}
+	return p.Provider.GetHistoricalData(ctx, symbol, period)
+}
+
+// StreamQuotes streams the symbols the provider does not cover from Yahoo
+// Finance alongside the provider's own stream
+func (p yahooRouter) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	var routed, own []string
+	for _, symbol := range symbols {
+		if p.routes(symbol) {
+			routed = append(routed, symbol)
+		} else {
+			own = append(own, symbol)
+		}
+	}
+	if len(routed) == 0 {
+		return p.Provider.StreamQuotes(ctx, own, ch)
+	}
+	if len(own) == 0 {
+		return p.yahoo.StreamQuotes(ctx, routed, ch)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.yahoo.StreamQuotes(ctx, routed, ch)
+	}()
+	err := p.Provider.StreamQuotes(ctx, own, ch)
+	cancel()
+	wg.Wait()
+	return err
+}
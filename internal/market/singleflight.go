@@ -0,0 +1,65 @@
+package market
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"stockmarket/internal/models"
+)
+
+// sharedQuoteTimeout bounds an upstream quote fetch shared by several callers,
+// which must not be cut short when the caller that started it goes away
+const sharedQuoteTimeout = 30 * time.Second
+
+// quoteGroup deduplicates concurrent quote fetches across all provider instances
+var quoteGroup singleflight.Group
+
+// singleflightProvider wraps a provider so concurrent GetQuote calls for the
+// same symbol share one upstream request and result, and recent quotes are
+// served from the shared quote cache
+type singleflightProvider struct {
+	Provider
+}
+
+// WithSingleflight wraps p so concurrent quote fetches for a symbol are shared
+func WithSingleflight(p Provider) Provider {
+	if _, ok := p.(singleflightProvider); ok {
+		return p
+	}
+	return singleflightProvider{p}
+}
+
+// GetQuote returns a cached quote when fresh, otherwise fetches one, joining
+// an in-flight fetch for the same symbol
+func (p singleflightProvider) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	key := p.Name() + ":" + symbol
+	if quote, ok := quotes.get(key, time.Now()); ok {
+		return quote, nil
+	}
+
+	ch := quoteGroup.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedQuoteTimeout)
+		defer cancel()
+		quote, err := p.Provider.GetQuote(fetchCtx, symbol)
+		if err == nil {
+			annotateSession(quote, time.Now())
+			annotateCurrency(quote)
+			quotes.put(key, quote, time.Now())
+		}
+		return quote, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		// Callers may modify their quote, so each gets its own copy
+		quote := *res.Val.(*models.Quote)
+		return &quote, nil
+	}
+}
@@ -0,0 +1,126 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// gatedProvider counts quote fetches, each blocking until gate is closed
+type gatedProvider struct {
+	Provider
+	name    string
+	calls   atomic.Int32
+	started chan struct{}
+	gate    chan struct{}
+	ctxErr  error // the fetch context's error once the gate opened
+}
+
+// gatedProviders numbers the fakes, whose names key the shared quote cache,
+// so repeated test runs don't get cached quotes
+var gatedProviders atomic.Int32
+
+func newGatedProvider(name string) *gatedProvider {
+	name = fmt.Sprintf("%s-%d", name, gatedProviders.Add(1))
+	return &gatedProvider{name: name, started: make(chan struct{}, 1), gate: make(chan struct{})}
+}
+
+func (p *gatedProvider) Name() string { return p.name }
+
+func (p *gatedProvider) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	p.calls.Add(1)
+	p.started <- struct{}{}
+	<-p.gate
+	p.ctxErr = ctx.Err()
+	return &models.Quote{Symbol: symbol, Price: 100}, nil
+}
+
+func TestSingleflightSharesOneFetch(t *testing.T) {
+	fake := newGatedProvider("sf-shared")
+	p := WithSingleflight(fake)
+
+	const callers = 20
+	var ready, done sync.WaitGroup
+	results := make([]*models.Quote, callers)
+	errs := make([]error, callers)
+	ready.Add(callers)
+	done.Add(callers)
+	for i := range callers {
+		go func() {
+			defer done.Done()
+			ready.Done()
+			results[i], errs[i] = p.GetQuote(context.Background(), "SHARED")
+		}()
+	}
+	ready.Wait()
+	<-fake.started
+	// Let every caller join the in-flight fetch before it completes
+	time.Sleep(50 * time.Millisecond)
+	close(fake.gate)
+	done.Wait()
+
+	if n := fake.calls.Load(); n != 1 {
+		t.Fatalf("upstream calls = %d, want 1", n)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("caller %d: %v", i, err)
+		}
+	}
+
+	// Each caller owns its quote
+	results[0].Price = 1
+	for i, q := range results[1:] {
+		if q == results[0] {
+			t.Fatalf("caller %d shares a quote pointer with caller 0", i+1)
+		}
+		if q.Price != 100 {
+			t.Fatalf("caller %d price = %v after caller 0 changed its copy", i+1, q.Price)
+		}
+	}
+}
+
+func TestSingleflightCancelledCallerKeepsFetch(t *testing.T) {
+	fake := newGatedProvider("sf-cancel")
+	p := WithSingleflight(fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := p.GetQuote(ctx, "CANCEL")
+		first <- err
+	}()
+	<-fake.started
+
+	second := make(chan *models.Quote, 1)
+	go func() {
+		quote, err := p.GetQuote(context.Background(), "CANCEL")
+		if err != nil {
+			t.Error(err)
+		}
+		second <- quote
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller err = %v, want context.Canceled", err)
+	}
+
+	close(fake.gate)
+	if quote := <-second; quote == nil || quote.Price != 100 {
+		t.Fatalf("remaining caller got %+v", quote)
+	}
+	if fake.ctxErr != nil {
+		t.Fatalf("shared fetch context was cancelled: %v", fake.ctxErr)
+	}
+	if n := fake.calls.Load(); n != 1 {
+		t.Fatalf("upstream calls = %d, want 1", n)
+	}
+}
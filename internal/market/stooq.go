@@ -0,0 +1,254 @@
+package market
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+const stooqBaseURL = "https://stooq.com"
+
+// stooqMarkets are the Stooq market suffixes recognized in symbols such as
+// "VOD.UK"; any other suffix is treated as a share class, as in "BRK.B"
+var stooqMarkets = map[string]bool{
+	"us": true, "uk": true, "de": true, "jp": true, "hk": true, "pl": true, "hu": true,
+}
+
+// Stooq implements the Provider interface with Stooq's free CSV downloads:
+// delayed quotes and daily (or longer) candles, with no API key. It is also
+// the fallback when a provider that needs a key has none configured.
+type Stooq struct {
+	client *http.Client
+}
+
+// NewStooq creates a new Stooq provider
+func NewStooq() *Stooq {
+	return &Stooq{client: sharedHTTPClient}
+}
+
+// Name returns the provider name
+func (s *Stooq) Name() string {
+	return "stooq"
+}
+
+// stooqIndices maps Yahoo index symbols to Stooq's where they differ
+var stooqIndices = map[string]string{
+	"^GSPC": "^spx",
+	"^IXIC": "^ndq",
+	"^FTSE": "^ukx",
+}
+
+// stooqSuffixes maps the Yahoo suffixes of the international listings Stooq
+// has data for to its market suffixes
+var stooqSuffixes = map[string]string{
+	"L":  "uk",
+	"DE": "de",
+	"T":  "jp",
+	"HK": "hk",
+}
+
+// covers reports whether Stooq has data for symbol: US listings, indices and
+// the markets in stooqSuffixes
+func (s *Stooq) covers(symbol string) bool {
+	suffix := ListingSuffix(symbol)
+	return suffix == "" || stooqSuffixes[suffix] != ""
+}
+
+// stooqSymbol maps a ticker to Stooq's form: lowercase with a market suffix,
+// defaulting to US listings ("AAPL" to "aapl.us", "BRK.B" to "brk-b.us")
+func stooqSymbol(symbol string) string {
+	symbol = strings.TrimSpace(symbol)
+	if index, ok := stooqIndices[strings.ToUpper(symbol)]; ok {
+		return index
+	}
+	symbol = strings.ToLower(symbol)
+	if IsIndexSymbol(symbol) {
+		return symbol // indices, e.g. ^dji
+	}
+	if base, quote, ok := ParseCurrencyPair(symbol); ok {
+		return strings.ToLower(base + quote) // currency pairs, e.g. eurusd
+	}
+	if ticker, suffix := splitListing(symbol); stooqSuffixes[suffix] != "" {
+		return strings.ToLower(ticker) + "." + stooqSuffixes[suffix] // e.g. 7203.T to 7203.jp
+	}
+	if i := strings.LastIndex(symbol, "."); i >= 0 && stooqMarkets[symbol[i+1:]] {
+		return symbol
+	}
+	return strings.ReplaceAll(symbol, ".", "-") + ".us"
+}
+
+// GetQuote fetches the delayed quote for a symbol
+func (s *Stooq) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	// Fields: symbol, date, time, open, high, low, close, volume, previous close
+	params := url.Values{"s": {stooqSymbol(symbol)}, "f": {"sd2t2ohlcvp"}, "e": {"csv"}}
+	records, err := s.getCSV(ctx, "/q/l/", params)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || len(records[0]) < 9 {
+		return nil, ErrInvalidSymbol
+	}
+
+	r := records[0]
+	price, err := strconv.ParseFloat(r[6], 64)
+	if err != nil || price == 0 {
+		// Unknown symbols come back with N/D in every field
+		return nil, ErrInvalidSymbol
+	}
+	volume, _ := strconv.ParseInt(r[7], 10, 64)
+	timestamp, _ := time.ParseInLocation("2006-01-02 15:04:05", r[1]+" "+r[2], time.UTC)
+
+	quote := &models.Quote{
+		Symbol:        symbol,
+		Price:         price,
+		Open:          parseStooqFloat(r[3]),
+		High:          parseStooqFloat(r[4]),
+		Low:           parseStooqFloat(r[5]),
+		Volume:        volume,
+		PreviousClose: parseStooqFloat(r[8]),
+		Timestamp:     timestamp,
+	}
+	if quote.PreviousClose > 0 {
+		quote.Change = price - quote.PreviousClose
+		quote.ChangePercent = quote.Change / quote.PreviousClose * 100
+	}
+	return quote, nil
+}
+
+// GetHistoricalData fetches daily, weekly or monthly candles, newest first.
+// Stooq has no free intraday data, so "1d" and "5d" return the latest daily
+// candles instead.
+func (s *Stooq) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from, to := p.Bounds(now)
+
+	// Map period to Stooq intervals (d, w, m); intraday periods keep the
+	// last n daily candles from a window wide enough to span weekends
+	interval, keep := "d", 0
+	switch p.Name {
+	case "1d":
+		from, keep = now.AddDate(0, 0, -7), 1
+	case "5d":
+		from, keep = now.AddDate(0, 0, -14), 5
+	case "5y":
+		interval = "w"
+	case PeriodMax:
+		interval = "m"
+	case "":
+		if p.Granularity(now) == GranularityWeekly {
+			interval = "w"
+		}
+	}
+
+	params := url.Values{
+		"s":  {stooqSymbol(symbol)},
+		"i":  {interval},
+		"d1": {from.Format("20060102")},
+		"d2": {to.Format("20060102")},
+	}
+	records, err := s.getCSV(ctx, "/q/d/l/", params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows are Date, Open, High, Low, Close, Volume (header first)
+	var candles []models.Candle
+	for _, r := range records {
+		if len(r) < 5 {
+			continue
+		}
+		date, err := time.ParseInLocation("2006-01-02", r[0], easternTime)
+		if err != nil {
+			continue // header row
+		}
+		var volume int64
+		if len(r) > 5 {
+			volume, _ = strconv.ParseInt(r[5], 10, 64)
+		}
+		candles = append(candles, models.Candle{
+			Timestamp: date,
+			Open:      parseStooqFloat(r[1]),
+			High:      parseStooqFloat(r[2]),
+			Low:       parseStooqFloat(r[3]),
+			Close:     parseStooqFloat(r[4]),
+			Volume:    volume,
+		})
+	}
+
+	if len(candles) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Timestamp.After(candles[j].Timestamp)
+	})
+	if keep > 0 && len(candles) > keep {
+		candles = candles[:keep]
+	}
+
+	return candles, nil
+}
+
+// getCSV downloads a CSV file; Stooq answers unknown symbols with a plain
+// "No data" body, which yields no records
+func (s *Stooq) getCSV(ctx context.Context, path string, params url.Values) ([][]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", stooqBaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return nil, ErrRateLimited
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%w: Stooq returned status %d", ErrAPIError, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "No data") {
+		return nil, nil
+	}
+	// Stooq signals an exhausted daily download limit in plain text
+	if strings.Contains(string(body), "Exceeded the daily hits limit") {
+		return nil, ErrRateLimited
+	}
+
+	reader := csv.NewReader(strings.NewReader(string(body)))
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
+
+// StreamQuotes streams delayed quotes by polling
+func (s *Stooq) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return pollQuotes(ctx, s, symbols, ch, 30*time.Second) // Quotes are delayed anyway
+}
+
+// parseStooqFloat parses a CSV number, returning 0 for N/D
+func parseStooqFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
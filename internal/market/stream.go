@@ -0,0 +1,129 @@
+package market
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// Dropped streaming connections are retried with a doubling backoff capped at
+// maxStreamReconnect; a session that stayed up that long resets it
+const (
+	minStreamReconnect = time.Second
+	maxStreamReconnect = time.Minute
+)
+
+// streamWithReconnect runs session until ctx is done, reconnecting with
+// backoff whenever it returns. name prefixes the log messages.
+func streamWithReconnect(ctx context.Context, name string, session func(ctx context.Context) error) error {
+	backoff := minStreamReconnect
+	for {
+		start := time.Now()
+		err := session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Since(start) > maxStreamReconnect {
+			backoff = minStreamReconnect
+		}
+		log.Printf("[%s] Stream disconnected (%v), reconnecting in %s", name, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, maxStreamReconnect)
+	}
+}
+
+// streamProvider wraps a provider's REST calls made for a stream with its
+// shared rate limit, circuit breaker and quote cache, so polling and seeding
+// queue behind the same request budget as every other caller and share
+// their fetches with other clients
+func streamProvider(p Provider) Provider {
+	return WithSingleflight(WithCircuitBreaker(WithRateLimit(p)))
+}
+
+// pollQuotes streams quotes for providers without a push API by fetching each
+// symbol every interval. Symbols that cannot be quoted are skipped, and a
+// round stops early while the provider's circuit is open or its queue is full.
+func pollQuotes(ctx context.Context, provider Provider, symbols []string, ch chan<- models.Quote, interval time.Duration) error {
+	provider = streamProvider(provider)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			for _, symbol := range symbols {
+				quote, err := provider.GetQuote(ctx, symbol)
+				if Rejected(err) {
+					break
+				}
+				if err != nil {
+					continue
+				}
+				select {
+				case ch <- *quote:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+// seedQuotes fetches a REST quote for each symbol and sends it on ch, so
+// streams that only carry trade prices have a full quote to update. Symbols
+// that cannot be quoted are skipped, and seeding stops early while the
+// provider's circuit is open or its queue is full.
+func seedQuotes(ctx context.Context, provider Provider, symbols []string, ch chan<- models.Quote) (map[string]*models.Quote, error) {
+	provider = streamProvider(provider)
+	quotes := make(map[string]*models.Quote, len(symbols))
+	for _, symbol := range symbols {
+		quote, err := provider.GetQuote(ctx, symbol)
+		if Rejected(err) {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		quotes[symbol] = quote
+		select {
+		case ch <- *quote:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return quotes, nil
+}
+
+// applyTrade updates a quote with a streamed trade price. As in REST quotes,
+// a trade outside the regular session moves the extended-hours price and the
+// price stays at the regular close; the session follows the trade time.
+func applyTrade(quote *models.Quote, price float64, t time.Time) {
+	quote.Timestamp = t
+	quote.Session = ExchangeState(SymbolExchange(quote.Symbol), t)
+	quote.ExtendedChangePercent = 0
+	if IsExtendedSession(quote.Session) {
+		quote.ExtendedPrice = price
+		annotateSession(quote, t)
+		return
+	}
+	quote.ExtendedPrice = 0
+
+	quote.Price = price
+	quote.High = max(quote.High, price)
+	if quote.Low == 0 || price < quote.Low {
+		quote.Low = price
+	}
+	if quote.PreviousClose > 0 {
+		quote.Change = price - quote.PreviousClose
+		quote.ChangePercent = quote.Change / quote.PreviousClose * 100
+	}
+}
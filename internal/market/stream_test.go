@@ -0,0 +1,110 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// countingProvider counts quote fetches and answers each with a fixed quote
+type countingProvider struct {
+	Provider
+	name  string
+	calls atomic.Int32
+}
+
+// countingProviders numbers the fakes, whose names key the shared rate
+// limits, circuit breakers and quote cache
+var countingProviders atomic.Int32
+
+func newCountingProvider(name string) *countingProvider {
+	return &countingProvider{name: fmt.Sprintf("%s-%d", name, countingProviders.Add(1))}
+}
+
+func (p *countingProvider) Name() string { return p.name }
+
+func (p *countingProvider) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	p.calls.Add(1)
+	return &models.Quote{Symbol: symbol, Price: 100}, nil
+}
+
+// limitProvider gives a fake provider a rate limit for the test
+func limitProvider(t *testing.T, name string, limit RateLimit) {
+	t.Helper()
+	providerRateLimits[name] = limit
+	t.Cleanup(func() { delete(providerRateLimits, name) })
+}
+
+func TestSeedQuotesRateLimited(t *testing.T) {
+	fake := newCountingProvider("seed-limited")
+	limitProvider(t, fake.name, RateLimit{Requests: 1, Per: time.Hour, Burst: 2})
+
+	ch := make(chan models.Quote, 3)
+	quotes, err := seedQuotes(context.Background(), fake, []string{"AAA", "BBB", "CCC"}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.calls.Load(); n != 2 {
+		t.Fatalf("upstream calls = %d, want the burst of 2", n)
+	}
+	if len(quotes) != 2 || len(ch) != 2 {
+		t.Fatalf("seeded %d quotes and sent %d, want 2", len(quotes), len(ch))
+	}
+}
+
+func TestStreamQuotesShareCache(t *testing.T) {
+	fake := newCountingProvider("poll-cached")
+	symbols := []string{"AAA", "BBB"}
+
+	// Two clients seeding the same symbols share the cached quotes
+	for range 2 {
+		ch := make(chan models.Quote, len(symbols))
+		if _, err := seedQuotes(context.Background(), fake, symbols, ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan models.Quote)
+	go pollQuotes(ctx, fake, symbols, ch, time.Millisecond)
+	for range symbols {
+		<-ch
+	}
+
+	if n := fake.calls.Load(); n != int32(len(symbols)) {
+		t.Fatalf("upstream calls = %d, want one per symbol", n)
+	}
+}
+
+func TestStreamedQuotesHaveSession(t *testing.T) {
+	fake := newCountingProvider("session")
+	ch := make(chan models.Quote, 1)
+	quotes, err := seedQuotes(context.Background(), fake, []string{"AAPL"}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seeded := <-ch; seeded.Session == "" {
+		t.Fatal("seeded quote has no session")
+	}
+
+	quote := quotes["AAPL"]
+	quote.PreviousClose = 90
+	preMarket := time.Date(2026, 3, 10, 8, 0, 0, 0, easternTime)
+	applyTrade(quote, 110, preMarket)
+	if quote.Session != MarketStatePreMarket || quote.ExtendedPrice != 110 || quote.Price != 100 {
+		t.Fatalf("pre-market trade: session %q, extended %v, price %v", quote.Session, quote.ExtendedPrice, quote.Price)
+	}
+	if quote.ExtendedChangePercent != 10 {
+		t.Fatalf("extended change = %v%%, want 10%%", quote.ExtendedChangePercent)
+	}
+
+	applyTrade(quote, 99, preMarket.Add(2*time.Hour))
+	if quote.Session != MarketStateOpen || quote.ExtendedPrice != 0 || quote.Price != 99 || quote.Change != 9 {
+		t.Fatalf("regular trade: session %q, extended %v, price %v, change %v", quote.Session, quote.ExtendedPrice, quote.Price, quote.Change)
+	}
+}
@@ -0,0 +1,226 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+const tiingoBaseURL = "https://api.tiingo.com"
+
+// tiingoDateLayout is the date format of Tiingo's startDate/endDate parameters
+const tiingoDateLayout = "2006-01-02"
+
+// Tiingo implements the Provider interface for the Tiingo API: IEX for quotes
+// and intraday candles, adjusted end-of-day prices for daily and longer
+type Tiingo struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewTiingo creates a new Tiingo provider
+func NewTiingo(apiKey string) *Tiingo {
+	return &Tiingo{
+		apiKey: apiKey,
+		client: sharedHTTPClient,
+	}
+}
+
+// Name returns the provider name
+func (t *Tiingo) Name() string {
+	return "tiingo"
+}
+
+// GetQuote fetches the current IEX quote for a symbol
+func (t *Tiingo) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	var result []struct {
+		Ticker    string    `json:"ticker"`
+		Timestamp time.Time `json:"timestamp"`
+		Last      float64   `json:"last"`
+		TngoLast  float64   `json:"tngoLast"` // last sale, or mid price outside market hours
+		Open      float64   `json:"open"`
+		High      float64   `json:"high"`
+		Low       float64   `json:"low"`
+		PrevClose float64   `json:"prevClose"`
+		Volume    int64     `json:"volume"`
+	}
+	if err := t.get(ctx, "/iex/"+url.PathEscape(symbol), nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	q := result[0]
+	price := q.TngoLast
+	if price == 0 {
+		price = q.Last
+	}
+	if price == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	var change, changePercent float64
+	if q.PrevClose > 0 {
+		change = price - q.PrevClose
+		changePercent = change / q.PrevClose * 100
+	}
+
+	return &models.Quote{
+		Symbol:        symbol,
+		Price:         price,
+		Open:          q.Open,
+		High:          q.High,
+		Low:           q.Low,
+		Volume:        q.Volume,
+		PreviousClose: q.PrevClose,
+		Change:        change,
+		ChangePercent: changePercent,
+		Timestamp:     q.Timestamp,
+	}, nil
+}
+
+// GetHistoricalData fetches historical OHLCV data, newest first. Intraday
+// periods come from IEX; daily and longer use split/dividend adjusted prices.
+func (t *Tiingo) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from, to := p.Bounds(now)
+
+	// Map period to IEX intraday or end-of-day resample frequencies
+	intraday := ""
+	daily := "daily"
+	switch p.Name {
+	case "1d":
+		intraday = "5min"
+	case "5d":
+		intraday = "15min"
+	case "5y":
+		daily = "weekly"
+	case PeriodMax:
+		daily = "monthly"
+	case "":
+		switch p.Granularity(now) {
+		case GranularityIntraday:
+			intraday = "15min"
+		case GranularityWeekly:
+			daily = "weekly"
+		}
+	}
+
+	params := url.Values{}
+	params.Set("startDate", from.Format(tiingoDateLayout))
+	params.Set("endDate", to.Format(tiingoDateLayout))
+
+	var candles []models.Candle
+	if intraday != "" {
+		params.Set("resampleFreq", intraday)
+		params.Set("columns", "open,high,low,close,volume")
+		var result []struct {
+			Date   time.Time `json:"date"`
+			Open   float64   `json:"open"`
+			High   float64   `json:"high"`
+			Low    float64   `json:"low"`
+			Close  float64   `json:"close"`
+			Volume float64   `json:"volume"`
+		}
+		if err := t.get(ctx, "/iex/"+url.PathEscape(symbol)+"/prices", params, &result); err != nil {
+			return nil, err
+		}
+		for _, r := range result {
+			candles = append(candles, models.Candle{
+				Timestamp: r.Date,
+				Open:      r.Open,
+				High:      r.High,
+				Low:       r.Low,
+				Close:     r.Close,
+				Volume:    int64(r.Volume),
+			})
+		}
+		// Drop bars before the window (IEX works in whole days)
+		candles = filterCandles(candles, from, now)
+	} else {
+		params.Set("resampleFreq", daily)
+		var result []struct {
+			Date      time.Time `json:"date"`
+			AdjOpen   float64   `json:"adjOpen"`
+			AdjHigh   float64   `json:"adjHigh"`
+			AdjLow    float64   `json:"adjLow"`
+			AdjClose  float64   `json:"adjClose"`
+			AdjVolume int64     `json:"adjVolume"`
+		}
+		if err := t.get(ctx, "/tiingo/daily/"+url.PathEscape(symbol)+"/prices", params, &result); err != nil {
+			return nil, err
+		}
+		for _, r := range result {
+			candles = append(candles, models.Candle{
+				Timestamp: r.Date,
+				Open:      r.AdjOpen,
+				High:      r.AdjHigh,
+				Low:       r.AdjLow,
+				Close:     r.AdjClose,
+				Volume:    r.AdjVolume,
+			})
+		}
+	}
+
+	if len(candles) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Sort by timestamp (newest first)
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Timestamp.After(candles[j].Timestamp)
+	})
+
+	return candles, nil
+}
+
+// get performs an authenticated GET request and decodes the JSON response
+func (t *Tiingo) get(ctx context.Context, path string, params url.Values, out any) error {
+	u := tiingoBaseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	// The token goes in a header so it never shows up in logged URLs
+	req.Header.Set("Authorization", "Token "+t.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrInvalidSymbol
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return fmt.Errorf("%w: tiingo returned status %d", ErrAPIError, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StreamQuotes streams quotes by polling the IEX endpoint
+func (t *Tiingo) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return pollQuotes(ctx, t, symbols, ch, 10*time.Second)
+}
@@ -0,0 +1,379 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"stockmarket/internal/market/resample"
+	"stockmarket/internal/models"
+)
+
+const (
+	twelveDataBaseURL   = "https://api.twelvedata.com"
+	twelveDataStreamURL = "wss://ws.twelvedata.com/v1/quotes/price"
+)
+
+// Twelve Data caps time_series responses at 5000 values
+const twelveDataMaxOutputSize = 5000
+
+// twelveDataHeartbeat is how often the stream expects a heartbeat message
+const twelveDataHeartbeat = 10 * time.Second
+
+// TwelveData implements the Provider interface for the Twelve Data API, with
+// real-time prices streamed over its WebSocket
+type TwelveData struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewTwelveData creates a new Twelve Data provider
+func NewTwelveData(apiKey string) *TwelveData {
+	return &TwelveData{
+		apiKey: apiKey,
+		client: sharedHTTPClient,
+	}
+}
+
+// Name returns the provider name
+func (td *TwelveData) Name() string {
+	return "twelvedata"
+}
+
+// twelveDataError is the error body Twelve Data returns, often with status 200
+type twelveDataError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// err maps an error body to the provider errors
+func (e twelveDataError) err() error {
+	if e.Status != "error" {
+		return nil
+	}
+	switch {
+	case e.Code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.Code == http.StatusNotFound, strings.Contains(strings.ToLower(e.Message), "symbol"):
+		return ErrInvalidSymbol
+	default:
+		return fmt.Errorf("%w: %s", ErrAPIError, e.Message)
+	}
+}
+
+// twelveDataSymbol maps currency pairs such as EURUSD=X to Twelve Data's
+// EUR/USD form
+func twelveDataSymbol(symbol string) string {
+	if base, quote, ok := ParseCurrencyPair(symbol); ok {
+		return base + "/" + quote
+	}
+	return symbol
+}
+
+// GetQuote fetches the current quote for a symbol
+func (td *TwelveData) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	var result struct {
+		twelveDataError
+		Timestamp     int64  `json:"timestamp"`
+		Open          string `json:"open"`
+		High          string `json:"high"`
+		Low           string `json:"low"`
+		Close         string `json:"close"`
+		Volume        string `json:"volume"`
+		PreviousClose string `json:"previous_close"`
+		Change        string `json:"change"`
+		PercentChange string `json:"percent_change"`
+		Currency      string `json:"currency"`
+	}
+	if err := td.get(ctx, "/quote", url.Values{"symbol": {twelveDataSymbol(symbol)}}, &result); err != nil {
+		return nil, err
+	}
+	if err := result.err(); err != nil {
+		return nil, err
+	}
+
+	price := parseTwelveDataFloat(result.Close)
+	if price == 0 {
+		return nil, ErrInvalidSymbol
+	}
+	volume, _ := strconv.ParseInt(result.Volume, 10, 64)
+
+	return &models.Quote{
+		Symbol:        symbol,
+		Price:         price,
+		Open:          parseTwelveDataFloat(result.Open),
+		High:          parseTwelveDataFloat(result.High),
+		Low:           parseTwelveDataFloat(result.Low),
+		Volume:        volume,
+		PreviousClose: parseTwelveDataFloat(result.PreviousClose),
+		Change:        parseTwelveDataFloat(result.Change),
+		ChangePercent: parseTwelveDataFloat(result.PercentChange),
+		Timestamp:     time.Unix(result.Timestamp, 0),
+		Currency:      result.Currency,
+	}, nil
+}
+
+// GetHistoricalData fetches historical OHLCV data from time_series, newest first
+func (td *TwelveData) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from, to := p.Bounds(now)
+
+	// Map period to Twelve Data intervals
+	interval := "1day"
+	switch p.Name {
+	case "1d":
+		interval = "5min"
+	case "5d":
+		interval = "15min"
+	case "5y":
+		interval = "1week"
+	case PeriodMax:
+		interval = "1month"
+	case "":
+		switch p.Granularity(now) {
+		case GranularityIntraday:
+			interval = "15min"
+		case GranularityWeekly:
+			interval = "1week"
+		}
+	}
+
+	return td.timeSeries(ctx, symbol, interval, from, to)
+}
+
+// twelveDataIntervals maps canonical intervals to Twelve Data intervals
+var twelveDataIntervals = map[string]string{
+	resample.Interval1m:  "1min",
+	resample.Interval5m:  "5min",
+	resample.Interval15m: "15min",
+	resample.Interval30m: "30min",
+	resample.Interval1h:  "1h",
+	resample.Interval1d:  "1day",
+	resample.Interval1w:  "1week",
+}
+
+// GetHistoricalDataInterval fetches candles of the given interval for period
+func (td *TwelveData) GetHistoricalDataInterval(ctx context.Context, symbol, period, interval string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	tdInterval, ok := twelveDataIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", resample.ErrInvalidInterval, interval)
+	}
+	from, to := p.Bounds(time.Now())
+	return td.timeSeries(ctx, symbol, tdInterval, from, to)
+}
+
+// timeSeries fetches candles between from and to, newest first
+func (td *TwelveData) timeSeries(ctx context.Context, symbol, interval string, from, to time.Time) ([]models.Candle, error) {
+	params := url.Values{
+		"symbol":     {twelveDataSymbol(symbol)},
+		"interval":   {interval},
+		"outputsize": {strconv.Itoa(twelveDataMaxOutputSize)},
+		"start_date": {from.In(easternTime).Format("2006-01-02 15:04:05")},
+		"end_date":   {to.In(easternTime).Format("2006-01-02 15:04:05")},
+		"timezone":   {"America/New_York"},
+	}
+
+	var result struct {
+		twelveDataError
+		Values []struct {
+			Datetime string `json:"datetime"`
+			Open     string `json:"open"`
+			High     string `json:"high"`
+			Low      string `json:"low"`
+			Close    string `json:"close"`
+			Volume   string `json:"volume"`
+		} `json:"values"`
+	}
+	if err := td.get(ctx, "/time_series", params, &result); err != nil {
+		return nil, err
+	}
+	if err := result.err(); err != nil {
+		return nil, err
+	}
+	if len(result.Values) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	// Values are newest first, with datetimes in exchange time
+	candles := make([]models.Candle, 0, len(result.Values))
+	for _, v := range result.Values {
+		layout := "2006-01-02"
+		if strings.Contains(v.Datetime, " ") {
+			layout = "2006-01-02 15:04:05"
+		}
+		timestamp, err := time.ParseInLocation(layout, v.Datetime, easternTime)
+		if err != nil {
+			continue
+		}
+		volume, _ := strconv.ParseInt(v.Volume, 10, 64)
+
+		candles = append(candles, models.Candle{
+			Timestamp: timestamp,
+			Open:      parseTwelveDataFloat(v.Open),
+			High:      parseTwelveDataFloat(v.High),
+			Low:       parseTwelveDataFloat(v.Low),
+			Close:     parseTwelveDataFloat(v.Close),
+			Volume:    volume,
+		})
+	}
+
+	return candles, nil
+}
+
+// get performs a GET request against the REST API and decodes the JSON response
+func (td *TwelveData) get(ctx context.Context, path string, params url.Values, out any) error {
+	params.Set("apikey", td.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", twelveDataBaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := td.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return ErrRateLimited
+	}
+	if resp.StatusCode != 200 {
+		return ErrAPIError
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StreamQuotes streams real-time prices over the Twelve Data WebSocket. Each
+// symbol is seeded with a REST quote, and streamed prices update its price,
+// change and day range. Dropped connections are retried until ctx is done.
+func (td *TwelveData) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	quotes, err := seedQuotes(ctx, td, symbols, ch)
+	if err != nil {
+		return err
+	}
+
+	// Subscribe with Twelve Data symbols such as EUR/USD and map them back
+	// to the symbols as tracked
+	subscribed := make([]string, 0, len(symbols))
+	bySymbol := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		subscribed = append(subscribed, twelveDataSymbol(symbol))
+		bySymbol[twelveDataSymbol(symbol)] = symbol
+	}
+
+	return streamWithReconnect(ctx, "TWELVEDATA", func(ctx context.Context) error {
+		return td.stream(ctx, subscribed, bySymbol, quotes, ch)
+	})
+}
+
+// stream runs a single WebSocket session until it fails or ctx is done
+func (td *TwelveData) stream(ctx context.Context, symbols []string, bySymbol map[string]string, quotes map[string]*models.Quote, ch chan<- models.Quote) error {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	conn, _, err := dialer.DialContext(ctx, twelveDataStreamURL+"?apikey="+url.QueryEscape(td.apiKey), nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Close the connection when ctx is done to unblock ReadJSON
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	subscribe := map[string]any{
+		"action": "subscribe",
+		"params": map[string]string{"symbols": strings.Join(symbols, ",")},
+	}
+	if err := conn.WriteJSON(subscribe); err != nil {
+		return err
+	}
+
+	// Heartbeats share the connection with nothing else that writes, so they
+	// need no locking
+	go func() {
+		ticker := time.NewTicker(twelveDataHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.WriteJSON(map[string]string{"action": "heartbeat"}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var event struct {
+			Event     string  `json:"event"`
+			Symbol    string  `json:"symbol"`
+			Price     float64 `json:"price"`
+			Timestamp int64   `json:"timestamp"`
+			DayVolume int64   `json:"day_volume"`
+			Message   string  `json:"message"`
+		}
+		if err := conn.ReadJSON(&event); err != nil {
+			return err
+		}
+
+		switch event.Event {
+		case "price":
+		case "subscribe-status":
+			continue
+		default:
+			if event.Message != "" {
+				log.Printf("[TWELVEDATA] %s: %s", event.Event, event.Message)
+			}
+			continue
+		}
+
+		quote, ok := quotes[bySymbol[event.Symbol]]
+		if !ok || event.Price <= 0 {
+			continue
+		}
+		applyTrade(quote, event.Price, time.Unix(event.Timestamp, 0))
+		if event.DayVolume > 0 {
+			quote.Volume = event.DayVolume
+		}
+
+		select {
+		case ch <- *quote:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// parseTwelveDataFloat parses the string-encoded numbers Twelve Data returns
+func parseTwelveDataFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
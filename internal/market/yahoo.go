@@ -5,13 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
+	"stockmarket/internal/market/resample"
 	"stockmarket/internal/models"
 )
 
 const yahooBaseURL = "https://query1.finance.yahoo.com/v8/finance"
 
+const yahooSearchURL = "https://query2.finance.yahoo.com/v1/finance/search"
+
+// yahooRecommendationsURL lists symbols similar to a symbol
+const yahooRecommendationsURL = "https://query2.finance.yahoo.com/v6/finance/recommendationsbysymbol"
+
 // YahooFinance implements the Provider interface for Yahoo Finance API
 type YahooFinance struct {
 	client *http.Client
@@ -29,9 +36,10 @@ func (yf *YahooFinance) Name() string {
 	return "yahoo"
 }
 
-// GetQuote fetches the current quote for a symbol
+// GetQuote fetches the current quote for a symbol; the minute bars include
+// pre- and post-market trading so the latest extended-hours trade is reported
 func (yf *YahooFinance) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
-	url := fmt.Sprintf("%s/chart/%s?interval=1m&range=1d", yahooBaseURL, symbol)
+	url := fmt.Sprintf("%s/chart/%s?interval=1m&range=1d&includePrePost=true", yahooBaseURL, symbol)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -63,7 +71,20 @@ func (yf *YahooFinance) GetQuote(ctx context.Context, symbol string) (*models.Qu
 					RegularMarketDayLow  float64 `json:"regularMarketDayLow"`
 					RegularMarketVolume  int64   `json:"regularMarketVolume"`
 					RegularMarketOpen    float64 `json:"regularMarketOpen"`
+					Currency             string  `json:"currency"`
+					CurrentTradingPeriod struct {
+						Regular struct {
+							Start int64 `json:"start"`
+							End   int64 `json:"end"`
+						} `json:"regular"`
+					} `json:"currentTradingPeriod"`
 				} `json:"meta"`
+				Timestamp  []int64 `json:"timestamp"`
+				Indicators struct {
+					Quote []struct {
+						Close []*float64 `json:"close"`
+					} `json:"quote"`
+				} `json:"indicators"`
 			} `json:"result"`
 			Error *struct {
 				Code        string `json:"code"`
@@ -91,6 +112,24 @@ func (yf *YahooFinance) GetQuote(ctx context.Context, symbol string) (*models.Qu
 	change := meta.RegularMarketPrice - meta.PreviousClose
 	changePercent := (change / meta.PreviousClose) * 100
 
+	// The last minute bar outside the regular session is the latest
+	// pre-market or after-hours trade
+	extendedPrice := 0.0
+	data := result.Chart.Result[0]
+	if len(data.Indicators.Quote) > 0 {
+		closes := data.Indicators.Quote[0].Close
+		regular := meta.CurrentTradingPeriod.Regular
+		for i := min(len(data.Timestamp), len(closes)) - 1; i >= 0; i-- {
+			if closes[i] == nil {
+				continue
+			}
+			if ts := data.Timestamp[i]; ts < regular.Start || ts >= regular.End {
+				extendedPrice = *closes[i]
+			}
+			break
+		}
+	}
+
 	return &models.Quote{
 		Symbol:        symbol,
 		Price:         meta.RegularMarketPrice,
@@ -102,16 +141,28 @@ func (yf *YahooFinance) GetQuote(ctx context.Context, symbol string) (*models.Qu
 		Change:        change,
 		ChangePercent: changePercent,
 		Timestamp:     time.Unix(meta.RegularMarketTime, 0),
+		ExtendedPrice: extendedPrice,
+		Currency:      meta.Currency,
 	}, nil
 }
 
 // GetHistoricalData fetches historical OHLCV data
 func (yf *YahooFinance) GetHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
-	// Map period to Yahoo Finance parameters
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	window, interval := yahooHistoryWindow(p)
+	return yf.chart(ctx, symbol, interval, window, false)
+}
+
+// yahooHistoryWindow maps a period to the chart window and the interval
+// Yahoo Finance serves it at
+func yahooHistoryWindow(p Period) (window, interval string) {
 	range_ := "1mo"
-	interval := "1d"
+	interval = "1d"
 
-	switch period {
+	switch p.Name {
 	case "1d":
 		range_ = "1d"
 		interval = "5m"
@@ -130,9 +181,85 @@ func (yf *YahooFinance) GetHistoricalData(ctx context.Context, symbol string, pe
 	case "5y":
 		range_ = "5y"
 		interval = "1wk"
+	case PeriodYTD:
+		range_ = "ytd"
+		interval = "1d"
+	case PeriodMax:
+		range_ = "max"
+		interval = "1mo"
+	}
+
+	window = "range=" + range_
+	if p.IsCustom() {
+		now := time.Now()
+		from, to := p.Bounds(now)
+		window = fmt.Sprintf("period1=%d&period2=%d", from.Unix(), to.Unix())
+		switch p.Granularity(now) {
+		case GranularityIntraday:
+			interval = "15m"
+		case GranularityWeekly:
+			interval = "1wk"
+		}
+	}
+	return window, interval
+}
+
+// GetAdjustedHistoricalData fetches candles for period with prices adjusted
+// for splits and dividends using the adjclose series
+func (yf *YahooFinance) GetAdjustedHistoricalData(ctx context.Context, symbol string, period string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	window, interval := yahooHistoryWindow(p)
+	return yf.chart(ctx, symbol, interval, window, true)
+}
+
+// yahooIntervals maps canonical intervals to Yahoo Finance chart intervals
+var yahooIntervals = map[string]string{
+	resample.Interval1m:  "1m",
+	resample.Interval5m:  "5m",
+	resample.Interval15m: "15m",
+	resample.Interval30m: "30m",
+	resample.Interval1h:  "60m",
+	resample.Interval1d:  "1d",
+	resample.Interval1w:  "1wk",
+}
+
+// yahooIntervalLookback is how far back Yahoo Finance keeps intraday candles
+var yahooIntervalLookback = map[string]time.Duration{
+	resample.Interval1m:  7 * 24 * time.Hour,
+	resample.Interval5m:  intradayHistoryLimit,
+	resample.Interval15m: intradayHistoryLimit,
+	resample.Interval30m: intradayHistoryLimit,
+	resample.Interval1h:  730 * 24 * time.Hour,
+}
+
+// GetHistoricalDataInterval fetches candles of the given interval for period
+func (yf *YahooFinance) GetHistoricalDataInterval(ctx context.Context, symbol, period, interval string) ([]models.Candle, error) {
+	p, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	yahooInterval, ok := yahooIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", resample.ErrInvalidInterval, interval)
 	}
 
-	url := fmt.Sprintf("%s/chart/%s?interval=%s&range=%s", yahooBaseURL, symbol, interval, range_)
+	now := time.Now()
+	from, to := p.Bounds(now)
+	if lookback, ok := yahooIntervalLookback[interval]; ok && now.Sub(from) > lookback {
+		return nil, fmt.Errorf("%w: %s candles only go back %d days", ErrIntervalUnavailable, interval, int(lookback.Hours()/24))
+	}
+	window := fmt.Sprintf("period1=%d&period2=%d", from.Unix(), to.Unix())
+
+	return yf.chart(ctx, symbol, yahooInterval, window, false)
+}
+
+// chart fetches candles from the chart endpoint, newest first; adjusted
+// scales them to the adjclose series, which daily and longer intervals have
+func (yf *YahooFinance) chart(ctx context.Context, symbol, interval, window string, adjusted bool) ([]models.Candle, error) {
+	url := fmt.Sprintf("%s/chart/%s?interval=%s&%s", yahooBaseURL, symbol, interval, window)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -162,6 +289,9 @@ func (yf *YahooFinance) GetHistoricalData(ctx context.Context, symbol string, pe
 						Close  []float64 `json:"close"`
 						Volume []int64   `json:"volume"`
 					} `json:"quote"`
+					AdjClose []struct {
+						AdjClose []float64 `json:"adjclose"`
+					} `json:"adjclose"`
 				} `json:"indicators"`
 			} `json:"result"`
 			Error *struct {
@@ -191,14 +321,18 @@ func (yf *YahooFinance) GetHistoricalData(ctx context.Context, symbol string, pe
 			break
 		}
 
-		candles = append(candles, models.Candle{
+		candle := models.Candle{
 			Timestamp: time.Unix(r.Timestamp[i], 0),
 			Open:      q.Open[i],
 			High:      q.High[i],
 			Low:       q.Low[i],
 			Close:     q.Close[i],
 			Volume:    q.Volume[i],
-		})
+		}
+		if adjusted && len(r.Indicators.AdjClose) > 0 && i < len(r.Indicators.AdjClose[0].AdjClose) {
+			adjustCandle(&candle, r.Indicators.AdjClose[0].AdjClose[i])
+		}
+		candles = append(candles, candle)
 	}
 
 	// Reverse to get newest first
@@ -209,27 +343,223 @@ func (yf *YahooFinance) GetHistoricalData(ctx context.Context, symbol string, pe
 	return candles, nil
 }
 
-// StreamQuotes streams real-time quotes via polling
-func (yf *YahooFinance) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			for _, symbol := range symbols {
-				quote, err := yf.GetQuote(ctx, symbol)
-				if err != nil {
-					continue
-				}
-				select {
-				case ch <- *quote:
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
+// GetCompanyProfile returns the name, exchange and currency from the chart
+// metadata; the keyless endpoints do not include sector or valuation data
+func (yf *YahooFinance) GetCompanyProfile(ctx context.Context, symbol string) (*models.CompanyProfile, error) {
+	url := fmt.Sprintf("%s/chart/%s?interval=1d&range=1d", yahooBaseURL, symbol)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	resp, err := yf.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, ErrInvalidSymbol
+	}
+	if resp.StatusCode != 200 {
+		return nil, ErrAPIError
+	}
+
+	var result struct {
+		Chart struct {
+			Result []struct {
+				Meta struct {
+					LongName         string `json:"longName"`
+					ShortName        string `json:"shortName"`
+					FullExchangeName string `json:"fullExchangeName"`
+					Currency         string `json:"currency"`
+					InstrumentType   string `json:"instrumentType"`
+				} `json:"meta"`
+			} `json:"result"`
+		} `json:"chart"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Chart.Result) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	meta := result.Chart.Result[0].Meta
+	name := meta.LongName
+	if name == "" {
+		name = meta.ShortName
+	}
+	return &models.CompanyProfile{
+		Symbol:    symbol,
+		Name:      name,
+		Exchange:  meta.FullExchangeName,
+		Currency:  meta.Currency,
+		AssetType: meta.InstrumentType,
+	}, nil
+}
+
+// SearchSymbols finds symbols via Yahoo's autocomplete search
+func (yf *YahooFinance) SearchSymbols(ctx context.Context, query string) ([]models.SymbolMatch, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("quotesCount", fmt.Sprint(maxSymbolMatches))
+	params.Set("newsCount", "0")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", yahooSearchURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	resp, err := yf.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return nil, ErrRateLimited
+	}
+	if resp.StatusCode != 200 {
+		return nil, ErrAPIError
+	}
+
+	var result struct {
+		Quotes []struct {
+			Symbol    string `json:"symbol"`
+			ShortName string `json:"shortname"`
+			LongName  string `json:"longname"`
+			Exchange  string `json:"exchDisp"`
+			QuoteType string `json:"quoteType"`
+		} `json:"quotes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	matches := make([]models.SymbolMatch, 0, len(result.Quotes))
+	for _, q := range result.Quotes {
+		// News and other non-instrument results have no symbol
+		if q.Symbol == "" {
+			continue
+		}
+		name := q.LongName
+		if name == "" {
+			name = q.ShortName
+		}
+		matches = append(matches, models.SymbolMatch{
+			Symbol:   q.Symbol,
+			Name:     name,
+			Exchange: q.Exchange,
+			Type:     q.QuoteType,
+		})
+	}
+	return matches, nil
+}
+
+// GetCompanyNews lists the headlines Yahoo's search returns for symbol that
+// were published between from and to
+func (yf *YahooFinance) GetCompanyNews(ctx context.Context, symbol string, from, to time.Time) ([]models.NewsItem, error) {
+	params := url.Values{}
+	params.Set("q", symbol)
+	params.Set("quotesCount", "0")
+	params.Set("newsCount", fmt.Sprint(maxNewsItems))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", yahooSearchURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	resp, err := yf.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return nil, ErrRateLimited
+	}
+	if resp.StatusCode != 200 {
+		return nil, ErrAPIError
+	}
+
+	var result struct {
+		News []struct {
+			Title       string `json:"title"`
+			Publisher   string `json:"publisher"`
+			Link        string `json:"link"`
+			PublishTime int64  `json:"providerPublishTime"`
+		} `json:"news"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	items := make([]models.NewsItem, 0, len(result.News))
+	for _, n := range result.News {
+		published := time.Unix(n.PublishTime, 0)
+		if n.Title == "" || published.Before(from) || published.After(to) {
+			continue
 		}
+		items = append(items, models.NewsItem{
+			Headline:    n.Title,
+			Source:      n.Publisher,
+			URL:         n.Link,
+			PublishedAt: published,
+		})
+	}
+	return items, nil
+}
+
+// GetPeers lists the symbols Yahoo Finance recommends as similar to symbol
+func (yf *YahooFinance) GetPeers(ctx context.Context, symbol string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", yahooRecommendationsURL+"/"+url.PathEscape(symbol), nil)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	resp, err := yf.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 429 {
+		return nil, ErrRateLimited
+	}
+	if resp.StatusCode != 200 {
+		return nil, ErrAPIError
+	}
+
+	var result struct {
+		Finance struct {
+			Result []struct {
+				RecommendedSymbols []struct {
+					Symbol string `json:"symbol"`
+				} `json:"recommendedSymbols"`
+			} `json:"result"`
+		} `json:"finance"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Finance.Result) == 0 {
+		return nil, ErrInvalidSymbol
+	}
+
+	var peers []string
+	for _, s := range result.Finance.Result[0].RecommendedSymbols {
+		peers = append(peers, s.Symbol)
+	}
+	return peers, nil
+}
+
+// StreamQuotes streams real-time quotes via polling
+func (yf *YahooFinance) StreamQuotes(ctx context.Context, symbols []string, ch chan<- models.Quote) error {
+	return pollQuotes(ctx, yf, symbols, ch, 10*time.Second)
 }
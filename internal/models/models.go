@@ -1,31 +1,64 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // UserConfig holds all user configuration settings
 type UserConfig struct {
 	ID                   int64                `json:"id"`
-	MarketDataProvider   string               `json:"market_data_provider"` // "alphavantage" | "yahoo" | "finnhub"
+	MarketDataProvider   string               `json:"market_data_provider"` // "alphavantage" | "yahoo" | "stooq" | "finnhub" | "tiingo" | "twelvedata" | "iex" | "alpaca" | "binance" | "coinbase" | "eodhd" | "demo"
 	MarketDataAPIKey     string               `json:"market_data_api_key"`  // encrypted at rest
-	AIProvider           string               `json:"ai_provider"`          // "openai" | "claude" | "gemini"
+	AIProvider           string               `json:"ai_provider"`          // "openai" | "claude" | "gemini" | "groq" | "mistral" | "azure-openai" | "deepseek"
 	AIProviderAPIKey     string               `json:"ai_provider_api_key"`  // encrypted at rest
-	AIModel              string               `json:"ai_model"`             // e.g., "gpt-4o", "claude-sonnet"
-	RiskTolerance        string               `json:"risk_tolerance"`       // "conservative" | "moderate" | "aggressive"
-	TradeFrequency       string               `json:"trade_frequency"`      // "daily" | "weekly" | "swing"
-	TrackedSymbols       []string             `json:"tracked_symbols"`      // e.g., ["AAPL", "GOOGL", "MSFT"]
-	PollingInterval      int                  `json:"polling_interval"`     // in seconds, default 30
+	AIModel              string               `json:"ai_model"`             // e.g., "gpt-4o", "claude-sonnet"; the deployment name for Azure OpenAI
+	FallbackAIProvider   string               `json:"fallback_ai_provider"` // optional, "" disables fallback
+	FallbackAIModel      string               `json:"fallback_ai_model"`
+	FallbackAIAPIKey     string               `json:"fallback_ai_api_key"`    // encrypted at rest
+	AzureOpenAIEndpoint  string               `json:"azure_openai_endpoint"`  // resource URL used by the azure-openai provider
+	RiskTolerance        string               `json:"risk_tolerance"`         // "conservative" | "moderate" | "aggressive"
+	TradeFrequency       string               `json:"trade_frequency"`        // "daily" | "weekly" | "swing"
+	TrackedSymbols       []string             `json:"tracked_symbols"`        // e.g., ["AAPL", "GOOGL", "MSFT"]
+	PollingInterval      int                  `json:"polling_interval"`       // in seconds, default 30
+	MinStoreConfidence   float64              `json:"min_store_confidence"`   // 0.0 - 1.0, analyses below are returned but not saved
+	StaleQuoteMinutes    int                  `json:"stale_quote_minutes"`    // quotes older than this mark an analysis as stale, default 15
+	ExtendedHoursAlerts  bool                 `json:"extended_hours_alerts"`  // evaluate price alerts on pre-market and after-hours prices
+	DisplayCurrency      string               `json:"display_currency"`       // ISO code watchlist prices are converted to, default "USD"
+	SymbolExchanges      map[string]string    `json:"symbol_exchanges"`       // exchange overrides per symbol, e.g. {"VOD.L": "LSE"}
+	BenchmarkSymbol      string               `json:"benchmark_symbol"`       // index or ETF relative performance is measured against, default "SPY"
+	IncludeNews          bool                 `json:"include_news"`           // add recent headlines to the analysis prompt, default true
+	AnalysisSchedule     string               `json:"analysis_schedule"`      // cadence of automatic watchlist analyses: off, daily or weekly
+	AnalysisScheduleTime string               `json:"analysis_schedule_time"` // HH:MM New York time of scheduled analyses, default "08:30"
+	AITemperature        float64              `json:"ai_temperature"`         // sampling temperature of analyses, default 0.3
+	AIMaxTokens          int                  `json:"ai_max_tokens"`          // reply length limit of analyses, default 2048
+	AIReasoningEffort    string               `json:"ai_reasoning_effort"`    // "low" | "medium" | "high" for models that reason, "" = model default
+	InvestmentThesis     string               `json:"investment_thesis"`      // standing instructions added to every analysis prompt
+	Retention            RetentionPolicy      `json:"retention"`              // how long history is kept before pruning
 	NotificationChannels []NotificationConfig `json:"notification_channels"`
 	CreatedAt            time.Time            `json:"created_at"`
 	UpdatedAt            time.Time            `json:"updated_at"`
 }
 
+// RetentionPolicy sets how many days each kind of history is kept; 0 keeps
+// it forever
+type RetentionPolicy struct {
+	AnalysisDays     int `json:"analysis_days"`
+	AlertDays        int `json:"alert_days"` // triggered alerts only
+	NotificationDays int `json:"notification_days"`
+}
+
 // NotificationConfig holds notification channel settings
 type NotificationConfig struct {
 	ID      int64    `json:"id"`
-	Type    string   `json:"type"`   // "email" | "discord" | "sms"
-	Target  string   `json:"target"` // email address, webhook URL, phone number
+	Type    string   `json:"type"`   // "email" | "discord" | "sms" | "pushover" | "webhook" | "matrix" | "webpush" | "fcm" | "smtp"
+	Target  string   `json:"target"` // email address, webhook URL, phone number, Pushover user key, Matrix room ID, FCM device token; unused for webpush
 	Enabled bool     `json:"enabled"`
 	Events  []string `json:"events"` // ["buy_signal", "sell_signal", "price_alert"]
+	Digest  bool     `json:"digest"` // send only the daily digest instead of each notification
+	// Template is a text/template for the channel's messages, the default
+	// message when empty
+	Template string `json:"template"`
 }
 
 // Quote represents a stock quote
@@ -40,6 +73,17 @@ type Quote struct {
 	Change        float64   `json:"change"`
 	ChangePercent float64   `json:"change_percent"`
 	Timestamp     time.Time `json:"timestamp"`
+	// Currency is the ISO code prices are quoted in; London listings use GBp
+	// (pence)
+	Currency string `json:"currency,omitempty"`
+
+	// Session is the market session the quote was taken in: "open",
+	// "pre_market", "after_hours" or "closed"
+	Session string `json:"session,omitempty"`
+	// ExtendedPrice is the latest pre-market or after-hours trade, compared
+	// with the regular-session Price; zero when unavailable
+	ExtendedPrice         float64 `json:"extended_price,omitempty"`
+	ExtendedChangePercent float64 `json:"extended_change_percent,omitempty"`
 }
 
 // Candle represents OHLCV data
@@ -54,25 +98,416 @@ type Candle struct {
 
 // AnalysisRequest represents a request for AI analysis
 type AnalysisRequest struct {
-	Symbol         string   `json:"symbol"`
-	CurrentPrice   float64  `json:"current_price"`
-	HistoricalData []Candle `json:"historical_data"`
-	RiskProfile    string   `json:"risk_profile"`
-	TradeFrequency string   `json:"trade_frequency"`
-	UserContext    string   `json:"user_context"` // optional user notes
+	Symbol           string             `json:"symbol"`
+	CurrentPrice     float64            `json:"current_price"`
+	Currency         string             `json:"currency,omitempty"` // currency of every price in the request
+	HistoricalData   []Candle           `json:"historical_data"`
+	RiskProfile      string             `json:"risk_profile"`
+	TradeFrequency   string             `json:"trade_frequency"`
+	UserContext      string             `json:"user_context"`     // optional user notes
+	Thesis           string             `json:"thesis,omitempty"` // the user's standing investment thesis
+	Indicators       Indicators         `json:"indicators"`
+	Timeframes       []Timeframe        `json:"timeframes,omitempty"` // extra windows for multi-timeframe analysis
+	Profile          *CompanyProfile    `json:"profile,omitempty"`
+	Benchmark        *Benchmark         `json:"benchmark,omitempty"`
+	News             []NewsItem         `json:"news,omitempty"`              // recent headlines, newest first
+	Position         *PositionContext   `json:"position,omitempty"`          // the user's holding, for portfolio-aware analyses
+	RelativeStrength *RelativeStrength  `json:"relative_strength,omitempty"` // comparison with sector peers, for relative analyses
+	Generation       GenerationSettings `json:"generation"`
+
+	// Set when the model is asked to repair a reply that could not be parsed
+	PreviousReply string `json:"-"`
+	ReplyError    string `json:"-"`
+}
+
+// GenerationSettings tune how the AI model generates an analysis; a zero
+// MaxTokens uses the defaults of the ai package
+type GenerationSettings struct {
+	Temperature     float64 `json:"temperature"`
+	MaxTokens       int     `json:"max_tokens"`
+	ReasoningEffort string  `json:"reasoning_effort,omitempty"` // "low" | "medium" | "high", "" = model default
+}
+
+// Benchmark compares the return of the analyzed symbol over the history
+// window with that of a benchmark index or ETF over the same period
+type Benchmark struct {
+	Symbol       string  `json:"symbol"`
+	Return       float64 `json:"return"`        // percent change of the benchmark
+	SymbolReturn float64 `json:"symbol_return"` // percent change of the analyzed symbol
+}
+
+// RelativeStrength compares the return of the analyzed symbol over the
+// history window with those of its sector peers and a benchmark index
+type RelativeStrength struct {
+	Period          string       `json:"period"`
+	SymbolReturn    float64      `json:"symbol_return"`              // percent change of the analyzed symbol
+	PeerAverage     float64      `json:"peer_average"`               // mean percent change of the peers
+	Spread          float64      `json:"spread"`                     // SymbolReturn minus PeerAverage, in percentage points
+	Benchmark       string       `json:"benchmark,omitempty"`        // empty when the benchmark could not be fetched
+	BenchmarkReturn float64      `json:"benchmark_return,omitempty"` // percent change of the benchmark
+	Peers           []PeerReturn `json:"peers"`
+}
+
+// PeerReturn is the performance of one sector peer
+type PeerReturn struct {
+	Symbol        string  `json:"symbol"`
+	Return        float64 `json:"return"`         // percent change over the period
+	ChangePercent float64 `json:"change_percent"` // percent change of the latest session
+}
+
+// CompanyProfile describes the company or fund behind a symbol; fields the
+// provider does not report are left empty
+type CompanyProfile struct {
+	Symbol    string  `json:"symbol"`
+	Name      string  `json:"name"`
+	Exchange  string  `json:"exchange,omitempty"`
+	Currency  string  `json:"currency,omitempty"`
+	Sector    string  `json:"sector,omitempty"`
+	Industry  string  `json:"industry,omitempty"`
+	AssetType string  `json:"asset_type,omitempty"` // e.g. "EQUITY", "ETF", "INDEX"
+	MarketCap float64 `json:"market_cap,omitempty"` // in Currency
+	PERatio   float64 `json:"pe_ratio,omitempty"`
+}
+
+// Symbol is the stored metadata of a symbol, filled in from company profiles
+// the first time the symbol is shown
+type Symbol struct {
+	Symbol    string    `json:"symbol"`
+	Name      string    `json:"name"`
+	Exchange  string    `json:"exchange"`
+	Sector    string    `json:"sector"`
+	AssetType string    `json:"asset_type"`
+	Currency  string    `json:"currency"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Position is the user's holding of a symbol
+type Position struct {
+	Symbol    string    `json:"symbol"`
+	Shares    float64   `json:"shares"`
+	CostBasis float64   `json:"cost_basis"` // average cost per share, in the symbol's currency
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PositionContext describes a holding at the current price for a
+// portfolio-aware analysis
+type PositionContext struct {
+	Shares              float64 `json:"shares"`
+	CostBasis           float64 `json:"cost_basis"`
+	MarketValue         float64 `json:"market_value"`
+	UnrealizedPL        float64 `json:"unrealized_pl"`
+	UnrealizedPLPercent float64 `json:"unrealized_pl_percent"`
+	PortfolioWeight     float64 `json:"portfolio_weight"` // percent of the cost basis of all positions
+}
+
+// NewsItem is a recent headline about a symbol
+type NewsItem struct {
+	Headline    string    `json:"headline"`
+	Source      string    `json:"source,omitempty"`
+	Summary     string    `json:"summary,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// CandleSync records how much daily history is stored for a symbol and when
+// it was last brought up to date
+type CandleSync struct {
+	CoveredFrom time.Time
+	SyncedAt    time.Time
+}
+
+// SymbolMatch is a symbol search result
+type SymbolMatch struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Exchange string `json:"exchange"`
+	Type     string `json:"type,omitempty"` // e.g. "EQUITY", "ETF"
+}
+
+// ListedSymbol is a symbol from an exchange's symbol list
+type ListedSymbol struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Exchange string `json:"exchange"`
+	Country  string `json:"country"`
+	Currency string `json:"currency"`
+	Type     string `json:"type"` // e.g. "Common Stock", "ETF"
+}
+
+// Timeframe is a down-sampled historical window summarized alongside the main one
+type Timeframe struct {
+	Label   string   `json:"label"`  // e.g. "Daily", "Weekly"
+	Period  string   `json:"period"` // provider period the candles were fetched with
+	Candles []Candle `json:"candles"`
+}
+
+// Indicators holds technical indicators computed from the historical window;
+// a nil value means there was not enough data
+type Indicators struct {
+	RSI14 *float64 `json:"rsi_14,omitempty"`
+	SMA20 *float64 `json:"sma_20,omitempty"`
+	ATR14 *float64 `json:"atr_14,omitempty"`
 }
 
 // AnalysisResponse represents the AI analysis result
 type AnalysisResponse struct {
-	ID           int64        `json:"id"`
-	Symbol       string       `json:"symbol"`
-	Action       string       `json:"action"`     // "BUY" | "SELL" | "HOLD" | "WATCH"
-	Confidence   float64      `json:"confidence"` // 0.0 - 1.0
-	Reasoning    string       `json:"reasoning"`  // AI explanation
-	PriceTargets PriceTargets `json:"price_targets"`
-	Risks        []string     `json:"risks"`
-	Timeframe    string       `json:"timeframe"`
-	GeneratedAt  time.Time    `json:"generated_at"`
+	ID               int64             `json:"id"`
+	Symbol           string            `json:"symbol"`
+	Action           string            `json:"action"`     // "BUY" | "SELL" | "HOLD" | "WATCH", or "ADD" | "TRIM" relative to a position
+	Confidence       float64           `json:"confidence"` // 0.0 - 1.0
+	Reasoning        string            `json:"reasoning"`  // AI explanation
+	Highlights       []string          `json:"highlights"` // 2-3 bullet summary of the reasoning
+	PriceTargets     PriceTargets      `json:"price_targets"`
+	Risks            []string          `json:"risks"`
+	Timeframe        string            `json:"timeframe"`
+	Preset           string            `json:"preset,omitempty"`            // name of the preset used, if any
+	AIProvider       string            `json:"ai_provider,omitempty"`       // provider that produced the analysis, "consensus" for merged ones
+	Consensus        []ConsensusVote   `json:"consensus,omitempty"`         // per-model answers of a consensus analysis
+	RelativeStrength *RelativeStrength `json:"relative_strength,omitempty"` // comparison with sector peers of a relative analysis
+	Usage            *TokenUsage       `json:"usage,omitempty"`             // tokens used by the AI call, summed for consensus analyses
+	Feedback         *Feedback         `json:"feedback,omitempty"`          // nil until the user rates the analysis
+	QuoteTime        *time.Time        `json:"quote_time,omitempty"`        // timestamp of the quote the analysis used
+	MarketState      string            `json:"market_state,omitempty"`      // "open" | "pre_market" | "after_hours" | "closed"
+	StaleData        bool              `json:"stale_data"`                  // quote was older than the configured threshold
+	AfterHours       bool              `json:"after_hours"`                 // regular session was not open
+	Tags             []string          `json:"tags,omitempty"`              // set in archives only
+	GeneratedAt      time.Time         `json:"generated_at"`
+}
+
+// AnalysisComparison sets two analyses of a symbol side by side, older
+// first, with what changed between them
+type AnalysisComparison struct {
+	Symbol           string           `json:"symbol"`
+	Before           AnalysisResponse `json:"before"`
+	After            AnalysisResponse `json:"after"`
+	ActionChanged    bool             `json:"action_changed"`
+	ConfidenceChange float64          `json:"confidence_change"` // After minus Before
+	TargetChanges    PriceTargets     `json:"target_changes"`    // After minus Before, per target
+	RisksAdded       []string         `json:"risks_added"`       // risks only After lists
+	RisksRemoved     []string         `json:"risks_removed"`     // risks only Before lists
+}
+
+// TokenUsage is the token count and estimated cost of an AI call
+type TokenUsage struct {
+	Provider         string   `json:"provider"`
+	Model            string   `json:"model"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	CostUSD          *float64 `json:"cost_usd"` // estimated from list prices, nil when the model's price is unknown
+}
+
+// ConsensusVote is one model's answer in a consensus analysis
+type ConsensusVote struct {
+	Provider   string  `json:"provider"`
+	Model      string  `json:"model,omitempty"` // empty = provider default
+	Action     string  `json:"action,omitempty"`
+	Confidence float64 `json:"confidence"`
+	Error      string  `json:"error,omitempty"` // set when the model did not answer
+}
+
+// Feedback is the user's rating of an analysis
+type Feedback struct {
+	Rating  int        `json:"rating"` // -1 = thumbs down, 0 = neutral, 1 = thumbs up
+	Note    string     `json:"note"`
+	RatedAt *time.Time `json:"rated_at"`
+}
+
+// Outcomes of a directional analysis, decided by whether the price reached
+// its target or its stop loss first
+const (
+	OutcomeHit  = "hit"
+	OutcomeMiss = "miss"
+)
+
+// PendingOutcome is a directional analysis whose outcome is not known yet
+type PendingOutcome struct {
+	ID           int64
+	Symbol       string
+	Action       string
+	PriceTargets PriceTargets
+	GeneratedAt  time.Time
+}
+
+// ResolvedOutcome is the stated confidence of an analysis and whether its
+// call came true
+type ResolvedOutcome struct {
+	Provider   string
+	Confidence float64
+	Hit        bool
+}
+
+// CalibrationReport compares the stated confidence of past analyses with
+// their realized hit rate
+type CalibrationReport struct {
+	Resolved  int                   `json:"resolved"`
+	Pending   int                   `json:"pending"` // directional analyses still between target and stop
+	Buckets   []CalibrationBucket   `json:"buckets"`
+	Providers []ProviderCalibration `json:"providers"`
+}
+
+// CalibrationBucket aggregates the resolved analyses of a confidence range
+type CalibrationBucket struct {
+	Range          string   `json:"range"` // e.g. "0.6-0.7"
+	MinConfidence  float64  `json:"min_confidence"`
+	MaxConfidence  float64  `json:"max_confidence"`
+	Resolved       int      `json:"resolved"`
+	Hits           int      `json:"hits"`
+	HitRate        *float64 `json:"hit_rate"` // nil without resolved analyses
+	MeanConfidence *float64 `json:"mean_confidence"`
+}
+
+// ProviderCalibration is the calibration of one AI provider
+type ProviderCalibration struct {
+	Provider string              `json:"provider"`
+	Resolved int                 `json:"resolved"`
+	Hits     int                 `json:"hits"`
+	HitRate  *float64            `json:"hit_rate"`
+	Buckets  []CalibrationBucket `json:"buckets"`
+}
+
+// PruneResult counts the rows removed by a retention pass
+type PruneResult struct {
+	Analyses      int64 `json:"analyses"`
+	Alerts        int64 `json:"alerts"`
+	Notifications int64 `json:"notifications"`
+	ExpiredAlerts int64 `json:"expired_alerts"`
+	AlertTriggers int64 `json:"alert_triggers"` // history of alerts still active
+	Deliveries    int64 `json:"deliveries"`     // sent or failed notification deliveries
+}
+
+// DBStats reports the size, contents and connection use of the database
+type DBStats struct {
+	Driver        string       `json:"driver"` // "sqlite" or "postgres"
+	SchemaVersion int          `json:"schema_version"`
+	SizeBytes     int64        `json:"size_bytes"` // database file, or the database on PostgreSQL
+	WALBytes      int64        `json:"wal_bytes"`  // SQLite write-ahead log, not yet checkpointed
+	Tables        []TableStats `json:"tables"`
+	Cache         CacheStats   `json:"cache"`
+	Pool          PoolStats    `json:"pool"`
+}
+
+// TableStats counts the rows of a table
+type TableStats struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// CacheStats reports how reads are served from memory: the in-process config
+// cache, and the SQLite page cache size or the PostgreSQL buffer cache
+type CacheStats struct {
+	ConfigHits   int64    `json:"config_hits"`
+	ConfigMisses int64    `json:"config_misses"`
+	PageCacheKiB int64    `json:"page_cache_kib,omitempty"` // per SQLite connection
+	BlocksHit    int64    `json:"blocks_hit,omitempty"`
+	BlocksRead   int64    `json:"blocks_read,omitempty"`
+	HitRatio     *float64 `json:"hit_ratio,omitempty"` // blocks hit / (hit + read)
+}
+
+// PoolStats mirrors sql.DBStats for the connection pool
+type PoolStats struct {
+	MaxOpen           int   `json:"max_open"`
+	Open              int   `json:"open"`
+	InUse             int   `json:"in_use"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"wait_count"`
+	WaitMillis        int64 `json:"wait_ms"`
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// ProviderPerformance aggregates user feedback for one AI provider
+type ProviderPerformance struct {
+	Provider      string   `json:"provider"`
+	Analyses      int      `json:"analyses"`
+	Rated         int      `json:"rated"`
+	Up            int      `json:"up"`
+	Down          int      `json:"down"`
+	AgreementRate *float64 `json:"agreement_rate"` // up / (up + down), nil without votes
+}
+
+// Usage metrics recorded in the local usage rollup
+const (
+	UsageAnalysis       = "analysis"        // keyed by symbol
+	UsageAlertTriggered = "alert_triggered" // keyed by symbol
+	UsageProviderError  = "provider_error"  // keyed by "kind:provider", e.g. "ai:openai"
+)
+
+// UsageSummary reports local usage trends over the last Days days
+type UsageSummary struct {
+	Days           int          `json:"days"`
+	Since          string       `json:"since"`
+	Enabled        bool         `json:"enabled"`
+	Totals         UsageDay     `json:"totals"`
+	Daily          []UsageDay   `json:"daily"` // one entry per day, oldest first
+	TopSymbols     []UsageCount `json:"top_symbols"`
+	AlertSymbols   []UsageCount `json:"alert_symbols"`
+	ProviderErrors []UsageCount `json:"provider_errors"`
+}
+
+// AIUsageSummary reports AI token usage and estimated cost over the last Days days
+type AIUsageSummary struct {
+	Days       int             `json:"days"`
+	Since      string          `json:"since"`
+	Totals     AIUsageTotals   `json:"totals"`
+	Daily      []AIUsageTotals `json:"daily"` // one entry per day, oldest first
+	ByProvider []AIUsageTotals `json:"by_provider"`
+	ByModel    []AIUsageTotals `json:"by_model"`
+}
+
+// AIUsageTotals sums the AI calls of a day, provider or model
+type AIUsageTotals struct {
+	Date             string  `json:"date,omitempty"`
+	Provider         string  `json:"provider,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	UnpricedCalls    int     `json:"unpriced_calls"` // calls to models without a known price, not in CostUSD
+}
+
+// ScheduledRun records a scheduled analysis of the watchlist
+type ScheduledRun struct {
+	ID           int64      `json:"id"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"` // nil while the run is in progress
+	Symbols      int        `json:"symbols"`
+	Succeeded    int        `json:"succeeded"`
+	Failed       int        `json:"failed"`
+	Signals      int        `json:"signals"`          // high-confidence BUY or SELL analyses, which were notified
+	Errors       string     `json:"errors,omitempty"` // failures by symbol
+}
+
+// UsageDay holds the usage counters of a single day
+type UsageDay struct {
+	Date            string `json:"date,omitempty"`
+	Analyses        int    `json:"analyses"`
+	AlertsTriggered int    `json:"alerts_triggered"`
+	ProviderErrors  int    `json:"provider_errors"`
+}
+
+// UsageCount is a counter for a single symbol or provider
+type UsageCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// HighlightsOrTruncated returns highlights, or when there are none the
+// reasoning truncated to maxLen characters
+func HighlightsOrTruncated(highlights []string, reasoning string, maxLen int) []string {
+	if len(highlights) > 0 {
+		return highlights
+	}
+	reasoning = strings.TrimSpace(reasoning)
+	if reasoning == "" {
+		return nil
+	}
+	if runes := []rune(reasoning); len(runes) > maxLen {
+		reasoning = strings.TrimSpace(string(runes[:maxLen])) + "…"
+	}
+	return []string{reasoning}
 }
 
 // PriceTargets holds price target information
@@ -82,25 +517,189 @@ type PriceTargets struct {
 	StopLoss float64 `json:"stop_loss"`
 }
 
+// AnalysisPreset is a named set of analysis parameter overrides
+type AnalysisPreset struct {
+	ID               int64     `json:"id"`
+	Name             string    `json:"name"`
+	AIProvider       string    `json:"ai_provider"`         // empty = use configured provider
+	AIModel          string    `json:"ai_model"`            // empty = use configured/default model
+	AIProviderAPIKey string    `json:"ai_provider_api_key"` // optional, encrypted at rest
+	TradeFrequency   string    `json:"trade_frequency"`     // empty = use configured frequency
+	UserContext      string    `json:"user_context"`        // context snippet prepended to user notes
+	HistoryPeriod    string    `json:"history_period"`      // "1d" | "5d" | "1m" | "3m" | "1y" | "5y" | "ytd" | "max"
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ArchiveVersion is the format of the archives written by GET /api/export;
+// version 2 added paused alerts
+const ArchiveVersion = 2
+
+// Archive is a portable copy of the user's data, written by GET /api/export
+// and restored by POST /api/import. Its API keys are in plain text.
+type Archive struct {
+	Version    int                `json:"version"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Config     UserConfig         `json:"config"` // with the watchlist and notification channels
+	Presets    []AnalysisPreset   `json:"presets"`
+	Alerts     []PriceAlert       `json:"alerts"`
+	Positions  []Position         `json:"positions"`
+	Analyses   []AnalysisResponse `json:"analyses"` // with their tags
+}
+
+// ArchiveCounts reports how many records of each kind an import restored
+type ArchiveCounts struct {
+	NotificationChannels int `json:"notification_channels"`
+	Presets              int `json:"presets"`
+	Alerts               int `json:"alerts"`
+	Positions            int `json:"positions"`
+	Analyses             int `json:"analyses"`
+}
+
 // PriceAlert represents a user-defined price alert
 type PriceAlert struct {
-	ID        int64     `json:"id"`
-	Symbol    string    `json:"symbol"`
-	Condition string    `json:"condition"` // "above" | "below"
-	Price     float64   `json:"price"`
-	Triggered bool      `json:"triggered"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                int64      `json:"id"`
+	Symbol            string     `json:"symbol"`
+	Condition         string     `json:"condition"`             // "above" | "below" | "gap" | "gap_up" | "gap_down" | "trail_percent" | "trail_amount" | "sma_cross_above" | "sma_cross_below" | "rsi_above" | "rsi_below" | "high_52w" | "low_52w" | "composite"
+	Price             float64    `json:"price"`                 // price or RSI level, gap threshold in percent, or trailing distance in percent or dollars
+	PeakPrice         float64    `json:"peak_price,omitempty"`  // highest price seen since a trailing alert was created
+	FastPeriod        int        `json:"fast_period,omitempty"` // days of the moving averages of a crossover alert
+	SlowPeriod        int        `json:"slow_period,omitempty"`
+	Period            int        `json:"period,omitempty"`             // RSI period of an RSI alert, 14 by default
+	Recurring         bool       `json:"recurring"`                    // re-arms after its cooldown instead of staying triggered
+	CooldownMinutes   int        `json:"cooldown_minutes,omitempty"`   // how long a recurring alert stays quiet after firing
+	TriggeredAt       *time.Time `json:"triggered_at,omitempty"`       // when the alert last fired
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`         // when the alert stops being evaluated and is deleted
+	HysteresisPercent float64    `json:"hysteresis_percent,omitempty"` // band a recurring price alert's price must leave before it re-arms
+	Rearming          bool       `json:"rearming,omitempty"`           // the price has not left the band since the alert fired
+	Enabled           bool       `json:"enabled"`                      // paused alerts are kept but not evaluated
+	Channels          []string   `json:"channels,omitempty"`           // types of the notification channels to notify, all when empty
+	Rule              *AlertRule `json:"rule,omitempty"`               // conditions of a "composite" alert
+	Label             string     `json:"label,omitempty"`              // short name shown with the alert and its notifications
+	Note              string     `json:"note,omitempty"`               // why the alert was set
+	Triggered         bool       `json:"triggered"`
+	LastFiredDate     string     `json:"last_fired_date,omitempty"` // trading day a gap alert last fired (YYYY-MM-DD)
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// AlertRule is a condition tree of a composite alert: either a group of
+// rules joined by Op, or a comparison of a metric with a value
+type AlertRule struct {
+	Op      string      `json:"op,omitempty"` // "and" | "or" for a group
+	Rules   []AlertRule `json:"rules,omitempty"`
+	Metric  string      `json:"metric,omitempty"`  // "price" | "change_percent" | "volume_ratio" | "rsi"
+	Compare string      `json:"compare,omitempty"` // "above" | "below"
+	Value   float64     `json:"value,omitempty"`
+	Period  int         `json:"period,omitempty"` // RSI period, 14 by default
+}
+
+// AlertTrigger records a price alert firing
+type AlertTrigger struct {
+	ID          int64     `json:"id"`
+	AlertID     int64     `json:"alert_id"`
+	Price       float64   `json:"price"`
+	Message     string    `json:"message"`
+	Channels    []string  `json:"channels"` // types of the notification channels reached
+	TriggeredAt time.Time `json:"triggered_at"`
 }
 
 // Notification represents a notification to be sent
 type Notification struct {
-	ID       int64     `json:"id"`
-	Type     string    `json:"type"` // "buy_signal", "sell_signal", "price_alert"
-	Title    string    `json:"title"`
-	Message  string    `json:"message"`
-	Symbol   string    `json:"symbol"`
-	SentAt   time.Time `json:"sent_at"`
-	Channels []string  `json:"channels"` // which channels it was sent to
+	ID           int64     `json:"id"`
+	Type         string    `json:"type"` // "buy_signal", "sell_signal", "price_alert"
+	Title        string    `json:"title"`
+	Message      string    `json:"message"`
+	Symbol       string    `json:"symbol"`
+	Action       string    `json:"action,omitempty"`     // BUY, SELL, ADD or TRIM for signals
+	Price        float64   `json:"price,omitempty"`      // entry price of a signal, price that fired an alert
+	Confidence   float64   `json:"confidence,omitempty"` // of a signal
+	SentAt       time.Time `json:"sent_at"`
+	Channels     []string  `json:"channels"` // which channels it was sent to
+	ChannelTypes []string  `json:"-"`        // types of the channels to send to, all when empty
+	Digest       *Digest   `json:"digest,omitempty"`
+	Templated    bool      `json:"templated,omitempty"` // Message was rendered from the channel's template
+}
+
+// DigestNotification is the type of the daily digest notification
+const DigestNotification = "digest"
+
+// Digest batches a day's signals, triggered alerts and watchlist movers into
+// one notification
+type Digest struct {
+	Date    time.Time      `json:"date"`
+	Signals []DigestSignal `json:"signals"`
+	Alerts  []DigestAlert  `json:"alerts"`
+	Movers  []DigestMover  `json:"movers"`
+}
+
+// DigestSignal is a high-confidence trading signal in a digest
+type DigestSignal struct {
+	Symbol     string    `json:"symbol"`
+	Action     string    `json:"action"`
+	Confidence float64   `json:"confidence"`
+	Summary    string    `json:"summary"`
+	At         time.Time `json:"at"`
+}
+
+// DigestAlert is a triggered price alert in a digest
+type DigestAlert struct {
+	Symbol  string    `json:"symbol"`
+	Message string    `json:"message"`
+	Price   float64   `json:"price"`
+	At      time.Time `json:"at"`
+}
+
+// DigestMover is a watchlist symbol that moved most during the day
+type DigestMover struct {
+	Symbol        string  `json:"symbol"`
+	Price         float64 `json:"price"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+// Notification delivery states: waiting for a (re)try, sent, or given up
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+)
+
+// NotificationDelivery is the sending of a notification to one channel,
+// kept so failed sends can be retried
+type NotificationDelivery struct {
+	ID            int64        `json:"id"`
+	ChannelType   string       `json:"channel_type"`
+	Target        string       `json:"target"`
+	Notification  Notification `json:"notification"`
+	Status        string       `json:"status"` // "pending" | "sent" | "failed"
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"last_error,omitempty"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// PushSubscription is a browser's Web Push subscription, as its
+// PushSubscription.toJSON()
+type PushSubscription struct {
+	ID        int64     `json:"id"`
+	Endpoint  string    `json:"endpoint"`
+	Keys      PushKeys  `json:"keys"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PushKeys are a subscription's public key and auth secret, base64url
+type PushKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// Device is a mobile app or home-screen PWA registered for FCM push
+type Device struct {
+	ID        int64     `json:"id"`
+	Token     string    `json:"token"`
+	Platform  string    `json:"platform"` // "android" | "ios" | "web"
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // RiskProfile defines analysis behavior based on risk tolerance
@@ -164,11 +763,24 @@ type Recommendation struct {
 	TargetPrice float64   `json:"target_price"`
 	StopLoss    float64   `json:"stop_loss"`
 	Reasoning   string    `json:"reasoning"`
+	Highlights  []string  `json:"highlights"`
 	Timeframe   string    `json:"timeframe"`
 	AIProvider  string    `json:"ai_provider"`
+	Feedback    int       `json:"feedback"` // user rating: -1, 0 or 1
+	StaleData   bool      `json:"stale_data"`
+	AfterHours  bool      `json:"after_hours"`
+	Tags        []string  `json:"tags,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// Tag is a user-defined label for organizing analyses
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Analyses  int       `json:"analyses"` // number of analyses tagged
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // Alert for HTMX templates
 type Alert struct {
 	ID          int64     `json:"id"`
@@ -186,26 +798,59 @@ type Analysis struct {
 	Recommendation Recommendation `json:"recommendation"`
 	MarketData     *Quote         `json:"market_data"`
 	AIProvider     string         `json:"ai_provider"`
+	Feedback       *Feedback      `json:"feedback,omitempty"`
 	CreatedAt      time.Time      `json:"created_at"`
 }
 
 // AppConfig for settings page
 type AppConfig struct {
-	MarketDataProvider string   `json:"market_data_provider"`
-	HasMarketAPIKey    bool     `json:"has_market_api_key"`
-	MarketAPIKeyMasked string   `json:"market_api_key_masked"`
-	AIProvider         string   `json:"ai_provider"`
-	HasAIAPIKey        bool     `json:"has_ai_api_key"`
-	AIAPIKeyMasked     string   `json:"ai_api_key_masked"`
-	AIModel            string   `json:"ai_model"`
-	RiskTolerance      string   `json:"risk_tolerance"`
-	TradeFrequency     string   `json:"trade_frequency"`
-	TrackedSymbols     []string `json:"tracked_symbols"`
-	PollingInterval    int      `json:"polling_interval"` // in seconds
-	EmailAddress       string   `json:"email_address"`
-	EmailEnabled       bool     `json:"email_enabled"`
-	DiscordWebhook     string   `json:"discord_webhook"`
-	DiscordEnabled     bool     `json:"discord_enabled"`
-	SMSPhone           string   `json:"sms_phone"`
-	SMSEnabled         bool     `json:"sms_enabled"`
+	MarketDataProvider   string            `json:"market_data_provider"`
+	HasMarketAPIKey      bool              `json:"has_market_api_key"`
+	MarketAPIKeyMasked   string            `json:"market_api_key_masked"`
+	AIProvider           string            `json:"ai_provider"`
+	HasAIAPIKey          bool              `json:"has_ai_api_key"`
+	AIAPIKeyMasked       string            `json:"ai_api_key_masked"`
+	AIModel              string            `json:"ai_model"`
+	FallbackAIProvider   string            `json:"fallback_ai_provider"`
+	FallbackAIModel      string            `json:"fallback_ai_model"`
+	HasFallbackAIKey     bool              `json:"has_fallback_ai_key"`
+	AzureOpenAIEndpoint  string            `json:"azure_openai_endpoint"`
+	RiskTolerance        string            `json:"risk_tolerance"`
+	TradeFrequency       string            `json:"trade_frequency"`
+	TrackedSymbols       []string          `json:"tracked_symbols"`
+	PollingInterval      int               `json:"polling_interval"` // in seconds
+	MinStoreConfidence   float64           `json:"min_store_confidence"`
+	StaleQuoteMinutes    int               `json:"stale_quote_minutes"`
+	ExtendedHoursAlerts  bool              `json:"extended_hours_alerts"`
+	DisplayCurrency      string            `json:"display_currency"`
+	SymbolExchanges      map[string]string `json:"symbol_exchanges"`
+	BenchmarkSymbol      string            `json:"benchmark_symbol"`
+	IncludeNews          bool              `json:"include_news"`
+	AnalysisSchedule     string            `json:"analysis_schedule"`
+	AnalysisScheduleTime string            `json:"analysis_schedule_time"`
+	AITemperature        float64           `json:"ai_temperature"`
+	AIMaxTokens          int               `json:"ai_max_tokens"`
+	AIReasoningEffort    string            `json:"ai_reasoning_effort"`
+	InvestmentThesis     string            `json:"investment_thesis"`
+	Retention            RetentionPolicy   `json:"retention"`
+	EmailAddress         string            `json:"email_address"`
+	EmailEnabled         bool              `json:"email_enabled"`
+	SMTPAddress          string            `json:"smtp_address"`
+	SMTPEnabled          bool              `json:"smtp_enabled"`
+	DiscordWebhook       string            `json:"discord_webhook"`
+	DiscordEnabled       bool              `json:"discord_enabled"`
+	SMSPhone             string            `json:"sms_phone"`
+	SMSEnabled           bool              `json:"sms_enabled"`
+	PushoverUserKey      string            `json:"pushover_user_key"`
+	PushoverEnabled      bool              `json:"pushover_enabled"`
+	WebhookURL           string            `json:"webhook_url"`
+	WebhookEnabled       bool              `json:"webhook_enabled"`
+	MatrixRoomID         string            `json:"matrix_room_id"`
+	MatrixEnabled        bool              `json:"matrix_enabled"`
+	WebPushEnabled       bool              `json:"webpush_enabled"`
+	FCMDeviceToken       string            `json:"fcm_device_token"`
+	FCMEnabled           bool              `json:"fcm_enabled"`
+	DigestChannels       []string          `json:"digest_channels"`   // types of the channels in digest mode
+	ChannelTemplates     map[string]string `json:"channel_templates"` // message template by channel type
+	ChannelIDs           map[string]int64  `json:"channel_ids"`       // notification channel ID by type
 }
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// ComposeDigest turns a digest into the notification sent to digest
+// channels: a plain text summary, and the digest itself for email
+func ComposeDigest(d models.Digest) models.Notification {
+	var b strings.Builder
+	if len(d.Signals) > 0 {
+		fmt.Fprintf(&b, "Signals (%d)\n", len(d.Signals))
+		for _, s := range d.Signals {
+			fmt.Fprintf(&b, "• %s %s (%.0f%%)\n", s.Action, s.Symbol, s.Confidence*100)
+		}
+	}
+	if len(d.Alerts) > 0 {
+		fmt.Fprintf(&b, "Triggered alerts (%d)\n", len(d.Alerts))
+		for _, a := range d.Alerts {
+			fmt.Fprintf(&b, "• %s: %s\n", a.Symbol, a.Message)
+		}
+	}
+	if len(d.Movers) > 0 {
+		b.WriteString("Watchlist movers\n")
+		for _, m := range d.Movers {
+			fmt.Fprintf(&b, "• %s %+.2f%% at %.2f\n", m.Symbol, m.ChangePercent, m.Price)
+		}
+	}
+	message := strings.TrimSpace(b.String())
+	if message == "" {
+		message = "No signals, alerts or watchlist moves today."
+	}
+
+	return models.Notification{
+		Type:    models.DigestNotification,
+		Title:   "Daily digest: " + d.Date.Format("Mon, Jan 2"),
+		Message: message,
+		Digest:  &d,
+	}
+}
+
+// digestEmailTemplate lays the digest out as sections of tables, styled
+// inline like the other emails
+var digestEmailTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"percent": func(v float64) string { return fmt.Sprintf("%.0f%%", v*100) },
+	"change":  func(v float64) string { return fmt.Sprintf("%+.2f%%", v) },
+	"price":   func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"changeColor": func(v float64) string {
+		if v < 0 {
+			return "#ef4444"
+		}
+		return "#22c55e"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+</head>
+<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #f3f4f6;">
+  <table role="presentation" style="width: 100%; border-collapse: collapse;">
+    <tr>
+      <td style="padding: 40px 20px;">
+        <table role="presentation" style="max-width: 600px; margin: 0 auto; background: white; border-radius: 12px; overflow: hidden; box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);">
+          <tr>
+            <td style="background: linear-gradient(135deg, #1e1b4b 0%, #312e81 100%); padding: 30px; text-align: center;">
+              <h1 style="margin: 0; color: white; font-size: 24px; font-weight: 600;">📈 StockAI Daily Digest</h1>
+              <p style="margin: 8px 0 0 0; color: #c7d2fe; font-size: 14px;">{{.Date.Format "Monday, January 2, 2006"}}</p>
+            </td>
+          </tr>
+          {{if .Signals}}
+          <tr>
+            <td style="padding: 30px 30px 0 30px;">
+              <h2 style="margin: 0 0 12px 0; color: #111827; font-size: 18px; font-weight: 600;">Signals</h2>
+              <table role="presentation" style="width: 100%; border-collapse: collapse;">
+                {{range .Signals}}
+                <tr>
+                  <td style="padding: 10px 0; border-top: 1px solid #e5e7eb; vertical-align: top; width: 120px;">
+                    <strong style="color: #111827;">{{.Symbol}}</strong>
+                    <span style="display: block; color: {{if or (eq .Action "SELL") (eq .Action "TRIM")}}#ef4444{{else}}#22c55e{{end}}; font-size: 12px; font-weight: 600;">{{.Action}} · {{percent .Confidence}}</span>
+                  </td>
+                  <td style="padding: 10px 0; border-top: 1px solid #e5e7eb; color: #6b7280; font-size: 14px; line-height: 1.5;">{{.Summary}}</td>
+                </tr>
+                {{end}}
+              </table>
+            </td>
+          </tr>
+          {{end}}
+          {{if .Alerts}}
+          <tr>
+            <td style="padding: 30px 30px 0 30px;">
+              <h2 style="margin: 0 0 12px 0; color: #111827; font-size: 18px; font-weight: 600;">Triggered Alerts</h2>
+              <table role="presentation" style="width: 100%; border-collapse: collapse;">
+                {{range .Alerts}}
+                <tr>
+                  <td style="padding: 10px 0; border-top: 1px solid #e5e7eb; vertical-align: top; width: 120px;"><strong style="color: #111827;">{{.Symbol}}</strong></td>
+                  <td style="padding: 10px 0; border-top: 1px solid #e5e7eb; color: #6b7280; font-size: 14px; line-height: 1.5;">{{.Message}}</td>
+                  <td style="padding: 10px 0; border-top: 1px solid #e5e7eb; color: #9ca3af; font-size: 12px; text-align: right; white-space: nowrap;">{{.At.Format "3:04 PM"}}</td>
+                </tr>
+                {{end}}
+              </table>
+            </td>
+          </tr>
+          {{end}}
+          {{if .Movers}}
+          <tr>
+            <td style="padding: 30px 30px 0 30px;">
+              <h2 style="margin: 0 0 12px 0; color: #111827; font-size: 18px; font-weight: 600;">Watchlist Movers</h2>
+              <table role="presentation" style="width: 100%; border-collapse: collapse;">
+                {{range .Movers}}
+                <tr>
+                  <td style="padding: 10px 0; border-top: 1px solid #e5e7eb;"><strong style="color: #111827;">{{.Symbol}}</strong></td>
+                  <td style="padding: 10px 0; border-top: 1px solid #e5e7eb; color: #6b7280; font-size: 14px; text-align: right;">{{price .Price}}</td>
+                  <td style="padding: 10px 0; border-top: 1px solid #e5e7eb; color: {{changeColor .ChangePercent}}; font-size: 14px; font-weight: 600; text-align: right;">{{change .ChangePercent}}</td>
+                </tr>
+                {{end}}
+              </table>
+            </td>
+          </tr>
+          {{end}}
+          {{if not (or .Signals .Alerts .Movers)}}
+          <tr>
+            <td style="padding: 30px 30px 0 30px; color: #6b7280; font-size: 16px;">No signals, alerts or watchlist moves today.</td>
+          </tr>
+          {{end}}
+          <tr>
+            <td style="padding: 30px 0 0 0;"></td>
+          </tr>
+          <tr>
+            <td style="padding: 20px 30px; background: #f9fafb; text-align: center; border-top: 1px solid #e5e7eb;">
+              <p style="margin: 0; color: #9ca3af; font-size: 12px;">Sent by StockAI • Stock Market Analysis Platform</p>
+            </td>
+          </tr>
+        </table>
+      </td>
+    </tr>
+  </table>
+</body>
+</html>
+`))
+
+// formatDigestEmail renders a digest as an HTML email, falling back to the
+// plain text summary if the template fails
+func formatDigestEmail(n models.Notification) string {
+	var buf bytes.Buffer
+	if err := digestEmailTemplate.Execute(&buf, n.Digest); err != nil {
+		return "<pre>" + template.HTMLEscapeString(n.Message) + "</pre>"
+	}
+	return buf.String()
+}
@@ -33,7 +33,7 @@ func (d *DiscordNotifier) Send(notification models.Notification, target string)
 		fmt.Println("[DISCORD] No webhook URL provided, skipping")
 		return nil
 	}
-	fmt.Printf("[DISCORD] Sending to webhook: %s...\n", target[:50])
+	fmt.Printf("[DISCORD] Sending to webhook: %s...\n", target[:min(len(target), 50)])
 
 	// Choose color based on notification type
 	color := 0x808080 // gray
@@ -10,83 +10,181 @@ import (
 	"stockmarket/internal/models"
 )
 
-// EmailNotifier sends notifications via email (using Resend API)
+// Email providers EMAIL_PROVIDER can choose
+const (
+	EmailResend   = "resend"
+	EmailSendGrid = "sendgrid"
+	EmailMailgun  = "mailgun"
+	EmailSMTP     = "smtp"
+)
+
+// EmailTransport delivers a rendered HTML email through a provider
+type EmailTransport interface {
+	Send(from, to, subject, html string) error
+	// Configured reports whether the provider's credentials are set
+	Configured() bool
+	// DefaultFrom is the sender used when none is configured, or ""
+	DefaultFrom() string
+}
+
+// configValue reads a notifier setting from its config key, then from the
+// environment
+func configValue(config map[string]string, key, env string) string {
+	if v := config[key]; v != "" {
+		return v
+	}
+	return os.Getenv(env)
+}
+
+// EmailNotifier sends notifications via email, through Resend, SendGrid,
+// Mailgun or SMTP
 type EmailNotifier struct {
-	apiKey    string
-	fromEmail string
-	client    *http.Client
+	channelType string
+	provider    string
+	from        string
+	transport   EmailTransport
 }
 
-// NewEmailNotifier creates a new email notifier using Resend
+// NewEmailNotifier creates a new email notifier using the provider in
+// EMAIL_PROVIDER, Resend by default
 func NewEmailNotifier(config map[string]string) *EmailNotifier {
-	apiKey := config["resend_api_key"]
-	if apiKey == "" {
-		apiKey = os.Getenv("RESEND_API_KEY")
+	provider := configValue(config, "email_provider", "EMAIL_PROVIDER")
+	if provider == "" {
+		provider = EmailResend
 	}
+	return newEmailNotifier("email", provider, config)
+}
 
-	fromEmail := config["from_email"]
-	if fromEmail == "" {
-		fromEmail = "StockAI <alerts@resend.dev>" // Default Resend sender
+// NewSMTPNotifier creates a new email notifier that always sends through
+// SMTP, for the smtp channel type
+func NewSMTPNotifier(config map[string]string) *EmailNotifier {
+	return newEmailNotifier("smtp", EmailSMTP, config)
+}
+
+func newEmailNotifier(channelType, provider string, config map[string]string) *EmailNotifier {
+	transport, err := NewEmailTransport(provider, config)
+	if err != nil {
+		fmt.Printf("[EMAIL] %v, using %s\n", err, EmailResend)
+		provider = EmailResend
+		transport = newResendTransport(config)
+	}
+
+	from := configValue(config, "from_email", "EMAIL_FROM")
+	if from == "" {
+		from = transport.DefaultFrom()
 	}
 
 	return &EmailNotifier{
-		apiKey:    apiKey,
-		fromEmail: fromEmail,
-		client:    sharedHTTPClient,
+		channelType: channelType,
+		provider:    provider,
+		from:        from,
+		transport:   transport,
+	}
+}
+
+// NewEmailTransport creates the transport of an email provider
+func NewEmailTransport(provider string, config map[string]string) (EmailTransport, error) {
+	switch provider {
+	case EmailResend:
+		return newResendTransport(config), nil
+	case EmailSendGrid:
+		return newSendGridTransport(config), nil
+	case EmailMailgun:
+		return newMailgunTransport(config), nil
+	case EmailSMTP:
+		return newSMTPTransport(config), nil
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", provider)
 	}
 }
 
 // Type returns the notifier type
 func (e *EmailNotifier) Type() string {
-	return "email"
+	return e.channelType
 }
 
-// Send sends an email notification via Resend API
+// Send emails the notification to the address target
 func (e *EmailNotifier) Send(notification models.Notification, target string) error {
-	if e.apiKey == "" {
+	if !e.transport.Configured() {
 		// Log but don't fail - email not configured
-		fmt.Printf("[EMAIL] Would send to %s: %s - %s\n", target, notification.Title, notification.Message)
+		fmt.Printf("[EMAIL] Would send to %s via %s: %s - %s\n", target, e.provider, notification.Title, notification.Message)
 		return nil
 	}
+	if e.from == "" {
+		return fmt.Errorf("%w: set EMAIL_FROM to a sender verified with %s", ErrNotificationFailed, e.provider)
+	}
 
+	if err := e.transport.Send(e.from, target, notification.Title, formatEmailBody(notification)); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrNotificationFailed, e.provider, err)
+	}
+
+	fmt.Printf("[EMAIL] Successfully sent email to %s via %s\n", target, e.provider)
+	return nil
+}
+
+// resendTransport sends email with the Resend API
+type resendTransport struct {
+	apiKey string
+	client *http.Client
+}
+
+func newResendTransport(config map[string]string) *resendTransport {
+	return &resendTransport{
+		apiKey: configValue(config, "resend_api_key", "RESEND_API_KEY"),
+		client: sharedHTTPClient,
+	}
+}
+
+func (t *resendTransport) Configured() bool {
+	return t.apiKey != ""
+}
+
+func (t *resendTransport) DefaultFrom() string {
+	return "StockAI <alerts@resend.dev>" // Default Resend sender
+}
+
+func (t *resendTransport) Send(from, to, subject, html string) error {
 	// Build the email payload for Resend
 	payload := map[string]interface{}{
-		"from":    e.fromEmail,
-		"to":      []string{target},
-		"subject": notification.Title,
-		"html":    formatEmailBody(notification),
+		"from":    from,
+		"to":      []string{to},
+		"subject": subject,
+		"html":    html,
 	}
 
 	jsonBody, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("%w: failed to marshal email payload: %v", ErrNotificationFailed, err)
+		return fmt.Errorf("failed to marshal email payload: %v", err)
 	}
 
 	req, err := http.NewRequest("POST", "https://api.resend.com/emails", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return fmt.Errorf("%w: failed to create request: %v", ErrNotificationFailed, err)
+		return fmt.Errorf("failed to create request: %v", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := e.client.Do(req)
+	resp, err := t.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("%w: failed to send email: %v", ErrNotificationFailed, err)
+		return fmt.Errorf("failed to send email: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		var errResp map[string]interface{}
 		json.NewDecoder(resp.Body).Decode(&errResp)
-		return fmt.Errorf("%w: resend returned status %d: %v", ErrNotificationFailed, resp.StatusCode, errResp)
+		return fmt.Errorf("status %d: %v", resp.StatusCode, errResp)
 	}
 
-	fmt.Printf("[EMAIL] Successfully sent email to %s\n", target)
 	return nil
 }
 
 func formatEmailBody(n models.Notification) string {
+	if n.Digest != nil {
+		return formatDigestEmail(n)
+	}
+
 	// Choose color based on notification type
 	color := "#6366f1" // default indigo
 	switch n.Type {
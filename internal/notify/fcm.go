@@ -0,0 +1,288 @@
+package notify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// FCM endpoints and the OAuth scope of its HTTP v1 API
+const (
+	fcmSendURL     = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	fcmScope       = "https://www.googleapis.com/auth/firebase.messaging"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// fcmTokenTTL is how long the signed assertion exchanged for an access token
+// is valid; fcmTokenSlack renews the access token this long before it expires
+const (
+	fcmTokenTTL   = time.Hour
+	fcmTokenSlack = time.Minute
+)
+
+// DeviceStore lists the registered devices and forgets the unregistered ones
+type DeviceStore interface {
+	GetDevices() ([]models.Device, error)
+	DeleteDevice(token string) error
+}
+
+// fcmServiceAccount is the part of a Google service account key file FCM
+// needs
+type fcmServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMNotifier sends native push notifications via Firebase Cloud Messaging
+type FCMNotifier struct {
+	account *fcmServiceAccount
+	key     *rsa.PrivateKey
+	store   DeviceStore
+	client  *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMNotifier creates a new FCM notifier from the service account key file
+// in FCM_SERVICE_ACCOUNT_FILE
+func NewFCMNotifier(config map[string]string, store DeviceStore) *FCMNotifier {
+	path := config["fcm_service_account_file"]
+	if path == "" {
+		path = os.Getenv("FCM_SERVICE_ACCOUNT_FILE")
+	}
+
+	f := &FCMNotifier{
+		store:  store,
+		client: sharedHTTPClient,
+	}
+	if path != "" {
+		if err := f.loadServiceAccount(path); err != nil {
+			fmt.Printf("[FCM] Could not load service account %s: %v\n", path, err)
+		}
+	}
+	return f
+}
+
+// loadServiceAccount reads a service account key file
+func (f *FCMNotifier) loadServiceAccount(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var account fcmServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return err
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" {
+		return errors.New("project_id and client_email are required")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = googleTokenURL
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return errors.New("private_key is not PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return errors.New("private_key is not an RSA key")
+	}
+
+	f.account = &account
+	f.key = key
+	return nil
+}
+
+// Type returns the notifier type
+func (f *FCMNotifier) Type() string {
+	return "fcm"
+}
+
+// Send pushes the notification to the device token target, or to every
+// registered device when target is empty. Tokens FCM reports unregistered
+// are deleted.
+func (f *FCMNotifier) Send(notification models.Notification, target string) error {
+	if f.account == nil {
+		// Log but don't fail - FCM not configured
+		fmt.Printf("[FCM] Would send to %s: %s - %s\n", target, notification.Title, notification.Message)
+		return nil
+	}
+
+	tokens := []string{target}
+	if target == "" {
+		devices, err := f.store.GetDevices()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNotificationFailed, err)
+		}
+		if len(devices) == 0 {
+			fmt.Println("[FCM] No registered devices, skipping")
+			return nil
+		}
+		tokens = tokens[:0]
+		for _, d := range devices {
+			tokens = append(tokens, d.Token)
+		}
+	}
+
+	accessToken, err := f.token()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationFailed, err)
+	}
+
+	var failed []string
+	for _, token := range tokens {
+		if err := f.sendTo(notification, token, accessToken); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %d of %d devices: %s", ErrNotificationFailed, len(failed), len(tokens), strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// sendTo sends one message to a device token
+func (f *FCMNotifier) sendTo(notification models.Notification, token, accessToken string) error {
+	message := map[string]any{
+		"message": map[string]any{
+			"token": token,
+			"notification": map[string]string{
+				"title": notification.Title,
+				"body":  notification.Message,
+			},
+			"data": map[string]string{
+				"type":   notification.Type,
+				"symbol": notification.Symbol,
+			},
+			"android": map[string]string{"priority": "high"},
+			"apns": map[string]any{
+				"headers": map[string]string{"apns-priority": "10"},
+			},
+		},
+	}
+	jsonBody, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf(fcmSendURL, f.account.ProjectID), bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp struct {
+			Error struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		if resp.StatusCode == http.StatusNotFound {
+			// The app was uninstalled or the token expired
+			if err := f.store.DeleteDevice(token); err != nil {
+				return err
+			}
+			fmt.Println("[FCM] Removed unregistered device")
+			return nil
+		}
+		return fmt.Errorf("fcm returned status %d: %s %s", resp.StatusCode, errResp.Error.Status, errResp.Error.Message)
+	}
+
+	return nil
+}
+
+// token returns an OAuth access token for the service account, exchanging a
+// signed assertion for a new one when the last has expired
+func (f *FCMNotifier) token() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.accessToken != "" && time.Now().Add(fcmTokenSlack).Before(f.expiresAt) {
+		return f.accessToken, nil
+	}
+
+	assertion, err := f.assertion()
+	if err != nil {
+		return "", err
+	}
+	data := url.Values{}
+	data.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	data.Set("assertion", assertion)
+
+	resp, err := f.client.PostForm(f.account.TokenURI, data)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var tokenResp struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	json.NewDecoder(resp.Body).Decode(&tokenResp)
+	if resp.StatusCode != http.StatusOK || tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("token exchange returned status %d: %s %s", resp.StatusCode, tokenResp.Error, tokenResp.ErrorDescription)
+	}
+
+	f.accessToken = tokenResp.AccessToken
+	f.expiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
+
+// assertion signs the JWT the service account exchanges for an access token
+func (f *FCMNotifier) assertion() (string, error) {
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "RS256"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   f.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(fcmTokenTTL).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
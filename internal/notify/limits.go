@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// Default limits: an identical notification is sent to a channel at most
+// once per DefaultDedupWindow, and a channel gets at most DefaultHourlyLimit
+// notifications an hour
+const (
+	DefaultDedupWindow = 15 * time.Minute
+	DefaultHourlyLimit = 30
+)
+
+// dedupKey identifies a notification's content on one channel
+type dedupKey struct {
+	channel string
+	hash    [sha256.Size]byte
+}
+
+// limiter suppresses repeated notifications and caps how many each channel
+// is sent an hour, so a flapping alert doesn't flood it
+type limiter struct {
+	mu      sync.Mutex
+	window  time.Duration          // 0 disables deduplication
+	perHour int                    // 0 disables the cap
+	seen    map[dedupKey]time.Time // when each notification was last let through
+	sends   map[string][]time.Time // sends of each channel in the last hour, oldest first
+}
+
+func newLimiter(window time.Duration, perHour int) *limiter {
+	return &limiter{
+		window:  window,
+		perHour: perHour,
+		seen:    make(map[dedupKey]time.Time),
+		sends:   make(map[string][]time.Time),
+	}
+}
+
+// allow reports whether a notification may be sent to a channel now,
+// counting it as sent when it may, or why it is suppressed
+func (l *limiter) allow(ch models.NotificationConfig, n models.Notification, now time.Time) (bool, string) {
+	if l == nil {
+		return true, ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	channel := ch.Type + "\x00" + ch.Target
+	key := dedupKey{channel: channel, hash: sha256.Sum256([]byte(n.Symbol + "\x00" + n.Type + "\x00" + n.Message))}
+
+	if l.window > 0 {
+		for k, t := range l.seen {
+			if now.Sub(t) >= l.window {
+				delete(l.seen, k)
+			}
+		}
+		if last, ok := l.seen[key]; ok {
+			return false, fmt.Sprintf("identical notification sent %s ago", now.Sub(last).Round(time.Second))
+		}
+	}
+
+	if l.perHour > 0 {
+		recent := l.sends[channel]
+		for len(recent) > 0 && now.Sub(recent[0]) >= time.Hour {
+			recent = recent[1:]
+		}
+		l.sends[channel] = recent
+		if len(recent) >= l.perHour {
+			return false, fmt.Sprintf("%d notifications sent in the last hour", len(recent))
+		}
+		l.sends[channel] = append(recent, now)
+	}
+
+	if l.window > 0 {
+		l.seen[key] = now
+	}
+	return true, ""
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// fakeClock is a clock the test moves by hand
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)}
+}
+
+var testChannel = models.NotificationConfig{Type: "discord", Target: "https://discord.example/hook", Enabled: true, Events: []string{"price_alert"}}
+
+func alertNotification(message string) models.Notification {
+	return models.Notification{Type: "price_alert", Symbol: "AAPL", Message: message}
+}
+
+func TestLimiterDedup(t *testing.T) {
+	clock := newFakeClock()
+	l := newLimiter(15*time.Minute, 0)
+	n := alertNotification("AAPL crossed 150")
+
+	if ok, reason := l.allow(testChannel, n, clock.Now()); !ok {
+		t.Fatalf("first notification suppressed: %s", reason)
+	}
+
+	// Identical notifications are suppressed inside the window
+	clock.advance(10 * time.Minute)
+	ok, reason := l.allow(testChannel, n, clock.Now())
+	if ok {
+		t.Fatal("identical notification allowed inside the window")
+	}
+	if !strings.Contains(reason, "sent 10m0s ago") {
+		t.Fatalf("reason = %q", reason)
+	}
+
+	// Other messages and other channels are not
+	if ok, reason := l.allow(testChannel, alertNotification("AAPL crossed 160"), clock.Now()); !ok {
+		t.Fatalf("different message suppressed: %s", reason)
+	}
+	other := testChannel
+	other.Target = "https://discord.example/other"
+	if ok, reason := l.allow(other, n, clock.Now()); !ok {
+		t.Fatalf("different channel suppressed: %s", reason)
+	}
+
+	// The window counts from the last notification let through
+	clock.advance(5 * time.Minute)
+	if ok, reason := l.allow(testChannel, n, clock.Now()); !ok {
+		t.Fatalf("identical notification suppressed after the window: %s", reason)
+	}
+	clock.advance(time.Minute)
+	if ok, _ := l.allow(testChannel, n, clock.Now()); ok {
+		t.Fatal("identical notification allowed inside the new window")
+	}
+}
+
+func TestLimiterHourlyCap(t *testing.T) {
+	clock := newFakeClock()
+	l := newLimiter(0, 3)
+
+	for i := range 3 {
+		if ok, reason := l.allow(testChannel, alertNotification("alert"), clock.Now()); !ok {
+			t.Fatalf("notification %d suppressed: %s", i+1, reason)
+		}
+		clock.advance(10 * time.Minute)
+	}
+	ok, reason := l.allow(testChannel, alertNotification("alert"), clock.Now())
+	if ok {
+		t.Fatal("notification over the hourly cap allowed")
+	}
+	if reason != "3 notifications sent in the last hour" {
+		t.Fatalf("reason = %q", reason)
+	}
+
+	// The cap is per channel
+	other := testChannel
+	other.Type = "webhook"
+	if ok, reason := l.allow(other, alertNotification("alert"), clock.Now()); !ok {
+		t.Fatalf("other channel suppressed: %s", reason)
+	}
+
+	// Sends older than an hour stop counting, one at a time
+	clock.advance(30 * time.Minute)
+	if ok, reason := l.allow(testChannel, alertNotification("alert"), clock.Now()); !ok {
+		t.Fatalf("notification suppressed once the first send is an hour old: %s", reason)
+	}
+	if ok, _ := l.allow(testChannel, alertNotification("alert"), clock.Now()); ok {
+		t.Fatal("second notification allowed with only one send expired")
+	}
+	clock.advance(time.Hour)
+	for i := range 3 {
+		if ok, reason := l.allow(testChannel, alertNotification("alert"), clock.Now()); !ok {
+			t.Fatalf("notification %d suppressed after the cap reset: %s", i+1, reason)
+		}
+	}
+}
+
+func TestLimiterSuppressedNotCounted(t *testing.T) {
+	clock := newFakeClock()
+	l := newLimiter(15*time.Minute, 2)
+
+	l.allow(testChannel, alertNotification("first"), clock.Now())
+	if ok, _ := l.allow(testChannel, alertNotification("first"), clock.Now()); ok {
+		t.Fatal("repeated notification allowed")
+	}
+	// The suppressed repeat did not use up the cap
+	if ok, reason := l.allow(testChannel, alertNotification("second"), clock.Now()); !ok {
+		t.Fatalf("second notification suppressed: %s", reason)
+	}
+	if ok, _ := l.allow(testChannel, alertNotification("third"), clock.Now()); ok {
+		t.Fatal("third notification allowed over the cap of 2")
+	}
+}
+
+// fakeNotifier records the messages it is sent
+type fakeNotifier struct {
+	sent []string
+}
+
+func (n *fakeNotifier) Type() string { return "discord" }
+
+func (n *fakeNotifier) Send(notification models.Notification, target string) error {
+	n.sent = append(n.sent, notification.Message)
+	return nil
+}
+
+func TestDeliverAppliesLimits(t *testing.T) {
+	clock := newFakeClock()
+	notifier := &fakeNotifier{}
+	s := NewService()
+	s.RegisterNotifier(notifier)
+	s.SetLimits(15*time.Minute, 0)
+	s.now = clock.Now
+
+	channels := []models.NotificationConfig{testChannel}
+	n := alertNotification("AAPL crossed 150")
+	s.Deliver(n, channels)
+	clock.advance(time.Minute)
+	if sent, _ := s.Deliver(n, channels); len(sent) != 0 {
+		t.Fatalf("repeat sent to %v inside the window", sent)
+	}
+
+	// Test notifications skip the limits
+	if sent, _ := s.Attempt(n, channels); len(sent) != 1 {
+		t.Fatalf("test notification sent to %v", sent)
+	}
+
+	clock.advance(15 * time.Minute)
+	if sent, _ := s.Deliver(n, channels); len(sent) != 1 {
+		t.Fatalf("sent to %v after the window, want discord", sent)
+	}
+	if len(notifier.sent) != 3 {
+		t.Fatalf("notifier sent %d messages, want 3", len(notifier.sent))
+	}
+}
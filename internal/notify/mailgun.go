@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Mailgun API hosts of its US and EU regions
+const (
+	mailgunUS = "https://api.mailgun.net"
+	mailgunEU = "https://api.eu.mailgun.net"
+)
+
+// mailgunTransport sends email with the Mailgun API
+type mailgunTransport struct {
+	apiKey  string
+	domain  string
+	baseURL string
+	client  *http.Client
+}
+
+func newMailgunTransport(config map[string]string) *mailgunTransport {
+	baseURL := mailgunUS
+	if strings.EqualFold(configValue(config, "mailgun_region", "MAILGUN_REGION"), "eu") {
+		baseURL = mailgunEU
+	}
+
+	return &mailgunTransport{
+		apiKey:  configValue(config, "mailgun_api_key", "MAILGUN_API_KEY"),
+		domain:  configValue(config, "mailgun_domain", "MAILGUN_DOMAIN"),
+		baseURL: baseURL,
+		client:  sharedHTTPClient,
+	}
+}
+
+func (t *mailgunTransport) Configured() bool {
+	return t.apiKey != "" && t.domain != ""
+}
+
+// DefaultFrom sends from the Mailgun domain
+func (t *mailgunTransport) DefaultFrom() string {
+	if t.domain == "" {
+		return ""
+	}
+	return "StockAI <alerts@" + t.domain + ">"
+}
+
+func (t *mailgunTransport) Send(from, to, subject, html string) error {
+	data := url.Values{}
+	data.Set("from", from)
+	data.Set("to", to)
+	data.Set("subject", subject)
+	data.Set("html", html)
+
+	apiURL := fmt.Sprintf("%s/v3/%s/messages", t.baseURL, url.PathEscape(t.domain))
+	req, err := http.NewRequest("POST", apiURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", t.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, errResp.Message)
+	}
+
+	return nil
+}
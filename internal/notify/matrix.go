@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// matrixTxnCounter makes Matrix transaction IDs unique within a process
+var matrixTxnCounter atomic.Int64
+
+// MatrixNotifier sends notifications to Matrix rooms
+type MatrixNotifier struct {
+	homeserverURL string
+	accessToken   string
+	client        *http.Client
+}
+
+// NewMatrixNotifier creates a new Matrix notifier
+func NewMatrixNotifier(config map[string]string) *MatrixNotifier {
+	homeserverURL := config["matrix_homeserver_url"]
+	if homeserverURL == "" {
+		homeserverURL = os.Getenv("MATRIX_HOMESERVER_URL")
+	}
+
+	accessToken := config["matrix_access_token"]
+	if accessToken == "" {
+		accessToken = os.Getenv("MATRIX_ACCESS_TOKEN")
+	}
+
+	return &MatrixNotifier{
+		homeserverURL: strings.TrimRight(homeserverURL, "/"),
+		accessToken:   accessToken,
+		client:        sharedHTTPClient,
+	}
+}
+
+// Type returns the notifier type
+func (m *MatrixNotifier) Type() string {
+	return "matrix"
+}
+
+// Send posts a notification to the Matrix room ID target, with an HTML
+// body and a plain-text fallback
+func (m *MatrixNotifier) Send(notification models.Notification, target string) error {
+	if m.homeserverURL == "" || m.accessToken == "" {
+		// Log but don't fail - Matrix not configured
+		fmt.Printf("[MATRIX] Would send to %s: %s - %s\n", target, notification.Title, notification.Message)
+		return nil
+	}
+
+	event := map[string]string{
+		"msgtype":        "m.text",
+		"body":           fmt.Sprintf("%s\n%s", notification.Title, notification.Message),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatMatrixBody(notification),
+	}
+	jsonBody, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	txnID := fmt.Sprintf("stockmarket-%d-%d", time.Now().UnixNano(), matrixTxnCounter.Add(1))
+	apiURL := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.homeserverURL, url.PathEscape(target), txnID)
+
+	req, err := http.NewRequest("PUT", apiURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationFailed, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+m.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("%w: matrix returned status %d: %s %s", ErrNotificationFailed, resp.StatusCode, errResp.ErrCode, errResp.Error)
+	}
+
+	return nil
+}
+
+// formatMatrixBody renders a notification as Matrix HTML: the title in bold,
+// colored by type, then the message with its line breaks
+func formatMatrixBody(n models.Notification) string {
+	color := "#6366f1" // default indigo
+	switch n.Type {
+	case "buy_signal":
+		color = "#22c55e" // green
+	case "sell_signal":
+		color = "#ef4444" // red
+	case "price_alert":
+		color = "#eab308" // yellow
+	}
+
+	message := strings.ReplaceAll(html.EscapeString(n.Message), "\n", "<br>")
+	return fmt.Sprintf(`<p><strong><font color="%s">%s</font></strong></p><p>%s</p>`,
+		color, html.EscapeString(n.Title), message)
+}
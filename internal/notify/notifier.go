@@ -3,26 +3,20 @@ package notify
 import (
 	"errors"
 	"log"
-	"net"
 	"net/http"
+	"slices"
 	"time"
 
+	"stockmarket/internal/httpclient"
 	"stockmarket/internal/models"
 )
 
-// Shared HTTP client with optimized transport for all notifiers
-var sharedHTTPClient = &http.Client{
-	Timeout: 10 * time.Second,
-	Transport: &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   5 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		MaxIdleConns:        50,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-		TLSHandshakeTimeout: 5 * time.Second,
-	},
+// Shared HTTP client for all notifiers, replaced at startup by SetHTTPClient
+var sharedHTTPClient = httpclient.New(httpclient.NotifyDefaults)
+
+// SetHTTPClient replaces the shared client; call it before creating any notifiers
+func SetHTTPClient(client *http.Client) {
+	sharedHTTPClient = client
 }
 
 // Notifier defines the interface for notification dispatchers
@@ -34,29 +28,60 @@ type Notifier interface {
 // ErrNotificationFailed is returned when notification fails
 var ErrNotificationFailed = errors.New("notification failed")
 
-// NewNotifier creates a notifier based on the type
+// NewNotifier creates a notifier based on the type; the webpush and fcm
+// notifiers need a store and have their own constructors
 func NewNotifier(notifType string, config map[string]string) (Notifier, error) {
 	switch notifType {
 	case "email":
 		return NewEmailNotifier(config), nil
+	case "smtp":
+		return NewSMTPNotifier(config), nil
 	case "discord":
 		return NewDiscordNotifier(), nil
 	case "sms":
 		return NewSMSNotifier(config), nil
+	case "pushover":
+		return NewPushoverNotifier(config), nil
+	case "webhook":
+		return NewWebhookNotifier(config), nil
+	case "matrix":
+		return NewMatrixNotifier(config), nil
 	default:
 		return nil, errors.New("unknown notifier type: " + notifType)
 	}
 }
 
+// Retry policy for failed deliveries: the first retry comes after
+// retryBaseDelay, doubling up to retryMaxDelay, and a delivery is marked
+// failed after maxDeliveryAttempts
+const (
+	retryBaseDelay      = time.Minute
+	retryMaxDelay       = time.Hour
+	maxDeliveryAttempts = 8
+	retryBatchSize      = 50
+)
+
+// DeliveryQueue persists deliveries so failed ones are retried, across
+// restarts too
+type DeliveryQueue interface {
+	EnqueueDelivery(d *models.NotificationDelivery) error
+	UpdateDelivery(d *models.NotificationDelivery) error
+	DueDeliveries(now time.Time, limit int) ([]models.NotificationDelivery, error)
+}
+
 // Service manages sending notifications to configured channels
 type Service struct {
 	notifiers map[string]Notifier
+	queue     DeliveryQueue
+	limits    *limiter
+	now       func() time.Time // the clock the limits are applied on
 }
 
 // NewService creates a new notification service
 func NewService() *Service {
 	return &Service{
 		notifiers: make(map[string]Notifier),
+		now:       time.Now,
 	}
 }
 
@@ -65,9 +90,43 @@ func (s *Service) RegisterNotifier(n Notifier) {
 	s.notifiers[n.Type()] = n
 }
 
-// SendToChannels sends a notification to all enabled channels
+// SetQueue makes the service record every delivery in queue and retry the
+// failed ones from RetryDue
+func (s *Service) SetQueue(queue DeliveryQueue) {
+	s.queue = queue
+}
+
+// SetLimits suppresses a notification identical to one a channel was sent
+// within window, and caps each channel at perHour notifications an hour;
+// zero disables either
+func (s *Service) SetLimits(window time.Duration, perHour int) {
+	s.limits = newLimiter(window, perHour)
+}
+
+// SendToChannels sends a notification to all enabled channels, or to those
+// of its channel types when it has any
 func (s *Service) SendToChannels(notification models.Notification, channels []models.NotificationConfig) []error {
-	var errs []error
+	_, errs := s.Deliver(notification, channels)
+	return errs
+}
+
+// Deliver sends a notification like SendToChannels, also reporting the types
+// of the channels it was sent to. Failed sends are queued for retry, and
+// repeated notifications and those over a channel's hourly cap are dropped.
+func (s *Service) Deliver(notification models.Notification, channels []models.NotificationConfig) (sent []string, errs []error) {
+	return s.deliver(notification, channels, false)
+}
+
+// Attempt sends a notification like Deliver but only once, without queueing
+// failed sends or applying the limits, for test notifications
+func (s *Service) Attempt(notification models.Notification, channels []models.NotificationConfig) (sent []string, errs []error) {
+	return s.deliver(notification, channels, true)
+}
+
+// deliver sends a notification to the matching channels; unless it is a
+// test, within the limits and recording each delivery in the queue
+func (s *Service) deliver(notification models.Notification, channels []models.NotificationConfig, test bool) (sent []string, errs []error) {
+	retry := !test && s.queue != nil
 
 	log.Printf("[NOTIFY] Sending notification type=%s to %d channels", notification.Type, len(channels))
 
@@ -77,8 +136,23 @@ func (s *Service) SendToChannels(notification models.Notification, channels []mo
 			continue
 		}
 
+		if len(notification.ChannelTypes) > 0 && !slices.Contains(notification.ChannelTypes, ch.Type) {
+			log.Printf("[NOTIFY] Skipping channel %s not selected for this notification", ch.Type)
+			continue
+		}
+
+		// Digest channels get the daily digest, which covers every event,
+		// instead of each notification
+		isDigest := notification.Type == models.DigestNotification
+		if ch.Digest != isDigest {
+			if ch.Digest {
+				log.Printf("[NOTIFY] Leaving %s notification for the digest on channel %s", notification.Type, ch.Type)
+			}
+			continue
+		}
+
 		// Check if this event should trigger the channel
-		eventMatch := false
+		eventMatch := isDigest
 		for _, event := range ch.Events {
 			if event == notification.Type {
 				eventMatch = true
@@ -97,14 +171,117 @@ func (s *Service) SendToChannels(notification models.Notification, channels []mo
 			continue
 		}
 
+		if !test {
+			if ok, reason := s.limits.allow(ch, notification, s.now()); !ok {
+				log.Printf("[NOTIFY] Suppressed %s notification to %s: %s", notification.Type, ch.Type, reason)
+				continue
+			}
+		}
+
+		// The channel's template, if any, replaces the default message
+		message := notification
+		if ch.Template != "" && !isDigest {
+			rendered, err := renderTemplate(ch.Template, notification, time.Now())
+			if err != nil {
+				log.Printf("[NOTIFY] Template of channel %s failed, sending the default message: %v", ch.Type, err)
+			} else {
+				message = rendered
+			}
+		}
+
+		// Record the delivery first, so it is retried even if the server
+		// stops while sending
+		var delivery *models.NotificationDelivery
+		if retry {
+			delivery = &models.NotificationDelivery{
+				ChannelType:   ch.Type,
+				Target:        ch.Target,
+				Notification:  message,
+				Status:        models.DeliveryPending,
+				NextAttemptAt: time.Now().Add(retryDelay(1)),
+			}
+			if err := s.queue.EnqueueDelivery(delivery); err != nil {
+				log.Printf("[NOTIFY] Failed to queue %s delivery, it will not be retried: %v", ch.Type, err)
+				delivery = nil
+			}
+		}
+
 		log.Printf("[NOTIFY] Sending %s notification to %s", ch.Type, ch.Target)
-		if err := notifier.Send(notification, ch.Target); err != nil {
+		err := notifier.Send(message, ch.Target)
+		if delivery != nil {
+			s.recordAttempt(delivery, err, time.Now())
+		}
+		if err != nil {
 			log.Printf("[NOTIFY] Failed to send %s notification: %v", ch.Type, err)
 			errs = append(errs, err)
 		} else {
 			log.Printf("[NOTIFY] Successfully sent %s notification", ch.Type)
+			sent = append(sent, ch.Type)
 		}
 	}
 
-	return errs
+	return sent, errs
+}
+
+// RetryDue resends the queued deliveries whose next attempt is due,
+// returning how many were sent
+func (s *Service) RetryDue(now time.Time) (int, error) {
+	if s.queue == nil {
+		return 0, nil
+	}
+	due, err := s.queue.DueDeliveries(now, retryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range due {
+		d := &due[i]
+		notifier, ok := s.notifiers[d.ChannelType]
+		if !ok {
+			s.recordAttempt(d, errors.New("no notifier for type: "+d.ChannelType), now)
+			continue
+		}
+
+		err := notifier.Send(d.Notification, d.Target)
+		s.recordAttempt(d, err, time.Now())
+		if err != nil {
+			log.Printf("[NOTIFY] Retry %d of %s delivery %d failed: %v", d.Attempts, d.ChannelType, d.ID, err)
+			continue
+		}
+		log.Printf("[NOTIFY] Retry %d of %s delivery %d succeeded", d.Attempts, d.ChannelType, d.ID)
+		sent++
+	}
+	return sent, nil
+}
+
+// recordAttempt saves the outcome of sending a delivery: sent, retried later
+// with backoff, or failed for good after the last attempt
+func (s *Service) recordAttempt(d *models.NotificationDelivery, err error, now time.Time) {
+	d.Attempts++
+	switch {
+	case err == nil:
+		d.Status = models.DeliverySent
+		d.LastError = ""
+	case d.Attempts >= maxDeliveryAttempts:
+		d.Status = models.DeliveryFailed
+		d.LastError = err.Error()
+		log.Printf("[NOTIFY] Giving up on %s delivery %d after %d attempts", d.ChannelType, d.ID, d.Attempts)
+	default:
+		d.Status = models.DeliveryPending
+		d.LastError = err.Error()
+		d.NextAttemptAt = now.Add(retryDelay(d.Attempts))
+	}
+	if err := s.queue.UpdateDelivery(d); err != nil {
+		log.Printf("[NOTIFY] Failed to update %s delivery %d: %v", d.ChannelType, d.ID, err)
+	}
+}
+
+// retryDelay is the wait after a delivery's attempts-th failed attempt
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
 }
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"stockmarket/internal/models"
+)
+
+// pushoverURL is the Pushover messages API
+const pushoverURL = "https://api.pushover.net/1/messages.json"
+
+// Pushover priorities, and how often and for how long an emergency
+// notification is repeated until acknowledged, in seconds
+const (
+	pushoverNormal    = 0
+	pushoverEmergency = 2
+	pushoverRetry     = 60
+	pushoverExpire    = 3600
+)
+
+// Pushover limits, in characters
+const (
+	pushoverMaxTitle   = 250
+	pushoverMaxMessage = 1024
+)
+
+// PushoverNotifier sends notifications via Pushover
+type PushoverNotifier struct {
+	appToken string
+	client   *http.Client
+}
+
+// NewPushoverNotifier creates a new Pushover notifier
+func NewPushoverNotifier(config map[string]string) *PushoverNotifier {
+	appToken := config["pushover_app_token"]
+	if appToken == "" {
+		appToken = os.Getenv("PUSHOVER_APP_TOKEN")
+	}
+
+	return &PushoverNotifier{
+		appToken: appToken,
+		client:   sharedHTTPClient,
+	}
+}
+
+// Type returns the notifier type
+func (p *PushoverNotifier) Type() string {
+	return "pushover"
+}
+
+// Send sends a Pushover notification to the user key target. SELL signals
+// use emergency priority, repeating until acknowledged.
+func (p *PushoverNotifier) Send(notification models.Notification, target string) error {
+	if p.appToken == "" {
+		// Log but don't fail - Pushover not configured
+		fmt.Printf("[PUSHOVER] Would send to %s: %s - %s\n", target, notification.Title, notification.Message)
+		return nil
+	}
+
+	data := url.Values{}
+	data.Set("token", p.appToken)
+	data.Set("user", target)
+	data.Set("title", truncateRunes(notification.Title, pushoverMaxTitle))
+	data.Set("message", truncateRunes(notification.Message, pushoverMaxMessage))
+	if notification.Type == "sell_signal" {
+		data.Set("priority", strconv.Itoa(pushoverEmergency))
+		data.Set("retry", strconv.Itoa(pushoverRetry))
+		data.Set("expire", strconv.Itoa(pushoverExpire))
+	} else {
+		data.Set("priority", strconv.Itoa(pushoverNormal))
+	}
+
+	req, err := http.NewRequest("POST", pushoverURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("%w: pushover returned status %d: %s", ErrNotificationFailed, resp.StatusCode, strings.Join(errResp.Errors, "; "))
+	}
+
+	return nil
+}
+
+// truncateRunes shortens s to at most n characters, ending with "..."
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+// sendGridURL is the SendGrid v3 mail send API
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// sendGridTransport sends email with the SendGrid API
+type sendGridTransport struct {
+	apiKey string
+	client *http.Client
+}
+
+func newSendGridTransport(config map[string]string) *sendGridTransport {
+	return &sendGridTransport{
+		apiKey: configValue(config, "sendgrid_api_key", "SENDGRID_API_KEY"),
+		client: sharedHTTPClient,
+	}
+}
+
+func (t *sendGridTransport) Configured() bool {
+	return t.apiKey != ""
+}
+
+// DefaultFrom is empty: SendGrid only sends from verified senders
+func (t *sendGridTransport) DefaultFrom() string {
+	return ""
+}
+
+func (t *sendGridTransport) Send(from, to, subject, html string) error {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %v", err)
+	}
+
+	type address struct {
+		Email string `json:"email"`
+		Name  string `json:"name,omitempty"`
+	}
+	payload := map[string]any{
+		"personalizations": []map[string]any{{"to": []address{{Email: to}}}},
+		"from":             address{Email: sender.Address, Name: sender.Name},
+		"subject":          subject,
+		"content":          []map[string]string{{"type": "text/html", "value": html}},
+	}
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", sendGridURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		messages := make([]string, len(errResp.Errors))
+		for i, e := range errResp.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(messages, "; "))
+	}
+
+	return nil
+}
@@ -60,6 +60,10 @@ func (s *SMSNotifier) Send(notification models.Notification, target string) erro
 	apiURL := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", s.accountSID)
 
 	message := fmt.Sprintf("%s\n%s: %s", notification.Title, notification.Symbol, notification.Message)
+	if notification.Templated {
+		// A template gives the whole text, for terse messages
+		message = notification.Message
+	}
 	if len(message) > 160 {
 		message = message[:157] + "..."
 	}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP connection security: STARTTLS after connecting, TLS from the start
+// (usually port 465) or none
+const (
+	smtpSTARTTLS = "starttls"
+	smtpTLS      = "tls"
+	smtpNone     = "none"
+)
+
+// smtpTimeout bounds connecting to the server and sending one email
+const smtpTimeout = 30 * time.Second
+
+// smtpTransport sends email through an SMTP server
+type smtpTransport struct {
+	host     string
+	port     string
+	security string
+	username string
+	password string
+	from     string
+}
+
+func newSMTPTransport(config map[string]string) *smtpTransport {
+	t := &smtpTransport{
+		host:     configValue(config, "smtp_host", "SMTP_HOST"),
+		port:     configValue(config, "smtp_port", "SMTP_PORT"),
+		security: strings.ToLower(configValue(config, "smtp_tls", "SMTP_TLS")),
+		username: configValue(config, "smtp_username", "SMTP_USERNAME"),
+		password: configValue(config, "smtp_password", "SMTP_PASSWORD"),
+		from:     configValue(config, "smtp_from", "SMTP_FROM"),
+	}
+	if t.port == "" {
+		t.port = "587"
+	}
+	if t.security == "" {
+		t.security = smtpSTARTTLS
+	}
+	if t.from == "" {
+		t.from = t.username
+	}
+	return t
+}
+
+func (t *smtpTransport) Configured() bool {
+	return t.host != ""
+}
+
+func (t *smtpTransport) DefaultFrom() string {
+	return t.from
+}
+
+func (t *smtpTransport) Send(from, to, subject, html string) error {
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %v", err)
+	}
+	toAddr, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid email address: %v", err)
+	}
+
+	msg, err := buildEmailMessage(fromAddr, toAddr, subject, html)
+	if err != nil {
+		return err
+	}
+	return t.deliver(fromAddr.Address, toAddr.Address, msg)
+}
+
+// deliver connects to the server, secures the connection and authenticates
+// as configured, and sends one message
+func (t *smtpTransport) deliver(from, to string, msg []byte) error {
+	addr := net.JoinHostPort(t.host, t.port)
+	tlsConfig := &tls.Config{ServerName: t.host}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if t.security == smtpTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if t.security == smtpSTARTTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if t.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", t.username, t.password, t.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildEmailMessage renders an HTML email with its headers, the body
+// quoted-printable
+func buildEmailMessage(from, to *mail.Address, subject, html string) ([]byte, error) {
+	id := make([]byte, 16)
+	rand.Read(id)
+	domain := "localhost"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(html)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
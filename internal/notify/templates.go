@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// maxTemplateLength bounds a channel's message template
+const maxTemplateLength = 2000
+
+// templateData are the fields a channel's message template can use, e.g.
+// {{.Action}} {{.Symbol}} at {{printf "%.2f" .Price}}
+type templateData struct {
+	Type       string // "buy_signal", "sell_signal", "price_alert", ...
+	Title      string
+	Message    string // the default message
+	Symbol     string
+	Action     string // BUY, SELL, ADD or TRIM for signals
+	Price      float64
+	Confidence float64 // 0-1, for signals
+	Time       time.Time
+}
+
+// templateFuncs are the functions message templates can call besides the
+// text/template builtins
+var templateFuncs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"price":   func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"percent": func(v float64) string { return fmt.Sprintf("%.0f%%", v*100) },
+}
+
+// CheckTemplate reports whether text is a valid message template by
+// rendering it with sample data
+func CheckTemplate(text string) error {
+	if len(text) > maxTemplateLength {
+		return fmt.Errorf("longer than %d characters", maxTemplateLength)
+	}
+	sample := models.Notification{
+		Type:       "buy_signal",
+		Title:      "BUY Signal: AAPL",
+		Message:    "• Strong earnings",
+		Symbol:     "AAPL",
+		Action:     "BUY",
+		Price:      190.5,
+		Confidence: 0.8,
+	}
+	rendered, err := renderTemplate(text, sample, time.Now())
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(rendered.Message) == "" {
+		return errors.New("renders an empty message")
+	}
+	return nil
+}
+
+// renderTemplate replaces a notification's message with a channel's message
+// template applied to it
+func renderTemplate(text string, n models.Notification, now time.Time) (models.Notification, error) {
+	tmpl, err := template.New("message").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return n, err
+	}
+	var b strings.Builder
+	err = tmpl.Execute(&b, templateData{
+		Type:       n.Type,
+		Title:      n.Title,
+		Message:    n.Message,
+		Symbol:     n.Symbol,
+		Action:     n.Action,
+		Price:      n.Price,
+		Confidence: n.Confidence,
+		Time:       now,
+	})
+	if err != nil {
+		return n, err
+	}
+	n.Message = strings.TrimSpace(b.String())
+	n.Templated = true
+	return n, nil
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// WebhookSignatureHeader carries the HMAC-SHA256 of a webhook body, as
+// "sha256=" and the hex digest, when a webhook secret is set
+const WebhookSignatureHeader = "X-Stockmarket-Signature"
+
+// WebhookNotifier posts notifications as JSON to any URL
+type WebhookNotifier struct {
+	secret string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a new webhook notifier
+func NewWebhookNotifier(config map[string]string) *WebhookNotifier {
+	secret := config["webhook_secret"]
+	if secret == "" {
+		secret = os.Getenv("WEBHOOK_SECRET")
+	}
+
+	return &WebhookNotifier{
+		secret: secret,
+		client: sharedHTTPClient,
+	}
+}
+
+// Type returns the notifier type
+func (wh *WebhookNotifier) Type() string {
+	return "webhook"
+}
+
+// Send posts the notification as JSON to the URL target, signed with the
+// webhook secret if one is set
+func (wh *WebhookNotifier) Send(notification models.Notification, target string) error {
+	if target == "" {
+		fmt.Println("[WEBHOOK] No URL provided, skipping")
+		return nil
+	}
+
+	if notification.SentAt.IsZero() {
+		notification.SentAt = time.Now().UTC()
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if wh.secret != "" {
+		mac := hmac.New(sha256.New, []byte(wh.secret))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: webhook returned status %d", ErrNotificationFailed, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"stockmarket/internal/models"
+)
+
+// defaultVAPIDSubject is the contact push services are given when
+// VAPID_SUBJECT is not set
+const defaultVAPIDSubject = "mailto:admin@example.com"
+
+// Web Push delivery: how long a push service keeps an undelivered message, in
+// seconds, how long a VAPID token is valid, the record size of the encrypted
+// body and the longest message sent
+const (
+	webPushTTL        = 24 * 60 * 60
+	vapidTokenTTL     = 12 * time.Hour
+	webPushRecordSize = 4096
+	webPushMaxMessage = 1000
+)
+
+// PushStore lists the browser push subscriptions and forgets the expired ones
+type PushStore interface {
+	GetPushSubscriptions() ([]models.PushSubscription, error)
+	DeletePushSubscription(endpoint string) error
+}
+
+// VAPIDKeys identify this server to browser push services (RFC 8292)
+type VAPIDKeys struct {
+	private *ecdsa.PrivateKey
+}
+
+// GenerateVAPIDKeys creates a new P-256 key pair
+func GenerateVAPIDKeys() (*VAPIDKeys, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPIDKeys{private: private}, nil
+}
+
+// ParseVAPIDKeys reads keys saved with PrivateKey
+func ParseVAPIDKeys(privateKey string) (*VAPIDKeys, error) {
+	raw, err := decodePushKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	private, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPIDKeys{private: private}, nil
+}
+
+// PublicKey is the application server key browsers subscribe with, as
+// unpadded base64url
+func (k *VAPIDKeys) PublicKey() string {
+	public, _ := k.private.PublicKey.Bytes()
+	return base64.RawURLEncoding.EncodeToString(public)
+}
+
+// PrivateKey is the private key as unpadded base64url
+func (k *VAPIDKeys) PrivateKey() string {
+	private, _ := k.private.Bytes()
+	return base64.RawURLEncoding.EncodeToString(private)
+}
+
+// WebPushNotifier sends notifications to every subscribed browser
+type WebPushNotifier struct {
+	keys    *VAPIDKeys
+	store   PushStore
+	subject string
+	client  *http.Client
+}
+
+// NewWebPushNotifier creates a new Web Push notifier; keys may be nil when
+// none could be loaded
+func NewWebPushNotifier(keys *VAPIDKeys, store PushStore) *WebPushNotifier {
+	subject := os.Getenv("VAPID_SUBJECT")
+	if subject == "" {
+		subject = defaultVAPIDSubject
+	}
+
+	return &WebPushNotifier{
+		keys:    keys,
+		store:   store,
+		subject: subject,
+		client:  sharedHTTPClient,
+	}
+}
+
+// Type returns the notifier type
+func (wp *WebPushNotifier) Type() string {
+	return "webpush"
+}
+
+// Send pushes the notification to every subscribed browser, ignoring target.
+// Subscriptions the push service reports gone are deleted.
+func (wp *WebPushNotifier) Send(notification models.Notification, target string) error {
+	if wp.keys == nil {
+		// Log but don't fail - Web Push not configured
+		fmt.Printf("[WEBPUSH] Would send: %s - %s\n", notification.Title, notification.Message)
+		return nil
+	}
+
+	subs, err := wp.store.GetPushSubscriptions()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationFailed, err)
+	}
+	if len(subs) == 0 {
+		fmt.Println("[WEBPUSH] No browser subscriptions, skipping")
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"type":    notification.Type,
+		"title":   notification.Title,
+		"message": truncateRunes(notification.Message, webPushMaxMessage),
+		"symbol":  notification.Symbol,
+		"url":     "/alerts",
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, sub := range subs {
+		if err := wp.push(sub, payload); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %d of %d browsers: %s", ErrNotificationFailed, len(failed), len(subs), strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// push encrypts the payload for one subscription and posts it to its push
+// service
+func (wp *WebPushNotifier) push(sub models.PushSubscription, payload []byte) error {
+	body, err := encryptPushPayload(sub, payload)
+	if err != nil {
+		return err
+	}
+	authorization, err := wp.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(webPushTTL))
+	req.Header.Set("Urgency", "high")
+
+	resp, err := wp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		// The browser unsubscribed or the subscription expired
+		if err := wp.store.DeletePushSubscription(sub.Endpoint); err != nil {
+			return err
+		}
+		fmt.Printf("[WEBPUSH] Removed expired subscription %s\n", pushService(sub.Endpoint))
+		return nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("%s returned status %d", pushService(sub.Endpoint), resp.StatusCode)
+	}
+
+	return nil
+}
+
+// vapidAuthorization signs a VAPID token for the push service of endpoint
+func (wp *WebPushNotifier) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, _ := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenTTL).Unix(),
+		"sub": wp.subject,
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, wp.keys.private, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, wp.keys.PublicKey()), nil
+}
+
+// encryptPushPayload encrypts a payload for a subscription as a single
+// aes128gcm record (RFC 8291)
+func encryptPushPayload(sub models.PushSubscription, payload []byte) ([]byte, error) {
+	userPublic, authSecret, err := subscriptionKeys(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	curve := ecdh.P256()
+	browserKey, err := curve.NewPublicKey(userPublic)
+	if err != nil {
+		return nil, err
+	}
+	serverKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := serverKey.ECDH(browserKey)
+	if err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	ikm, err := hkdf.Key(sha256.New, shared, authSecret, "WebPush: info\x00"+string(userPublic)+string(serverPublic), 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt, record size, key length and the server's public key
+	body := make([]byte, 0, 16+4+1+len(serverPublic)+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, webPushRecordSize)
+	body = append(body, byte(len(serverPublic)))
+	body = append(body, serverPublic...)
+
+	// The last (and only) record ends with the 0x02 delimiter
+	record := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(body, nonce, record, nil), nil
+}
+
+// CheckPushSubscription reports whether a subscription has an HTTPS
+// endpoint and well-formed keys
+func CheckPushSubscription(sub models.PushSubscription) error {
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("endpoint must be an https URL")
+	}
+	userPublic, _, err := subscriptionKeys(sub)
+	if err != nil {
+		return err
+	}
+	if _, err := ecdh.P256().NewPublicKey(userPublic); err != nil {
+		return errors.New("p256dh is not a P-256 public key")
+	}
+	return nil
+}
+
+// subscriptionKeys decodes the browser's public key and auth secret
+func subscriptionKeys(sub models.PushSubscription) (userPublic, authSecret []byte, err error) {
+	if userPublic, err = decodePushKey(sub.Keys.P256dh); err != nil || len(userPublic) != 65 {
+		return nil, nil, errors.New("p256dh must be a 65-byte base64url key")
+	}
+	if authSecret, err = decodePushKey(sub.Keys.Auth); err != nil || len(authSecret) != 16 {
+		return nil, nil, errors.New("auth must be a 16-byte base64url secret")
+	}
+	return userPublic, authSecret, nil
+}
+
+// decodePushKey decodes base64url, padded or not
+func decodePushKey(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// pushService names the push service of an endpoint for logs, without the
+// subscription's secret path
+func pushService(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "push service"
+}
@@ -0,0 +1,172 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"stockmarket/internal/models"
+)
+
+// metricNames maps the metric names of the rule syntax to metrics
+var metricNames = map[string]string{
+	"price":  MetricPrice,
+	"change": MetricChangePercent,
+	"volume": MetricVolumeRatio,
+	"rsi":    MetricRSI,
+}
+
+// Parse reads a rule such as "price > 150 AND volume > 2x" or
+// "rsi < 30 OR price < 100". Comparisons are a metric (price, change in
+// percent, volume as a multiple of the average volume, or rsi, optionally
+// with its period as rsi(9)), > or <, and a number; AND binds tighter than
+// OR, and parentheses group. The rule is validated.
+func Parse(expr string) (models.AlertRule, error) {
+	p := &parser{tokens: tokenize(expr)}
+	rule, err := p.or()
+	if err != nil {
+		return models.AlertRule{}, err
+	}
+	if tok := p.peek(); tok != "" {
+		return models.AlertRule{}, fmt.Errorf("unexpected %s", token(tok))
+	}
+	if err := Validate(&rule); err != nil {
+		return models.AlertRule{}, err
+	}
+	return rule, nil
+}
+
+// tokenize splits a rule into words, numbers and the symbols ( ) > <
+func tokenize(expr string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range expr {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case strings.ContainsRune("()<>", r):
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// token quotes a token for an error message, naming the end of the rule
+// when there are no tokens left
+func token(tok string) string {
+	if tok == "" {
+		return "the end of the rule"
+	}
+	return "'" + tok + "'"
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+// or reads comparisons or groups joined by OR
+func (p *parser) or() (models.AlertRule, error) {
+	return p.group("or", p.and)
+}
+
+// and reads comparisons or groups joined by AND
+func (p *parser) and() (models.AlertRule, error) {
+	return p.group("and", p.term)
+}
+
+// group reads operands joined by op, collapsing a single operand
+func (p *parser) group(op string, operand func() (models.AlertRule, error)) (models.AlertRule, error) {
+	first, err := operand()
+	if err != nil {
+		return first, err
+	}
+	rules := []models.AlertRule{first}
+	for strings.EqualFold(p.peek(), op) {
+		p.next()
+		rule, err := operand()
+		if err != nil {
+			return rule, err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 1 {
+		return first, nil
+	}
+	return models.AlertRule{Op: op, Rules: rules}, nil
+}
+
+// term reads a parenthesized rule or a comparison
+func (p *parser) term() (models.AlertRule, error) {
+	if p.peek() == "(" {
+		p.next()
+		rule, err := p.or()
+		if err != nil {
+			return rule, err
+		}
+		if tok := p.next(); tok != ")" {
+			return rule, fmt.Errorf("expected ) but found %s", token(tok))
+		}
+		return rule, nil
+	}
+
+	name := strings.ToLower(p.next())
+	metric, ok := metricNames[name]
+	if !ok {
+		return models.AlertRule{}, fmt.Errorf("unknown metric %s, expected price, change, volume or rsi", token(name))
+	}
+	rule := models.AlertRule{Metric: metric}
+
+	if metric == MetricRSI && p.peek() == "(" {
+		p.next()
+		period, err := strconv.Atoi(p.next())
+		if err != nil {
+			return rule, fmt.Errorf("invalid RSI period")
+		}
+		if tok := p.next(); tok != ")" {
+			return rule, fmt.Errorf("expected ) but found %s", token(tok))
+		}
+		rule.Period = period
+	}
+
+	switch tok := p.next(); tok {
+	case ">":
+		rule.Compare = "above"
+	case "<":
+		rule.Compare = "below"
+	default:
+		return rule, fmt.Errorf("expected > or < after %s but found %s", name, token(tok))
+	}
+
+	tok := p.next()
+	value, err := strconv.ParseFloat(strings.TrimLeft(strings.TrimRight(tok, "%xX×"), "$"), 64)
+	if err != nil {
+		return rule, fmt.Errorf("expected a number but found %s", token(tok))
+	}
+	rule.Value = value
+	return rule, nil
+}
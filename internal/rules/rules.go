@@ -0,0 +1,172 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"stockmarket/internal/indicators"
+	"stockmarket/internal/models"
+)
+
+// Metrics a rule compares
+const (
+	MetricPrice         = "price"          // latest price
+	MetricChangePercent = "change_percent" // change from the previous close, in percent
+	MetricVolumeRatio   = "volume_ratio"   // volume so far today over the average daily volume
+	MetricRSI           = "rsi"            // RSI of the daily closes
+)
+
+// Bounds of a rule tree
+const (
+	MaxComparisons = 10
+	MaxDepth       = 3
+	MaxRSIPeriod   = 50
+)
+
+// Source supplies the metric values rules are evaluated on; ok is false
+// when a value is unavailable, which makes its comparison false
+type Source interface {
+	Value(metric string, period int) (value float64, ok bool)
+}
+
+// Validate checks a rule tree, defaulting RSI periods and lowercasing
+// operators
+func Validate(rule *models.AlertRule) error {
+	comparisons := 0
+	return validate(rule, 1, &comparisons)
+}
+
+func validate(rule *models.AlertRule, depth int, comparisons *int) error {
+	if depth > MaxDepth {
+		return fmt.Errorf("rules can be nested %d deep at most", MaxDepth)
+	}
+
+	if rule.Op != "" {
+		rule.Op = strings.ToLower(rule.Op)
+		if rule.Op != "and" && rule.Op != "or" {
+			return fmt.Errorf("unknown operator '%s', expected and or or", rule.Op)
+		}
+		if len(rule.Rules) == 0 {
+			return errors.New("a rule group needs at least one rule")
+		}
+		for i := range rule.Rules {
+			if err := validate(&rule.Rules[i], depth+1, comparisons); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if *comparisons++; *comparisons > MaxComparisons {
+		return fmt.Errorf("a rule can have %d comparisons at most", MaxComparisons)
+	}
+	if rule.Compare != "above" && rule.Compare != "below" {
+		return fmt.Errorf("unknown comparison '%s', expected above or below", rule.Compare)
+	}
+	switch rule.Metric {
+	case MetricPrice, MetricVolumeRatio:
+		if rule.Value <= 0 {
+			return fmt.Errorf("%s must be compared with a positive value", rule.Metric)
+		}
+	case MetricChangePercent:
+	case MetricRSI:
+		if rule.Period == 0 {
+			rule.Period = indicators.DefaultRSIPeriod
+		}
+		if rule.Period < 2 || rule.Period > MaxRSIPeriod {
+			return fmt.Errorf("RSI period must be 2-%d days", MaxRSIPeriod)
+		}
+		if rule.Value <= 0 || rule.Value >= 100 {
+			return errors.New("RSI must be compared with a level between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("unknown metric '%s'", rule.Metric)
+	}
+	if rule.Metric != MetricRSI {
+		rule.Period = 0
+	}
+	return nil
+}
+
+// Evaluate reports whether a rule holds. Groups stop at the first rule that
+// decides them, so src is only asked for the metrics needed.
+func Evaluate(rule models.AlertRule, src Source) bool {
+	switch rule.Op {
+	case "and":
+		for _, r := range rule.Rules {
+			if !Evaluate(r, src) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, r := range rule.Rules {
+			if Evaluate(r, src) {
+				return true
+			}
+		}
+		return false
+	}
+
+	value, ok := src.Value(rule.Metric, rule.Period)
+	if !ok {
+		return false
+	}
+	if rule.Compare == "below" {
+		return value < rule.Value
+	}
+	return value > rule.Value
+}
+
+// Describe formats a rule in the syntax Parse reads, e.g.
+// "price > 150 AND volume > 2x"
+func Describe(rule models.AlertRule) string {
+	return describe(rule, false)
+}
+
+func describe(rule models.AlertRule, nested bool) string {
+	if rule.Op != "" {
+		parts := make([]string, len(rule.Rules))
+		for i, r := range rule.Rules {
+			parts[i] = describe(r, true)
+		}
+		s := strings.Join(parts, " "+strings.ToUpper(rule.Op)+" ")
+		if nested && len(parts) > 1 {
+			s = "(" + s + ")"
+		}
+		return s
+	}
+
+	op := ">"
+	if rule.Compare == "below" {
+		op = "<"
+	}
+	value := strconv.FormatFloat(rule.Value, 'f', -1, 64)
+	switch rule.Metric {
+	case MetricChangePercent:
+		return fmt.Sprintf("change %s %s%%", op, value)
+	case MetricVolumeRatio:
+		return fmt.Sprintf("volume %s %sx", op, value)
+	case MetricRSI:
+		if rule.Period != indicators.DefaultRSIPeriod {
+			return fmt.Sprintf("rsi(%d) %s %s", rule.Period, op, value)
+		}
+		return fmt.Sprintf("rsi %s %s", op, value)
+	}
+	return fmt.Sprintf("%s %s %s", rule.Metric, op, value)
+}
+
+// Uses reports whether a rule compares a metric
+func Uses(rule models.AlertRule, metric string) bool {
+	if rule.Op == "" {
+		return rule.Metric == metric
+	}
+	for _, r := range rule.Rules {
+		if Uses(r, metric) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"stockmarket/internal/models"
+)
+
+func TestParseDescribe(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // Describe of the parsed rule
+	}{
+		{"price > 150", "price > 150"},
+		{"price > $150.5", "price > 150.5"},
+		{"change < -2.5%", "change < -2.5%"},
+		{"volume > 2X", "volume > 2x"},
+		{"rsi < 30", "rsi < 30"},
+		{"rsi(14) < 30", "rsi < 30"},
+		{"RSI(9) > 70", "rsi(9) > 70"},
+		{"price > 150 and volume > 2x", "price > 150 AND volume > 2x"},
+		{"rsi < 30 OR price < 100", "rsi < 30 OR price < 100"},
+		// AND binds tighter than OR
+		{"price > 1 OR price < 0.5 AND volume > 3x", "price > 1 OR (price < 0.5 AND volume > 3x)"},
+		{"price > 1 AND price < 2 OR volume > 3x", "(price > 1 AND price < 2) OR volume > 3x"},
+		{"(price > 1 OR price < 0.5) AND volume > 3x", "(price > 1 OR price < 0.5) AND volume > 3x"},
+		// Parentheses around a single comparison collapse
+		{"((price > 1))", "price > 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			rule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got := Describe(rule)
+			if got != tt.want {
+				t.Fatalf("Describe = %q, want %q", got, tt.want)
+			}
+
+			// The description parses back to the same rule
+			again, err := Parse(got)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", got, err)
+			}
+			if Describe(again) != got {
+				t.Fatalf("round trip = %q, want %q", Describe(again), got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // part of the error
+	}{
+		{"", "unknown metric the end of the rule"},
+		{"(price > 1", "expected ) but found the end of the rule"},
+		{"(price > 1 OR volume > 2x", "expected ) but found the end of the rule"},
+		{"price > 1)", "unexpected ')'"},
+		{"price > 1 volume > 2x", "unexpected 'volume'"},
+		{"price >", "expected a number but found the end of the rule"},
+		{"price > abc", "expected a number but found 'abc'"},
+		{"price = 1", "expected > or < after price but found '='"},
+		{"cost > 1", "unknown metric 'cost'"},
+		{"price > 1 AND", "unknown metric the end of the rule"},
+		{"rsi(x) < 30", "invalid RSI period"},
+		{"rsi(9 < 30", "expected ) but found '<'"},
+		{"rsi(1) < 30", "RSI period must be 2-50 days"},
+		{"rsi < 100", "RSI must be compared with a level between 0 and 100"},
+		{"price > 0", "price must be compared with a positive value"},
+		{"price > 1 AND (price < 2 OR (volume > 2x AND rsi < 30))", "nested 3 deep at most"},
+		{strings.Repeat("price > 1 OR ", MaxComparisons) + "price > 1", "10 comparisons at most"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil {
+				t.Fatalf("Parse(%q) succeeded, want an error", tt.expr)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	// The limits themselves are allowed
+	if _, err := Parse("price > 1 AND (price < 2 OR volume > 2x)"); err != nil {
+		t.Fatalf("rule %d deep: %v", MaxDepth, err)
+	}
+	if _, err := Parse(strings.Repeat("price > 1 OR ", MaxComparisons-1) + "price > 1"); err != nil {
+		t.Fatalf("%d comparisons: %v", MaxComparisons, err)
+	}
+}
+
+// fakeSource serves metric values, recording which were asked for; metrics
+// without a value are unavailable
+type fakeSource struct {
+	values map[string]float64
+	asked  []string
+}
+
+func (s *fakeSource) Value(metric string, period int) (float64, bool) {
+	s.asked = append(s.asked, metric)
+	value, ok := s.values[metric]
+	return value, ok
+}
+
+func TestEvaluate(t *testing.T) {
+	values := map[string]float64{MetricPrice: 120, MetricVolumeRatio: 3}
+	tests := []struct {
+		expr  string
+		want  bool
+		asked []string
+	}{
+		{"price > 100", true, []string{MetricPrice}},
+		{"price < 100", false, []string{MetricPrice}},
+		{"price > 100 AND volume > 2x", true, []string{MetricPrice, MetricVolumeRatio}},
+		// AND stops at the first false rule
+		{"price < 100 AND volume > 2x", false, []string{MetricPrice}},
+		// OR stops at the first true rule
+		{"price > 100 OR volume > 2x", true, []string{MetricPrice}},
+		// Unavailable values make their comparison false either way
+		{"rsi < 30", false, []string{MetricRSI}},
+		{"rsi > 30", false, []string{MetricRSI}},
+		{"rsi < 30 AND price > 100", false, []string{MetricRSI}},
+		{"rsi < 30 OR price > 100", true, []string{MetricRSI, MetricPrice}},
+		{"change > 1% OR rsi < 30", false, []string{MetricChangePercent, MetricRSI}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			rule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src := &fakeSource{values: values}
+			if got := Evaluate(rule, src); got != tt.want {
+				t.Fatalf("Evaluate = %v, want %v", got, tt.want)
+			}
+			if strings.Join(src.asked, ",") != strings.Join(tt.asked, ",") {
+				t.Fatalf("asked for %v, want %v", src.asked, tt.asked)
+			}
+		})
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	rule := models.AlertRule{Op: "AND", Rules: []models.AlertRule{
+		{Metric: MetricRSI, Compare: "below", Value: 30},
+		{Metric: MetricPrice, Compare: "above", Value: 10, Period: 9},
+	}}
+	if err := Validate(&rule); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Op != "and" || rule.Rules[0].Period != 14 || rule.Rules[1].Period != 0 {
+		t.Fatalf("validated rule = %+v", rule)
+	}
+
+	for _, bad := range []models.AlertRule{
+		{Op: "xor", Rules: []models.AlertRule{{Metric: MetricPrice, Compare: "above", Value: 1}}},
+		{Op: "or"},
+		{Metric: MetricPrice, Compare: "equals", Value: 1},
+	} {
+		if err := Validate(&bad); err == nil {
+			t.Fatalf("Validate(%+v) succeeded, want an error", bad)
+		}
+	}
+}
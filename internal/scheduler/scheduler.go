@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Job is a task that runs at a fixed local time every day, or on chosen weekdays
+type Job struct {
+	Name     string
+	Hour     int
+	Minute   int
+	Location *time.Location // time zone of Hour/Minute, defaults to time.Local
+	Weekdays []time.Weekday // days the job runs on, every day when empty
+	CatchUp  bool           // run once on startup if a scheduled run was missed
+	Run      func(ctx context.Context) error
+}
+
+// Store persists the last scheduled run of each job
+type Store interface {
+	GetJobLastRun(name string) (time.Time, error)
+	SetJobLastRun(name string, t time.Time) error
+}
+
+// Clock provides the current time
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Scheduler runs daily jobs and catches up on runs missed while the server was down
+type Scheduler struct {
+	store Store
+	clock Clock
+	jobs  []Job
+	mu    sync.Mutex
+}
+
+// New creates a new scheduler backed by store
+func New(store Store) *Scheduler {
+	return NewWithClock(store, systemClock{})
+}
+
+// NewWithClock creates a new scheduler using the given clock
+func NewWithClock(store Store, clock Clock) *Scheduler {
+	return &Scheduler{store: store, clock: clock}
+}
+
+// Register adds a job to the scheduler; it must be called before Start
+func (s *Scheduler) Register(job Job) {
+	if job.Location == nil {
+		job.Location = time.Local
+	}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, job)
+	s.mu.Unlock()
+}
+
+// Start performs the startup catch-up pass and runs each job on its schedule until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]Job{}, s.jobs...)
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		go func(job Job) {
+			s.CatchUp(ctx, job)
+			s.loop(ctx, job)
+		}(job)
+	}
+	log.Printf("[SCHEDULER] Started with %d jobs", len(jobs))
+}
+
+// CatchUp handles runs missed since the job last ran. The missed boundary is
+// recorded before the job runs, so repeated restarts never catch up twice.
+func (s *Scheduler) CatchUp(ctx context.Context, job Job) {
+	now := s.clock.Now()
+	lastRun, err := s.store.GetJobLastRun(job.Name)
+	if err != nil {
+		log.Printf("[SCHEDULER] Failed to load state for %s: %v", job.Name, err)
+		return
+	}
+
+	latest := PreviousRun(job, now)
+	if lastRun.IsZero() {
+		// First start: nothing could have been missed
+		s.markRun(job, latest)
+		return
+	}
+
+	missed := MissedRuns(job, lastRun, now)
+	if missed == 0 {
+		return
+	}
+
+	s.markRun(job, latest)
+	if !job.CatchUp {
+		log.Printf("[SCHEDULER] %s missed %d scheduled run(s) since %s, skipping",
+			job.Name, missed, lastRun.Format(time.RFC3339))
+		return
+	}
+
+	log.Printf("[SCHEDULER] %s missed %d scheduled run(s) since %s, catching up once",
+		job.Name, missed, lastRun.Format(time.RFC3339))
+	s.run(ctx, job)
+}
+
+// loop waits for each scheduled time and runs the job
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	for {
+		next := NextRun(job, s.clock.Now())
+		timer := time.NewTimer(next.Sub(s.clock.Now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.markRun(job, next)
+			s.run(ctx, job)
+		}
+	}
+}
+
+// run executes a job and logs its outcome
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	start := s.clock.Now()
+	if err := job.Run(ctx); err != nil {
+		log.Printf("[SCHEDULER] %s failed: %v", job.Name, err)
+		return
+	}
+	log.Printf("[SCHEDULER] %s completed in %s", job.Name, s.clock.Now().Sub(start))
+}
+
+// markRun persists the scheduled time of the job's most recent run
+func (s *Scheduler) markRun(job Job, t time.Time) {
+	if err := s.store.SetJobLastRun(job.Name, t); err != nil {
+		log.Printf("[SCHEDULER] Failed to save state for %s: %v", job.Name, err)
+	}
+}
+
+// PreviousRun returns the most recent scheduled time at or before now
+func PreviousRun(job Job, now time.Time) time.Time {
+	local := now.In(job.Location)
+	run := time.Date(local.Year(), local.Month(), local.Day(), job.Hour, job.Minute, 0, 0, job.Location)
+	if run.After(local) {
+		run = time.Date(local.Year(), local.Month(), local.Day()-1, job.Hour, job.Minute, 0, 0, job.Location)
+	}
+	for !runsOn(job, run.Weekday()) {
+		run = time.Date(run.Year(), run.Month(), run.Day()-1, job.Hour, job.Minute, 0, 0, job.Location)
+	}
+	return run
+}
+
+// NextRun returns the first scheduled time strictly after now
+func NextRun(job Job, now time.Time) time.Time {
+	run := PreviousRun(job, now)
+	for {
+		run = time.Date(run.Year(), run.Month(), run.Day()+1, job.Hour, job.Minute, 0, 0, job.Location)
+		if runsOn(job, run.Weekday()) {
+			return run
+		}
+	}
+}
+
+// runsOn reports whether job is scheduled on weekday
+func runsOn(job Job, weekday time.Weekday) bool {
+	return len(job.Weekdays) == 0 || slices.Contains(job.Weekdays, weekday)
+}
+
+// MissedRuns counts scheduled times after lastRun and at or before now
+func MissedRuns(job Job, lastRun, now time.Time) int {
+	missed := 0
+	for run := PreviousRun(job, now); run.After(lastRun); run = PreviousRun(job, run.Add(-time.Second)) {
+		missed++
+	}
+	return missed
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock the test moves by hand
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// memStore keeps job state in memory
+type memStore struct {
+	mu   sync.Mutex
+	runs map[string]time.Time
+}
+
+func (s *memStore) GetJobLastRun(name string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[name], nil
+}
+
+func (s *memStore) SetJobLastRun(name string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[name] = t
+	return nil
+}
+
+func TestCatchUpRunsOnceAfterDowntime(t *testing.T) {
+	loc := time.UTC
+	clock := &fakeClock{}
+	store := &memStore{runs: map[string]time.Time{}}
+	s := NewWithClock(store, clock)
+
+	runs := 0
+	job := Job{
+		Name:     "nightly",
+		Hour:     3,
+		Minute:   30,
+		Location: loc,
+		CatchUp:  true,
+		Run: func(ctx context.Context) error {
+			runs++
+			return nil
+		},
+	}
+
+	// Last ran on the 1st, then the server was down until the 5th: the runs
+	// of the 2nd through the 5th were missed
+	lastRun := time.Date(2026, 3, 1, 3, 30, 0, 0, loc)
+	store.runs[job.Name] = lastRun
+	clock.Set(time.Date(2026, 3, 5, 10, 0, 0, 0, loc))
+	if missed := MissedRuns(job, lastRun, clock.Now()); missed != 4 {
+		t.Fatalf("MissedRuns = %d, want 4", missed)
+	}
+
+	s.CatchUp(context.Background(), job)
+	if runs != 1 {
+		t.Fatalf("catch-up ran %d times, want 1", runs)
+	}
+	if got, want := store.runs[job.Name], time.Date(2026, 3, 5, 3, 30, 0, 0, loc); !got.Equal(want) {
+		t.Fatalf("last run = %s, want %s", got, want)
+	}
+
+	// The next tick, and a restart before the next slot, must not run it again
+	clock.Set(clock.Now().Add(time.Minute))
+	s.CatchUp(context.Background(), job)
+	clock.Set(time.Date(2026, 3, 6, 3, 0, 0, 0, loc))
+	s.CatchUp(context.Background(), job)
+	if runs != 1 {
+		t.Fatalf("job ran %d times after catching up, want 1", runs)
+	}
+}
+
+func TestCatchUpDisabledSkipsMissedRuns(t *testing.T) {
+	loc := time.UTC
+	clock := &fakeClock{now: time.Date(2026, 3, 5, 10, 0, 0, 0, loc)}
+	store := &memStore{runs: map[string]time.Time{"nightly": time.Date(2026, 3, 1, 3, 30, 0, 0, loc)}}
+	s := NewWithClock(store, clock)
+
+	runs := 0
+	job := Job{Name: "nightly", Hour: 3, Minute: 30, Location: loc, Run: func(ctx context.Context) error {
+		runs++
+		return nil
+	}}
+	s.CatchUp(context.Background(), job)
+	if runs != 0 {
+		t.Fatalf("job without CatchUp ran %d times, want 0", runs)
+	}
+}
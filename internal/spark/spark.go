@@ -0,0 +1,67 @@
+package spark
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Options controls the size and stroke of a sparkline
+type Options struct {
+	Width       float64
+	Height      float64
+	StrokeWidth float64
+}
+
+// DefaultOptions returns the sparkline options used in the watchlist
+func DefaultOptions() Options {
+	return Options{Width: 80, Height: 24, StrokeWidth: 1.5}
+}
+
+// ViewBox returns the SVG viewBox attribute matching the options
+func (o Options) ViewBox() string {
+	return fmt.Sprintf("0 0 %s %s", formatCoord(o.Width), formatCoord(o.Height))
+}
+
+// Points scales values into the options' box and returns them as an SVG
+// polyline points attribute. The series is inset by half the stroke width so
+// the line is never clipped, and a flat series is drawn at mid-height.
+// Fewer than two values produce an empty string.
+func Points(values []float64, opts Options) string {
+	if len(values) < 2 || opts.Width <= 0 || opts.Height <= 0 {
+		return ""
+	}
+
+	minV, maxV := values[0], values[0]
+	for _, v := range values[1:] {
+		minV = math.Min(minV, v)
+		maxV = math.Max(maxV, v)
+	}
+
+	inset := opts.StrokeWidth / 2
+	width := opts.Width - 2*inset
+	height := opts.Height - 2*inset
+	stepX := width / float64(len(values)-1)
+
+	var b strings.Builder
+	for i, v := range values {
+		y := height / 2
+		if maxV > minV {
+			// SVG y grows downwards, so the highest value sits at the top
+			y = (maxV - v) / (maxV - minV) * height
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(formatCoord(inset + float64(i)*stepX))
+		b.WriteByte(',')
+		b.WriteString(formatCoord(inset + y))
+	}
+	return b.String()
+}
+
+// formatCoord formats a coordinate with at most two decimals
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
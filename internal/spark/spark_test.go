@@ -0,0 +1,36 @@
+package spark
+
+import "testing"
+
+func TestPoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		opts   Options
+		want   string
+	}{
+		{"rising", []float64{1, 2, 3}, Options{Width: 80, Height: 24, StrokeWidth: 2}, "1,23 40,12 79,1"},
+		{"falling", []float64{3, 1}, Options{Width: 80, Height: 24, StrokeWidth: 2}, "1,1 79,23"},
+		{"flat at mid-height", []float64{5, 5, 5}, Options{Width: 80, Height: 24, StrokeWidth: 2}, "1,12 40,12 79,12"},
+		{"rounded to two decimals", []float64{1, 2, 3, 4}, DefaultOptions(), "0.75,23.25 26.92,15.75 53.08,8.25 79.25,0.75"},
+		{"single value", []float64{1}, DefaultOptions(), ""},
+		{"no values", nil, DefaultOptions(), ""},
+		{"empty box", []float64{1, 2}, Options{Width: 0, Height: 24}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Points(tt.values, tt.opts); got != tt.want {
+				t.Errorf("Points(%v) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewBox(t *testing.T) {
+	if got := DefaultOptions().ViewBox(); got != "0 0 80 24" {
+		t.Errorf("ViewBox() = %q", got)
+	}
+	if got := (Options{Width: 10.5, Height: 3.125}).ViewBox(); got != "0 0 10.5 3.13" {
+		t.Errorf("ViewBox() = %q", got)
+	}
+}
@@ -1,27 +1,26 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"stockmarket/internal/ai"
+	"stockmarket/internal/analysis"
 	"stockmarket/internal/api"
 	"stockmarket/internal/db"
 	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+	"stockmarket/internal/rules"
+	"stockmarket/internal/spark"
 	"stockmarket/internal/web/pages"
-
-	"github.com/scmhub/calendar"
 )
 
-// Package-level cached calendar (immutable, safe to share)
-var nyseCalendar = calendar.XNYS()
-
-// EST timezone for market hours
-var estLocation = time.FixedZone("EST", -5*60*60)
-
 // TemplHandlers uses templ components for rendering
 type TemplHandlers struct {
 	db *db.DB
@@ -43,8 +42,10 @@ func (h *TemplHandlers) Dashboard(w http.ResponseWriter, r *http.Request) {
 		trackedSymbols = config.TrackedSymbols
 	}
 
+	now := time.Now()
 	data := pages.DashboardData{
-		MarketOpen:     isMarketOpen(),
+		MarketState:    market.MarketState(now),
+		MarketNext:     marketNext(now),
 		TrackedSymbols: trackedSymbols,
 		SignalsToday:   len(recommendations),
 		ActiveAlerts:   len(alerts),
@@ -54,6 +55,28 @@ func (h *TemplHandlers) Dashboard(w http.ResponseWriter, r *http.Request) {
 	pages.Dashboard(data).Render(r.Context(), w)
 }
 
+// marketNext describes when the regular session next opens or closes
+func marketNext(now time.Time) string {
+	if market.IsMarketOpen(now) {
+		return "Closes in " + formatCountdown(market.NextClose(now).Sub(now))
+	}
+	return "Opens in " + formatCountdown(market.NextOpen(now).Sub(now))
+}
+
+// formatCountdown formats a duration as "2h 13m", or "3d 4h" beyond a day
+func formatCountdown(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days, hours, minutes := int(d.Hours())/24, int(d.Hours())%24, int(d.Minutes())%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
 // Analysis renders the analysis page using templ
 func (h *TemplHandlers) Analysis(w http.ResponseWriter, r *http.Request) {
 	symbol := strings.TrimPrefix(r.URL.Path, "/analysis/")
@@ -62,7 +85,7 @@ func (h *TemplHandlers) Analysis(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := pages.AnalysisPageData{
-		Symbol: strings.ToUpper(symbol),
+		Symbol: market.NormalizeSymbol(symbol),
 	}
 
 	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
@@ -71,8 +94,15 @@ func (h *TemplHandlers) Analysis(w http.ResponseWriter, r *http.Request) {
 
 // Recommendations renders the recommendations page using templ
 func (h *TemplHandlers) Recommendations(w http.ResponseWriter, r *http.Request) {
+	var tags []string
+	if all, err := h.db.GetTags(); err == nil {
+		for _, tag := range all {
+			tags = append(tags, tag.Name)
+		}
+	}
+
 	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
-	pages.RecommendationsPage().Render(r.Context(), w)
+	pages.RecommendationsPage(tags).Render(r.Context(), w)
 }
 
 // Alerts renders the alerts page using templ
@@ -86,12 +116,21 @@ func (h *TemplHandlers) Settings(w http.ResponseWriter, r *http.Request) {
 	config, _ := h.db.GetConfig()
 
 	data := pages.SettingsConfig{
-		MarketDataProvider: "yahoo",
-		AIProvider:         "openai",
-		AIModel:            "gpt-4o",
-		RiskTolerance:      "moderate",
-		TradeFrequency:     "weekly",
-		PollingInterval:    60,
+		MarketDataProvider:   "yahoo",
+		AIProvider:           "openai",
+		AIModel:              "gpt-4o",
+		RiskTolerance:        "moderate",
+		TradeFrequency:       "weekly",
+		PollingInterval:      60,
+		DisplayCurrency:      market.DefaultCurrency,
+		BenchmarkSymbol:      market.DefaultBenchmark,
+		IncludeNews:          true,
+		AnalysisSchedule:     analysis.ScheduleOff,
+		AnalysisScheduleTime: analysis.DefaultScheduleTime,
+		AITemperature:        ai.DefaultTemperature,
+		AIMaxTokens:          ai.DefaultMaxTokens,
+		Currencies:           market.Currencies,
+		Exchanges:            market.Exchanges,
 	}
 
 	if config != nil {
@@ -100,16 +139,52 @@ func (h *TemplHandlers) Settings(w http.ResponseWriter, r *http.Request) {
 		data.AIProvider = config.AIProvider
 		data.AIModel = config.AIModel
 		data.HasAIAPIKey = config.HasAIAPIKey
+		data.FallbackAIProvider = config.FallbackAIProvider
+		data.FallbackAIModel = config.FallbackAIModel
+		data.HasFallbackAIKey = config.HasFallbackAIKey
+		data.AzureOpenAIEndpoint = config.AzureOpenAIEndpoint
 		data.RiskTolerance = config.RiskTolerance
 		data.TradeFrequency = config.TradeFrequency
 		data.PollingInterval = config.PollingInterval
-		data.TrackedSymbols = config.TrackedSymbols
+		data.MinStoreConfidence = config.MinStoreConfidence
+		data.StaleQuoteMinutes = config.StaleQuoteMinutes
+		data.ExtendedHoursAlerts = config.ExtendedHoursAlerts
+		data.DisplayCurrency = config.DisplayCurrency
+		data.BenchmarkSymbol = config.BenchmarkSymbol
+		data.IncludeNews = config.IncludeNews
+		data.AnalysisSchedule = config.AnalysisSchedule
+		data.AnalysisScheduleTime = config.AnalysisScheduleTime
+		data.AITemperature = config.AITemperature
+		data.AIMaxTokens = config.AIMaxTokens
+		data.AIReasoningEffort = config.AIReasoningEffort
+		data.InvestmentThesis = config.InvestmentThesis
+		data.AnalysisRetentionDays = config.Retention.AnalysisDays
+		data.AlertRetentionDays = config.Retention.AlertDays
+		data.NotificationRetentionDays = config.Retention.NotificationDays
+		data.Watchlist = make([]pages.WatchlistEntry, len(config.TrackedSymbols))
+		for i, symbol := range config.TrackedSymbols {
+			data.Watchlist[i] = pages.WatchlistEntry{Symbol: symbol, Exchange: market.ResolveExchange(config.SymbolExchanges, symbol)}
+		}
 		data.EmailAddress = config.EmailAddress
 		data.EmailEnabled = config.EmailEnabled
 		data.DiscordWebhook = config.DiscordWebhook
 		data.DiscordEnabled = config.DiscordEnabled
 		data.SMSPhone = config.SMSPhone
 		data.SMSEnabled = config.SMSEnabled
+		data.PushoverUserKey = config.PushoverUserKey
+		data.PushoverEnabled = config.PushoverEnabled
+		data.WebhookURL = config.WebhookURL
+		data.WebhookEnabled = config.WebhookEnabled
+		data.MatrixRoomID = config.MatrixRoomID
+		data.MatrixEnabled = config.MatrixEnabled
+		data.WebPushEnabled = config.WebPushEnabled
+		data.FCMDeviceToken = config.FCMDeviceToken
+		data.FCMEnabled = config.FCMEnabled
+		data.SMTPAddress = config.SMTPAddress
+		data.SMTPEnabled = config.SMTPEnabled
+		data.DigestChannels = config.DigestChannels
+		data.ChannelTemplates = config.ChannelTemplates
+		data.ChannelIDs = config.ChannelIDs
 	}
 
 	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
@@ -123,6 +198,7 @@ func (h *TemplHandlers) PartialWatchlist(w http.ResponseWriter, r *http.Request)
 	userConfig, _ := h.db.GetOrCreateConfig()
 
 	var stocks []pages.Stock
+	var benchmark *pages.Stock
 	if userConfig != nil && len(userConfig.TrackedSymbols) > 0 {
 		// Get the configured market data provider
 		provider, err := market.NewProvider(userConfig.MarketDataProvider, userConfig.MarketDataAPIKey)
@@ -131,29 +207,170 @@ func (h *TemplHandlers) PartialWatchlist(w http.ResponseWriter, r *http.Request)
 			provider = market.NewYahooFinance()
 		}
 
-		for _, sym := range userConfig.TrackedSymbols {
-			stock := pages.Stock{
-				Symbol: sym,
-				Name:   sym + " Inc.",
+		currency := userConfig.DisplayCurrency
+		now := time.Now()
+		known, _ := h.db.GetSymbols(append([]string{userConfig.BenchmarkSymbol}, userConfig.TrackedSymbols...))
+
+		// Fetch each symbol concurrently, keeping watchlist order, alongside
+		// the benchmark
+		stocks = make([]pages.Stock, len(userConfig.TrackedSymbols))
+		quoted := make([]bool, len(userConfig.TrackedSymbols))
+		var wg sync.WaitGroup
+		if userConfig.BenchmarkSymbol != "" {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				benchmark = fetchBenchmark(r.Context(), provider, userConfig.BenchmarkSymbol)
+				if benchmark != nil {
+					benchmark.Name = h.symbolInfo(r.Context(), provider, benchmark.Symbol, known).Name
+				}
+			}()
+		}
+		for i, sym := range userConfig.TrackedSymbols {
+			wg.Add(1)
+			go func(i int, sym string) {
+				defer wg.Done()
+				exchange := market.ResolveExchange(userConfig.SymbolExchanges, sym)
+				stock := pages.Stock{
+					Symbol:      sym,
+					Exchange:    exchange,
+					MarketState: market.ExchangeState(exchange, now),
+				}
+
+				// Fetch real quote (placeholder zeros if it fails)
+				quoteCtx, cancel := context.WithTimeout(r.Context(), quoteTimeout)
+				quote, err := provider.GetQuote(quoteCtx, sym)
+				cancel()
+				if err == nil && quote != nil {
+					stock.Price, stock.Currency = displayPrice(r.Context(), provider, quote, currency)
+					stock.ChangePercent = quote.ChangePercent
+					quoted[i] = true
+				}
+
+				info := h.symbolInfo(r.Context(), provider, sym, known)
+				stock.Name, stock.Sector = info.Name, info.Sector
+				stock.Sparkline = fetchSparkline(r.Context(), provider, sym)
+				stocks[i] = stock
+			}(i, sym)
+		}
+		wg.Wait()
+
+		if benchmark != nil {
+			for i := range stocks {
+				if quoted[i] && stocks[i].Symbol != benchmark.Symbol {
+					stocks[i].Benchmark = benchmark.Symbol
+					stocks[i].Relative = stocks[i].ChangePercent - benchmark.ChangePercent
+				}
 			}
+		}
+	}
 
-			// Fetch real quote
-			quote, err := provider.GetQuote(r.Context(), sym)
-			if err == nil && quote != nil {
-				stock.Price = quote.Price
-				stock.ChangePercent = quote.ChangePercent
-			} else {
-				// Fallback to placeholder if quote fails
-				stock.Price = 0
-				stock.ChangePercent = 0
-			}
+	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
+	pages.WatchlistPartial(stocks, benchmark, r.URL.Query().Get("group") == "sector").Render(r.Context(), w)
+}
 
-			stocks = append(stocks, stock)
+// fetchBenchmark quotes the benchmark for the watchlist, returning nil on failure
+func fetchBenchmark(ctx context.Context, provider market.Provider, symbol string) *pages.Stock {
+	quote, err := provider.GetQuote(ctx, symbol)
+	if err != nil {
+		return nil
+	}
+	return &pages.Stock{
+		Symbol:        symbol,
+		Price:         quote.Price,
+		Currency:      quote.Currency,
+		ChangePercent: quote.ChangePercent,
+	}
+}
+
+// sparklineTimeout bounds the per-symbol sparkline and profile fetches so a slow provider never delays the watchlist
+const sparklineTimeout = 2 * time.Second
+
+// quoteTimeout bounds each symbol's quote in the watchlist so a slow provider never holds up the other rows
+const quoteTimeout = 3 * time.Second
+
+// displayPrice converts a quote's price to the display currency, keeping the
+// listing currency when no exchange rate is available
+func displayPrice(ctx context.Context, provider market.Provider, quote *models.Quote, currency string) (float64, string) {
+	// Index levels are points, not money
+	if market.IsIndexSymbol(quote.Symbol) {
+		return quote.Price, quote.Currency
+	}
+	ctx, cancel := context.WithTimeout(ctx, sparklineTimeout)
+	defer cancel()
+
+	converted, err := market.ConvertQuote(ctx, provider, quote, currency)
+	if err != nil {
+		return quote.Price, quote.Currency
+	}
+	return converted.Price, converted.Currency
+}
+
+// symbolRefreshAge is how long stored symbol metadata is used before the
+// company profile is fetched again
+const symbolRefreshAge = 30 * 24 * time.Hour
+
+// symbolInfo returns the metadata of a symbol from known, the stored
+// metadata, fetching the company profile and storing it when it is missing
+// or old. The demo provider's made-up profiles are not stored. It returns
+// only the symbol on failure.
+func (h *TemplHandlers) symbolInfo(ctx context.Context, provider market.Provider, symbol string, known map[string]models.Symbol) models.Symbol {
+	info, ok := known[symbol]
+	if ok && time.Since(info.UpdatedAt) < symbolRefreshAge {
+		return info
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sparklineTimeout)
+	defer cancel()
+
+	profile, err := market.GetCompanyProfile(ctx, provider, symbol)
+	if err != nil {
+		if ok {
+			return info
 		}
+		return models.Symbol{Symbol: symbol}
+	}
+	info = models.Symbol{
+		Symbol:    symbol,
+		Name:      profile.Name,
+		Exchange:  profile.Exchange,
+		Sector:    profile.Sector,
+		AssetType: profile.AssetType,
+		Currency:  profile.Currency,
+		UpdatedAt: time.Now(),
+	}
+	// Some providers only classify by industry
+	if info.Sector == "" {
+		info.Sector = profile.Industry
+	}
+	if provider.Name() != "demo" {
+		h.db.SaveSymbol(&info)
 	}
+	return info
+}
 
-	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
-	pages.WatchlistPartial(stocks).Render(r.Context(), w)
+// fetchSparkline builds the intraday sparkline for a symbol, returning nil on failure
+func fetchSparkline(ctx context.Context, provider market.Provider, symbol string) *pages.Sparkline {
+	ctx, cancel := context.WithTimeout(ctx, sparklineTimeout)
+	defer cancel()
+
+	candles, err := provider.GetHistoricalData(ctx, symbol, "1d")
+	if err != nil || len(candles) < 2 {
+		return nil
+	}
+
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		closes[i] = candle.Close
+	}
+
+	opts := spark.DefaultOptions()
+	return &pages.Sparkline{
+		Points:      spark.Points(closes, opts),
+		ViewBox:     opts.ViewBox(),
+		StrokeWidth: opts.StrokeWidth,
+		Up:          candles[len(candles)-1].Close >= candles[0].Open,
+	}
 }
 
 // PartialRecommendations renders the recommendations partial
@@ -181,18 +398,24 @@ func (h *TemplHandlers) PartialRecommendations(w http.ResponseWriter, r *http.Re
 	pages.RecommendationsPartial(recs).Render(r.Context(), w)
 }
 
+// recommendationSummaryLength bounds the reasoning shown when an analysis has no highlights
+const recommendationSummaryLength = 160
+
 // PartialRecommendationsList renders the full recommendations list
 func (h *TemplHandlers) PartialRecommendationsList(w http.ResponseWriter, r *http.Request) {
 	action := r.URL.Query().Get("action")
 	minConfStr := r.URL.Query().Get("min_confidence")
 	symbol := r.URL.Query().Get("symbol")
+	preset := r.URL.Query().Get("preset")
+	feedback := r.URL.Query().Get("feedback")
+	tag := r.URL.Query().Get("tag")
 
 	var minConf float64
 	if minConfStr != "" {
 		minConf, _ = strconv.ParseFloat(minConfStr, 64)
 	}
 
-	recsRaw, _ := h.db.GetFilteredRecommendations(action, minConf, strings.ToUpper(symbol))
+	recsRaw, _ := h.db.GetFilteredRecommendations(action, minConf, market.NormalizeSymbol(symbol), preset, feedback, tag)
 
 	recs := make([]pages.RecommendationDetail, len(recsRaw))
 	for i, rec := range recsRaw {
@@ -202,7 +425,11 @@ func (h *TemplHandlers) PartialRecommendationsList(w http.ResponseWriter, r *htt
 			Action:      rec.Action,
 			Confidence:  rec.Confidence,
 			TargetPrice: rec.TargetPrice,
+			Highlights:  models.HighlightsOrTruncated(rec.Highlights, rec.Reasoning, recommendationSummaryLength),
 			AIProvider:  rec.AIProvider,
+			StaleData:   rec.StaleData,
+			AfterHours:  rec.AfterHours,
+			Tags:        rec.Tags,
 			CreatedAt:   rec.CreatedAt,
 		}
 	}
@@ -221,14 +448,37 @@ func (h *TemplHandlers) PartialAnalysisHistory(w http.ResponseWriter, r *http.Re
 		}
 	}
 
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query != "" {
+		analysesRaw, err := h.db.SearchAnalyses(query, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
+		pages.AnalysisSearchPartial(query, historyAnalyses(analysesRaw)).Render(r.Context(), w)
+		return
+	}
+
 	analysesRaw, _ := h.db.GetRecentAnalyses(limit)
 
+	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
+	pages.AnalysisHistoryPartial(historyAnalyses(analysesRaw)).Render(r.Context(), w)
+}
+
+// historyAnalyses converts analyses, newest first, for the history table
+func historyAnalyses(analysesRaw []models.AnalysisResponse) []pages.Analysis {
 	analyses := make([]pages.Analysis, len(analysesRaw))
 	for i, ar := range analysesRaw {
+		provider := ar.AIProvider
+		if provider == "" {
+			provider = "AI"
+		}
 		analyses[i] = pages.Analysis{
 			ID:         ar.ID,
 			Symbol:     ar.Symbol,
-			AIProvider: "AI",
+			AIProvider: provider,
+			Feedback:   analysisFeedback(ar.ID, ar.Feedback),
 			CreatedAt:  ar.GeneratedAt,
 			Recommendation: pages.Recommendation{
 				Symbol:     ar.Symbol,
@@ -236,10 +486,15 @@ func (h *TemplHandlers) PartialAnalysisHistory(w http.ResponseWriter, r *http.Re
 				Confidence: ar.Confidence,
 			},
 		}
+		// Link each analysis to the previous one of its symbol for comparison
+		for _, older := range analysesRaw[i+1:] {
+			if older.Symbol == ar.Symbol {
+				analyses[i].PreviousID = older.ID
+				break
+			}
+		}
 	}
-
-	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
-	pages.AnalysisHistoryPartial(analyses).Render(r.Context(), w)
+	return analyses
 }
 
 // PartialAnalysisDetail renders a single analysis result
@@ -262,12 +517,16 @@ func (h *TemplHandlers) PartialAnalysisDetail(w http.ResponseWriter, r *http.Req
 		Symbol:     analysis.Symbol,
 		CreatedAt:  analysis.CreatedAt,
 		AIProvider: analysis.AIProvider,
+		Feedback:   analysisFeedback(analysis.ID, analysis.Feedback),
+		Tags:       h.analysisTags(analysis.ID),
 		Recommendation: pages.AnalysisRecommendation{
 			Action:      analysis.Recommendation.Action,
 			Confidence:  analysis.Recommendation.Confidence,
 			TargetPrice: analysis.Recommendation.TargetPrice,
 			StopLoss:    analysis.Recommendation.StopLoss,
 			Reasoning:   analysis.Recommendation.Reasoning,
+			StaleData:   analysis.Recommendation.StaleData,
+			AfterHours:  analysis.Recommendation.AfterHours,
 		},
 	}
 
@@ -284,9 +543,16 @@ func (h *TemplHandlers) PartialAnalysisDetail(w http.ResponseWriter, r *http.Req
 	pages.AnalysisResultCard(result).Render(r.Context(), w)
 }
 
-// PartialAlertsList renders the alerts list
+// PartialAlertsList renders the active alerts, or the triggered ones with
+// ?state=triggered
 func (h *TemplHandlers) PartialAlertsList(w http.ResponseWriter, r *http.Request) {
-	alertsRaw, _ := h.db.GetActiveAlerts()
+	state := r.URL.Query().Get("state")
+	var alertsRaw []models.PriceAlert
+	if state == "triggered" {
+		alertsRaw, _ = h.db.GetTriggeredAlerts()
+	} else {
+		alertsRaw, _ = h.db.GetActiveAlerts()
+	}
 
 	alerts := make([]pages.Alert, len(alertsRaw))
 	for i, ar := range alertsRaw {
@@ -295,12 +561,29 @@ func (h *TemplHandlers) PartialAlertsList(w http.ResponseWriter, r *http.Request
 			Symbol:      ar.Symbol,
 			Condition:   ar.Condition,
 			TargetPrice: ar.Price,
+			PeakPrice:   ar.PeakPrice,
+			FastPeriod:  ar.FastPeriod,
+			SlowPeriod:  ar.SlowPeriod,
+			Period:      ar.Period,
+			Recurring:   ar.Recurring,
+			Cooldown:    ar.CooldownMinutes,
+			Hysteresis:  ar.HysteresisPercent,
+			Rearming:    ar.Rearming,
+			Enabled:     ar.Enabled,
+			Channels:    ar.Channels,
+			Label:       ar.Label,
+			Note:        ar.Note,
+			LastFired:   ar.TriggeredAt,
+			ExpiresAt:   ar.ExpiresAt,
 			Triggered:   ar.Triggered,
 		}
+		if ar.Rule != nil {
+			alerts[i].Rule = rules.Describe(*ar.Rule)
+		}
 	}
 
 	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
-	pages.AlertsListPartial(alerts).Render(r.Context(), w)
+	pages.AlertsListPartial(alerts, state).Render(r.Context(), w)
 }
 
 // PartialQuickAnalyze renders quick analyze buttons
@@ -316,6 +599,30 @@ func (h *TemplHandlers) PartialQuickAnalyze(w http.ResponseWriter, r *http.Reque
 	pages.QuickAnalyzePartial(symbols).Render(r.Context(), w)
 }
 
+// PartialAnalysisPresets renders the preset buttons for the analyze form
+func (h *TemplHandlers) PartialAnalysisPresets(w http.ResponseWriter, r *http.Request) {
+	var presets []pages.AnalysisPreset
+	if userConfig, err := h.db.GetOrCreateConfig(); err == nil {
+		presetsRaw, _ := h.db.GetAnalysisPresets(userConfig.ID)
+		for _, p := range presetsRaw {
+			var parts []string
+			for _, part := range []string{p.AIProvider, p.AIModel, p.TradeFrequency, p.HistoryPeriod} {
+				if part != "" {
+					parts = append(parts, part)
+				}
+			}
+			presets = append(presets, pages.AnalysisPreset{
+				ID:      p.ID,
+				Name:    p.Name,
+				Summary: strings.Join(parts, " · "),
+			})
+		}
+	}
+
+	w.Header().Set(api.HEADER_CONTENT_TYPE, api.CONTENT_TYPE_HTML)
+	pages.AnalysisPresetsPartial(presets).Render(r.Context(), w)
+}
+
 // PartialWatchlistAlertButtons renders watchlist buttons for alerts page
 func (h *TemplHandlers) PartialWatchlistAlertButtons(w http.ResponseWriter, r *http.Request) {
 	config, _ := h.db.GetConfig()
@@ -329,6 +636,30 @@ func (h *TemplHandlers) PartialWatchlistAlertButtons(w http.ResponseWriter, r *h
 	pages.WatchlistAlertButtonsPartial(symbols).Render(r.Context(), w)
 }
 
+// analysisFeedback converts stored feedback for the feedback buttons
+func analysisFeedback(id int64, fb *models.Feedback) pages.AnalysisFeedback {
+	out := pages.AnalysisFeedback{AnalysisID: id}
+	if fb != nil {
+		out.Rating = fb.Rating
+		out.Note = fb.Note
+	}
+	return out
+}
+
+// analysisTags loads the tags of an analysis for the tag editor
+func (h *TemplHandlers) analysisTags(id int64) pages.AnalysisTags {
+	out := pages.AnalysisTags{AnalysisID: id}
+	tags, _ := h.db.GetAnalysisTags(id)
+	for _, tag := range tags {
+		out.Tags = append(out.Tags, pages.AnalysisTag{ID: tag.ID, Name: tag.Name})
+	}
+	all, _ := h.db.GetTags()
+	for _, tag := range all {
+		out.Suggestions = append(out.Suggestions, tag.Name)
+	}
+	return out
+}
+
 // formatVolume formats a volume number for display
 func formatVolume(vol int64) string {
 	if vol >= 1_000_000_000 {
@@ -342,7 +673,3 @@ func formatVolume(vol int64) string {
 	}
 	return fmt.Sprintf("%d", vol)
 }
-
-func isMarketOpen() bool {
-	return nyseCalendar.IsOpen(time.Now().In(estLocation))
-}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stockmarket/internal/market"
+	"stockmarket/internal/models"
+)
+
+// historyProvider serves fixed intraday candles or an error
+type historyProvider struct {
+	market.Provider
+	candles []models.Candle
+	err     error
+}
+
+func (p historyProvider) GetHistoricalData(ctx context.Context, symbol, period string) ([]models.Candle, error) {
+	return p.candles, p.err
+}
+
+func TestFetchSparkline(t *testing.T) {
+	start := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	candles := []models.Candle{
+		{Timestamp: start, Open: 100, Close: 101},
+		{Timestamp: start.Add(5 * time.Minute), Open: 101, Close: 99},
+		{Timestamp: start.Add(10 * time.Minute), Open: 99, Close: 102},
+	}
+
+	line := fetchSparkline(context.Background(), historyProvider{candles: candles}, "AAPL")
+	if line == nil {
+		t.Fatal("no sparkline")
+	}
+	if line.Points == "" || line.ViewBox != "0 0 80 24" || !line.Up {
+		t.Fatalf("sparkline = %+v", line)
+	}
+}
+
+func TestFetchSparklineDegrades(t *testing.T) {
+	if line := fetchSparkline(context.Background(), historyProvider{err: errors.New("upstream down")}, "AAPL"); line != nil {
+		t.Fatalf("sparkline on a provider error = %+v, want nil", line)
+	}
+	one := []models.Candle{{Open: 1, Close: 2}}
+	if line := fetchSparkline(context.Background(), historyProvider{candles: one}, "AAPL"); line != nil {
+		t.Fatalf("sparkline from one candle = %+v, want nil", line)
+	}
+}
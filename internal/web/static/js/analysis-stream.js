@@ -0,0 +1,89 @@
+// Streaming analyses on the analysis page
+(function () {
+  'use strict';
+
+  if (!window.EventSource) {
+    return;
+  }
+
+  const RESULT_ID = 'analysis-result';
+  const SPINNER_ID = 'analyze-spinner';
+
+  // Requests htmx would post to /api/analyze for the result panel go to the
+  // stream endpoint instead, so the model's reply shows as it is generated
+  document.addEventListener('htmx:configRequest', function (evt) {
+    const detail = evt.detail;
+    if (detail.path !== '/api/analyze' || !detail.target || detail.target.id !== RESULT_ID) {
+      return;
+    }
+    const symbol = String(detail.parameters.symbol || '').trim();
+    if (!symbol) {
+      // Let the server report the missing symbol
+      return;
+    }
+    evt.preventDefault();
+    stream(symbol, detail.parameters, detail.target);
+  });
+
+  // Open the stream and render the reply, then the result card or the error
+  function stream(symbol, params, target) {
+    const query = new URLSearchParams();
+    if (params.context) {
+      query.set('context', params.context);
+    }
+    if (params.preset_id) {
+      query.set('preset_id', params.preset_id);
+    }
+
+    const spinner = document.getElementById(SPINNER_ID);
+    if (spinner) {
+      spinner.classList.add('htmx-request');
+    }
+
+    target.innerHTML =
+      '<div class="bg-bg-elevated rounded-xl border border-border p-6 animate-fade-in">' +
+      '<p class="text-xs font-medium text-content-muted uppercase tracking-wider mb-2">Generating analysis…</p>' +
+      '<pre class="whitespace-pre-wrap break-words font-mono text-sm text-content-secondary"></pre>' +
+      '</div>';
+    const output = target.querySelector('pre');
+
+    const source = new EventSource('/api/analyze/' + encodeURIComponent(symbol) + '/stream?' + query.toString());
+
+    function finish() {
+      source.close();
+      if (spinner) {
+        spinner.classList.remove('htmx-request');
+      }
+    }
+
+    source.addEventListener('token', function (e) {
+      output.textContent += JSON.parse(e.data);
+    });
+
+    source.addEventListener('card', function (e) {
+      finish();
+      target.innerHTML = JSON.parse(e.data);
+      htmx.process(target);
+    });
+
+    // Sent by the server on failure, and by the browser when the connection drops
+    source.addEventListener('error', function (e) {
+      finish();
+      let message = 'Analysis failed: connection lost';
+      if (e.data) {
+        try {
+          message = JSON.parse(e.data).error;
+        } catch (_) {
+          // keep the generic message
+        }
+      }
+      const box = document.createElement('div');
+      box.className = 'flex items-center gap-3 p-4 bg-negative-bg/50 border border-negative/20 rounded-xl text-negative';
+      const text = document.createElement('p');
+      text.className = 'text-sm font-medium';
+      text.textContent = message;
+      box.appendChild(text);
+      target.replaceChildren(box);
+    });
+  }
+})();
@@ -0,0 +1,37 @@
+// Service worker showing browser push notifications
+'use strict';
+
+self.addEventListener('push', function (event) {
+  let data = {};
+  try {
+    data = event.data ? event.data.json() : {};
+  } catch (e) {
+    data = { message: event.data.text() };
+  }
+
+  event.waitUntil(
+    self.registration.showNotification(data.title || 'StockAI', {
+      body: data.message || '',
+      tag: data.symbol ? data.type + '-' + data.symbol : undefined,
+      requireInteraction: data.type === 'sell_signal',
+      data: { url: data.url || '/alerts' },
+    })
+  );
+});
+
+// Focus an open tab on the notification's page, or open one
+self.addEventListener('notificationclick', function (event) {
+  event.notification.close();
+  const url = new URL(event.notification.data.url, self.location.origin).href;
+
+  event.waitUntil(
+    clients.matchAll({ type: 'window', includeUncontrolled: true }).then(function (windows) {
+      for (const win of windows) {
+        if (win.url === url && 'focus' in win) {
+          return win.focus();
+        }
+      }
+      return clients.openWindow(url);
+    })
+  );
+});
@@ -0,0 +1,114 @@
+// Browser push subscription on the settings page
+(function () {
+  'use strict';
+
+  const BUTTON_ID = 'push-subscribe';
+  const STATUS_ID = 'push-status';
+  const WORKER_URL = '/static/js/push-sw.js';
+
+  const button = document.getElementById(BUTTON_ID);
+  const status = document.getElementById(STATUS_ID);
+  if (!button || !status) {
+    return;
+  }
+
+  if (!('serviceWorker' in navigator) || !('PushManager' in window)) {
+    status.textContent = 'Not supported in this browser';
+    return;
+  }
+
+  let registration = null;
+
+  navigator.serviceWorker.register(WORKER_URL)
+    .then(function (reg) {
+      registration = reg;
+      return reg.pushManager.getSubscription();
+    })
+    .then(render)
+    .catch(function (err) {
+      status.textContent = 'Unavailable: ' + err.message;
+    });
+
+  button.addEventListener('click', function () {
+    button.disabled = true;
+    registration.pushManager.getSubscription()
+      .then(function (sub) {
+        return sub ? unsubscribe(sub) : subscribe();
+      })
+      .then(render)
+      .catch(function (err) {
+        button.disabled = false;
+        notify(err.message, 'error');
+      });
+  });
+
+  // Subscribe with the server's VAPID key and register the subscription
+  function subscribe() {
+    return Notification.requestPermission()
+      .then(function (permission) {
+        if (permission !== 'granted') {
+          throw new Error('Notifications are blocked for this site');
+        }
+        return request('GET', '/api/push/vapid-public-key');
+      })
+      .then(function (data) {
+        return registration.pushManager.subscribe({
+          userVisibleOnly: true,
+          applicationServerKey: decodeKey(data.public_key),
+        });
+      })
+      .then(function (sub) {
+        return request('POST', '/api/push/subscriptions', sub.toJSON()).then(function () {
+          notify('This browser will receive push notifications', 'success');
+          return sub;
+        });
+      });
+  }
+
+  // Forget the subscription on the server, then in the browser
+  function unsubscribe(sub) {
+    return request('DELETE', '/api/push/subscriptions', { endpoint: sub.endpoint })
+      .then(function () {
+        return sub.unsubscribe();
+      })
+      .then(function () {
+        notify('This browser will no longer receive push notifications', 'success');
+        return null;
+      });
+  }
+
+  function render(sub) {
+    button.disabled = false;
+    button.textContent = sub ? 'Unsubscribe this browser' : 'Subscribe this browser';
+    status.textContent = sub ? 'Subscribed' : '';
+  }
+
+  function request(method, url, body) {
+    const options = { method: method, headers: { 'Content-Type': 'application/json' } };
+    if (body) {
+      options.body = JSON.stringify(body);
+    }
+    return fetch(url, options).then(function (resp) {
+      return resp.json().then(function (data) {
+        if (!resp.ok) {
+          throw new Error(data.error || 'Request failed');
+        }
+        return data;
+      });
+    });
+  }
+
+  // The VAPID key is unpadded base64url
+  function decodeKey(key) {
+    const base64 = (key + '='.repeat((4 - (key.length % 4)) % 4)).replace(/-/g, '+').replace(/_/g, '/');
+    return Uint8Array.from(atob(base64), function (c) {
+      return c.charCodeAt(0);
+    });
+  }
+
+  function notify(message, type) {
+    if (typeof showToast === 'function') {
+      showToast(message, type);
+    }
+  }
+})();